@@ -8,16 +8,25 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"caster-generator/internal/analyze"
+	"caster-generator/internal/cache"
 	"caster-generator/internal/diagnostic"
 	"caster-generator/internal/gen"
 	"caster-generator/internal/mapping"
 	"caster-generator/internal/plan"
+	"caster-generator/internal/stats"
 )
 
 const (
@@ -32,6 +41,15 @@ Commands:
   suggest   Generate a suggested YAML mapping for a type pair
   gen       Generate casters using YAML mapping
   check     Validate YAML against current code; fail on drift
+  freeze    Write a fully explicit mapping with all auto-matched fields locked
+  effective-config  Show the rule deciding each target field and where it came from
+  explain   Show the full decision trail of one target field
+  review    Walk through unmapped fields and write the decisions into the YAML
+  stats     Report local usage statistics for mappings and generated code (JSON)
+  report    Report coverage and the estimated run-time cost of every caster
+  graph     Print the dependency graph of casters and transforms (DOT or Mermaid)
+  schema    Print the JSON Schema of mapping files, for editor completion
+  regen-field  Regenerate the assignment of one target field in an existing caster
 
 Global Options:
   -help     Show help for a command
@@ -50,6 +68,33 @@ Examples:
   # Validate existing mapping against code
   caster-generator check -mapping mapping.yaml
 
+  # Lock auto-matched fields for reproducible generation
+  caster-generator freeze -mapping mapping.yaml -out mapping.lock.yaml
+
+  # Show which rule decides each field after 121/fields/ignore/auto combine
+  caster-generator effective-config -mapping mapping.yaml
+
+  # Show why Status is mapped the way it is, and which candidates lost
+  caster-generator explain -mapping mapping.yaml -field warehouse.Order.Status
+
+  # Accept, skip or ignore each unmapped field interactively
+  caster-generator review -mapping mapping.yaml
+
+  # Report mapping and generated code statistics as JSON
+  caster-generator stats -root . -out stats.json
+
+  # List casters from the most to the least costly
+  caster-generator report -mapping mapping.yaml -by-cost
+
+  # Render how the DTO layers convert into each other
+  caster-generator graph -mapping mapping.yaml -format dot | dot -Tsvg > casters.svg
+
+  # Let editors complete and validate mapping files
+  caster-generator schema -out mapping.schema.json
+
+  # Patch only the assignment of Total after tweaking its transform
+  caster-generator regen-field -mapping mapping.yaml -pair store.Order:api.Order -field Total
+
 Run 'caster-generator <command> -help' for more information on a command.
 `
 )
@@ -77,6 +122,24 @@ func main() {
 		runGen(os.Args[2:])
 	case "check":
 		runCheck(os.Args[2:])
+	case "freeze":
+		runFreeze(os.Args[2:])
+	case "effective-config":
+		runEffectiveConfig(os.Args[2:])
+	case "explain":
+		runExplain(os.Args[2:])
+	case "review":
+		runReview(os.Args[2:])
+	case "stats":
+		runStats(os.Args[2:])
+	case "report":
+		runReport(os.Args[2:])
+	case "graph":
+		runGraph(os.Args[2:])
+	case "schema":
+		runSchema(os.Args[2:])
+	case "regen-field":
+		runRegenField(os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
 		fmt.Print(usage)
@@ -96,6 +159,145 @@ func (s *StringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// analysisFlags holds the package loading limits shared by commands that analyze packages.
+type analysisFlags struct {
+	maxPackages *int
+	maxTypes    *int
+	timeout     *time.Duration
+	skipDirs    StringSliceFlag
+	proto       *bool
+}
+
+// addAnalysisFlags registers the package loading limit flags on fs.
+func addAnalysisFlags(fs *flag.FlagSet) *analysisFlags {
+	defaults := analyze.DefaultLimits()
+	f := &analysisFlags{}
+
+	f.maxPackages = fs.Int("max-packages", defaults.MaxPackages, "Fail if the patterns match more packages (0 = no limit)")
+	f.maxTypes = fs.Int("max-types", defaults.MaxTypes, "Fail if the packages declare more exported types (0 = no limit)")
+	f.timeout = fs.Duration("load-timeout", defaults.Timeout, "Fail if loading packages takes longer (0 = no limit)")
+	fs.Var(&f.skipDirs, "skip-dir",
+		"Skip packages under directories with this name, in addition to vendor and testdata (can be specified multiple times)")
+	f.proto = fs.Bool("proto", false,
+		"Treat protoc-gen-go structs as messages: skip internal fields, map oneof members and read fields through getters")
+
+	return f
+}
+
+// limits returns the analyzer limits selected by the flags.
+func (f *analysisFlags) limits() analyze.Limits {
+	return analyze.Limits{
+		MaxPackages: *f.maxPackages,
+		MaxTypes:    *f.maxTypes,
+		Timeout:     *f.timeout,
+		SkipDirs:    append(append([]string{}, analyze.DefaultSkipDirs...), f.skipDirs...),
+	}
+}
+
+// newAnalyzer returns an analyzer with the limits and modes selected by the flags.
+func (f *analysisFlags) newAnalyzer() *analyze.Analyzer {
+	analyzer := analyze.NewAnalyzerWithLimits(f.limits())
+	analyzer.SetProto(*f.proto)
+
+	return analyzer
+}
+
+// suppressionFlags select the suppression file listing the fingerprints of
+// known diagnostics, which are not reported.
+type suppressionFlags struct {
+	file   *string
+	update *bool
+	// path is the suppression file used, set by resolve.
+	path string
+}
+
+// addSuppressionFlags registers the suppression file flags on fs, with
+// -update-suppressions if update is set.
+func addSuppressionFlags(fs *flag.FlagSet, update bool) *suppressionFlags {
+	f := &suppressionFlags{}
+
+	f.file = fs.String("suppressions", "",
+		"File listing fingerprints of known diagnostics not to report (default "+
+			diagnostic.SuppressionFile+" next to the mapping file)")
+
+	if update {
+		f.update = fs.Bool("update-suppressions", false,
+			"Rewrite the suppression file to list every current warning and error, accepting them as known")
+	}
+
+	return f
+}
+
+// resolve selects the suppression file of the mapping file mappingFile.
+func (f *suppressionFlags) resolve(mappingFile string) {
+	f.path = *f.file
+	if f.path == "" {
+		f.path = filepath.Join(filepath.Dir(mappingFile), diagnostic.SuppressionFile)
+	}
+}
+
+// contents returns the contents of the suppression file, empty if it can't be
+// read, for cache keys.
+func (f *suppressionFlags) contents() string {
+	data, _ := os.ReadFile(f.path)
+	return string(data)
+}
+
+// apply removes the diagnostics listed in the suppression file from diags or,
+// with -update-suppressions, rewrites the file to list all of them first. It
+// returns whether the unmapped_field warning of a target field of a type pair
+// ("Source->Target") was suppressed.
+func (f *suppressionFlags) apply(diags *diagnostic.Diagnostics) func(typePair, field string) bool {
+	if f.update != nil && *f.update {
+		if err := os.WriteFile(f.path, diagnostic.FormatSuppressions(diags), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing suppression file: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Suppression file %s lists %d known diagnostic(s)\n", f.path, len(diags.Errors)+len(diags.Warnings))
+	}
+
+	suppressions, err := diagnostic.LoadSuppressions(f.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	suppressed := diags.Suppress(suppressions)
+	if len(suppressed) > 0 {
+		fmt.Fprintf(os.Stderr, "Suppressed %d known diagnostic(s) listed in %s\n", len(suppressed), f.path)
+	}
+
+	if unused := suppressions.Unused(suppressed); len(unused) > 0 {
+		fmt.Fprintf(os.Stderr, "Note: %d suppression(s) in %s match no diagnostic and can be removed: %s\n",
+			len(unused), f.path, strings.Join(unused, ", "))
+	}
+
+	unmapped := make(map[string]bool)
+
+	for _, d := range suppressed {
+		if d.Code == "unmapped_field" {
+			unmapped[d.TypePair+"."+d.FieldPath] = true
+		}
+	}
+
+	return func(typePair, field string) bool {
+		return unmapped[typePair+"."+field]
+	}
+}
+
+// printLimitHint names the flag to adjust when loading failed on an analysis limit.
+func printLimitHint(err error) {
+	switch {
+	case errors.Is(err, analyze.ErrTooManyPackages):
+		fmt.Fprintln(os.Stderr, "Hint: narrow -pkg, add -skip-dir, or raise -max-packages")
+	case errors.Is(err, analyze.ErrTooManyTypes):
+		fmt.Fprintln(os.Stderr, "Hint: narrow -pkg, add -skip-dir, or raise -max-types")
+	case errors.Is(err, analyze.ErrLoadTimeout):
+		fmt.Fprintln(os.Stderr, "Hint: narrow -pkg, add -skip-dir, or raise -load-timeout")
+	}
+}
+
 // runAnalyze implements the 'analyze' command.
 func runAnalyze(args []string) {
 	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
@@ -112,6 +314,7 @@ Options:
 	var packages StringSliceFlag
 
 	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times, default: ./...)")
+	limits := addAnalysisFlags(fs)
 	verbose := fs.Bool("verbose", false, "Show detailed field information including tags")
 	typeFilter := fs.String("type", "", "Filter to show only a specific type")
 
@@ -125,11 +328,12 @@ Options:
 	}
 
 	// Load packages
-	analyzer := analyze.NewAnalyzer()
+	analyzer := limits.newAnalyzer()
 
 	graph, err := analyzer.LoadPackages(packages...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
 		os.Exit(1)
 	}
 
@@ -151,7 +355,12 @@ Options:
 				continue
 			}
 
-			fmt.Printf("\n  %s (%s)\n", typeID.Name, typeInfo.Kind)
+			name := typeID.Name
+			if typeInfo.IsGeneric() {
+				name += "[" + strings.Join(typeInfo.TypeParams, ", ") + "]"
+			}
+
+			fmt.Printf("\n  %s (%s)\n", name, typeInfo.Kind)
 
 			if typeInfo.Kind == analyze.TypeKindStruct {
 				for _, field := range typeInfo.Fields {
@@ -189,6 +398,7 @@ Options:
 	var packages StringSliceFlag
 
 	fs.Var(&packages, "pkg", "Package path to analyze (auto-detected from type names if not specified)")
+	limits := addAnalysisFlags(fs)
 	mappingFile := fs.String("mapping", "", "Path to existing YAML mapping file to improve")
 	fromType := fs.String("from", "", "Source type (e.g., store.Order) - required if no mapping file")
 	toType := fs.String("to", "", "Target type (e.g., warehouse.Order) - required if no mapping file")
@@ -197,6 +407,14 @@ Options:
 	minGap := fs.Float64("min-gap", 0.15, "Minimum score gap between top candidates for auto-accept")
 	ambiguityThreshold := fs.Float64("ambiguity-threshold", 0.1, "Score difference threshold for marking ambiguity")
 	maxCandidates := fs.Int("max-candidates", 5, "Maximum number of candidates to include in suggestions")
+	positionWeight := fs.Float64("position-weight", 0,
+		"Weight (0.0-1.0) of relative field position similarity in matching, saved as match.position_weight")
+	matchTag := fs.String("match-tag", "",
+		"Struct tag (e.g. json, db) whose equal values pin field matches regardless of Go names, saved as match.tag")
+	stripAffixes := fs.String("strip-affixes", "",
+		"Comma-separated name tokens (e.g. DTO,Model,V1) ignored at the start or end of field names in matching")
+	onIncompatiblePin := fs.String("on-incompatible-pin", "",
+		"Override on_incompatible_pin for 121 mappings with incompatible types: error, todo or fallback_auto")
 
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
@@ -204,15 +422,15 @@ Options:
 
 	// Auto-detect packages from type names if not specified
 	if len(packages) == 0 {
-		fromPkg := extractPackage(*fromType)
-		toPkg := extractPackage(*toType)
+		fromPkg := mapping.TypePackage(*fromType)
+		toPkg := mapping.TypePackage(*toType)
 
 		if fromPkg != "" {
-			packages = append(packages, "./"+fromPkg)
+			packages = append(packages, mapping.LoadPattern(fromPkg))
 		}
 
 		if toPkg != "" && toPkg != fromPkg {
-			packages = append(packages, "./"+toPkg)
+			packages = append(packages, mapping.LoadPattern(toPkg))
 		}
 	}
 
@@ -228,7 +446,7 @@ Options:
 
 			// Auto-detect packages from existing mapping if not specified
 			if len(packages) == 0 {
-				packages = extractPackagesFromMapping(mappingDef)
+				packages = mapping.Packages(mappingDef)
 			}
 		} else {
 			fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
@@ -243,7 +461,7 @@ Options:
 
 			// Auto-detect packages from existing mapping if not specified
 			if len(packages) == 0 {
-				packages = extractPackagesFromMapping(mappingDef)
+				packages = mapping.Packages(mappingDef)
 			}
 		} else {
 			fmt.Fprintf(os.Stderr, "Note: could not load existing mapping from %s: %v\n", *outFile, err)
@@ -276,21 +494,41 @@ Options:
 		os.Exit(1)
 	}
 
+	// The weight and tag are written to the suggested mapping, so that later
+	// commands rank candidates the same way.
+	if *matchTag != "" {
+		mappingDef.Match.Tag = *matchTag
+	}
+
+	if *positionWeight != 0 {
+		if *positionWeight < 0 || *positionWeight > 1 {
+			fmt.Fprintf(os.Stderr, "Error: invalid -position-weight %v (expected a number from 0 to 1)\n", *positionWeight)
+			os.Exit(1)
+		}
+
+		mappingDef.Match.PositionWeight = *positionWeight
+	}
+
 	// Load packages
-	analyzer := analyze.NewAnalyzer()
+	analyzer := limits.newAnalyzer()
 
 	graph, err := analyzer.LoadPackages(packages...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
 		os.Exit(1)
 	}
 
+	expandPackageMappings(mappingDef, graph)
+
 	// Run resolution with auto-matching
 	config := plan.DefaultConfig()
 	config.MinConfidence = *minConfidence
 	config.MinGap = *minGap
 	config.AmbiguityThreshold = *ambiguityThreshold
 	config.MaxCandidates = *maxCandidates
+	config.StripAffixes = parseTags(*stripAffixes)
+	config.OnIncompatiblePin = parsePinPolicy(*onIncompatiblePin)
 	resolver := plan.NewResolver(graph, mappingDef, config)
 
 	resolvedPlan, err := resolver.Resolve()
@@ -305,6 +543,7 @@ Options:
 		MinGap:                  *minGap,
 		AmbiguityThreshold:      *ambiguityThreshold,
 		IncludeRejectedComments: true,
+		IncludeThresholdSummary: true,
 	}
 
 	yamlData, err := plan.ExportSuggestionsYAMLWithConfig(resolvedPlan, exportConfig)
@@ -361,11 +600,53 @@ Options:
 	var packages StringSliceFlag
 
 	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
 	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
 	outDir := fs.String("out", "./generated", "Output directory for generated files")
 	pkgName := fs.String("package", "casters", "Package name for generated code")
-	strict := fs.Bool("strict", false, "Fail on any unresolved target fields")
+	strict := fs.Bool("strict", false,
+		"Fail on any resolution error, and set every fail_on_* check of the mapping file's policy")
+	policyFlag := fs.String("policy", "",
+		"Override checks of the mapping file's policy, as comma-separated settings "+
+			"(e.g. fail_on_unmapped,fail_on_lossy=false,min_auto_confidence=0.8)")
 	writeSuggestions := fs.String("write-suggestions", "", "Write suggested mapping YAML to this file")
+	manifestFile := fs.String("manifest", "", "Write caster dependency manifest (JSON) to this file")
+	singleFile := fs.String("single-file", "", "Write all casters, helpers and transform stubs to this one file")
+	deepCopy := fs.Bool("deep-copy", false, "Deep-copy reference values when the mapping file sets no copy_mode")
+	genericRequires := fs.Bool("generic-requires", false,
+		"Type untyped requires passed only to transforms and nested casters with a type parameter")
+	style := fs.String("style", gen.StyleFunctions,
+		"Emit casters as package-level functions or as methods of a Casters type (functions, methods)")
+	funcTemplate := fs.String("func-template", "",
+		"Naming template of casters, e.g. Map{{.SourceName}}To{{.TargetName}} (overrides naming.func of the mapping)")
+	overrides := fs.Bool("overrides", false,
+		"Give each caster a final variadic parameter of functions applied to its result, "+
+			"e.g. overrides ...func(*warehouse.Order), to post-process mapped values at call sites")
+	checkReproducible := fs.Bool("check-reproducible", false,
+		"Resolve and generate twice and fail unless both outputs are byte-identical")
+	tagsFlag := fs.String("tags", "", "Generate only mappings with one of these comma-separated tags")
+	benchmarks := fs.Bool("benchmarks", false, "Also write a _test.go file benchmarking each caster on synthesized values")
+	tests := fs.Bool("tests", false,
+		"Also write a _test.go file checking the fields each caster copies, converts or defaults on synthesized values")
+	debugCasters := fs.String("debug-casters", "",
+		"Also write a variant of each caster built with -tags "+gen.DebugBuildTag+
+			" that logs or panics on target fields left zero although their source is set (log, panic)")
+	cacheDir := fs.String("cache", "",
+		"Resolve only mappings changed since an earlier run cached in this directory, and skip generation when none did")
+	output := fs.String("output", outputDir,
+		"Write generated files to the output directory, a zip archive or a patch of the directory (dir, zip, patch)")
+	outputFile := fs.String("output-file", "-", "File the zip or patch output is written to (- for stdout)")
+	onIncompatiblePin := fs.String("on-incompatible-pin", "",
+		"Override on_incompatible_pin for 121 mappings with incompatible types: error, todo or fallback_auto")
+	diff := fs.Bool("diff", false,
+		"Print a unified diff of the generated code against the output directory without writing anything, "+
+			"and exit with status 1 if files would change")
+
+	var only StringSliceFlag
+
+	fs.Var(&only, "only", "Generate only this type pair, as Source->Target (can be specified multiple times)")
+
+	suppressions := addSuppressionFlags(fs, false)
 
 	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
@@ -377,6 +658,25 @@ Options:
 		os.Exit(1)
 	}
 
+	suppressions.resolve(*mappingFile)
+
+	if *output != outputDir && *output != outputZip && *output != outputPatch {
+		fmt.Fprintf(os.Stderr, "Error: -output must be %s, %s or %s, got %q\n", outputDir, outputZip, outputPatch, *output)
+		os.Exit(1)
+	}
+
+	if *diff && (*output != outputDir || *writeSuggestions != "" || *manifestFile != "") {
+		fmt.Fprintln(os.Stderr, "Error: -diff writes nothing, so it can't be combined with -output, "+
+			"-write-suggestions or -manifest")
+		os.Exit(1)
+	}
+
+	// Keep stdout for the archive or patch when they are written there
+	status := io.Writer(os.Stdout)
+	if (*output != outputDir && *outputFile == "-") || *diff {
+		status = os.Stderr
+	}
+
 	// Load mapping file
 	mappingDef, err := mapping.LoadFile(*mappingFile)
 	if err != nil {
@@ -386,7 +686,7 @@ Options:
 
 	// Auto-detect packages from mapping if not specified
 	if len(packages) == 0 {
-		packages = extractPackagesFromMapping(mappingDef)
+		packages = mapping.Packages(mappingDef)
 	}
 
 	if len(packages) == 0 {
@@ -395,15 +695,50 @@ Options:
 		os.Exit(1)
 	}
 
+	// Nothing to do when the inputs and the output are those of a cached run
+	var (
+		store *cache.Store
+		fps   *analyze.Fingerprints
+	)
+
+	genKey := ""
+
+	if *cacheDir != "" && !*checkReproducible {
+		store = cache.NewStore(*cacheDir)
+
+		fps, err = analyze.FingerprintPackages(packages...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fingerprinting packages: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if store != nil && *output == outputDir && *writeSuggestions == "" && *manifestFile == "" && !*diff {
+		genKey = genCacheKey(mappingDef, fps, limits, *outDir,
+			*pkgName, fmt.Sprint(*strict), *policyFlag, *singleFile, fmt.Sprint(*deepCopy), fmt.Sprint(*genericRequires),
+			*style, *funcTemplate, fmt.Sprint(*overrides), *tagsFlag, strings.Join(only, ","), fmt.Sprint(*benchmarks),
+			fmt.Sprint(*tests), *onIncompatiblePin, *debugCasters, suppressions.contents())
+
+		var cached cache.GenResult
+		if store.Load("gen", genKey, &cached) && cached.Current() {
+			fmt.Fprintf(status, "Generated files in %s are up to date\n", *outDir)
+
+			return
+		}
+	}
+
 	// Load packages
-	analyzer := analyze.NewAnalyzer()
+	analyzer := limits.newAnalyzer()
 
 	graph, err := analyzer.LoadPackages(packages...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
 		os.Exit(1)
 	}
 
+	expandPackageMappings(mappingDef, graph)
+
 	// Validate mapping against type graph
 	if result := mapping.Validate(mappingDef, graph); !result.IsValid() {
 		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
@@ -418,17 +753,43 @@ Options:
 	// Run resolution
 	config := plan.DefaultConfig()
 	config.StrictMode = *strict
+	config.Policy = resolvePolicy(mappingDef, *strict, *policyFlag)
+	config.OnIncompatiblePin = parsePinPolicy(*onIncompatiblePin)
 	resolver := plan.NewResolver(graph, mappingDef, config)
 
-	resolvedPlan, err := resolver.Resolve()
+	var resolvedPlan *plan.ResolvedMappingPlan
+
+	if store != nil {
+		resolvedPlan, err = resolveCached(store, fps, resolver, graph, mappingDef,
+			cacheOptions("resolve", limits, string(config.OnIncompatiblePin)), status)
+	} else {
+		resolvedPlan, err = resolver.Resolve()
+	}
+
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Restrict generation to the tagged mappings
+	tags := parseTags(*tagsFlag)
+	if err := resolvedPlan.FilterTags(tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -tags: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Leave out the known diagnostics
+	suppressions.apply(&resolvedPlan.Diagnostics)
+
 	// Print diagnostics
 	printDiagnostics(&resolvedPlan.Diagnostics)
 
+	// Fail on the mappings the policy rejects, reported above
+	if violations := resolvedPlan.PolicyViolations(); len(violations) > 0 {
+		fmt.Fprintf(os.Stderr, "\nError: %d mapping(s) violate the policy\n", len(violations))
+		os.Exit(1)
+	}
+
 	// Check for incomplete mappings (types that need transforms but don't have them)
 	incompleteMappings := resolvedPlan.FindIncompleteMappings()
 	if len(incompleteMappings) > 0 {
@@ -445,6 +806,7 @@ Options:
 		fmt.Fprintln(os.Stderr, "  2. Add a 'transform' function name for each")
 		fmt.Fprintln(os.Stderr, "  3. Implement the transform functions in your code")
 		fmt.Fprintln(os.Stderr, "\nOr run 'suggest' command to auto-generate updated YAML with placeholders.")
+		fmt.Fprintln(os.Stderr, "Or set on_incompatible_pin (or -on-incompatible-pin) to todo or fallback_auto.")
 		os.Exit(1)
 	}
 
@@ -461,7 +823,37 @@ Options:
 			os.Exit(1)
 		}
 
-		fmt.Printf("Suggested mapping written to %s\n", *writeSuggestions)
+		fmt.Fprintf(status, "Suggested mapping written to %s\n", *writeSuggestions)
+	}
+
+	// Restrict generation to the selected type pairs
+	if err := resolvedPlan.FilterTypePairs(only); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -only: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Make sure every nested caster called by generated code is generated as well
+	manifest := plan.BuildManifest(resolvedPlan)
+	if len(manifest.Missing) > 0 {
+		fmt.Fprintln(os.Stderr, "\nError: generated casters call nested casters that are not generated:")
+		fmt.Fprint(os.Stderr, manifest.FormatMissing())
+		fmt.Fprintln(os.Stderr, "\nAdd mappings for these type pairs, or include them in -only or -tags.")
+		os.Exit(1)
+	}
+
+	if *manifestFile != "" {
+		manifestData, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding manifest: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := os.WriteFile(*manifestFile, append(manifestData, '\n'), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing manifest file: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(status, "Caster manifest written to %s\n", *manifestFile)
 	}
 
 	// Generate code
@@ -471,218 +863,1940 @@ Options:
 		declaredTransforms[t.Name] = true
 	}
 
-	generator := gen.NewGenerator(gen.GeneratorConfig{
+	// Load the functions marked as hand-edited in the previous output
+	kept, err := gen.LoadKeptFuncs(*outDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading kept functions: %v\n", err)
+		os.Exit(1)
+	}
+
+	genConfig := gen.GeneratorConfig{
 		PackageName:          *pkgName,
 		OutputDir:            *outDir,
 		GenerateComments:     true,
 		IncludeUnmappedTODOs: true,
 		DeclaredTransforms:   declaredTransforms,
-	})
+		DeepCopy:             *deepCopy,
+		SingleFile:           *singleFile,
+		GenericRequires:      *genericRequires,
+		Style:                *style,
+		FuncTemplate:         *funcTemplate,
+		Overrides:            *overrides,
+		Kept:                 kept,
+		Benchmarks:           *benchmarks,
+		Tests:                *tests,
+		DebugCasters:         *debugCasters,
+	}
 
-	files, err := generator.Generate(resolvedPlan)
+	files, err := gen.NewGenerator(genConfig).Generate(resolvedPlan)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating code: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Write files
-	if err := gen.WriteFiles(files, *outDir); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing generated files: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Printf("Generated %d file(s) in %s\n", len(files), *outDir)
+	// Run the pipeline again from resolution and compare before writing anything
+	if *checkReproducible {
+		if err := checkGenReproducible(files, graph, mappingDef, config, tags, only, genConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: output is not reproducible: %v\n", err)
+			os.Exit(1)
+		}
 
-	for _, f := range files {
-		fmt.Printf("  - %s\n", f.Filename)
+		fmt.Fprintln(status, "Output is reproducible")
 	}
-}
 
-// runCheck implements the 'check' command.
-func runCheck(args []string) {
-	fs := flag.NewFlagSet("check", flag.ExitOnError)
-	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: caster-generator check [options]
+	// Show what writing would change instead
+	if *diff {
+		backend := &gen.PatchBackend{Dir: *outDir, W: os.Stdout}
+		if err := backend.Write(files); err != nil {
+			fmt.Fprintf(os.Stderr, "Error diffing generated files: %v\n", err)
+			os.Exit(1)
+		}
 
-Validate YAML against current code; fail on drift.
+		if len(backend.Changed) == 0 {
+			fmt.Fprintf(status, "Generated files in %s are up to date\n", *outDir)
 
-Options:
-`)
-		fs.PrintDefaults()
-	}
+			return
+		}
 
-	var packages StringSliceFlag
+		fmt.Fprintf(status, "%d generated file(s) differ from %s:\n", len(backend.Changed), *outDir)
 
-	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
-	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
-	strict := fs.Bool("strict", false, "Fail on any unresolved target fields")
+		for _, name := range backend.Changed {
+			fmt.Fprintf(status, "  - %s\n", name)
+		}
 
-	if err := fs.Parse(args); err != nil {
 		os.Exit(1)
 	}
 
-	if *mappingFile == "" {
-		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
-		fs.Usage()
+	// Write files
+	if err := writeGenOutput(files, *output, *outputFile, *outDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing generated files: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Load mapping file
-	mappingDef, err := mapping.LoadFile(*mappingFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
-		os.Exit(1)
+	dest := *outputFile
+	if dest == "-" {
+		dest = "stdout"
 	}
 
-	// Auto-detect packages from mapping if not specified
-	if len(packages) == 0 {
-		packages = extractPackagesFromMapping(mappingDef)
+	switch *output {
+	case outputZip:
+		fmt.Fprintf(status, "Archived %d generated file(s) to %s\n", len(files), dest)
+	case outputPatch:
+		fmt.Fprintf(status, "Wrote a patch of %d generated file(s) against %s to %s\n", len(files), *outDir, dest)
+	default:
+		fmt.Fprintf(status, "Generated %d file(s) in %s\n", len(files), *outDir)
 	}
 
-	if len(packages) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
-		fs.Usage()
-		os.Exit(1)
+	if genKey != "" {
+		saveGenCache(store, genKey, files, *outDir)
 	}
 
-	// Load packages
-	analyzer := analyze.NewAnalyzer()
+	if len(kept) > 0 {
+		fmt.Fprintf(status, "Kept %d hand-edited function(s) marked %s\n", len(kept), gen.KeepMarker)
+	}
 
-	graph, err := analyzer.LoadPackages(packages...)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
-		os.Exit(1)
+	for _, f := range files {
+		fmt.Fprintf(status, "  - %s\n", f.Filename)
 	}
+}
 
-	// Validate mapping against type graph
-	validationResult := mapping.Validate(mappingDef, graph)
-	if !validationResult.IsValid() {
-		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
+// Values of the gen -output flag.
+const (
+	outputDir   = "dir"
+	outputZip   = "zip"
+	outputPatch = "patch"
+)
 
-		for _, e := range validationResult.Errors {
-			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+// writeGenOutput writes files with the backend named by output. Archives and
+// patches go to outputFile, or stdout for "-". outputFile is replaced only
+// once the backend succeeds, so a failed run leaves no partial archive.
+func writeGenOutput(files []gen.GeneratedFile, output, outputFile, outDir string) error {
+	if output == outputDir {
+		return (&gen.DirBackend{Dir: outDir}).Write(files)
+	}
+
+	newBackend := func(w io.Writer) gen.OutputBackend {
+		if output == outputPatch {
+			return &gen.PatchBackend{Dir: outDir, W: w}
 		}
 
-		os.Exit(1)
+		return &gen.ZipBackend{W: w}
 	}
 
-	// Run resolution to check for issues
-	config := plan.DefaultConfig()
-	config.StrictMode = *strict
-	resolver := plan.NewResolver(graph, mappingDef, config)
+	if outputFile == "-" {
+		return newBackend(os.Stdout).Write(files)
+	}
 
-	resolvedPlan, err := resolver.Resolve()
+	f, err := os.CreateTemp(filepath.Dir(outputFile), "."+filepath.Base(outputFile)+".*")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	// Print diagnostics
-	printDiagnostics(&resolvedPlan.Diagnostics)
-
-	// Check for issues
-	hasIssues := false
+	err = newBackend(f).Write(files)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 
-	for _, tp := range resolvedPlan.TypePairs {
-		if len(tp.UnmappedTargets) > 0 {
-			hasIssues = true
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
 
-			fmt.Printf("\nUnmapped targets in %s -> %s:\n", tp.SourceType.ID, tp.TargetType.ID)
+	if err == nil {
+		err = os.Rename(f.Name(), outputFile)
+	}
 
-			for _, um := range tp.UnmappedTargets {
-				fmt.Printf("  - %s: %s\n", um.TargetPath, um.Reason)
-			}
-		}
+	if err != nil {
+		_ = os.Remove(f.Name())
 	}
 
-	if resolvedPlan.Diagnostics.HasErrors() {
-		hasIssues = true
+	return err
+}
+
+// genCacheKey returns the cache key of a gen run: it changes with any mapping,
+// the packages, the flags or the output directory.
+func genCacheKey(
+	mappingDef *mapping.MappingFile,
+	fps *analyze.Fingerprints,
+	limits *analysisFlags,
+	outDir string,
+	flags ...string,
+) string {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving output directory: %v\n", err)
+		os.Exit(1)
 	}
 
-	if hasIssues {
-		fmt.Fprintln(os.Stderr, "\nCheck failed: mapping has issues")
+	keys, err := cache.MappingKeys(mappingDef, fps, cacheOptions("gen", limits, flags...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing cache keys: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Check passed: mapping is valid")
+	return cache.Key(append(keys, absOut)...)
 }
 
-// extractPackage extracts the package path from a qualified type name.
-// Handles both short forms (e.g., "store.Order") and full import paths
-// (e.g., "caster-generator/store.Product").
-func extractPackage(typeName string) string {
-	// Find the last dot which separates package from type name
-	lastDot := strings.LastIndex(typeName, ".")
-	if lastDot == -1 {
-		return ""
+// resolveCached resolves the type mappings of mappingDef like
+// Resolver.Resolve, but only those whose cache key changed, or whose nested
+// pairs are resolved by a mapping that changed; the pairs of the others are
+// restored from the cache. The passes over the whole plan run on all pairs.
+func resolveCached(
+	store *cache.Store,
+	fps *analyze.Fingerprints,
+	resolver *plan.Resolver,
+	graph *analyze.TypeGraph,
+	mappingDef *mapping.MappingFile,
+	options string,
+	status io.Writer,
+) (*plan.ResolvedMappingPlan, error) {
+	keys, err := cache.MappingKeys(mappingDef, fps, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing cache keys: %v\n", err)
+		os.Exit(1)
 	}
 
-	return typeName[:lastDot]
-}
+	keysByPair := make(map[string]string, len(keys))
+	for i := range mappingDef.TypeMappings {
+		keysByPair[mappingDef.TypeMappings[i].Key()] = keys[i]
+	}
 
-// extractPackagesFromMapping extracts package paths from mapping type names.
-func extractPackagesFromMapping(mf *mapping.MappingFile) []string {
-	pkgSet := make(map[string]bool)
+	resolvedPlan, err := resolver.NewPlan()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, tm := range mf.TypeMappings {
-		if pkg := extractPackage(tm.Source); pkg != "" {
-			// Check if it's a full import path or relative
-			if strings.Contains(pkg, "/") {
-				pkgSet[pkg] = true
-			} else {
-				pkgSet["./"+pkg] = true
-			}
-		}
+	results := make([]*cache.PairResult, len(keys))
 
-		if pkg := extractPackage(tm.Target); pkg != "" {
-			// Check if it's a full import path or relative
-			if strings.Contains(pkg, "/") {
-				pkgSet[pkg] = true
-			} else {
-				pkgSet["./"+pkg] = true
-			}
+	for i, key := range keys {
+		var res cache.PairResult
+		if store.Load("resolve", key, &res) && res.Current(keysByPair) {
+			results[i] = &res
 		}
 	}
 
-	var packages []string
-	for pkg := range pkgSet {
-		packages = append(packages, pkg)
-	}
+	pairs := make([]*plan.ResolvedTypePair, len(keys))
+	fresh := make([]bool, len(keys))
 
-	return packages
-}
+	// Cached pairs are decoded once the others are resolved, which may add
+	// types to the graph. Those missing a type are resolved too.
+	for decoded := false; !decoded; {
+		for i := range keys {
+			if results[i] != nil {
+				continue
+			}
 
-// printDiagnostics prints diagnostic information to stderr.
-func printDiagnostics(diags *diagnostic.Diagnostics) {
-	if len(diags.Warnings) > 0 {
-		fmt.Fprintln(os.Stderr, "\nWarnings:")
+			var diags diagnostic.Diagnostics
 
-		for _, w := range diags.Warnings {
-			fmt.Fprintf(os.Stderr, "  [%s] %s\n", w.Code, w.Message)
+			pairs[i] = resolver.ResolveMapping(&mappingDef.TypeMappings[i], &diags)
+			results[i] = newPairResult(graph, mappingDef, i, pairs[i], diags, keysByPair)
+			fresh[i] = true
 
-			if w.TypePair != "" {
-				fmt.Fprintf(os.Stderr, "    type pair: %s\n", w.TypePair)
+			// Pairs that can't be encoded are left out of the cache and resolved by every run
+			if pairs[i] != nil && len(results[i].Pair) == 0 {
+				continue
 			}
 
-			if w.FieldPath != "" {
-				fmt.Fprintf(os.Stderr, "    field: %s\n", w.FieldPath)
+			if err := store.Save("resolve", keys[i], results[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
 		}
-	}
-
-	if len(diags.Errors) > 0 {
-		fmt.Fprintln(os.Stderr, "\nErrors:")
 
-		for _, e := range diags.Errors {
-			fmt.Fprintf(os.Stderr, "  [%s] %s\n", e.Code, e.Message)
+		decoded = true
 
-			if e.TypePair != "" {
-				fmt.Fprintf(os.Stderr, "    type pair: %s\n", e.TypePair)
+		for i, res := range results {
+			if fresh[i] || len(res.Pair) == 0 {
+				continue
 			}
 
-			if e.FieldPath != "" {
+			if pairs[i], err = plan.DecodeTypePair(res.Pair, graph); err != nil {
+				results[i] = nil
+				decoded = false
+			}
+		}
+	}
+
+	resolvedCount := 0
+
+	for i, res := range results {
+		if fresh[i] {
+			resolvedCount++
+		}
+
+		resolvedPlan.Diagnostics.MergeUnique(res.Diagnostics)
+
+		if pairs[i] != nil {
+			resolvedPlan.TypePairs = append(resolvedPlan.TypePairs, *pairs[i])
+		}
+	}
+
+	fmt.Fprintf(status, "Resolved %d of %d mapping(s), reused the others from the cache\n", resolvedCount, len(keys))
+
+	return resolver.Finish(resolvedPlan)
+}
+
+// newPairResult returns the cache entry of tp, the pair resolved from the
+// i-th type mapping of mappingDef (nil if it failed) with diags. The entry of
+// a pair that can't be encoded has no Pair.
+func newPairResult(
+	graph *analyze.TypeGraph,
+	mappingDef *mapping.MappingFile,
+	i int,
+	tp *plan.ResolvedTypePair,
+	diags diagnostic.Diagnostics,
+	keysByPair map[string]string,
+) *cache.PairResult {
+	res := &cache.PairResult{Diagnostics: diags}
+	if tp == nil {
+		return res
+	}
+
+	data, err := plan.EncodeTypePair(tp, graph)
+	if err != nil {
+		return res
+	}
+
+	others := slices.Delete(slices.Clone(mappingDef.TypeMappings), i, i+1)
+
+	res.Pair = data
+	res.Uses = usedMappings(graph, others, &plan.ResolvedMappingPlan{TypePairs: []plan.ResolvedTypePair{*tp}}, keysByPair)
+
+	return res
+}
+
+// saveGenCache records the files just written by gen under key. Failures
+// only cost the next run its shortcut, so they are reported as warnings.
+func saveGenCache(store *cache.Store, key string, files []gen.GeneratedFile, outDir string) {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, filepath.Join(absOut, f.Filename))
+	}
+
+	res, err := cache.HashFiles(absOut, paths)
+	if err == nil {
+		err = store.Save("gen", key, res)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// parsePinPolicy validates an -on-incompatible-pin value, exiting on unknown
+// policies.
+func parsePinPolicy(value string) mapping.PinPolicy {
+	policy := mapping.PinPolicy(value)
+	if !policy.IsValid() {
+		fmt.Fprintf(os.Stderr, "Error: -on-incompatible-pin must be %s, %s or %s, got %q\n",
+			mapping.PinError, mapping.PinTodo, mapping.PinFallbackAuto, value)
+		os.Exit(1)
+	}
+
+	return policy
+}
+
+// resolvePolicy returns the policy of mappingDef with every fail_on_* check
+// set for -strict, then the -policy overrides applied.
+func resolvePolicy(mappingDef *mapping.MappingFile, strict bool, overrides string) *mapping.Policy {
+	policy := mappingDef.Policy
+	if strict {
+		policy = policy.Or(mapping.StrictPolicy)
+	}
+
+	policy, err := policy.Override(overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	return &policy
+}
+
+// parseTags splits a comma-separated -tags (or -strip-affixes) value, dropping
+// empty entries.
+func parseTags(value string) []string {
+	var tags []string
+
+	for tag := range strings.SplitSeq(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// checkGenReproducible resolves and generates the mapping again and compares
+// the result with files.
+func checkGenReproducible(
+	files []gen.GeneratedFile,
+	graph *analyze.TypeGraph,
+	mappingDef *mapping.MappingFile,
+	config plan.ResolutionConfig,
+	tags, only []string,
+	genConfig gen.GeneratorConfig,
+) error {
+	resolvedPlan, err := plan.NewResolver(graph, mappingDef, config).Resolve()
+	if err != nil {
+		return fmt.Errorf("resolving again: %w", err)
+	}
+
+	if err := resolvedPlan.FilterTags(tags); err != nil {
+		return fmt.Errorf("resolving again: %w", err)
+	}
+
+	if err := resolvedPlan.FilterTypePairs(only); err != nil {
+		return fmt.Errorf("resolving again: %w", err)
+	}
+
+	again, err := gen.NewGenerator(genConfig).Generate(resolvedPlan)
+	if err != nil {
+		return fmt.Errorf("generating again: %w", err)
+	}
+
+	return gen.CompareFiles(files, again)
+}
+
+// runCheck implements the 'check' command.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator check [options]
+
+Validate YAML against current code; fail on drift.
+
+With -fix, the mapping file is first synced with the Go structs: paths of
+renamed fields are rewritten, entries of deleted fields dropped, and new
+target fields added to auto from their best candidate, or to ignore when
+they have none, with comments for a reviewer. The edits are printed.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	strict := fs.Bool("strict", false,
+		"Fail on any resolution error, and set every fail_on_* check of the mapping file's policy")
+	policyFlag := fs.String("policy", "",
+		"Override checks of the mapping file's policy, as comma-separated settings "+
+			"(e.g. fail_on_unmapped,fail_on_lossy=false,min_auto_confidence=0.8)")
+	tagsFlag := fs.String("tags", "", "Check only mappings with one of these comma-separated tags")
+	cacheDir := fs.String("cache", "", "Reuse the results of mappings unchanged since an earlier run cached in this directory")
+	onIncompatiblePin := fs.String("on-incompatible-pin", "",
+		"Override on_incompatible_pin for 121 mappings with incompatible types: error, todo or fallback_auto")
+	fix := fs.Bool("fix", false, "Rewrite the mapping file to follow fields added, removed or renamed in the Go structs")
+	suppressions := addSuppressionFlags(fs, true)
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *suppressions.update && *tagsFlag != "" {
+		fmt.Fprintln(os.Stderr, "Error: -update-suppressions can't be combined with -tags")
+		os.Exit(1)
+	}
+
+	suppressions.resolve(*mappingFile)
+
+	if *fix && *cacheDir != "" {
+		fmt.Fprintln(os.Stderr, "Error: -fix can't be combined with -cache")
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	explicitPackages := len(packages) > 0
+	if !explicitPackages {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	config := plan.DefaultConfig()
+	config.StrictMode = *strict
+	config.Policy = resolvePolicy(mappingDef, *strict, *policyFlag)
+	config.OnIncompatiblePin = parsePinPolicy(*onIncompatiblePin)
+
+	if *cacheDir != "" {
+		if *tagsFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: -cache can't be combined with -tags")
+			os.Exit(1)
+		}
+
+		checkCached(cache.NewStore(*cacheDir), mappingDef, packages, explicitPackages, limits, config, suppressions)
+
+		return
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	if *fix {
+		mappingDef = fixDrift(*mappingFile, graph, config)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Report issues of the tagged mappings only; the rest still takes part in resolution
+	tags := parseTags(*tagsFlag)
+	inScope := func(string) bool { return true }
+
+	if len(tags) > 0 {
+		tagged, err := mapping.TaggedTypePairs(mappingDef, tags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -tags: %v\n", err)
+			os.Exit(1)
+		}
+
+		inScope = func(typePair string) bool { return tagged[typePair] }
+	}
+
+	// Validate mapping against type graph
+	validationResult := mapping.Validate(mappingDef, graph)
+	validationResult.FilterTypePairs(inScope)
+
+	if !validationResult.IsValid() {
+		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
+
+		for _, e := range validationResult.Errors {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
+
+		os.Exit(1)
+	}
+
+	// Validate transforms that reference real Go functions against their declarations
+	signatureResult := mapping.ValidateTransformSignatures(mappingDef, graph)
+	signatureResult.FilterTypePairs(inScope)
+
+	// Run resolution to check for issues
+	resolver := plan.NewResolver(graph, mappingDef, config)
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := resolvedPlan.FilterTags(tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -tags: %v\n", err)
+		os.Exit(1)
+	}
+
+	resolvedPlan.Diagnostics.Merge(*signatureResult)
+
+	// Warn about deprecated field mappings, failing once their sunset date has
+	// passed, and about expired ignores
+	deprecations := mapping.CheckDeprecations(mappingDef, time.Now())
+	deprecations.FilterTypePairs(inScope)
+	resolvedPlan.Diagnostics.Merge(*deprecations)
+
+	// Leave out the known diagnostics
+	suppressed := suppressions.apply(&resolvedPlan.Diagnostics)
+
+	// Print diagnostics
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	// Check for issues
+	hasIssues := false
+
+	for _, tp := range resolvedPlan.TypePairs {
+		pair := fmt.Sprintf("%s->%s", tp.SourceType.ID, tp.TargetType.ID)
+		unmapped := slices.DeleteFunc(slices.Clone(tp.UnmappedTargets), func(um plan.UnmappedField) bool {
+			return suppressed(pair, um.TargetPath.String())
+		})
+
+		if len(unmapped) > 0 {
+			hasIssues = true
+
+			fmt.Printf("\nUnmapped targets in %s -> %s:\n", tp.SourceType.ID, tp.TargetType.ID)
+
+			for _, um := range unmapped {
+				fmt.Printf("  - %s: %s\n", um.TargetPath, um.Reason)
+			}
+		}
+	}
+
+	if resolvedPlan.Diagnostics.HasErrors() {
+		hasIssues = true
+	}
+
+	if hasIssues {
+		fmt.Fprintln(os.Stderr, "\nCheck failed: mapping has issues")
+		os.Exit(1)
+	}
+
+	fmt.Println("Check passed: mapping is valid")
+}
+
+// fixDrift implements 'check -fix': it rewrites mappingFile to follow the Go
+// structs of graph, prints the edits and returns the reloaded mapping. Stale
+// field paths are fixed first, then the target fields left unmapped get
+// decisions, unless the fixed mapping doesn't validate; check reports why.
+func fixDrift(mappingFile string, graph *analyze.TypeGraph, config plan.ResolutionConfig) *mapping.MappingFile {
+	data, err := os.ReadFile(mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, edits, err := mapping.FixStaleFields(data, graph)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fixing mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, edit := range edits {
+		fmt.Printf("Fixed %s\n", edit)
+	}
+
+	// Without includes, to tell the pairs this file maps from included ones
+	own, err := mapping.Parse(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(edits) > 0 {
+		if err := os.WriteFile(mappingFile, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Expanding package mappings changes the mapping, so check gets its own copy
+	mappingDef := loadFixedMapping(mappingFile)
+
+	fixed := loadFixedMapping(mappingFile)
+	if mapping.ExpandPackageMappings(fixed, graph).HasErrors() || !mapping.Validate(fixed, graph).IsValid() {
+		return mappingDef
+	}
+
+	resolver := plan.NewResolver(graph, fixed, config)
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		return mappingDef
+	}
+
+	// Pairs mapped by an included file or a package mapping are left to check
+	var items []plan.ReviewItem
+
+	for _, item := range resolver.ReviewItems(resolvedPlan) {
+		if own.FindTypeMapping(item.Source, item.Target, graph) != nil ||
+			fixed.FindTypeMapping(item.Source, item.Target, graph) == nil {
+			items = append(items, item)
+		}
+	}
+
+	if len(items) == 0 {
+		return mappingDef
+	}
+
+	decisions := plan.DriftDecisions(items)
+
+	patched, err := mapping.ApplyReviewDecisions(data, decisions, graph)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying decisions: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(mappingFile, patched, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, d := range decisions {
+		if d.Action == mapping.ReviewAuto {
+			fmt.Printf("Fixed %s -> %s: added %s to auto from %s\n", d.Source, d.Target, d.Field, d.From)
+		} else {
+			fmt.Printf("Fixed %s -> %s: added %s to ignore (no source candidate)\n", d.Source, d.Target, d.Field)
+		}
+	}
+
+	return loadFixedMapping(mappingFile)
+}
+
+// loadFixedMapping loads the mapping file check -fix rewrote.
+func loadFixedMapping(mappingFile string) *mapping.MappingFile {
+	mappingDef, err := mapping.LoadFile(mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	return mappingDef
+}
+
+// checkCached implements 'check -cache': only the mappings whose cache key
+// changed are analyzed and resolved, the results of the others are reused.
+// Without -pkg, only the packages of the changed mappings are loaded.
+func checkCached(
+	store *cache.Store,
+	mappingDef *mapping.MappingFile,
+	packages []string,
+	explicitPackages bool,
+	limits *analysisFlags,
+	config plan.ResolutionConfig,
+	suppressions *suppressionFlags,
+) {
+	fps, err := analyze.FingerprintPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fingerprinting packages: %v\n", err)
+		os.Exit(1)
+	}
+
+	options := cacheOptions("check", limits, fmt.Sprint(config.StrictMode), string(config.OnIncompatiblePin),
+		fmt.Sprintf("%+v", *config.Policy))
+
+	keys, err := cache.MappingKeys(mappingDef, fps, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing cache keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	keysByPair := make(map[string]string, len(keys))
+	for i := range mappingDef.TypeMappings {
+		keysByPair[mappingDef.TypeMappings[i].Key()] = keys[i]
+	}
+
+	results := make([]*cache.CheckResult, len(keys))
+	stale := &mapping.MappingFile{}
+	*stale = *mappingDef
+	stale.TypeMappings = nil
+
+	var staleIdx []int
+
+	for i, key := range keys {
+		var res cache.CheckResult
+		if store.Load("check", key, &res) && res.Current(keysByPair) {
+			results[i] = &res
+			continue
+		}
+
+		staleIdx = append(staleIdx, i)
+		stale.TypeMappings = append(stale.TypeMappings, mappingDef.TypeMappings[i])
+	}
+
+	if len(staleIdx) > 0 {
+		if !explicitPackages {
+			packages = mapping.Packages(stale)
+		}
+
+		graph, err := limits.newAnalyzer().LoadPackages(packages...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+			printLimitHint(err)
+			os.Exit(1)
+		}
+
+		for _, i := range staleIdx {
+			results[i] = checkMapping(graph, mappingDef, i, keysByPair, config)
+
+			if err := store.Save("check", keys[i], results[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	fmt.Printf("Resolved %d of %d mapping(s), reused the others from the cache\n", len(staleIdx), len(keys))
+
+	var diags diagnostic.Diagnostics
+	for _, res := range results {
+		diags.MergeUnique(res.Diagnostics)
+	}
+
+	// Deprecations and expired ignores depend on the date, so they are never cached
+	diags.Merge(*mapping.CheckDeprecations(mappingDef, time.Now()))
+
+	suppressed := suppressions.apply(&diags)
+
+	printDiagnostics(&diags)
+
+	hasIssues := diags.HasErrors()
+
+	for _, res := range results {
+		for _, um := range res.Unmapped {
+			pair := strings.ReplaceAll(um.Pair, " -> ", "->")
+			targets := slices.DeleteFunc(slices.Clone(um.Targets), func(target string) bool {
+				field, _, _ := strings.Cut(target, ":")
+				return suppressed(pair, field)
+			})
+
+			if len(targets) == 0 {
+				continue
+			}
+
+			hasIssues = true
+
+			fmt.Printf("\nUnmapped targets in %s:\n", um.Pair)
+
+			for _, target := range targets {
+				fmt.Printf("  - %s\n", target)
+			}
+		}
+	}
+
+	if hasIssues {
+		fmt.Fprintln(os.Stderr, "\nCheck failed: mapping has issues")
+		os.Exit(1)
+	}
+
+	fmt.Println("Check passed: mapping is valid")
+}
+
+// checkMapping checks the i-th type mapping of mappingDef alone, exiting on
+// validation errors like check does. The other mappings are only used to
+// resolve nested pairs; those used are recorded with their keys.
+func checkMapping(
+	graph *analyze.TypeGraph,
+	mappingDef *mapping.MappingFile,
+	i int,
+	keysByPair map[string]string,
+	config plan.ResolutionConfig,
+) *cache.CheckResult {
+	unit := &mapping.MappingFile{}
+	*unit = *mappingDef
+	unit.TypeMappings = []mapping.TypeMapping{mappingDef.TypeMappings[i]}
+
+	res := &cache.CheckResult{}
+
+	expansion := mapping.ExpandPackageMappings(unit, graph)
+	if expansion.HasErrors() {
+		printDiagnostics(expansion)
+		os.Exit(1)
+	}
+
+	res.Diagnostics.Merge(*expansion)
+
+	if result := mapping.Validate(unit, graph); !result.IsValid() {
+		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
+
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
+
+		os.Exit(1)
+	}
+
+	selected := make(map[string]bool)
+	for j := range unit.TypeMappings {
+		selected[unit.TypeMappings[j].Key()] = true
+	}
+
+	// Package mappings of other packages can't be expanded without loading them.
+	lookup := &mapping.MappingFile{}
+	*lookup = *unit
+
+	for j, tm := range mappingDef.TypeMappings {
+		if j != i && !tm.IsPackageMapping() && !selected[tm.Key()] {
+			lookup.TypeMappings = append(lookup.TypeMappings, tm)
+		}
+	}
+
+	resolvedPlan, err := plan.NewResolver(graph, lookup, config).ResolveSelected(func(tm *mapping.TypeMapping) bool {
+		return selected[tm.Key()]
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	res.Diagnostics.Merge(resolvedPlan.Diagnostics)
+	res.Diagnostics.Merge(*mapping.ValidateTransformSignatures(unit, graph))
+
+	for _, tp := range resolvedPlan.TypePairs {
+		if len(tp.UnmappedTargets) == 0 {
+			continue
+		}
+
+		um := cache.UnmappedTargets{Pair: fmt.Sprintf("%s -> %s", tp.SourceType.ID, tp.TargetType.ID)}
+		for _, t := range tp.UnmappedTargets {
+			um.Targets = append(um.Targets, fmt.Sprintf("%s: %s", t.TargetPath, t.Reason))
+		}
+
+		res.Unmapped = append(res.Unmapped, um)
+	}
+
+	res.Uses = usedMappings(graph, lookup.TypeMappings[len(unit.TypeMappings):], resolvedPlan, keysByPair)
+
+	return res
+}
+
+// usedMappings returns the keys of the mappings of others resolving nested
+// pairs of p, by mapping.TypeMapping.Key.
+func usedMappings(
+	graph *analyze.TypeGraph,
+	others []mapping.TypeMapping,
+	p *plan.ResolvedMappingPlan,
+	keysByPair map[string]string,
+) map[string]string {
+	nested := nestedPairs(p)
+	uses := make(map[string]string)
+
+	for _, tm := range others {
+		src, tgt := mapping.ResolveTypeID(tm.Source, graph), mapping.ResolveTypeID(tm.Target, graph)
+		if src != nil && tgt != nil && nested[fmt.Sprintf("%s->%s", src.ID, tgt.ID)] {
+			uses[tm.Key()] = keysByPair[tm.Key()]
+		}
+	}
+
+	return uses
+}
+
+// nestedPairs returns the keys ("Source->Target") of the pairs converted by
+// nested casters of the type pairs of p, at any depth.
+func nestedPairs(p *plan.ResolvedMappingPlan) map[string]bool {
+	pairs := make(map[string]bool)
+
+	var visit func(tp *plan.ResolvedTypePair)
+
+	visit = func(tp *plan.ResolvedTypePair) {
+		for _, nc := range tp.NestedPairs {
+			if nc.ResolvedPair == nil {
+				continue
+			}
+
+			key := fmt.Sprintf("%s->%s", nc.ResolvedPair.SourceType.ID, nc.ResolvedPair.TargetType.ID)
+			if !pairs[key] {
+				pairs[key] = true
+				visit(nc.ResolvedPair)
+			}
+		}
+	}
+
+	for i := range p.TypePairs {
+		visit(&p.TypePairs[i])
+	}
+
+	return pairs
+}
+
+// cacheOptions identifies a command, its flags affecting results and the
+// caster-generator binary in cache keys.
+func cacheOptions(command string, limits *analysisFlags, flags ...string) string {
+	return strings.Join(append([]string{
+		command,
+		fmt.Sprintf("%+v proto=%t", limits.limits(), *limits.proto),
+		executableHash(),
+	}, flags...), "\n")
+}
+
+// executableHash returns a hash of the running binary, so results cached by
+// another version are not reused. It is empty if the binary can't be read.
+func executableHash() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+
+	res, err := cache.HashFiles("", []string{path})
+	if err != nil {
+		return ""
+	}
+
+	return res.Files[path]
+}
+
+// runFreeze implements the 'freeze' command.
+func runFreeze(args []string) {
+	fs := flag.NewFlagSet("freeze", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator freeze [options]
+
+Resolve a YAML mapping and write it back fully explicit: every auto-matched
+field becomes a 121 or fields entry and every unmapped target is ignored.
+Generating from the result doesn't depend on fuzzy matching.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	outFile := fs.String("out", "", "Output YAML file (default: stdout)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Validate mapping against type graph
+	if result := mapping.Validate(mappingDef, graph); !result.IsValid() {
+		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
+
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
+
+		os.Exit(1)
+	}
+
+	// Resolve with the same settings as 'gen' so the lock reproduces its decisions
+	resolver := plan.NewResolver(graph, mappingDef, plan.DefaultConfig())
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	// A lock must be generatable as-is, so placeholder transforms are not allowed
+	incompleteMappings := resolvedPlan.FindIncompleteMappings()
+	if len(incompleteMappings) > 0 {
+		fmt.Fprintln(os.Stderr, "\nError: Found mappings with incompatible types that require custom transform functions:")
+
+		for _, im := range incompleteMappings {
+			fmt.Fprintf(os.Stderr, "  - %s -> %s (in %s)\n", im.SourcePath, im.TargetPath, im.TypePair)
+			fmt.Fprintf(os.Stderr, "    reason: %s\n", im.Explanation)
+		}
+
+		fmt.Fprintln(os.Stderr, "\nAdd transforms for these fields before freezing the mapping.")
+		os.Exit(1)
+	}
+
+	yamlData, err := plan.ExportLockedYAML(resolvedPlan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting locked mapping: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *outFile == "" {
+		fmt.Print(string(yamlData))
+
+		return
+	}
+
+	if err := os.WriteFile(*outFile, yamlData, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Locked mapping written to %s\n", *outFile)
+}
+
+// runEffectiveConfig implements the 'effective-config' command.
+func runEffectiveConfig(args []string) {
+	fs := flag.NewFlagSet("effective-config", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator effective-config [options]
+
+Resolve a YAML mapping and show, for every target field, the rule that decides
+it and where it came from (121, fields, ignore, auto, or auto-matching), along
+with the lower-priority rules it overrides.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	asJSON := fs.Bool("json", false, "Print the effective rules as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Resolve with the same settings as 'gen' so the rules match generated code
+	resolver := plan.NewResolver(graph, mappingDef, plan.DefaultConfig())
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	effective := plan.EffectiveConfig(resolvedPlan)
+
+	if !*asJSON {
+		fmt.Printf("# Effective mapping of %s\n\n", *mappingFile)
+		fmt.Print(plan.FormatEffectiveConfig(effective))
+
+		return
+	}
+
+	// Keep "->" readable in explanations
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(effective); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding effective config: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runExplain implements the 'explain' command.
+func runExplain(args []string) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator explain [options]
+
+Resolve a YAML mapping and show how one target field is decided in every type
+pair targeting its type: the rule producing it (121, fields, ignore, auto, or
+auto-matching), the lower-priority rules it overrides, its strategy, and the
+source fields auto-matching ranks for it with why each was picked or rejected.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	field := fs.String("field", "", "Target field to explain, e.g. warehouse.Order.Status (required)")
+	asJSON := fs.Bool("json", false, "Print the explanation as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" || *field == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping and -field flags are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Resolve with the same settings as 'gen' so the trail matches generated code
+	resolver := plan.NewResolver(graph, mappingDef, plan.DefaultConfig())
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	explanations, err := resolver.ExplainField(resolvedPlan, *field)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !*asJSON {
+		fmt.Print(plan.FormatFieldExplanations(explanations))
+
+		return
+	}
+
+	// Keep "->" readable in explanations
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(explanations); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding explanation: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runReview implements the 'review' command.
+func runReview(args []string) {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator review [options]
+
+Resolve a YAML mapping and walk through its unmapped target fields, showing
+why each was left unmapped and its top candidates with their scores. Answer
+with a candidate number to accept it, s to skip the field, i to ignore it or
+q to stop. Accepted fields are written to the 121 section, or to fields with
+a placeholder transform when their types need one; ignored fields to ignore.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	outFile := fs.String("out", "", "Output YAML file (default: update the mapping file in place)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *outFile == "" {
+		*outFile = *mappingFile
+	}
+
+	data, err := os.ReadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Without includes, to tell the pairs this file maps from included ones
+	own, err := mapping.Parse(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Resolve with the same settings as 'gen' so the fields to review match generated code
+	resolver := plan.NewResolver(graph, mappingDef, plan.DefaultConfig())
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Decisions on pairs mapped by an included file or a package mapping
+	// can't be written here: a mapping of the pair in this file would replace it.
+	var items []plan.ReviewItem
+
+	for _, item := range resolver.ReviewItems(resolvedPlan) {
+		if own.FindTypeMapping(item.Source, item.Target, graph) == nil &&
+			mappingDef.FindTypeMapping(item.Source, item.Target, graph) != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s of %s -> %s: mapped outside %s\n",
+				item.Field, item.Source, item.Target, *mappingFile)
+
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Nothing to review: every target field is mapped or ignored")
+
+		return
+	}
+
+	decisions, err := plan.Review(items, os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading answers: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(decisions) == 0 {
+		fmt.Println("No decisions taken, mapping left unchanged")
+
+		return
+	}
+
+	patched, err := mapping.ApplyReviewDecisions(data, decisions, graph)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying decisions: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*outFile, patched, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote %d decisions to %s\n", len(decisions), *outFile)
+}
+
+// runReport implements the 'report' command.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator report [options]
+
+Resolve a YAML mapping and report, for every type pair, its auto-matched and
+unmapped fields and the estimated cost of its caster and of each assignment
+(allocations, calls, loop nesting and a weighed score).
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	byCost := fs.Bool("by-cost", false, "Order type pairs and assignments from the most to the least costly")
+	deepCopy := fs.Bool("deep-copy", false, "Estimate costs as 'gen -deep-copy' generates")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Resolve with the same settings as 'gen' so costs match generated code
+	resolver := plan.NewResolver(graph, mappingDef, plan.DefaultConfig())
+
+	resolvedPlan, err := resolver.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	if *deepCopy {
+		resolvedPlan.CopyMode = resolvedPlan.CopyMode.Or(mapping.CopyDeep)
+	}
+
+	report := plan.GenerateReport(resolvedPlan)
+	if *byCost {
+		report.SortByCost()
+	}
+
+	fmt.Printf("# Report of %s\n", *mappingFile)
+	fmt.Print(plan.FormatReport(report))
+}
+
+// runGraph implements the 'graph' command.
+func runGraph(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator graph [options]
+
+Resolve a YAML mapping and print the dependency graph of its casters: the
+type pairs (top-level ones in bold), the nested casters they call and the
+transforms they use, with edges labeled by the target fields they assign.
+Render DOT output with Graphviz, Mermaid output in Markdown.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	format := fs.String("format", plan.GraphDOT, "Output format: dot or mermaid")
+	outFile := fs.String("out", "", "Output file (default: stdout)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	if *mappingFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *format != plan.GraphDOT && *format != plan.GraphMermaid {
+		fmt.Fprintf(os.Stderr, "Error: -format must be %s or %s, got %q\n", plan.GraphDOT, plan.GraphMermaid, *format)
+		os.Exit(1)
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	// Resolve with the same settings as 'gen' so the graph matches generated code
+	resolvedPlan, err := plan.NewResolver(graph, mappingDef, plan.DefaultConfig()).Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	out, err := plan.FormatGraph(plan.BuildGraph(resolvedPlan), *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *outFile == "" {
+		fmt.Print(out)
+
+		return
+	}
+
+	if err := os.WriteFile(*outFile, []byte(out), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote graph to %s\n", *outFile)
+}
+
+// runSchema implements the 'schema' command.
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator schema [options]
+
+Print the JSON Schema of the mapping file format. Editors with YAML language
+support complete keys and flag unknown ones once a mapping file refers to it:
+
+  # yaml-language-server: $schema=./mapping.schema.json
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	outFile := fs.String("out", "", "Output file (default: stdout)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	data, err := mapping.JSONSchema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *outFile == "" {
+		os.Stdout.Write(data)
+
+		return
+	}
+
+	if err := os.WriteFile(*outFile, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote schema to %s\n", *outFile)
+}
+
+// runRegenField implements the 'regen-field' command.
+func runRegenField(args []string) {
+	fs := flag.NewFlagSet("regen-field", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator regen-field [options]
+
+Regenerate the code assigning one target field of a caster and patch it into
+the existing generated file, leaving the rest of the file untouched. The
+-package, -style, -deep-copy and -generic-requires options of 'gen' are read
+from the generated files, and fail when given differently; use the same
+-func-template as 'gen'.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	var packages StringSliceFlag
+
+	fs.Var(&packages, "pkg", "Package path to analyze (can be specified multiple times)")
+	limits := addAnalysisFlags(fs)
+	mappingFile := fs.String("mapping", "", "Path to YAML mapping file (required)")
+	outDir := fs.String("out", "./generated", "Directory of the generated files")
+	pkgName := fs.String("package", "", "Package name of the generated code (default: read from the generated files)")
+	pair := fs.String("pair", "", "Type pair of the caster, as Source:Target (required)")
+	field := fs.String("field", "", "Target field path to regenerate, e.g. Total or Address.City (required)")
+	deepCopy := fs.Bool("deep-copy", false, "Deep-copy reference values when the mapping file sets no copy_mode")
+	genericRequires := fs.Bool("generic-requires", false,
+		"Type untyped requires passed only to transforms and nested casters with a type parameter")
+	style := fs.String("style", "",
+		"Emit casters as package-level functions or as methods of a Casters type (default: read from the generated files)")
+	funcTemplate := fs.String("func-template", "",
+		"Naming template of casters, e.g. Map{{.SourceName}}To{{.TargetName}} (overrides naming.func of the mapping)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	src, tgt, ok := strings.Cut(*pair, ":")
+	if *mappingFile == "" || !ok || *field == "" {
+		fmt.Fprintln(os.Stderr, "Error: -mapping, -pair Source:Target and -field flags are required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// The generated files tell how they were generated
+	header, err := gen.ReadOptions(*outDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (run 'gen' first)\n", err)
+		os.Exit(1)
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	for _, opt := range []struct{ name, flag, header string }{
+		{"package", *pkgName, header.PackageName},
+		{"style", *style, header.Style},
+		{"deep-copy", strconv.FormatBool(*deepCopy), strconv.FormatBool(header.DeepCopy)},
+		{"generic-requires", strconv.FormatBool(*genericRequires), strconv.FormatBool(header.GenericRequires)},
+	} {
+		if given[opt.name] && opt.flag != opt.header {
+			fmt.Fprintf(os.Stderr, "Error: -%s=%s given, but the files in %s were generated with -%s=%s\n",
+				opt.name, opt.flag, *outDir, opt.name, opt.header)
+			os.Exit(1)
+		}
+	}
+
+	// Load mapping file
+	mappingDef, err := mapping.LoadFile(*mappingFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading mapping file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Auto-detect packages from mapping if not specified
+	if len(packages) == 0 {
+		packages = mapping.Packages(mappingDef)
+	}
+
+	if len(packages) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: at least one -pkg flag is required, or mapping must use qualified type names")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Load packages
+	analyzer := limits.newAnalyzer()
+
+	graph, err := analyzer.LoadPackages(packages...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading packages: %v\n", err)
+		printLimitHint(err)
+		os.Exit(1)
+	}
+
+	expandPackageMappings(mappingDef, graph)
+
+	if result := mapping.Validate(mappingDef, graph); !result.IsValid() {
+		fmt.Fprintln(os.Stderr, "Mapping validation errors:")
+
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
+
+		os.Exit(1)
+	}
+
+	srcType, tgtType := mapping.ResolveTypeID(src, graph), mapping.ResolveTypeID(tgt, graph)
+	if srcType == nil || tgtType == nil {
+		fmt.Fprintf(os.Stderr, "Error: -pair: type pair %q: type not found\n", *pair)
+		os.Exit(1)
+	}
+
+	// Resolve the selected pair only; other mappings still serve its nested casters
+	resolvedPlan, err := plan.NewResolver(graph, mappingDef, plan.DefaultConfig()).
+		ResolveSelected(func(tm *mapping.TypeMapping) bool {
+			return mapping.ResolveTypeID(tm.Source, graph) == srcType && mapping.ResolveTypeID(tm.Target, graph) == tgtType
+		})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	printDiagnostics(&resolvedPlan.Diagnostics)
+
+	// Generate the selected caster only
+	if err := resolvedPlan.FilterTypePairs([]string{src + "->" + tgt}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -pair: %v\n", err)
+		os.Exit(1)
+	}
+
+	declaredTransforms := make(map[string]bool)
+	for _, t := range mappingDef.Transforms {
+		declaredTransforms[t.Name] = true
+	}
+
+	generator := gen.NewGenerator(gen.GeneratorConfig{
+		PackageName:          header.PackageName,
+		OutputDir:            *outDir,
+		GenerateComments:     true,
+		IncludeUnmappedTODOs: true,
+		DeclaredTransforms:   declaredTransforms,
+		DeepCopy:             header.DeepCopy,
+		GenericRequires:      header.GenericRequires,
+		Style:                header.Style,
+		FuncTemplate:         *funcTemplate,
+	})
+
+	files, err := generator.Generate(resolvedPlan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating code: %v\n", err)
+		os.Exit(1)
+	}
+
+	name := generator.CasterName(&resolvedPlan.TypePairs[0])
+
+	generated, err := gen.CasterSource(files, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Patch the file of the previous generation in place
+	path, err := gen.FindCaster(*outDir, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (run 'gen' first)\n", err)
+		os.Exit(1)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	patched, err := gen.PatchField(path, content, generated, name, *field)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error patching %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	patchedFile := gen.GeneratedFile{Filename: filepath.Base(path), Content: patched}
+	if err := (&gen.DirBackend{Dir: filepath.Dir(path)}).Write([]gen.GeneratedFile{patchedFile}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Regenerated %s in %s of %s\n", *field, name, path)
+}
+
+// runStats implements the 'stats' command.
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: caster-generator stats [options]
+
+Scan mapping files and generated casters under a directory and report
+aggregate statistics as JSON. Reads the local filesystem only.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+
+	root := fs.String("root", ".", "Directory to scan")
+	outFile := fs.String("out", "", "Output JSON file (default: stdout)")
+
+	if err := fs.Parse(args); err != nil {
+		os.Exit(1)
+	}
+
+	report, err := stats.Collect(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error collecting statistics: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding statistics: %v\n", err)
+		os.Exit(1)
+	}
+
+	data = append(data, '\n')
+
+	if *outFile == "" {
+		fmt.Print(string(data))
+
+		return
+	}
+
+	if err := os.WriteFile(*outFile, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing statistics file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Statistics written to %s\n", *outFile)
+}
+
+// expandPackageMappings replaces the package mappings of mappingDef with the
+// struct pairs of their packages, exiting on malformed ones.
+func expandPackageMappings(mappingDef *mapping.MappingFile, graph *analyze.TypeGraph) {
+	diags := mapping.ExpandPackageMappings(mappingDef, graph)
+	printDiagnostics(diags)
+
+	if diags.HasErrors() {
+		os.Exit(1)
+	}
+}
+
+// printDiagnostics prints diagnostic information to stderr.
+func printDiagnostics(diags *diagnostic.Diagnostics) {
+	if len(diags.Warnings) > 0 {
+		fmt.Fprintln(os.Stderr, "\nWarnings:")
+
+		for _, w := range diags.Warnings {
+			fmt.Fprintf(os.Stderr, "  [%s] %s\n", w.Code, w.Message)
+
+			if w.TypePair != "" {
+				fmt.Fprintf(os.Stderr, "    type pair: %s\n", w.TypePair)
+			}
+
+			if w.FieldPath != "" {
+				fmt.Fprintf(os.Stderr, "    field: %s\n", w.FieldPath)
+			}
+
+			if w.Pos.IsValid() {
+				fmt.Fprintf(os.Stderr, "    at: %s\n", w.Pos)
+			}
+
+			fmt.Fprintf(os.Stderr, "    id: %s\n", w.Fingerprint())
+		}
+	}
+
+	if len(diags.Errors) > 0 {
+		fmt.Fprintln(os.Stderr, "\nErrors:")
+
+		for _, e := range diags.Errors {
+			fmt.Fprintf(os.Stderr, "  [%s] %s\n", e.Code, e.Message)
+
+			if e.TypePair != "" {
+				fmt.Fprintf(os.Stderr, "    type pair: %s\n", e.TypePair)
+			}
+
+			if e.FieldPath != "" {
 				fmt.Fprintf(os.Stderr, "    field: %s\n", e.FieldPath)
 			}
+
+			if e.Pos.IsValid() {
+				fmt.Fprintf(os.Stderr, "    at: %s\n", e.Pos)
+			}
+
+			fmt.Fprintf(os.Stderr, "    id: %s\n", e.Fingerprint())
 		}
 	}
 }
@@ -216,14 +216,14 @@ yaml_add_after() {
   sed "/${pattern}/a\\${newline}" "$input" > "$output"
 }
 
-# Add hint: dive to a field mapping by target name
+# Add a dive hint to a field mapping by target name
 # Usage: yaml_add_dive_hint input.yaml output.yaml 'FieldName'
 yaml_add_dive_hint() {
   local input="$1"
   local output="$2"
   local field="$3"
-  # Add 'hint: dive' after 'target: FieldName' line
-  sed "/target: ${field}$/a\\        hint: dive" "$input" > "$output"
+  # Rewrite 'target: FieldName' as 'target: {FieldName: dive}' in block style
+  sed "s/^\\( *\\)target: ${field}$/\\1target:\\n\\1  ${field}: dive/" "$input" > "$output"
 }
 
 # Append content to YAML file
@@ -12,7 +12,7 @@ source "${here}/../_scripts/common.sh"
 # Types: arrays.APIBox -> arrays.DomainBox
 #        arrays.APIPoint -> arrays.DomainPoint
 #
-# Goal: Demonstrate array element mapping that needs `hint: dive`
+# Goal: Demonstrate array element mapping that needs a `dive` hint
 # ============================================================================
 
 scenario_init "Arrays" "Demonstrate dive hints for fixed-size array element mapping"
@@ -41,7 +41,7 @@ prompt_continue
 # ============================================================================
 # Stage 2: Patch YAML - add dive hint
 # ============================================================================
-stage_start "Patch YAML" "Add hint: dive for array field to enable element conversion"
+stage_start "Patch YAML" "Add a dive hint to the array field to enable element conversion"
 
 # Create a proper mapping with dive hint
 cat > "${stages_dir}/stage2.yaml" << 'EOF'
@@ -51,9 +51,10 @@ mappings:
   - source: caster-generator/examples/arrays.APIBox
     target: caster-generator/examples/arrays.DomainBox
     fields:
-      - source: Corners
-        target: Corners
-        hint: dive  # Enable element-wise conversion for the array
+      - source:
+          Corners: dive  # Enable element-wise conversion for the array
+        target:
+          Corners: dive
 
   - source: caster-generator/examples/arrays.APIPoint
     target: caster-generator/examples/arrays.DomainPoint
@@ -63,10 +64,10 @@ mappings:
 EOF
 
 show_yaml_with_comments "${stages_dir}/stage2.yaml" \
-  "Added hint: dive to Corners field + explicit APIPoint -> DomainPoint mapping"
+  "Added dive hints to Corners field + explicit APIPoint -> DomainPoint mapping"
 
 info "Key changes:"
-echo "  • Added 'hint: dive' to Corners field mapping"
+echo "  • Added dive hints to the Corners field mapping"
 echo "  • Added explicit mapping for APIPoint -> DomainPoint"
 echo ""
 
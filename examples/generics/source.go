@@ -0,0 +1,23 @@
+package generics
+
+// Page is a generic page of results returned by the API.
+type Page[T any] struct {
+	Items []T
+	Next  *T
+	Total int
+}
+
+type APIOrder struct {
+	ID    string
+	Cents int
+}
+
+type APIItem struct {
+	SKU string
+	Qty int
+}
+
+type APICatalog struct {
+	Orders Page[APIOrder]
+	Items  Page[APIItem]
+}
@@ -0,0 +1,23 @@
+package generics
+
+// Listing is the domain counterpart of Page.
+type Listing[T any] struct {
+	Items []T
+	Next  *T
+	Total int64
+}
+
+type Order struct {
+	ID    string
+	Cents int64
+}
+
+type Item struct {
+	SKU string
+	Qty int64
+}
+
+type Catalog struct {
+	Orders Listing[Order]
+	Items  Listing[Item]
+}
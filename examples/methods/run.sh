@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+here="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "${here}/../_scripts/common.sh"
+
+stages_dir="${here}/stages"
+out_dir="${here}/generated"
+clean_dir "$stages_dir"
+clean_dir "$out_dir"
+
+stage_start "Generate Code" "Generate casters as methods of an injectable Casters type"
+
+run_gen "${here}/map.yaml" "$out_dir" \
+  -pkg ./examples/methods \
+  -package generated \
+  -style methods
+
+test_compile ./examples/methods
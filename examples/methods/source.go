@@ -0,0 +1,12 @@
+package methods
+
+type APIOrder struct {
+	ID       string
+	PriceUSD float64
+	Lines    []APILine
+}
+
+type APILine struct {
+	SKU string
+	Qty int
+}
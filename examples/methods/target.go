@@ -0,0 +1,12 @@
+package methods
+
+type Order struct {
+	ID         string
+	PriceCents int64
+	Lines      []Line
+}
+
+type Line struct {
+	SKU string
+	Qty int
+}
@@ -119,12 +119,14 @@ mappings:
         target: CustomerEmail
       - source: BuyerName
         target: CustomerName
-      - source: Items
-        target: LineItems
-        hint: dive
-      - source: ShipTo
-        target: ShippingAddress
-        hint: dive
+      - source:
+          Items: dive
+        target:
+          LineItems: dive
+      - source:
+          ShipTo: dive
+        target:
+          ShippingAddress: dive
       - source: OrderDate
         target: PlacedAt
         transform: multi.ParseISODate
@@ -176,12 +178,14 @@ mappings:
         transform: multi.FormatOrderNumber
       - source: CustomerName
         target: Recipient
-      - source: LineItems
-        target: Items
-        hint: dive
-      - source: ShippingAddress
-        target: Address
-        hint: dive
+      - source:
+          LineItems: dive
+        target:
+          Items: dive
+      - source:
+          ShippingAddress: dive
+        target:
+          Address: dive
     ignore:
       - Priority  # Set by business logic, not mapping
 
@@ -59,9 +59,10 @@ mappings:
     fields:
       - source: ID
         target: ID
-      - source: Items
-        target: Lines
-        hint: dive  # Enable element-wise conversion for []*APIItem -> []*DomainLine
+      - source:
+          Items: dive  # Enable element-wise conversion for []*APIItem -> []*DomainLine
+        target:
+          Lines: dive
 
   - source: caster-generator/examples/nested-mixed-structs.APIItem
     target: caster-generator/examples/nested-mixed-structs.DomainLine
@@ -0,0 +1,14 @@
+// Package gentest is a simulation harness for the resolver and generator.
+//
+// It declares a source and a target struct with one field F each, typed by
+// Go type expressions, type-checks them in memory, resolves the F -> F mapping
+// and type-checks the generated casters. Matrix crosses field kinds (basic,
+// struct, slice, array, map, pointer, external and generated targets) so every
+// combination is exercised; users can add their own declarations and kinds to
+// try exotic types before adopting the tool:
+//
+//	h := gentest.New()
+//	h.Decls += "type Money struct{ Cents int64 }\n"
+//	kinds := append(gentest.Kinds, gentest.Kind{Name: "money", Type: "Money"})
+//	gentest.Run(t, h, gentest.Matrix(kinds))
+package gentest
@@ -0,0 +1,317 @@
+package gentest
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/gen"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// Package paths of the simulated packages.
+const (
+	SourcePkg = "gentest/src"
+	TargetPkg = "gentest/dst"
+	OutputPkg = "gentest/out"
+)
+
+// DefaultDecls declares the nested struct used by the built-in Kinds.
+const DefaultDecls = `type Inner struct {
+	ID   int
+	Name string
+}
+`
+
+// transformName is the transform used for cases the resolver can't convert on its own.
+const transformName = "Convert"
+
+// ErrNoStrategy is returned when the resolver leaves the target field unmapped.
+var ErrNoStrategy = errors.New("no conversion strategy")
+
+// Case is one source -> target field pairing.
+type Case struct {
+	// Name identifies the case in test output.
+	Name string
+	// Source is the Go type of the source field (e.g., "[]*Inner").
+	Source string
+	// Target is the Go type of the target field. It is unused when
+	// GenerateTarget is set.
+	Target string
+	// GenerateTarget maps to a target struct generated from the source.
+	GenerateTarget bool
+}
+
+// Result describes how a case was resolved and generated.
+type Result struct {
+	// Strategy is the conversion strategy chosen for F (e.g., "slice_map").
+	Strategy string
+	// Explanation is the resolver's reason for the strategy.
+	Explanation string
+	// NeedsTransform is set when the resolver asked for a transform, so the
+	// case was generated with a transform stub instead.
+	NeedsTransform bool
+	// Files are the generated files.
+	Files []gen.GeneratedFile
+}
+
+// Harness type-checks simulated packages and the code generated for them.
+type Harness struct {
+	// Decls are Go declarations added to both the source and target packages.
+	Decls string
+	// Imports are the packages Decls and case types refer to.
+	Imports []string
+
+	fset *token.FileSet
+	std  types.Importer
+}
+
+// New returns a harness declaring Inner and importing time.
+func New() *Harness {
+	return &Harness{
+		Decls:   DefaultDecls,
+		Imports: []string{"time"},
+	}
+}
+
+// Check resolves and generates c, returning an error if the resolver picks no
+// strategy or the generated code does not type-check.
+func (h *Harness) Check(c Case) (*Result, error) {
+	if h.fset == nil {
+		h.fset = token.NewFileSet()
+		h.std = importer.ForCompiler(h.fset, "source", nil)
+	}
+
+	src, err := h.checkPackage(SourcePkg, h.declFile("src", "Src", c.Source), nil)
+	if err != nil {
+		return nil, fmt.Errorf("source package: %w", err)
+	}
+
+	target := c.Target
+	if c.GenerateTarget {
+		target = ""
+	}
+
+	dst, err := h.checkPackage(TargetPkg, h.declFile("dst", "Dst", target), nil)
+	if err != nil {
+		return nil, fmt.Errorf("target package: %w", err)
+	}
+
+	graph := analyze.NewAnalyzer().AddPackages(src, dst)
+
+	resolved, res, err := h.resolveAll(graph, c)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := gen.DefaultGeneratorConfig()
+	cfg.PackageName = "out"
+	cfg.OutputDir = ""
+
+	res.Files, err = gen.NewGenerator(cfg).Generate(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("generate: %w", err)
+	}
+
+	imported := map[string]*types.Package{SourcePkg: src, TargetPkg: dst}
+	if _, err := h.checkPackage(OutputPkg, res.Files, imported); err != nil {
+		return res, fmt.Errorf("generated code (%s): %w", res.Strategy, err)
+	}
+
+	return res, nil
+}
+
+// resolveAll resolves c the way a user would iterate on gen's errors: it
+// supplies a transform for F when the resolver asks for one and maps nested
+// pairs that generated code calls but no mapping provides.
+func (h *Harness) resolveAll(graph *analyze.TypeGraph, c Case) (*plan.ResolvedMappingPlan, *Result, error) {
+	var (
+		transform string
+		nested    []string
+	)
+
+	for {
+		resolved, res, err := h.resolve(graph, mappingYAML(c, transform, nested))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if incomplete := resolved.FindIncompleteMappings(); len(incomplete) > 0 {
+			if transform != "" {
+				return nil, nil, fmt.Errorf("%w: %s -> %s in %s needs a transform",
+					ErrNoStrategy, incomplete[0].SourcePath, incomplete[0].TargetPath, incomplete[0].TypePair)
+			}
+
+			transform = transformName
+
+			continue
+		}
+
+		missing := plan.BuildManifest(resolved).Missing
+		if len(missing) == 0 {
+			res.NeedsTransform = transform != ""
+			return resolved, res, nil
+		}
+
+		for _, m := range missing {
+			if slices.Contains(nested, m.Pair) {
+				return nil, nil, fmt.Errorf("nested caster %s is not generated", m.Pair)
+			}
+
+			nested = append(nested, m.Pair)
+		}
+	}
+}
+
+// resolve validates and resolves mapping file yaml, returning the strategy
+// chosen for the F -> F mapping.
+func (h *Harness) resolve(graph *analyze.TypeGraph, yaml string) (*plan.ResolvedMappingPlan, *Result, error) {
+	mf, err := mapping.Parse([]byte(yaml))
+	if err != nil {
+		return nil, nil, fmt.Errorf("mapping: %w", err)
+	}
+
+	if diags := mapping.Validate(mf, graph); diags.HasErrors() {
+		return nil, nil, fmt.Errorf("mapping: %w", diags.Error())
+	}
+
+	resolved, err := plan.NewResolver(graph, mf, plan.DefaultConfig()).Resolve()
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve: %w", err)
+	}
+
+	for _, pair := range resolved.TypePairs {
+		if pair.SourceType.ID.PkgPath != SourcePkg || pair.SourceType.ID.Name != "Src" {
+			continue
+		}
+
+		for _, m := range pair.Mappings {
+			if len(m.TargetPaths) == 1 && m.TargetPaths[0].String() == "F" {
+				return resolved, &Result{Strategy: m.Strategy.String(), Explanation: m.Explanation}, nil
+			}
+		}
+	}
+
+	return nil, nil, ErrNoStrategy
+}
+
+// declFile returns a file of package name declaring the harness declarations
+// and a struct typeName with a single field F of type field. An empty field
+// declares no struct.
+func (h *Harness) declFile(name, typeName, field string) []gen.GeneratedFile {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "package %s\n\n", name)
+
+	for _, imp := range h.Imports {
+		fmt.Fprintf(&sb, "import %q\n", imp)
+	}
+
+	sb.WriteString("\n" + h.Decls + "\n")
+
+	if field != "" {
+		fmt.Fprintf(&sb, "type %s struct {\n\tF %s\n}\n", typeName, field)
+	}
+
+	return []gen.GeneratedFile{{Filename: name + ".go", Content: []byte(sb.String())}}
+}
+
+// checkPackage type-checks files as the package at path. Packages in imported
+// take precedence over the standard library. Unused imports of the harness
+// declarations are tolerated in the simulated packages only.
+func (h *Harness) checkPackage(
+	path string,
+	files []gen.GeneratedFile,
+	imported map[string]*types.Package,
+) (*types.Package, error) {
+	var parsed []*ast.File
+
+	for _, f := range files {
+		af, err := parser.ParseFile(h.fset, f.Filename, f.Content, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w\n%s", err, f.Content)
+		}
+
+		parsed = append(parsed, af)
+	}
+
+	var errs []error
+
+	conf := types.Config{
+		Importer: importerFunc(func(p string) (*types.Package, error) {
+			if pkg, ok := imported[p]; ok {
+				return pkg, nil
+			}
+
+			return h.std.Import(p)
+		}),
+		Error: func(err error) {
+			var terr types.Error
+			if imported == nil && errors.As(err, &terr) && terr.Soft && strings.Contains(terr.Msg, "not used") {
+				return
+			}
+
+			errs = append(errs, err)
+		},
+	}
+
+	pkg, _ := conf.Check(path, h.fset, parsed, nil)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w\n%s", errors.Join(errs...), dump(files))
+	}
+
+	return pkg, nil
+}
+
+// dump concatenates files for error messages.
+func dump(files []gen.GeneratedFile) string {
+	var sb strings.Builder
+
+	for _, f := range files {
+		fmt.Fprintf(&sb, "// %s\n%s\n", f.Filename, f.Content)
+	}
+
+	return sb.String()
+}
+
+// mappingYAML returns the mapping file of c, using transform for F if set and
+// mapping the nested "Source->Target" pairs.
+func mappingYAML(c Case, transform string, nested []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("version: \"1\"\nmappings:\n")
+	fmt.Fprintf(&sb, "  - source: %s.Src\n    target: %s.Dst\n", SourcePkg, TargetPkg)
+
+	if c.GenerateTarget {
+		sb.WriteString("    generate_target: true\n")
+	}
+
+	sb.WriteString("    fields:\n      - source: F\n        target: F\n")
+
+	if transform != "" {
+		fmt.Fprintf(&sb, "        transform: %s\n", transform)
+	}
+
+	for _, pair := range nested {
+		source, target, _ := strings.Cut(pair, "->")
+		fmt.Fprintf(&sb, "  - source: %s\n    target: %s\n", source, target)
+	}
+
+	return sb.String()
+}
+
+// importerFunc adapts a function to types.Importer.
+type importerFunc func(path string) (*types.Package, error)
+
+// Import implements types.Importer.
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
@@ -0,0 +1,69 @@
+package gentest
+
+import (
+	"testing"
+)
+
+// Kind is a field type shape of the matrix, written as a Go type expression
+// over the harness declarations.
+type Kind struct {
+	Name string
+	Type string
+}
+
+// Kinds are the built-in field shapes, one per analyzed type kind.
+var Kinds = []Kind{
+	{Name: "basic", Type: "int"},
+	{Name: "struct", Type: "Inner"},
+	{Name: "slice", Type: "[]Inner"},
+	{Name: "array", Type: "[2]Inner"},
+	{Name: "map", Type: "map[string]Inner"},
+	{Name: "pointer", Type: "*Inner"},
+	{Name: "external", Type: "time.Time"},
+}
+
+// Matrix returns a case for every source -> target pair of kinds, plus one
+// mapping each kind to a generated target.
+func Matrix(kinds []Kind) []Case {
+	cases := make([]Case, 0, len(kinds)*(len(kinds)+1))
+
+	for _, src := range kinds {
+		for _, dst := range kinds {
+			cases = append(cases, Case{
+				Name:   src.Name + "_to_" + dst.Name,
+				Source: src.Type,
+				Target: dst.Type,
+			})
+		}
+
+		cases = append(cases, Case{
+			Name:           src.Name + "_to_generated",
+			Source:         src.Type,
+			GenerateTarget: true,
+		})
+	}
+
+	return cases
+}
+
+// Run checks every case as a subtest of t, logging the chosen strategy.
+func Run(t *testing.T, h *Harness, cases []Case) {
+	t.Helper()
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			target := c.Target
+			if c.GenerateTarget {
+				target = "generated"
+			}
+
+			res, err := h.Check(c)
+			if err != nil {
+				t.Fatalf("%s -> %s: %v", c.Source, target, err)
+			}
+
+			t.Logf("%s -> %s: %s (%s), needs transform: %v",
+				c.Source, target, res.Strategy, res.Explanation, res.NeedsTransform)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package gentest_test
+
+import (
+	"testing"
+
+	"caster-generator/gentest"
+)
+
+func TestMatrix(t *testing.T) {
+	gentest.Run(t, gentest.New(), gentest.Matrix(gentest.Kinds))
+}
+
+func TestMatrix_CustomKinds(t *testing.T) {
+	h := gentest.New()
+	h.Decls += "type Money struct{ Cents int64 }\n"
+
+	kinds := []gentest.Kind{
+		{Name: "money", Type: "Money"},
+		{Name: "pointer_slice", Type: "[]*Inner"},
+		{Name: "pointer_map", Type: "map[string]*Inner"},
+	}
+
+	gentest.Run(t, h, gentest.Matrix(kinds))
+}
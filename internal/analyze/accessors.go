@@ -0,0 +1,172 @@
+package analyze
+
+import (
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Prefixes of the accessor methods exposing properties (see MethodFields).
+const (
+	getterPrefix = "Get"
+	setterPrefix = "Set"
+)
+
+// nonPropertyMethods are zero-arg methods returning a value that describe the
+// value as a whole rather than expose one of its properties.
+var nonPropertyMethods = map[string]bool{
+	"String":   true,
+	"GoString": true,
+	"Error":    true,
+}
+
+// analyzeMethods records the exported methods of *named into info.
+func (a *Analyzer) analyzeMethods(named *types.Named, info *TypeInfo) {
+	values := types.NewMethodSet(named)
+	methods := types.NewMethodSet(types.NewPointer(named))
+
+	for i := range methods.Len() {
+		fn, ok := methods.At(i).Obj().(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+
+		sig, ok := fn.Type().(*types.Signature)
+		if !ok {
+			continue
+		}
+
+		method := MethodInfo{
+			Name:            fn.Name(),
+			Variadic:        sig.Variadic(),
+			PointerReceiver: values.Lookup(fn.Pkg(), fn.Name()) == nil,
+		}
+
+		for v := range sig.Params().Variables() {
+			method.Params = append(method.Params, a.analyzeType(v.Type()))
+		}
+
+		for v := range sig.Results().Variables() {
+			method.Results = append(method.Results, a.analyzeType(v.Type()))
+		}
+
+		info.Methods = append(info.Methods, method)
+	}
+}
+
+// MethodFields returns the properties a struct exposes through accessor
+// methods only, as fields without a struct index (Index -1):
+//   - a zero-arg method returning one value, named after the property (Name())
+//     or prefixed with Get (GetName()), becomes the Getter of the property;
+//   - a method taking one value and returning nothing, prefixed with Set
+//     (SetName(v)), becomes its Setter.
+//
+// The type of a property is the getter's result, or the setter's parameter for
+// write-only properties; a setter of another type than the getter is ignored.
+// Names of accessible fields are skipped, so fields always win over methods.
+func (t *TypeInfo) MethodFields() []FieldInfo {
+	if t == nil || t.Kind != TypeKindStruct || len(t.Methods) == 0 {
+		return nil
+	}
+
+	var fields []FieldInfo
+
+	index := make(map[string]int)
+
+	property := func(name string) *FieldInfo {
+		if i, ok := index[name]; ok {
+			return &fields[i]
+		}
+
+		index[name] = len(fields)
+		fields = append(fields, FieldInfo{Name: name, Exported: true, Index: -1})
+
+		return &fields[len(fields)-1]
+	}
+
+	// Plain getters come first so they take precedence over Get-prefixed ones.
+	for _, m := range t.Methods {
+		if isGetter(m) && !nonPropertyMethods[m.Name] && !hasPropertyPrefix(m.Name, getterPrefix) &&
+			!hasPropertyPrefix(m.Name, setterPrefix) {
+			f := property(m.Name)
+			f.Getter, f.Type = m.Name, m.Results[0]
+		}
+	}
+
+	for _, m := range t.Methods {
+		if isGetter(m) && hasPropertyPrefix(m.Name, getterPrefix) {
+			if f := property(strings.TrimPrefix(m.Name, getterPrefix)); f.Getter == "" {
+				f.Getter, f.Type = m.Name, m.Results[0]
+			}
+		}
+	}
+
+	for _, m := range t.Methods {
+		if !isSetter(m) || !hasPropertyPrefix(m.Name, setterPrefix) {
+			continue
+		}
+
+		f := property(strings.TrimPrefix(m.Name, setterPrefix))
+		if f.Type == nil {
+			f.Type = m.Params[0]
+		}
+
+		if sameType(f.Type, m.Params[0]) {
+			f.Setter = m.Name
+		}
+	}
+
+	result := fields[:0]
+
+	for _, f := range fields {
+		if (f.Getter != "" || f.Setter != "") && !t.hasAccessibleField(f.Name) {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+// isGetter reports whether m takes no arguments and returns one value.
+func isGetter(m MethodInfo) bool {
+	return len(m.Params) == 0 && len(m.Results) == 1
+}
+
+// isSetter reports whether m takes one value and returns nothing.
+func isSetter(m MethodInfo) bool {
+	return len(m.Params) == 1 && len(m.Results) == 0 && !m.Variadic
+}
+
+// hasPropertyPrefix reports whether name is prefix followed by an exported
+// property name (e.g., GetName, but not Getaway).
+func hasPropertyPrefix(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok || rest == "" {
+		return false
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+
+	return unicode.IsUpper(r)
+}
+
+// hasAccessibleField reports whether the struct has a field accessible by name.
+func (t *TypeInfo) hasAccessibleField(name string) bool {
+	for _, pf := range t.AccessibleFields() {
+		if pf.Field.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameType reports whether x and y describe identical Go types.
+func sameType(x, y *TypeInfo) bool {
+	if x == y {
+		return true
+	}
+
+	return x != nil && y != nil && x.GoType != nil && y.GoType != nil && types.Identical(x.GoType, y.GoType)
+}
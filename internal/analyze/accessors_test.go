@@ -0,0 +1,85 @@
+package analyze
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const accessorSource = `package domain
+
+type User struct {
+	id    string
+	name  string
+	email string
+	Age   int
+}
+
+func (u User) ID() string { return u.id }
+
+func (u *User) GetName() string  { return u.name }
+func (u *User) SetName(v string) { u.name = v }
+
+func (u *User) SetEmail(v string) { u.email = v }
+
+func (u *User) SetAge(v int) {}
+func (u *User) GetAge() int  { return u.Age }
+
+func (u User) String() string      { return u.name }
+func (u *User) SetLabel(v []byte)  {}
+func (u *User) Label() string      { return "" }
+func (u *User) Getaway() string    { return "" }
+func (u *User) Rename(v string) error { return nil }
+`
+
+func TestTypeInfo_MethodFields(t *testing.T) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "user.go", accessorSource, 0)
+	require.NoError(t, err)
+
+	pkg, err := new(types.Config).Check("example/domain", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+
+	user := NewAnalyzer().AddPackages(pkg).GetType(TypeID{PkgPath: "example/domain", Name: "User"})
+	require.NotNil(t, user)
+
+	getters := make(map[string]string)
+	setters := make(map[string]string)
+
+	for _, f := range user.MethodFields() {
+		assert.Equal(t, -1, f.Index)
+		getters[f.Name], setters[f.Name] = f.Getter, f.Setter
+	}
+
+	// Age is a field, String describes the whole value, Getaway is a plain
+	// getter rather than Get-prefixed, and SetLabel takes another type than
+	// Label returns.
+	assert.Equal(t, map[string]string{
+		"ID": "ID", "Name": "GetName", "Email": "", "Label": "Label", "Getaway": "Getaway",
+	}, getters)
+	assert.Equal(t, map[string]string{
+		"ID": "", "Name": "SetName", "Email": "SetEmail", "Label": "", "Getaway": "",
+	}, setters)
+
+	name := user.FieldByName("Name")
+	require.NotNil(t, name)
+	assert.Equal(t, "string", name.Type.ID.Name)
+
+	assert.Equal(t, 3, user.FieldByName("Age").Index)
+
+	var id MethodInfo
+	for _, m := range user.Methods {
+		if m.Name == "ID" {
+			id = m
+		}
+	}
+
+	assert.False(t, id.PointerReceiver)
+	assert.Nil(t, user.FieldByName("String"))
+}
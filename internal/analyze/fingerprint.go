@@ -0,0 +1,161 @@
+package analyze
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// fingerprintMode loads package files and imports without type-checking,
+// which is much faster than LoadMode.
+const fingerprintMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedEmbedFiles |
+	packages.NeedImports |
+	packages.NeedDeps |
+	packages.NeedModule
+
+// Fingerprints identifies the content of loaded packages: a package's
+// fingerprint changes whenever its files or those of a package it imports,
+// directly or not, do.
+type Fingerprints struct {
+	// Packages maps the import path of every loaded package, dependencies
+	// included, to its fingerprint.
+	Packages map[string]string
+	// Roots are the import paths of the packages matched by the patterns.
+	Roots []string
+}
+
+// FingerprintPackages fingerprints the packages matched by patterns and their
+// dependencies. Other modules are identified by their version; the files of
+// all other packages, in or out of modules, are hashed, so fingerprints change
+// with any edit, even one keeping the size and modification time of a file.
+func FingerprintPackages(patterns ...string) (*Fingerprints, error) {
+	cfg := &packages.Config{Mode: fingerprintMode}
+
+	patterns, err := configureWorkspace(cfg, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	fps := &Fingerprints{Packages: make(map[string]string)}
+
+	for _, pkg := range pkgs {
+		fps.Roots = append(fps.Roots, pkg.PkgPath)
+	}
+
+	slices.Sort(fps.Roots)
+
+	var visit func(pkg *packages.Package) (string, error)
+
+	visit = func(pkg *packages.Package) (string, error) {
+		if fp, ok := fps.Packages[pkg.PkgPath]; ok {
+			return fp, nil
+		}
+
+		h := sha256.New()
+		fmt.Fprintf(h, "%s\n", pkg.PkgPath)
+
+		if err := hashPackageFiles(h, pkg); err != nil {
+			return "", err
+		}
+
+		for _, path := range slices.Sorted(maps.Keys(pkg.Imports)) {
+			fp, err := visit(pkg.Imports[path])
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "import %s %s\n", path, fp)
+		}
+
+		fp := hex.EncodeToString(h.Sum(nil))
+		fps.Packages[pkg.PkgPath] = fp
+
+		return fp, nil
+	}
+
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+
+		if _, err := visit(pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	return fps, nil
+}
+
+// hashPackageFiles writes what identifies the files of pkg to h.
+func hashPackageFiles(h io.Writer, pkg *packages.Package) error {
+	files := slices.Concat(pkg.GoFiles, pkg.OtherFiles, pkg.EmbedFiles)
+	slices.Sort(files)
+
+	// Module versions are immutable; the files of other packages, the main
+	// module's, replaced modules' and the standard library's, are hashed.
+	if mod := pkg.Module; mod != nil && !mod.Main && mod.Replace == nil {
+		fmt.Fprintf(h, "module %s@%s\n", mod.Path, mod.Version)
+
+		return nil
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("fingerprinting %s: %w", pkg.PkgPath, err)
+		}
+
+		fmt.Fprintf(h, "file %s %d\n", file, len(content))
+		h.Write(content)
+	}
+
+	return nil
+}
+
+// Lookup returns the fingerprint of the package named pkg in a mapping file:
+// an import path, or the last element of the path of one of the roots (e.g.,
+// "store" for "example.com/app/store"). Unknown or ambiguous names get the
+// fingerprint of all the roots, which changes whenever any of them does.
+func (f *Fingerprints) Lookup(pkg string) string {
+	if fp, ok := f.Packages[pkg]; ok {
+		return fp
+	}
+
+	var matches []string
+
+	for _, root := range f.Roots {
+		if strings.HasSuffix(root, "/"+strings.TrimPrefix(pkg, "./")) {
+			matches = append(matches, root)
+		}
+	}
+
+	if len(matches) == 1 {
+		return f.Packages[matches[0]]
+	}
+
+	return f.All()
+}
+
+// All returns a fingerprint of all the roots.
+func (f *Fingerprints) All() string {
+	h := sha256.New()
+	for _, root := range f.Roots {
+		fmt.Fprintf(h, "%s %s\n", root, f.Packages[root])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
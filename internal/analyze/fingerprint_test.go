@@ -0,0 +1,66 @@
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintPackages(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	write("go.mod", "module example.com/fp\n\ngo 1.24\n")
+	write("a/a.go", "package a\n\nimport \"example.com/fp/b\"\n\ntype A struct{ B b.B }\n")
+	write("b/b.go", "package b\n\ntype B struct{ X int }\n")
+	write("c/c.go", "package c\n\ntype C struct{}\n")
+	t.Chdir(dir)
+
+	before, err := FingerprintPackages("./...")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/fp/a", "example.com/fp/b", "example.com/fp/c"}, before.Roots)
+	assert.Equal(t, before.Packages["example.com/fp/a"], before.Lookup("a"), "short names")
+	assert.Equal(t, before.All(), before.Lookup("store"), "unknown package")
+
+	write("b/b.go", "package b\n\ntype B struct{ X, Y int }\n")
+
+	after, err := FingerprintPackages("./...")
+	require.NoError(t, err)
+	assert.NotEqual(t, before.Lookup("b"), after.Lookup("b"))
+	assert.NotEqual(t, before.Lookup("a"), after.Lookup("a"), "importers change with their imports")
+	assert.Equal(t, before.Lookup("c"), after.Lookup("c"))
+}
+
+func TestFingerprintPackages_OutsideModules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "src", "example.com", "legacy", "legacy.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("package legacy\n\ntype A struct{ X int }\n"), 0o644))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	t.Setenv("GO111MODULE", "off")
+	t.Setenv("GOPATH", dir)
+	t.Chdir(dir)
+
+	before, err := FingerprintPackages("example.com/legacy")
+	require.NoError(t, err)
+
+	// Same size and modification time, different content.
+	require.NoError(t, os.WriteFile(path, []byte("package legacy\n\ntype A struct{ Y int }\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+
+	after, err := FingerprintPackages("example.com/legacy")
+	require.NoError(t, err)
+	assert.NotEqual(t, before.Lookup("example.com/legacy"), after.Lookup("example.com/legacy"))
+}
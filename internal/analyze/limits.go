@@ -0,0 +1,128 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Errors reported when an analysis exceeds its Limits.
+var (
+	ErrTooManyPackages = errors.New("too many packages")
+	ErrTooManyTypes    = errors.New("too many types")
+	ErrLoadTimeout     = errors.New("package loading timed out")
+)
+
+// Limits bounds what an Analyzer loads, so accidental runs over a vendor tree
+// or a whole monorepo fail fast instead of hanging. Zero values mean no limit.
+type Limits struct {
+	// MaxPackages is the maximum number of packages matched by the patterns.
+	MaxPackages int
+	// MaxTypes is the maximum number of exported types across those packages.
+	MaxTypes int
+	// Timeout bounds the time spent loading and type-checking packages.
+	Timeout time.Duration
+	// SkipDirs lists directory names whose packages are dropped (e.g., vendor).
+	SkipDirs []string
+}
+
+// DefaultSkipDirs are the directories skipped by DefaultLimits.
+var DefaultSkipDirs = []string{"vendor", "testdata"}
+
+// DefaultLimits returns limits generous enough for any mapping workflow.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxPackages: 500,
+		MaxTypes:    20000,
+		Timeout:     2 * time.Minute,
+		SkipDirs:    DefaultSkipDirs,
+	}
+}
+
+// skipPackages drops packages located under one of the skipped directories,
+// except dependency packages a pattern names by import path (vendored ones).
+func (l *Limits) skipPackages(pkgs []*packages.Package, patterns []string) []*packages.Package {
+	if len(l.SkipDirs) == 0 {
+		return pkgs
+	}
+
+	cwd, _ := os.Getwd()
+
+	kept := pkgs[:0]
+
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 || requestedDependency(pkg, patterns) ||
+			!l.inSkippedDir(cwd, filepath.Dir(pkg.GoFiles[0])) {
+			kept = append(kept, pkg)
+		}
+	}
+
+	return kept
+}
+
+// requestedDependency reports whether pkg belongs to a dependency module and
+// one of patterns is its import path.
+func requestedDependency(pkg *packages.Package, patterns []string) bool {
+	return pkg.Module != nil && !pkg.Module.Main && slices.Contains(patterns, pkg.PkgPath)
+}
+
+// inSkippedDir reports whether dir, relative to cwd when below it, contains a skipped directory.
+func (l *Limits) inSkippedDir(cwd, dir string) bool {
+	if rel, err := filepath.Rel(cwd, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		dir = rel
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(dir), "/") {
+		if slices.Contains(l.SkipDirs, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPackages fails if more packages were matched than allowed.
+func (l *Limits) checkPackages(n int) error {
+	if l.MaxPackages > 0 && n > l.MaxPackages {
+		return fmt.Errorf("%w: patterns matched %d packages, limit is %d (did ./... reach vendored or unrelated code?)",
+			ErrTooManyPackages, n, l.MaxPackages)
+	}
+
+	return nil
+}
+
+// checkTypes fails if pkgs declare more exported types than allowed. Types
+// of their dependencies don't count.
+func (l *Limits) checkTypes(pkgs []*packages.Package) error {
+	if l.MaxTypes <= 0 {
+		return nil
+	}
+
+	n := 0
+
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && obj.Exported() {
+				n++
+			}
+		}
+
+		if n > l.MaxTypes {
+			return fmt.Errorf("%w: more than %d exported types after loading %s", ErrTooManyTypes, l.MaxTypes, pkg.PkgPath)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package analyze
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestAnalyzer_LoadPackages_Limits(t *testing.T) {
+	patterns := []string{"caster-generator/store", "caster-generator/warehouse"}
+
+	_, err := NewAnalyzerWithLimits(Limits{MaxPackages: 1}).LoadPackages(patterns...)
+	require.ErrorIs(t, err, ErrTooManyPackages)
+
+	_, err = NewAnalyzerWithLimits(Limits{MaxTypes: 1}).LoadPackages(patterns...)
+	require.ErrorIs(t, err, ErrTooManyTypes)
+
+	graph, err := NewAnalyzerWithLimits(Limits{MaxPackages: 1, SkipDirs: []string{"warehouse"}}).
+		LoadPackages(patterns...)
+	require.NoError(t, err)
+	assert.Contains(t, graph.Packages, "caster-generator/store")
+	assert.NotContains(t, graph.Packages, "caster-generator/warehouse")
+}
+
+func TestLimits_CheckTypes(t *testing.T) {
+	pkg := types.NewPackage("example.com/store", "store")
+	for _, name := range []string{"Order", "Item", "order", "item", "cache"} {
+		pkg.Scope().Insert(types.NewTypeName(0, pkg, name, types.Typ[types.Int]))
+	}
+
+	pkg.Scope().Insert(types.NewVar(0, pkg, "Default", types.Typ[types.Int]))
+
+	pkgs := []*packages.Package{{PkgPath: pkg.Path(), Types: pkg}}
+
+	// Only the exported types count.
+	require.NoError(t, (&Limits{MaxTypes: 2}).checkTypes(pkgs))
+	require.ErrorIs(t, (&Limits{MaxTypes: 1}).checkTypes(pkgs), ErrTooManyTypes)
+}
+
+func TestLimits_InSkippedDir(t *testing.T) {
+	limits := Limits{SkipDirs: DefaultSkipDirs}
+
+	assert.True(t, limits.inSkippedDir("/repo", "/repo/vendor/github.com/x/y"))
+	assert.True(t, limits.inSkippedDir("/repo", "/repo/internal/testdata/case"))
+	assert.False(t, limits.inSkippedDir("/repo", "/repo/internal/gen"))
+	// Only the part below the working directory counts.
+	assert.False(t, limits.inSkippedDir("/home/vendor/repo", "/home/vendor/repo/store"))
+}
@@ -1,46 +1,98 @@
 package analyze
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/token"
 	"go/types"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
+// generatedHeader starts the files written by caster-generator.
+const generatedHeader = "// Code generated by caster-generator."
+
 // LoadMode specifies what information to load from packages.
 const LoadMode = packages.NeedName |
 	packages.NeedFiles |
 	packages.NeedSyntax |
 	packages.NeedTypes |
 	packages.NeedTypesInfo |
-	packages.NeedImports
+	packages.NeedImports |
+	packages.NeedDeps |
+	packages.NeedModule
 
 // Analyzer loads Go packages and builds a type graph.
 type Analyzer struct {
 	graph     *TypeGraph
 	typeCache map[types.Type]*TypeInfo // Cache to handle recursive types
+	limits    Limits
+	proto     bool // See SetProto
 }
 
-// NewAnalyzer creates a new Analyzer.
+// NewAnalyzer creates a new Analyzer without limits.
 func NewAnalyzer() *Analyzer {
-	return &Analyzer{
+	return NewAnalyzerWithLimits(Limits{})
+}
+
+// NewAnalyzerWithLimits creates a new Analyzer that fails once the given limits are exceeded.
+func NewAnalyzerWithLimits(limits Limits) *Analyzer {
+	a := &Analyzer{
 		graph:     NewTypeGraph(),
 		typeCache: make(map[types.Type]*TypeInfo),
+		limits:    limits,
 	}
+	a.graph.instantiate = a.instantiate
+
+	return a
 }
 
 // LoadPackages loads the specified packages and builds the type graph.
-// Patterns are standard Go package patterns (e.g., "./store", "caster-generator/warehouse").
+// Patterns are standard Go package patterns (e.g., "./store", "caster-generator/warehouse"),
+// including import paths of dependency modules (e.g., "github.com/acme/client"),
+// whose packages are marked External. Within a go.work workspace, packages of
+// all its modules load as main modules, so patterns may span them (e.g.,
+// "./...", or "example.com/dto/... example.com/api/..." from anywhere in it).
 func (a *Analyzer) LoadPackages(patterns ...string) (*TypeGraph, error) {
+	ctx := context.Background()
+
+	if a.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, a.limits.Timeout)
+		defer cancel()
+	}
+
 	cfg := &packages.Config{
-		Mode: LoadMode,
+		Mode:    LoadMode,
+		Context: ctx,
 	}
 
-	pkgs, err := packages.Load(cfg, patterns...)
+	patterns, err := configureWorkspace(cfg, patterns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load packages: %w", err)
+		return nil, err
+	}
+
+	// List the matched packages first, so too many of them fail before any
+	// is type-checked.
+	if a.limits.MaxPackages > 0 {
+		listCfg := *cfg
+		listCfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedModule
+
+		if _, err := a.load(&listCfg, patterns); err != nil {
+			return nil, err
+		}
+	}
+
+	pkgs, err := a.load(cfg, patterns)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check for package errors
@@ -56,6 +108,10 @@ func (a *Analyzer) LoadPackages(patterns ...string) (*TypeGraph, error) {
 		return nil, fmt.Errorf("package errors: %v", errs)
 	}
 
+	if err := a.limits.checkTypes(pkgs); err != nil {
+		return nil, err
+	}
+
 	// Process each package
 	for _, pkg := range pkgs {
 		a.processPackage(pkg)
@@ -64,6 +120,37 @@ func (a *Analyzer) LoadPackages(patterns ...string) (*TypeGraph, error) {
 	return a.graph, nil
 }
 
+// load loads the packages matching patterns with cfg, dropping those of
+// skipped directories, and fails if more are left than the limits allow.
+func (a *Analyzer) load(cfg *packages.Config, patterns []string) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(cfg, patterns...)
+	if errors.Is(cfg.Context.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w after %s loading %v", ErrLoadTimeout, a.limits.Timeout, patterns)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	pkgs = a.limits.skipPackages(pkgs, patterns)
+
+	if err := a.limits.checkPackages(len(pkgs)); err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
+
+// AddPackages adds already type-checked packages (e.g., built in memory by
+// tests) to the type graph, as LoadPackages does for loaded ones.
+func (a *Analyzer) AddPackages(pkgs ...*types.Package) *TypeGraph {
+	for _, pkg := range pkgs {
+		a.processPackage(&packages.Package{PkgPath: pkg.Path(), Name: pkg.Name(), Types: pkg})
+	}
+
+	return a.graph
+}
+
 // Graph returns the current type graph.
 func (a *Analyzer) Graph() *TypeGraph {
 	return a.graph
@@ -80,10 +167,37 @@ func (a *Analyzer) processPackage(pkg *packages.Package) {
 		pkgInfo.Dir = filepath.Dir(pkg.GoFiles[0])
 	}
 
+	if pkg.Module != nil {
+		pkgInfo.Module = pkg.Module.Path
+		pkgInfo.External = !pkg.Module.Main
+	}
+
+	// Register the package first so its own named types aren't taken for external ones.
+	a.graph.Packages[pkg.PkgPath] = pkgInfo
+
+	generated := casterGeneratedTypes(pkg.Syntax)
+
 	scope := pkg.Types.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
 
+		// Record exported functions so transforms can be checked against them
+		if fn, ok := obj.(*types.Func); ok && fn.Exported() {
+			a.processFunc(pkg.PkgPath, fn)
+			continue
+		}
+
+		// Record exported constants so const values can be checked against them
+		if c, ok := obj.(*types.Const); ok && c.Exported() {
+			a.graph.Consts[TypeID{PkgPath: pkg.PkgPath, Name: name}] = &ConstInfo{
+				ID:    TypeID{PkgPath: pkg.PkgPath, Name: name},
+				Type:  a.analyzeType(c.Type()),
+				Value: c.Val().ExactString(),
+			}
+
+			continue
+		}
+
 		// Only process type names (not variables, constants, functions)
 		typeName, ok := obj.(*types.TypeName)
 		if !ok {
@@ -100,15 +214,86 @@ func (a *Analyzer) processPackage(pkg *packages.Package) {
 			Name:    name,
 		}
 
+		if typeName.IsAlias() {
+			a.processAlias(typeID, typeName)
+			continue
+		}
+
 		typeInfo := a.analyzeType(typeName.Type())
 		typeInfo.ID = typeID
+		typeInfo.FromCaster = generated[name]
 
 		a.graph.Types[typeID] = typeInfo
 
 		pkgInfo.Types = append(pkgInfo.Types, typeID)
 	}
+}
 
-	a.graph.Packages[pkg.PkgPath] = pkgInfo
+// processAlias makes the name of an exported alias of a named type (e.g.,
+// type Order = v2.Order, or an alias of such an alias) look up its canonical
+// type, so mappings may name the type by it. The alias isn't listed as a type
+// of its package.
+func (a *Analyzer) processAlias(typeID TypeID, typeName *types.TypeName) {
+	alias, ok := typeName.Type().(*types.Alias)
+	if !ok || alias.TypeParams().Len() > 0 {
+		return
+	}
+
+	if canonical := a.analyzeType(alias); canonical.ID.Name != "" && canonical.ID.PkgPath != "" {
+		a.graph.Types[typeID] = canonical
+	}
+}
+
+// casterGeneratedTypes returns the names of the types declared in files
+// written by caster-generator, recognized by their header.
+func casterGeneratedTypes(files []*ast.File) map[string]bool {
+	names := make(map[string]bool)
+
+	for _, file := range files {
+		if len(file.Comments) == 0 || file.Comments[0].Pos() > file.Package ||
+			!strings.HasPrefix(file.Comments[0].List[0].Text, generatedHeader) {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					names[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// processFunc records an exported package-level function in the graph.
+func (a *Analyzer) processFunc(pkgPath string, fn *types.Func) {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil {
+		return
+	}
+
+	info := &FuncInfo{
+		ID:        TypeID{PkgPath: pkgPath, Name: fn.Name()},
+		Variadic:  sig.Variadic(),
+		Signature: sig,
+	}
+
+	for v := range sig.Params().Variables() {
+		info.Params = append(info.Params, a.analyzeType(v.Type()))
+	}
+
+	for v := range sig.Results().Variables() {
+		info.Results = append(info.Results, a.analyzeType(v.Type()))
+	}
+
+	a.graph.Funcs[info.ID] = info
 }
 
 // analyzeType recursively analyzes a go/types.Type and returns a TypeInfo.
@@ -118,6 +303,15 @@ func (a *Analyzer) analyzeType(t types.Type) *TypeInfo {
 		return cached
 	}
 
+	// Aliases (type A = B, following chains like type B = C) are their
+	// canonical type.
+	if alias, ok := t.(*types.Alias); ok {
+		info := a.analyzeType(types.Unalias(alias))
+		a.typeCache[t] = info
+
+		return info
+	}
+
 	info := &TypeInfo{
 		GoType: t,
 	}
@@ -151,9 +345,15 @@ func (a *Analyzer) analyzeType(t types.Type) *TypeInfo {
 		info.ElemType = a.analyzeType(tt.Elem())
 
 	case *types.Struct:
+		// Anonymous structs have no name; their literal identifies them.
 		info.Kind = TypeKindStruct
+		info.ID.Literal = types.TypeString(tt, nil)
 		a.analyzeStructFields(tt, info)
 
+	case *types.TypeParam:
+		info.Kind = TypeKindParam
+		info.ID.Name = tt.Obj().Name()
+
 	default:
 		// Maps, interfaces, channels, etc. are marked as unknown (unsupported)
 		info.Kind = TypeKindUnknown
@@ -165,18 +365,55 @@ func (a *Analyzer) analyzeType(t types.Type) *TypeInfo {
 // analyzeNamedType analyzes a named type.
 func (a *Analyzer) analyzeNamedType(named *types.Named, info *TypeInfo) {
 	obj := named.Obj()
-	info.ID = TypeID{
-		PkgPath: obj.Pkg().Path(),
-		Name:    obj.Name(),
+	info.ID = TypeID{Name: obj.Name()}
+
+	// Predeclared named types (e.g., error) have no package.
+	if obj.Pkg() != nil {
+		info.ID.PkgPath = obj.Pkg().Path()
+	}
+
+	// Record type parameters of generic declarations (e.g., Page[T any]);
+	// instances report their origin's parameters too, so skip them.
+	if params := named.TypeParams(); params != nil && named.TypeArgs().Len() == 0 {
+		for i := range params.Len() {
+			info.TypeParams = append(info.TypeParams, params.At(i).Obj().Name())
+		}
+	}
+
+	// Record type arguments of instantiated generic types (e.g., Nullable[string])
+	args := slices.Collect(named.TypeArgs().Types())
+	if len(args) > 0 {
+		for _, arg := range args {
+			info.TypeArgs = append(info.TypeArgs, a.analyzeType(arg))
+		}
+
+		info.ID.Args = TypeArgsString(args)
 	}
 
 	underlying := named.Underlying()
 
+	// Concrete struct instantiations (e.g., Page[Order]) can be mapped like any
+	// named struct, so they are registered under their own ID.
+	if _, isStruct := underlying.(*types.Struct); isStruct && len(args) > 0 && !hasTypeParam(named) {
+		if _, exists := a.graph.Types[info.ID]; !exists {
+			a.graph.Types[info.ID] = info
+		}
+	}
+
 	switch ut := underlying.(type) {
 	case *types.Struct:
 		info.Kind = TypeKindStruct
 		a.analyzeStructFields(ut, info)
 
+		if a.proto && isProtoMessage(ut) {
+			a.applyProto(named, ut, info)
+		}
+
+		if !a.isExternalPackage(info.ID.PkgPath) {
+			a.analyzeMethods(named, info)
+			a.analyzeUnexportedFields(ut, info)
+		}
+
 	case *types.Basic:
 		// Type alias for a basic type (e.g., type OrderStatus string)
 		info.Kind = TypeKindAlias
@@ -185,7 +422,7 @@ func (a *Analyzer) analyzeNamedType(named *types.Named, info *TypeInfo) {
 	default:
 		// External/opaque type (e.g., time.Time, or complex named types)
 		// We check if it's from an external package
-		if a.isExternalPackage(obj.Pkg().Path()) {
+		if a.isExternalPackage(info.ID.PkgPath) {
 			info.Kind = TypeKindExternal
 		} else {
 			// Named type wrapping something else in our packages
@@ -195,6 +432,41 @@ func (a *Analyzer) analyzeNamedType(named *types.Named, info *TypeInfo) {
 	}
 }
 
+// instantiate analyzes generic type named instantiated with args and registers
+// the result in the graph.
+func (a *Analyzer) instantiate(named *types.Named, args []types.Type) (*TypeInfo, error) {
+	inst, err := types.Instantiate(nil, named, args, true)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating %s: %w", named.Obj().Name(), err)
+	}
+
+	info := a.analyzeType(inst)
+	a.graph.Types[info.ID] = info
+
+	return info, nil
+}
+
+// hasTypeParam reports whether t mentions a type parameter, as type
+// arguments inside generic declarations do.
+func hasTypeParam(t types.Type) bool {
+	switch tt := t.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return hasTypeParam(tt.Elem())
+	case *types.Slice:
+		return hasTypeParam(tt.Elem())
+	case *types.Array:
+		return hasTypeParam(tt.Elem())
+	case *types.Map:
+		return hasTypeParam(tt.Key()) || hasTypeParam(tt.Elem())
+	case *types.Named:
+		return slices.ContainsFunc(slices.Collect(tt.TypeArgs().Types()), hasTypeParam)
+	default:
+		return false
+	}
+}
+
 // isExternalPackage returns true if the package is not in our analyzed set.
 func (a *Analyzer) isExternalPackage(pkgPath string) bool {
 	_, ok := a.graph.Packages[pkgPath]
@@ -225,6 +497,26 @@ func (a *Analyzer) analyzeStructFields(st *types.Struct, info *TypeInfo) {
 	}
 }
 
+// analyzeUnexportedFields extracts the unexported fields of a struct type,
+// kept apart from its fields since only casters generated into the package
+// declaring them may access them.
+func (a *Analyzer) analyzeUnexportedFields(st *types.Struct, info *TypeInfo) {
+	for i := range st.NumFields() {
+		field := st.Field(i)
+		if field.Exported() || field.Name() == "_" {
+			continue
+		}
+
+		info.Unexported = append(info.Unexported, FieldInfo{
+			Name:     field.Name(),
+			Type:     a.analyzeType(field.Type()),
+			Tag:      reflect.StructTag(st.Tag(i)),
+			Embedded: field.Embedded(),
+			Index:    i,
+		})
+	}
+}
+
 // GetStruct returns the TypeInfo for a named struct by its fully qualified name.
 // The typeName should be in the format "package.TypeName" (e.g., "store.Order").
 func (a *Analyzer) GetStruct(pkgPath, typeName string) (*TypeInfo, error) {
@@ -1,6 +1,10 @@
 package analyze
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"os"
 	"path/filepath"
 	"testing"
@@ -27,6 +31,22 @@ func TestAnalyzer_LoadPackages(t *testing.T) {
 	assert.Contains(t, graph.Types, warehouseOrder)
 }
 
+func TestAnalyzer_LoadPackages_External(t *testing.T) {
+	graph, err := NewAnalyzer().LoadPackages("caster-generator/store", "gopkg.in/yaml.v3")
+	require.NoError(t, err)
+
+	store := graph.Packages["caster-generator/store"]
+	require.NotNil(t, store)
+	assert.Equal(t, "caster-generator", store.Module)
+	assert.False(t, store.External)
+
+	yaml := graph.Packages["gopkg.in/yaml.v3"]
+	require.NotNil(t, yaml)
+	assert.Equal(t, "gopkg.in/yaml.v3", yaml.Module)
+	assert.True(t, yaml.External)
+	assert.Contains(t, graph.Types, TypeID{PkgPath: "gopkg.in/yaml.v3", Name: "Node"})
+}
+
 func TestAnalyzer_StoreOrderFields(t *testing.T) {
 	analyzer := NewAnalyzer()
 	graph, err := analyzer.LoadPackages("caster-generator/store")
@@ -51,6 +71,18 @@ func TestAnalyzer_StoreOrderFields(t *testing.T) {
 	assert.True(t, fieldNames["OrderedAt"], "Order should have OrderedAt field")
 }
 
+func TestAnalyzer_Consts(t *testing.T) {
+	analyzer := NewAnalyzer()
+	graph, err := analyzer.LoadPackages("caster-generator/store")
+	require.NoError(t, err)
+
+	pending := graph.GetConst(TypeID{PkgPath: "caster-generator/store", Name: "StatusPending"})
+	require.NotNil(t, pending)
+	assert.Equal(t, `"PENDING"`, pending.Value)
+	require.NotNil(t, pending.Type)
+	assert.Equal(t, "caster-generator/store.OrderStatus", pending.Type.GoType.String())
+}
+
 func TestAnalyzer_FieldTags(t *testing.T) {
 	analyzer := NewAnalyzer()
 	graph, err := analyzer.LoadPackages("caster-generator/store")
@@ -153,6 +185,22 @@ func TestTypeID_String(t *testing.T) {
 	assert.Equal(t, "int", idNoPkg.String())
 }
 
+func TestAnalyzer_AnonymousStruct(t *testing.T) {
+	str := types.Typ[types.String]
+	st := types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, nil, "Key", str, false),
+		types.NewField(token.NoPos, nil, "Value", str, false),
+	}, []string{`json:"key"`, ""})
+
+	info := NewAnalyzer().analyzeType(st)
+
+	assert.True(t, info.IsAnonymousStruct())
+	assert.False(t, info.IsNamed())
+	assert.Equal(t, `struct{Key string "json:\"key\""; Value string}`, info.ID.String())
+	require.Len(t, info.Fields, 2)
+	assert.Equal(t, "Key", info.Fields[0].Name)
+}
+
 func TestTypeKind_String(t *testing.T) {
 	assert.Equal(t, "basic", TypeKindBasic.String())
 	assert.Equal(t, "struct", TypeKindStruct.String())
@@ -181,6 +229,15 @@ func TestFieldInfo_JSONName(t *testing.T) {
 	assert.Equal(t, "MyField", f4.JSONName())
 }
 
+func TestFieldInfo_TagName(t *testing.T) {
+	f := FieldInfo{Name: "MyField", Tag: `json:"my_field,omitempty" db:"-" xml:",attr"`}
+
+	assert.Equal(t, "my_field", f.TagName("json"))
+	assert.Empty(t, f.TagName("db"), "\"-\" means no name")
+	assert.Empty(t, f.TagName("xml"), "options only means no name")
+	assert.Empty(t, f.TagName("yaml"), "absent tag")
+}
+
 func TestPackageInfo_Dir(t *testing.T) {
 	// We need to load a real package from the file system.
 	// We can use "caster-generator/internal/analyze" itself.
@@ -199,3 +256,107 @@ func TestPackageInfo_Dir(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, info.IsDir())
 }
+
+func TestAnalyzer_GenericTypes(t *testing.T) {
+	analyzer := NewAnalyzer()
+	graph, err := analyzer.LoadPackages("caster-generator/examples/generics")
+	require.NoError(t, err)
+
+	const pkg = "caster-generator/examples/generics"
+
+	page := graph.GetType(TypeID{PkgPath: pkg, Name: "Page"})
+	require.NotNil(t, page)
+	assert.True(t, page.IsGeneric())
+	assert.Equal(t, []string{"T"}, page.TypeParams)
+
+	// Instances used by fields are registered under their own ID.
+	orders := graph.GetType(TypeID{PkgPath: pkg, Name: "Page", Args: pkg + ".APIOrder"})
+	require.NotNil(t, orders)
+	assert.False(t, orders.IsGeneric())
+	assert.Equal(t, TypeKindStruct, orders.Kind)
+	require.Len(t, orders.TypeArgs, 1)
+	assert.Equal(t, "APIOrder", orders.TypeArgs[0].ID.Name)
+	assert.Equal(t, pkg+".Page["+pkg+".APIOrder]", orders.ID.String())
+
+	// Other instances are created on demand.
+	strs, err := graph.Instantiate(page, []types.Type{types.Typ[types.String]})
+	require.NoError(t, err)
+	assert.Equal(t, "string", strs.ID.Args)
+	assert.Same(t, strs, graph.GetType(strs.ID))
+
+	items := strs.FieldByName("Items")
+	require.NotNil(t, items)
+	assert.Equal(t, TypeKindSlice, items.Type.Kind)
+	assert.Equal(t, "string", items.Type.ElemType.ID.Name)
+}
+
+func TestCasterGeneratedTypes(t *testing.T) {
+	parse := func(src string) *ast.File {
+		file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+		require.NoError(t, err)
+
+		return file
+	}
+
+	generated := parse("// Code generated by caster-generator. DO NOT EDIT.\n\npackage casters\n\ntype OrderDTO struct{}\n")
+	handWritten := parse("// Package casters holds casters.\npackage casters\n\ntype Order struct{}\n")
+	otherTool := parse("// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage casters\n\ntype Item struct{}\n")
+
+	assert.Equal(t, map[string]bool{"OrderDTO": true}, casterGeneratedTypes([]*ast.File{generated, handWritten, otherTool}))
+}
+
+func TestAnalyzer_AliasChains(t *testing.T) {
+	const src = `package aliases
+
+type Item struct{ Name string }
+
+type ItemAlias = Item
+
+type ItemAlias2 = ItemAlias
+
+type Cents int64
+
+type Money = Cents
+
+type Money2 = Money
+
+type Items = []ItemAlias2
+
+type Order struct {
+	Item   ItemAlias2
+	Ptr    *ItemAlias
+	Items  Items
+	Amount Money2
+}
+`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "aliases.go", src, 0)
+	require.NoError(t, err)
+
+	pkg, err := (&types.Config{}).Check("example.com/aliases", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+
+	graph := NewAnalyzer().AddPackages(pkg)
+
+	item := graph.GetType(TypeID{PkgPath: "example.com/aliases", Name: "Item"})
+	require.NotNil(t, item)
+
+	// Aliases look up their canonical type and aren't listed in their package.
+	assert.Same(t, item, graph.GetType(TypeID{PkgPath: "example.com/aliases", Name: "ItemAlias2"}))
+	assert.NotContains(t, graph.Packages["example.com/aliases"].Types,
+		TypeID{PkgPath: "example.com/aliases", Name: "ItemAlias"})
+
+	order := graph.GetType(TypeID{PkgPath: "example.com/aliases", Name: "Order"})
+	require.NotNil(t, order)
+
+	assert.Same(t, item, order.FieldByName("Item").Type)
+	assert.Equal(t, TypeKindPointer, order.FieldByName("Ptr").Type.Kind)
+	assert.Same(t, item, order.FieldByName("Ptr").Type.ElemType)
+	assert.Equal(t, TypeKindSlice, order.FieldByName("Items").Type.Kind)
+	assert.Same(t, item, order.FieldByName("Items").Type.ElemType)
+
+	amount := order.FieldByName("Amount").Type
+	assert.Equal(t, TypeKindAlias, amount.Kind, "defined type over int64")
+	assert.Equal(t, "Cents", amount.ID.Name)
+}
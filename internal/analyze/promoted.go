@@ -0,0 +1,177 @@
+package analyze
+
+// PromotedField is a struct field that is accessible by its bare name, either
+// declared directly on the struct or promoted from an embedded struct.
+type PromotedField struct {
+	Field      *FieldInfo // The field as declared on its own struct
+	Path       []string   // Embedded field names traversed to reach Field (empty for direct fields)
+	ViaPointer bool       // Whether an embedded pointer is traversed to reach Field
+}
+
+// Depth returns the embedding depth of the field (0 for direct fields).
+func (p PromotedField) Depth() int {
+	return len(p.Path)
+}
+
+// embeddedLevel is a struct reached through embedding during promotion lookup.
+type embeddedLevel struct {
+	typ        *TypeInfo
+	path       []string
+	viaPointer bool
+}
+
+// AccessibleFields returns all fields accessible on a struct by bare name,
+// following Go's promotion rules: a field at a shallower embedding depth
+// shadows deeper ones, and names declared more than once at the same depth
+// are ambiguous and not promoted at all. Direct fields come first, followed
+// by promoted fields ordered by depth and declaration order.
+func (t *TypeInfo) AccessibleFields() []PromotedField {
+	if t == nil || t.Kind != TypeKindStruct {
+		return nil
+	}
+
+	var result []PromotedField
+
+	// Names decided at a shallower depth (either found or ambiguous).
+	decided := make(map[string]bool)
+	seen := make(map[*TypeInfo]bool)
+	current := []embeddedLevel{{typ: t}}
+
+	for len(current) > 0 {
+		var (
+			found []PromotedField
+			next  []embeddedLevel
+		)
+
+		counts := make(map[string]int)
+
+		// The same struct reached twice at one depth makes its fields ambiguous,
+		// so only structs from shallower depths are skipped.
+		var level []embeddedLevel
+
+		for _, lvl := range current {
+			if !seen[lvl.typ] {
+				level = append(level, lvl)
+			}
+		}
+
+		for _, lvl := range level {
+			seen[lvl.typ] = true
+		}
+
+		for _, lvl := range level {
+			for i := range lvl.typ.Fields {
+				f := &lvl.typ.Fields[i]
+
+				if !decided[f.Name] {
+					counts[f.Name]++
+
+					found = append(found, PromotedField{Field: f, Path: lvl.path, ViaPointer: lvl.viaPointer})
+				}
+
+				if !f.Embedded {
+					continue
+				}
+
+				embedded, viaPointer := embeddedStruct(f.Type)
+				if embedded == nil {
+					continue
+				}
+
+				path := append(append([]string{}, lvl.path...), f.Name)
+				next = append(next, embeddedLevel{typ: embedded, path: path, viaPointer: lvl.viaPointer || viaPointer})
+			}
+		}
+
+		for _, pf := range found {
+			if counts[pf.Field.Name] == 1 {
+				result = append(result, pf)
+			}
+		}
+
+		for name := range counts {
+			decided[name] = true
+		}
+
+		current = next
+	}
+
+	return result
+}
+
+// FieldByName looks up a field accessible on a struct by bare name, including
+// fields promoted from embedded structs, then properties exposed by accessor
+// methods (see MethodFields) and unexported fields. It returns nil if the name
+// is not found or is ambiguous.
+func (t *TypeInfo) FieldByName(name string) *FieldInfo {
+	if t == nil || t.Kind != TypeKindStruct {
+		return nil
+	}
+
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+
+	for _, pf := range t.AccessibleFields() {
+		if pf.Field.Name == name {
+			return pf.Field
+		}
+	}
+
+	methodFields := t.MethodFields()
+	for i := range methodFields {
+		if methodFields[i].Name == name {
+			return &methodFields[i]
+		}
+	}
+
+	for i := range t.Unexported {
+		if t.Unexported[i].Name == name {
+			return &t.Unexported[i]
+		}
+	}
+
+	return nil
+}
+
+// DeclaresField reports whether a struct declares a field by name, exported
+// or not, which a method of it can't be named as.
+func (t *TypeInfo) DeclaresField(name string) bool {
+	if t == nil || t.Kind != TypeKindStruct {
+		return false
+	}
+
+	for _, fields := range [][]FieldInfo{t.Fields, t.Unexported} {
+		for i := range fields {
+			if fields[i].Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// embeddedStruct returns the struct type behind an embedded field type,
+// dereferencing a single pointer, or nil if the field doesn't embed a struct.
+func embeddedStruct(t *TypeInfo) (*TypeInfo, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	if t.Kind == TypeKindPointer {
+		if t.ElemType != nil && t.ElemType.Kind == TypeKindStruct {
+			return t.ElemType, true
+		}
+
+		return nil, false
+	}
+
+	if t.Kind == TypeKindStruct {
+		return t, false
+	}
+
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package analyze
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func structType(name string, fields ...FieldInfo) *TypeInfo {
+	return &TypeInfo{ID: TypeID{PkgPath: "test", Name: name}, Kind: TypeKindStruct, Fields: fields}
+}
+
+func field(name string, typ *TypeInfo) FieldInfo {
+	return FieldInfo{Name: name, Exported: true, Type: typ}
+}
+
+func embed(typ *TypeInfo) FieldInfo {
+	return FieldInfo{Name: typ.ID.Name, Exported: true, Type: typ, Embedded: true}
+}
+
+func TestTypeInfo_AccessibleFields(t *testing.T) {
+	str := &TypeInfo{Kind: TypeKindBasic}
+
+	base := structType("Base", field("ID", str), field("Name", str))
+	audit := structType("Audit", field("Name", str), field("CreatedBy", str))
+	inner := structType("Inner", field("Deep", str), field("ID", str))
+	wrapper := structType("Wrapper", embed(inner))
+
+	entity := structType("Entity",
+		embed(base),
+		embed(audit),
+		embed(wrapper),
+		field("Title", str),
+	)
+
+	names := make(map[string][]string)
+	for _, pf := range entity.AccessibleFields() {
+		names[pf.Field.Name] = pf.Path
+	}
+
+	// Direct fields, including the embedded fields themselves.
+	assert.Contains(t, names, "Base")
+	assert.Contains(t, names, "Title")
+	assert.Empty(t, names["Title"])
+
+	// Promoted from depth 1.
+	assert.Equal(t, []string{"Base"}, names["ID"], "shallower Base.ID shadows Wrapper.Inner.ID")
+	assert.Equal(t, []string{"Audit"}, names["CreatedBy"])
+
+	// Promoted from depth 2.
+	assert.Equal(t, []string{"Wrapper", "Inner"}, names["Deep"])
+
+	// Name is declared by both Base and Audit at depth 1, so it's ambiguous.
+	assert.NotContains(t, names, "Name")
+	assert.Nil(t, entity.FieldByName("Name"))
+
+	id := entity.FieldByName("ID")
+	require.NotNil(t, id)
+	assert.Same(t, &base.Fields[0], id)
+}
+
+func TestTypeInfo_AccessibleFieldsViaPointer(t *testing.T) {
+	str := &TypeInfo{Kind: TypeKindBasic}
+
+	base := structType("Base", field("ID", str))
+	entity := structType("Entity", FieldInfo{
+		Name:     "Base",
+		Exported: true,
+		Embedded: true,
+		Type:     &TypeInfo{Kind: TypeKindPointer, ElemType: base},
+	})
+
+	// A self-referencing embed must not loop forever.
+	base.Fields = append(base.Fields, FieldInfo{
+		Name:     "Entity",
+		Exported: true,
+		Embedded: true,
+		Type:     &TypeInfo{Kind: TypeKindPointer, ElemType: entity},
+	})
+
+	var id *PromotedField
+
+	fields := entity.AccessibleFields()
+	for i := range fields {
+		if fields[i].Field.Name == "ID" {
+			id = &fields[i]
+		}
+	}
+
+	require.NotNil(t, id)
+	assert.True(t, id.ViaPointer)
+	assert.Equal(t, 1, id.Depth())
+}
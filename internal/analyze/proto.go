@@ -0,0 +1,150 @@
+package analyze
+
+import (
+	"go/types"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// protoInternalPrefix starts the exported bookkeeping fields of messages
+// generated by the legacy protobuf API (e.g., XXX_unrecognized).
+const protoInternalPrefix = "XXX_"
+
+// SetProto enables the proto mode: structs generated by protoc-gen-go lose
+// their internal fields, expose the members of their oneofs as fields read
+// through getters, and record the getters of their regular fields (see
+// FieldInfo.Getter). Call it before loading packages.
+func (a *Analyzer) SetProto(enabled bool) {
+	a.proto = enabled
+}
+
+// isProtoMessage reports whether st was generated by protoc-gen-go, which
+// tags message fields with protobuf or protobuf_oneof.
+func isProtoMessage(st *types.Struct) bool {
+	for i := range st.NumFields() {
+		tag := reflect.StructTag(st.Tag(i))
+		if _, ok := tag.Lookup("protobuf"); ok {
+			return true
+		}
+
+		if _, ok := tag.Lookup("protobuf_oneof"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyProto adapts the fields of proto message named, already analyzed into
+// info, for mapping.
+func (a *Analyzer) applyProto(named *types.Named, st *types.Struct, info *TypeInfo) {
+	fields := make([]FieldInfo, 0, len(info.Fields))
+
+	for _, field := range info.Fields {
+		switch {
+		case strings.HasPrefix(field.Name, protoInternalPrefix):
+			// Legacy bookkeeping, not message data.
+		case field.Tag.Get("protobuf_oneof") != "":
+			fields = append(fields, a.oneofMembers(named, st.Field(field.Index))...)
+		default:
+			field.Getter = protoGetter(named, field.Name, st.Field(field.Index).Type())
+			fields = append(fields, field)
+		}
+	}
+
+	info.Fields = fields
+}
+
+// oneofMembers returns the members of oneof field of message named as fields
+// read through their getters. The members are the single fields of the
+// wrapper structs implementing the oneof interface, in field number order.
+func (a *Analyzer) oneofMembers(named *types.Named, oneof *types.Var) []FieldInfo {
+	iface, ok := oneof.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	type member struct {
+		field  *types.Var
+		tag    string
+		number int
+	}
+
+	var members []member
+
+	scope := named.Obj().Pkg().Scope()
+
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !types.Implements(types.NewPointer(obj.Type()), iface) {
+			continue
+		}
+
+		wrapper, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok || wrapper.NumFields() != 1 {
+			continue
+		}
+
+		tag := wrapper.Tag(0)
+		members = append(members, member{field: wrapper.Field(0), tag: tag, number: protoFieldNumber(tag)})
+	}
+
+	slices.SortStableFunc(members, func(x, y member) int { return x.number - y.number })
+
+	fields := make([]FieldInfo, 0, len(members))
+
+	for _, m := range members {
+		getter := protoGetter(named, m.field.Name(), m.field.Type())
+		if getter == "" || !m.field.Exported() {
+			continue
+		}
+
+		fields = append(fields, FieldInfo{
+			Name:     m.field.Name(),
+			Exported: true,
+			Type:     a.analyzeType(m.field.Type()),
+			Tag:      reflect.StructTag(m.tag),
+			Index:    -1,
+			Getter:   getter,
+			Oneof:    oneof.Name(),
+		})
+	}
+
+	return fields
+}
+
+// protoGetter returns the name of the getter of field name on message named
+// if it returns the field's type t unchanged, or "" otherwise (e.g., proto3
+// optional fields, whose getters dereference the field).
+func protoGetter(named *types.Named, name string, t types.Type) string {
+	getter := "Get" + name
+
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, named.Obj().Pkg(), getter)
+
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return ""
+	}
+
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Params().Len() != 0 || sig.Results().Len() != 1 || !types.Identical(sig.Results().At(0).Type(), t) {
+		return ""
+	}
+
+	return getter
+}
+
+// protoFieldNumber returns the field number of a protobuf struct tag
+// (e.g., 3 for `protobuf:"bytes,3,opt,name=card,proto3,oneof"`), or 0.
+func protoFieldNumber(tag string) int {
+	parts := strings.Split(reflect.StructTag(tag).Get("protobuf"), ",")
+	if len(parts) < 2 {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(parts[1])
+
+	return n
+}
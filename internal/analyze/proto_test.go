@@ -0,0 +1,162 @@
+package analyze
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoSource mimics the output of protoc-gen-go, without the protoimpl
+// and legacy runtime dependencies.
+const protoSource = `package pb
+
+type Order struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	Id       string    ` + "`protobuf:\"bytes,1,opt,name=id,proto3\" json:\"id,omitempty\"`" + `
+	Customer *Customer ` + "`protobuf:\"bytes,2,opt,name=customer,proto3\" json:\"customer,omitempty\"`" + `
+	Note     *string   ` + "`protobuf:\"bytes,3,opt,name=note,proto3,oneof\" json:\"note,omitempty\"`" + `
+	// Types that are valid to be assigned to Payment:
+	//
+	//	*Order_Cash
+	//	*Order_Card
+	Payment isOrder_Payment ` + "`protobuf_oneof:\"payment\"`" + `
+
+	XXX_unrecognized []byte ` + "`json:\"-\"`" + `
+}
+
+func (x *Order) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Order) GetCustomer() *Customer {
+	if x != nil {
+		return x.Customer
+	}
+	return nil
+}
+
+func (x *Order) GetNote() string {
+	if x != nil && x.Note != nil {
+		return *x.Note
+	}
+	return ""
+}
+
+func (x *Order) GetPayment() isOrder_Payment {
+	if x != nil {
+		return x.Payment
+	}
+	return nil
+}
+
+func (x *Order) GetCard() *Card {
+	if x, ok := x.GetPayment().(*Order_Card); ok {
+		return x.Card
+	}
+	return nil
+}
+
+func (x *Order) GetCash() int64 {
+	if x, ok := x.GetPayment().(*Order_Cash); ok {
+		return x.Cash
+	}
+	return 0
+}
+
+type isOrder_Payment interface {
+	isOrder_Payment()
+}
+
+type Order_Card struct {
+	Card *Card ` + "`protobuf:\"bytes,5,opt,name=card,proto3,oneof\"`" + `
+}
+
+type Order_Cash struct {
+	Cash int64 ` + "`protobuf:\"varint,4,opt,name=cash,proto3,oneof\"`" + `
+}
+
+func (*Order_Card) isOrder_Payment() {}
+
+func (*Order_Cash) isOrder_Payment() {}
+
+type Customer struct {
+	Name string ` + "`protobuf:\"bytes,1,opt,name=name,proto3\" json:\"name,omitempty\"`" + `
+}
+
+func (x *Customer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Card struct {
+	Number string ` + "`protobuf:\"bytes,1,opt,name=number,proto3\" json:\"number,omitempty\"`" + `
+}
+`
+
+func checkProtoPackage(t *testing.T) *types.Package {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "order.pb.go", protoSource, 0)
+	require.NoError(t, err)
+
+	pkg, err := new(types.Config).Check("example/pb", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+
+	return pkg
+}
+
+func TestAnalyzer_Proto(t *testing.T) {
+	pkg := checkProtoPackage(t)
+
+	t.Run("proto mode", func(t *testing.T) {
+		a := NewAnalyzer()
+		a.SetProto(true)
+
+		order := a.AddPackages(pkg).GetType(TypeID{PkgPath: "example/pb", Name: "Order"})
+		require.NotNil(t, order)
+
+		var names, getters, oneofs []string
+		for _, f := range order.Fields {
+			names = append(names, f.Name)
+			getters = append(getters, f.Getter)
+			oneofs = append(oneofs, f.Oneof)
+		}
+
+		// Internal fields are dropped and oneof members replace the oneof, in field number order.
+		assert.Equal(t, []string{"Id", "Customer", "Note", "Cash", "Card"}, names)
+		// The getter of the optional Note dereferences it, so Note is read directly.
+		assert.Equal(t, []string{"GetId", "GetCustomer", "", "GetCash", "GetCard"}, getters)
+		assert.Equal(t, []string{"", "", "", "Payment", "Payment"}, oneofs)
+
+		card := order.FieldByName("Card")
+		require.NotNil(t, card)
+		assert.Equal(t, TypeKindPointer, card.Type.Kind)
+		assert.Equal(t, "Card", card.Type.ElemType.ID.Name)
+	})
+
+	t.Run("default mode", func(t *testing.T) {
+		order := NewAnalyzer().AddPackages(pkg).GetType(TypeID{PkgPath: "example/pb", Name: "Order"})
+		require.NotNil(t, order)
+
+		assert.NotNil(t, order.FieldByName("Payment"))
+		assert.NotNil(t, order.FieldByName("XXX_unrecognized"))
+		// Oneof members are still exposed by their getters, as any accessor method.
+		assert.Equal(t, -1, order.FieldByName("Card").Index)
+		assert.Empty(t, order.FieldByName("Id").Getter)
+	})
+}
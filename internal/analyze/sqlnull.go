@@ -0,0 +1,27 @@
+package analyze
+
+import "strings"
+
+// sqlPkgPath is the package declaring the nullable column types.
+const sqlPkgPath = "database/sql"
+
+// SQLNullValue returns the value field of t if t is one of the nullable
+// types of database/sql (sql.NullString, sql.NullInt64, ..., sql.Null[T]):
+// a struct holding the value next to a Valid flag. It returns nil otherwise.
+func (t *TypeInfo) SQLNullValue() *FieldInfo {
+	if t == nil || t.Kind != TypeKindStruct || t.ID.PkgPath != sqlPkgPath ||
+		!strings.HasPrefix(t.ID.Name, "Null") || len(t.Fields) != 2 {
+		return nil
+	}
+
+	value, valid := &t.Fields[0], &t.Fields[1]
+	if value.Name == "Valid" {
+		value, valid = valid, value
+	}
+
+	if valid.Name != "Valid" || valid.Type == nil || valid.Type.ID.Name != "bool" || value.Type == nil {
+		return nil
+	}
+
+	return value
+}
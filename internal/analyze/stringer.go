@@ -68,6 +68,21 @@ func NewTypeStringer() *TypeStringer {
 	return &TypeStringer{}
 }
 
+// typeArgs returns the type argument list of an instantiated generic type
+// (e.g., "[Order]"), or an empty string.
+func (s *TypeStringer) typeArgs(t *TypeInfo) string {
+	if len(t.TypeArgs) == 0 {
+		return ""
+	}
+
+	args := make([]string, len(t.TypeArgs))
+	for i, arg := range t.TypeArgs {
+		args[i] = s.TypeString(arg)
+	}
+
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
 // TypeString returns a human-readable string representation of a TypeInfo.
 func (s *TypeStringer) TypeString(t *TypeInfo) string {
 	if t == nil {
@@ -80,7 +95,7 @@ func (s *TypeStringer) TypeString(t *TypeInfo) string {
 
 	case TypeKindStruct:
 		if t.IsNamed() {
-			return t.ID.Name
+			return t.ID.Name + s.typeArgs(t)
 		}
 
 		return "struct{...}"
@@ -106,7 +121,7 @@ func (s *TypeStringer) TypeString(t *TypeInfo) string {
 
 	case TypeKindAlias:
 		if t.IsNamed() {
-			return t.ID.Name
+			return t.ID.Name + s.typeArgs(t)
 		}
 
 		return s.TypeString(t.Underlying)
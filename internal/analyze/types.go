@@ -1,25 +1,60 @@
 package analyze
 
 import (
+	"errors"
+	"fmt"
 	"go/types"
 	"reflect"
+	"strings"
 
 	"caster-generator/internal/common"
 )
 
+// ErrNotGeneric is returned when instantiating a type without type parameters.
+var ErrNotGeneric = errors.New("type is not generic")
+
 // TypeID uniquely identifies a type by its package path and name.
 type TypeID struct {
 	PkgPath string // e.g., "caster-generator/store"
 	Name    string // e.g., "Order"
+	Args    string // Type arguments of a generic instantiation, e.g., "caster-generator/store.Order"
+	// Literal identifies an anonymous struct type by its type literal, with
+	// full package paths (e.g., "struct{Key string; Value string}").
+	Literal string
 }
 
 // String returns a human-readable representation of the TypeID.
 func (t TypeID) String() string {
+	if t.Literal != "" {
+		return t.Literal
+	}
+
+	name := t.Name
+	if t.Args != "" {
+		name += "[" + t.Args + "]"
+	}
+
 	if t.PkgPath == "" {
-		return t.Name
+		return name
+	}
+
+	return t.PkgPath + "." + name
+}
+
+// Generic returns the ID of the generic type t instantiates (t itself when it has no type arguments).
+func (t TypeID) Generic() TypeID {
+	return TypeID{PkgPath: t.PkgPath, Name: t.Name}
+}
+
+// TypeArgsString returns the canonical type argument list of a TypeID,
+// spelling each argument with full package paths.
+func TypeArgsString(args []types.Type) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = types.TypeString(arg, nil)
 	}
 
-	return t.PkgPath + "." + t.Name
+	return strings.Join(parts, ", ")
 }
 
 // TypeKind represents the kind of a type.
@@ -35,6 +70,7 @@ const (
 	TypeKindMap               // map of another type
 	TypeKindAlias             // type alias (named type wrapping another)
 	TypeKindExternal          // external/opaque type (e.g., time.Time)
+	TypeKindParam             // type parameter of a generic declaration (e.g., T)
 )
 
 // String returns a human-readable representation of the TypeKind.
@@ -56,6 +92,8 @@ func (k TypeKind) String() string {
 		return "alias"
 	case TypeKindExternal:
 		return "external"
+	case TypeKindParam:
+		return "type parameter"
 	default:
 		return common.UnknownStr
 	}
@@ -63,14 +101,19 @@ func (k TypeKind) String() string {
 
 // TypeInfo describes a Go type in the type graph.
 type TypeInfo struct {
-	ID          TypeID      // Unique identifier (empty for unnamed types like *T or []T)
-	Kind        TypeKind    // Kind of type
-	Underlying  *TypeInfo   // For named types, the underlying type
-	ElemType    *TypeInfo   // For pointers and slices, the element type
-	KeyType     *TypeInfo   // For maps, the key type
-	Fields      []FieldInfo // For structs, the list of fields
-	GoType      types.Type  // The original go/types.Type (for compatibility checks)
-	IsGenerated bool        // True if the type is virtual/generated
+	ID          TypeID       // Unique identifier (empty for unnamed types like *T or []T)
+	Kind        TypeKind     // Kind of type
+	Underlying  *TypeInfo    // For named types, the underlying type
+	ElemType    *TypeInfo    // For pointers and slices, the element type
+	KeyType     *TypeInfo    // For maps, the key type
+	Fields      []FieldInfo  // For structs, the list of fields
+	Unexported  []FieldInfo  // For named structs of loaded packages, the unexported fields
+	TypeArgs    []*TypeInfo  // For instantiated generic types, the type arguments
+	TypeParams  []string     // For generic declarations, the type parameter names
+	GoType      types.Type   // The original go/types.Type (for compatibility checks)
+	IsGenerated bool         // True if the type is virtual/generated
+	Methods     []MethodInfo // For named structs of loaded packages, the exported methods of *T
+	FromCaster  bool         // Declared in a file written by caster-generator (e.g., an earlier generate_target run)
 }
 
 // IsNamed returns true if this type has a name (TypeID is set).
@@ -78,6 +121,17 @@ func (t *TypeInfo) IsNamed() bool {
 	return t.ID.Name != ""
 }
 
+// IsAnonymousStruct returns true if the type is an inline struct type (e.g.,
+// the type of Meta in Meta struct{ Key, Value string }).
+func (t *TypeInfo) IsAnonymousStruct() bool {
+	return t.Kind == TypeKindStruct && t.ID.Literal != ""
+}
+
+// IsInterface returns true if the type is an interface (named or literal).
+func (t *TypeInfo) IsInterface() bool {
+	return t.GoType != nil && types.IsInterface(t.GoType)
+}
+
 // FieldInfo describes a struct field.
 type FieldInfo struct {
 	Name     string            // Go field name
@@ -85,7 +139,20 @@ type FieldInfo struct {
 	Type     *TypeInfo         // Field type
 	Tag      reflect.StructTag // Raw struct tag
 	Embedded bool              // Whether the field is embedded (anonymous)
-	Index    int               // Field index in the struct
+	Index    int               // Field index in the struct (-1 for proto oneof members)
+	Getter   string            // Proto getter reading the field (e.g., "GetName"), set in proto mode
+	Oneof    string            // Proto oneof holding the field, which exists only through its getter
+	Setter   string            // Method assigning a property exposed by methods only (e.g., "SetName")
+	Doc      string            // Doc comment of a generated field, without comment markers
+}
+
+// MethodInfo describes an exported method.
+type MethodInfo struct {
+	Name            string      // Method name
+	Params          []*TypeInfo // Parameter types
+	Results         []*TypeInfo // Result types
+	Variadic        bool        // Whether the last parameter is variadic
+	PointerReceiver bool        // Whether the method is declared on *T, so callers need an addressable value
 }
 
 // JSONName returns the JSON tag name if present, otherwise the field name.
@@ -104,6 +171,17 @@ func (f *FieldInfo) JSONName() string {
 	return f.Name
 }
 
+// TagName returns the name part of the specified tag (before any options),
+// or an empty string if the tag is absent, unnamed, or "-".
+func (f *FieldInfo) TagName(key string) string {
+	name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
 // HasTag returns true if the field has the specified tag.
 func (f *FieldInfo) HasTag(key string) bool {
 	return f.Tag.Get(key) != ""
@@ -114,12 +192,37 @@ func (f *FieldInfo) GetTag(key string) string {
 	return f.Tag.Get(key)
 }
 
+// FuncInfo describes an exported package-level function.
+// Used to validate transform signatures against real Go declarations.
+type FuncInfo struct {
+	ID        TypeID           // Package path + function name
+	Params    []*TypeInfo      // Parameter types in declaration order
+	Results   []*TypeInfo      // Result types in declaration order
+	Variadic  bool             // Whether the last parameter is variadic
+	Signature *types.Signature // The original go/types signature
+}
+
+// ConstInfo describes an exported package-level constant.
+// Used to check const values of field mappings against their target field.
+type ConstInfo struct {
+	ID    TypeID    // Package path + constant name
+	Type  *TypeInfo // Constant type (basic for untyped constants)
+	Value string    // Constant value in Go syntax
+}
+
 // TypeGraph holds all analyzed types from loaded packages.
 type TypeGraph struct {
 	// Types maps TypeID to TypeInfo for all named types.
 	Types map[TypeID]*TypeInfo
 	// Packages maps package paths to their package info.
 	Packages map[string]*PackageInfo
+	// Funcs maps TypeID (package path + name) to exported package-level functions.
+	Funcs map[TypeID]*FuncInfo
+	// Consts maps TypeID (package path + name) to exported package-level constants.
+	Consts map[TypeID]*ConstInfo
+
+	// instantiate analyzes instantiations of generic types; set by the Analyzer.
+	instantiate func(generic *types.Named, args []types.Type) (*TypeInfo, error)
 }
 
 // NewTypeGraph creates a new empty TypeGraph.
@@ -127,6 +230,8 @@ func NewTypeGraph() *TypeGraph {
 	return &TypeGraph{
 		Types:    make(map[TypeID]*TypeInfo),
 		Packages: make(map[string]*PackageInfo),
+		Funcs:    make(map[TypeID]*FuncInfo),
+		Consts:   make(map[TypeID]*ConstInfo),
 	}
 }
 
@@ -135,10 +240,48 @@ func (g *TypeGraph) GetType(id TypeID) *TypeInfo {
 	return g.Types[id]
 }
 
+// IsGeneric returns true if t is a generic declaration that must be
+// instantiated before use (e.g., Page[T any]).
+func (t *TypeInfo) IsGeneric() bool {
+	return len(t.TypeParams) > 0
+}
+
+// Instantiate returns the instantiation of generic type t with the given
+// type arguments, analyzing and registering it on first use.
+func (g *TypeGraph) Instantiate(t *TypeInfo, args []types.Type) (*TypeInfo, error) {
+	named, ok := t.GoType.(*types.Named)
+	if !ok || !t.IsGeneric() {
+		return nil, fmt.Errorf("%s: %w", t.ID, ErrNotGeneric)
+	}
+
+	id := TypeID{PkgPath: t.ID.PkgPath, Name: t.ID.Name, Args: TypeArgsString(args)}
+	if inst := g.Types[id]; inst != nil {
+		return inst, nil
+	}
+
+	if g.instantiate == nil {
+		return nil, fmt.Errorf("%s: no analyzer to instantiate generic types", id)
+	}
+
+	return g.instantiate(named, args)
+}
+
+// GetFunc returns the FuncInfo for a given function ID, or nil if not found.
+func (g *TypeGraph) GetFunc(id TypeID) *FuncInfo {
+	return g.Funcs[id]
+}
+
+// GetConst returns the ConstInfo for a given constant ID, or nil if not found.
+func (g *TypeGraph) GetConst(id TypeID) *ConstInfo {
+	return g.Consts[id]
+}
+
 // PackageInfo holds information about a loaded package.
 type PackageInfo struct {
-	Path  string   // Import path
-	Name  string   // Package name
-	Dir   string   // Directory on disk
-	Types []TypeID // Named types defined in this package
+	Path     string   // Import path
+	Name     string   // Package name
+	Dir      string   // Directory on disk
+	Types    []TypeID // Named types defined in this package
+	Module   string   // Path of the module declaring the package, empty outside modules
+	External bool     // Declared by a dependency module (module cache or vendor), so read-only
 }
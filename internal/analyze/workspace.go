@@ -0,0 +1,209 @@
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// Workspace is a go.work workspace. Packages of all its modules load as main
+// modules into one type graph, so source and target types may live in
+// different modules.
+type Workspace struct {
+	// File is the absolute path of the go.work file.
+	File string
+
+	// Modules are the modules the workspace uses.
+	Modules []WorkspaceModule
+}
+
+// WorkspaceModule is a module used by a workspace.
+type WorkspaceModule struct {
+	Path string // Module path (e.g., "example.com/api")
+	Dir  string // Absolute directory of its go.mod
+}
+
+// FindWorkspace returns the workspace the go command uses in dir: the go.work
+// file named by GOWORK, or else the first one found in dir or its parents.
+// It returns nil without a workspace or with GOWORK=off.
+func FindWorkspace(dir string) (*Workspace, error) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return nil, nil
+	case "":
+	default:
+		return LoadWorkspace(gowork)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(path); err == nil {
+			return LoadWorkspace(path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+
+		dir = parent
+	}
+}
+
+// LoadWorkspace reads the go.work file at path and the go.mod files of the
+// modules it uses.
+func LoadWorkspace(path string) (*Workspace, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading workspace: %w", err)
+	}
+
+	wf, err := modfile.ParseWork(path, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing workspace: %w", err)
+	}
+
+	w := &Workspace{File: path}
+
+	for _, use := range wf.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+
+		gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("reading workspace module %s: %w", use.Path, err)
+		}
+
+		w.Modules = append(w.Modules, WorkspaceModule{Path: modfile.ModulePath(gomod), Dir: dir})
+	}
+
+	return w, nil
+}
+
+// Dir returns the root directory of w.
+func (w *Workspace) Dir() string {
+	return filepath.Dir(w.File)
+}
+
+// ExpandPatterns rewrites patterns given in dir to load from the workspace
+// root. Directory patterns become absolute, and "dir/..." patterns of a
+// directory containing workspace modules without being in one, which the go
+// command rejects (e.g., "./..." at the root), become a "/..." pattern per
+// module under it. Import path patterns are kept, including ones of modules.
+func (w *Workspace) ExpandPatterns(dir string, patterns []string) []string {
+	expanded := make([]string, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		if !isDirPattern(pattern) {
+			expanded = append(expanded, pattern)
+			continue
+		}
+
+		base, recursive := strings.CutSuffix(filepath.ToSlash(pattern), "/...")
+
+		abs := filepath.FromSlash(base)
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(dir, abs)
+		}
+
+		if !recursive {
+			expanded = append(expanded, abs)
+			continue
+		}
+
+		var modules []string
+
+		if w.moduleOf(abs) == nil {
+			for _, m := range w.Modules {
+				if within(abs, m.Dir) {
+					modules = append(modules, m.Dir+"/...")
+				}
+			}
+		}
+
+		if len(modules) == 0 {
+			modules = []string{abs + "/..."}
+		}
+
+		expanded = append(expanded, modules...)
+	}
+
+	return expanded
+}
+
+// moduleOf returns the workspace module containing dir, or nil.
+func (w *Workspace) moduleOf(dir string) *WorkspaceModule {
+	for i := range w.Modules {
+		if within(w.Modules[i].Dir, dir) {
+			return &w.Modules[i]
+		}
+	}
+
+	return nil
+}
+
+// env returns the environment of go commands loading from w: GOWORK names its
+// file and "-mod=mod", which workspace mode rejects, is dropped from GOFLAGS.
+func (w *Workspace) env() []string {
+	var flags []string
+
+	for _, flag := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if flag != "-mod=mod" {
+			flags = append(flags, flag)
+		}
+	}
+
+	return append(os.Environ(), "GOWORK="+w.File, "GOFLAGS="+strings.Join(flags, " "))
+}
+
+// configureWorkspace sets up cfg to load patterns within the workspace of the
+// current directory, if any, and returns the patterns to load.
+func configureWorkspace(cfg *packages.Config, patterns []string) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := FindWorkspace(cwd)
+	if err != nil || w == nil {
+		return patterns, err
+	}
+
+	cfg.Dir = w.Dir()
+	cfg.Env = w.env()
+
+	return w.ExpandPatterns(cwd, patterns), nil
+}
+
+// isDirPattern reports whether pattern names directories rather than import
+// paths.
+func isDirPattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || filepath.IsAbs(pattern) ||
+		strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../")
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
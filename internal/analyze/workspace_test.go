@@ -0,0 +1,94 @@
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeWorkspace writes a go.work workspace using a dto and an api module,
+// whose models import the dto module, and returns its directory.
+func writeWorkspace(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	write("go.work", "go 1.24\n\nuse (\n\t./dto\n\t./api\n)\n")
+	write("dto/go.mod", "module example.com/dto\n\ngo 1.24\n")
+	write("dto/order.go", "package dto\n\ntype Order struct {\n\tID    int64\n\tTotal float64\n}\n")
+	write("api/go.mod", "module example.com/api\n\ngo 1.24\n")
+	write("api/models/order.go", "package models\n\nimport \"example.com/dto\"\n\n"+
+		"type Order struct {\n\tID     int64\n\tSource dto.Order\n}\n")
+
+	return dir
+}
+
+func TestAnalyzer_LoadPackages_Workspace(t *testing.T) {
+	dir := writeWorkspace(t)
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Chdir(dir)
+
+	graph, err := NewAnalyzer().LoadPackages("./...")
+	require.NoError(t, err)
+
+	for _, path := range []string{"example.com/dto", "example.com/api/models"} {
+		require.Contains(t, graph.Packages, path)
+		assert.False(t, graph.Packages[path].External, "workspace modules are main modules")
+	}
+
+	order := graph.GetType(TypeID{PkgPath: "example.com/api/models", Name: "Order"})
+	require.NotNil(t, order)
+	require.Len(t, order.Fields, 2)
+	assert.Equal(t, TypeID{PkgPath: "example.com/dto", Name: "Order"}, order.Fields[1].Type.ID)
+
+	// Module-qualified patterns load from anywhere in the workspace.
+	t.Chdir(filepath.Join(dir, "api"))
+
+	graph, err = NewAnalyzer().LoadPackages("example.com/dto/...", "./models")
+	require.NoError(t, err)
+	assert.Contains(t, graph.Packages, "example.com/dto")
+	assert.Contains(t, graph.Packages, "example.com/api/models")
+
+	fps, err := FingerprintPackages("example.com/dto", "example.com/api/...")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/dto", "example.com/api/models"}, fps.Roots)
+}
+
+func TestWorkspace_ExpandPatterns(t *testing.T) {
+	dir := writeWorkspace(t)
+	t.Setenv("GOWORK", "")
+
+	w, err := FindWorkspace(filepath.Join(dir, "api", "models"))
+	require.NoError(t, err)
+	require.NotNil(t, w)
+	assert.Equal(t, dir, w.Dir())
+	assert.Equal(t, []WorkspaceModule{
+		{Path: "example.com/dto", Dir: filepath.Join(dir, "dto")},
+		{Path: "example.com/api", Dir: filepath.Join(dir, "api")},
+	}, w.Modules)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "dto") + "/...",
+		filepath.Join(dir, "api") + "/...",
+		filepath.Join(dir, "api", "models"),
+		filepath.Join(dir, "api") + "/...",
+		"example.com/dto/...",
+	}, w.ExpandPatterns(dir, []string{"./...", "./api/models", "./api/...", "example.com/dto/..."}))
+
+	t.Setenv("GOWORK", "off")
+
+	w, err = FindWorkspace(dir)
+	require.NoError(t, err)
+	assert.Nil(t, w)
+}
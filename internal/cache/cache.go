@@ -0,0 +1,340 @@
+package cache
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+)
+
+// formatVersion is part of every key, so entries written in an older format
+// are never read.
+const formatVersion = "1"
+
+// Store reads and writes cache entries as JSON files of a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store keeping its entries in dir, created on first save.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Load decodes the entry of kind (e.g., "check") stored under key into v, and
+// reports whether it exists. Unreadable entries are treated as missing.
+func (s *Store) Load(kind, key string, v any) bool {
+	data, err := os.ReadFile(s.path(kind, key))
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, v) == nil
+}
+
+// Save stores v as the entry of kind under key.
+func (s *Store) Save(kind, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s cache entry: %w", kind, err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	// Write then rename, so concurrent runs never read a partial entry.
+	tmp, err := os.CreateTemp(s.dir, kind+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing %s cache entry: %w", kind, err)
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(kind, key))
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return fmt.Errorf("writing %s cache entry: %w", kind, err)
+	}
+
+	return nil
+}
+
+func (s *Store) path(kind, key string) string {
+	return filepath.Join(s.dir, kind+"-"+key+".json")
+}
+
+// Key hashes parts into a cache key.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MappingKeys returns the cache key of each type mapping of mf, in order.
+// options identifies everything else the cached result depends on, such as
+// the command and its flags.
+func MappingKeys(mf *mapping.MappingFile, fps *analyze.Fingerprints, options string) ([]string, error) {
+	fileLevel := *mf
+	fileLevel.Include = nil
+	fileLevel.TypeMappings = nil
+
+	fileYAML, err := yaml.Marshal(&fileLevel)
+	if err != nil {
+		return nil, fmt.Errorf("encoding mapping file options: %w", err)
+	}
+
+	shared := []string{formatVersion, options, string(fileYAML), fmt.Sprint(mf.IncludeConflicts)}
+
+	// Adding or removing a mapping may change the nested conversions of others.
+	for i := range mf.TypeMappings {
+		shared = append(shared, "pair "+mf.TypeMappings[i].Key())
+	}
+
+	// Transforms and implementations refer to functions and types of packages.
+	var pkgs []string
+
+	for _, t := range mf.Transforms {
+		if t.Package != "" {
+			pkgs = append(pkgs, t.Package)
+		} else {
+			pkgs = append(pkgs, typePackages(t.Name)...)
+		}
+	}
+
+	for _, impl := range mf.Implementations {
+		pkgs = append(pkgs, typePackages(impl.Source)...)
+		pkgs = append(pkgs, typePackages(impl.Target)...)
+	}
+
+	shared = append(shared, fingerprints(fps, pkgs)...)
+	sharedKey := Key(shared...)
+
+	keys := make([]string, len(mf.TypeMappings))
+
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+
+		tmYAML, err := yaml.Marshal(tm)
+		if err != nil {
+			return nil, fmt.Errorf("encoding mapping %s: %w", tm.Key(), err)
+		}
+
+		pkgs := slices.Concat(typePackages(tm.Source), typePackages(tm.Target), typePackages(tm.Via))
+		for _, pkg := range []string{tm.SourcePkg, tm.TargetPkg} {
+			if pkg != "" {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+
+		target, err := generatedTarget(mf, tm)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[i] = Key(append([]string{sharedKey, string(tmYAML), target}, fingerprints(fps, pkgs)...)...)
+	}
+
+	return keys, nil
+}
+
+// generatedTarget returns what the struct of a generate_target mapping
+// depends on besides the mapping: the other mappings generating the same
+// target, and the order of their entries with field_order yaml.
+func generatedTarget(mf *mapping.MappingFile, tm *mapping.TypeMapping) (string, error) {
+	if !tm.GenerateTarget {
+		return "", nil
+	}
+
+	var parts []string
+
+	for i := range mf.TypeMappings {
+		other := &mf.TypeMappings[i]
+		if !other.GenerateTarget || other.Target != tm.Target {
+			continue
+		}
+
+		otherYAML, err := yaml.Marshal(other)
+		if err != nil {
+			return "", fmt.Errorf("encoding mapping %s: %w", other.Key(), err)
+		}
+
+		parts = append(parts, string(otherYAML))
+
+		if mf.GeneratedTypes.FieldOrder == mapping.FieldOrderYAML {
+			parts = append(parts, strings.Join(entryOrder(other), ","))
+		}
+	}
+
+	return Key(parts...), nil
+}
+
+// entryOrder returns the 121 entries (by source field) and fields entries
+// (by index) of tm in the order of their positions.
+func entryOrder(tm *mapping.TypeMapping) []string {
+	positions := make(map[string]diagnostic.Position, len(tm.OneToOne)+len(tm.Fields))
+	for source := range tm.OneToOne {
+		positions["121 "+source] = tm.OneToOnePos[source]
+	}
+
+	for i := range tm.Fields {
+		positions[fmt.Sprintf("fields %d", i)] = tm.Fields[i].Pos
+	}
+
+	return slices.SortedFunc(maps.Keys(positions), func(a, b string) int {
+		pa, pb := positions[a], positions[b]
+
+		return cmp.Or(cmp.Compare(pa.Line, pb.Line), cmp.Compare(pa.Column, pb.Column), strings.Compare(a, b))
+	})
+}
+
+// fingerprints returns "package fingerprint" entries for pkgs.
+func fingerprints(fps *analyze.Fingerprints, pkgs []string) []string {
+	entries := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		entries = append(entries, pkg+" "+fps.Lookup(pkg))
+	}
+
+	return entries
+}
+
+// typePackages returns the packages qualifying the type name of a mapping
+// file, type arguments included (e.g., "store" and "api" for
+// "store.Page[api.Item]"). Names of generated types have none.
+func typePackages(name string) []string {
+	var pkgs []string
+
+	for part := range strings.FieldsFuncSeq(name, func(r rune) bool { return strings.ContainsRune("[], *", r) }) {
+		if dot := strings.LastIndex(part, "."); dot > 0 {
+			pkgs = append(pkgs, part[:dot])
+		}
+	}
+
+	return pkgs
+}
+
+// CheckResult is the outcome of checking one type mapping.
+type CheckResult struct {
+	// Diagnostics are those of the mapping and of the nested pairs it resolves.
+	Diagnostics diagnostic.Diagnostics `json:"diagnostics"`
+	// Unmapped lists the target fields left unmapped, by type pair.
+	Unmapped []UnmappedTargets `json:"unmapped,omitempty"`
+	// Uses are the keys of the other mappings resolving nested pairs of this
+	// one, by mapping (see mapping.TypeMapping.Key); the result is stale once
+	// one of them changes.
+	Uses map[string]string `json:"uses,omitempty"`
+}
+
+// UnmappedTargets lists the unmapped target fields of a type pair.
+type UnmappedTargets struct {
+	// Pair is the resolved type pair, as "Source -> Target".
+	Pair string `json:"pair"`
+	// Targets describe each field and why it is unmapped.
+	Targets []string `json:"targets"`
+}
+
+// Current reports whether the mappings the result uses still have the keys
+// it was computed with. keys maps mapping.TypeMapping.Key to current keys.
+func (r *CheckResult) Current(keys map[string]string) bool {
+	return usesCurrent(r.Uses, keys)
+}
+
+// PairResult is the resolution of one type mapping, before the passes over
+// the whole plan (see plan.Resolver.Finish).
+type PairResult struct {
+	// Pair is the resolved type pair encoded by plan.EncodeTypePair, empty if
+	// the mapping failed to resolve.
+	Pair json.RawMessage `json:"pair,omitempty"`
+	// Diagnostics are those of the mapping and of the nested pairs it resolves.
+	Diagnostics diagnostic.Diagnostics `json:"diagnostics"`
+	// Uses are the keys of the other mappings resolving nested pairs of this
+	// one, as in CheckResult.
+	Uses map[string]string `json:"uses,omitempty"`
+}
+
+// Current reports whether the mappings the result uses still have the keys
+// it was computed with. keys maps mapping.TypeMapping.Key to current keys.
+func (r *PairResult) Current(keys map[string]string) bool {
+	return usesCurrent(r.Uses, keys)
+}
+
+// usesCurrent reports whether the mappings of uses have the same keys in
+// keys.
+func usesCurrent(uses, keys map[string]string) bool {
+	for pair, key := range uses {
+		if keys[pair] != key {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GenResult records the files written by a gen run.
+type GenResult struct {
+	// Dir is the output directory. Go files added to it since invalidate the
+	// result, since they may hold hand-edited functions gen keeps.
+	Dir string `json:"dir"`
+	// Files maps the path of each file to the hash of its content.
+	Files map[string]string `json:"files"`
+}
+
+// HashFiles returns a GenResult of the files at paths and of the Go files of
+// dir, which may be empty.
+func HashFiles(dir string, paths []string) (*GenResult, error) {
+	if dir != "" {
+		existing, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", dir, err)
+		}
+
+		paths = slices.Concat(paths, existing)
+	}
+
+	res := &GenResult{Dir: dir, Files: make(map[string]string, len(paths))}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", path, err)
+		}
+
+		sum := sha256.Sum256(content)
+		res.Files[path] = hex.EncodeToString(sum[:])
+	}
+
+	return res, nil
+}
+
+// Current reports whether the files of r still have the content they were
+// written with, and its directory has no other Go file.
+func (r *GenResult) Current() bool {
+	now, err := HashFiles(r.Dir, slices.Collect(maps.Keys(r.Files)))
+	if err != nil {
+		return false
+	}
+
+	return maps.Equal(now.Files, r.Files)
+}
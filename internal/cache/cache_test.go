@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store := NewStore(dir)
+
+	var res CheckResult
+	assert.False(t, store.Load("check", "k", &res), "missing entry")
+
+	saved := CheckResult{Unmapped: []UnmappedTargets{{Pair: "a.A -> b.B", Targets: []string{"X: no match"}}}}
+	require.NoError(t, store.Save("check", "k", &saved))
+
+	require.True(t, store.Load("check", "k", &res))
+	assert.Equal(t, saved, res)
+	assert.False(t, store.Load("gen", "k", &res), "entries are per kind")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "check-bad.json"), []byte("{"), 0o644))
+	assert.False(t, store.Load("check", "bad", &res), "unreadable entry")
+}
+
+func TestMappingKeys(t *testing.T) {
+	fps := func(store string) *analyze.Fingerprints {
+		return &analyze.Fingerprints{
+			Packages: map[string]string{"ex/store": store, "ex/api": "api1", "ex/other": "other1"},
+			Roots:    []string{"ex/api", "ex/other", "ex/store"},
+		}
+	}
+	file := func() *mapping.MappingFile {
+		return &mapping.MappingFile{TypeMappings: []mapping.TypeMapping{
+			{Source: "store.Order", Target: "api.Order"},
+			{Source: "other.A", Target: "other.B", Ignore: []mapping.IgnoreEntry{{Target: "Y"}}},
+			{Source: "other.Page[store.Item]", Target: "api.Page"},
+		}}
+	}
+
+	base, err := MappingKeys(file(), fps("store1"), "check")
+	require.NoError(t, err)
+	require.Len(t, base, 3)
+
+	again, err := MappingKeys(file(), fps("store1"), "check")
+	require.NoError(t, err)
+	assert.Equal(t, base, again, "keys are stable")
+
+	t.Run("package change", func(t *testing.T) {
+		keys, err := MappingKeys(file(), fps("store2"), "check")
+		require.NoError(t, err)
+		assert.NotEqual(t, base[0], keys[0])
+		assert.Equal(t, base[1], keys[1])
+		assert.NotEqual(t, base[2], keys[2], "type arguments count")
+	})
+
+	t.Run("via package change", func(t *testing.T) {
+		mf := file()
+		mf.TypeMappings[1].Via = "store.Bridge"
+
+		viaBase, err := MappingKeys(mf, fps("store1"), "check")
+		require.NoError(t, err)
+
+		keys, err := MappingKeys(mf, fps("store2"), "check")
+		require.NoError(t, err)
+		assert.NotEqual(t, viaBase[1], keys[1])
+	})
+
+	t.Run("section change", func(t *testing.T) {
+		mf := file()
+		mf.TypeMappings[1].Ignore = nil
+
+		keys, err := MappingKeys(mf, fps("store1"), "check")
+		require.NoError(t, err)
+		assert.Equal(t, base[0], keys[0])
+		assert.NotEqual(t, base[1], keys[1])
+	})
+
+	t.Run("file-level change", func(t *testing.T) {
+		mf := file()
+		mf.CopyMode = mapping.CopyDeep
+
+		keys, err := MappingKeys(mf, fps("store1"), "check")
+		require.NoError(t, err)
+
+		for i := range keys {
+			assert.NotEqual(t, base[i], keys[i])
+		}
+	})
+
+	t.Run("generated target", func(t *testing.T) {
+		generated := func() *mapping.MappingFile {
+			return &mapping.MappingFile{TypeMappings: []mapping.TypeMapping{
+				{Source: "store.User", Target: "View", GenerateTarget: true, OneToOne: map[string]string{"A": "A", "B": "B"}},
+				{Source: "store.Account", Target: "View", GenerateTarget: true},
+				{Source: "store.Order", Target: "api.Order"},
+			}}
+		}
+
+		genBase, err := MappingKeys(generated(), fps("store1"), "gen")
+		require.NoError(t, err)
+
+		// The struct of View depends on every mapping generating it.
+		mf := generated()
+		mf.TypeMappings[1].OneToOne = map[string]string{"ID": "AccountID"}
+
+		keys, err := MappingKeys(mf, fps("store1"), "gen")
+		require.NoError(t, err)
+		assert.NotEqual(t, genBase[0], keys[0])
+		assert.NotEqual(t, genBase[1], keys[1])
+		assert.Equal(t, genBase[2], keys[2])
+
+		// Reordered entries only matter when fields follow the mapping file.
+		reorder := func(order mapping.FieldOrder) (string, string) {
+			mf := generated()
+			mf.GeneratedTypes.FieldOrder = order
+			mf.TypeMappings[0].OneToOnePos = map[string]diagnostic.Position{"A": {Line: 1}, "B": {Line: 2}}
+
+			before, err := MappingKeys(mf, fps("store1"), "gen")
+			require.NoError(t, err)
+
+			mf.TypeMappings[0].OneToOnePos = map[string]diagnostic.Position{"A": {Line: 2}, "B": {Line: 1}}
+
+			after, err := MappingKeys(mf, fps("store1"), "gen")
+			require.NoError(t, err)
+
+			return before[0], after[0]
+		}
+
+		before, after := reorder(mapping.FieldOrderAlphabetical)
+		assert.Equal(t, before, after)
+
+		before, after = reorder(mapping.FieldOrderYAML)
+		assert.NotEqual(t, before, after)
+	})
+
+	t.Run("options change", func(t *testing.T) {
+		keys, err := MappingKeys(file(), fps("store1"), "gen")
+		require.NoError(t, err)
+		assert.NotEqual(t, base[0], keys[0])
+	})
+}
+
+func TestGenResult(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "order.go")
+	require.NoError(t, os.WriteFile(path, []byte("package casters\n"), 0o644))
+
+	res, err := HashFiles(dir, []string{path})
+	require.NoError(t, err)
+	assert.True(t, res.Current())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hand.go"), []byte("package casters\n"), 0o644))
+	assert.False(t, res.Current(), "new Go file")
+
+	res, err = HashFiles(dir, []string{path})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("package casters // edited\n"), 0o644))
+	assert.False(t, res.Current(), "edited file")
+}
@@ -0,0 +1,17 @@
+// Package cache persists the results of earlier check and gen runs, so large
+// repositories only analyze and resolve what changed since. check stores the
+// diagnostics of each mapping, gen its resolved type pair and the files it
+// wrote.
+//
+// Results are stored under keys computed by MappingKeys, one per type mapping
+// of a mapping file. A key covers:
+//   - The mapping's YAML section and the file-level options (transforms,
+//     wrappers, implementations, copy_mode, ignore_tags)
+//   - The type pairs declared by the other mappings, which nested
+//     conversions may use
+//   - The fingerprints of the packages of its source, target and via types,
+//     which change with their code or the code of any package they import
+//   - Run options, such as command flags
+//
+// Entries are never removed; deleting the cache directory clears it.
+package cache
@@ -0,0 +1,139 @@
+package diagnostic
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SuppressionFile is the name of the suppression file read from the directory
+// of the mapping file.
+const SuppressionFile = ".casterignore"
+
+// Fingerprint returns the stable ID of d: its code and a hash of its code,
+// type pair and field path, so it survives changes of its message and
+// position (e.g., "unmapped_field:3fa9c1e2b7d0").
+func (d Diagnostic) Fingerprint() string {
+	sum := sha256.Sum256([]byte(d.Code + "\x00" + d.TypePair + "\x00" + d.FieldPath))
+	return d.Code + ":" + hex.EncodeToString(sum[:6])
+}
+
+// Suppressions is a set of fingerprints of diagnostics accepted as known.
+type Suppressions map[string]bool
+
+// ParseSuppressions parses a suppression file: a fingerprint per line, with
+// blank lines and anything after a "#" ignored.
+func ParseSuppressions(data []byte) Suppressions {
+	s := make(Suppressions)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if fp := strings.TrimSpace(line); fp != "" {
+			s[fp] = true
+		}
+	}
+
+	return s
+}
+
+// LoadSuppressions reads the suppression file at path. A missing file
+// suppresses nothing.
+func LoadSuppressions(path string) (Suppressions, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Suppressions{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading suppression file: %w", err)
+	}
+
+	return ParseSuppressions(data), nil
+}
+
+// Suppress removes the diagnostics of d listed in s and returns them.
+func (d *Diagnostics) Suppress(s Suppressions) []Diagnostic {
+	var suppressed []Diagnostic
+
+	filter := func(diags []Diagnostic) []Diagnostic {
+		return slices.DeleteFunc(diags, func(diag Diagnostic) bool {
+			if s[diag.Fingerprint()] {
+				suppressed = append(suppressed, diag)
+				return true
+			}
+
+			return false
+		})
+	}
+
+	d.Errors = filter(d.Errors)
+	d.Warnings = filter(d.Warnings)
+	d.Infos = filter(d.Infos)
+
+	return suppressed
+}
+
+// Unused returns the sorted fingerprints of s matching none of suppressed.
+func (s Suppressions) Unused(suppressed []Diagnostic) []string {
+	used := make(map[string]bool, len(suppressed))
+	for _, diag := range suppressed {
+		used[diag.Fingerprint()] = true
+	}
+
+	var unused []string
+
+	for fp := range s {
+		if !used[fp] {
+			unused = append(unused, fp)
+		}
+	}
+
+	slices.Sort(unused)
+
+	return unused
+}
+
+// FormatSuppressions returns a suppression file listing the errors and
+// warnings of d ordered by type pair and field, each followed by a comment
+// describing it.
+func FormatSuppressions(d *Diagnostics) []byte {
+	var (
+		buf  bytes.Buffer
+		seen = make(map[string]bool)
+	)
+
+	diags := slices.Concat(d.Errors, d.Warnings)
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return cmp.Or(strings.Compare(a.TypePair, b.TypePair), strings.Compare(a.FieldPath, b.FieldPath),
+			strings.Compare(a.Code, b.Code))
+	})
+
+	buf.WriteString("# Known caster-generator diagnostics, accepted until fixed.\n")
+
+	for _, diag := range diags {
+		fp := diag.Fingerprint()
+		if seen[fp] {
+			continue
+		}
+
+		seen[fp] = true
+
+		desc := diag.Message
+		if diag.TypePair != "" {
+			desc = "[" + diag.TypePair + "] " + desc
+		}
+
+		fmt.Fprintf(&buf, "%s  # %s\n", fp, strings.ReplaceAll(desc, "\n", " "))
+	}
+
+	return buf.Bytes()
+}
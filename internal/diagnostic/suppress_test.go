@@ -0,0 +1,56 @@
+package diagnostic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostic_Fingerprint(t *testing.T) {
+	d := Diagnostic{Code: "unmapped_field", Message: "no match", TypePair: "a.X->b.Y", FieldPath: "Name"}
+	fp := d.Fingerprint()
+
+	assert.Regexp(t, `^unmapped_field:[0-9a-f]{12}$`, fp)
+
+	// Messages and positions don't take part.
+	moved := d
+	moved.Message = "best match below threshold"
+	moved.Pos = Position{File: "mapping.yaml", Line: 3, Column: 5}
+	assert.Equal(t, fp, moved.Fingerprint())
+
+	other := d
+	other.FieldPath = "Email"
+	assert.NotEqual(t, fp, other.Fingerprint())
+}
+
+func TestDiagnostics_Suppress(t *testing.T) {
+	var diags Diagnostics
+
+	diags.AddError("policy_lossy", "lossy", "a.X->b.Y", "Total")
+	diags.AddWarning("unmapped_field", "unmapped", "a.X->b.Y", "Name")
+	diags.AddWarning("unmapped_field", "unmapped", "a.X->b.Y", "Email")
+
+	known := diags.Warnings[0].Fingerprint()
+	s := ParseSuppressions([]byte("# accepted\n\n" + known + "  # Name is filled later\nstale:000000000000\n"))
+
+	suppressed := diags.Suppress(s)
+	require.Len(t, suppressed, 1)
+	assert.Equal(t, "Name", suppressed[0].FieldPath)
+	assert.Len(t, diags.Errors, 1)
+	require.Len(t, diags.Warnings, 1)
+	assert.Equal(t, "Email", diags.Warnings[0].FieldPath)
+	assert.Equal(t, []string{"stale:000000000000"}, s.Unused(suppressed))
+
+	// A baseline of the remaining diagnostics suppresses them all.
+	baseline := FormatSuppressions(&diags)
+	assert.Contains(t, string(baseline), diags.Errors[0].Fingerprint()+"  # [a.X->b.Y] lossy\n")
+	assert.Len(t, diags.Suppress(ParseSuppressions(baseline)), 2)
+}
+
+func TestLoadSuppressions_Missing(t *testing.T) {
+	s, err := LoadSuppressions(filepath.Join(t.TempDir(), SuppressionFile))
+	require.NoError(t, err)
+	assert.Empty(t, s)
+}
@@ -3,6 +3,7 @@ package diagnostic
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"caster-generator/internal/common"
@@ -29,6 +30,31 @@ type Diagnostic struct {
 	FieldPath string
 	// Suggestions are potential fixes or alternatives.
 	Suggestions []string
+	// Pos is where the mapping entry at fault is defined (if known).
+	Pos Position
+}
+
+// Position locates an entry of a mapping file.
+type Position struct {
+	// File is the path of the mapping file, empty if parsed from bytes.
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid returns true if the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "file:line:column", or "line L, column C"
+// without a file.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
 }
 
 // DiagnosticSeverity represents the severity level of a diagnostic.
@@ -99,6 +125,74 @@ func (d *Diagnostics) Merge(other Diagnostics) {
 	d.Infos = append(d.Infos, other.Infos...)
 }
 
+// MergeUnique merges another Diagnostics instance into this one, skipping the
+// diagnostics this one already has.
+func (d *Diagnostics) MergeUnique(other Diagnostics) {
+	merge := func(into []Diagnostic, from []Diagnostic) []Diagnostic {
+		for _, diag := range from {
+			if !slices.ContainsFunc(into, diag.sameAs) {
+				into = append(into, diag)
+			}
+		}
+
+		return into
+	}
+
+	d.Errors = merge(d.Errors, other.Errors)
+	d.Warnings = merge(d.Warnings, other.Warnings)
+	d.Infos = merge(d.Infos, other.Infos)
+}
+
+// sameAs reports whether d and other report the same thing.
+func (d Diagnostic) sameAs(other Diagnostic) bool {
+	return d.Code == other.Code && d.Message == other.Message &&
+		d.TypePair == other.TypePair && d.FieldPath == other.FieldPath
+}
+
+// Mark records how many diagnostics a Diagnostics holds, for Locate.
+type Mark struct {
+	errors, warnings, infos int
+}
+
+// Mark returns the current mark of d.
+func (d *Diagnostics) Mark() Mark {
+	return Mark{errors: len(d.Errors), warnings: len(d.Warnings), infos: len(d.Infos)}
+}
+
+// Locate sets pos as the position of the diagnostics added since mark that
+// have none yet, so that the innermost entry located first wins.
+func (d *Diagnostics) Locate(mark Mark, pos Position) {
+	if !pos.IsValid() {
+		return
+	}
+
+	locate := func(diags []Diagnostic) {
+		for i := range diags {
+			if !diags[i].Pos.IsValid() {
+				diags[i].Pos = pos
+			}
+		}
+	}
+
+	locate(d.Errors[mark.errors:])
+	locate(d.Warnings[mark.warnings:])
+	locate(d.Infos[mark.infos:])
+}
+
+// FilterTypePairs keeps the diagnostics whose type pair satisfies keep, and
+// those not tied to a type pair.
+func (d *Diagnostics) FilterTypePairs(keep func(typePair string) bool) {
+	filter := func(diags []Diagnostic) []Diagnostic {
+		return slices.DeleteFunc(diags, func(diag Diagnostic) bool {
+			return diag.TypePair != "" && !keep(diag.TypePair)
+		})
+	}
+
+	d.Errors = filter(d.Errors)
+	d.Warnings = filter(d.Warnings)
+	d.Infos = filter(d.Infos)
+}
+
 // IsValid returns true if there are no errors.
 func (d *Diagnostics) IsValid() bool {
 	return len(d.Errors) == 0
@@ -135,7 +229,11 @@ func (d Diagnostic) String() string {
 	}
 
 	if len(prefix) > 0 {
-		return strings.Join(prefix, " ") + ": " + msg
+		msg = strings.Join(prefix, " ") + ": " + msg
+	}
+
+	if d.Pos.IsValid() {
+		return d.Pos.String() + ": " + msg
 	}
 
 	return msg
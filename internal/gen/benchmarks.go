@@ -0,0 +1,138 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"maps"
+	"sort"
+	"text/template"
+
+	"caster-generator/internal/plan"
+)
+
+// benchmarksFile is the name of the file holding the caster benchmarks.
+const benchmarksFile = "casters_bench_test.go"
+
+// benchmarksTemplateData holds data for the benchmarks file template.
+type benchmarksTemplateData struct {
+	PackageName string
+	Imports     []importSpec
+	Benchmarks  []casterFixture
+	Skipped     []casterFixture
+	UsesPtr     bool
+}
+
+// generateBenchmarksFile generates a benchmark of each caster. Casters
+// calling transform stubs, directly or through nested casters, would panic
+// and get none.
+func (g *Generator) generateBenchmarksFile(p *plan.ResolvedMappingPlan) (*GeneratedFile, error) {
+	stubs := g.stubCallers(p)
+
+	data := &benchmarksTemplateData{PackageName: g.config.PackageName}
+	imports := make(map[string]importSpec)
+	seen := make(map[string]bool)
+
+	for _, fx := range g.fixtures {
+		if seen[fx.Caster] {
+			continue
+		}
+
+		seen[fx.Caster] = true
+
+		if fx.Skip == "" && stubs[fx.pairKey] {
+			fx.Skip = "it calls transform stubs, which panic"
+		}
+
+		if fx.Skip != "" {
+			data.Skipped = append(data.Skipped, fx)
+			continue
+		}
+
+		maps.Copy(imports, fx.imports)
+		maps.Copy(imports, fx.targetImports)
+
+		if fx.Fixture == "" {
+			maps.Copy(imports, fx.sourceImports)
+		}
+
+		data.Benchmarks = append(data.Benchmarks, fx)
+		data.UsesPtr = data.UsesPtr || fx.usesPtr
+	}
+
+	// The tests file declares fixturePtr when both are generated
+	data.UsesPtr = data.UsesPtr && !g.config.Tests
+
+	if len(data.Benchmarks) > 0 {
+		imports["runtime"] = importSpec{Path: "runtime"}
+		imports["testing"] = importSpec{Path: "testing"}
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := benchmarksTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, benchmarksFile, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: benchmarksFile,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: benchmarksFile,
+		Content:  formatted,
+	}, nil
+}
+
+var benchmarksTemplate = template.Must(template.New("benchmarks").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+{{range .Skipped}}// No benchmark for {{.Caster}}: {{.Skip}}.
+{{end}}
+{{range .Benchmarks}}
+// Benchmark{{.Name}} measures {{.Caster}} on a synthesized {{.SourceType}}.
+func Benchmark{{.Name}}(b *testing.B) {
+{{if .UsesContext}}	ctx := context.Background()
+{{end}}{{if .Methods}}	c := NewCasters()
+{{end}}{{if .Fixture}}	in := {{.Fixture}}
+{{else}}	var in {{.SourceType}}
+{{end}}{{range .ExtraArgs}}	var {{.Name}} {{.Type}}
+{{end}}
+	var out {{.TargetType}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		out = {{.Call}}
+	}
+
+	runtime.KeepAlive(out)
+}
+{{end}}{{if .UsesPtr}}
+// fixturePtr returns a pointer to a copy of v.
+func fixturePtr[T any](v T) *T {
+	return &v
+}
+{{end}}`))
@@ -0,0 +1,342 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strings"
+	"text/template"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// CloneHelper describes a generated copy function for the template.
+type CloneHelper struct {
+	Name string
+	Type string
+	Body string
+	Doc  string
+}
+
+// cloneSpec records the type a copy helper takes and whether it copies deeply.
+type cloneSpec struct {
+	t    *analyze.TypeInfo
+	deep bool
+}
+
+// copyModeFor returns the copy mode of a field: its own, else its mapping's,
+// else the plan-wide one.
+func (g *Generator) copyModeFor(m *plan.ResolvedFieldMapping, pair *plan.ResolvedTypePair) mapping.CopyMode {
+	return m.Copy.Or(pair.CopyMode).Or(g.copyMode)
+}
+
+// applyCopyMode copies the source value of directly assigned fields according
+// to their copy mode. Loop-based strategies always allocate the target and are
+// left alone.
+func (g *Generator) applyCopyMode(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+) {
+	if m.Strategy != plan.StrategyDirectAssign && m.Strategy != plan.StrategyConvert {
+		return
+	}
+
+	if len(m.SourcePaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+
+	var helper string
+
+	switch g.copyModeFor(m, pair) {
+	case mapping.CopyDeep:
+		if needsClone(srcType) {
+			helper = g.registerClone(srcType)
+		}
+	case mapping.CopyShallow:
+		helper = g.registerShallowCopy(srcType)
+	default:
+	}
+
+	if helper != "" {
+		assignment.SourceExpr = helper + "(" + assignment.SourceExpr + ")"
+	}
+}
+
+// cloneShape returns the type describing the layout of t, looking through
+// named types declared over slices, maps, pointers or arrays.
+func cloneShape(t *analyze.TypeInfo) *analyze.TypeInfo {
+	for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	return t
+}
+
+// needsClone reports whether a copy of a t value can share memory with the original.
+// Structs need cloning when one of their exported fields does; unexported
+// reference fields stay shared.
+func needsClone(t *analyze.TypeInfo) bool {
+	shape := cloneShape(t)
+	if shape == nil {
+		return false
+	}
+
+	switch shape.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindMap, analyze.TypeKindPointer:
+		return true
+	case analyze.TypeKindArray:
+		return needsClone(shape.ElemType)
+	case analyze.TypeKindStruct:
+		return len(cloneFields(shape)) > 0
+	default:
+		return false
+	}
+}
+
+// cloneFields returns the exported fields of struct t that need cloning.
+func cloneFields(t *analyze.TypeInfo) []analyze.FieldInfo {
+	var fields []analyze.FieldInfo
+
+	for _, f := range t.Fields {
+		if f.Exported && needsClone(f.Type) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// registerClone records the deep copy helper for t, and for any element or
+// field types it needs, and returns the helper name.
+func (g *Generator) registerClone(t *analyze.TypeInfo) string {
+	name := "clone" + g.cloneTypeKey(t)
+	if _, exists := g.clones[name]; exists {
+		return name
+	}
+
+	g.clones[name] = cloneSpec{t: t, deep: true}
+
+	shape := cloneShape(t)
+	if needsClone(shape.ElemType) {
+		g.registerClone(shape.ElemType)
+	}
+
+	if shape.Kind == analyze.TypeKindStruct {
+		for _, f := range cloneFields(shape) {
+			g.registerClone(f.Type)
+		}
+	}
+
+	return name
+}
+
+// registerShallowCopy records the shallow copy helper for t and returns its
+// name, or "" when assigning a t value already copies everything a shallow
+// copy would. Without elements to clone, the deep copy helper is the same
+// function and is reused.
+func (g *Generator) registerShallowCopy(t *analyze.TypeInfo) string {
+	shape := cloneShape(t)
+	if shape == nil {
+		return ""
+	}
+
+	switch shape.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindMap, analyze.TypeKindPointer:
+	default:
+		return ""
+	}
+
+	if !needsClone(shape.ElemType) {
+		return g.registerClone(t)
+	}
+
+	name := "copy" + g.cloneTypeKey(t)
+	g.clones[name] = cloneSpec{t: t}
+
+	return name
+}
+
+// cloneTypeKey returns a stable identifier fragment naming type t.
+func (g *Generator) cloneTypeKey(t *analyze.TypeInfo) string {
+	if t == nil {
+		return "Value"
+	}
+
+	if t.ID.PkgPath != "" {
+		return g.capitalize(g.getPkgName(t.ID.PkgPath)) + t.ID.Name + g.typeArgsKey(t)
+	}
+
+	switch t.Kind {
+	case analyze.TypeKindBasic:
+		return g.capitalize(t.ID.Name)
+	case analyze.TypeKindPointer:
+		return "Ptr" + g.cloneTypeKey(t.ElemType)
+	case analyze.TypeKindSlice:
+		return "Slice" + g.cloneTypeKey(t.ElemType)
+	case analyze.TypeKindArray:
+		return fmt.Sprintf("Array%d%s", arrayLen(t), g.cloneTypeKey(t.ElemType))
+	case analyze.TypeKindMap:
+		return "Map" + g.cloneTypeKey(t.KeyType) + "To" + g.cloneTypeKey(t.ElemType)
+	default:
+		return "Value"
+	}
+}
+
+// arrayLen returns the length of an array type.
+func arrayLen(t *analyze.TypeInfo) int64 {
+	if arr, ok := t.GoType.(*types.Array); ok {
+		return arr.Len()
+	}
+
+	return 0
+}
+
+// cloneTypeString returns the type of a clone helper's parameter and result.
+func (g *Generator) cloneTypeString(t *analyze.TypeInfo, imports map[string]importSpec) string {
+	// typeRefString spells unnamed arrays with full package paths.
+	if t.Kind == analyze.TypeKindArray && t.ID.PkgPath == "" {
+		return fmt.Sprintf("[%d]%s", arrayLen(t), g.typeRefString(t.ElemType, imports))
+	}
+
+	return g.typeRefString(t, imports)
+}
+
+// cloneElemExpr returns an expression copying elem-typed value expr, deeply
+// or by plain assignment.
+func (g *Generator) cloneElemExpr(expr string, elem *analyze.TypeInfo, deep bool) string {
+	if !deep || !needsClone(elem) {
+		return expr
+	}
+
+	return "clone" + g.cloneTypeKey(elem) + "(" + expr + ")"
+}
+
+// cloneBody returns the statements of the copy helper for type t.
+func (g *Generator) cloneBody(spec cloneSpec, typeStr string) string {
+	shape := cloneShape(spec.t)
+	elem := shape.ElemType
+
+	switch shape.Kind {
+	case analyze.TypeKindPointer:
+		return fmt.Sprintf("if v == nil {\nreturn nil\n}\n\nc := %s\n\nreturn &c", g.cloneElemExpr("*v", elem, spec.deep))
+
+	case analyze.TypeKindSlice:
+		fill := "copy(out, v)"
+		if spec.deep && needsClone(elem) {
+			fill = fmt.Sprintf("for i := range v {\nout[i] = %s\n}", g.cloneElemExpr("v[i]", elem, true))
+		}
+
+		return fmt.Sprintf("if v == nil {\nreturn nil\n}\n\nout := make(%s, len(v))\n%s\n\nreturn out", typeStr, fill)
+
+	case analyze.TypeKindMap:
+		return fmt.Sprintf("if v == nil {\nreturn nil\n}\n\nout := make(%s, len(v))\nfor k, e := range v {\nout[k] = %s\n}\n\nreturn out",
+			typeStr, g.cloneElemExpr("e", elem, spec.deep))
+
+	case analyze.TypeKindStruct:
+		// Structs are copied by value; only their reference fields need cloning.
+		var b strings.Builder
+
+		b.WriteString("out := v\n")
+
+		for _, f := range cloneFields(shape) {
+			fmt.Fprintf(&b, "out.%s = %s\n", f.Name, g.cloneElemExpr("v."+f.Name, f.Type, true))
+		}
+
+		b.WriteString("\nreturn out")
+
+		return b.String()
+
+	default:
+		// Arrays are copied by value; only their elements need cloning.
+		return fmt.Sprintf("out := v\nfor i := range v {\nout[i] = %s\n}\n\nreturn out", g.cloneElemExpr("v[i]", elem, true))
+	}
+}
+
+// generateCloneHelpersFile generates a shared file with the copy helpers used
+// by deep and shallow copied fields.
+func (g *Generator) generateCloneHelpersFile() (*GeneratedFile, error) {
+	data := &templateData{
+		PackageName: g.config.PackageName,
+		Filename:    "clone_helpers.go",
+	}
+
+	imports := make(map[string]importSpec)
+
+	var names []string
+	for name := range g.clones {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := g.clones[name]
+		typeStr := g.cloneTypeString(spec.t, imports)
+
+		doc := "a shallow copy"
+		if spec.deep {
+			doc = "a deep copy"
+		}
+
+		data.CloneHelpers = append(data.CloneHelpers, CloneHelper{
+			Name: name,
+			Type: typeStr,
+			Body: g.cloneBody(spec, typeStr),
+			Doc:  doc,
+		})
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := cloneHelpersTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, data.Filename, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: data.Filename,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: data.Filename,
+		Content:  formatted,
+	}, nil
+}
+
+var cloneHelpersTemplate = template.Must(template.New("clone").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+{{range .CloneHelpers}}
+// {{.Name}} returns {{.Doc}} of v.
+func {{.Name}}(v {{.Type}}) {{.Type}} {
+{{.Body}}
+}
+{{end}}
+`))
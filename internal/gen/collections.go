@@ -2,6 +2,7 @@ package gen
 
 import (
 	"fmt"
+	"strings"
 
 	"caster-generator/internal/analyze"
 	"caster-generator/internal/plan"
@@ -9,6 +10,7 @@ import (
 
 // buildCollectionMapping is a helper for slice and map mappings.
 func (g *Generator) buildCollectionMapping(
+	target string,
 	m *plan.ResolvedFieldMapping,
 	pair *plan.ResolvedTypePair,
 	imports map[string]importSpec,
@@ -18,8 +20,8 @@ func (g *Generator) buildCollectionMapping(
 		return ""
 	}
 
-	srcField := "in." + m.SourcePaths[0].String()
-	tgtField := "out." + m.TargetPaths[0].String()
+	srcField := g.sourcePathExpr(pair.SourceType, m.SourcePaths[0])
+	tgtField := target
 
 	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
 	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
@@ -32,7 +34,83 @@ func (g *Generator) buildCollectionMapping(
 	// Build extra args string from m.Extra
 	extraArgs := g.buildExtraArgsForNestedCall(m.Extra)
 
-	return g.generateCollectionLoop(srcField, tgtField, srcType, tgtType, imports, 0, extraArgs)
+	loop := g.generateCollectionLoop(srcField, tgtField, srcType, tgtType, imports, extraArgs)
+
+	// The loop always allocates the target; guard it so a nil source stays nil.
+	if m.PreserveNil && (srcType.Kind == analyze.TypeKindSlice || srcType.Kind == analyze.TypeKindMap) {
+		return fmt.Sprintf("if %s != nil {\n%s\n}", srcField, loop)
+	}
+
+	return loop
+}
+
+// buildDedupSliceMapping generates a slice mapping dropping the source
+// elements whose DedupBy field equals that of an earlier element. Nil
+// pointer elements are kept.
+func (g *Generator) buildDedupSliceMapping(
+	target string,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) string {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return ""
+	}
+
+	srcField := g.sourcePathExpr(pair.SourceType, m.SourcePaths[0])
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	if srcType == nil || tgtType == nil || g.getSliceElementType(srcType) == nil ||
+		g.getSliceElementType(tgtType) == nil {
+		return fmt.Sprintf("// TODO: could not determine types for deduplicated slice mapping %s -> %s",
+			m.SourcePaths[0], m.TargetPaths[0])
+	}
+
+	srcElem := srcType.ElemType
+	tgtElem := tgtType.ElemType
+
+	elemStruct := srcElem
+	if elemStruct.Kind == analyze.TypeKindPointer {
+		elemStruct = elemStruct.ElemType
+	}
+
+	keyType := g.findFieldInStruct(elemStruct, m.DedupBy)
+	if keyType == nil {
+		return fmt.Sprintf("// TODO: could not find dedup_by field %s of %s", m.DedupBy, m.SourcePaths[0])
+	}
+
+	idxVar := g.localIndexed("i")
+	seenVar := g.local("seen")
+	dupVar := g.local("dup")
+
+	srcItem := fmt.Sprintf("%s[%s]", srcField, idxVar)
+	key := srcItem + "." + m.DedupBy
+
+	dedup := fmt.Sprintf("if _, %s := %s[%s]; %s {\ncontinue\n}\n%s[%s] = struct{}{}",
+		dupVar, seenVar, key, dupVar, seenVar, key)
+	if srcElem.Kind == analyze.TypeKindPointer {
+		dedup = fmt.Sprintf("if %s != nil {\n%s\n}", srcItem, dedup)
+	}
+
+	tgtElemStr := g.typeRefString(tgtElem, imports)
+	expr := g.buildValueConversionWithExtra(srcItem, srcElem, tgtElem, tgtElemStr,
+		g.buildExtraArgsForNestedCall(m.Extra))
+
+	loop := fmt.Sprintf("%s = make(%s, 0, len(%s))\n%s := make(map[%s]struct{}, len(%s))\n"+
+		"for %s := range %s {\n%s\n%s = append(%s, %s)\n}",
+		target, g.typeRefString(tgtType, imports), srcField,
+		seenVar, g.typeRefString(keyType, imports), srcField,
+		idxVar, srcField, dedup, target, target, expr)
+
+	// The loop always allocates the target; guard it so a nil source stays nil.
+	if m.PreserveNil && srcType.Kind == analyze.TypeKindSlice {
+		return fmt.Sprintf("if %s != nil {\n%s\n}", srcField, loop)
+	}
+
+	// Scope the seen-set, so several deduplicated fields don't collide.
+	return "{\n" + loop + "\n}"
 }
 
 // generateCollectionLoop generates the loop code for collection mappings.
@@ -40,7 +118,6 @@ func (g *Generator) generateCollectionLoop(
 	srcField, tgtField string,
 	srcType, tgtType *analyze.TypeInfo,
 	imports map[string]importSpec,
-	depth int,
 	extraArgs string,
 ) string {
 	if srcType == nil || tgtType == nil {
@@ -50,12 +127,12 @@ func (g *Generator) generateCollectionLoop(
 	// Handle Slices and Arrays
 	if (srcType.Kind == analyze.TypeKindSlice || srcType.Kind == analyze.TypeKindArray) &&
 		(tgtType.Kind == analyze.TypeKindSlice || tgtType.Kind == analyze.TypeKindArray) {
-		return g.generateSliceArrayLoop(srcField, tgtField, srcType, tgtType, imports, depth, extraArgs)
+		return g.generateSliceArrayLoop(srcField, tgtField, srcType, tgtType, imports, extraArgs)
 	}
 
 	// Handle Maps
 	if srcType.Kind == analyze.TypeKindMap && tgtType.Kind == analyze.TypeKindMap {
-		return g.generateMapLoop(srcField, tgtField, srcType, tgtType, imports, depth, extraArgs)
+		return g.generateMapLoop(srcField, tgtField, srcType, tgtType, imports, extraArgs)
 	}
 
 	return "// TODO: unsupported collection type combination " + srcType.Kind.String() + " -> " + tgtType.Kind.String()
@@ -66,10 +143,9 @@ func (g *Generator) generateSliceArrayLoop(
 	srcField, tgtField string,
 	srcType, tgtType *analyze.TypeInfo,
 	imports map[string]importSpec,
-	depth int,
 	extraArgs string,
 ) string {
-	idxVar := fmt.Sprintf("i_%d", depth)
+	idxVar := g.localIndexed("i")
 	srcElem := g.getSliceElementType(srcType)
 	tgtElem := g.getSliceElementType(tgtType)
 
@@ -95,7 +171,7 @@ func (g *Generator) generateSliceArrayLoop(
 
 	// Recursion or conversion
 	if g.isCollection(srcElem) && g.isCollection(tgtElem) {
-		body = g.generateCollectionLoop(srcItem, tgtItem, srcElem, tgtElem, imports, depth+1, extraArgs)
+		body = g.generateCollectionLoop(srcItem, tgtItem, srcElem, tgtElem, imports, extraArgs)
 	} else {
 		// Leaf conversion
 		tgtElemStr := g.typeRefString(tgtElem, imports)
@@ -111,11 +187,10 @@ func (g *Generator) generateMapLoop(
 	srcField, tgtField string,
 	srcType, tgtType *analyze.TypeInfo,
 	imports map[string]importSpec,
-	depth int,
 	extraArgs string,
 ) string {
-	keyVar := fmt.Sprintf("k_%d", depth)
-	valVar := fmt.Sprintf("v_%d", depth)
+	keyVar := g.localIndexed("k")
+	valVar := g.localIndexed("v")
 
 	srcVal := g.getMapValueType(srcType)
 	tgtVal := g.getMapValueType(tgtType)
@@ -142,7 +217,7 @@ func (g *Generator) generateMapLoop(
 
 	if g.isCollection(srcVal) && g.isCollection(tgtVal) {
 		// For nested collections, we might need a block not just a string statement
-		body = g.generateCollectionLoop(valVar, tgtItem, srcVal, tgtVal, imports, depth+1, extraArgs)
+		body = g.generateCollectionLoop(valVar, tgtItem, srcVal, tgtVal, imports, extraArgs)
 	} else {
 		tgtValStr := g.typeRefString(tgtVal, imports)
 		expr := g.buildValueConversionWithExtra(valVar, srcVal, tgtVal, tgtValStr, extraArgs)
@@ -208,71 +283,52 @@ func (g *Generator) buildValueConversionWithExtra(
 	}
 
 	if srcType.Kind == analyze.TypeKindStruct && tgtType.Kind == analyze.TypeKindStruct {
-		casterName := g.nestedFunctionName(srcType, tgtType)
-		if extraArgs != "" {
-			return fmt.Sprintf("%s(%s, %s)", casterName, srcExpr, extraArgs)
-		}
-
-		return fmt.Sprintf("%s(%s)", casterName, srcExpr)
+		return g.nestedCall(srcType, tgtType, srcExpr, extraArgs)
 	}
 
-	// Handle pointer-to-struct element conversion (both pointers)
-	if srcType.Kind == analyze.TypeKindPointer && tgtType.Kind == analyze.TypeKindPointer {
-		srcInner := srcType.ElemType
-		tgtInner := tgtType.ElemType
-
-		if srcInner != nil && tgtInner != nil &&
-			srcInner.Kind == analyze.TypeKindStruct && tgtInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcInner, tgtInner)
+	return g.buildPointerElemConversion(srcExpr, srcType, tgtType, tgtTypeStr, extraArgs)
+}
 
-			casterCall := casterName + "(*" + srcExpr + ")"
-			if extraArgs != "" {
-				casterCall = fmt.Sprintf("%s(*%s, %s)", casterName, srcExpr, extraArgs)
-			}
+// buildPointerElemConversion converts between elements differing in pointer
+// indirection (T -> *U, *T -> U, *T -> *U), converting the pointed-to values
+// like any other element. A nil source element becomes a nil pointer, or the
+// zero value of a non-pointer target.
+func (g *Generator) buildPointerElemConversion(
+	srcExpr string,
+	srcType, tgtType *analyze.TypeInfo,
+	tgtTypeStr string,
+	extraArgs string,
+) string {
+	srcPtr := srcType.Kind == analyze.TypeKindPointer && srcType.ElemType != nil
+	tgtPtr := tgtType.Kind == analyze.TypeKindPointer && tgtType.ElemType != nil
 
-			return fmt.Sprintf("func() %s { if %s == nil { return nil }; v := %s; return &v }()",
-				tgtTypeStr, srcExpr, casterCall)
-		}
+	if !srcPtr && !tgtPtr {
+		// Fallback - hope for the best
+		return srcExpr
 	}
 
-	// Handle pointer-to-struct -> struct element conversion (dereference + convert)
-	if srcType.Kind == analyze.TypeKindPointer && tgtType.Kind == analyze.TypeKindStruct {
-		srcInner := srcType.ElemType
+	v := g.local("v")
 
-		if srcInner != nil && srcInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcInner, tgtType)
+	if srcPtr && tgtPtr {
+		inner := g.buildValueConversionWithExtra("*"+srcExpr, srcType.ElemType, tgtType.ElemType,
+			strings.TrimPrefix(tgtTypeStr, "*"), extraArgs)
 
-			casterCall := fmt.Sprintf("%s(*%s)", casterName, srcExpr)
-			if extraArgs != "" {
-				casterCall = fmt.Sprintf("%s(*%s, %s)", casterName, srcExpr, extraArgs)
-			}
-
-			return fmt.Sprintf(
-				"func() %s {"+
-					" if %s == nil { return %s{} /* FIXME: zero value used for nil pointer */ };"+
-					" return %s }()",
-				tgtTypeStr, srcExpr, tgtTypeStr, casterCall)
-		}
+		return fmt.Sprintf("func() %s { if %s == nil { return nil }; %s := %s; return &%s }()",
+			tgtTypeStr, srcExpr, v, inner, v)
 	}
 
-	// Handle struct -> pointer-to-struct element conversion (convert + reference)
-	if srcType.Kind == analyze.TypeKindStruct && tgtType.Kind == analyze.TypeKindPointer {
-		tgtInner := tgtType.ElemType
-
-		if tgtInner != nil && tgtInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcType, tgtInner)
+	if srcPtr {
+		inner := g.buildValueConversionWithExtra("*"+srcExpr, srcType.ElemType, tgtType, tgtTypeStr, extraArgs)
 
-			casterCall := fmt.Sprintf("%s(%s)", casterName, srcExpr)
-			if extraArgs != "" {
-				casterCall = fmt.Sprintf("%s(%s, %s)", casterName, srcExpr, extraArgs)
-			}
-
-			return fmt.Sprintf("func() %s { v := %s; return &v }()", tgtTypeStr, casterCall)
-		}
+		return fmt.Sprintf("func() %s { var %s %s; if %s != nil { %s = %s }; return %s }()",
+			tgtTypeStr, v, tgtTypeStr, srcExpr, v, inner, v)
 	}
 
-	// Fallback - hope for the best
-	return srcExpr
+	// Only the target is a pointer: point to a copy, never into the source.
+	inner := g.buildValueConversionWithExtra(srcExpr, srcType, tgtType.ElemType,
+		strings.TrimPrefix(tgtTypeStr, "*"), extraArgs)
+
+	return fmt.Sprintf("func() %s { %s := %s; return &%s }()", tgtTypeStr, v, inner, v)
 }
 
 // buildValueConversion builds a value conversion expression without extra args.
@@ -281,60 +337,5 @@ func (g *Generator) buildValueConversion(
 	srcType, tgtType *analyze.TypeInfo,
 	tgtTypeStr string,
 ) string {
-	if g.typesIdentical(srcType, tgtType) {
-		return srcExpr
-	}
-
-	if g.typesConvertible(srcType, tgtType) {
-		return fmt.Sprintf("%s(%s)", tgtTypeStr, srcExpr)
-	}
-
-	if srcType.Kind == analyze.TypeKindStruct && tgtType.Kind == analyze.TypeKindStruct {
-		casterName := g.nestedFunctionName(srcType, tgtType)
-
-		return fmt.Sprintf("%s(%s)", casterName, srcExpr)
-	}
-
-	// Handle pointer-to-struct element conversion (both pointers)
-	if srcType.Kind == analyze.TypeKindPointer && tgtType.Kind == analyze.TypeKindPointer {
-		srcInner := srcType.ElemType
-		tgtInner := tgtType.ElemType
-
-		if srcInner != nil && tgtInner != nil &&
-			srcInner.Kind == analyze.TypeKindStruct && tgtInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcInner, tgtInner)
-
-			return fmt.Sprintf("func() %s { if %s == nil { return nil }; v := %s(*%s); return &v }()",
-				tgtTypeStr, srcExpr, casterName, srcExpr)
-		}
-	}
-
-	// Handle pointer-to-struct -> struct element conversion (dereference + convert)
-	if srcType.Kind == analyze.TypeKindPointer && tgtType.Kind == analyze.TypeKindStruct {
-		srcInner := srcType.ElemType
-
-		if srcInner != nil && srcInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcInner, tgtType)
-
-			return fmt.Sprintf(
-				"func() %s {"+
-					" if %s == nil { return %s{} /* FIXME: zero value used for nil pointer */ };"+
-					" return %s(*%s) }()",
-				tgtTypeStr, srcExpr, tgtTypeStr, casterName, srcExpr)
-		}
-	}
-
-	// Handle struct -> pointer-to-struct element conversion (convert + reference)
-	if srcType.Kind == analyze.TypeKindStruct && tgtType.Kind == analyze.TypeKindPointer {
-		tgtInner := tgtType.ElemType
-
-		if tgtInner != nil && tgtInner.Kind == analyze.TypeKindStruct {
-			casterName := g.nestedFunctionName(srcType, tgtInner)
-
-			return fmt.Sprintf("func() %s { v := %s(%s); return &v }()", tgtTypeStr, casterName, srcExpr)
-		}
-	}
-
-	// Fallback - hope for the best
-	return srcExpr
+	return g.buildValueConversionWithExtra(srcExpr, srcType, tgtType, tgtTypeStr, "")
 }
@@ -0,0 +1,243 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"caster-generator/internal/plan"
+)
+
+// Debug caster modes selectable with GeneratorConfig.DebugCasters.
+const (
+	// DebugLog logs the target fields a debug caster left zero.
+	DebugLog = "log"
+	// DebugPanic panics on the target fields a debug caster left zero.
+	DebugPanic = "panic"
+)
+
+const (
+	// DebugBuildTag is the build tag selecting the debug variants of casters.
+	DebugBuildTag = "casterdebug"
+	// debugHelpersFile is the name of the file holding the check of debug casters.
+	debugHelpersFile = "casters_debug.go"
+	// debugFileSuffix ends the name of the debug variant of a caster file.
+	debugFileSuffix = "_debug.go"
+)
+
+// debugCheck is a target field checked by a debug caster: it must not stay
+// zero when one of its sources is set.
+type debugCheck struct {
+	Target  string
+	Sources string
+}
+
+// checkDebugCasters validates the configured debug mode.
+func (g *Generator) checkDebugCasters() error {
+	switch g.config.DebugCasters {
+	case "", DebugLog, DebugPanic:
+		return nil
+	default:
+		return fmt.Errorf("unknown debug casters mode %q (expected %s or %s)",
+			g.config.DebugCasters, DebugLog, DebugPanic)
+	}
+}
+
+// debugFilename returns the name of the debug variant of a caster file.
+func debugFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + debugFileSuffix
+}
+
+// debugChecks returns the target fields of the pair whose strategy keeps a set
+// source from yielding a zero target. Only top-level fields assigned directly
+// from top-level source fields are checked, so reading them can't panic.
+// Fields assigned under a when condition legitimately stay zero when it
+// doesn't hold, so they aren't checked either.
+func (g *Generator) debugChecks(pair *plan.ResolvedTypePair) []debugCheck {
+	var checks []debugCheck
+
+	for _, m := range pair.Mappings {
+		if len(m.TargetPaths) != 1 || len(m.TargetPaths[0].Segments) != 1 || m.PendingSource != "" ||
+			m.When != "" || len(m.SourcePaths) == 0 || !keepsSetSource(m.Strategy) {
+			continue
+		}
+
+		target := pair.TargetType.FieldByName(m.TargetPaths[0].Segments[0].Name)
+		if target == nil || !target.Exported || target.Setter != "" {
+			continue
+		}
+
+		sources := make([]string, 0, len(m.SourcePaths))
+
+		for _, path := range m.SourcePaths {
+			if !path.IsSimple() || isRequiresArg(pair, path.Segments[0].Name) {
+				sources = nil
+				break
+			}
+
+			sources = append(sources, g.sourcePathExpr(pair.SourceType, path))
+		}
+
+		if len(sources) == 0 {
+			continue
+		}
+
+		checks = append(checks, debugCheck{Target: target.Name, Sources: strings.Join(sources, ", ")})
+	}
+
+	return checks
+}
+
+// keepsSetSource reports whether a strategy never turns a non-zero source
+// into a zero target. Transforms, collections and enum mappings may, so
+// their fields are not checked.
+func keepsSetSource(s plan.ConversionStrategy) bool {
+	switch s {
+	case plan.StrategyDirectAssign, plan.StrategyConvert, plan.StrategyPointerWrap,
+		plan.StrategyNestedCast, plan.StrategyPointerNestedCast:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRequiresArg reports whether name is a requires argument of the pair.
+func isRequiresArg(pair *plan.ResolvedTypePair, name string) bool {
+	for _, req := range pair.Requires {
+		if req.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// debugVariantFiles returns the debug files of the output: the debug variant of
+// each caster file in variants (keyed by the caster file name) and the check
+// helper, or a single debug file merging them with the other files of the
+// package when SingleFile is set.
+func (g *Generator) debugVariantFiles(files []GeneratedFile, variants map[string]GeneratedFile) ([]GeneratedFile, error) {
+	helpers, err := g.generateDebugHelpersFile()
+	if err != nil {
+		return nil, fmt.Errorf("generating debug helpers: %w", err)
+	}
+
+	if g.config.SingleFile == "" {
+		var debugFiles []GeneratedFile
+
+		for _, file := range files {
+			if variant, ok := variants[file.Filename]; ok {
+				debugFiles = append(debugFiles, variant)
+			}
+		}
+
+		return append(debugFiles, *helpers), nil
+	}
+
+	parts := make([]GeneratedFile, 0, len(files)+1)
+
+	for _, file := range files {
+		if variant, ok := variants[file.Filename]; ok {
+			file = variant
+		}
+
+		parts = append(parts, file)
+	}
+
+	merged, err := g.mergeFiles(debugFilename(g.config.SingleFile), append(parts, *helpers))
+	if err != nil {
+		return nil, fmt.Errorf("merging into %s: %w", debugFilename(g.config.SingleFile), err)
+	}
+
+	return []GeneratedFile{*merged}, nil
+}
+
+// withBuildTag returns content constrained by the build expression.
+func withBuildTag(content []byte, expr string) []byte {
+	return append([]byte("//go:build "+expr+"\n\n"), content...)
+}
+
+// generateDebugHelpersFile generates the check called by debug casters.
+func (g *Generator) generateDebugHelpersFile() (*GeneratedFile, error) {
+	var buf bytes.Buffer
+
+	data := struct {
+		PackageName string
+		Panic       bool
+	}{g.config.PackageName, g.config.DebugCasters == DebugPanic}
+
+	if err := debugHelpersTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: debugHelpersFile,
+		Content:  formatted,
+	}, nil
+}
+
+var debugHelpersTemplate = template.Must(template.New("debug").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+import (
+	"fmt"
+{{if not .Panic}}	"log"
+{{end}}	"reflect"
+	"strings"
+)
+
+// casterDebugField is a target field checked by a debug caster, with the
+// source fields it is assigned from.
+type casterDebugField struct {
+	Name    string
+	Target  any
+	Sources []any
+}
+
+// casterDebugCheck reports the fields of a caster's result that stayed zero
+// although one of their sources is set, along with the fields it populated.
+func casterDebugCheck(caster string, fields []casterDebugField) {
+	var populated, zero []string
+
+	for _, f := range fields {
+		if !casterDebugIsZero(f.Target) {
+			populated = append(populated, f.Name)
+			continue
+		}
+
+		for _, s := range f.Sources {
+			if !casterDebugIsZero(s) {
+				zero = append(zero, f.Name)
+				break
+			}
+		}
+	}
+
+	if len(zero) == 0 {
+		return
+	}
+
+	if len(populated) == 0 {
+		populated = []string{"none"}
+	}
+
+	msg := fmt.Sprintf("%s: %s stayed zero although set in the source (populated: %s)",
+		caster, strings.Join(zero, ", "), strings.Join(populated, ", "))
+{{if .Panic}}	panic(msg){{else}}	log.Print(msg){{end}}
+}
+
+// casterDebugIsZero reports whether v is nil or the zero value of its type.
+func casterDebugIsZero(v any) bool {
+	rv := reflect.ValueOf(v)
+
+	return !rv.IsValid() || rv.IsZero()
+}
+`))
@@ -0,0 +1,185 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each hunk.
+	diffContext = 3
+	// maxDiffCells bounds the table diffing the changed lines of a file. Past
+	// it, they are replaced as a whole, which is correct but not minimal.
+	maxDiffCells = 1 << 22
+)
+
+// diffLine is a line of a diff: kept (' '), removed ('-') or added ('+').
+// Its text ends with a newline, unless it is the last line of a file that
+// doesn't.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// splitLines splits content after each newline.
+func splitLines(content []byte) []string {
+	var lines []string
+
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 {
+			end = len(content)
+		}
+
+		lines = append(lines, string(content[:end]))
+		content = content[end:]
+	}
+
+	return lines
+}
+
+// diffLines returns the edit script turning lines from into lines to,
+// removing and adding as few lines as possible.
+func diffLines(from, to []string) []diffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix &&
+		from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(from)+len(to)-prefix-suffix)
+	for _, text := range from[:prefix] {
+		lines = append(lines, diffLine{op: ' ', text: text})
+	}
+
+	lines = append(lines, diffChanged(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+
+	for _, text := range from[len(from)-suffix:] {
+		lines = append(lines, diffLine{op: ' ', text: text})
+	}
+
+	return lines
+}
+
+// diffChanged diffs lines by their longest common subsequence.
+func diffChanged(from, to []string) []diffLine {
+	var lines []diffLine
+
+	if len(from)*len(to) <= maxDiffCells {
+		// common[i*width+j] is the length of the longest common subsequence
+		// of from[i:] and to[j:].
+		width := len(to) + 1
+		common := make([]int32, (len(from)+1)*width)
+
+		for i := len(from) - 1; i >= 0; i-- {
+			for j := len(to) - 1; j >= 0; j-- {
+				if from[i] == to[j] {
+					common[i*width+j] = common[(i+1)*width+j+1] + 1
+				} else {
+					common[i*width+j] = max(common[(i+1)*width+j], common[i*width+j+1])
+				}
+			}
+		}
+
+		for len(from) > 0 && len(to) > 0 {
+			switch {
+			case from[0] == to[0]:
+				lines = append(lines, diffLine{op: ' ', text: from[0]})
+				from, to = from[1:], to[1:]
+			case common[width] >= common[1]:
+				lines = append(lines, diffLine{op: '-', text: from[0]})
+				from, common = from[1:], common[width:]
+			default:
+				lines = append(lines, diffLine{op: '+', text: to[0]})
+				to, common = to[1:], common[1:]
+			}
+		}
+	}
+
+	for _, text := range from {
+		lines = append(lines, diffLine{op: '-', text: text})
+	}
+
+	for _, text := range to {
+		lines = append(lines, diffLine{op: '+', text: text})
+	}
+
+	return lines
+}
+
+// writeHunks writes the changes of lines as unified diff hunks, merging
+// those less than two contexts apart.
+func writeHunks(w *strings.Builder, lines []diffLine) {
+	// oldLine and newLine count the lines of each side before lines[i].
+	oldLine, newLine := 0, 0
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			oldLine++
+			newLine++
+			i++
+
+			continue
+		}
+
+		start := max(i-diffContext, 0)
+		for _, line := range lines[start:i] {
+			if line.op == ' ' {
+				oldLine--
+				newLine--
+			}
+		}
+
+		last := i
+		for j := i + 1; j < len(lines) && j-last <= 2*diffContext+1; j++ {
+			if lines[j].op != ' ' {
+				last = j
+			}
+		}
+
+		end := min(last+diffContext+1, len(lines))
+
+		var oldCount, newCount int
+
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				oldCount++
+			}
+
+			if line.op != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+
+		for _, line := range lines[start:end] {
+			w.WriteByte(line.op)
+			w.WriteString(line.text)
+
+			if !strings.HasSuffix(line.text, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+}
+
+// hunkRange formats the range of a hunk side starting after line before.
+// Empty ranges name the line before them.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
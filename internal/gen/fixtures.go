@@ -0,0 +1,434 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// maxFixtureDepth bounds the nesting of structs in synthesized fixture values.
+const maxFixtureDepth = 4
+
+// fixtureReserved are the local names of generated benchmarks and tests,
+// which requires arguments can't take.
+var fixtureReserved = map[string]bool{
+	"b": true, "c": true, "ctx": true, "got": true, "i": true, "in": true, "out": true, "t": true, "want": true,
+}
+
+// casterFixture describes the benchmark and test of one caster, run on a
+// synthesized source value.
+type casterFixture struct {
+	Caster string
+	// Name ends the names of the benchmark and test, e.g. "Order_ToWarehouse"
+	// for the method ToWarehouse of Order.
+	Name       string
+	Call       string
+	SourceType string
+	TargetType string
+	// Fixture is the synthesized source value, or "" for its zero value.
+	Fixture     string
+	ExtraArgs   []extraArg
+	UsesContext bool
+	Methods     bool
+	// Checks are the target fields the test of the caster checks.
+	Checks []fieldCheck
+	// Skip is why the caster gets no benchmark or test, if it doesn't.
+	Skip string
+
+	pairKey    string
+	transforms []string
+	// imports are the packages of the fixture, checks and call; the
+	// packages of SourceType and TargetType are kept apart, since only
+	// benchmarks and zero-valued fixtures name those types.
+	imports       map[string]importSpec
+	sourceImports map[string]importSpec
+	targetImports map[string]importSpec
+	usesPtr       bool
+}
+
+// collectFixtureFields records the source fields whose fixture value is
+// imposed by their mappings: fields passed to transforms stay zero, since
+// transforms may reject arbitrary values, and fields of strict enum maps get
+// a listed value.
+func (g *Generator) collectFixtureFields(pairs []plan.ResolvedTypePair) {
+	g.fixtureFields = make(map[analyze.TypeID]map[string]string)
+
+	set := func(t analyze.TypeID, field, value string) {
+		if g.fixtureFields[t] == nil {
+			g.fixtureFields[t] = make(map[string]string)
+		}
+
+		if old, ok := g.fixtureFields[t][field]; !ok || old != "" {
+			g.fixtureFields[t][field] = value
+		}
+	}
+
+	for i := range pairs {
+		pair := &pairs[i]
+
+		for _, m := range pair.Mappings {
+			switch {
+			case m.Transform != "":
+				for _, sp := range m.SourcePaths {
+					if len(sp.Segments) > 0 {
+						set(pair.SourceType.ID, sp.Segments[0].Name, "")
+					}
+				}
+			case m.Strategy == plan.StrategyEnumMap && m.EnumStrict && len(m.EnumMap) > 0 &&
+				len(m.SourcePaths) == 1 && m.SourcePaths[0].IsSimple():
+				srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+				if lit, err := mapping.EnumLiteral(mapping.SortedEnumKeys(m.EnumMap)[0], srcType); err == nil {
+					set(pair.SourceType.ID, m.SourcePaths[0].Segments[0].Name, lit)
+				}
+			}
+		}
+	}
+}
+
+// fieldCheck is the expected value of a target field.
+type fieldCheck struct {
+	Target string
+	Want   string
+	// Deep compares with reflect.DeepEqual, for values != can't compare.
+	Deep bool
+}
+
+// recordFixture adds the fixture of the caster of data.
+func (g *Generator) recordFixture(data *templateData, pair *plan.ResolvedTypePair) {
+	fx := casterFixture{
+		Caster:      data.FunctionName,
+		Name:        data.FunctionName,
+		SourceType:  data.SourceType.String(),
+		TargetType:  data.TargetType.String(),
+		ExtraArgs:   data.ExtraArgs,
+		UsesContext: data.UsesContext,
+		Methods:     g.methods(),
+		pairKey:     fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID),
+		imports:     make(map[string]importSpec),
+
+		sourceImports: make(map[string]importSpec),
+		targetImports: make(map[string]importSpec),
+	}
+
+	if data.SourceMethod {
+		fx.Caster = pair.SourceType.ID.Name + "." + data.FunctionName
+		fx.Name = pair.SourceType.ID.Name + "_" + data.FunctionName
+	}
+
+	for _, m := range pair.Mappings {
+		if m.Transform != "" {
+			fx.transforms = append(fx.transforms, m.Transform)
+		}
+	}
+
+	if data.TypeParams != "" {
+		fx.Skip = "it has type parameters"
+	}
+
+	for _, arg := range data.ExtraArgs {
+		switch {
+		case fixtureReserved[arg.Name]:
+			fx.Skip = fmt.Sprintf("requires argument %s shadows a variable of the generated code", arg.Name)
+		case strings.Contains(arg.Type, "."):
+			fx.Skip = fmt.Sprintf("requires argument %s has a package-qualified type", arg.Name)
+		}
+	}
+
+	if fx.Skip == "" {
+		g.addImport(fx.sourceImports, pair.SourceType.ID.PkgPath)
+		g.typeArgsString(pair.SourceType, fx.sourceImports)
+
+		if data.TargetType.Package != "" {
+			g.addImport(fx.targetImports, pair.TargetType.ID.PkgPath)
+		}
+
+		g.typeArgsString(pair.TargetType, fx.targetImports)
+
+		var args []string
+
+		if data.UsesContext {
+			fx.imports["context"] = importSpec{Path: "context"}
+			args = append(args, "ctx")
+		}
+
+		if !data.SourceMethod {
+			args = append(args, "in")
+		}
+
+		for _, arg := range data.ExtraArgs {
+			args = append(args, arg.Name)
+		}
+
+		fx.Call = fmt.Sprintf("%s(%s)", data.FunctionName, strings.Join(args, ", "))
+
+		switch {
+		case fx.Methods:
+			fx.Call = methodsReceiver + "." + fx.Call
+		case data.SourceMethod:
+			fx.Call = "in." + fx.Call
+		}
+
+		fx.Fixture = g.fixtureValue(pair.SourceType, "in", &fx, 0, make(map[analyze.TypeID]bool))
+
+		if g.config.Tests {
+			fx.Checks = g.fieldChecks(pair, &fx)
+		}
+	}
+
+	g.fixtures = append(g.fixtures, fx)
+}
+
+// fixtureValue returns an expression of a non-zero value of t, or "" when its
+// zero value is used: for interfaces, type parameters, structs without
+// exported fields, and structs nested too deep or within themselves. name
+// seeds string values.
+func (g *Generator) fixtureValue(
+	t *analyze.TypeInfo,
+	name string,
+	fx *casterFixture,
+	depth int,
+	visiting map[analyze.TypeID]bool,
+) string {
+	if t == nil || depth > maxFixtureDepth || t.IsInterface() {
+		return ""
+	}
+
+	switch t.Kind {
+	case analyze.TypeKindBasic:
+		return basicFixture(t.ID.Name, name)
+
+	case analyze.TypeKindAlias:
+		if t.Underlying == nil || t.Underlying.Kind == analyze.TypeKindStruct {
+			return ""
+		}
+
+		value := g.fixtureValue(t.Underlying, name, fx, depth, visiting)
+		if value == "" {
+			return ""
+		}
+
+		return g.typeRefString(t, fx.imports) + "(" + value + ")"
+
+	case analyze.TypeKindStruct:
+		if !t.IsNamed() || visiting[t.ID] {
+			return ""
+		}
+
+		visiting[t.ID] = true
+		defer delete(visiting, t.ID)
+
+		var fields []string
+
+		for _, f := range t.Fields {
+			if !f.Exported || f.Index < 0 {
+				continue
+			}
+
+			value, imposed := g.fixtureFields[t.ID][f.Name]
+			if !imposed {
+				value = g.fixtureValue(f.Type, f.Name, fx, depth+1, visiting)
+			}
+
+			if value != "" {
+				fields = append(fields, f.Name+": "+value)
+			}
+		}
+
+		if len(fields) == 0 {
+			return ""
+		}
+
+		return g.typeRefString(t, fx.imports) + "{" + strings.Join(fields, ", ") + "}"
+
+	case analyze.TypeKindPointer:
+		value := g.fixtureValue(t.ElemType, name, fx, depth, visiting)
+		if value == "" {
+			return ""
+		}
+
+		if t.ElemType.Kind == analyze.TypeKindStruct {
+			return "&" + value
+		}
+
+		fx.usesPtr = true
+
+		return fmt.Sprintf("fixturePtr[%s](%s)", g.typeRefString(t.ElemType, fx.imports), value)
+
+	case analyze.TypeKindSlice, analyze.TypeKindArray:
+		value := g.fixtureValue(t.ElemType, name, fx, depth, visiting)
+		if value == "" {
+			return ""
+		}
+
+		return g.typeRefString(t, fx.imports) + "{" + g.elideElemType(value, t.ElemType, fx) + "}"
+
+	case analyze.TypeKindMap:
+		key := g.fixtureValue(t.KeyType, name, fx, depth, visiting)
+		value := g.fixtureValue(t.ElemType, name, fx, depth, visiting)
+
+		if key == "" || value == "" {
+			return ""
+		}
+
+		return g.typeRefString(t, fx.imports) + "{" +
+			g.elideElemType(key, t.KeyType, fx) + ": " + g.elideElemType(value, t.ElemType, fx) + "}"
+
+	default:
+		return ""
+	}
+}
+
+// elideElemType drops the type of a composite literal element of type t, as
+// gofmt -s does (e.g., "{Name: 1}" for "store.Item{Name: 1}" or
+// "&store.Item{Name: 1}").
+func (g *Generator) elideElemType(value string, t *analyze.TypeInfo, fx *casterFixture) string {
+	if t.Kind == analyze.TypeKindPointer {
+		rest, ok := strings.CutPrefix(value, "&")
+		if !ok {
+			return value
+		}
+
+		value, t = rest, t.ElemType
+	}
+
+	if t.Kind != analyze.TypeKindStruct {
+		return value
+	}
+
+	if rest, ok := strings.CutPrefix(value, g.typeRefString(t, fx.imports)+"{"); ok {
+		return "{" + rest
+	}
+
+	return value
+}
+
+// basicFixture returns a non-zero literal of a basic type, or "" for complex
+// and unsafe types.
+func basicFixture(basic, name string) string {
+	switch {
+	case basic == "string":
+		return strconv.Quote(name)
+	case basic == "bool":
+		return "true"
+	case strings.HasPrefix(basic, "int"), strings.HasPrefix(basic, "uint"), basic == "byte", basic == "rune":
+		return "1"
+	case strings.HasPrefix(basic, "float"):
+		return "1.5"
+	default:
+		return ""
+	}
+}
+
+// fieldChecks returns the checks of the top-level target fields of pair
+// whose value follows from their source field alone: direct copies,
+// conversions between basic types, and literal defaults. Fields assigned
+// under a when condition aren't checked, as the fixture may not satisfy it.
+func (g *Generator) fieldChecks(pair *plan.ResolvedTypePair, fx *casterFixture) []fieldCheck {
+	var checks []fieldCheck
+
+	for _, m := range pair.Mappings {
+		if len(m.TargetPaths) != 1 || len(m.TargetPaths[0].Segments) != 1 || m.PendingSource != "" || m.When != "" {
+			continue
+		}
+
+		target := m.TargetPaths[0].String()
+		dstType := g.getFieldTypeInfo(pair.TargetType, target)
+
+		var srcType *analyze.TypeInfo
+
+		source := ""
+		if len(m.SourcePaths) == 1 && len(m.SourcePaths[0].Segments) == 1 && !m.SourcePaths[0].IsKeyed() {
+			source = m.SourcePaths[0].String()
+			srcType = g.getFieldTypeInfo(pair.SourceType, source)
+		}
+
+		switch {
+		case dstType == nil:
+			continue
+
+		case m.Strategy == plan.StrategyDirectAssign && srcType != nil && !m.NilToEmpty && m.DedupBy == "":
+			if isBasicValue(srcType) && isBasicValue(dstType) {
+				checks = append(checks, fieldCheck{Target: target, Want: "in." + source})
+			} else if g.typeRefString(srcType, nil) == g.typeRefString(dstType, nil) {
+				checks = append(checks, fieldCheck{Target: target, Want: "in." + source, Deep: true})
+			}
+
+		case m.Strategy == plan.StrategyConvert && srcType != nil && isBasicValue(srcType) && isBasicValue(dstType):
+			checks = append(checks, fieldCheck{
+				Target: target,
+				Want:   g.typeRefString(dstType, fx.imports) + "(in." + source + ")",
+			})
+
+		case m.Strategy == plan.StrategyDefault && m.Default != nil && isBasicValue(dstType) && isLiteral(*m.Default):
+			checks = append(checks, fieldCheck{
+				Target: target,
+				Want:   g.typeRefString(dstType, fx.imports) + "(" + *m.Default + ")",
+			})
+		}
+	}
+
+	return checks
+}
+
+// isBasicValue reports whether t is a basic type, or a named type of one,
+// whose values compare with ==.
+func isBasicValue(t *analyze.TypeInfo) bool {
+	if t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	return t.Kind == analyze.TypeKindBasic
+}
+
+// isLiteral reports whether expr is a constant literal, possibly negated,
+// rather than an expression that may vary between calls.
+func isLiteral(expr string) bool {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return false
+	}
+
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		e = u.X
+	}
+
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return e.Name == "true" || e.Name == "false"
+	default:
+		return false
+	}
+}
+
+// stubCallers returns the pairs whose casters call transform stubs, which
+// panic, directly or through nested casters.
+func (g *Generator) stubCallers(p *plan.ResolvedMappingPlan) map[string]bool {
+	stubs := make(map[string]bool)
+
+	for _, fx := range g.fixtures {
+		for _, t := range fx.transforms {
+			if _, missing := g.missingTransforms[t]; missing {
+				stubs[fx.pairKey] = true
+			}
+		}
+	}
+
+	// Casters come after the casters they call.
+	for _, entry := range plan.BuildManifest(p).Casters {
+		stubs[entry.Pair] = stubs[entry.Pair] || slices.ContainsFunc(entry.DependsOn, func(dep string) bool {
+			return stubs[dep]
+		})
+	}
+
+	return stubs
+}
@@ -2,14 +2,17 @@ package gen
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"go/format"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
 
 	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
 	"caster-generator/internal/plan"
 )
 
@@ -26,6 +29,43 @@ type GeneratorConfig struct {
 	// DeclaredTransforms is a set of transform names declared in the mapping file.
 	// Transforms in this set won't have stubs generated.
 	DeclaredTransforms map[string]bool
+	// DeepCopy clones slices, maps and pointers assigned from the source instead
+	// of sharing them when the mapping file sets no copy_mode. Mappings and
+	// fields with an explicit copy mode override it.
+	DeepCopy bool
+	// SingleFile, when set, is the name of the one file that receives all casters,
+	// helpers and transform stubs of the output package instead of a file per pair.
+	SingleFile string
+	// GenericRequires gives untyped requires arguments that are only passed
+	// through to transforms and nested casters a type parameter on the caster
+	// instead of interface{}.
+	GenericRequires bool
+	// Style selects how casters are emitted: as package-level functions
+	// (StyleFunctions, the default) or as methods of a generated Casters type
+	// calling transforms through an injectable Transforms interface (StyleMethods).
+	Style string
+	// Kept holds the functions marked with KeepMarker in the previous output,
+	// keyed by name (see LoadKeptFuncs). They replace the functions generated
+	// with the same name and signature.
+	Kept map[string]KeptFunc
+	// Benchmarks adds a _test.go file benchmarking each caster on a
+	// synthesized source value.
+	Benchmarks bool
+	// Tests adds a _test.go file running each caster on a synthesized source
+	// value and checking the target fields copied, converted or defaulted.
+	Tests bool
+	// DebugCasters, when set to DebugLog or DebugPanic, adds a variant of each
+	// caster file built with the DebugBuildTag tag, whose casters log or panic
+	// on target fields left zero although their source is set.
+	DebugCasters string
+	// FuncTemplate is the naming template of casters and nested casters (see
+	// mapping.FuncName), overriding the one of the mapping file.
+	FuncTemplate string
+	// Overrides gives each caster a final variadic parameter of functions
+	// applied to its result before it is returned (overrides ...func(*T)),
+	// so call sites can post-process mapped values without editing the
+	// generated files.
+	Overrides bool
 }
 
 // DefaultGeneratorConfig returns the default generator configuration.
@@ -51,9 +91,99 @@ type Generator struct {
 	// Key is the directory path.
 	missingTypes map[string][]MissingTypeInfo
 
+	// declaredTargets is the set of generated target types already declared,
+	// so targets generated from several sources are declared once.
+	declaredTargets map[analyze.TypeID]bool
+
+	// aliases holds the import aliases of the packages whose names collide,
+	// keyed by package path.
+	aliases map[string]string
+
 	// contextPkgPath is the package path currently being generated into.
 	// Used to suppress package prefixes for types in the same package.
 	contextPkgPath string
+
+	// idents allocates loop and temporary variable names of the assignment
+	// currently being generated.
+	idents *identScope
+
+	// ctxTransforms is the set of transforms that take a context.Context first argument.
+	ctxTransforms map[string]bool
+	// ctxPairs is the set of type pair keys whose casters take a ctx argument.
+	ctxPairs map[string]bool
+
+	// templateTransforms holds the transforms declared with a template, keyed
+	// by transform name, and templateErr the first error executing one.
+	templateTransforms map[string]templateTransform
+	templateErr        error
+
+	// memoTransforms is the set of transforms declared with memoize: true.
+	memoTransforms map[string]bool
+	// memoized stores the memoized transforms actually used, keyed by signature.
+	memoized map[string]memoizedTransformInfo
+
+	// clones stores the copy helpers used, keyed by helper name.
+	clones map[string]cloneSpec
+	// copyMode is the plan-wide copy mode applied when neither the field nor
+	// its mapping chooses one.
+	copyMode mapping.CopyMode
+
+	// wrappers holds the generic wrapper declarations of the plan.
+	wrappers []mapping.WrapperDef
+	// implementations holds the interface implementation pairs of the plan.
+	implementations []mapping.ImplementationDef
+
+	// casterMethods lists the casters of the CastersAPI interface in the methods style.
+	casterMethods []casterMethod
+	// transformMethods stores the transforms called through the Transforms
+	// interface in the methods style, keyed by transform name.
+	transformMethods map[string]transformMethod
+	// methodImports collects the imports of the casters file.
+	methodImports map[string]importSpec
+
+	// fixtures describes the benchmark and test of each caster when Benchmarks
+	// or Tests is set.
+	fixtures []casterFixture
+	// fixtureFields imposes the fixture values of source fields, by type and
+	// field name; "" leaves a field zero (see collectFixtureFields).
+	fixtureFields map[analyze.TypeID]map[string]string
+
+	// debugVariants holds the debug variant of each caster file when
+	// DebugCasters is set, keyed by the caster file name.
+	debugVariants map[string]GeneratedFile
+
+	// funcTemplate names casters, parsed by checkNaming.
+	funcTemplate *template.Template
+
+	// foreign holds the casters generated into other output packages, keyed
+	// by type pair; it is nil unless generating one of several packages.
+	foreign map[string]foreignCaster
+	// foreignImports collects the output packages the current caster calls.
+	foreignImports map[string]importSpec
+
+	// depthCycles holds the depth-limited recursive cycle of each pair in
+	// one, and depthLimits the limit of each cycle (see recursionCycles).
+	depthCycles map[string]string
+	depthLimits map[string]int
+	// casterKey is the key of the pair whose caster is being generated, and
+	// recursed is set when one of its assignments calls a caster of its cycle.
+	casterKey string
+	recursed  bool
+
+	// inlinePairs holds the conversions between anonymous structs, generated
+	// inline, keyed by type pair (see collectInlinePairs).
+	inlinePairs map[string]*plan.ResolvedTypePair
+
+	// outputPkgPath is the import path of the analyzed package generated
+	// into, if any; its declarations are referred to unqualified.
+	outputPkgPath string
+
+	// casters registers the type pairs whose casters are generated, keyed by
+	// type pair, so that each caster is defined by a single file.
+	casters map[string]bool
+	// sourceMethods holds the methods of source types casters are generated
+	// as, keyed by type pair.
+	sourceMethods map[string]string
 }
 
 // MissingTransformInfo represents a missing transform function info.
@@ -96,11 +226,100 @@ type GeneratedFile struct {
 func (g *Generator) Generate(p *plan.ResolvedMappingPlan) ([]GeneratedFile, error) {
 	g.graph = p.TypeGraph
 
+	p, err := g.registerCasters(p)
+	if err != nil {
+		return nil, err
+	}
+
+	g.aliases = g.pkgAliases(p)
+
+	if g.foreign == nil && hasOutputs(p.TypePairs) {
+		return g.generateOutputs(p)
+	}
+
+	if err := g.checkStyle(p); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkDebugCasters(); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkOverrides(p); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkNaming(p); err != nil {
+		return nil, err
+	}
+
+	outputPkgPath, err := g.outputPackage()
+	if err != nil {
+		return nil, err
+	}
+
+	g.outputPkgPath = outputPkgPath
+
+	if err := g.checkUnexported(p); err != nil {
+		return nil, err
+	}
+
 	var files []GeneratedFile
 
 	// Reset missing transforms for this run
 	g.missingTransforms = make(map[string]MissingTransformInfo)
 	g.missingTypes = make(map[string][]MissingTypeInfo)
+	g.declaredTargets = make(map[analyze.TypeID]bool)
+
+	// Collect context-aware transforms and casters
+	g.ctxTransforms = make(map[string]bool)
+	g.ctxPairs = make(map[string]bool)
+	g.memoTransforms = make(map[string]bool)
+	g.templateErr = nil
+	g.memoized = make(map[string]memoizedTransformInfo)
+	g.clones = make(map[string]cloneSpec)
+	g.casterMethods = nil
+	g.transformMethods = make(map[string]transformMethod)
+	g.methodImports = make(map[string]importSpec)
+	g.fixtures = nil
+	g.debugVariants = make(map[string]GeneratedFile)
+	g.copyMode = p.CopyMode.Or(mapping.CopyAlias)
+
+	if g.config.DeepCopy {
+		g.copyMode = p.CopyMode.Or(mapping.CopyDeep)
+	}
+
+	g.wrappers = p.Wrappers
+	g.implementations = p.Implementations
+
+	// Declared transforms replace the builtin money transforms of the same name
+	g.templateTransforms, err = loadTemplateTransforms(slices.Concat(p.Currency.Transforms(), p.OriginalTransforms))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range p.OriginalTransforms {
+		if t.Ctx {
+			g.ctxTransforms[t.Name] = true
+		}
+
+		if t.Memoize {
+			g.memoTransforms[t.Name] = true
+		}
+	}
+
+	for _, pair := range p.TypePairs {
+		if pair.NeedsContext {
+			g.ctxPairs[fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)] = true
+		}
+	}
+
+	g.depthCycles, g.depthLimits = recursionCycles(p.TypePairs)
+	g.inlinePairs = collectInlinePairs(p.TypePairs)
+
+	if g.config.Benchmarks || g.config.Tests {
+		g.collectFixtureFields(p.TypePairs)
+	}
 
 	for _, pair := range p.TypePairs {
 		file, err := g.generateTypePair(&pair)
@@ -112,6 +331,16 @@ func (g *Generator) Generate(p *plan.ResolvedMappingPlan) ([]GeneratedFile, erro
 		files = append(files, *file)
 	}
 
+	// Generate the Casters type bundling the caster methods
+	if g.methods() && len(g.casterMethods) > 0 {
+		file, err := g.generateCastersFile()
+		if err != nil {
+			return nil, fmt.Errorf("generating casters: %w", err)
+		}
+
+		files = append(files, *file)
+	}
+
 	// Generate missing transforms file if needed
 	if len(g.missingTransforms) > 0 {
 		file, err := g.generateMissingTransformsFile()
@@ -122,6 +351,87 @@ func (g *Generator) Generate(p *plan.ResolvedMappingPlan) ([]GeneratedFile, erro
 		files = append(files, *file)
 	}
 
+	// Generate memoized transform wrappers if needed
+	if len(g.memoized) > 0 {
+		file, err := g.generateMemoizedTransformsFile()
+		if err != nil {
+			return nil, fmt.Errorf("generating memoized transforms: %w", err)
+		}
+
+		files = append(files, *file)
+	}
+
+	// Generate copy helpers if needed
+	if len(g.clones) > 0 {
+		file, err := g.generateCloneHelpersFile()
+		if err != nil {
+			return nil, fmt.Errorf("generating clone helpers: %w", err)
+		}
+
+		files = append(files, *file)
+	}
+
+	var debugFiles []GeneratedFile
+
+	if len(g.debugVariants) > 0 {
+		var err error
+
+		debugFiles, err = g.debugVariantFiles(files, g.debugVariants)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if g.config.SingleFile != "" && len(files) > 0 {
+		file, err := g.mergeFiles(g.config.SingleFile, files)
+		if err != nil {
+			return nil, fmt.Errorf("merging into %s: %w", g.config.SingleFile, err)
+		}
+
+		files = []GeneratedFile{*file}
+	}
+
+	// Keep hand-edited functions of the previous output, in debug variants too
+	casterFiles := len(files)
+	files = append(files, debugFiles...)
+
+	if err := g.applyKept(files); err != nil {
+		return nil, err
+	}
+
+	// Debug variants replace their caster files in debug builds
+	if len(debugFiles) > 0 {
+		for i := range files {
+			_, replaced := g.debugVariants[files[i].Filename]
+
+			switch {
+			case i >= casterFiles:
+				files[i].Content = withBuildTag(files[i].Content, DebugBuildTag)
+			case replaced || g.config.SingleFile != "":
+				files[i].Content = withBuildTag(files[i].Content, "!"+DebugBuildTag)
+			}
+		}
+	}
+
+	// Benchmarks and tests are test code, so never merged into the single file
+	if g.config.Benchmarks && len(g.fixtures) > 0 {
+		file, err := g.generateBenchmarksFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("generating benchmarks: %w", err)
+		}
+
+		files = append(files, *file)
+	}
+
+	if g.config.Tests && len(g.fixtures) > 0 {
+		file, err := g.generateTestsFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("generating tests: %w", err)
+		}
+
+		files = append(files, *file)
+	}
+
 	// Generate missing types files
 	if len(g.missingTypes) > 0 {
 		missingFiles, err := g.generateMissingTypesFiles()
@@ -132,13 +442,96 @@ func (g *Generator) Generate(p *plan.ResolvedMappingPlan) ([]GeneratedFile, erro
 		files = append(files, missingFiles...)
 	}
 
+	if g.outputPkgPath != "" {
+		if err := g.unqualifyOutputPackage(files); err != nil {
+			return nil, err
+		}
+	}
+
+	g.recordOptions(files)
+	normalizeFiles(files)
+
 	return files, nil
 }
 
+// registerCasters returns p with the pairs whose casters are generated: its
+// pairs once each, followed by its implicit pairs, which no mapping declares
+// but other casters call, placed in their output packages. Output packages
+// get their pairs placed by the plan of all of them.
+func (g *Generator) registerCasters(p *plan.ResolvedMappingPlan) (*plan.ResolvedMappingPlan, error) {
+	g.casters = make(map[string]bool, len(p.TypePairs))
+	g.sourceMethods = make(map[string]string)
+
+	pairs := p.TypePairs
+	if g.foreign == nil {
+		pairs = append(slices.Clone(pairs), p.ImplicitPairs()...)
+	}
+
+	registered := *p
+	registered.TypePairs = make([]plan.ResolvedTypePair, 0, len(pairs))
+
+	for _, pair := range pairs {
+		key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+		if g.casters[key] {
+			continue
+		}
+
+		g.casters[key] = true
+		registered.TypePairs = append(registered.TypePairs, pair)
+
+		if pair.Method != "" {
+			g.sourceMethods[key] = pair.Method
+		}
+	}
+
+	if g.foreign != nil {
+		return &registered, nil
+	}
+
+	if err := g.placeCasters(registered.TypePairs); err != nil {
+		return nil, err
+	}
+
+	if err := g.checkSourceMethods(&registered); err != nil {
+		return nil, err
+	}
+
+	return &registered, nil
+}
+
 // generateTypePair generates code for a single type pair.
 func (g *Generator) generateTypePair(pair *plan.ResolvedTypePair) (*GeneratedFile, error) {
+	if err := g.checkMerge(pair); err != nil {
+		return nil, err
+	}
+
 	data := g.buildTemplateData(pair)
+	if g.templateErr != nil {
+		return nil, g.templateErr
+	}
+
+	file, err := g.renderCaster(data)
+	if err != nil || g.config.DebugCasters == "" {
+		return file, err
+	}
+
+	// The debug variant is the same file checking fields before returning
+	variant := *data
+	variant.Filename = debugFilename(data.Filename)
+	variant.DebugChecks = g.debugChecks(pair)
+
+	debugFile, err := g.renderCaster(&variant)
+	if err != nil {
+		return nil, fmt.Errorf("debug variant: %w", err)
+	}
+
+	g.debugVariants[data.Filename] = *debugFile
 
+	return file, nil
+}
+
+// renderCaster executes the caster template on data and formats the result.
+func (g *Generator) renderCaster(data *templateData) (*GeneratedFile, error) {
 	var buf bytes.Buffer
 	if err := casterTemplate.Execute(&buf, data); err != nil {
 		return nil, fmt.Errorf("executing template: %w", err)
@@ -236,15 +629,29 @@ func (g *Generator) generateMissingTransformsFile() (*GeneratedFile, error) {
 	}, nil
 }
 
-// generateMissingTypesFiles generates missing_types.go files in respective directories.
+// generateMissingTypesFiles generates missing_types.go files in respective directories,
+// ordered by directory, with struct definitions ordered by type name.
 func (g *Generator) generateMissingTypesFiles() ([]GeneratedFile, error) {
 	var files []GeneratedFile
 
-	for dir, infos := range g.missingTypes {
+	dirs := make([]string, 0, len(g.missingTypes))
+	for dir := range g.missingTypes {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		infos := g.missingTypes[dir]
 		if len(infos) == 0 {
 			continue
 		}
 
+		// Definitions start with "type Name struct", so this orders them by name.
+		sort.SliceStable(infos, func(i, j int) bool {
+			return infos[i].StructDef < infos[j].StructDef
+		})
+
 		// Group import specs
 		imports := make(map[string]importSpec)
 
@@ -303,7 +710,14 @@ func (g *Generator) generateMissingTypesFiles() ([]GeneratedFile, error) {
 			return nil, fmt.Errorf("formatting missing types code for %s: %w", dir, err)
 		}
 
-		relPath, relErr := filepath.Rel(g.config.OutputDir, dir)
+		var relPath string
+
+		// Package directories are absolute, so compare against the absolute output dir.
+		outDir, relErr := filepath.Abs(g.config.OutputDir)
+		if relErr == nil {
+			relPath, relErr = filepath.Rel(outDir, dir)
+		}
+
 		if relErr != nil {
 			// Fallback to absolute path and hope caller handles it or Writer is updated
 			relPath = filepath.Join(dir, "missing_types.go")
@@ -343,8 +757,8 @@ func (g *Generator) addMissingType(dir, pkgName, structDef string, imports []imp
 // Helper functions for naming
 
 func (g *Generator) filename(pair *plan.ResolvedTypePair) string {
-	src := strings.ToLower(pair.SourceType.ID.Name)
-	tgt := strings.ToLower(pair.TargetType.ID.Name)
+	src := strings.ToLower(pair.SourceType.ID.Name + g.typeArgsKey(pair.SourceType))
+	tgt := strings.ToLower(pair.TargetType.ID.Name + g.typeArgsKey(pair.TargetType))
 	srcPkg := g.getPkgName(pair.SourceType.ID.PkgPath)
 	tgtPkg := g.getPkgName(pair.TargetType.ID.PkgPath)
 
@@ -357,6 +771,12 @@ func (g *Generator) filename(pair *plan.ResolvedTypePair) string {
 }
 
 func (g *Generator) functionName(pair *plan.ResolvedTypePair) string {
+	return g.casterName(pair.SourceType, pair.TargetType, pair.IsGeneratedTarget)
+}
+
+// pairNames returns the identifier fragments naming the source and target
+// types of a pair in its functions (e.g., "StoreOrder" and "WarehouseOrder").
+func (g *Generator) pairNames(pair *plan.ResolvedTypePair) (string, string) {
 	srcPkg := g.capitalize(g.getPkgName(pair.SourceType.ID.PkgPath))
 	tgtPkg := g.capitalize(g.getPkgName(pair.TargetType.ID.PkgPath))
 
@@ -365,21 +785,81 @@ func (g *Generator) functionName(pair *plan.ResolvedTypePair) string {
 		tgtPkg = g.capitalize(g.config.PackageName)
 	}
 
-	return fmt.Sprintf("%s%sTo%s%s",
-		srcPkg, pair.SourceType.ID.Name,
-		tgtPkg, pair.TargetType.ID.Name)
+	return srcPkg + pair.SourceType.ID.Name + g.typeArgsKey(pair.SourceType),
+		tgtPkg + pair.TargetType.ID.Name + g.typeArgsKey(pair.TargetType)
 }
 
 func (g *Generator) nestedFunctionName(src, tgt *analyze.TypeInfo) string {
-	srcPkg := g.capitalize(g.getPkgName(src.ID.PkgPath))
-	tgtPkg := g.capitalize(g.getPkgName(tgt.ID.PkgPath))
+	return g.casterName(src, tgt, tgt.IsGenerated)
+}
 
-	// For generated targets with no package path, use the output package name
-	if tgtPkg == "" && tgt.IsGenerated {
-		tgtPkg = g.capitalize(g.config.PackageName)
+// typeArgsKey returns an identifier fragment naming the type arguments of an
+// instantiated generic type (e.g., "StoreOrder" for Page[store.Order]), so
+// casters of different instantiations get distinct names.
+func (g *Generator) typeArgsKey(t *analyze.TypeInfo) string {
+	var sb strings.Builder
+
+	for _, arg := range t.TypeArgs {
+		sb.WriteString(g.cloneTypeKey(arg))
+	}
+
+	return sb.String()
+}
+
+// nestedCall builds a call to the nested caster for src->tgt with the given arguments,
+// prepending ctx when that caster is context-aware. Empty arguments are skipped.
+// In the methods style the caster is called on the receiver. Conversions
+// between anonymous structs are inlined instead.
+func (g *Generator) nestedCall(src, tgt *analyze.TypeInfo, args ...string) string {
+	var callArgs []string
+
+	key := fmt.Sprintf("%s->%s", src.ID, tgt.ID)
+	if pair, ok := g.inlinePairs[key]; ok && len(args) > 0 {
+		return g.inlineCall(pair, args[0])
+	}
+
+	ref, foreign := g.foreign[key]
+	recurses := !foreign && g.recurses(key)
+
+	// Casters generated as methods are called on their source value, except
+	// by the depth-limited function of a cycle
+	method := cmp.Or(g.sourceMethods[key], ref.method)
+	if recurses || len(args) == 0 || args[0] == "" {
+		method = ""
 	}
 
-	return fmt.Sprintf("%s%sTo%s%s", srcPkg, src.ID.Name, tgtPkg, tgt.ID.Name)
+	if g.ctxPairs[key] || ref.ctx {
+		callArgs = append(callArgs, "ctx")
+	}
+
+	for i, a := range args {
+		if a != "" && (i > 0 || method == "") {
+			callArgs = append(callArgs, a)
+		}
+	}
+
+	if method != "" {
+		return methodCall(args[0], method, callArgs)
+	}
+
+	fn := g.nestedFunctionName(src, tgt)
+
+	// Casters of a depth-limited cycle call each other one level deeper
+	if recurses {
+		fn = depthFunctionName(fn)
+		callArgs = append(callArgs, "depth+1")
+		g.recursed = true
+	}
+
+	switch {
+	case g.methods():
+		fn = methodsReceiver + "." + fn
+	case foreign:
+		fn = ref.pkgName + "." + fn
+		g.foreignImports[ref.importPath] = importSpec{Alias: ref.pkgName, Path: ref.importPath}
+	}
+
+	return fmt.Sprintf("%s(%s)", fn, strings.Join(callArgs, ", "))
 }
 
 func (g *Generator) capitalize(s string) string {
@@ -390,6 +870,29 @@ func (g *Generator) capitalize(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
+// overridesParam names the parameter of the functions applied to the result
+// of casters with GeneratorConfig.Overrides.
+const overridesParam = "overrides"
+
+// checkOverrides fails if a requires argument would collide with the
+// overrides parameter.
+func (g *Generator) checkOverrides(p *plan.ResolvedMappingPlan) error {
+	if !g.config.Overrides {
+		return nil
+	}
+
+	for _, pair := range p.TypePairs {
+		for _, req := range pair.Requires {
+			if req.Name == overridesParam {
+				return fmt.Errorf("%s->%s: requires argument %q is the parameter of overrides",
+					pair.SourceType.ID, pair.TargetType.ID, req.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Templates
 
 var casterTemplate = template.Must(template.New("caster").Parse(`// Code generated by caster-generator. DO NOT EDIT.
@@ -405,26 +908,57 @@ import (
 // Generated target type
 {{.StructDef}}
 {{end}}
-// {{.FunctionName}} converts {{.SourceType}} to {{.TargetType}}.
-func {{.FunctionName}}(in {{.SourceType}}{{range .ExtraArgs}}, {{.Name}} {{.Type}}{{end}}) {{.TargetType}} {
-	out := {{.TargetType}}{}
+{{if .DepthLimit}}// {{.FunctionName}} converts {{.SourceType}} to {{.TargetType}}, converting at most {{.DepthLimit}} levels of
+// recursive fields{{if .Overrides}}, then applies overrides to the result{{end}}.
+func {{if .Receiver}}({{.Receiver}}) {{else if .SourceMethod}}(in {{.SourceType}}) {{end}}{{.FunctionName}}{{.TypeParams}}({{template "casterParams" .}}) {{.TargetType}} {
+{{if .Overrides}}	out := {{.DepthCall}}({{if .UsesContext}}ctx, {{end}}in{{range .ExtraArgs}}, {{.Name}}{{end}}, 0)
+{{template "applyOverrides"}}
+	return out
+{{else}}	return {{.DepthCall}}({{if .UsesContext}}ctx, {{end}}in{{range .ExtraArgs}}, {{.Name}}{{end}}, 0)
+{{end}}}
+
+// {{.DepthFunctionName}} converts {{.SourceType}} to {{.TargetType}} at the given depth of recursion.
+func {{if .Receiver}}({{.Receiver}}) {{end}}{{.DepthFunctionName}}{{.TypeParams}}({{if .UsesContext}}ctx context.Context, {{end}}in {{.SourceType}}{{range .ExtraArgs}}, {{.Name}} {{.Type}}{{end}}, depth int) {{.TargetType}} {
+{{else}}// {{.FunctionName}} converts {{.SourceType}} to {{.TargetType}}{{if .Overrides}}, then applies overrides to the result{{end}}.
+func {{if .Receiver}}({{.Receiver}}) {{else if .SourceMethod}}(in {{.SourceType}}) {{end}}{{.FunctionName}}{{.TypeParams}}({{template "casterParams" .}}) {{.TargetType}} {
+{{end}}{{if .BeforeHook}}	in = {{.BeforeHook}}(in)
+
+{{end}}{{if .ViaCall}}{{if or .Overrides .AfterHook}}	out := {{.ViaCall}}
+{{if .AfterHook}}	{{.AfterHook}}(in, &out)
+{{end}}{{if .Overrides}}{{template "applyOverrides"}}
+{{end}}
+	return out
+{{else}}	return {{.ViaCall}}
+{{end}}}
+{{else}}	out := {{.TargetType}}{}
 {{range .Assignments}}
-{{if .Comment}}	// {{.Comment}}
-{{end}}{{if .IsSlice}}	{{.SliceBody}}
-{{else if .IsMap}}	{{.MapBody}}
-{{else if .NeedsNilCheck}}	if ({{if .NilCheckExpr}}{{.NilCheckExpr}}{{else}}{{.SourceExpr}}{{end}}) != nil {
-		{{.TargetField}} = {{.SourceExpr}}
-	} else {
-		{{.TargetField}} = {{.NilDefault}}
-	}
-{{else}}	{{.TargetField}} = {{.SourceExpr}}
-{{end}}{{end}}
+{{template "assignment" .}}{{end}}
 {{if .UnmappedTODOs}}
 {{range .UnmappedTODOs}}	// {{.}}
-{{end}}{{end}}
+{{end}}{{end}}{{if .DebugChecks}}
+	casterDebugCheck("{{.FunctionName}}", []casterDebugField{
+{{range .DebugChecks}}		{Name: "{{.Target}}", Target: out.{{.Target}}, Sources: []any{ {{- .Sources -}} }},
+{{end}}	})
+{{end}}{{if .AfterHook}}
+	{{.AfterHook}}(in, &out)
+{{end}}{{if and .Overrides (not .DepthLimit)}}
+{{template "applyOverrides"}}
+{{end}}
 	return out
 }
-
+{{end}}{{if .MergeFunctionName}}
+// {{.MergeFunctionName}} updates an existing {{.TargetType}} from {{.SourceType}}.
+func {{if .Receiver}}({{.Receiver}}) {{end}}{{.MergeFunctionName}}{{.TypeParams}}({{if .UsesContext}}ctx context.Context, {{end}}in {{.SourceType}}, out *{{.TargetType}}{{range .ExtraArgs}}, {{.Name}} {{.Type}}{{end}}) {
+{{if .DepthLimit}}	const depth = 0
+
+{{end}}{{if .BeforeHook}}	in = {{.BeforeHook}}(in)
+
+{{end}}{{$sep := ""}}{{range .Assignments}}{{if not .MergeNever}}{{$sep}}{{$sep = "\n"}}{{if .MergeCheck}}	if {{.MergeCheck}} {
+{{template "assignment" .}}	}
+{{else}}{{template "assignment" .}}{{end}}{{end}}{{end}}{{if .AfterHook}}
+	{{.AfterHook}}(in, out)
+{{end}}}
+{{end}}
 {{if .MissingTransforms}}
 // Missing transforms. Ideally, these should be implemented in your project or defined as transforms in map.yaml
 {{range .MissingTransforms}}func {{.Name}}({{range $index, $arg := .Args}}{{if $index}}, {{end}}v{{$index}} {{$arg}}{{end}}) {{.ReturnType}} {
@@ -432,6 +966,42 @@ func {{.FunctionName}}(in {{.SourceType}}{{range .ExtraArgs}}, {{.Name}} {{.Type
 }
 
 {{end}}{{end}}
+{{define "casterParams"}}{{$sep := ""}}{{if .UsesContext}}ctx context.Context{{$sep = ", "}}{{end}}{{if not .SourceMethod}}{{$sep}}in {{.SourceType}}{{$sep = ", "}}{{end}}{{range .ExtraArgs}}{{$sep}}{{.Name}} {{.Type}}{{$sep = ", "}}{{end}}{{if .Overrides}}{{$sep}}overrides ...func(*{{.TargetType}}){{end}}{{end}}
+{{define "applyOverrides"}}	for _, override := range overrides {
+		override(&out)
+	}{{end}}
+{{define "assignment"}}{{if .DepthGuard}}	if {{.DepthGuard}} {
+{{template "guardedAssignment" .}}	}
+{{else}}{{template "guardedAssignment" .}}{{end}}{{end}}
+{{define "guardedAssignment"}}{{if .SourceGuard}}	if {{.SourceGuard}} {
+{{template "conditionalAssignment" .}}	}
+{{else}}{{template "conditionalAssignment" .}}{{end}}{{end}}
+{{define "conditionalAssignment"}}{{if .When}}	if {{.When}} {
+{{template "assignmentBody" .}}	}{{if .ElseDefault}} else {
+		{{if .Setter}}{{.Setter}}({{.ElseDefault}}){{else}}{{.TargetField}} = {{.ElseDefault}}{{end}}
+	}{{end}}
+{{else}}{{template "assignmentBody" .}}{{end}}{{end}}
+{{define "assignmentBody"}}{{if .Comment}}	// {{.Comment}}
+{{end}}{{if .Deprecated}}	// Deprecated: {{.Deprecated}}
+{{end}}{{if and .Setter (not (or .IsSlice .IsMap .NeedsNilCheck .ValidCheck))}}	{{.Setter}}({{.SourceExpr}})
+{{else}}{{if .Setter}}	{
+	var {{.TargetField}} {{.SetterType}}
+{{end}}{{if .IsSlice}}	{{.SliceBody}}
+{{else if .IsMap}}	{{.MapBody}}
+{{else if .NeedsNilCheck}}	if ({{if .NilCheckExpr}}{{.NilCheckExpr}}{{else}}{{.SourceExpr}}{{end}}) != nil {
+		{{.TargetField}} = {{.SourceExpr}}
+	} else {
+		{{.TargetField}} = {{.NilDefault}}
+	}
+{{else if .ValidCheck}}	if {{.ValidCheck}} {
+		{{.TargetField}} = {{.SourceExpr}}
+	}{{if .ValidDefault}} else {
+		{{.TargetField}} = {{.ValidDefault}}
+	}{{end}}
+{{else}}	{{.TargetField}} = {{.SourceExpr}}
+{{end}}{{if .Setter}}	{{.Setter}}({{.TargetField}})
+	}
+{{end}}{{end}}{{end}}
 `))
 
 var missingTransformsTemplate = template.Must(template.New("missing").Parse(`// Code generated by caster-generator. DO NOT EDIT.
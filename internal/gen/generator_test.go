@@ -1,6 +1,14 @@
 package gen
 
 import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -189,6 +197,210 @@ func TestGenerator_Generate_WithSliceMapping(t *testing.T) {
 	assert.Contains(t, content, "for i_0 := range in.Tags")
 }
 
+// sliceTagsPlan builds a plan mapping Order.Tags ([]string) to Order.Tags with the given field mapping options.
+func sliceTagsPlan(strategy plan.ConversionStrategy, nilToEmpty, preserveNil bool) *plan.ResolvedMappingPlan {
+	sliceOf := func() *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			Kind:     analyze.TypeKindSlice,
+			ElemType: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic},
+		}
+	}
+
+	srcType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Tags", Exported: true, Type: sliceOf()}},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Tags", Exported: true, Type: sliceOf()}},
+	}
+
+	return &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Tags"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Tags"}}}},
+						Strategy:    strategy,
+						NilToEmpty:  nilToEmpty,
+						PreserveNil: preserveNil,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerator_Generate_SlicePreserveNil(t *testing.T) {
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(sliceTagsPlan(plan.StrategySliceMap, false, true))
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "if in.Tags != nil {")
+	assert.Contains(t, content, "make([]string, len(in.Tags))")
+}
+
+func TestGenerator_Generate_SliceDedupBy(t *testing.T) {
+	itemType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "SKU", Exported: true, Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+		},
+	}
+
+	resolvedPlan := sliceTagsPlan(plan.StrategyDirectAssign, false, false)
+	for _, typ := range []*analyze.TypeInfo{resolvedPlan.TypePairs[0].SourceType, resolvedPlan.TypePairs[0].TargetType} {
+		typ.Fields[0].Type.ElemType = itemType
+	}
+
+	resolvedPlan.TypePairs[0].Mappings[0].DedupBy = "SKU"
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.Tags = make([]store.Item, 0, len(in.Tags))")
+	assert.Contains(t, content, "seen := make(map[string]struct{}, len(in.Tags))")
+	assert.Contains(t, content, "if _, dup := seen[in.Tags[i_0].SKU]; dup {")
+	assert.Contains(t, content, "out.Tags = append(out.Tags, in.Tags[i_0])")
+}
+
+func TestGenerator_Generate_LoopVarsAvoidRequires(t *testing.T) {
+	resolvedPlan := sliceTagsPlan(plan.StrategySliceMap, false, false)
+	resolvedPlan.TypePairs[0].Requires = []mapping.ArgDef{{Name: "i_0", Type: "int"}}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "for i_1 := range in.Tags")
+	assert.Contains(t, content, "out.Tags[i_1] = in.Tags[i_1]")
+}
+
+func TestGenerator_Generate_SliceNilToEmpty(t *testing.T) {
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(sliceTagsPlan(plan.StrategyDirectAssign, true, false))
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "if (in.Tags) != nil {")
+	assert.Contains(t, content, "out.Tags = []string{}")
+}
+
+func TestGenerator_Generate_PointerNilToEmpty(t *testing.T) {
+	stringType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+
+	tests := []struct {
+		name       string
+		collection *analyze.TypeInfo
+		want       string
+	}{
+		{
+			name:       "slice",
+			collection: &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: stringType},
+			want:       "out.Tags = &[]string{}",
+		},
+		{
+			name:       "map",
+			collection: &analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: stringType, ElemType: stringType},
+			want:       "out.Tags = &map[string]string{}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolvedPlan := sliceTagsPlan(plan.StrategyDirectAssign, true, false)
+			for _, typ := range []*analyze.TypeInfo{resolvedPlan.TypePairs[0].SourceType, resolvedPlan.TypePairs[0].TargetType} {
+				typ.Fields[0].Type = &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: tt.collection}
+			}
+
+			files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+
+			content := string(files[0].Content)
+			assert.Contains(t, content, "if (in.Tags) != nil {")
+			assert.Contains(t, content, "out.Tags = in.Tags")
+			assert.Contains(t, content, tt.want)
+		})
+	}
+}
+
+func TestGenerator_Generate_SliceDefaultNilHandling(t *testing.T) {
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(sliceTagsPlan(plan.StrategyDirectAssign, false, false))
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.Tags = in.Tags")
+	assert.NotContains(t, content, "[]string{}")
+}
+
+func TestGenerator_Generate_SlicePointerElements(t *testing.T) {
+	stringType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	stringPtr := &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: stringType}
+
+	tests := []struct {
+		name     string
+		src, tgt *analyze.TypeInfo
+		want     []string
+	}{
+		{
+			name: "wrap",
+			src:  stringType,
+			tgt:  stringPtr,
+			want: []string{
+				"out.Tags = make([]*string, len(in.Tags))",
+				"out.Tags[i_0] = func() *string { v := in.Tags[i_0]; return &v }()",
+			},
+		},
+		{
+			name: "deref",
+			src:  stringPtr,
+			tgt:  stringType,
+			want: []string{
+				"out.Tags = make([]string, len(in.Tags))",
+				"var v string",
+				"if in.Tags[i_0] != nil {",
+				"v = *in.Tags[i_0]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolvedPlan := sliceTagsPlan(plan.StrategySliceMap, false, false)
+			resolvedPlan.TypePairs[0].SourceType.Fields[0].Type.ElemType = tt.src
+			resolvedPlan.TypePairs[0].TargetType.Fields[0].Type.ElemType = tt.tgt
+
+			files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+
+			content := string(files[0].Content)
+			for _, want := range tt.want {
+				assert.Contains(t, content, want)
+			}
+		})
+	}
+}
+
 func TestGenerator_Generate_WithUnmappedTODOs(t *testing.T) {
 	srcType := &analyze.TypeInfo{
 		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
@@ -333,257 +545,410 @@ func TestGenerator_Generate_WithTransform(t *testing.T) {
 	assert.Contains(t, content, "ConcatNames(in.FirstName, in.LastName)")
 }
 
-func TestGenerator_Generate_MissingTransformStubs(t *testing.T) {
-	srcType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
-		Kind: analyze.TypeKindStruct,
-		Fields: []analyze.FieldInfo{
-			{Name: "ID", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
-			}},
-		},
+func TestGenerator_Generate_ContextAwareTransform(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+
+	srcItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Status", Exported: true, Type: strType}},
+	}
+	tgtItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Status", Exported: true, Type: strType}},
+	}
+	srcOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: srcItem}},
+	}
+	tgtOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: tgtItem}},
 	}
 
-	tgtType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
-		Kind: analyze.TypeKindStruct,
-		Fields: []analyze.FieldInfo{
-			{Name: "CustomerID", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
-			}},
-		},
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
 	}
 
 	resolvedPlan := &plan.ResolvedMappingPlan{
+		OriginalTransforms: []mapping.TransformDef{{Name: "LookupStatus", Ctx: true}},
 		TypePairs: []plan.ResolvedTypePair{
 			{
-				SourceType: srcType,
-				TargetType: tgtType,
+				SourceType:   srcOrder,
+				TargetType:   tgtOrder,
+				NeedsContext: true,
+				Mappings: []plan.ResolvedFieldMapping{
+					{TargetPaths: path("Item"), SourcePaths: path("Item"), Strategy: plan.StrategyNestedCast},
+				},
+			},
+			{
+				SourceType:   srcItem,
+				TargetType:   tgtItem,
+				NeedsContext: true,
 				Mappings: []plan.ResolvedFieldMapping{
 					{
-						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "CustomerID"}}}},
-						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						TargetPaths: path("Status"),
+						SourcePaths: path("Status"),
 						Strategy:    plan.StrategyTransform,
-						Transform:   "ID2CustomerID",
-						Explanation: "custom transform",
+						Transform:   "LookupStatus",
 					},
 				},
 			},
 		},
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
+	gen := NewGenerator(GeneratorConfig{
+		PackageName:        "casters",
+		DeclaredTransforms: map[string]bool{"LookupStatus": true},
+	})
 	files, err := gen.Generate(resolvedPlan)
 
 	require.NoError(t, err)
-	require.Len(t, files, 2) // caster file + missing_transforms.go
+	require.Len(t, files, 2)
 
-	// First file is the caster
-	casterContent := string(files[0].Content)
-	assert.Contains(t, casterContent, "out.CustomerID = ID2CustomerID(in.ID)")
+	order := string(files[0].Content)
+	assert.Contains(t, order, `"context"`)
+	assert.Contains(t, order, "func StoreOrderToWarehouseOrder(ctx context.Context, in store.Order)")
+	assert.Contains(t, order, "StoreItemToWarehouseItem(ctx, in.Item)")
 
-	// Second file is the missing transforms
-	transformsContent := string(files[1].Content)
-	assert.Contains(t, transformsContent, "func ID2CustomerID(v0 int64) string {")
-	assert.Contains(t, transformsContent, `panic("transform ID2CustomerID not implemented")`)
+	item := string(files[1].Content)
+	assert.Contains(t, item, "func StoreItemToWarehouseItem(ctx context.Context, in store.Item)")
+	assert.Contains(t, item, "LookupStatus(ctx, in.Status)")
 }
 
-func TestGenerator_Generate_MissingTransformStubs_WithRequires(t *testing.T) {
-	// Test that transform signatures inherit types from 'requires' arguments
+func TestGenerator_Generate_Wrapper(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	nullable := &analyze.TypeInfo{
+		ID:       analyze.TypeID{PkgPath: "example.com/nullable", Name: "Nullable"},
+		Kind:     analyze.TypeKindExternal,
+		TypeArgs: []*analyze.TypeInfo{strType},
+	}
+	strPtr := &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: strType}
+
 	srcType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "User"},
 		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "Name", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
-			}},
+			{Name: "Name", Exported: true, Type: nullable},
+			{Name: "Nick", Exported: true, Type: nullable},
+			{Name: "Email", Exported: true, Type: strPtr},
 		},
 	}
-
 	tgtType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "User"},
 		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "Name", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
-			}},
-			{Name: "OrderID", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "uint"}, Kind: analyze.TypeKindBasic,
-			}},
+			{Name: "Name", Exported: true, Type: strType},
+			{Name: "Nick", Exported: true, Type: strPtr},
+			{Name: "Email", Exported: true, Type: nullable},
 		},
 	}
 
+	path := func(name string) mapping.FieldPath {
+		return mapping.FieldPath{Segments: []mapping.PathSegment{{Name: name}}}
+	}
+
+	var mappings []plan.ResolvedFieldMapping
+	for _, name := range []string{"Name", "Nick", "Email"} {
+		mappings = append(mappings, plan.ResolvedFieldMapping{
+			TargetPaths: []mapping.FieldPath{path(name)},
+			SourcePaths: []mapping.FieldPath{path(name)},
+			Strategy:    plan.StrategyWrapper,
+		})
+	}
+
 	resolvedPlan := &plan.ResolvedMappingPlan{
-		TypePairs: []plan.ResolvedTypePair{
-			{
-				SourceType: srcType,
-				TargetType: tgtType,
-				// Requires defines OrderID as uint
-				Requires: []mapping.ArgDef{
-					{Name: "OrderID", Type: "uint"},
-				},
-				Mappings: []plan.ResolvedFieldMapping{
-					{
-						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
-						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
-						Strategy:    plan.StrategyDirectAssign,
-					},
-					{
-						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "OrderID"}}}},
-						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "OrderID"}}}},
-						Strategy:    plan.StrategyTransform,
-						Transform:   "PassThroughOrderID",
-						Explanation: "pass through OrderID from requires",
-					},
-				},
-			},
+		Wrappers: []mapping.WrapperDef{
+			{Type: "nullable.Nullable", Get: "MustGet", Present: "IsSpecified", New: "NewNullableWithValue"},
 		},
+		TypePairs: []plan.ResolvedTypePair{{SourceType: srcType, TargetType: tgtType, Mappings: mappings}},
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(resolvedPlan)
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
 
 	require.NoError(t, err)
-	require.Len(t, files, 2) // caster file + missing_transforms.go
+	require.Len(t, files, 1)
 
-	// Second file is the missing transforms
-	transformsContent := string(files[1].Content)
-	// The signature should be `func PassThroughOrderID(v0 uint) uint` - NOT interface{}
-	assert.Contains(t, transformsContent, "func PassThroughOrderID(v0 uint) uint {")
-	assert.NotContains(t, transformsContent, "interface{}")
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.Name = in.Name.MustGet()")
+	assert.Contains(t, content, "if !in.Nick.IsSpecified() {")
+	assert.Contains(t, content, "func() nullable.Nullable[string] {")
+	assert.Contains(t, content, "w = nullable.NewNullableWithValue(*in.Email)")
+	assert.Contains(t, content, `"example.com/nullable"`)
 }
 
-func TestGenerator_Generate_MissingTransformStubs_WithExtra(t *testing.T) {
-	// Test that transform signatures inherit types from 'extra' arguments when they reference 'requires'
+func TestGenerator_Generate_MemoizedTransform(t *testing.T) {
+	floatType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "float64"}, Kind: analyze.TypeKindBasic}
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+
 	srcType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
 		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "Price", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "float64"}, Kind: analyze.TypeKindBasic,
-			}},
+			{Name: "Price", Exported: true, Type: floatType},
+			{Name: "Currency", Exported: true, Type: strType},
 		},
 	}
-
 	tgtType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
-		Kind: analyze.TypeKindStruct,
-		Fields: []analyze.FieldInfo{
-			{Name: "PriceInCents", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
-			}},
-		},
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Price", Exported: true, Type: strType}},
+	}
+
+	path := func(name string) mapping.FieldPath {
+		return mapping.FieldPath{Segments: []mapping.PathSegment{{Name: name}}}
 	}
 
 	resolvedPlan := &plan.ResolvedMappingPlan{
+		OriginalTransforms: []mapping.TransformDef{{Name: "FormatMoney", Memoize: true}},
 		TypePairs: []plan.ResolvedTypePair{
 			{
 				SourceType: srcType,
 				TargetType: tgtType,
-				// Requires defines Multiplier as int
-				Requires: []mapping.ArgDef{
-					{Name: "Multiplier", Type: "int"},
-				},
 				Mappings: []plan.ResolvedFieldMapping{
 					{
-						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "PriceInCents"}}}},
-						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Price"}}}},
+						TargetPaths: []mapping.FieldPath{path("Price")},
+						SourcePaths: []mapping.FieldPath{path("Price"), path("Currency")},
 						Strategy:    plan.StrategyTransform,
-						Transform:   "PriceToCents",
-						// Extra references the Multiplier required argument
-						Extra: []mapping.ExtraVal{
-							{Name: "Multiplier"},
-						},
+						Transform:   "FormatMoney",
 					},
 				},
 			},
 		},
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
+	gen := NewGenerator(GeneratorConfig{
+		PackageName:        "casters",
+		DeclaredTransforms: map[string]bool{"FormatMoney": true},
+	})
 	files, err := gen.Generate(resolvedPlan)
 
 	require.NoError(t, err)
-	require.Len(t, files, 2) // caster file + missing_transforms.go
-
-	// Second file is the missing transforms
-	transformsContent := string(files[1].Content)
-	// The signature should have float64 from source Price and int from extra Multiplier
-	assert.Contains(t, transformsContent, "func PriceToCents(v0 float64, v1 int) int64 {")
-	assert.NotContains(t, transformsContent, "interface{}")
+	require.Len(t, files, 2)
+
+	assert.Contains(t, string(files[0].Content), "out.Price = memoFormatMoney(in.Price, in.Currency)")
+
+	assert.Equal(t, "memoized_transforms.go", files[1].Filename)
+	memo := string(files[1].Content)
+	assert.Contains(t, memo, "var memoFormatMoneyCache sync.Map")
+	assert.Contains(t, memo, "func memoFormatMoney(v0 float64, v1 string) string {")
+	assert.Contains(t, memo, "key := struct {\n\t\tv0 float64\n\t\tv1 string\n\t}{v0, v1}")
+	assert.Contains(t, memo, "result := FormatMoney(v0, v1)")
+
+	t.Run("context-aware", func(t *testing.T) {
+		resolvedPlan.OriginalTransforms[0].Ctx = true
+		resolvedPlan.TypePairs[0].NeedsContext = true
+
+		files, err := NewGenerator(GeneratorConfig{
+			PackageName:        "casters",
+			DeclaredTransforms: map[string]bool{"FormatMoney": true},
+		}).Generate(resolvedPlan)
+		require.NoError(t, err)
+
+		// The cache key would leave out ctx, so the transform is called directly
+		require.Len(t, files, 1)
+		assert.Contains(t, string(files[0].Content), "out.Price = FormatMoney(ctx, in.Price, in.Currency)")
+	})
 }
 
-func TestTypeRef_String(t *testing.T) {
-	tests := []struct {
-		name     string
-		ref      typeRef
-		expected string
-	}{
-		{
-			name:     "simple type",
-			ref:      typeRef{Name: "string"},
-			expected: "string",
-		},
-		{
-			name:     "package qualified type",
-			ref:      typeRef{Package: "store", Name: "Order"},
-			expected: "store.Order",
-		},
-		{
-			name:     "pointer type",
-			ref:      typeRef{Package: "store", Name: "Order", IsPointer: true},
-			expected: "*store.Order",
-		},
-		{
-			name: "slice type",
-			ref: typeRef{
-				IsSlice: true,
-				ElemRef: &typeRef{Name: "string"},
+func TestGenerator_Generate_MemoizedTransformSignatures(t *testing.T) {
+	intType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.Int]}
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.String]}
+	anyType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "any"}, Kind: analyze.TypeKindBasic, GoType: types.Universe.Lookup("any").Type()}
+
+	pairOf := func(name string, src *analyze.TypeInfo) plan.ResolvedTypePair {
+		path := []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Value"}}}}
+
+		return plan.ResolvedTypePair{
+			SourceType: &analyze.TypeInfo{
+				ID:     analyze.TypeID{PkgPath: "example/store", Name: name},
+				Kind:   analyze.TypeKindStruct,
+				Fields: []analyze.FieldInfo{{Name: "Value", Exported: true, Type: src}},
 			},
-			expected: "[]string",
-		},
+			TargetType: &analyze.TypeInfo{
+				ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: name},
+				Kind:   analyze.TypeKindStruct,
+				Fields: []analyze.FieldInfo{{Name: "Value", Exported: true, Type: strType}},
+			},
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path, SourcePaths: path, Strategy: plan.StrategyTransform, Transform: "Describe"},
+			},
+		}
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.expected, tt.ref.String())
-		})
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		OriginalTransforms: []mapping.TransformDef{{Name: "Describe", Memoize: true}},
+		TypePairs:          []plan.ResolvedTypePair{pairOf("A", intType), pairOf("B", strType), pairOf("C", intType), pairOf("D", anyType)},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{
+		PackageName:        "casters",
+		DeclaredTransforms: map[string]bool{"Describe": true},
+	}).Generate(resolvedPlan)
+
+	require.NoError(t, err)
+
+	contents := make(map[string]string, len(files))
+	for _, f := range files {
+		contents[f.Filename] = string(f.Content)
+	}
+
+	// Each signature gets its own wrapper, reused by the casters calling it alike.
+	memo := contents["memoized_transforms.go"]
+	assert.Contains(t, memo, "func memoDescribe(v0 int) string {")
+	assert.Contains(t, memo, "func memoDescribe_2(v0 string) string {")
+	assert.Equal(t, 2, strings.Count(memo, "sync.Map"))
+
+	var casters string
+	for name, content := range contents {
+		if name != "memoized_transforms.go" {
+			casters += content
+		}
 	}
+
+	assert.Equal(t, 2, strings.Count(casters, "out.Value = memoDescribe(in.Value)"))
+	assert.Contains(t, casters, "out.Value = memoDescribe_2(in.Value)")
+
+	// Interface values may hold uncomparable values, which would panic as keys.
+	assert.Contains(t, casters, "out.Value = Describe(in.Value)")
 }
 
-func TestGenerator_filename(t *testing.T) {
-	gen := NewGenerator(DefaultGeneratorConfig())
+func TestGenerator_Generate_TemplateTransform(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
 
-	pair := &plan.ResolvedTypePair{
-		SourceType: &analyze.TypeInfo{
-			ID: analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Code", Exported: true, Type: strType},
+			{Name: "Prefix", Exported: true, Type: strType},
 		},
-		TargetType: &analyze.TypeInfo{
-			ID: analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Item"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Code", Exported: true, Type: strType},
+			{Name: "SKU", Exported: true, Type: strType},
 		},
 	}
 
-	filename := gen.filename(pair)
-	assert.Equal(t, "store_order_to_warehouse_order.go", filename)
+	path := func(name string) mapping.FieldPath {
+		return mapping.FieldPath{Segments: []mapping.PathSegment{{Name: name}}}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		OriginalTransforms: []mapping.TransformDef{
+			{Name: "Upper", Template: "strings.ToUpper({{.Source}})", Imports: []string{"strings"}},
+			{Name: "Join", Template: `{{index .Args 1}} + "-" + {{index .Args 0}}`},
+		},
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{path("Code")},
+						SourcePaths: []mapping.FieldPath{path("Code")},
+						Strategy:    plan.StrategyTransform,
+						Transform:   "Upper",
+					},
+					{
+						TargetPaths: []mapping.FieldPath{path("SKU")},
+						SourcePaths: []mapping.FieldPath{path("Code"), path("Prefix")},
+						Strategy:    plan.StrategyTransform,
+						Transform:   "Join",
+					},
+				},
+			},
+		},
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 1, "template transforms must not get stubs")
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.Code = strings.ToUpper(in.Code)")
+	assert.Contains(t, content, `out.SKU = in.Prefix + "-" + in.Code`)
+	assert.Contains(t, content, `"strings"`)
+
+	resolvedPlan.OriginalTransforms[1].Template = "{{index .Args 2}}"
+
+	_, err = NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `transform "Join"`)
 }
 
-func TestGenerator_functionName(t *testing.T) {
-	gen := NewGenerator(DefaultGeneratorConfig())
+func TestGenerator_Generate_MoneyTransforms(t *testing.T) {
+	intType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic}
+	decType := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "github.com/shopspring/decimal", Name: "Decimal"},
+		Kind: analyze.TypeKindExternal}
 
-	pair := &plan.ResolvedTypePair{
-		SourceType: &analyze.TypeInfo{
-			ID: analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Invoice"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "TotalCents", Exported: true, Type: intType},
+			{Name: "Tax", Exported: true, Type: decType},
 		},
-		TargetType: &analyze.TypeInfo{
-			ID: analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Invoice"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Total", Exported: true, Type: decType},
+			{Name: "TaxCents", Exported: true, Type: intType},
 		},
 	}
 
-	funcName := gen.functionName(pair)
-	assert.Equal(t, "StoreOrderToWarehouseOrder", funcName)
+	path := func(name string) mapping.FieldPath {
+		return mapping.FieldPath{Segments: []mapping.PathSegment{{Name: name}}}
+	}
+
+	generate := func(currency mapping.Currency) string {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+			Currency: currency,
+			TypePairs: []plan.ResolvedTypePair{{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{path("Total")},
+						SourcePaths: []mapping.FieldPath{path("TotalCents")},
+						Strategy:    plan.StrategyTransform,
+						Transform:   mapping.TransformCentsToDecimal,
+					},
+					{
+						TargetPaths: []mapping.FieldPath{path("TaxCents")},
+						SourcePaths: []mapping.FieldPath{path("Tax")},
+						Strategy:    plan.StrategyTransform,
+						Transform:   mapping.TransformDecimalToCents,
+					},
+				},
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, files, 1, "builtin money transforms must not get stubs")
+
+		return string(files[0].Content)
+	}
+
+	content := generate(mapping.Currency{})
+	assert.Contains(t, content, "out.Total = decimal.New(int64(in.TotalCents), -2)")
+	assert.Contains(t, content, "out.TaxCents = in.Tax.Shift(2).Round(0).IntPart()")
+	assert.Contains(t, content, `"github.com/shopspring/decimal"`)
+
+	content = generate(mapping.Currency{Code: "JPY"})
+	assert.Contains(t, content, "out.Total = decimal.New(int64(in.TotalCents), 0)")
+	assert.Contains(t, content, "out.TaxCents = in.Tax.Shift(0).Round(0).IntPart()")
 }
 
-func TestGenerator_Generate_FormattedOutput(t *testing.T) {
+func TestGenerator_Generate_MissingTransformStubs(t *testing.T) {
 	srcType := &analyze.TypeInfo{
 		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
 		Kind: analyze.TypeKindStruct,
@@ -598,8 +963,8 @@ func TestGenerator_Generate_FormattedOutput(t *testing.T) {
 		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
 		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "ID", Exported: true, Type: &analyze.TypeInfo{
-				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
+			{Name: "CustomerID", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
 			}},
 		},
 	}
@@ -611,9 +976,11 @@ func TestGenerator_Generate_FormattedOutput(t *testing.T) {
 				TargetType: tgtType,
 				Mappings: []plan.ResolvedFieldMapping{
 					{
-						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "CustomerID"}}}},
 						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
-						Strategy:    plan.StrategyDirectAssign,
+						Strategy:    plan.StrategyTransform,
+						Transform:   "ID2CustomerID",
+						Explanation: "custom transform",
 					},
 				},
 			},
@@ -624,89 +991,803 @@ func TestGenerator_Generate_FormattedOutput(t *testing.T) {
 	files, err := gen.Generate(resolvedPlan)
 
 	require.NoError(t, err)
-	require.Len(t, files, 1)
+	require.Len(t, files, 2) // caster file + missing_transforms.go
 
-	content := string(files[0].Content)
+	// First file is the caster
+	casterContent := string(files[0].Content)
+	assert.Contains(t, casterContent, "out.CustomerID = ID2CustomerID(in.ID)")
 
-	// Check that output is properly formatted (no double newlines except intended)
-	assert.True(t, strings.HasPrefix(content, "// Code generated by caster-generator"))
-	assert.Contains(t, content, "package casters")
+	// Second file is the missing transforms
+	transformsContent := string(files[1].Content)
+	assert.Contains(t, transformsContent, "func ID2CustomerID(v0 int64) string {")
+	assert.Contains(t, transformsContent, `panic("transform ID2CustomerID not implemented")`)
 }
 
-func TestGenerateMissingTypesFile_Basic(t *testing.T) {
-	// Setup Source
+func TestGenerator_Generate_MissingTransformStubs_WithRequires(t *testing.T) {
+	// Test that transform signatures inherit types from 'requires' arguments
 	srcType := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "testpkg", Name: "Source"},
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
 		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "ID", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
-			{Name: "Name", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+			{Name: "Name", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
+			}},
 		},
 	}
 
-	// Setup Target (Generated)
-	tgtID := analyze.TypeID{PkgPath: "testpkg", Name: "Target"}
 	tgtType := &analyze.TypeInfo{
-		ID:          tgtID,
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
+		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{Name: "ID", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
-			{Name: "Label", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
-		},
-	}
-
-	// Mock TypeGraph
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"testpkg": {
-				Name: "testpkg",
-				Dir:  "/abs/path/to/testpkg",
-			},
+			{Name: "Name", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
+			}},
+			{Name: "OrderID", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "uint"}, Kind: analyze.TypeKindBasic,
+			}},
 		},
 	}
 
-	// Setup Plan
-	p := &plan.ResolvedMappingPlan{
+	resolvedPlan := &plan.ResolvedMappingPlan{
 		TypePairs: []plan.ResolvedTypePair{
 			{
-				SourceType:        srcType,
-				TargetType:        tgtType,
-				IsGeneratedTarget: true,
-				Mappings:          []plan.ResolvedFieldMapping{},
+				SourceType: srcType,
+				TargetType: tgtType,
+				// Requires defines OrderID as uint
+				Requires: []mapping.ArgDef{
+					{Name: "OrderID", Type: "uint"},
+				},
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+						Strategy:    plan.StrategyDirectAssign,
+					},
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "OrderID"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "OrderID"}}}},
+						Strategy:    plan.StrategyTransform,
+						Transform:   "PassThroughOrderID",
+						Explanation: "pass through OrderID from requires",
+					},
+				},
 			},
 		},
-		TypeGraph: graph,
 	}
 
-	// Generate
 	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	files, err := gen.Generate(resolvedPlan)
+
 	require.NoError(t, err)
+	require.Len(t, files, 2) // caster file + missing_transforms.go
 
-	// Verify
-	found := false
+	// Second file is the missing transforms
+	transformsContent := string(files[1].Content)
+	// The signature should be `func PassThroughOrderID(v0 uint) uint` - NOT interface{}
+	assert.Contains(t, transformsContent, "func PassThroughOrderID(v0 uint) uint {")
+	assert.NotContains(t, transformsContent, "interface{}")
+}
 
-	for _, f := range files {
-		if strings.Contains(f.Filename, "missing_types.go") {
-			found = true
-			content := string(f.Content)
-			assert.Contains(t, content, "package testpkg")
-			assert.Contains(t, content, "type Target struct")
-			assert.Regexp(t, `ID\s+string`, content)
-			assert.Regexp(t, `Label\s+string`, content)
-			// Should NOT contain "testpkg." in struct definition
-			assert.NotContains(t, content, "testpkg.")
-		}
+func TestGenerator_Generate_MissingTransformStubs_WithExtra(t *testing.T) {
+	// Test that transform signatures inherit types from 'extra' arguments when they reference 'requires'
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Price", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "float64"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
 	}
 
-	assert.True(t, found, "missing_types.go not generated")
-}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "PriceInCents", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: srcType,
+				TargetType: tgtType,
+				// Requires defines Multiplier as int
+				Requires: []mapping.ArgDef{
+					{Name: "Multiplier", Type: "int"},
+				},
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "PriceInCents"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Price"}}}},
+						Strategy:    plan.StrategyTransform,
+						Transform:   "PriceToCents",
+						// Extra references the Multiplier required argument
+						Extra: []mapping.ExtraVal{
+							{Name: "Multiplier"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 2) // caster file + missing_transforms.go
+
+	// Second file is the missing transforms
+	transformsContent := string(files[1].Content)
+	// The signature should have float64 from source Price and int from extra Multiplier
+	assert.Contains(t, transformsContent, "func PriceToCents(v0 float64, v1 int) int64 {")
+	assert.NotContains(t, transformsContent, "interface{}")
+}
+
+func TestGenerator_Generate_TransformConstExtra(t *testing.T) {
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Event"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "At", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
+	}
+
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Event"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "At", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "At"}}}},
+				SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "At"}}}},
+				Strategy:    plan.StrategyTransform,
+				Transform:   "FormatUnix",
+				Extra: []mapping.ExtraVal{
+					{Name: "layout", Const: `"2006-01-02"`},
+					{Name: "offset", Const: "-60"},
+					{Name: "utc", Const: "true"},
+				},
+			}},
+		}},
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 2) // caster file + missing_transforms.go
+
+	assert.Contains(t, string(files[0].Content), `out.At = FormatUnix(in.At, "2006-01-02", -60, true)`)
+
+	// Stubs take the constants with their default types
+	assert.Contains(t, string(files[1].Content), "func FormatUnix(v0 int64, v1 string, v2 int, v3 bool) string {")
+}
+
+func TestGenerator_Generate_GenericRequires(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	srcType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+		},
+	}
+
+	newPlan := func(requires ...mapping.ArgDef) *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Requires:   requires,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						Strategy:    plan.StrategyTransform,
+						Transform:   "Stamp",
+						Extra:       []mapping.ExtraVal{{Name: "meta"}, {Name: "locale"}},
+					},
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Note"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "note"}}}},
+						Strategy:    plan.StrategyDirectAssign,
+					},
+				},
+			}},
+		}
+	}
+
+	generate := func(cfg GeneratorConfig, p *plan.ResolvedMappingPlan) string {
+		files, err := NewGenerator(cfg).Generate(p)
+		require.NoError(t, err)
+		require.NotEmpty(t, files)
+
+		return string(files[0].Content)
+	}
+
+	cfg := DefaultGeneratorConfig()
+	cfg.GenericRequires = true
+
+	t.Run("single pass-through arg is T", func(t *testing.T) {
+		content := generate(cfg, newPlan(
+			mapping.ArgDef{Name: "meta", Type: "interface{}"},
+			mapping.ArgDef{Name: "locale", Type: "string"},
+		))
+
+		assert.Contains(t, content, "func StoreItemToWarehouseLineItem[T any](in store.Item, meta T, locale string)")
+		assert.Contains(t, content, "Stamp(in.ID, meta, locale)")
+	})
+
+	t.Run("several pass-through args are named", func(t *testing.T) {
+		content := generate(cfg, newPlan(
+			mapping.ArgDef{Name: "meta", Type: "interface{}"},
+			mapping.ArgDef{Name: "locale", Type: "any"},
+		))
+
+		assert.Contains(t, content, "[TMeta any, TLocale any](in store.Item, meta TMeta, locale TLocale)")
+	})
+
+	t.Run("directly used arg stays interface", func(t *testing.T) {
+		content := generate(cfg, newPlan(
+			mapping.ArgDef{Name: "meta", Type: "interface{}"},
+			mapping.ArgDef{Name: "locale", Type: "string"},
+			mapping.ArgDef{Name: "note", Type: "interface{}"},
+		))
+
+		assert.Contains(t, content, "[T any](in store.Item, meta T, locale string, note interface{})")
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		content := generate(DefaultGeneratorConfig(), newPlan(
+			mapping.ArgDef{Name: "meta", Type: "interface{}"},
+			mapping.ArgDef{Name: "locale", Type: "string"},
+		))
+
+		assert.Contains(t, content, "(in store.Item, meta interface{}, locale string)")
+	})
+}
+
+func TestGenerator_Generate_MethodsStyle(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	srcItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+	}
+	tgtItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "LineItem"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+	}
+	srcOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: srcItem}},
+	}
+	tgtOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: tgtItem}},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	newPlan := func(requires ...mapping.ArgDef) *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{
+				{
+					SourceType: srcOrder,
+					TargetType: tgtOrder,
+					Requires:   requires,
+					Mappings: []plan.ResolvedFieldMapping{{
+						TargetPaths: path("Item"),
+						SourcePaths: path("Item"),
+						Strategy:    plan.StrategyNestedCast,
+					}},
+				},
+				{
+					SourceType: srcItem,
+					TargetType: tgtItem,
+					Mappings: []plan.ResolvedFieldMapping{{
+						TargetPaths: path("ID"),
+						SourcePaths: path("ID"),
+						Strategy:    plan.StrategyTransform,
+						Transform:   "normalizeID",
+					}},
+				},
+			},
+		}
+	}
+
+	cfg := DefaultGeneratorConfig()
+	cfg.Style = StyleMethods
+
+	t.Run("casters are methods calling injected transforms", func(t *testing.T) {
+		files, err := NewGenerator(cfg).Generate(newPlan())
+		require.NoError(t, err)
+
+		contents := make(map[string]string)
+		for _, f := range files {
+			contents[f.Filename] = string(f.Content)
+		}
+
+		order := contents["store_order_to_warehouse_order.go"]
+		assert.Contains(t, order, "func (c *Casters) StoreOrderToWarehouseOrder(in store.Order) warehouse.Order")
+		assert.Contains(t, order, "c.StoreItemToWarehouseLineItem(in.Item)")
+
+		item := contents["store_item_to_warehouse_lineitem.go"]
+		assert.Contains(t, item, "c.Transforms.NormalizeID(in.ID)")
+
+		casters := contents["casters.go"]
+		assert.Contains(t, casters, "StoreOrderToWarehouseOrder(in store.Order) warehouse.Order\n")
+		assert.Contains(t, casters, "NormalizeID(v0 string) string\n")
+		assert.Contains(t, casters, "return normalizeID(v0)")
+		assert.Contains(t, casters, "return &Casters{Transforms: DefaultTransforms{}}")
+
+		// The stub of the undeclared transform is still generated.
+		assert.Contains(t, contents["missing_transforms.go"], "func normalizeID(v0 string) string")
+	})
+
+	t.Run("functions by default", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan())
+		require.NoError(t, err)
+
+		for _, f := range files {
+			assert.NotEqual(t, "casters.go", f.Filename)
+			assert.NotContains(t, string(f.Content), "c.Transforms")
+		}
+	})
+
+	t.Run("receiver name is not a requires argument", func(t *testing.T) {
+		_, err := NewGenerator(cfg).Generate(newPlan(mapping.ArgDef{Name: "c", Type: "string"}))
+		require.ErrorContains(t, err, "receiver")
+	})
+
+	t.Run("unknown style", func(t *testing.T) {
+		bad := DefaultGeneratorConfig()
+		bad.Style = "classes"
+
+		_, err := NewGenerator(bad).Generate(newPlan())
+		require.ErrorContains(t, err, "unknown style")
+	})
+}
+
+func TestTypeRef_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      typeRef
+		expected string
+	}{
+		{
+			name:     "simple type",
+			ref:      typeRef{Name: "string"},
+			expected: "string",
+		},
+		{
+			name:     "package qualified type",
+			ref:      typeRef{Package: "store", Name: "Order"},
+			expected: "store.Order",
+		},
+		{
+			name:     "pointer type",
+			ref:      typeRef{Package: "store", Name: "Order", IsPointer: true},
+			expected: "*store.Order",
+		},
+		{
+			name: "slice type",
+			ref: typeRef{
+				IsSlice: true,
+				ElemRef: &typeRef{Name: "string"},
+			},
+			expected: "[]string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.ref.String())
+		})
+	}
+}
+
+func TestGenerator_filename(t *testing.T) {
+	gen := NewGenerator(DefaultGeneratorConfig())
+
+	pair := &plan.ResolvedTypePair{
+		SourceType: &analyze.TypeInfo{
+			ID: analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		},
+		TargetType: &analyze.TypeInfo{
+			ID: analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		},
+	}
+
+	filename := gen.filename(pair)
+	assert.Equal(t, "store_order_to_warehouse_order.go", filename)
+}
+
+func TestGenerator_functionName(t *testing.T) {
+	gen := NewGenerator(DefaultGeneratorConfig())
+
+	pair := &plan.ResolvedTypePair{
+		SourceType: &analyze.TypeInfo{
+			ID: analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		},
+		TargetType: &analyze.TypeInfo{
+			ID: analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		},
+	}
+
+	funcName := gen.functionName(pair)
+	assert.Equal(t, "StoreOrderToWarehouseOrder", funcName)
+}
+
+func TestGenerator_Generate_FormattedOutput(t *testing.T) {
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
+	}
+
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: &analyze.TypeInfo{
+				ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic,
+			}},
+		},
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}},
+						Strategy:    plan.StrategyDirectAssign,
+					},
+				},
+			},
+		},
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	// Check that output is properly formatted (no double newlines except intended)
+	assert.True(t, strings.HasPrefix(content, "// Code generated by caster-generator"))
+	assert.Contains(t, content, "package casters")
+}
+
+func TestGenerateMissingTypesFile_Basic(t *testing.T) {
+	// Setup Source
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "testpkg", Name: "Source"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+			{Name: "Name", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+		},
+	}
+
+	// Setup Target (Generated)
+	tgtID := analyze.TypeID{PkgPath: "testpkg", Name: "Target"}
+	tgtType := &analyze.TypeInfo{
+		ID:          tgtID,
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+			{Name: "Label", Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}},
+		},
+	}
+
+	// Mock TypeGraph
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"testpkg": {
+				Name: "testpkg",
+				Dir:  "/abs/path/to/testpkg",
+			},
+		},
+	}
+
+	// Setup Plan
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType:        srcType,
+				TargetType:        tgtType,
+				IsGeneratedTarget: true,
+				Mappings:          []plan.ResolvedFieldMapping{},
+			},
+		},
+		TypeGraph: graph,
+	}
+
+	// Generate
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	// Verify
+	found := false
+
+	for _, f := range files {
+		if strings.Contains(f.Filename, "missing_types.go") {
+			found = true
+			content := string(f.Content)
+			assert.Contains(t, content, "package testpkg")
+			assert.Contains(t, content, "type Target struct")
+			assert.Regexp(t, `ID\s+string`, content)
+			assert.Regexp(t, `Label\s+string`, content)
+			// Should NOT contain "testpkg." in struct definition
+			assert.NotContains(t, content, "testpkg.")
+		}
+	}
+
+	assert.True(t, found, "missing_types.go not generated")
+}
 
 func TestGenerateMissingTypesFile_MultipleTypes(t *testing.T) {
 	// Two targets in same package
 	tgt1 := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target1"},
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target1"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{{
+			Name: "F",
+			Type: &analyze.TypeInfo{
+				ID:   analyze.TypeID{Name: "int"},
+				Kind: analyze.TypeKindBasic}}},
+	}
+	tgt2 := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target2"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{{
+			Name: "G",
+			Type: &analyze.TypeInfo{
+				ID:   analyze.TypeID{Name: "int"},
+				Kind: analyze.TypeKindBasic}}},
+	}
+
+	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
+		},
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgt1, IsGeneratedTarget: true},
+			{SourceType: src, TargetType: tgt2, IsGeneratedTarget: true},
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	missingFiles := 0
+
+	for _, f := range files {
+		if strings.Contains(f.Filename, "missing_types.go") {
+			missingFiles++
+			content := string(f.Content)
+			assert.Contains(t, content, "package testpkg")
+			assert.Contains(t, content, "type Target1 struct")
+			assert.Contains(t, content, "type Target2 struct")
+		}
+	}
+
+	assert.Equal(t, 1, missingFiles)
+}
+
+func TestGenerateMissingTypesFile_CrossPackageReference(t *testing.T) {
+	// Target has field of type TargetItem (same package)
+	itemType := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "TargetItem"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+	}
+
+	tgtType := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{
+			{
+				Name: "Items",
+				Type: &analyze.TypeInfo{
+					Kind: analyze.TypeKindSlice,
+					ElemType: &analyze.TypeInfo{
+						Kind:     analyze.TypeKindPointer,
+						ElemType: itemType,
+					},
+				},
+			},
+		},
+	}
+
+	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
+		},
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
+			// We don't necessarily need a mapping for TargetItem for this test,
+			// just need to check how Target refers to it.
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	found := false
+
+	for _, f := range files {
+		if strings.Contains(f.Filename, "missing_types.go") {
+			found = true
+			content := string(f.Content)
+			assert.Contains(t, content, "Items []*TargetItem")
+			assert.NotContains(t, content, "Items []*testpkg.TargetItem")
+		}
+	}
+
+	assert.True(t, found)
+}
+
+func TestGenerateMissingTypesFile_ExternalTypeReference(t *testing.T) {
+	// Target has field of type time.Time
+	tgtType := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{
+			{
+				Name: "CreatedAt",
+				Type: &analyze.TypeInfo{
+					ID:   analyze.TypeID{PkgPath: "time", Name: "Time"},
+					Kind: analyze.TypeKindStruct,
+				},
+			},
+		},
+	}
+	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
+			"time":    {Name: "time", Dir: ""}, // External, dir empty?
+		},
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	found := false
+
+	for _, f := range files {
+		if strings.Contains(f.Filename, "missing_types.go") {
+			found = true
+			content := string(f.Content)
+			assert.Contains(t, content, `import (`)
+			assert.Contains(t, content, `"time"`)
+			assert.Contains(t, content, "CreatedAt time.Time")
+		}
+	}
+
+	assert.True(t, found)
+}
+
+func TestGenerateMissingTypesFile_DifferentPackages(t *testing.T) {
+	tgt1 := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "pkg1", Name: "Target"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+	}
+	tgt2 := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "pkg2", Name: "Target"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+	}
+	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"pkg1": {Name: "pkg1", Dir: "/path/to/pkg1"},
+			"pkg2": {Name: "pkg2", Dir: "/path/to/pkg2"},
+		},
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgt1, IsGeneratedTarget: true},
+			{SourceType: src, TargetType: tgt2, IsGeneratedTarget: true},
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	missingFiles := 0
+
+	for _, f := range files {
+		if strings.Contains(f.Filename, "missing_types.go") {
+			missingFiles++
+
+			content := string(f.Content)
+			if strings.Contains(content, "package pkg1") {
+				assert.Contains(t, f.Filename, "pkg1")
+			} else if strings.Contains(content, "package pkg2") {
+				assert.Contains(t, f.Filename, "pkg2")
+			}
+		}
+	}
+
+	assert.Equal(t, 2, missingFiles)
+}
+
+func TestGenerateMissingTypesFile_NoPackagePath(t *testing.T) {
+	// Target has empty PkgPath -> should be embedded in caster file
+	tgtType := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "", Name: "Target"},
 		Kind:        analyze.TypeKindStruct,
 		IsGenerated: true,
 		Fields: []analyze.FieldInfo{{
@@ -715,314 +1796,2286 @@ func TestGenerateMissingTypesFile_MultipleTypes(t *testing.T) {
 				ID:   analyze.TypeID{Name: "int"},
 				Kind: analyze.TypeKindBasic}}},
 	}
-	tgt2 := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target2"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-		Fields: []analyze.FieldInfo{{
-			Name: "G",
-			Type: &analyze.TypeInfo{
-				ID:   analyze.TypeID{Name: "int"},
-				Kind: analyze.TypeKindBasic}}},
+	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+
+	// Empty graph ok?
+	graph := &analyze.TypeGraph{Packages: map[string]*analyze.PackageInfo{}}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	for _, f := range files {
+		assert.NotContains(t, f.Filename, "missing_types.go")
+
+		if strings.HasSuffix(f.Filename, ".go") {
+			content := string(f.Content)
+			assert.Contains(t, content, "type Target struct")
+		}
+	}
+}
+
+func TestCasterFile_ImportsGeneratedType(t *testing.T) {
+	tgtType := &analyze.TypeInfo{
+		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+	}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "srcpkg", Name: "Source"},
+		Kind: analyze.TypeKindStruct,
+	}
+
+	graph := &analyze.TypeGraph{
+		Packages: map[string]*analyze.PackageInfo{
+			"testpkg": {Name: "testpkg", Dir: "/path/to/testpkg"},
+			"srcpkg":  {Name: "srcpkg", Dir: "/path/to/srcpkg"},
+		},
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
+		},
+		TypeGraph: graph,
+	}
+
+	gen := NewGenerator(DefaultGeneratorConfig())
+	files, err := gen.Generate(p)
+	require.NoError(t, err)
+
+	for _, f := range files {
+		if !strings.Contains(f.Filename, "missing_types.go") {
+			// This is the caster file
+			content := string(f.Content)
+			assert.Contains(t, content, `import (`)
+			// Should import testpkg
+			assert.Contains(t, content, `"testpkg"`)
+			// Function signature return type
+			assert.Contains(t, content, "testpkg.Target")
+			// Instantiation
+			assert.Contains(t, content, "out := testpkg.Target{}")
+		}
+	}
+}
+
+func TestTypeRefString_ContextPackagePath(t *testing.T) {
+	g := &Generator{}
+	imports := make(map[string]importSpec)
+
+	// Case 1: Matching context -> no prefix
+	g.contextPkgPath = "my/pkg"
+	typMatched := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "my/pkg", Name: "Foo"},
+		Kind: analyze.TypeKindStruct,
+	}
+	assert.Equal(t, "Foo", g.typeRefString(typMatched, imports))
+	assert.Empty(t, imports)
+
+	// Case 2: Different context -> prefix + import
+	typOther := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "other/pkg", Name: "Bar"},
+		Kind: analyze.TypeKindStruct,
+	}
+	assert.Equal(t, "pkg.Bar", g.typeRefString(typOther, imports))
+	assert.Contains(t, imports, "other/pkg")
+}
+
+func TestGenerator_Generate_ImportAliases(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	named := func(pkgPath string, fields ...analyze.FieldInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:     analyze.TypeID{PkgPath: pkgPath, Name: "Order"},
+			Kind:   analyze.TypeKindStruct,
+			Fields: fields,
+		}
+	}
+
+	status := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/billing/v1", Name: "Status"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: strType,
+	}
+	srcType := named("example/store/v1", analyze.FieldInfo{Name: "Status", Exported: true, Type: strType})
+	tgtType := named("example/billing/v1", analyze.FieldInfo{Name: "Status", Exported: true, Type: status})
+
+	path := mapping.FieldPath{Segments: []mapping.PathSegment{{Name: "Status"}}}
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{path},
+				SourcePaths: []mapping.FieldPath{path},
+				Strategy:    plan.StrategyConvert,
+			}},
+		}},
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Equal(t, "storev1_order_to_billingv1_order.go", files[0].Filename)
+	assert.Contains(t, content, `billingv1 "example/billing/v1"`)
+	assert.Contains(t, content, `storev1 "example/store/v1"`)
+	assert.Contains(t, content, "func Storev1OrderToBillingv1Order(in storev1.Order) billingv1.Order")
+	assert.Contains(t, content, "out.Status = billingv1.Status(in.Status)")
+
+	// A third v1 package lengthens the prefixes until they differ
+	resolvedPlan.TypePairs = append(resolvedPlan.TypePairs, plan.ResolvedTypePair{
+		SourceType: named("legacy/store/v1"),
+		TargetType: named("example/store/v1"),
+	})
+
+	g := NewGenerator(DefaultGeneratorConfig())
+	assert.Equal(t, map[string]string{
+		"example/billing/v1": "examplebillingv1",
+		"example/store/v1":   "examplestorev1",
+		"legacy/store/v1":    "legacystorev1",
+	}, g.pkgAliases(resolvedPlan))
+}
+
+func TestGenerator_Generate_DeprecatedField(t *testing.T) {
+	intType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+	srcType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Price", Exported: true, Type: intType}},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Amount", Exported: true, Type: intType}},
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Amount"}}}},
+				SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Price"}}}},
+				Strategy:    plan.StrategyDirectAssign,
+				Deprecated:  "use Total",
+				Sunset:      "2025-01-31",
+			}},
+		}},
+	}
+
+	config := DefaultGeneratorConfig()
+	config.GenerateComments = false
+
+	files, err := NewGenerator(config).Generate(resolvedPlan)
+
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Contains(t, string(files[0].Content), "// Deprecated: use Total (sunset 2025-01-31)\n\tout.Amount = in.Price")
+}
+
+func TestGenerator_Generate_CopyMode(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	itemsType := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: strType}
+	metaType := &analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: strType, ElemType: strType}
+
+	fields := []analyze.FieldInfo{
+		{Name: "Items", Exported: true, Type: itemsType},
+		{Name: "Metadata", Exported: true, Type: metaType},
+	}
+	srcType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: fields,
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: fields,
+	}
+
+	field := func(name string, mode mapping.CopyMode) plan.ResolvedFieldMapping {
+		path := []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+
+		return plan.ResolvedFieldMapping{
+			TargetPaths: path,
+			SourcePaths: path,
+			Strategy:    plan.StrategyDirectAssign,
+			Copy:        mode,
+		}
+	}
+
+	newPlan := func(items, metadata mapping.CopyMode) *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings:   []plan.ResolvedFieldMapping{field("Items", items), field("Metadata", metadata)},
+			}},
+		}
+	}
+
+	t.Run("per-field deep", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan(mapping.CopyDeep, mapping.CopyDefault))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		caster := string(files[0].Content)
+		assert.Contains(t, caster, "out.Items = cloneSliceString(in.Items)")
+		assert.Contains(t, caster, "out.Metadata = in.Metadata")
+
+		assert.Equal(t, "clone_helpers.go", files[1].Filename)
+		assert.Contains(t, string(files[1].Content), "func cloneSliceString(v []string) []string {")
+		assert.NotContains(t, string(files[1].Content), "cloneMapStringToString")
+	})
+
+	t.Run("alias overrides global deep copy", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.DeepCopy = true
+
+		files, err := NewGenerator(config).Generate(newPlan(mapping.CopyDefault, mapping.CopyAlias))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		caster := string(files[0].Content)
+		assert.Contains(t, caster, "out.Items = cloneSliceString(in.Items)")
+		assert.Contains(t, caster, "out.Metadata = in.Metadata")
+	})
+
+	t.Run("no helpers by default", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan(mapping.CopyDefault, mapping.CopyDefault))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+	})
+}
+
+func TestGenerator_Generate_CopyModeLevels(t *testing.T) {
+	strType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	tagsType := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: strType}
+	rowsType := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: tagsType}
+	personType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Person"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: strType},
+			{Name: "Tags", Exported: true, Type: tagsType},
+			{Name: "notes", Exported: false, Type: tagsType},
+		},
+	}
+	ownerType := &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: personType}
+
+	fields := []analyze.FieldInfo{
+		{Name: "Rows", Exported: true, Type: rowsType},
+		{Name: "Owner", Exported: true, Type: ownerType},
+	}
+	srcType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: fields,
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: fields,
+	}
+
+	field := func(name string, mode mapping.CopyMode) plan.ResolvedFieldMapping {
+		path := []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+
+		return plan.ResolvedFieldMapping{
+			TargetPaths: path,
+			SourcePaths: path,
+			Strategy:    plan.StrategyDirectAssign,
+			Copy:        mode,
+		}
+	}
+
+	newPlan := func(planMode, pairMode, ownerMode mapping.CopyMode) *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			CopyMode: planMode,
+			TypePairs: []plan.ResolvedTypePair{{
+				SourceType: srcType,
+				TargetType: tgtType,
+				CopyMode:   pairMode,
+				Mappings:   []plan.ResolvedFieldMapping{field("Rows", mapping.CopyDefault), field("Owner", ownerMode)},
+			}},
+		}
+	}
+
+	t.Run("plan-level deep clones nested pointers and struct fields", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan(mapping.CopyDeep, mapping.CopyDefault, mapping.CopyDefault))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		caster := string(files[0].Content)
+		assert.Contains(t, caster, "out.Rows = cloneSliceSliceString(in.Rows)")
+		assert.Contains(t, caster, "out.Owner = clonePtrStorePerson(in.Owner)")
+
+		helpers := string(files[1].Content)
+		assert.Contains(t, helpers, "out[i] = cloneSliceString(v[i])")
+		assert.Contains(t, helpers, "c := cloneStorePerson(*v)")
+		assert.Contains(t, helpers, "out.Tags = cloneSliceString(v.Tags)")
+		assert.NotContains(t, helpers, "v.notes")
+	})
+
+	t.Run("mapping-level shallow overrides plan, field overrides mapping", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan(mapping.CopyDeep, mapping.CopyShallow, mapping.CopyAlias))
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		caster := string(files[0].Content)
+		assert.Contains(t, caster, "out.Rows = copySliceSliceString(in.Rows)")
+		assert.Contains(t, caster, "out.Owner = in.Owner")
+
+		helpers := string(files[1].Content)
+		assert.Contains(t, helpers, "// copySliceSliceString returns a shallow copy of v.")
+		assert.Contains(t, helpers, "copy(out, v)")
+		assert.NotContains(t, helpers, "cloneSliceString")
+	})
+}
+
+func TestGenerator_Generate_SingleFile(t *testing.T) {
+	// Two casters plus a clone helper, which would otherwise be three files.
+	resolvedPlan := sliceTagsPlan(plan.StrategyDirectAssign, false, false)
+	pair := resolvedPlan.TypePairs[0]
+	pair.SourceType, pair.TargetType = pair.TargetType, pair.SourceType
+	pair.Mappings = append([]plan.ResolvedFieldMapping{}, pair.Mappings...)
+	pair.Mappings[0].Copy = mapping.CopyDeep
+	resolvedPlan.TypePairs = append(resolvedPlan.TypePairs, pair)
+
+	config := DefaultGeneratorConfig()
+	config.SingleFile = "casters.go"
+
+	files, err := NewGenerator(config).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "casters.go", files[0].Filename)
+
+	content := string(files[0].Content)
+	assert.Equal(t, 1, strings.Count(content, "// Code generated by caster-generator."))
+	assert.Equal(t, 1, strings.Count(content, "\"example/store\""))
+	assert.Contains(t, content, "func StoreOrderToWarehouseOrder(")
+	assert.Contains(t, content, "func WarehouseOrderToStoreOrder(")
+	assert.Contains(t, content, "func cloneSliceString(")
+}
+
+func TestGenerator_Generate_EnumMap(t *testing.T) {
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Status", Exported: true, Type: &analyze.TypeInfo{
+			ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic,
+		}}},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "State", Exported: true, Type: &analyze.TypeInfo{
+			ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic,
+		}}},
+	}
+
+	newPlan := func(enumDefault string, strict bool) *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{{
+				SourceType: srcType,
+				TargetType: tgtType,
+				Mappings: []plan.ResolvedFieldMapping{{
+					TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "State"}}}},
+					SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Status"}}}},
+					Strategy:    plan.StrategyEnumMap,
+					EnumMap:     map[string]string{"ACTIVE": "1", "INACTIVE": "0"},
+					EnumDefault: enumDefault,
+					EnumStrict:  strict,
+				}},
+			}},
+		}
+	}
+
+	t.Run("default value", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan("-1", false))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		content := string(files[0].Content)
+		assert.Contains(t, content, "switch in.Status {")
+		assert.Contains(t, content, "case \"ACTIVE\":\n\t\t\tr = 1")
+		assert.Contains(t, content, "case \"INACTIVE\":\n\t\t\tr = 0")
+		assert.Contains(t, content, "default:\n\t\t\tr = -1")
+		assert.NotContains(t, content, "\"fmt\"")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan("", true))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		content := string(files[0].Content)
+		assert.Contains(t, content, `panic(fmt.Sprintf("unmapped Status value %v", in.Status))`)
+		assert.Contains(t, content, "\"fmt\"")
+	})
+
+	t.Run("normalized literals", func(t *testing.T) {
+		p := newPlan("", false)
+		p.TypePairs[0].Mappings[0].EnumMap = map[string]string{"ACTIVE": "0x1", "INACTIVE": "+0"}
+
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(p)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		content := string(files[0].Content)
+		assert.Contains(t, content, "case \"ACTIVE\":\n\t\t\tr = 1")
+		assert.Contains(t, content, "case \"INACTIVE\":\n\t\t\tr = 0")
+	})
+
+	t.Run("quoted keys and target constants", func(t *testing.T) {
+		p := newPlan("warehouse.StateUnknown", false)
+		m := &p.TypePairs[0].Mappings[0]
+		m.EnumMap = map[string]string{`"ACTIVE"`: "warehouse.StateActive", "INACTIVE": "0"}
+		m.Qualifiers = map[string]string{"warehouse": "example/warehouse"}
+
+		files, err := NewGenerator(DefaultGeneratorConfig()).Generate(p)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		content := string(files[0].Content)
+		assert.Contains(t, content, "case \"ACTIVE\":\n\t\t\tr = warehouse.StateActive")
+		assert.Contains(t, content, "default:\n\t\t\tr = warehouse.StateUnknown")
+		assert.Contains(t, content, `warehouse "example/warehouse"`)
+	})
+}
+
+func TestGenerateMissingTypesFiles_Ordered(t *testing.T) {
+	g := NewGenerator(DefaultGeneratorConfig())
+	g.graph = analyze.NewTypeGraph()
+
+	for _, dir := range []string{"/src/zeta", "/src/alpha"} {
+		g.addMissingType(dir, filepath.Base(dir), "type Zed struct {\n}\n", nil)
+		g.addMissingType(dir, filepath.Base(dir), "type Ada struct {\n}\n", nil)
+	}
+
+	files, err := g.generateMissingTypesFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	assert.Contains(t, files[0].Filename, "alpha")
+	assert.Contains(t, files[1].Filename, "zeta")
+
+	content := string(files[0].Content)
+	assert.Less(t, strings.Index(content, "type Ada"), strings.Index(content, "type Zed"))
+}
+
+func TestNormalizeFiles(t *testing.T) {
+	files := []GeneratedFile{{
+		Filename: filepath.Join("..", "target", "missing_types.go"),
+		Content:  []byte("package a\r\n\r\n// old mac\rtype A struct{}\r\n"),
+	}}
+
+	normalizeFiles(files)
+
+	assert.Equal(t, "../target/missing_types.go", files[0].Filename)
+	assert.Equal(t, "package a\n\n// old mac\ntype A struct{}\n", string(files[0].Content))
+}
+
+func TestCompareFiles(t *testing.T) {
+	a := []GeneratedFile{{Filename: "a.go", Content: []byte("package a\n")}}
+
+	require.NoError(t, CompareFiles(a, []GeneratedFile{{Filename: "a.go", Content: []byte("package a\n")}}))
+	require.ErrorContains(t, CompareFiles(a, nil), "generated 1 files, then 0")
+	require.ErrorContains(t, CompareFiles(a, []GeneratedFile{{Filename: "b.go"}}), "file #1 is a.go, then b.go")
+	require.ErrorContains(t,
+		CompareFiles(a, []GeneratedFile{{Filename: "a.go", Content: []byte("package b\n")}}),
+		"a.go differs at byte 8")
+}
+
+func TestGenerator_Generate_ProtoGetters(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	customer := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/pb", Name: "Customer"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str, Getter: "GetName"}},
+	}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/pb", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Customer", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: customer}, Getter: "GetCustomer"},
+			{Name: "Note", Exported: true, Type: str},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/domain", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "CustomerName", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+		},
+	}
+
+	path := func(names ...string) []mapping.FieldPath {
+		var segs []mapping.PathSegment
+		for _, n := range names {
+			segs = append(segs, mapping.PathSegment{Name: n})
+		}
+
+		return []mapping.FieldPath{{Segments: segs}}
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: src,
+			TargetType: tgt,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("CustomerName"), SourcePaths: path("Customer", "Name"), Strategy: plan.StrategyDirectAssign},
+				{TargetPaths: path("Note"), SourcePaths: path("Note"), Strategy: plan.StrategyDirectAssign},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.CustomerName = in.GetCustomer().GetName()")
+	assert.Contains(t, content, "out.Note = in.Note")
+}
+
+func TestGenerator_Generate_Accessors(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	tags := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: str}
+	src := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/domain", Name: "User"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}},
+		Methods: []analyze.MethodInfo{
+			{Name: "ID", Results: []*analyze.TypeInfo{str}},
+			{Name: "GetTags", Results: []*analyze.TypeInfo{tags}, PointerReceiver: true},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/api", Name: "User"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+		Methods: []analyze.MethodInfo{
+			{Name: "SetName", Params: []*analyze.TypeInfo{str}, PointerReceiver: true},
+			{Name: "SetTags", Params: []*analyze.TypeInfo{tags}, PointerReceiver: true},
+		},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: src,
+			TargetType: tgt,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("ID"), SourcePaths: path("ID"), Strategy: plan.StrategyDirectAssign},
+				{TargetPaths: path("Name"), SourcePaths: path("Name"), Strategy: plan.StrategyDirectAssign},
+				{TargetPaths: path("Tags"), SourcePaths: path("Tags"), Strategy: plan.StrategySliceMap},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "out.ID = in.ID()")
+	assert.Contains(t, content, "out.SetName(in.Name)")
+	assert.Contains(t, content, "var value []string")
+	assert.Contains(t, content, "range in.GetTags()")
+	assert.Contains(t, content, "out.SetTags(value)")
+}
+
+func TestGenerator_Generate_PendingSource(t *testing.T) {
+	p := keepTestPlan()
+	p.TypePairs[0].Mappings[0].Strategy = plan.StrategyIgnore
+	p.TypePairs[0].Mappings[0].PendingSource = "FullName"
+
+	cfg := DefaultGeneratorConfig()
+	cfg.IncludeUnmappedTODOs = false
+
+	files, err := NewGenerator(cfg).Generate(p)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "// TODO: out.Name - assign from FullName once it exists")
+	assert.NotContains(t, content, "out.Name =")
+}
+
+func TestGenerator_Generate_SQLNull(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	nullString := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "database/sql", Name: "NullString"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "String", Exported: true, Type: str},
+			{Name: "Valid", Exported: true, Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "bool"}, Kind: analyze.TypeKindBasic}},
+		},
+	}
+	row := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/db", Name: "User"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: nullString},
+			{Name: "Nick", Exported: true, Type: nullString},
+		},
+	}
+	user := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "User"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Nick", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: str}},
+		},
+	}
+
+	field := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	newPair := func(src, tgt *analyze.TypeInfo) plan.ResolvedTypePair {
+		return plan.ResolvedTypePair{
+			SourceType: src,
+			TargetType: tgt,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: field("Name"), SourcePaths: field("Name"), Strategy: plan.StrategySQLNull, NullDefault: "n/a"},
+				// A Go string literal isn't quoted again
+				{TargetPaths: field("Nick"), SourcePaths: field("Nick"), Strategy: plan.StrategySQLNull, NullDefault: `"none"`},
+			},
+		}
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{newPair(row, user), newPair(user, row)},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	var content string
+	for _, f := range files {
+		content += string(f.Content)
+	}
+
+	assert.Contains(t, content, "if in.Name.Valid {\n\t\tout.Name = in.Name.String\n\t} else {\n\t\tout.Name = \"n/a\"\n\t}")
+	assert.Contains(t, content, "if in.Nick.Valid {\n\t\tout.Nick = func() *string { v := in.Nick.String; return &v }()\n\t} else {\n"+
+		"\t\tout.Nick = func() *string { v := string(\"none\"); return &v }()\n\t}\n")
+	assert.Contains(t, content, "out.Name = sql.NullString{String: in.Name, Valid: true}")
+	assert.Contains(t, content, "if in.Nick != nil {\n\t\tout.Nick = sql.NullString{String: *in.Nick, Valid: true}\n\t}")
+	assert.Contains(t, content, "\"database/sql\"")
+}
+
+func TestGenerator_Generate_Benchmarks(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	integer := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Price", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+			{Name: "Count", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: integer}},
+			{Name: "secret", Type: str},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Cents", Exported: true, Type: integer},
+			{Name: "Note", Exported: true, Type: str},
+			{Name: "Count", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: integer}},
+		},
+	}
+	srcItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}},
+	}
+	tgtItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/api", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	config := DefaultGeneratorConfig()
+	config.Benchmarks = true
+
+	files, err := NewGenerator(config).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: src,
+				TargetType: tgt,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: path("Cents"), SourcePaths: path("Price"),
+						Strategy: plan.StrategyTransform, Transform: "conv.Cents",
+					},
+					{TargetPaths: path("Note"), SourcePaths: path("Note"), Strategy: plan.StrategyDirectAssign},
+					{TargetPaths: path("Count"), SourcePaths: path("Count"), Strategy: plan.StrategyDirectAssign},
+				},
+			},
+			{
+				SourceType: srcItem,
+				TargetType: tgtItem,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: path("Name"), SourcePaths: path("Name"),
+						Strategy: plan.StrategyTransform, Transform: "upper",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var bench *GeneratedFile
+
+	for i := range files {
+		if files[i].Filename == "casters_bench_test.go" {
+			bench = &files[i]
+		}
+	}
+
+	require.NotNil(t, bench)
+
+	content := string(bench.Content)
+	// Fields passed to transforms stay zero
+	assert.Contains(t, content, `in := store.Order{Note: "Note", Count: fixturePtr[int](1)}`)
+	assert.Contains(t, content, "out = StoreOrderToApiOrder(in)")
+	assert.Contains(t, content, "// No benchmark for StoreItemToApiItem: it calls transform stubs, which panic.")
+	assert.NotContains(t, content, "func BenchmarkStoreItemToApiItem")
+	assert.Equal(t, "casters_bench_test.go", files[len(files)-1].Filename)
+}
+
+func TestGenerator_Generate_Tests(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	integer := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Price", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+			{Name: "Count", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: integer}},
+			{Name: "Qty", Exported: true, Type: integer},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Cents", Exported: true, Type: integer},
+			{Name: "Note", Exported: true, Type: str},
+			{Name: "Count", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: integer}},
+			{Name: "Qty", Exported: true, Type: &analyze.TypeInfo{ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic}},
+			{Name: "Status", Exported: true, Type: str},
+			{Name: "Ref", Exported: true, Type: str},
+			{Name: "Memo", Exported: true, Type: str},
+		},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	literal, call := `"new"`, "uuid.NewString()"
+
+	config := DefaultGeneratorConfig()
+	config.Tests = true
+	config.Benchmarks = true
+
+	files, err := NewGenerator(config).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: src,
+				TargetType: tgt,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: path("Cents"), SourcePaths: path("Price"),
+						Strategy: plan.StrategyTransform, Transform: "conv.Cents",
+					},
+					{TargetPaths: path("Note"), SourcePaths: path("Note"), Strategy: plan.StrategyDirectAssign},
+					{TargetPaths: path("Count"), SourcePaths: path("Count"), Strategy: plan.StrategyDirectAssign},
+					{TargetPaths: path("Qty"), SourcePaths: path("Qty"), Strategy: plan.StrategyConvert},
+					{TargetPaths: path("Status"), Strategy: plan.StrategyDefault, Default: &literal},
+					{TargetPaths: path("Ref"), Strategy: plan.StrategyDefault, Default: &call},
+					{
+						TargetPaths: path("Memo"), SourcePaths: path("Note"),
+						Strategy: plan.StrategyDirectAssign, When: "in.Qty > 1", ElseDefault: `"none"`,
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	byName := make(map[string]string)
+	for _, f := range files {
+		byName[f.Filename] = string(f.Content)
+	}
+
+	content := byName["casters_golden_test.go"]
+	require.NotEmpty(t, content)
+	assert.Contains(t, content, `in := store.Order{Note: "Note", Count: fixturePtr[int](1), Qty: 1}`)
+	assert.Contains(t, content, "out := StoreOrderToApiOrder(in)")
+	assert.Contains(t, content, "if got, want := out.Note, in.Note; got != want {")
+	assert.Contains(t, content, "if got, want := out.Count, in.Count; !reflect.DeepEqual(got, want) {")
+	assert.Contains(t, content, "if got, want := out.Qty, int64(in.Qty); got != want {")
+	assert.Contains(t, content, `if got, want := out.Status, string("new"); got != want {`)
+	// Transformed fields and non-literal defaults aren't checked
+	assert.NotContains(t, content, "out.Cents")
+	assert.NotContains(t, content, "out.Ref")
+	// Nor are fields whose when condition the fixture may not satisfy
+	assert.NotContains(t, content, "out.Memo")
+	assert.Contains(t, content, "func fixturePtr[T any]")
+
+	// The pointer helper is declared once per package
+	assert.NotContains(t, byName["casters_bench_test.go"], "func fixturePtr")
+
+	// The tests compile against the casters, importing only what they use
+	assert.NotContains(t, content, `"example/api"`)
+	assertFileTypeChecks(t, files, "casters_golden_test.go", map[string]string{
+		"example/store": `package store
+
+type Order struct {
+	Price string
+	Note  string
+	Count *int
+	Qty   int
+}`,
+		"example/api": `package api
+
+type Order struct {
+	Cents  int
+	Note   string
+	Count  *int
+	Qty    int64
+	Status string
+	Ref    string
+	Memo   string
+}`,
+	})
+}
+
+// assertFileTypeChecks type-checks the generated files as one package, with
+// the given sources of its imports, and asserts name has no errors. Errors of
+// the other files, e.g. calls of unresolved transforms, are ignored.
+func assertFileTypeChecks(t *testing.T, files []GeneratedFile, name string, deps map[string]string) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	pkgs := make(map[string]*types.Package)
+
+	for path, src := range deps {
+		file, err := parser.ParseFile(fset, path+".go", src, 0)
+		require.NoError(t, err)
+
+		pkg, err := new(types.Config).Check(path, fset, []*ast.File{file}, nil)
+		require.NoError(t, err)
+
+		pkgs[path] = pkg
+	}
+
+	var parsed []*ast.File
+
+	for _, f := range files {
+		file, err := parser.ParseFile(fset, f.Filename, f.Content, 0)
+		require.NoError(t, err)
+
+		parsed = append(parsed, file)
+	}
+
+	var errs []string
+
+	config := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if pkg, ok := pkgs[path]; ok {
+				return pkg, nil
+			}
+
+			return importer.Default().Import(path)
+		}),
+		Error: func(err error) {
+			var e types.Error
+			if errors.As(err, &e) && e.Fset.Position(e.Pos).Filename == name {
+				errs = append(errs, e.Msg)
+			}
+		},
+	}
+
+	_, _ = config.Check("casters", fset, parsed, nil)
+	assert.Empty(t, errs, "type errors in %s", name)
+}
+
+// importerFunc implements types.Importer with a function.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+func TestGenerator_Generate_DebugCasters(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Price", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Cents", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+			{Name: "Memo", Exported: true, Type: str},
+		},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	p := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType: src,
+				TargetType: tgt,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: path("Cents"), SourcePaths: path("Price"),
+						Strategy: plan.StrategyTransform, Transform: "ToCents",
+					},
+					{TargetPaths: path("Note"), SourcePaths: path("Note"), Strategy: plan.StrategyDirectAssign},
+					{
+						TargetPaths: path("Memo"), SourcePaths: path("Note"),
+						Strategy: plan.StrategyDirectAssign, When: `in.Price != ""`,
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("files", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.DebugCasters = DebugPanic
+
+		files, err := NewGenerator(config).Generate(p)
+		require.NoError(t, err)
+
+		byName := make(map[string]string)
+		for _, f := range files {
+			byName[f.Filename] = string(f.Content)
+		}
+
+		caster := byName["store_order_to_api_order.go"]
+		assert.True(t, strings.HasPrefix(caster, "//go:build !casterdebug\n\n"))
+		assert.NotContains(t, caster, "casterDebugCheck")
+
+		variant := byName["store_order_to_api_order_debug.go"]
+		assert.True(t, strings.HasPrefix(variant, "//go:build casterdebug\n\n"))
+		assert.Contains(t, variant, `{Name: "Note", Target: out.Note, Sources: []any{in.Note}},`)
+		// Transforms may yield zero from a set source, so aren't checked
+		assert.NotContains(t, variant, `Name: "Cents"`)
+		// Conditional fields stay zero when their condition doesn't hold
+		assert.NotContains(t, variant, `Name: "Memo"`)
+
+		helpers := byName["casters_debug.go"]
+		assert.True(t, strings.HasPrefix(helpers, "//go:build casterdebug\n\n"))
+		assert.Contains(t, helpers, "panic(msg)")
+
+		// Shared files are built either way
+		require.Contains(t, byName, "missing_transforms.go")
+		assert.False(t, strings.HasPrefix(byName["missing_transforms.go"], "//go:build"))
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.DebugCasters = DebugLog
+		config.SingleFile = "casters.go"
+
+		files, err := NewGenerator(config).Generate(p)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+
+		assert.Equal(t, "casters.go", files[0].Filename)
+		assert.True(t, strings.HasPrefix(string(files[0].Content), "//go:build !casterdebug\n\n"))
+		assert.Equal(t, "casters_debug.go", files[1].Filename)
+		assert.Contains(t, string(files[1].Content), "log.Print(msg)")
+		assert.Contains(t, string(files[1].Content), "func ToCents(")
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.DebugCasters = "warn"
+
+		_, err := NewGenerator(config).Generate(p)
+		require.ErrorContains(t, err, `unknown debug casters mode "warn"`)
+	})
+}
+
+func TestGenerator_Generate_Merge(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	integer := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+	boolean := &analyze.TypeInfo{ID: analyze.TypeID{Name: "bool"}, Kind: analyze.TypeKindBasic}
+	address := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/api", Name: "Address"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "City", Exported: true, Type: str}},
+	}
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Patch"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Age", Exported: true, Type: integer},
+			{Name: "Admin", Exported: true, Type: boolean},
+			{Name: "City", Exported: true, Type: str},
+		},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "User"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Age", Exported: true, Type: integer},
+			{Name: "Admin", Exported: true, Type: boolean},
+			{Name: "Home", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: address}},
+		},
+	}
+
+	path := func(name ...string) []mapping.FieldPath {
+		fp := mapping.FieldPath{}
+		for _, n := range name {
+			fp.Segments = append(fp.Segments, mapping.PathSegment{Name: n})
+		}
+
+		return []mapping.FieldPath{fp}
+	}
+
+	pair := plan.ResolvedTypePair{
+		SourceType: src,
+		TargetType: tgt,
+		Mappings: []plan.ResolvedFieldMapping{
+			{TargetPaths: path("Name"), SourcePaths: path("Name"), Strategy: plan.StrategyDirectAssign},
+			{
+				TargetPaths: path("Age"), SourcePaths: path("Age"),
+				Strategy: plan.StrategyDirectAssign, Merge: mapping.MergeIfZero,
+			},
+			{
+				TargetPaths: path("Admin"), SourcePaths: path("Admin"),
+				Strategy: plan.StrategyDirectAssign, Merge: mapping.MergeNever,
+			},
+			{
+				TargetPaths: path("Home", "City"), SourcePaths: path("City"),
+				Strategy: plan.StrategyDirectAssign, Merge: mapping.MergeIfZero,
+			},
+		},
+	}
+
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{pair},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "func StorePatchToApiUser(in store.Patch) api.User {")
+	assert.Contains(t, content, "out.Admin = in.Admin\n")
+
+	merge := content[strings.Index(content, "func MergeStorePatchIntoApiUser"):]
+	assert.True(t, strings.HasPrefix(merge, "func MergeStorePatchIntoApiUser(in store.Patch, out *api.User) {\n"))
+	assert.Contains(t, merge, "\tout.Name = in.Name\n")
+	assert.Contains(t, merge, "if out.Age == 0 {\n\t\tout.Age = in.Age\n\t}")
+	assert.Contains(t, merge, `if out.Home == nil || out.Home.City == "" {`)
+	// never leaves the field as it is
+	assert.NotContains(t, merge, "out.Admin")
+
+	t.Run("setter", func(t *testing.T) {
+		setterTgt := *tgt
+		setterTgt.Fields = []analyze.FieldInfo{{Name: "Age", Type: integer, Setter: "SetAge"}}
+
+		p := pair
+		p.TargetType = &setterTgt
+		p.Mappings = p.Mappings[1:2]
+
+		_, err := NewGenerator(DefaultGeneratorConfig()).Generate(&plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{p},
+		})
+		require.ErrorContains(t, err, "merge: if_zero can't read out.Age, which is set by out.SetAge")
+	})
+}
+
+func TestGenerator_Generate_Outputs(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n"), 0o600))
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	srcItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Status", Exported: true, Type: str}},
+	}
+	tgtItem := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Item"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Status", Exported: true, Type: str}},
+	}
+	srcOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: srcItem}},
+	}
+	tgtOrder := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Item", Exported: true, Type: tgtItem}},
 	}
 
-	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
 
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		OriginalTransforms: []mapping.TransformDef{{Name: "LookupStatus", Ctx: true}},
+		TypePairs: []plan.ResolvedTypePair{
+			{
+				SourceType:   srcOrder,
+				TargetType:   tgtOrder,
+				NeedsContext: true,
+				Mappings: []plan.ResolvedFieldMapping{
+					{TargetPaths: path("Item"), SourcePaths: path("Item"), Strategy: plan.StrategyNestedCast},
+				},
+				NestedPairs: []plan.NestedConversion{{SourceType: srcItem, TargetType: tgtItem}},
+			},
+			{
+				SourceType:   srcItem,
+				TargetType:   tgtItem,
+				NeedsContext: true,
+				Mappings: []plan.ResolvedFieldMapping{
+					{
+						TargetPaths: path("Status"),
+						SourcePaths: path("Status"),
+						Strategy:    plan.StrategyTransform,
+						Transform:   "LookupStatus",
+					},
+				},
+				Output: &mapping.Output{Package: "apiconv"},
+			},
 		},
 	}
 
-	p := &plan.ResolvedMappingPlan{
+	files, err := NewGenerator(GeneratorConfig{
+		PackageName: "casters",
+		OutputDir:   filepath.Join(root, "casters"),
+	}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	order := string(files[0].Content)
+	assert.Equal(t, "store_order_to_warehouse_order.go", files[0].Filename)
+	assert.Contains(t, order, "package casters")
+	assert.Contains(t, order, `apiconv "example.com/app/casters/apiconv"`)
+	assert.Contains(t, order, "apiconv.StoreItemToWarehouseItem(ctx, in.Item)")
+
+	// The missing transform is stubbed in the package calling it
+	assert.Equal(t, filepath.Join("apiconv", "store_item_to_warehouse_item.go"), files[1].Filename)
+	assert.Contains(t, string(files[1].Content), "package apiconv")
+	assert.Equal(t, filepath.Join("apiconv", "missing_transforms.go"), files[2].Filename)
+
+	t.Run("conflicting packages", func(t *testing.T) {
+		resolvedPlan.TypePairs[0].Output = &mapping.Output{Package: "other", Dir: filepath.Join(root, "casters", "apiconv")}
+
+		_, err := NewGenerator(GeneratorConfig{
+			PackageName: "casters",
+			OutputDir:   filepath.Join(root, "casters"),
+		}).Generate(resolvedPlan)
+		require.ErrorContains(t, err, `is given packages "other" and "apiconv"`)
+	})
+
+	t.Run("import cycle", func(t *testing.T) {
+		_, err := NewGenerator(GeneratorConfig{
+			PackageName: "casters",
+			OutputDir:   filepath.Join(root, "casters"),
+		}).Generate(&plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{
+				{
+					SourceType:  srcOrder,
+					TargetType:  tgtOrder,
+					NestedPairs: []plan.NestedConversion{{SourceType: srcItem, TargetType: tgtItem}},
+					Output:      &mapping.Output{Package: "orders"},
+				},
+				{
+					SourceType:  srcItem,
+					TargetType:  tgtItem,
+					NestedPairs: []plan.NestedConversion{{SourceType: srcOrder, TargetType: tgtOrder}},
+				},
+			},
+		})
+		require.ErrorContains(t, err, "output packages import each other ("+
+			"example/store.Item->example/warehouse.Item calls example/store.Order->example/warehouse.Order "+
+			"in package orders, "+
+			"example/store.Order->example/warehouse.Order calls example/store.Item->example/warehouse.Item "+
+			"in package casters)")
+	})
+}
+
+func TestGenerator_Generate_AllowUnexported(t *testing.T) {
+	dir := t.TempDir()
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	src := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Note", Exported: true, Type: str}},
+	}
+	tgt := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind:       analyze.TypeKindStruct,
+		Unexported: []analyze.FieldInfo{{Name: "note", Type: str}},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypeGraph: &analyze.TypeGraph{
+			Packages: map[string]*analyze.PackageInfo{
+				"example/warehouse": {Path: "example/warehouse", Name: "warehouse", Dir: dir},
+			},
+		},
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType:      src,
+			TargetType:      tgt,
+			AllowUnexported: true,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("note"), SourcePaths: path("Note"), Strategy: plan.StrategyDirectAssign},
+			},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "warehouse", OutputDir: dir}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// The caster is generated into the target package, so refers to it unqualified
+	content := string(files[0].Content)
+	assert.Contains(t, content, "package warehouse")
+	assert.NotContains(t, content, `"example/warehouse"`)
+	assert.Contains(t, content, "func StoreOrderToWarehouseOrder(in store.Order) Order {")
+	assert.Contains(t, content, "out.note = in.Note")
+
+	t.Run("other package", func(t *testing.T) {
+		_, err := NewGenerator(GeneratorConfig{
+			PackageName: "casters",
+			OutputDir:   filepath.Join(dir, "casters"),
+		}).Generate(resolvedPlan)
+		require.ErrorContains(t, err, "unexported field example/warehouse.Order.note is only accessible")
+	})
+
+	t.Run("package name mismatch", func(t *testing.T) {
+		_, err := NewGenerator(GeneratorConfig{PackageName: "casters", OutputDir: dir}).Generate(resolvedPlan)
+		require.ErrorContains(t, err, "holds package warehouse, not casters")
+	})
+
+	t.Run("unresolved type", func(t *testing.T) {
+		pair := &resolvedPlan.TypePairs[0]
+		nilPointer := &analyze.TypeInfo{Kind: analyze.TypeKindPointer}
+
+		g := NewGenerator(GeneratorConfig{PackageName: "casters"})
+		require.NoError(t, g.checkAccessible(pair, nil, path("note")[0]))
+		require.NoError(t, g.checkAccessible(pair, nilPointer, path("note")[0]))
+	})
+}
+
+func TestGenerator_Generate_MaxDepth(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	node := func(pkg string) *analyze.TypeInfo {
+		n := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: "Node"}, Kind: analyze.TypeKindStruct}
+		n.Fields = []analyze.FieldInfo{
+			{Name: "Value", Exported: true, Type: str},
+			{Name: "Next", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: n}},
+		}
+
+		return n
+	}
+	src, tgt := node("example/store"), node("example/warehouse")
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType:    src,
+			TargetType:    tgt,
+			MaxDepth:      2,
+			GenerateMerge: true,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("Next"), SourcePaths: path("Next"), Strategy: plan.StrategyPointerNestedCast},
+				{TargetPaths: path("Value"), SourcePaths: path("Value"), Strategy: plan.StrategyDirectAssign},
+			},
+			NestedPairs: []plan.NestedConversion{{SourceType: src, TargetType: tgt}},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	// The caster starts the recursion of its depth-limited variant
+	assert.Contains(t, content, "func StoreNodeToWarehouseNode(in store.Node) warehouse.Node {\n"+
+		"\treturn storeNodeToWarehouseNodeAtDepth(in, 0)\n}")
+	assert.Contains(t, content, "func storeNodeToWarehouseNodeAtDepth(in store.Node, depth int) warehouse.Node {")
+	assert.Contains(t, content, "if depth < 2 {")
+	assert.Contains(t, content, "v := storeNodeToWarehouseNodeAtDepth(*in.Next, depth+1)")
+	assert.Contains(t, content, "const depth = 0")
+
+	// Non-recursive fields are always converted
+	assert.Contains(t, content, "\tout.Value = in.Value\n")
+	assert.NotContains(t, content, "if depth < 2 {\n\t\tout.Value")
+
+	t.Run("unlimited", func(t *testing.T) {
+		resolvedPlan.TypePairs[0].MaxDepth = 0
+
+		files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+		require.NoError(t, err)
+		assert.NotContains(t, string(files[0].Content), "depth")
+	})
+}
+
+func TestGenerator_Generate_Via(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	order := func(pkg string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:     analyze.TypeID{PkgPath: pkg, Name: "Order"},
+			Kind:   analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+		}
+	}
+	store, canonical, api := order("example/store"), order("example/canonical"), order("example/api")
+
+	path := []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "ID"}}}}
+	hop := func(src, tgt *analyze.TypeInfo) plan.ResolvedTypePair {
+		return plan.ResolvedTypePair{
+			SourceType: src,
+			TargetType: tgt,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path, SourcePaths: path, Strategy: plan.StrategyDirectAssign},
+			},
+		}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
 		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgt1, IsGeneratedTarget: true},
-			{SourceType: src, TargetType: tgt2, IsGeneratedTarget: true},
+			{
+				SourceType: store,
+				TargetType: api,
+				Via:        canonical,
+				NestedPairs: []plan.NestedConversion{
+					{SourceType: store, TargetType: canonical},
+					{SourceType: canonical, TargetType: api},
+				},
+			},
+			hop(store, canonical),
+			hop(canonical, api),
 		},
-		TypeGraph: graph,
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
 	require.NoError(t, err)
+	require.Len(t, files, 3)
 
-	missingFiles := 0
+	content := string(files[0].Content)
 
-	for _, f := range files {
-		if strings.Contains(f.Filename, "missing_types.go") {
-			missingFiles++
-			content := string(f.Content)
-			assert.Contains(t, content, "package testpkg")
-			assert.Contains(t, content, "type Target1 struct")
-			assert.Contains(t, content, "type Target2 struct")
+	// The caster chains the casters to and from the intermediate type
+	assert.Contains(t, content, "func StoreOrderToApiOrder(in store.Order) api.Order {\n"+
+		"\treturn CanonicalOrderToApiOrder(StoreOrderToCanonicalOrder(in))\n}")
+	assert.NotContains(t, content, "out :=")
+	assert.NotContains(t, content, "example/canonical")
+}
+
+func TestGenerator_Generate_When(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	order := func(pkg string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: pkg, Name: "Order"},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: "Type", Exported: true, Type: str},
+				{Name: "Label", Exported: true, Type: str},
+			},
 		}
 	}
 
-	assert.Equal(t, 1, missingFiles)
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: order("example/store"),
+			TargetType: order("example/warehouse"),
+			Mappings: []plan.ResolvedFieldMapping{
+				{
+					TargetPaths: path("Label"),
+					SourcePaths: path("Label"),
+					Strategy:    plan.StrategyDirectAssign,
+					When:        `in.Type == "premium"`,
+					ElseDefault: `"basic"`,
+				},
+				{
+					TargetPaths: path("Type"),
+					SourcePaths: path("Type"),
+					Strategy:    plan.StrategyDirectAssign,
+					When:        `in.Type != ""`,
+				},
+			},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	assert.Contains(t, content, "\tif in.Type == \"premium\" {\n\t\tout.Label = in.Label\n"+
+		"\t} else {\n\t\tout.Label = \"basic\"\n\t}\n")
+	assert.Contains(t, content, "\tif in.Type != \"\" {\n\t\tout.Type = in.Type\n\t}\n")
 }
 
-func TestGenerateMissingTypesFile_CrossPackageReference(t *testing.T) {
-	// Target has field of type TargetItem (same package)
-	itemType := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "TargetItem"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
+func TestGenerator_Generate_ConstExpr(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	order := func(pkg string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: pkg, Name: "Order"},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: "Status", Exported: true, Type: str},
+				{Name: "Label", Exported: true, Type: str},
+			},
+		}
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	status, label := "warehouse.StatusPending", "strings.ToUpper(in.Label)"
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: order("example/store"),
+			TargetType: order("example/warehouse"),
+			Mappings: []plan.ResolvedFieldMapping{
+				{
+					TargetPaths: path("Status"),
+					Strategy:    plan.StrategyDefault,
+					Default:     &status,
+					Const:       status,
+					Qualifiers:  map[string]string{"warehouse": "example/warehouse"},
+				},
+				{
+					TargetPaths: path("Label"),
+					Strategy:    plan.StrategyDefault,
+					Default:     &label,
+					Expr:        label,
+					Qualifiers:  map[string]string{"strings": "strings"},
+				},
+			},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	assert.Contains(t, content, "\tout.Status = warehouse.StatusPending\n")
+	assert.Contains(t, content, "\tout.Label = strings.ToUpper(in.Label)\n")
+	assert.Contains(t, content, "\tstrings \"strings\"\n")
+}
+
+func TestGenerator_Generate_Collect(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	color := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Color"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: str,
+	}
+	kv := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "KV"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Key", Exported: true, Type: str},
+			{Name: "Value", Exported: true, Type: str},
+		},
 	}
 
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Product"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Color", Exported: true, Type: color},
+			{Name: "Size", Exported: true, Type: str},
+		},
+	}
 	tgtType := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Product"},
+		Kind: analyze.TypeKindStruct,
 		Fields: []analyze.FieldInfo{
-			{
-				Name: "Items",
-				Type: &analyze.TypeInfo{
-					Kind: analyze.TypeKindSlice,
-					ElemType: &analyze.TypeInfo{
-						Kind:     analyze.TypeKindPointer,
-						ElemType: itemType,
-					},
-				},
+			{Name: "Attributes", Exported: true, Type: &analyze.TypeInfo{
+				Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+			}},
+			{Name: "Labels", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: kv}},
+		},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	collect := map[string]string{"Color": "color", "Size": "size"}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("Attributes"), Strategy: plan.StrategyCollect, Collect: collect},
+				{TargetPaths: path("Labels"), Strategy: plan.StrategyCollect, Collect: collect},
+			},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	assert.Contains(t, content,
+		"\tout.Attributes = map[string]string{\"color\": string(in.Color), \"size\": in.Size}\n")
+	assert.Contains(t, content,
+		"\tout.Labels = []warehouse.KV{{Key: \"color\", Value: string(in.Color)}, {Key: \"size\", Value: in.Size}}\n")
+}
+
+func TestGenerator_Generate_CollectOmitZero(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	str := basic("string")
+
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Product"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Size", Exported: true, Type: basic("int")},
+			{Name: "OnSale", Exported: true, Type: basic("bool")},
+		},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Product"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Attributes", Exported: true, Type: &analyze.TypeInfo{
+				Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+			}},
+		},
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Attributes"}}}},
+				Strategy:    plan.StrategyCollect,
+				Collect:     map[string]string{"Name": "name", "Size": "size", "OnSale": "on_sale"},
+				OmitZero:    true,
+			}},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	assert.Contains(t, content, "\tout.Attributes = func() map[string]string {\n\t\tcollected := map[string]string{}\n\n")
+	assert.Contains(t, content, "\t\tif in.Name != \"\" {\n\t\t\tcollected[\"name\"] = in.Name\n\t\t}\n")
+	assert.Contains(t, content, "\t\tif in.OnSale {\n\t\t\tcollected[\"on_sale\"] = strconv.FormatBool(in.OnSale)\n")
+	assert.Contains(t, content, "\t\t\tcollected[\"size\"] = strconv.FormatInt(int64(in.Size), 10)\n")
+	assert.Contains(t, content, "\t\treturn collected\n\t}()\n")
+}
+
+func TestGenerator_Generate_FromMapKeys(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	str := basic("string")
+	status := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/warehouse", Name: "Status"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: str,
+	}
+
+	srcType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Request"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Params", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+		}}},
+	}
+	tgtType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Status", Exported: true, Type: status},
+			{Name: "ID", Exported: true, Type: basic("int64")},
+			{Name: "Count", Exported: true, Type: basic("int")},
+			{Name: "Ratio", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: basic("float32")}},
+			{Name: "Name", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: str}},
+		},
+	}
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	mapKey := func(key, target string) plan.ResolvedFieldMapping {
+		return plan.ResolvedFieldMapping{
+			SourcePaths: path("Params"), TargetPaths: path(target), Strategy: plan.StrategyMapKey, MapKey: key,
+		}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: srcType,
+			TargetType: tgtType,
+			Mappings: []plan.ResolvedFieldMapping{
+				mapKey("status", "Status"),
+				mapKey("id", "ID"),
+				mapKey("count", "Count"),
+				mapKey("ratio", "Ratio"),
+				mapKey("name", "Name"),
 			},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+
+	assert.Contains(t, content, "\t\"strconv\"\n")
+	assert.Contains(t, content, "\tif v, ok := in.Params[\"status\"]; ok {\n\t\tout.Status = warehouse.Status(v)\n\t}\n")
+	assert.Contains(t, content,
+		"\tif v, err := strconv.ParseInt(in.Params[\"id\"], 10, 64); err == nil {\n\t\tout.ID = v\n\t}\n")
+	assert.Contains(t, content,
+		"\tif v, err := strconv.ParseInt(in.Params[\"count\"], 10, 0); err == nil {\n\t\tout.Count = int(v)\n")
+	assert.Contains(t, content, "\t\tout.Ratio = func() *float32 { p := float32(v); return &p }()\n")
+	assert.Contains(t, content, "\tif v, ok := in.Params[\"name\"]; ok {\n\t\tout.Name = &v\n")
+}
+
+func TestGenerator_Generate_GeneratedTargetOfSources(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic}
+
+	user := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "User"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}},
+	}
+	account := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Account"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Balance", Exported: true, Type: num}},
+	}
+	view := &analyze.TypeInfo{
+		ID:          analyze.TypeID{Name: "View"},
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Balance", Exported: true, Type: num, Index: 1},
 		},
 	}
 
-	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	pair := func(src *analyze.TypeInfo, own, other string) plan.ResolvedTypePair {
+		return plan.ResolvedTypePair{
+			SourceType:        src,
+			TargetType:        view,
+			IsGeneratedTarget: true,
+			Mappings: []plan.ResolvedFieldMapping{
+				{SourcePaths: path(own), TargetPaths: path(own), Strategy: plan.StrategyDirectAssign},
+				{TargetPaths: path(other), Source: plan.MappingSourceSharedTarget, Strategy: plan.StrategyIgnore},
+			},
+		}
+	}
 
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
-		},
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{pair(user, "Name", "Balance"), pair(account, "Balance", "Name")},
 	}
 
-	p := &plan.ResolvedMappingPlan{
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// The target is declared once, by the caster of the first source.
+	users, accounts := string(files[0].Content), string(files[1].Content)
+	assert.Contains(t, users,
+		"type View struct {\n\tName    string `json:\"name\"`\n\tBalance int64  `json:\"balance\"`\n}\n")
+	assert.Contains(t, users, "\tout.Name = in.Name\n")
+	assert.NotContains(t, accounts, "type View struct")
+	assert.Contains(t, accounts, "func StoreAccountToCastersView(in store.Account) View {")
+	assert.Contains(t, accounts, "\tout.Balance = in.Balance\n")
+	assert.NotContains(t, accounts, "out.Name")
+}
+
+func TestGenerator_Generate_Flatten(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	address := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Address"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Street", Exported: true, Type: str}},
+	}
+	nested := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/store", Name: "Customer"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Address", Exported: true, Type: address}},
+	}
+	flat := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Customer"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "AddressStreet", Exported: true, Type: str}},
+	}
+
+	path := func(names ...string) []mapping.FieldPath {
+		segments := make([]mapping.PathSegment, 0, len(names))
+		for _, name := range names {
+			segments = append(segments, mapping.PathSegment{Name: name})
+		}
+
+		return []mapping.FieldPath{{Segments: segments}}
+	}
+
+	resolvedPlan := &plan.ResolvedMappingPlan{
 		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
-			// We don't necessarily need a mapping for TargetItem for this test,
-			// just need to check how Target refers to it.
+			{
+				SourceType: nested,
+				TargetType: flat,
+				Mappings: []plan.ResolvedFieldMapping{{
+					TargetPaths: path("AddressStreet"),
+					SourcePaths: path("Address", "Street"),
+					Strategy:    plan.StrategyDirectAssign,
+					Flattened:   "Address",
+				}},
+			},
+			{
+				SourceType: flat,
+				TargetType: nested,
+				Mappings: []plan.ResolvedFieldMapping{{
+					TargetPaths: path("Address", "Street"),
+					SourcePaths: path("AddressStreet"),
+					Strategy:    plan.StrategyDirectAssign,
+					Flattened:   "Address",
+				}},
+			},
 		},
-		TypeGraph: graph,
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
 	require.NoError(t, err)
+	require.Len(t, files, 2)
 
-	found := false
-
+	var content string
 	for _, f := range files {
-		if strings.Contains(f.Filename, "missing_types.go") {
-			found = true
-			content := string(f.Content)
-			assert.Contains(t, content, "Items []*TargetItem")
-			assert.NotContains(t, content, "Items []*testpkg.TargetItem")
-		}
+		content += string(f.Content)
 	}
 
-	assert.True(t, found)
+	assert.Contains(t, content, "\tout.AddressStreet = in.Address.Street\n")
+	assert.Contains(t, content, "\tout.Address.Street = in.AddressStreet\n")
 }
 
-func TestGenerateMissingTypesFile_ExternalTypeReference(t *testing.T) {
-	// Target has field of type time.Time
-	tgtType := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-		Fields: []analyze.FieldInfo{
-			{
-				Name: "CreatedAt",
-				Type: &analyze.TypeInfo{
-					ID:   analyze.TypeID{PkgPath: "time", Name: "Time"},
-					Kind: analyze.TypeKindStruct,
-				},
+func TestGenerator_Generate_AnonymousStructs(t *testing.T) {
+	// Meta struct{ Key string `json:"key"`; Count int } -> the same with Count int64
+	anonymous := func(count types.BasicKind) *analyze.TypeInfo {
+		st := types.NewStruct([]*types.Var{
+			types.NewField(token.NoPos, nil, "Key", types.Typ[types.String], false),
+			types.NewField(token.NoPos, nil, "Count", types.Typ[count], false),
+		}, []string{`json:"key"`, ""})
+
+		return &analyze.TypeInfo{
+			ID:     analyze.TypeID{Literal: types.TypeString(st, nil)},
+			Kind:   analyze.TypeKindStruct,
+			GoType: st,
+			Fields: []analyze.FieldInfo{
+				{Name: "Key", Exported: true, Index: 0, Type: &analyze.TypeInfo{
+					ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.String],
+				}},
+				{Name: "Count", Exported: true, Index: 1, Type: &analyze.TypeInfo{
+					ID: analyze.TypeID{Name: types.Typ[count].Name()}, Kind: analyze.TypeKindBasic, GoType: types.Typ[count],
+				}},
 			},
-		},
+		}
 	}
-	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
 
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"testpkg": {Name: "testpkg", Dir: "/abs/path/to/testpkg"},
-			"time":    {Name: "time", Dir: ""}, // External, dir empty?
-		},
+	srcMeta, tgtMeta := anonymous(types.Int), anonymous(types.Int64)
+	item := func(pkg string, meta *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:     analyze.TypeID{PkgPath: "example/" + pkg, Name: "Item"},
+			Kind:   analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{{Name: "Meta", Exported: true, Type: meta}},
+		}
 	}
 
-	p := &plan.ResolvedMappingPlan{
-		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
-		},
-		TypeGraph: graph,
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	resolvedPlan := &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: item("store", srcMeta),
+			TargetType: item("warehouse", tgtMeta),
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: path("Meta"), SourcePaths: path("Meta"), Strategy: plan.StrategyNestedCast,
+			}},
+			NestedPairs: []plan.NestedConversion{{
+				SourceType: srcMeta,
+				TargetType: tgtMeta,
+				ResolvedPair: &plan.ResolvedTypePair{
+					SourceType: srcMeta,
+					TargetType: tgtMeta,
+					Mappings: []plan.ResolvedFieldMapping{
+						{TargetPaths: path("Count"), SourcePaths: path("Count"), Strategy: plan.StrategyConvert},
+						{TargetPaths: path("Key"), SourcePaths: path("Key"), Strategy: plan.StrategyDirectAssign},
+					},
+				},
+			}},
+		}},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(resolvedPlan)
 	require.NoError(t, err)
+	require.Len(t, files, 1)
 
-	found := false
+	content := string(files[0].Content)
+	assert.Contains(t, content, "\tout.Meta = func(in struct {\n\t\tKey   string `json:\"key\"`\n\t\tCount int\n\t}) struct {\n")
+	assert.Contains(t, content, "\t\tout.Count = int64(in.Count)\n")
+	assert.Contains(t, content, "\t\tout.Key = in.Key\n")
+	assert.Contains(t, content, "\t}(in.Meta)\n")
+}
 
-	for _, f := range files {
-		if strings.Contains(f.Filename, "missing_types.go") {
-			found = true
-			content := string(f.Content)
-			assert.Contains(t, content, `import (`)
-			assert.Contains(t, content, `"time"`)
-			assert.Contains(t, content, "CreatedAt time.Time")
+func TestGenerator_Generate_FuncTemplate(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	structType := func(pkg, name string, fields ...analyze.FieldInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct, Fields: fields}
+	}
+	srcItem := structType("example/store", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	tgtItem := structType("example/warehouse", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	srcOrder := structType("example/store", "Order", analyze.FieldInfo{Name: "Item", Exported: true, Type: srcItem})
+	tgtOrder := structType("example/warehouse", "Order", analyze.FieldInfo{Name: "Item", Exported: true, Type: tgtItem})
+
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	newPlan := func() *plan.ResolvedMappingPlan {
+		return &plan.ResolvedMappingPlan{
+			FuncTemplate: "Map{{.SourceName}}To{{.TargetName}}",
+			TypePairs: []plan.ResolvedTypePair{
+				{
+					SourceType: srcOrder,
+					TargetType: tgtOrder,
+					Mappings: []plan.ResolvedFieldMapping{
+						{TargetPaths: path("Item"), SourcePaths: path("Item"), Strategy: plan.StrategyNestedCast},
+					},
+				},
+				{
+					SourceType: srcItem,
+					TargetType: tgtItem,
+					Mappings: []plan.ResolvedFieldMapping{
+						{TargetPaths: path("SKU"), SourcePaths: path("SKU"), Strategy: plan.StrategyDirectAssign},
+					},
+				},
+			},
 		}
 	}
 
-	assert.True(t, found)
+	files, err := NewGenerator(DefaultGeneratorConfig()).Generate(newPlan())
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	order := string(files[0].Content)
+	assert.Contains(t, order, "func MapOrderToOrder(in store.Order) warehouse.Order {")
+	assert.Contains(t, order, "out.Item = MapItemToItem(in.Item)")
+
+	t.Run("flag overrides the mapping file", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.FuncTemplate = "{{.SourceType}}As{{.TargetPkg}}"
+
+		files, err := NewGenerator(config).Generate(newPlan())
+		require.NoError(t, err)
+		assert.Contains(t, string(files[0].Content), "out.Item = StoreItemAsWarehouse(in.Item)")
+	})
+
+	t.Run("colliding casters", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.FuncTemplate = "Convert{{.TargetPkg}}"
+
+		_, err := NewGenerator(config).Generate(newPlan())
+		require.ErrorContains(t, err, "caster name ConvertWarehouse of example/store.Item->example/warehouse.Item "+
+			"collides with the caster of example/store.Order->example/warehouse.Order")
+	})
+
+	t.Run("colliding with hand-written code", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "legacy.go"),
+			[]byte("package casters\n\nfunc MapItemToItem() {}\n"), 0o600))
+
+		config := DefaultGeneratorConfig()
+		config.OutputDir = dir
+
+		_, err := NewGenerator(config).Generate(newPlan())
+		require.ErrorContains(t, err, "caster name MapItemToItem of example/store.Item->example/warehouse.Item "+
+			"collides with a declaration of legacy.go")
+	})
+
+	t.Run("colliding with a transform", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.DeclaredTransforms = map[string]bool{"MapOrderToOrder": true}
+
+		_, err := NewGenerator(config).Generate(newPlan())
+		require.ErrorContains(t, err, "collides with transform MapOrderToOrder")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		config := DefaultGeneratorConfig()
+		config.FuncTemplate = "{{.Source}}To{{.Target}}"
+
+		_, err := NewGenerator(config).Generate(newPlan())
+		require.ErrorContains(t, err, "invalid naming template")
+	})
 }
 
-func TestGenerateMissingTypesFile_DifferentPackages(t *testing.T) {
-	tgt1 := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "pkg1", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-	}
-	tgt2 := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "pkg2", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-	}
-	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
+func TestGenerator_Generate_Overrides(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	node := func(pkg string) *analyze.TypeInfo {
+		n := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: "Node"}, Kind: analyze.TypeKindStruct}
+		n.Fields = []analyze.FieldInfo{
+			{Name: "Value", Exported: true, Type: str},
+			{Name: "Next", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: n}},
+		}
 
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"pkg1": {Name: "pkg1", Dir: "/path/to/pkg1"},
-			"pkg2": {Name: "pkg2", Dir: "/path/to/pkg2"},
-		},
+		return n
 	}
+	src, tgt, canonical := node("example/store"), node("example/warehouse"), node("example/canonical")
 
-	p := &plan.ResolvedMappingPlan{
-		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgt1, IsGeneratedTarget: true},
-			{SourceType: src, TargetType: tgt2, IsGeneratedTarget: true},
-		},
-		TypeGraph: graph,
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+	pair := func(src, tgt *analyze.TypeInfo) plan.ResolvedTypePair {
+		return plan.ResolvedTypePair{
+			SourceType: src,
+			TargetType: tgt,
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("Next"), SourcePaths: path("Next"), Strategy: plan.StrategyPointerNestedCast},
+				{TargetPaths: path("Value"), SourcePaths: path("Value"), Strategy: plan.StrategyDirectAssign},
+			},
+			NestedPairs: []plan.NestedConversion{{SourceType: src, TargetType: tgt}},
+		}
 	}
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	config := GeneratorConfig{PackageName: "casters", Overrides: true}
+
+	files, err := NewGenerator(config).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{pair(src, tgt)},
+	})
 	require.NoError(t, err)
+	require.Len(t, files, 1)
 
-	missingFiles := 0
+	content := string(files[0].Content)
 
-	for _, f := range files {
-		if strings.Contains(f.Filename, "missing_types.go") {
-			missingFiles++
+	// Overrides are applied to the result; nested casters are called without them
+	assert.Contains(t, content, "func StoreNodeToWarehouseNode(in store.Node, overrides ...func(*warehouse.Node)) "+
+		"warehouse.Node {")
+	assert.Contains(t, content, "\tfor _, override := range overrides {\n\t\toverride(&out)\n\t}\n\n\treturn out\n}")
+	assert.Contains(t, content, "v := StoreNodeToWarehouseNode(*in.Next)")
 
-			content := string(f.Content)
-			if strings.Contains(content, "package pkg1") {
-				assert.Contains(t, f.Filename, "pkg1")
-			} else if strings.Contains(content, "package pkg2") {
-				assert.Contains(t, f.Filename, "pkg2")
-			}
+	t.Run("depth-limited", func(t *testing.T) {
+		limited := pair(src, tgt)
+		limited.MaxDepth = 2
+
+		files, err := NewGenerator(config).Generate(&plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{limited},
+		})
+		require.NoError(t, err)
+
+		content := string(files[0].Content)
+		assert.Contains(t, content, "\tout := storeNodeToWarehouseNodeAtDepth(in, 0)\n"+
+			"\tfor _, override := range overrides {")
+		assert.Equal(t, 1, strings.Count(content, "override(&out)"))
+	})
+
+	t.Run("via", func(t *testing.T) {
+		via := plan.ResolvedTypePair{
+			SourceType: src,
+			TargetType: tgt,
+			Via:        canonical,
+			NestedPairs: []plan.NestedConversion{
+				{SourceType: src, TargetType: canonical},
+				{SourceType: canonical, TargetType: tgt},
+			},
 		}
-	}
 
-	assert.Equal(t, 2, missingFiles)
+		files, err := NewGenerator(config).Generate(&plan.ResolvedMappingPlan{
+			TypePairs: []plan.ResolvedTypePair{via, pair(src, canonical), pair(canonical, tgt)},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, string(files[0].Content),
+			"\tout := CanonicalNodeToWarehouseNode(StoreNodeToCanonicalNode(in))\n"+
+				"\tfor _, override := range overrides {")
+	})
 }
 
-func TestGenerateMissingTypesFile_NoPackagePath(t *testing.T) {
-	// Target has empty PkgPath -> should be embedded in caster file
-	tgtType := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-		Fields: []analyze.FieldInfo{{
-			Name: "F",
-			Type: &analyze.TypeInfo{
-				ID:   analyze.TypeID{Name: "int"},
-				Kind: analyze.TypeKindBasic}}},
+func TestGenerator_Generate_Hooks(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	order := func(pkg string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:     analyze.TypeID{PkgPath: pkg, Name: "Order"},
+			Kind:   analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}},
+		}
 	}
-	src := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Source"}, Kind: analyze.TypeKindStruct}
 
-	// Empty graph ok?
-	graph := &analyze.TypeGraph{Packages: map[string]*analyze.PackageInfo{}}
+	graph := analyze.NewTypeGraph()
+	normalize := analyze.TypeID{PkgPath: "example/hooks", Name: "Normalize"}
+	graph.Funcs[normalize] = &analyze.FuncInfo{ID: normalize}
 
-	p := &plan.ResolvedMappingPlan{
-		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
-		},
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(&plan.ResolvedMappingPlan{
 		TypeGraph: graph,
-	}
-
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: order("example/store"),
+			TargetType: order("example/warehouse"),
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+				SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+				Strategy:    plan.StrategyDirectAssign,
+			}},
+			Before:        "hooks.Normalize",
+			After:         "stampOrder",
+			GenerateMerge: true,
+		}},
+	})
 	require.NoError(t, err)
+	require.Len(t, files, 1)
 
-	for _, f := range files {
-		assert.NotContains(t, f.Filename, "missing_types.go")
+	content := string(files[0].Content)
 
-		if strings.HasSuffix(f.Filename, ".go") {
-			content := string(f.Content)
-			assert.Contains(t, content, "type Target struct")
-		}
-	}
+	// Loaded hooks are imported, others are called as written
+	assert.Contains(t, content, "\thooks \"example/hooks\"\n")
+	assert.Contains(t, content, "warehouse.Order {\n\tin = hooks.Normalize(in)\n\n\tout := warehouse.Order{}\n")
+	assert.Contains(t, content, "\tstampOrder(in, &out)\n\n\treturn out\n}")
+
+	// The merge variant calls them too
+	assert.Contains(t, content, "out *warehouse.Order) {\n\tin = hooks.Normalize(in)\n")
+	assert.Contains(t, content, "\tstampOrder(in, out)\n}")
 }
 
-func TestCasterFile_ImportsGeneratedType(t *testing.T) {
-	tgtType := &analyze.TypeInfo{
-		ID:          analyze.TypeID{PkgPath: "testpkg", Name: "Target"},
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
+func TestGenerator_Generate_ImplicitPairs(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	typ := func(pkg, name string, fields ...analyze.FieldInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct,
+			Fields: fields}
 	}
-	src := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "srcpkg", Name: "Source"},
-		Kind: analyze.TypeKindStruct,
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
 	}
 
-	graph := &analyze.TypeGraph{
-		Packages: map[string]*analyze.PackageInfo{
-			"testpkg": {Name: "testpkg", Dir: "/path/to/testpkg"},
-			"srcpkg":  {Name: "srcpkg", Dir: "/path/to/srcpkg"},
-		},
+	srcItem := typ("example/store", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	dstItem := typ("example/warehouse", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	item := &plan.ResolvedTypePair{
+		SourceType: srcItem,
+		TargetType: dstItem,
+		Mappings:   []plan.ResolvedFieldMapping{{TargetPaths: path("SKU"), SourcePaths: path("SKU")}},
+		Implicit:   true,
 	}
 
-	p := &plan.ResolvedMappingPlan{
-		TypePairs: []plan.ResolvedTypePair{
-			{SourceType: src, TargetType: tgtType, IsGeneratedTarget: true},
-		},
-		TypeGraph: graph,
+	// Both casters call the caster of the Item pair, which no mapping declares
+	pair := func(name string) plan.ResolvedTypePair {
+		return plan.ResolvedTypePair{
+			SourceType: typ("example/store", name, analyze.FieldInfo{Name: "Item", Exported: true, Type: srcItem}),
+			TargetType: typ("example/warehouse", name, analyze.FieldInfo{Name: "Item", Exported: true, Type: dstItem}),
+			Mappings: []plan.ResolvedFieldMapping{
+				{TargetPaths: path("Item"), SourcePaths: path("Item"), Strategy: plan.StrategyNestedCast},
+			},
+			NestedPairs: []plan.NestedConversion{{SourceType: srcItem, TargetType: dstItem, ResolvedPair: item}},
+		}
 	}
+	order := pair("Order")
 
-	gen := NewGenerator(DefaultGeneratorConfig())
-	files, err := gen.Generate(p)
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{order, pair("Shipment"), order},
+	})
 	require.NoError(t, err)
 
+	names := make([]string, 0, len(files))
+	defined := 0
+
 	for _, f := range files {
-		if !strings.Contains(f.Filename, "missing_types.go") {
-			// This is the caster file
-			content := string(f.Content)
-			assert.Contains(t, content, `import (`)
-			// Should import testpkg
-			assert.Contains(t, content, `"testpkg"`)
-			// Function signature return type
-			assert.Contains(t, content, "testpkg.Target")
-			// Instantiation
-			assert.Contains(t, content, "out := testpkg.Target{}")
-		}
+		names = append(names, f.Filename)
+		defined += strings.Count(string(f.Content), "func StoreItemToWarehouseItem(")
 	}
+
+	assert.Equal(t, []string{
+		"store_order_to_warehouse_order.go",
+		"store_shipment_to_warehouse_shipment.go",
+		"store_item_to_warehouse_item.go",
+	}, names)
+	assert.Equal(t, 1, defined)
 }
 
-func TestTypeRefString_ContextPackagePath(t *testing.T) {
-	g := &Generator{}
-	imports := make(map[string]importSpec)
+func TestGenerator_Generate_SourceMethods(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	typ := func(pkg, name string, fields ...analyze.FieldInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct,
+			Fields: fields}
+	}
+	path := func(name string) []mapping.FieldPath {
+		return []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: name}}}}
+	}
+
+	srcItem := typ("example/store", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	dstItem := typ("example/warehouse", "Item", analyze.FieldInfo{Name: "SKU", Exported: true, Type: str})
+	item := &plan.ResolvedTypePair{
+		SourceType: srcItem,
+		TargetType: dstItem,
+		Mappings:   []plan.ResolvedFieldMapping{{TargetPaths: path("SKU"), SourcePaths: path("SKU")}},
+		Implicit:   true,
+	}
+	order := plan.ResolvedTypePair{
+		SourceType: typ("example/store", "Order", analyze.FieldInfo{Name: "Item", Exported: true, Type: srcItem}),
+		TargetType: typ("example/warehouse", "Order", analyze.FieldInfo{Name: "Item", Exported: true, Type: dstItem}),
+		Mappings: []plan.ResolvedFieldMapping{
+			{TargetPaths: path("Item"), SourcePaths: path("Item"), Strategy: plan.StrategyNestedCast},
+		},
+		NestedPairs: []plan.NestedConversion{{SourceType: srcItem, TargetType: dstItem, ResolvedPair: item}},
+		Method:      "ToWarehouse",
+	}
 
-	// Case 1: Matching context -> no prefix
-	g.contextPkgPath = "my/pkg"
-	typMatched := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "my/pkg", Name: "Foo"},
-		Kind: analyze.TypeKindStruct,
+	dir := t.TempDir()
+	storeDir := filepath.Join(dir, "store")
+	require.NoError(t, os.Mkdir(storeDir, 0o755))
+
+	graph := analyze.NewTypeGraph()
+	graph.Packages["example/store"] = &analyze.PackageInfo{Path: "example/store", Name: "store", Dir: storeDir}
+
+	generate := func() ([]GeneratedFile, error) {
+		return NewGenerator(GeneratorConfig{PackageName: "casters", OutputDir: filepath.Join(dir, "casters")}).
+			Generate(&plan.ResolvedMappingPlan{TypePairs: []plan.ResolvedTypePair{order}, TypeGraph: graph})
 	}
-	assert.Equal(t, "Foo", g.typeRefString(typMatched, imports))
-	assert.Empty(t, imports)
 
-	// Case 2: Different context -> prefix + import
-	typOther := &analyze.TypeInfo{
-		ID:   analyze.TypeID{PkgPath: "other/pkg", Name: "Bar"},
-		Kind: analyze.TypeKindStruct,
+	t.Run("generated into the source package", func(t *testing.T) {
+		files, err := generate()
+		require.NoError(t, err)
+
+		// The implicit Item pair follows its caller into the source package
+		require.Len(t, files, 2)
+		assert.Equal(t, filepath.Join("..", "store", "store_order_to_warehouse_order.go"), files[0].Filename)
+		assert.Equal(t, filepath.Join("..", "store", "store_item_to_warehouse_item.go"), files[1].Filename)
+
+		code := string(files[0].Content)
+		assert.Contains(t, code, "package store\n")
+		assert.Contains(t, code, "func (in Order) ToWarehouse() warehouse.Order {")
+		assert.Contains(t, code, "out.Item = StoreItemToWarehouseItem(in.Item)")
+	})
+
+	t.Run("nested method call", func(t *testing.T) {
+		g := NewGenerator(GeneratorConfig{PackageName: "casters"})
+		g.sourceMethods = map[string]string{"example/store.Item->example/warehouse.Item": "ToWarehouse"}
+
+		assert.Equal(t, "in.Item.ToWarehouse()", g.nestedCall(srcItem, dstItem, "in.Item"))
+		assert.Equal(t, "(*in.Main).ToWarehouse()", g.nestedCall(srcItem, dstItem, "*in.Main"))
+	})
+
+	t.Run("hand-written method collision", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(storeDir, "order.go"),
+			[]byte("package store\n\nfunc (o *Order) ToWarehouse() {}\n"), 0o644))
+
+		_, err := generate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "method example/store.Order.ToWarehouse collides with a declaration of order.go")
+	})
+}
+
+func TestGenerator_Generate_KeyedSourcePaths(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	typ := func(pkg, name string, fields ...analyze.FieldInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct,
+			Fields: fields}
 	}
-	assert.Equal(t, "pkg.Bar", g.typeRefString(typOther, imports))
-	assert.Contains(t, imports, "other/pkg")
+	field := func(name string, t *analyze.TypeInfo) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: t}
+	}
+	mapped := func(source, target string) plan.ResolvedFieldMapping {
+		sp, err := mapping.ParsePath(source)
+		require.NoError(t, err)
+
+		return plan.ResolvedFieldMapping{
+			TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: target}}}},
+			SourcePaths: []mapping.FieldPath{sp},
+			Strategy:    plan.StrategyDirectAssign,
+		}
+	}
+
+	line := typ("example/store", "Line", field("SKU", str))
+	pair := plan.ResolvedTypePair{
+		SourceType: typ("example/store", "Order",
+			field("Lines", &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: line}),
+			field("Refs", &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: &analyze.TypeInfo{
+				Kind: analyze.TypeKindPointer, ElemType: line,
+			}}),
+			field("Attrs", &analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: str, ElemType: str}),
+		),
+		TargetType: typ("example/warehouse", "Summary",
+			field("FirstSKU", str), field("RefSKU", str), field("Color", str)),
+		Mappings: []plan.ResolvedFieldMapping{
+			mapped("Lines[0].SKU", "FirstSKU"),
+			mapped("Refs[1].SKU", "RefSKU"),
+			mapped(`Attrs["color"]`, "Color"),
+		},
+	}
+
+	files, err := NewGenerator(GeneratorConfig{PackageName: "casters"}).Generate(&plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{pair},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	code := string(files[0].Content)
+	assert.Contains(t, code, "\tif len(in.Lines) > 0 {\n\t\tout.FirstSKU = in.Lines[0].SKU\n\t}\n")
+	assert.Contains(t, code, "\tif len(in.Refs) > 1 && in.Refs[1] != nil {\n\t\tout.RefSKU = in.Refs[1].SKU\n\t}\n")
+	assert.Contains(t, code, "\tout.Color = in.Attrs[\"color\"]\n")
 }
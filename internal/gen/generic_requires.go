@@ -0,0 +1,93 @@
+package gen
+
+import (
+	"strings"
+
+	"caster-generator/internal/common"
+	"caster-generator/internal/plan"
+)
+
+// genericRequires returns the type parameter name standing in for each untyped
+// requires argument of pair, keyed by argument name. Only arguments passed
+// through verbatim to transforms and nested casters qualify: any other use
+// needs a concrete type. The result is empty unless GenericRequires is set.
+func (g *Generator) genericRequires(pair *plan.ResolvedTypePair) map[string]string {
+	if !g.config.GenericRequires {
+		return nil
+	}
+
+	var names []string
+
+	for _, req := range pair.Requires {
+		if isUntypedArg(req.Type) && passedThrough(pair, req.Name) {
+			names = append(names, req.Name)
+		}
+	}
+
+	params := make(map[string]string, len(names))
+
+	for _, name := range names {
+		// A lone parameter is plain T; several are told apart by argument name.
+		if len(names) == 1 {
+			params[name] = "T"
+			continue
+		}
+
+		params[name] = "T" + strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	return params
+}
+
+// typeParamList returns the type parameter list of a caster using params in
+// requires order (e.g., "[T any]"), or an empty string.
+func typeParamList(pair *plan.ResolvedTypePair, params map[string]string) string {
+	var list []string
+
+	for _, req := range pair.Requires {
+		if param, ok := params[req.Name]; ok {
+			list = append(list, param+" any")
+		}
+	}
+
+	if len(list) == 0 {
+		return ""
+	}
+
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+// isUntypedArg reports whether a requires argument type was left to the
+// interface{} default.
+func isUntypedArg(typ string) bool {
+	return typ == "" || typ == common.InterfaceTypeStr || typ == "any"
+}
+
+// passedThrough reports whether the requires argument name is used by pair,
+// and only as a whole value handed to a transform or nested caster.
+func passedThrough(pair *plan.ResolvedTypePair, name string) bool {
+	used := false
+
+	for _, m := range pair.Mappings {
+		for _, sp := range m.SourcePaths {
+			if len(sp.Segments) == 0 || sp.Segments[0].Name != name {
+				continue
+			}
+
+			// Direct assignments and field accesses need a concrete type.
+			if m.Transform == "" || len(sp.Segments) > 1 {
+				return false
+			}
+
+			used = true
+		}
+
+		for _, ev := range m.Extra {
+			if ev.Name == name && ev.Def.Source == "" && ev.Def.Target == "" && ev.Const == "" {
+				used = true
+			}
+		}
+	}
+
+	return used
+}
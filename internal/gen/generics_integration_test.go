@@ -0,0 +1,9 @@
+package gen_test
+
+import (
+	"testing"
+)
+
+func TestGenerate_GenericsExample_Compiles(t *testing.T) {
+	runExampleIntegrationTest(t, "generics")
+}
@@ -0,0 +1,136 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"maps"
+	"sort"
+	"text/template"
+
+	"caster-generator/internal/plan"
+)
+
+// testsFile is the name of the file holding the golden caster tests.
+const testsFile = "casters_golden_test.go"
+
+// testsTemplateData holds data for the tests file template.
+type testsTemplateData struct {
+	PackageName string
+	Imports     []importSpec
+	Tests       []casterFixture
+	Skipped     []casterFixture
+	UsesPtr     bool
+}
+
+// generateTestsFile generates a golden test of each caster, checking the
+// fields of fieldChecks. Casters without such fields are still run, so a
+// panic fails their test. Casters calling transform stubs get none.
+func (g *Generator) generateTestsFile(p *plan.ResolvedMappingPlan) (*GeneratedFile, error) {
+	stubs := g.stubCallers(p)
+
+	data := &testsTemplateData{PackageName: g.config.PackageName}
+	imports := make(map[string]importSpec)
+	seen := make(map[string]bool)
+
+	for _, fx := range g.fixtures {
+		if seen[fx.Caster] {
+			continue
+		}
+
+		seen[fx.Caster] = true
+
+		if fx.Skip == "" && stubs[fx.pairKey] {
+			fx.Skip = "it calls transform stubs, which panic"
+		}
+
+		if fx.Skip != "" {
+			data.Skipped = append(data.Skipped, fx)
+			continue
+		}
+
+		maps.Copy(imports, fx.imports)
+
+		// Tests name the source type only to declare a zero-valued fixture
+		if fx.Fixture == "" {
+			maps.Copy(imports, fx.sourceImports)
+		}
+
+		for _, check := range fx.Checks {
+			if check.Deep {
+				imports["reflect"] = importSpec{Path: "reflect"}
+			}
+		}
+
+		data.Tests = append(data.Tests, fx)
+		data.UsesPtr = data.UsesPtr || fx.usesPtr
+	}
+
+	if len(data.Tests) > 0 {
+		imports["testing"] = importSpec{Path: "testing"}
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := testsTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, testsFile, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: testsFile,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: testsFile,
+		Content:  formatted,
+	}, nil
+}
+
+var testsTemplate = template.Must(template.New("tests").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+{{range .Skipped}}// No test for {{.Caster}}: {{.Skip}}.
+{{end}}
+{{range .Tests}}
+// Test{{.Name}} runs {{.Caster}} on a synthesized {{.SourceType}}.
+func Test{{.Name}}(t *testing.T) {
+{{if .UsesContext}}	ctx := context.Background()
+{{end}}{{if .Methods}}	c := NewCasters()
+{{end}}{{if .Fixture}}	in := {{.Fixture}}
+{{else}}	var in {{.SourceType}}
+{{end}}{{range .ExtraArgs}}	var {{.Name}} {{.Type}}
+{{end}}
+{{if .Checks}}	out := {{.Call}}
+{{range .Checks}}
+	if got, want := out.{{.Target}}, {{.Want}}; {{if .Deep}}!reflect.DeepEqual(got, want){{else}}got != want{{end}} {
+		t.Errorf("{{.Target}} = %v, want %v", got, want)
+	}
+{{end}}{{else}}	_ = {{.Call}}
+{{end}}}
+{{end}}{{if .UsesPtr}}
+// fixturePtr returns a pointer to a copy of v.
+func fixturePtr[T any](v T) *T {
+	return &v
+}
+{{end}}`))
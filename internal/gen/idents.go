@@ -0,0 +1,78 @@
+package gen
+
+import (
+	"fmt"
+
+	"caster-generator/internal/plan"
+)
+
+// identScope allocates the local identifiers of one generated assignment, so
+// loop and temporary variables never shadow each other, the caster's
+// parameters or its requires arguments. Names depend only on allocation order.
+type identScope struct {
+	used map[string]bool
+}
+
+// newIdentScope returns a scope in which the reserved names are taken.
+func newIdentScope(reserved ...string) *identScope {
+	s := &identScope{used: make(map[string]bool)}
+	for _, name := range reserved {
+		s.used[name] = true
+	}
+
+	return s
+}
+
+// name returns base if it is free, otherwise the first free base_N.
+func (s *identScope) name(base string) string {
+	if !s.used[base] {
+		s.used[base] = true
+		return base
+	}
+
+	return s.indexed(base)
+}
+
+// indexed returns the first free base_N, counting from zero.
+func (s *identScope) indexed(base string) string {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s_%d", base, i)
+		if !s.used[name] {
+			s.used[name] = true
+			return name
+		}
+	}
+}
+
+// assignmentScope returns a fresh scope for an assignment of pair's caster,
+// reserving its parameters, requires arguments and receiver.
+func (g *Generator) assignmentScope(pair *plan.ResolvedTypePair) *identScope {
+	reserved := []string{"in", "out", "ctx"}
+	if g.methods() {
+		reserved = append(reserved, methodsReceiver)
+	}
+
+	for _, req := range pair.Requires {
+		reserved = append(reserved, req.Name)
+	}
+
+	return newIdentScope(reserved...)
+}
+
+// local returns a fresh local identifier based on base in the current assignment scope.
+func (g *Generator) local(base string) string {
+	if g.idents == nil {
+		g.idents = newIdentScope("in", "out", "ctx")
+	}
+
+	return g.idents.name(base)
+}
+
+// localIndexed returns a fresh base_N identifier in the current assignment scope.
+func (g *Generator) localIndexed(base string) string {
+	if g.idents == nil {
+		g.idents = newIdentScope("in", "out", "ctx")
+	}
+
+	return g.idents.indexed(base)
+}
@@ -0,0 +1,18 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentScope(t *testing.T) {
+	s := newIdentScope("in", "out", "v")
+
+	assert.Equal(t, "i_0", s.indexed("i"))
+	assert.Equal(t, "i_1", s.indexed("i"))
+	assert.Equal(t, "v_0", s.name("v"))
+	assert.Equal(t, "v_1", s.indexed("v"))
+	assert.Equal(t, "r", s.name("r"))
+	assert.Equal(t, "r_0", s.name("r"))
+}
@@ -0,0 +1,139 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/plan"
+)
+
+// collectInlinePairs indexes the resolved conversions between anonymous
+// structs reached from pairs, by type pair. Such types have no name to declare
+// a caster for, so their conversions are generated inline.
+func collectInlinePairs(pairs []plan.ResolvedTypePair) map[string]*plan.ResolvedTypePair {
+	inline := make(map[string]*plan.ResolvedTypePair)
+
+	var walk func(pair *plan.ResolvedTypePair)
+
+	walk = func(pair *plan.ResolvedTypePair) {
+		for _, nested := range pair.NestedPairs {
+			if nested.ResolvedPair == nil || !nested.Inline() {
+				continue
+			}
+
+			key := fmt.Sprintf("%s->%s", nested.SourceType.ID, nested.TargetType.ID)
+			if _, ok := inline[key]; !ok {
+				inline[key] = nested.ResolvedPair
+				walk(nested.ResolvedPair)
+			}
+		}
+	}
+
+	for i := range pairs {
+		walk(&pairs[i])
+	}
+
+	return inline
+}
+
+// inlineCall converts arg between the anonymous structs of pair with a
+// function literal called in place, holding the assignments a caster of the
+// pair would. The literal captures ctx and the requires arguments of the
+// enclosing caster.
+func (g *Generator) inlineCall(pair *plan.ResolvedTypePair, arg string) string {
+	imports := make(map[string]importSpec)
+	srcType := g.typeRefString(pair.SourceType, imports)
+	tgtType := g.typeRefString(pair.TargetType, imports)
+
+	// The assignments of the literal allocate their locals in their own scope.
+	idents := g.idents
+	defer func() { g.idents = idents }()
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "func(in %s) %s {\n\tout := %s{}\n", srcType, tgtType, tgtType)
+
+	for i := range pair.Mappings {
+		assignment := g.buildAssignment(&pair.Mappings[i], pair, imports)
+		if assignment == nil {
+			continue
+		}
+
+		sb.WriteString("\n")
+
+		if err := casterTemplate.ExecuteTemplate(&sb, "assignment", assignment); err != nil && g.templateErr == nil {
+			g.templateErr = fmt.Errorf("inline conversion %s -> %s: %w", srcType, tgtType, err)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n\treturn out\n}(%s)", arg)
+
+	// Imports of the types spelled by the literal, merged into the caster's
+	if g.foreignImports == nil {
+		g.foreignImports = make(map[string]importSpec)
+	}
+
+	for path, spec := range imports {
+		g.foreignImports[path] = spec
+	}
+
+	return sb.String()
+}
+
+// structLiteral spells an anonymous struct type, qualifying the types of its
+// fields like any other type reference.
+func (g *Generator) structLiteral(t *analyze.TypeInfo, imports map[string]importSpec) string {
+	st, ok := t.GoType.(*types.Struct)
+	if !ok {
+		return t.ID.Literal
+	}
+
+	fields := make(map[int]*analyze.TypeInfo, len(t.Fields))
+	for _, f := range t.Fields {
+		fields[f.Index] = f.Type
+	}
+
+	parts := make([]string, 0, st.NumFields())
+
+	for i := range st.NumFields() {
+		f := st.Field(i)
+
+		// Unexported fields aren't analyzed, and their types only spelled
+		// in their own package anyway
+		typ := types.TypeString(f.Type(), func(p *types.Package) string { return p.Name() })
+		if ft, ok := fields[i]; ok {
+			typ = g.typeRefString(ft, imports)
+		}
+
+		part := f.Name() + " " + typ
+		if f.Embedded() {
+			part = typ
+		}
+
+		if tag := st.Tag(i); tag != "" {
+			part += " " + quoteTag(tag)
+		}
+
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 0 {
+		return "struct{}"
+	}
+
+	// One field per line, which gofmt needs to lay out the function
+	// literals spelling the type.
+	return "struct {\n" + strings.Join(parts, "\n") + "\n}"
+}
+
+// quoteTag quotes a struct tag, raw unless it holds a backquote.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+
+	return "`" + tag + "`"
+}
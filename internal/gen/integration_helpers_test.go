@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func runExampleIntegrationTest(t *testing.T, exampleName string) {
+func runExampleIntegrationTest(t *testing.T, exampleName string, genArgs ...string) {
 	t.Helper()
 
 	repoRoot, err := filepath.Abs(filepath.Join("..", ".."))
@@ -26,6 +26,7 @@ func runExampleIntegrationTest(t *testing.T, exampleName string) {
 		"-mapping", filepath.Join(exampleDir, "map.yaml"),
 		"-out", outDir,
 	)
+	cmd.Args = append(cmd.Args, genArgs...)
 	cmd.Dir = repoRoot
 
 	b, err := cmd.CombinedOutput()
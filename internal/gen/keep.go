@@ -0,0 +1,387 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// KeepMarker marks a function of a generated file as edited by hand. Put on
+// its own line in the function's doc comment, it makes gen keep the function
+// instead of regenerating it.
+const KeepMarker = "//caster:keep"
+
+// KeptFunc is a function marked with KeepMarker in a previously generated file.
+type KeptFunc struct {
+	// Name is the function name, qualified by the receiver type for methods
+	// (e.g., "Casters.StoreOrderToWarehouseOrder").
+	Name string
+	// File is the file the function was found in.
+	File string
+	// Signature is the function's type, without parameter names.
+	Signature string
+	// Source is the declaration, including its doc comment.
+	Source []byte
+	// Imports are the imports of File the declaration uses.
+	Imports []importSpec
+}
+
+// LoadKeptFuncs parses the Go files in dir and returns the functions marked
+// with KeepMarker, keyed by name. A missing dir has none.
+func LoadKeptFuncs(dir string) (map[string]KeptFunc, error) {
+	kept := make(map[string]KeptFunc)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+
+	for _, p := range paths {
+		// Debug variants get the kept functions of their caster files
+		if strings.HasSuffix(p, "_test.go") || strings.HasSuffix(p, debugFileSuffix) {
+			continue
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+
+		if !bytes.Contains(content, []byte(KeepMarker)) {
+			continue
+		}
+
+		funcs, err := parseKeptFuncs(filepath.Base(p), content)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fn := range funcs {
+			if prev, exists := kept[fn.Name]; exists {
+				return nil, fmt.Errorf("kept function %s is declared in both %s and %s", fn.Name, prev.File, fn.File)
+			}
+
+			kept[fn.Name] = fn
+		}
+	}
+
+	return kept, nil
+}
+
+// parseKeptFuncs returns the functions of file marked with KeepMarker.
+func parseKeptFuncs(filename string, content []byte) ([]KeptFunc, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	var funcs []KeptFunc
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !hasKeepMarker(fn) {
+			continue
+		}
+
+		start := fset.Position(fn.Doc.Pos()).Offset
+		end := fset.Position(fn.End()).Offset
+
+		funcs = append(funcs, KeptFunc{
+			Name:      funcDeclName(fn),
+			File:      filename,
+			Signature: funcSignature(fset, fn),
+			Source:    content[start:end],
+			Imports:   usedImports(file, fn),
+		})
+	}
+
+	return funcs, nil
+}
+
+// hasKeepMarker reports whether the doc comment of fn has a KeepMarker line.
+func hasKeepMarker(fn *ast.FuncDecl) bool {
+	if fn.Doc == nil {
+		return false
+	}
+
+	for _, c := range fn.Doc.List {
+		if strings.TrimSpace(c.Text) == KeepMarker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// funcDeclName returns the name of fn, qualified by its receiver type for methods.
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		recv = r.X
+	case *ast.IndexListExpr:
+		recv = r.X
+	}
+
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+
+	return fn.Name.Name
+}
+
+// funcSignature formats the type parameters, receiver, parameters and results
+// of fn without their names, so renaming a parameter keeps the signature.
+func funcSignature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	var sb strings.Builder
+
+	if fn.Recv != nil {
+		sb.WriteString("(" + fieldTypes(fset, fn.Recv) + ") ")
+	}
+
+	sb.WriteString("func")
+
+	if fn.Type.TypeParams != nil {
+		sb.WriteString("[" + fieldTypes(fset, fn.Type.TypeParams) + "]")
+	}
+
+	sb.WriteString("(" + fieldTypes(fset, fn.Type.Params) + ")")
+
+	if fn.Type.Results != nil {
+		sb.WriteString(" (" + fieldTypes(fset, fn.Type.Results) + ")")
+	}
+
+	return sb.String()
+}
+
+// fieldTypes formats the types of fields, one per name.
+func fieldTypes(fset *token.FileSet, fields *ast.FieldList) string {
+	var types []string
+
+	for _, field := range fields.List {
+		var buf bytes.Buffer
+		_ = printer.Fprint(&buf, fset, field.Type)
+
+		for range max(len(field.Names), 1) {
+			types = append(types, buf.String())
+		}
+	}
+
+	return strings.Join(types, ", ")
+}
+
+// usedImports returns the imports of file that fn refers to.
+func usedImports(file *ast.File, fn *ast.FuncDecl) []importSpec {
+	used := make(map[string]bool)
+
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+
+		return true
+	})
+
+	var imports []importSpec
+
+	for _, imp := range file.Imports {
+		spec := importSpecOf(imp)
+		if used[importName(spec)] {
+			imports = append(imports, spec)
+		}
+	}
+
+	return imports
+}
+
+// importSpecOf converts an import declaration to an importSpec.
+func importSpecOf(imp *ast.ImportSpec) importSpec {
+	p, _ := strconv.Unquote(imp.Path.Value)
+
+	spec := importSpec{Path: p}
+	if imp.Name != nil {
+		spec.Alias = imp.Name.Name
+	}
+
+	return spec
+}
+
+// importName returns the name an import is referred to by, assuming unaliased
+// packages are named after the last element of their path.
+func importName(spec importSpec) string {
+	if spec.Alias != "" {
+		return spec.Alias
+	}
+
+	return path.Base(spec.Path)
+}
+
+// applyKept replaces the functions of files marked as kept in the previous
+// generation by their kept declarations. Kept functions must still be
+// generated and with the same signature.
+func (g *Generator) applyKept(files []GeneratedFile) error {
+	if len(g.config.Kept) == 0 {
+		return nil
+	}
+
+	found := make(map[string]bool)
+
+	for i := range files {
+		content, err := g.keepFuncs(files[i], found)
+		if err != nil {
+			return fmt.Errorf("%s: %w", files[i].Filename, err)
+		}
+
+		files[i].Content = content
+	}
+
+	var gone []string
+
+	for name := range g.config.Kept {
+		if !found[name] {
+			gone = append(gone, name)
+		}
+	}
+
+	if len(gone) > 0 {
+		sort.Strings(gone)
+
+		return fmt.Errorf("kept functions are no longer generated: %s (move them out of the output directory or remove their %s marker)",
+			strings.Join(gone, ", "), KeepMarker)
+	}
+
+	return nil
+}
+
+// keepFuncs returns the content of file with its kept functions spliced in,
+// recording their names in found.
+func (g *Generator) keepFuncs(file GeneratedFile, found map[string]bool) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	parsed, err := parser.ParseFile(fset, file.Filename, file.Content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	type splice struct {
+		start, end int
+		kept       KeptFunc
+	}
+
+	var splices []splice
+
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		kept, ok := g.config.Kept[funcDeclName(fn)]
+		if !ok {
+			continue
+		}
+
+		if sig := funcSignature(fset, fn); sig != kept.Signature {
+			return nil, fmt.Errorf("kept function %s has signature %s, but the mapping now generates %s; update it or remove its %s marker",
+				kept.Name, kept.Signature, sig, KeepMarker)
+		}
+
+		start := fn.Pos()
+		if fn.Doc != nil {
+			start = fn.Doc.Pos()
+		}
+
+		splices = append(splices, splice{
+			start: fset.Position(start).Offset,
+			end:   fset.Position(fn.End()).Offset,
+			kept:  kept,
+		})
+		found[kept.Name] = true
+	}
+
+	if len(splices) == 0 {
+		return file.Content, nil
+	}
+
+	var buf bytes.Buffer
+
+	prev := 0
+
+	for _, s := range splices {
+		buf.Write(file.Content[prev:s.start])
+		buf.Write(s.kept.Source)
+		prev = s.end
+	}
+
+	buf.Write(file.Content[prev:])
+
+	var imports []importSpec
+	for _, s := range splices {
+		imports = append(imports, s.kept.Imports...)
+	}
+
+	return fixImports(file.Filename, buf.Bytes(), imports)
+}
+
+// fixImports adds imports to the source of filename and drops the imports it
+// no longer uses, since kept functions may use other packages than the
+// generated ones they replace.
+func fixImports(filename string, src []byte, imports []importSpec) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing with kept functions: %w", err)
+	}
+
+	for _, imp := range imports {
+		astutil.AddNamedImport(fset, file, imp.Alias, imp.Path)
+	}
+
+	for _, imp := range file.Imports {
+		spec := importSpecOf(imp)
+		if spec.Alias == "_" || spec.Alias == "." {
+			continue
+		}
+
+		if !astutil.UsesImport(file, spec.Path) {
+			astutil.DeleteNamedImport(fset, file, spec.Alias, spec.Path)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("formatting with kept functions: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
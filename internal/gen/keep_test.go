@@ -0,0 +1,141 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+func keepTestPlan() *plan.ResolvedMappingPlan {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	fields := []analyze.FieldInfo{{Name: "Name", Exported: true, Type: str}}
+
+	return &plan.ResolvedMappingPlan{
+		TypePairs: []plan.ResolvedTypePair{{
+			SourceType: &analyze.TypeInfo{
+				ID:     analyze.TypeID{PkgPath: "example/store", Name: "Order"},
+				Kind:   analyze.TypeKindStruct,
+				Fields: fields,
+			},
+			TargetType: &analyze.TypeInfo{
+				ID:     analyze.TypeID{PkgPath: "example/warehouse", Name: "Order"},
+				Kind:   analyze.TypeKindStruct,
+				Fields: fields,
+			},
+			Mappings: []plan.ResolvedFieldMapping{{
+				TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+				SourcePaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: "Name"}}}},
+				Strategy:    plan.StrategyDirectAssign,
+			}},
+		}},
+	}
+}
+
+const keptOrderCaster = `package casters
+
+import (
+	"strings"
+
+	store "example/store"
+	warehouse "example/warehouse"
+)
+
+// StoreOrderToWarehouseOrder trims names.
+//
+//caster:keep
+func StoreOrderToWarehouseOrder(order store.Order) warehouse.Order {
+	return warehouse.Order{Name: strings.TrimSpace(order.Name)}
+}
+
+// helper is not kept.
+func helper() {}
+`
+
+func TestLoadKeptFuncs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.go"), []byte(keptOrderCaster), 0o644))
+
+	kept, err := LoadKeptFuncs(dir)
+	require.NoError(t, err)
+	require.Len(t, kept, 1)
+
+	fn := kept["StoreOrderToWarehouseOrder"]
+	assert.Equal(t, "order.go", fn.File)
+	assert.Equal(t, "func(store.Order) (warehouse.Order)", fn.Signature)
+	assert.Equal(t, []importSpec{
+		{Path: "strings"},
+		{Alias: "store", Path: "example/store"},
+		{Alias: "warehouse", Path: "example/warehouse"},
+	}, fn.Imports)
+
+	// Debug variants repeat the kept functions of their caster files
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_debug.go"), []byte(keptOrderCaster), 0o644))
+
+	kept, err = LoadKeptFuncs(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "order.go", kept["StoreOrderToWarehouseOrder"].File)
+
+	kept, err = LoadKeptFuncs(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, kept)
+}
+
+func TestGenerator_Generate_Kept(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.go"), []byte(keptOrderCaster), 0o644))
+
+	kept, err := LoadKeptFuncs(dir)
+	require.NoError(t, err)
+
+	cfg := DefaultGeneratorConfig()
+	cfg.Kept = kept
+
+	files, err := NewGenerator(cfg).Generate(keepTestPlan())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content := string(files[0].Content)
+	assert.Contains(t, content, "//caster:keep\nfunc StoreOrderToWarehouseOrder(order store.Order)")
+	assert.Contains(t, content, "strings.TrimSpace(order.Name)")
+	assert.Contains(t, content, `"strings"`)
+	assert.NotContains(t, content, "out.Name = in.Name")
+	assert.NotContains(t, content, "helper")
+
+	t.Run("debug variant", func(t *testing.T) {
+		cfg := cfg
+		cfg.DebugCasters = DebugLog
+
+		files, err := NewGenerator(cfg).Generate(keepTestPlan())
+		require.NoError(t, err)
+		require.Len(t, files, 3)
+
+		variant := string(files[1].Content)
+		assert.True(t, strings.HasPrefix(variant, "//go:build casterdebug\n\n"))
+		assert.Contains(t, variant, "strings.TrimSpace(order.Name)")
+		assert.NotContains(t, variant, "casterDebugCheck")
+	})
+
+	t.Run("changed signature", func(t *testing.T) {
+		fn := kept["StoreOrderToWarehouseOrder"]
+		fn.Signature = "func(*store.Order) (warehouse.Order)"
+		cfg.Kept = map[string]KeptFunc{fn.Name: fn}
+
+		_, err := NewGenerator(cfg).Generate(keepTestPlan())
+		require.ErrorContains(t, err, "kept function StoreOrderToWarehouseOrder has signature")
+	})
+
+	t.Run("no longer generated", func(t *testing.T) {
+		cfg.Kept = map[string]KeptFunc{"StoreItemToWarehouseItem": {Name: "StoreItemToWarehouseItem"}}
+
+		_, err := NewGenerator(cfg).Generate(keepTestPlan())
+		require.ErrorContains(t, err, "kept functions are no longer generated: StoreItemToWarehouseItem")
+	})
+}
@@ -0,0 +1,271 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/plan"
+)
+
+// memoizedTransformInfo records a memoized transform and the types it is called with.
+type memoizedTransformInfo struct {
+	Name       string
+	Wrapper    string
+	Args       []*analyze.TypeInfo
+	ReturnType *analyze.TypeInfo
+}
+
+// MemoizedTransform describes a cached wrapper around a pure transform for the template.
+type MemoizedTransform struct {
+	// Name is the wrapped transform function.
+	Name string
+	// Wrapper is the generated wrapper function name called by casters.
+	Wrapper string
+	// Cache is the name of the package-level sync.Map holding results.
+	Cache      string
+	Params     string
+	CallArgs   string
+	KeyExpr    string
+	ReturnType string
+}
+
+// memoizedWrapperName returns the wrapper function name for a memoized transform.
+func memoizedWrapperName(transform string) string {
+	return "memo" + strings.ReplaceAll(transform, ".", "_")
+}
+
+// memoizeTransform registers a memoized wrapper for the transform used by m and returns
+// the wrapper name. It returns false if the transform can't be memoized safely, e.g.
+// when an argument type is unknown or not comparable (it can't be used as a map key),
+// or when the transform is context-aware, as its result may depend on ctx.
+// Each signature the transform is called with gets its own wrapper: the first is
+// named after the transform, the next ones are numbered in order of use.
+func (g *Generator) memoizeTransform(m *plan.ResolvedFieldMapping, pair *plan.ResolvedTypePair) (string, bool) {
+	if !g.memoTransforms[m.Transform] || g.ctxTransforms[m.Transform] {
+		return "", false
+	}
+
+	args, ret := g.transformSignature(m, pair)
+	if ret == nil {
+		return "", false
+	}
+
+	for _, a := range args {
+		if !isComparableType(a) {
+			return "", false
+		}
+	}
+
+	key := g.memoizedSignature(m.Transform, args, ret)
+	if info, exists := g.memoized[key]; exists {
+		return info.Wrapper, true
+	}
+
+	wrapper := memoizedWrapperName(m.Transform)
+	if n := g.memoizedWrappers(m.Transform); n > 0 {
+		wrapper = fmt.Sprintf("%s_%d", wrapper, n+1)
+	}
+
+	g.memoized[key] = memoizedTransformInfo{
+		Name:       m.Transform,
+		Wrapper:    wrapper,
+		Args:       args,
+		ReturnType: ret,
+	}
+
+	return wrapper, true
+}
+
+// memoizedSignature returns the key of the memoized wrapper of transform called
+// with args and returning ret.
+func (g *Generator) memoizedSignature(transform string, args []*analyze.TypeInfo, ret *analyze.TypeInfo) string {
+	// The references only qualify the key, so their imports are discarded.
+	imports := make(map[string]importSpec)
+
+	refs := make([]string, len(args))
+	for i, a := range args {
+		refs[i] = g.typeRefString(a, imports)
+	}
+
+	return fmt.Sprintf("%s(%s) %s", transform, strings.Join(refs, ", "), g.typeRefString(ret, imports))
+}
+
+// memoizedWrappers returns the number of wrappers registered for transform.
+func (g *Generator) memoizedWrappers(transform string) int {
+	n := 0
+
+	for _, info := range g.memoized {
+		if info.Name == transform {
+			n++
+		}
+	}
+
+	return n
+}
+
+// isComparableType reports whether values of t can always be used as map keys.
+// Interface types are comparable, but not their dynamic values of slice, map
+// or func types, which would panic as keys; so they are excluded.
+func isComparableType(t *analyze.TypeInfo) bool {
+	if t == nil {
+		return false
+	}
+
+	if t.GoType != nil {
+		return isStrictlyComparable(t.GoType)
+	}
+
+	switch t.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindMap:
+		return false
+	case analyze.TypeKindArray:
+		return isComparableType(t.ElemType)
+	case analyze.TypeKindStruct:
+		for _, f := range t.Fields {
+			if !isComparableType(f.Type) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return true
+	}
+}
+
+// isStrictlyComparable reports whether t is comparable and holds no interface
+// (or type parameter) values whose comparison could panic.
+func isStrictlyComparable(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Interface:
+		return false
+	case *types.Array:
+		return isStrictlyComparable(u.Elem())
+	case *types.Struct:
+		for field := range u.Fields() {
+			if !isStrictlyComparable(field.Type()) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return types.Comparable(t)
+	}
+}
+
+// generateMemoizedTransformsFile generates a shared file with cached transform wrappers.
+func (g *Generator) generateMemoizedTransformsFile() (*GeneratedFile, error) {
+	data := &templateData{
+		PackageName: g.config.PackageName,
+		Filename:    "memoized_transforms.go",
+	}
+
+	imports := map[string]importSpec{"sync": {Path: "sync"}}
+
+	infos := slices.SortedFunc(maps.Values(g.memoized), func(a, b memoizedTransformInfo) int {
+		return strings.Compare(a.Wrapper, b.Wrapper)
+	})
+
+	for _, info := range infos {
+
+		var params, callArgs, keyFields, keyVals []string
+
+		for i, a := range info.Args {
+			v := fmt.Sprintf("v%d", i)
+			typeStr := g.typeRefString(a, imports)
+			params = append(params, v+" "+typeStr)
+			callArgs = append(callArgs, v)
+			keyFields = append(keyFields, v+" "+typeStr)
+			keyVals = append(keyVals, v)
+		}
+
+		// A single argument is its own key; several are combined into a comparable struct.
+		var keyExpr string
+
+		switch len(info.Args) {
+		case 0:
+			keyExpr = "struct{}{}"
+		case 1:
+			keyExpr = keyVals[0]
+		default:
+			keyExpr = fmt.Sprintf("struct{ %s }{%s}", strings.Join(keyFields, "; "), strings.Join(keyVals, ", "))
+		}
+
+		data.MemoizedTransforms = append(data.MemoizedTransforms, MemoizedTransform{
+			Name:       info.Name,
+			Wrapper:    info.Wrapper,
+			Cache:      info.Wrapper + "Cache",
+			Params:     strings.Join(params, ", "),
+			CallArgs:   strings.Join(callArgs, ", "),
+			KeyExpr:    keyExpr,
+			ReturnType: g.typeRefString(info.ReturnType, imports),
+		})
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := memoizedTransformsTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, data.Filename, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: data.Filename,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: data.Filename,
+		Content:  formatted,
+	}, nil
+}
+
+var memoizedTransformsTemplate = template.Must(template.New("memoized").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+{{range .MemoizedTransforms}}
+// {{.Cache}} caches results of {{.Name}} keyed by its arguments.
+var {{.Cache}} sync.Map
+
+// {{.Wrapper}} calls {{.Name}} once per distinct set of arguments and reuses the result.
+func {{.Wrapper}}({{.Params}}) {{.ReturnType}} {
+	key := {{.KeyExpr}}
+	if cached, ok := {{.Cache}}.Load(key); ok {
+		return cached.({{.ReturnType}})
+	}
+
+	result := {{.Name}}({{.CallArgs}})
+	{{.Cache}}.Store(key, result)
+
+	return result
+}
+{{end}}
+`))
@@ -0,0 +1,157 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// needsMerge reports whether the pair gets a merge variant of its caster:
+// when its mapping sets generate_merge, or any field sets merge.
+func needsMerge(pair *plan.ResolvedTypePair) bool {
+	if pair.GenerateMerge {
+		return true
+	}
+
+	for _, m := range pair.Mappings {
+		if m.Merge != mapping.MergeDefault {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeFunctionName returns the name of the merge variant of the pair's
+// caster (e.g., "MergeStoreOrderIntoWarehouseOrder").
+func (g *Generator) mergeFunctionName(pair *plan.ResolvedTypePair) string {
+	src, tgt := g.pairNames(pair)
+
+	return "Merge" + src + "Into" + tgt
+}
+
+// checkMerge reports if_zero fields of the pair whose target can't be read
+// back, being set through a setter method.
+func (g *Generator) checkMerge(pair *plan.ResolvedTypePair) error {
+	for _, m := range pair.Mappings {
+		if m.Merge != mapping.MergeIfZero {
+			continue
+		}
+
+		if setter, _ := g.targetSetter(pair.TargetType, m.TargetPaths); setter != "" {
+			return fmt.Errorf("merge: if_zero can't read %s, which is set by %s",
+				g.targetFieldExpr(m.TargetPaths), setter)
+		}
+	}
+
+	return nil
+}
+
+// applyMergePolicy records how the merge variant of the caster assigns the
+// field: skipped for never, guarded by a zero check of the target for if_zero.
+func (g *Generator) applyMergePolicy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	switch m.Merge {
+	case mapping.MergeNever:
+		assignment.MergeNever = true
+	case mapping.MergeIfZero:
+		if len(m.TargetPaths) > 0 {
+			assignment.MergeCheck = g.mergeZeroCheck(pair.TargetType, m.TargetPaths[0], imports)
+		}
+	}
+}
+
+// mergeZeroCheck returns the condition under which an if_zero field is
+// assigned: its target is zero, or a pointer on the way to it is nil.
+func (g *Generator) mergeZeroCheck(
+	targetType *analyze.TypeInfo,
+	path mapping.FieldPath,
+	imports map[string]importSpec,
+) string {
+	var conds []string
+
+	expr := "out"
+	current := targetType
+
+	for i, seg := range path.Segments {
+		expr += "." + seg.Name
+
+		var field *analyze.FieldInfo
+
+		if current != nil {
+			if current.Kind == analyze.TypeKindPointer {
+				current = current.ElemType
+			}
+
+			field = current.FieldByName(seg.Name)
+		}
+
+		if field == nil {
+			conds = append(conds, g.isZeroExpr(expr, nil, imports))
+			break
+		}
+
+		if i == len(path.Segments)-1 {
+			conds = append(conds, g.isZeroExpr(expr, field.Type, imports))
+			break
+		}
+
+		if field.Type.Kind == analyze.TypeKindPointer {
+			conds = append(conds, expr+" == nil")
+		}
+
+		current = field.Type
+	}
+
+	return strings.Join(conds, " || ")
+}
+
+// isZeroExpr returns the condition that expr, of type t, is zero: compared
+// with nil or a zero literal where possible, and through reflect otherwise.
+func (g *Generator) isZeroExpr(expr string, t *analyze.TypeInfo, imports map[string]importSpec) string {
+	return g.zeroCheck(expr, t, true, imports)
+}
+
+// isNonZeroExpr returns the condition that expr, of type t, isn't zero.
+func (g *Generator) isNonZeroExpr(expr string, t *analyze.TypeInfo, imports map[string]importSpec) string {
+	return g.zeroCheck(expr, t, false, imports)
+}
+
+// zeroCheck returns the condition that expr, of type t, is zero, or isn't
+// unless zero is set.
+func (g *Generator) zeroCheck(expr string, t *analyze.TypeInfo, zero bool, imports map[string]importSpec) string {
+	eq, not := " == ", "!"
+	if !zero {
+		eq, not = " != ", ""
+	}
+
+	for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	if t != nil {
+		switch t.Kind {
+		case analyze.TypeKindPointer, analyze.TypeKindSlice, analyze.TypeKindMap:
+			return expr + eq + "nil"
+
+		case analyze.TypeKindBasic:
+			switch lit := g.zeroValueForBasicType(t.ID.Name); {
+			case lit == "false":
+				return not + expr
+			case lit == "0", t.ID.Name == "string":
+				return expr + eq + lit
+			}
+		}
+	}
+
+	imports["reflect"] = importSpec{Path: "reflect"}
+
+	return not + "reflect.ValueOf(" + expr + ").IsZero()"
+}
@@ -0,0 +1,315 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/plan"
+)
+
+// Caster styles selectable with GeneratorConfig.Style.
+const (
+	// StyleFunctions emits each caster as a package-level function.
+	StyleFunctions = "functions"
+	// StyleMethods emits each caster as a method of the generated Casters type,
+	// calling transforms through its Transforms interface.
+	StyleMethods = "methods"
+)
+
+// methodsReceiver is the receiver of caster methods, reserved in their bodies.
+const methodsReceiver = "c"
+
+// casterMethod describes a caster method for the CastersAPI interface.
+type casterMethod struct {
+	Name   string
+	Params string
+	Result string
+}
+
+// transformMethod records a transform called through the Transforms interface.
+type transformMethod struct {
+	// Method is the interface method name.
+	Method string
+	// Func is the transform function DefaultTransforms delegates to.
+	Func       string
+	Args       []*analyze.TypeInfo
+	ReturnType *analyze.TypeInfo
+	Ctx        bool
+}
+
+// TransformMethod describes a Transforms interface method for the template.
+type TransformMethod struct {
+	Method     string
+	Func       string
+	Params     string
+	CallArgs   string
+	ReturnType string
+}
+
+// methods reports whether casters are emitted as methods.
+func (g *Generator) methods() bool {
+	return g.config.Style == StyleMethods
+}
+
+// checkStyle validates the configured style against the plan.
+func (g *Generator) checkStyle(p *plan.ResolvedMappingPlan) error {
+	switch g.config.Style {
+	case "", StyleFunctions:
+		return nil
+	case StyleMethods:
+	default:
+		return fmt.Errorf("unknown style %q (expected %s or %s)", g.config.Style, StyleFunctions, StyleMethods)
+	}
+
+	// Methods can't have type parameters.
+	if g.config.GenericRequires {
+		return errors.New("generic requires are not supported with the methods style")
+	}
+
+	for _, pair := range p.TypePairs {
+		for _, req := range pair.Requires {
+			if req.Name == methodsReceiver {
+				return fmt.Errorf("%s->%s: requires argument %q is the caster method receiver",
+					pair.SourceType.ID, pair.TargetType.ID, req.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// receiver returns the receiver of caster methods, or "" for functions.
+func (g *Generator) receiver() string {
+	if !g.methods() {
+		return ""
+	}
+
+	return methodsReceiver + " *Casters"
+}
+
+// recordCasterMethod adds the caster of data to the CastersAPI interface.
+func (g *Generator) recordCasterMethod(data *templateData, pair *plan.ResolvedTypePair) {
+	g.addImport(g.methodImports, pair.SourceType.ID.PkgPath)
+	g.typeArgsString(pair.SourceType, g.methodImports)
+
+	if data.TargetType.Package != "" {
+		g.addImport(g.methodImports, pair.TargetType.ID.PkgPath)
+	}
+
+	g.typeArgsString(pair.TargetType, g.methodImports)
+
+	var params []string
+
+	if data.UsesContext {
+		g.methodImports["context"] = importSpec{Path: "context"}
+		params = append(params, "ctx context.Context")
+	}
+
+	params = append(params, "in "+data.SourceType.String())
+	for _, arg := range data.ExtraArgs {
+		params = append(params, arg.Name+" "+arg.Type)
+	}
+
+	casterParams := params
+	if data.Overrides {
+		casterParams = append(slices.Clip(params), overridesParam+" ...func(*"+data.TargetType.String()+")")
+	}
+
+	g.casterMethods = append(g.casterMethods, casterMethod{
+		Name:   data.FunctionName,
+		Params: strings.Join(casterParams, ", "),
+		Result: data.TargetType.String(),
+	})
+
+	if data.MergeFunctionName != "" {
+		// The merge variant takes the target to update right after in
+		in := len(params) - len(data.ExtraArgs)
+		params = slices.Insert(params, in, "out *"+data.TargetType.String())
+
+		g.casterMethods = append(g.casterMethods, casterMethod{
+			Name:   data.MergeFunctionName,
+			Params: strings.Join(params, ", "),
+		})
+	}
+}
+
+// transformMethod registers the transform used by m as a Transforms method and
+// returns the expression calling it. It returns false outside the methods style
+// and when the transform's signature can't be determined, so it is called directly.
+func (g *Generator) transformMethod(m *plan.ResolvedFieldMapping, pair *plan.ResolvedTypePair) (string, bool) {
+	if !g.methods() {
+		return "", false
+	}
+
+	if tm, exists := g.transformMethods[m.Transform]; exists {
+		return methodsReceiver + ".Transforms." + tm.Method, true
+	}
+
+	args, ret := g.transformSignature(m, pair)
+	if ret == nil {
+		return "", false
+	}
+
+	for _, a := range args {
+		if a == nil {
+			return "", false
+		}
+	}
+
+	tm := transformMethod{
+		Method:     transformMethodName(m.Transform),
+		Func:       m.Transform,
+		Args:       args,
+		ReturnType: ret,
+		Ctx:        g.ctxTransforms[m.Transform],
+	}
+	g.transformMethods[m.Transform] = tm
+
+	return methodsReceiver + ".Transforms." + tm.Method, true
+}
+
+// transformMethodName returns the exported method name of a transform
+// (e.g., "ConvParse" for conv.Parse, "PriceToAmount" for priceToAmount).
+func transformMethodName(transform string) string {
+	var sb strings.Builder
+
+	for part := range strings.SplitSeq(transform, ".") {
+		if part != "" {
+			sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	return sb.String()
+}
+
+// castersTemplateData holds data for the casters file template.
+type castersTemplateData struct {
+	PackageName string
+	Imports     []importSpec
+	Casters     []casterMethod
+	Transforms  []TransformMethod
+}
+
+// generateCastersFile generates the Casters type, its CastersAPI interface and
+// the Transforms interface with its default implementation.
+func (g *Generator) generateCastersFile() (*GeneratedFile, error) {
+	const filename = "casters.go"
+
+	imports := g.methodImports
+
+	data := &castersTemplateData{
+		PackageName: g.config.PackageName,
+		Casters:     g.casterMethods,
+	}
+
+	var names []string
+	for name := range g.transformMethods {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		tm := g.transformMethods[name]
+
+		var params, callArgs []string
+
+		if tm.Ctx {
+			imports["context"] = importSpec{Path: "context"}
+			params = append(params, "ctx context.Context")
+			callArgs = append(callArgs, "ctx")
+		}
+
+		for i, a := range tm.Args {
+			v := fmt.Sprintf("v%d", i)
+			params = append(params, v+" "+g.typeRefString(a, imports))
+			callArgs = append(callArgs, v)
+		}
+
+		data.Transforms = append(data.Transforms, TransformMethod{
+			Method:     tm.Method,
+			Func:       tm.Func,
+			Params:     strings.Join(params, ", "),
+			CallArgs:   strings.Join(callArgs, ", "),
+			ReturnType: g.typeRefString(tm.ReturnType, imports),
+		})
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := castersTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, filename, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: filename,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: filename,
+		Content:  formatted,
+	}, nil
+}
+
+var castersTemplate = template.Must(template.New("casters").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+// CastersAPI is implemented by Casters. Depend on it to substitute conversions (e.g., in tests).
+type CastersAPI interface {
+{{range .Casters}}	{{.Name}}({{.Params}}) {{.Result}}
+{{end}}}
+
+var _ CastersAPI = (*Casters)(nil)
+
+// Casters bundles the generated casters as methods.
+type Casters struct {
+{{if .Transforms}}	// Transforms are the transforms called by the casters.
+	Transforms Transforms
+{{end}}}
+
+// NewCasters returns Casters calling the package-level transforms.
+func NewCasters() *Casters {
+	return &Casters{ {{- if .Transforms}}Transforms: DefaultTransforms{}{{end -}} }
+}
+{{if .Transforms}}
+// Transforms are the transforms called by Casters. Implement it to inject other transforms.
+type Transforms interface {
+{{range .Transforms}}	{{.Method}}({{.Params}}) {{.ReturnType}}
+{{end}}}
+
+// DefaultTransforms implements Transforms with the package-level transforms.
+type DefaultTransforms struct{}
+{{range .Transforms}}
+// {{.Method}} calls {{.Func}}.
+func (DefaultTransforms) {{.Method}}({{.Params}}) {{.ReturnType}} {
+	return {{.Func}}({{.CallArgs}})
+}
+{{end}}{{end}}`))
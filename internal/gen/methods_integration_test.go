@@ -0,0 +1,9 @@
+package gen_test
+
+import (
+	"testing"
+)
+
+func TestGenerate_MethodsExample_Compiles(t *testing.T) {
+	runExampleIntegrationTest(t, "methods", "-package", "generated", "-style", "methods")
+}
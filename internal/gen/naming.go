@@ -0,0 +1,196 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// checkNaming parses the naming template of casters and checks the names of
+// the casters of the plan collide neither with each other nor with declared
+// transforms and hand-written declarations of the output directory.
+func (g *Generator) checkNaming(p *plan.ResolvedMappingPlan) error {
+	text := g.config.FuncTemplate
+	if text == "" {
+		text = p.FuncTemplate
+	}
+
+	if text == "" {
+		text = mapping.DefaultFuncTemplate
+	}
+
+	tmpl, err := mapping.ParseFuncTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	g.funcTemplate = tmpl
+
+	owners := make(map[string]string)
+
+	if !g.methods() {
+		for name := range g.config.DeclaredTransforms {
+			owners[name] = "transform " + name
+		}
+
+		declared, err := handWrittenDecls(g.config.OutputDir)
+		if err != nil {
+			return err
+		}
+
+		for name, file := range declared {
+			owners[name] = "a declaration of " + file
+		}
+	}
+
+	for i := range p.TypePairs {
+		pair := &p.TypePairs[i]
+		key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+
+		// Methods don't collide with package-level names
+		var names []string
+		if pair.Method == "" {
+			names = append(names, g.functionName(pair))
+		}
+
+		if needsMerge(pair) {
+			names = append(names, g.mergeFunctionName(pair))
+		}
+
+		for _, name := range names {
+			if owner, ok := owners[name]; ok {
+				return fmt.Errorf("caster name %s of %s collides with %s; set a naming template", name, key, owner)
+			}
+
+			owners[name] = "the caster of " + key
+		}
+	}
+
+	return nil
+}
+
+// casterName names the caster converting src to tgt with the naming
+// template. A generated target without a package belongs to the output package.
+func (g *Generator) casterName(src, tgt *analyze.TypeInfo, generatedTarget bool) string {
+	srcPkg := g.capitalize(g.getPkgName(src.ID.PkgPath))
+	tgtPkg := g.capitalize(g.getPkgName(tgt.ID.PkgPath))
+
+	// For generated targets with no package path, use the output package name
+	if tgtPkg == "" && generatedTarget {
+		tgtPkg = g.capitalize(g.config.PackageName)
+	}
+
+	name := mapping.FuncName{
+		SourcePkg:  srcPkg,
+		SourceName: src.ID.Name + g.typeArgsKey(src),
+		TargetPkg:  tgtPkg,
+		TargetName: tgt.ID.Name + g.typeArgsKey(tgt),
+	}
+	name.SourceType = name.SourcePkg + name.SourceName
+	name.TargetType = name.TargetPkg + name.TargetName
+
+	if g.funcTemplate == nil {
+		return name.SourceType + "To" + name.TargetType
+	}
+
+	// The template was checked on a sample name, so it executes.
+	fn, _ := mapping.ExecuteFuncTemplate(g.funcTemplate, name)
+
+	return fn
+}
+
+// handWrittenDecls returns the top-level declarations of the Go files of dir
+// not written by the generator, with their file names. A missing dir has none.
+func handWrittenDecls(dir string) (map[string]string, error) {
+	decls := make(map[string]string)
+
+	files, err := handWrittenFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		for _, decl := range files[name].Decls {
+			for _, declName := range declNames(decl) {
+				decls[declName] = name
+			}
+		}
+	}
+
+	return decls, nil
+}
+
+// handWrittenFiles parses the Go files of dir not written by the generator,
+// keyed by file name. A missing dir has none.
+func handWrittenFiles(dir string) (map[string]*ast.File, error) {
+	files := make(map[string]*ast.File)
+
+	if dir == "" {
+		return files, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+
+		if bytes.HasPrefix(content, []byte(generatedHeader)) {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), p, content, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", p, err)
+		}
+
+		files[filepath.Base(p)] = file
+	}
+
+	return files, nil
+}
+
+// declNames returns the package-level names a declaration introduces.
+func declNames(decl ast.Decl) []string {
+	var names []string
+
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			names = append(names, d.Name.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					names = append(names, n.Name)
+				}
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,359 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"caster-generator/internal/plan"
+)
+
+// outputGroup is a package casters are generated into, with its type pairs.
+type outputGroup struct {
+	pkgName string
+	dir     string
+	pairs   []plan.ResolvedTypePair
+}
+
+// foreignCaster is a caster generated into another output package than the
+// one being generated.
+type foreignCaster struct {
+	pkgName    string
+	importPath string
+	ctx        bool
+	method     string
+}
+
+// hasOutputs reports whether a type pair is generated into its own package.
+func hasOutputs(pairs []plan.ResolvedTypePair) bool {
+	for i := range pairs {
+		if pairs[i].Output != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pairOutput returns the package name and directory the caster of a pair is
+// generated into.
+func (g *Generator) pairOutput(pair *plan.ResolvedTypePair) (string, string) {
+	out := pair.Output
+	if out == nil {
+		return g.config.PackageName, filepath.Clean(g.config.OutputDir)
+	}
+
+	pkgName, dir := out.Package, out.Dir
+	if dir == "" {
+		dir = filepath.Join(g.config.OutputDir, pkgName)
+	}
+
+	// Packages of source types are given by absolute directory
+	if filepath.IsAbs(dir) {
+		if outDir, err := filepath.Abs(g.config.OutputDir); err == nil && filepath.Clean(dir) == outDir {
+			dir = g.config.OutputDir
+		}
+	}
+
+	if pkgName == "" {
+		pkgName = filepath.Base(dir)
+	}
+
+	return pkgName, filepath.Clean(dir)
+}
+
+// generateOutputs generates each output package of the plan with its own
+// generator, calling the casters of the other packages qualified. File names
+// stay relative to the output directory.
+func (g *Generator) generateOutputs(p *plan.ResolvedMappingPlan) ([]GeneratedFile, error) {
+	if g.methods() {
+		return nil, errors.New("output packages are not supported with the methods style")
+	}
+
+	groups, err := g.outputGroups(p.TypePairs)
+	if err != nil {
+		return nil, err
+	}
+
+	importPaths, err := referencedImportPaths(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	outDir, err := filepath.Abs(g.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []GeneratedFile
+
+	written := make(map[string]string)
+
+	for i, grp := range groups {
+		config := g.config
+		config.PackageName = grp.pkgName
+		config.OutputDir = grp.dir
+
+		// Kept functions are loaded from the output directory only
+		if i > 0 {
+			config.Kept = nil
+		}
+
+		sub := NewGenerator(config)
+		sub.foreign = make(map[string]foreignCaster)
+
+		for j, other := range groups {
+			if j == i {
+				continue
+			}
+
+			for k := range other.pairs {
+				pair := &other.pairs[k]
+				key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+				sub.foreign[key] = foreignCaster{
+					pkgName:    other.pkgName,
+					importPath: importPaths[other.dir],
+					ctx:        pair.NeedsContext,
+					method:     pair.Method,
+				}
+			}
+		}
+
+		groupPlan := *p
+		groupPlan.TypePairs = grp.pairs
+
+		groupFiles, err := sub.Generate(&groupPlan)
+		if err != nil {
+			return nil, fmt.Errorf("output package %s: %w", grp.dir, err)
+		}
+
+		dir, err := filepath.Abs(grp.dir)
+		if err == nil {
+			dir, err = filepath.Rel(outDir, dir)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("output package %s: %w", grp.dir, err)
+		}
+
+		for _, f := range groupFiles {
+			f.Filename = filepath.Join(dir, f.Filename)
+			if other, ok := written[f.Filename]; ok {
+				return nil, fmt.Errorf("%s is generated by output packages %s and %s", f.Filename, other, grp.dir)
+			}
+
+			written[f.Filename] = grp.dir
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// outputGroups groups the pairs by output package, the output directory
+// first and the others by directory.
+func (g *Generator) outputGroups(pairs []plan.ResolvedTypePair) ([]*outputGroup, error) {
+	_, outDir := g.pairOutput(&plan.ResolvedTypePair{})
+	byDir := map[string]*outputGroup{
+		outDir: {pkgName: g.config.PackageName, dir: outDir},
+	}
+
+	for _, pair := range pairs {
+		pkgName, dir := g.pairOutput(&pair)
+
+		grp, ok := byDir[dir]
+		if !ok {
+			grp = &outputGroup{pkgName: pkgName, dir: dir}
+			byDir[dir] = grp
+		}
+
+		if grp.pkgName != pkgName {
+			return nil, fmt.Errorf("output dir %s is given packages %q and %q", dir, grp.pkgName, pkgName)
+		}
+
+		grp.pairs = append(grp.pairs, pair)
+	}
+
+	groups := []*outputGroup{byDir[outDir]}
+	delete(byDir, outDir)
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		groups = append(groups, byDir[dir])
+	}
+
+	return groups, nil
+}
+
+// outputImport is an import of an output package by another one, with the
+// nesting of casters that causes it.
+type outputImport struct {
+	from   *outputGroup
+	to     *outputGroup
+	caller string
+	nested string
+}
+
+// referencedImportPaths returns the import path of each output package whose
+// casters are nested in the casters of another one, by directory. It fails
+// when output packages would import each other, which Go rejects.
+func referencedImportPaths(groups []*outputGroup) (map[string]string, error) {
+	owner := make(map[string]*outputGroup)
+
+	for _, grp := range groups {
+		for i := range grp.pairs {
+			pair := &grp.pairs[i]
+			owner[fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)] = grp
+		}
+	}
+
+	importPaths := make(map[string]string)
+	imports := make(map[*outputGroup][]outputImport)
+
+	for _, grp := range groups {
+		for i := range grp.pairs {
+			pair := &grp.pairs[i]
+
+			for _, nested := range pair.NestedPairs {
+				key := fmt.Sprintf("%s->%s", nested.SourceType.ID, nested.TargetType.ID)
+
+				other := owner[key]
+				if other == nil || other == grp {
+					continue
+				}
+
+				if !slices.ContainsFunc(imports[grp], func(imp outputImport) bool { return imp.to == other }) {
+					imports[grp] = append(imports[grp], outputImport{
+						from:   grp,
+						to:     other,
+						caller: fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID),
+						nested: key,
+					})
+				}
+
+				if nested.TargetType.IsGenerated && nested.TargetType.ID.PkgPath == "" {
+					return nil, fmt.Errorf("%s is generated into package %s and can't be used by package %s",
+						nested.TargetType.ID.Name, other.pkgName, grp.pkgName)
+				}
+
+				if _, ok := importPaths[other.dir]; ok {
+					continue
+				}
+
+				importPath, err := outputImportPath(other.dir)
+				if err != nil {
+					return nil, fmt.Errorf("output package %s: %w", other.dir, err)
+				}
+
+				importPaths[other.dir] = importPath
+			}
+		}
+	}
+
+	if cycle := importCycle(groups, imports); cycle != nil {
+		steps := make([]string, len(cycle))
+		for i, imp := range cycle {
+			steps[i] = fmt.Sprintf("%s calls %s in package %s", imp.caller, imp.nested, imp.to.pkgName)
+		}
+
+		return nil, fmt.Errorf("output packages import each other (%s); "+
+			"generate these casters into the same package", strings.Join(steps, ", "))
+	}
+
+	return importPaths, nil
+}
+
+// importCycle returns the imports of a cycle between output packages, in
+// order, or nil when there is none.
+func importCycle(groups []*outputGroup, imports map[*outputGroup][]outputImport) []outputImport {
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[*outputGroup]int)
+
+	var (
+		stack []outputImport
+		visit func(grp *outputGroup) []outputImport
+	)
+
+	visit = func(grp *outputGroup) []outputImport {
+		state[grp] = visiting
+
+		for _, imp := range imports[grp] {
+			stack = append(stack, imp)
+
+			switch state[imp.to] {
+			case visiting:
+				// The cycle starts at the import leaving imp.to
+				start := slices.IndexFunc(stack, func(s outputImport) bool { return s.from == imp.to })
+
+				return slices.Clone(stack[start:])
+			case 0:
+				if cycle := visit(imp.to); cycle != nil {
+					return cycle
+				}
+			}
+
+			stack = stack[:len(stack)-1]
+		}
+
+		state[grp] = done
+
+		return nil
+	}
+
+	for _, grp := range groups {
+		if state[grp] == 0 {
+			if cycle := visit(grp); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// outputImportPath returns the import path of the package in dir, from the
+// go.mod of the module holding it.
+func outputImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			modPath := modfile.ModulePath(data)
+			if modPath == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+			}
+
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+
+			return path.Join(modPath, filepath.ToSlash(rel)), nil
+		}
+
+		if filepath.Dir(root) == root {
+			return "", errors.New("no go.mod found to derive its import path")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package gen
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/common"
+	"caster-generator/internal/plan"
+)
+
+// pkgAliases returns the import aliases of the packages p refers to whose
+// names collide, keyed by package path. Colliding packages are prefixed with
+// as many of their parent path elements as tell them apart (store/v1 and
+// billing/v1 import as storev1 and billingv1), or numbered if that isn't
+// enough. Aliases only depend on the set of packages, not on the order of
+// types or files.
+func (g *Generator) pkgAliases(p *plan.ResolvedMappingPlan) map[string]string {
+	paths := make(map[string]bool)
+
+	if p.TypeGraph != nil {
+		for path := range p.TypeGraph.Packages {
+			paths[path] = true
+		}
+
+		for id := range p.TypeGraph.Types {
+			paths[id.PkgPath] = true
+		}
+
+		for id := range p.TypeGraph.Funcs {
+			paths[id.PkgPath] = true
+		}
+	}
+
+	seen := make(map[*analyze.TypeInfo]bool)
+	for i := range p.TypePairs {
+		collectPkgPaths(p.TypePairs[i].SourceType, paths, seen)
+		collectPkgPaths(p.TypePairs[i].TargetType, paths, seen)
+	}
+
+	delete(paths, "")
+
+	byName := make(map[string][]string)
+	for path := range paths {
+		name := g.pkgName(path)
+		byName[name] = append(byName[name], path)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		if len(byName[name]) == 1 {
+			taken[name] = true
+		}
+	}
+
+	aliases := make(map[string]string)
+
+	for _, name := range names {
+		group := byName[name]
+		if len(group) == 1 {
+			continue
+		}
+
+		sort.Strings(group)
+
+		grouped := groupAliases(name, group)
+
+		for _, path := range group {
+			alias := grouped[path]
+			base := alias
+			for i := 2; taken[alias]; i++ {
+				alias = base + strconv.Itoa(i)
+			}
+
+			taken[alias] = true
+
+			if alias != name {
+				aliases[path] = alias
+			}
+		}
+	}
+
+	return aliases
+}
+
+// groupAliases returns the aliases of the paths of packages sharing name: name
+// prefixed with the fewest trailing parent elements telling them apart. The
+// aliases left equal are numbered by pkgAliases.
+func groupAliases(name string, group []string) map[string]string {
+	parents := make(map[string][]string, len(group))
+	depth := 0
+
+	for _, path := range group {
+		parents[path] = parentElems(path, name)
+		depth = max(depth, len(parents[path]))
+	}
+
+	aliases := make(map[string]string, len(group))
+	for _, path := range group {
+		aliases[path] = name
+	}
+
+	for k := 1; k <= depth; k++ {
+		distinct := make(map[string]bool, len(group))
+
+		for _, path := range group {
+			elems := parents[path][max(0, len(parents[path])-k):]
+			aliases[path] = strings.Join(elems, "") + name
+			distinct[aliases[path]] = true
+		}
+
+		if len(distinct) == len(group) {
+			break
+		}
+	}
+
+	return aliases
+}
+
+// parentElems returns the sanitized elements of path before its last, leaving
+// out those equal to the package name (e.g., foo of example.com/foo/v2).
+func parentElems(path, name string) []string {
+	elems := strings.Split(path, "/")
+
+	var parents []string
+
+	for _, elem := range elems[:len(elems)-1] {
+		elem = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+
+			return -1
+		}, elem)
+		if elem != "" && elem != name {
+			parents = append(parents, elem)
+		}
+	}
+
+	return parents
+}
+
+// collectPkgPaths adds the package paths of t and of the types it refers to.
+func collectPkgPaths(t *analyze.TypeInfo, paths map[string]bool, seen map[*analyze.TypeInfo]bool) {
+	if t == nil || seen[t] {
+		return
+	}
+
+	seen[t] = true
+	paths[t.ID.PkgPath] = true
+
+	collectPkgPaths(t.Underlying, paths, seen)
+	collectPkgPaths(t.ElemType, paths, seen)
+	collectPkgPaths(t.KeyType, paths, seen)
+
+	for _, arg := range t.TypeArgs {
+		collectPkgPaths(arg, paths, seen)
+	}
+
+	for i := range t.Fields {
+		collectPkgPaths(t.Fields[i].Type, paths, seen)
+	}
+}
+
+// pkgName returns the declared name of the package at pkgPath, or its last
+// path element if the graph doesn't hold it.
+func (g *Generator) pkgName(pkgPath string) string {
+	if g.graph != nil {
+		if pkgInfo, ok := g.graph.Packages[pkgPath]; ok {
+			return pkgInfo.Name
+		}
+	}
+
+	return common.PkgAlias(pkgPath)
+}
@@ -0,0 +1,102 @@
+package gen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/plan"
+)
+
+// recursionCycles groups the pairs whose casters call each other, directly or
+// through other casters, for the cycles holding a pair with max_depth. It
+// returns the cycle of each of their pairs, named by its smallest pair key,
+// and the depth limit of each cycle, the smallest max_depth of its pairs.
+func recursionCycles(pairs []plan.ResolvedTypePair) (map[string]string, map[string]int) {
+	calls := make(map[string][]string, len(pairs))
+
+	for i := range pairs {
+		pair := &pairs[i]
+		key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+
+		for _, nested := range pair.NestedPairs {
+			calls[key] = append(calls[key], fmt.Sprintf("%s->%s", nested.SourceType.ID, nested.TargetType.ID))
+		}
+	}
+
+	reaches := func(from string) map[string]bool {
+		seen := make(map[string]bool)
+		stack := slices.Clone(calls[from])
+
+		for len(stack) > 0 {
+			key := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if !seen[key] {
+				seen[key] = true
+				stack = append(stack, calls[key]...)
+			}
+		}
+
+		return seen
+	}
+
+	cycles := make(map[string]string)
+	limits := make(map[string]int)
+
+	for i := range pairs {
+		pair := &pairs[i]
+		key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+
+		from := reaches(key)
+		if pair.MaxDepth == 0 || !from[key] {
+			continue
+		}
+
+		var members []string
+
+		for other := range from {
+			if reaches(other)[key] {
+				members = append(members, other)
+			}
+		}
+
+		// Cycles sharing a pair are the same cycle
+		cycle := slices.Min(members)
+		if limit, ok := limits[cycle]; !ok || pair.MaxDepth < limit {
+			limits[cycle] = pair.MaxDepth
+		}
+
+		for _, member := range members {
+			cycles[member] = cycle
+		}
+	}
+
+	return cycles, limits
+}
+
+// depthLimit returns the depth limit of the caster of the pair, 0 if it isn't
+// part of a depth-limited recursive cycle.
+func (g *Generator) depthLimit(key string) int {
+	cycle, ok := g.depthCycles[key]
+	if !ok {
+		return 0
+	}
+
+	return g.depthLimits[cycle]
+}
+
+// depthFunctionName names the variant of a caster called at a given depth of
+// recursion (e.g., "storeNodeToWarehouseNodeAtDepth").
+func depthFunctionName(fn string) string {
+	return strings.ToLower(fn[:1]) + fn[1:] + "AtDepth"
+}
+
+// recurses reports whether the caster of the pair being generated calls the
+// caster of key at the next depth of recursion, being in the same
+// depth-limited cycle.
+func (g *Generator) recurses(key string) bool {
+	cycle, ok := g.depthCycles[key]
+
+	return ok && cycle == g.depthCycles[g.casterKey]
+}
@@ -0,0 +1,455 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/plan"
+)
+
+// generatedHeader starts the files written by the generator, the only ones
+// PatchField and FindCaster touch.
+const generatedHeader = "// Code generated by caster-generator."
+
+// optionsPrefix starts the header line recording the generator options that
+// differ from their defaults, so regen-field regenerates code the same way.
+const optionsPrefix = "// caster-generator options:"
+
+// ErrCasterNotFound is returned by FindCaster when no generated file of the
+// directory declares the caster.
+var ErrCasterNotFound = errors.New("caster not found")
+
+// HeaderOptions are the generator options a generated file was written with.
+type HeaderOptions struct {
+	PackageName     string
+	Style           string
+	DeepCopy        bool
+	GenericRequires bool
+}
+
+// optionsLine returns the header line recording the options of g, or "" when
+// they are all defaults.
+func (g *Generator) optionsLine() string {
+	var opts []string
+
+	if g.methods() {
+		opts = append(opts, "-style="+StyleMethods)
+	}
+
+	if g.config.DeepCopy {
+		opts = append(opts, "-deep-copy")
+	}
+
+	if g.config.GenericRequires {
+		opts = append(opts, "-generic-requires")
+	}
+
+	if len(opts) == 0 {
+		return ""
+	}
+
+	return optionsPrefix + " " + strings.Join(opts, " ")
+}
+
+// recordOptions adds the options line of g below the generated header of files.
+func (g *Generator) recordOptions(files []GeneratedFile) {
+	line := g.optionsLine()
+	if line == "" {
+		return
+	}
+
+	for i := range files {
+		content := files[i].Content
+
+		at := bytes.Index(content, []byte(generatedHeader))
+		if at < 0 {
+			continue
+		}
+
+		end := bytes.IndexByte(content[at:], '\n')
+		if end < 0 {
+			continue
+		}
+
+		end += at + 1
+		files[i].Content = slices.Concat(content[:end], []byte(line+"\n"), content[end:])
+	}
+}
+
+// ReadOptions returns the options the generated files of dir were written
+// with, read from the header and package clause of the first one. The files
+// of a gen run share their options.
+func ReadOptions(dir string) (HeaderOptions, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return HeaderOptions{}, fmt.Errorf("listing %s: %w", dir, err)
+	}
+
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return HeaderOptions{}, fmt.Errorf("reading %s: %w", p, err)
+		}
+
+		if !bytes.HasPrefix(content, []byte(generatedHeader)) {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), p, content, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return HeaderOptions{}, fmt.Errorf("parsing %s: %w", p, err)
+		}
+
+		opts := HeaderOptions{PackageName: file.Name.Name, Style: StyleFunctions}
+
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				fields, ok := strings.CutPrefix(c.Text, optionsPrefix)
+				if !ok {
+					continue
+				}
+
+				for _, opt := range strings.Fields(fields) {
+					switch name, value, _ := strings.Cut(opt, "="); name {
+					case "-style":
+						opts.Style = value
+					case "-deep-copy":
+						opts.DeepCopy = true
+					case "-generic-requires":
+						opts.GenericRequires = true
+					default:
+						return HeaderOptions{}, fmt.Errorf("%s: unknown generator option %s", p, opt)
+					}
+				}
+			}
+		}
+
+		return opts, nil
+	}
+
+	return HeaderOptions{}, fmt.Errorf("no files generated by caster-generator in %s", dir)
+}
+
+// CasterName returns the name of the caster generated for pair, qualified by
+// the receiver type in the methods style (e.g., "Casters.StoreOrderToAPIOrder").
+func (g *Generator) CasterName(pair *plan.ResolvedTypePair) string {
+	name := g.functionName(pair)
+	if g.methods() {
+		name = "Casters." + name
+	}
+
+	return name
+}
+
+// CasterSource returns the content of the file of files declaring the caster
+// named name.
+func CasterSource(files []GeneratedFile, name string) ([]byte, error) {
+	for _, f := range files {
+		ok, err := declaresFunc(f.Filename, f.Content, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return f.Content, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrCasterNotFound, name)
+}
+
+// FindCaster returns the path of the generated Go file of dir declaring the
+// caster named name (as returned by CasterName).
+func FindCaster(dir, name string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", fmt.Errorf("listing %s: %w", dir, err)
+	}
+
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", p, err)
+		}
+
+		if !bytes.HasPrefix(content, []byte(generatedHeader)) {
+			continue
+		}
+
+		ok, err := declaresFunc(p, content, name)
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s in %s", ErrCasterNotFound, name, dir)
+}
+
+// declaresFunc reports whether the Go source content declares function name.
+func declaresFunc(filename string, content []byte, name string) (bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, content, parser.SkipObjectResolution)
+	if err != nil {
+		return false, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	return findFunc(file, name) != nil, nil
+}
+
+// PatchField replaces the code assigning target field path field (e.g.,
+// "Total" or "Address.City") in caster name of the generated file content
+// with the code assigning it in generated, a fresh generation of the same
+// caster. The statements writing the field, their comments and the TODO left
+// for the field if it is unmapped are swapped; the rest of the file is kept
+// byte for byte, except for imports, which are fixed up.
+func PatchField(filename string, content, generated []byte, name, field string) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte(generatedHeader)) {
+		return nil, fmt.Errorf("%s was not generated by caster-generator", filename)
+	}
+
+	oldSet := token.NewFileSet()
+
+	oldFile, err := parser.ParseFile(oldSet, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	newSet := token.NewFileSet()
+
+	newFile, err := parser.ParseFile(newSet, filename, generated, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing generated code: %w", err)
+	}
+
+	oldFn, newFn := findFunc(oldFile, name), findFunc(newFile, name)
+	if oldFn == nil || newFn == nil || oldFn.Body == nil || newFn.Body == nil {
+		return nil, fmt.Errorf("%w: %s in %s", ErrCasterNotFound, name, filename)
+	}
+
+	segments := strings.Split(field, ".")
+	oldSpans := fieldSpans(oldSet, oldFile, oldFn, segments)
+	newSpans := fieldSpans(newSet, newFile, newFn, segments)
+
+	if len(oldSpans) == 0 && len(newSpans) == 0 {
+		return nil, fmt.Errorf("caster %s doesn't assign field %s", name, field)
+	}
+
+	// Keep the layout between the new statements, e.g. a slice's make and loop.
+	var replacement []byte
+
+	for i, s := range newSpans {
+		if i > 0 {
+			sep := generated[newSpans[i-1].end:s.start]
+			if len(bytes.TrimSpace(sep)) > 0 {
+				sep = []byte("\n\n\t")
+			}
+
+			replacement = append(replacement, sep...)
+		}
+
+		replacement = append(replacement, generated[s.start:s.end]...)
+	}
+
+	// New statements for a field without any go above the TODOs and the final
+	// return; TODOs replace each other in place.
+	isStmt := func(s span) bool { return s.stmt }
+	if len(oldSpans) == 0 || (slices.ContainsFunc(newSpans, isStmt) && !slices.ContainsFunc(oldSpans, isStmt)) {
+		at := tailOffset(oldSet, oldFile, oldFn)
+		oldSpans = append([]span{{start: at, end: at}}, oldSpans...)
+		replacement = append(replacement, "\n\n\t"...)
+	}
+
+	var buf bytes.Buffer
+
+	prev := 0
+
+	for i, s := range oldSpans {
+		if s.start < prev {
+			continue // a TODO inside the comments of a statement
+		}
+
+		buf.Write(content[prev:s.start])
+
+		if i == 0 {
+			buf.Write(replacement)
+		}
+
+		prev = s.end
+	}
+
+	buf.Write(content[prev:])
+
+	return fixImports(filename, buf.Bytes(), usedImports(newFile, newFn))
+}
+
+// span is a byte range of a source file.
+type span struct {
+	start, end int
+	// stmt is true for statements, false for TODO comments.
+	stmt bool
+}
+
+// findFunc returns the declaration of function name (see funcDeclName) in file.
+func findFunc(file *ast.File, name string) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && funcDeclName(fn) == name {
+			return fn
+		}
+	}
+
+	return nil
+}
+
+// fieldSpans returns the byte ranges of the top-level statements of fn
+// writing the target field at segments, each with the comments above it, and
+// of the TODO comments left for the field, in source order.
+func fieldSpans(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl, segments []string) []span {
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	var spans []span
+
+	prevEnd := fn.Body.Lbrace + 1
+
+	for _, stmt := range fn.Body.List {
+		if writesField(stmt, segments) {
+			start := stmt.Pos()
+			if c := leadingComments(fset, file, prevEnd, stmt.Pos()); c != nil {
+				start = c.Pos()
+			}
+
+			spans = append(spans, span{start: offset(start), end: offset(stmt.End()), stmt: true})
+		}
+
+		prevEnd = stmt.End()
+	}
+
+	path := strings.Join(segments, ".")
+	prefixes := []string{"// TODO: " + path + " - ", "// TODO: out." + path + " - "}
+
+	for _, group := range file.Comments {
+		if group.Pos() < fn.Body.Lbrace || group.End() > fn.Body.Rbrace {
+			continue
+		}
+
+		for _, c := range group.List {
+			if slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(c.Text, p) }) {
+				spans = append(spans, span{start: offset(c.Pos()), end: offset(c.End())})
+			}
+		}
+	}
+
+	slices.SortFunc(spans, func(a, b span) int { return a.start - b.start })
+
+	return spans
+}
+
+// leadingComments returns the first comment group between after and before
+// that starts on a line of its own, if any.
+func leadingComments(fset *token.FileSet, file *ast.File, after, before token.Pos) *ast.CommentGroup {
+	for _, group := range file.Comments {
+		if group.Pos() < after || group.End() > before {
+			continue
+		}
+
+		if fset.Position(group.Pos()).Line == fset.Position(after).Line {
+			continue
+		}
+
+		return group
+	}
+
+	return nil
+}
+
+// tailOffset returns the offset of the last statement of fn, its return, or
+// of the comments above it (the TODOs of unmapped fields).
+func tailOffset(fset *token.FileSet, file *ast.File, fn *ast.FuncDecl) int {
+	n := len(fn.Body.List)
+	if n == 0 {
+		return fset.Position(fn.Body.Rbrace).Offset
+	}
+
+	after := fn.Body.Lbrace + 1
+	if n > 1 {
+		after = fn.Body.List[n-2].End()
+	}
+
+	last := fn.Body.List[n-1].Pos()
+	if c := leadingComments(fset, file, after, last); c != nil {
+		last = c.Pos()
+	}
+
+	return fset.Position(last).Offset
+}
+
+// writesField reports whether stmt assigns the target field at segments, or
+// one of its subfields or elements, or passes it to a setter.
+func writesField(stmt ast.Stmt, segments []string) bool {
+	found := false
+
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			found = found || slices.ContainsFunc(n.Lhs, func(e ast.Expr) bool { return outPathHas(e, segments) })
+		case *ast.CallExpr:
+			setter := slices.Clone(segments)
+			setter[len(setter)-1] = "Set" + setter[len(setter)-1]
+			found = found || outPathHas(n.Fun, setter)
+		}
+
+		return !found
+	})
+
+	return found
+}
+
+// outPathHas reports whether e is a selector path on out starting with
+// segments (e.g., out.Address.City[i] for Address.City).
+func outPathHas(e ast.Expr, segments []string) bool {
+	var path []string
+
+	for {
+		switch x := e.(type) {
+		case *ast.SelectorExpr:
+			path = append(path, x.Sel.Name)
+			e = x.X
+
+			continue
+		case *ast.IndexExpr:
+			e = x.X
+
+			continue
+		case *ast.StarExpr:
+			e = x.X
+
+			continue
+		case *ast.ParenExpr:
+			e = x.X
+
+			continue
+		case *ast.Ident:
+			if x.Name != "out" {
+				return false
+			}
+		default:
+			return false
+		}
+
+		break
+	}
+
+	slices.Reverse(path)
+
+	return len(path) >= len(segments) && slices.Equal(path[:len(segments)], segments)
+}
@@ -0,0 +1,162 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const regenOld = `// Code generated by caster-generator. DO NOT EDIT.
+
+package casters
+
+import (
+	api "example/api"
+	store "example/store"
+)
+
+// StoreOrderToApiOrder converts store.Order to api.Order.
+func StoreOrderToApiOrder(in store.Order) api.Order {
+	out := api.Order{}
+
+	// field mapping: 1:1 (transform)
+	out.Total = DollarsToCents(in.Total)
+
+	// auto-matched: Name -> Name (score: 1.00, identical)
+	out.Name = in.Name // hand tweak
+
+	// TODO: Count - no high-confidence match
+	// TODO: Note - no high-confidence match
+
+	return out
+}
+`
+
+const regenNew = `// Code generated by caster-generator. DO NOT EDIT.
+
+package casters
+
+import (
+	api "example/api"
+	store "example/store"
+	strconv "strconv"
+)
+
+// StoreOrderToApiOrder converts store.Order to api.Order.
+func StoreOrderToApiOrder(in store.Order) api.Order {
+	out := api.Order{}
+
+	// field mapping: 1:1 (transform)
+	out.Total = ToCents(in.Total)
+
+	// field mapping: 1:1 (string method)
+	out.Count = strconv.Itoa(in.Count)
+
+	// auto-matched: Items -> Items (score: 0.76, slice map)
+	out.Items = make([]api.Item, len(in.Items))
+	for i_0 := range in.Items {
+		out.Items[i_0] = StoreItemToApiItem(in.Items[i_0])
+	}
+
+	// auto-matched: Name -> Name (score: 1.00, identical)
+	out.Name = in.Name
+
+	// TODO: Note - no high-confidence match
+
+	return out
+}
+`
+
+func TestPatchField(t *testing.T) {
+	patch := func(t *testing.T, field string) string {
+		t.Helper()
+
+		out, err := PatchField("order.go", []byte(regenOld), []byte(regenNew), "StoreOrderToApiOrder", field)
+		require.NoError(t, err)
+
+		return string(out)
+	}
+
+	t.Run("replaces the statement", func(t *testing.T) {
+		out := patch(t, "Total")
+		assert.Contains(t, out, "out.Total = ToCents(in.Total)")
+		assert.NotContains(t, out, "DollarsToCents")
+		assert.Contains(t, out, "out.Name = in.Name // hand tweak", "other fields are kept")
+	})
+
+	t.Run("replaces a TODO above the others", func(t *testing.T) {
+		out := patch(t, "Count")
+		assert.Contains(t, out, "\t// field mapping: 1:1 (string method)\n\tout.Count = strconv.Itoa(in.Count)\n\n"+
+			"\t// TODO: Note - no high-confidence match\n")
+		assert.NotContains(t, out, "TODO: Count")
+		assert.Contains(t, out, `"strconv"`, "imports of the new code are added")
+	})
+
+	t.Run("inserts statements with their layout", func(t *testing.T) {
+		out := patch(t, "Items")
+		assert.Contains(t, out, "\tout.Items = make([]api.Item, len(in.Items))\n\tfor i_0 := range in.Items {\n")
+		assert.NotContains(t, out, `"strconv"`)
+	})
+
+	t.Run("keeps a TODO", func(t *testing.T) {
+		assert.Equal(t, regenOld, patch(t, "Note"))
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := PatchField("order.go", []byte(regenOld), []byte(regenNew), "StoreOrderToApiOrder", "Missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't assign field Missing")
+	})
+
+	t.Run("unknown caster", func(t *testing.T) {
+		_, err := PatchField("order.go", []byte(regenOld), []byte(regenNew), "OtherCaster", "Total")
+		require.ErrorIs(t, err, ErrCasterNotFound)
+	})
+
+	t.Run("hand-written file", func(t *testing.T) {
+		_, err := PatchField("order.go", []byte("package casters\n"), []byte(regenNew), "StoreOrderToApiOrder", "Total")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "was not generated by caster-generator")
+	})
+}
+
+func TestFindCaster(t *testing.T) {
+	dir := t.TempDir()
+
+	handWritten := "package casters\n\nfunc StoreOrderToApiOrder() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a_hand.go"), []byte(handWritten), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.go"), []byte(regenOld), 0o644))
+
+	path, err := FindCaster(dir, "StoreOrderToApiOrder")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "order.go"), path, "only generated files are searched")
+
+	_, err = FindCaster(dir, "OtherCaster")
+	require.ErrorIs(t, err, ErrCasterNotFound)
+}
+
+func TestReadOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ReadOptions(dir)
+	require.ErrorContains(t, err, "no files generated by caster-generator")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.go"), []byte(regenOld), 0o644))
+
+	opts, err := ReadOptions(dir)
+	require.NoError(t, err)
+	assert.Equal(t, HeaderOptions{PackageName: "casters", Style: StyleFunctions}, opts)
+
+	files := []GeneratedFile{{Filename: "order.go", Content: []byte(regenOld)}}
+	NewGenerator(GeneratorConfig{Style: StyleMethods, DeepCopy: true}).recordOptions(files)
+	assert.Contains(t, string(files[0].Content),
+		"DO NOT EDIT.\n// caster-generator options: -style=methods -deep-copy\n\npackage casters")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order.go"), files[0].Content, 0o644))
+
+	opts, err = ReadOptions(dir)
+	require.NoError(t, err)
+	assert.Equal(t, HeaderOptions{PackageName: "casters", Style: StyleMethods, DeepCopy: true}, opts)
+}
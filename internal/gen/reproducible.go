@@ -0,0 +1,60 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+)
+
+// normalizeFiles makes generated files identical across platforms: filenames
+// use forward slashes and contents use LF line endings, whatever the mapping
+// file or templates carried.
+func normalizeFiles(files []GeneratedFile) {
+	for i := range files {
+		files[i].Filename = filepath.ToSlash(files[i].Filename)
+		files[i].Content = normalizeNewlines(files[i].Content)
+	}
+}
+
+// normalizeNewlines converts CRLF and lone CR line endings to LF.
+func normalizeNewlines(b []byte) []byte {
+	if !bytes.ContainsRune(b, '\r') {
+		return b
+	}
+
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+
+	return bytes.ReplaceAll(b, []byte("\r"), []byte("\n"))
+}
+
+// CompareFiles reports the first difference between two generations of the
+// same plan, or nil if they are byte-identical and in the same order.
+func CompareFiles(a, b []GeneratedFile) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("generated %d files, then %d", len(a), len(b))
+	}
+
+	for i := range a {
+		if a[i].Filename != b[i].Filename {
+			return fmt.Errorf("file #%d is %s, then %s", i+1, a[i].Filename, b[i].Filename)
+		}
+
+		if !bytes.Equal(a[i].Content, b[i].Content) {
+			return fmt.Errorf("%s differs at byte %d", a[i].Filename, firstDiff(a[i].Content, b[i].Content))
+		}
+	}
+
+	return nil
+}
+
+// firstDiff returns the offset of the first differing byte of a and b.
+func firstDiff(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+
+	return n
+}
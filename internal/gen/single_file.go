@@ -0,0 +1,101 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"text/template"
+)
+
+// mergeFiles combines generated files of the output package into a single file
+// named filename. Imports are deduplicated and the remaining code of each file
+// is kept in order, so the result is as deterministic as its inputs.
+func (g *Generator) mergeFiles(filename string, files []GeneratedFile) (*GeneratedFile, error) {
+	imports := make(map[string]importSpec)
+
+	var body bytes.Buffer
+
+	for _, file := range files {
+		fset := token.NewFileSet()
+
+		parsed, err := parser.ParseFile(fset, file.Filename, file.Content, parser.ParseComments|parser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file.Filename, err)
+		}
+
+		for _, imp := range parsed.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", file.Filename, err)
+			}
+
+			spec := importSpec{Path: path}
+			if imp.Name != nil {
+				spec.Alias = imp.Name.Name
+			}
+
+			imports[path] = spec
+		}
+
+		// Everything after the import block (or the package clause) is declarations.
+		end := parsed.Name.End()
+		if len(parsed.Decls) > 0 {
+			end = parsed.Decls[len(parsed.Decls)-1].End()
+		}
+
+		body.Write(file.Content[fset.Position(end).Offset:])
+		body.WriteString("\n")
+	}
+
+	data := &templateData{
+		PackageName: g.config.PackageName,
+		Filename:    filename,
+	}
+
+	for _, imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := singleFileTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	buf.Write(body.Bytes())
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		if g.config.OutputDir != "" {
+			_ = writeDebugUnformatted(g.config.OutputDir, filename, buf.Bytes())
+		}
+
+		return &GeneratedFile{
+			Filename: filename,
+			Content:  buf.Bytes(),
+		}, fmt.Errorf("formatting code: %w", err)
+	}
+
+	return &GeneratedFile{
+		Filename: filename,
+		Content:  formatted,
+	}, nil
+}
+
+var singleFileTemplate = template.Must(template.New("single").Parse(`// Code generated by caster-generator. DO NOT EDIT.
+
+package {{.PackageName}}
+
+{{if .Imports}}
+import (
+{{range .Imports}}	{{if .Alias}}{{.Alias}} {{end}}"{{.Path}}"
+{{end}})
+{{end}}
+`))
@@ -0,0 +1,198 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// placeCasters sets the output packages of pairs generated outside the output
+// package: casters generated as methods go into the package of their source
+// type, and implicit pairs into the package of the first caster calling them,
+// so neither imports a package importing it.
+func (g *Generator) placeCasters(pairs []plan.ResolvedTypePair) error {
+	for i := range pairs {
+		pair := &pairs[i]
+		if pair.Method == "" || pair.Output != nil {
+			continue
+		}
+
+		var pkg *analyze.PackageInfo
+		if g.graph != nil {
+			pkg = g.graph.Packages[pair.SourceType.ID.PkgPath]
+		}
+
+		if pkg == nil {
+			return fmt.Errorf("%s->%s: method %s needs the package of %s loaded",
+				pair.SourceType.ID, pair.TargetType.ID, pair.Method, pair.SourceType.ID)
+		}
+
+		if pkg.External {
+			return fmt.Errorf("%s->%s: method %s can't be generated into %s of module %s, which is read-only",
+				pair.SourceType.ID, pair.TargetType.ID, pair.Method, pkg.Path, pkg.Module)
+		}
+
+		pair.Output = &mapping.Output{Package: pkg.Name, Dir: pkg.Dir}
+	}
+
+	implicit := make(map[string]int)
+
+	for i := range pairs {
+		if pairs[i].Implicit {
+			implicit[fmt.Sprintf("%s->%s", pairs[i].SourceType.ID, pairs[i].TargetType.ID)] = i
+		}
+	}
+
+	placed := make(map[string]bool, len(implicit))
+
+	var place func(caller *plan.ResolvedTypePair, out *mapping.Output)
+
+	place = func(caller *plan.ResolvedTypePair, out *mapping.Output) {
+		for _, nested := range caller.NestedPairs {
+			if nested.ResolvedPair == nil {
+				continue
+			}
+
+			// Inline conversions call the casters of their fields directly
+			if nested.Inline() {
+				place(nested.ResolvedPair, out)
+				continue
+			}
+
+			key := fmt.Sprintf("%s->%s", nested.SourceType.ID, nested.TargetType.ID)
+
+			i, ok := implicit[key]
+			if !ok || placed[key] {
+				continue
+			}
+
+			placed[key] = true
+			pairs[i].Output = out
+			place(&pairs[i], out)
+		}
+	}
+
+	for i := range pairs {
+		if !pairs[i].Implicit {
+			place(&pairs[i], pairs[i].Output)
+		}
+	}
+
+	return nil
+}
+
+// checkSourceMethods checks the casters generated as methods of their source
+// type, which the methods style and generic requires rule out: each must be
+// the only one of its name on its type, and collide with neither a field nor
+// a hand-written method of it.
+func (g *Generator) checkSourceMethods(p *plan.ResolvedMappingPlan) error {
+	owners := make(map[string]string)
+	declared := make(map[string]map[string]string)
+
+	for i := range p.TypePairs {
+		pair := &p.TypePairs[i]
+		if pair.Method == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+
+		switch {
+		case g.methods():
+			return fmt.Errorf("%s: casters can't be methods of their source type with the methods style", key)
+		case len(g.genericRequires(pair)) > 0:
+			return fmt.Errorf("%s: method %s can't take generic requires", key, pair.Method)
+		case pair.SourceType.DeclaresField(pair.Method):
+			return fmt.Errorf("%s: method %s collides with field %s.%s", key, pair.Method, pair.SourceType.ID,
+				pair.Method)
+		}
+
+		method := pair.SourceType.ID.String() + "." + pair.Method
+		if owner, ok := owners[method]; ok {
+			return fmt.Errorf("%s: method %s is also the caster of %s", key, method, owner)
+		}
+
+		owners[method] = key
+
+		dir := pair.Output.Dir
+		if _, ok := declared[dir]; !ok {
+			methods, err := handWrittenMethods(dir)
+			if err != nil {
+				return err
+			}
+
+			declared[dir] = methods
+		}
+
+		if file, ok := declared[dir][pair.SourceType.ID.Name+"."+pair.Method]; ok {
+			return fmt.Errorf("%s: method %s collides with a declaration of %s", key, method, file)
+		}
+	}
+
+	return nil
+}
+
+// handWrittenMethods returns the methods declared by the Go files of dir not
+// written by the generator, keyed by receiver type and name (e.g.,
+// "Order.Total"), with their file names.
+func handWrittenMethods(dir string) (map[string]string, error) {
+	files, err := handWrittenFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make(map[string]string)
+
+	for name, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+
+			if recv := receiverTypeName(fn.Recv.List[0].Type); recv != "" {
+				methods[recv+"."+fn.Name.Name] = name
+			}
+		}
+	}
+
+	return methods, nil
+}
+
+// receiverTypeName returns the name of the type of a receiver expression
+// (e.g., "Order" for *Order or Page[T]), or "".
+func receiverTypeName(x ast.Expr) string {
+	for {
+		switch t := x.(type) {
+		case *ast.Ident:
+			return t.Name
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.IndexListExpr:
+			x = t.X
+		default:
+			return ""
+		}
+	}
+}
+
+// methodCall returns the call of method on the value of expression recv with
+// args, parenthesizing recv unless it is an operand (e.g., (*in.Item).To()).
+func methodCall(recv, method string, args []string) string {
+	switch x, _ := parser.ParseExpr(recv); x.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.CallExpr, *ast.IndexExpr, *ast.ParenExpr:
+	default:
+		recv = "(" + recv + ")"
+	}
+
+	return fmt.Sprintf("%s.%s(%s)", recv, method, strings.Join(args, ", "))
+}
@@ -1,7 +1,13 @@
 package gen
 
 import (
+	"bytes"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
 	"strings"
 
 	"caster-generator/internal/analyze"
@@ -16,6 +22,15 @@ func (g *Generator) applyConversionStrategy(
 	pair *plan.ResolvedTypePair,
 	imports map[string]importSpec,
 ) {
+	// Deduplicated slices are always converted element by element.
+	if m.DedupBy != "" {
+		assignment.IsSlice = true
+		assignment.SliceElemVar = "i"
+		assignment.SliceBody = g.buildDedupSliceMapping(assignment.TargetField, m, pair, imports)
+
+		return
+	}
+
 	switch m.Strategy {
 	case plan.StrategyDirectAssign:
 		// Simple assignment, nothing extra needed
@@ -24,7 +39,7 @@ func (g *Generator) applyConversionStrategy(
 		g.applyConvertStrategy(assignment, m, pair, imports)
 
 	case plan.StrategyPointerDeref:
-		g.applyPointerDerefStrategy(assignment, m, pair)
+		g.applyPointerDerefStrategy(assignment, m, pair, imports)
 
 	case plan.StrategyPointerWrap:
 		g.applyPointerWrapStrategy(assignment, m, pair, imports)
@@ -32,11 +47,11 @@ func (g *Generator) applyConversionStrategy(
 	case plan.StrategySliceMap:
 		assignment.IsSlice = true
 		assignment.SliceElemVar = "i"
-		assignment.SliceBody = g.buildSliceMapping(m, pair, imports)
+		assignment.SliceBody = g.buildSliceMapping(assignment.TargetField, m, pair, imports)
 
 	case plan.StrategyMap:
 		assignment.IsMap = true
-		assignment.MapBody = g.buildMapMapping(m, pair, imports)
+		assignment.MapBody = g.buildMapMapping(assignment.TargetField, m, pair, imports)
 
 	case plan.StrategyPointerNestedCast:
 		g.applyPointerNestedCastStrategy(assignment, m, pair, imports)
@@ -45,16 +60,75 @@ func (g *Generator) applyConversionStrategy(
 		g.applyNestedCastStrategy(assignment, m, pair)
 
 	case plan.StrategyTransform:
-		g.applyTransformStrategy(assignment, m, pair)
+		g.applyTransformStrategy(assignment, m, pair, imports)
 
 	case plan.StrategyDefault:
 		if m.Default != nil {
-			assignment.SourceExpr = *m.Default
+			assignment.SourceExpr = g.qualifyValue(*m.Default, m.Qualifiers, imports)
 		}
 
 	case plan.StrategyIgnore:
 		// Already handled above
+
+	case plan.StrategyWrapper:
+		g.applyWrapperStrategy(assignment, m, pair, imports)
+
+	case plan.StrategyInterfaceSwitch:
+		g.applyInterfaceSwitchStrategy(assignment, m, pair, imports)
+
+	case plan.StrategyStringMethod:
+		g.applyStringMethodStrategy(assignment, m, pair, imports)
+
+	case plan.StrategyEnumMap:
+		g.applyEnumMapStrategy(assignment, m, pair, imports)
+
+	case plan.StrategySQLNull:
+		g.applySQLNullStrategy(assignment, m, pair, imports)
+
+	case plan.StrategyCollect:
+		g.applyCollectStrategy(assignment, m, pair, imports)
+
+	case plan.StrategyMapKey:
+		g.applyMapKeyStrategy(assignment, m, pair, imports)
+	}
+}
+
+// applyNilToEmpty replaces a nil source slice or map with an empty collection
+// for strategies that assign the source directly, or a nil source pointer with
+// a pointer to an empty collection for *[]T and *map[K]V targets. Loop-based
+// strategies always allocate the target, so they already produce an empty
+// collection.
+func (g *Generator) applyNilToEmpty(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if !m.NilToEmpty || len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	if m.Strategy != plan.StrategyDirectAssign && m.Strategy != plan.StrategyConvert {
+		return
+	}
+
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+	if tgtType == nil {
+		return
+	}
+
+	collection, ref := tgtType, ""
+	if tgtType.Kind == analyze.TypeKindPointer && tgtType.ElemType != nil {
+		collection, ref = tgtType.ElemType, "&"
 	}
+
+	if collection.Kind != analyze.TypeKindSlice && collection.Kind != analyze.TypeKindMap {
+		return
+	}
+
+	assignment.NeedsNilCheck = true
+	assignment.NilCheckExpr = g.sourceFieldExpr(m.SourcePaths, m, pair)
+	assignment.NilDefault = ref + g.typeRefString(collection, imports) + "{}"
 }
 
 // applyConvertStrategy applies the type conversion strategy.
@@ -77,21 +151,28 @@ func (g *Generator) applyPointerDerefStrategy(
 	assignment *assignmentData,
 	m *plan.ResolvedFieldMapping,
 	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
 ) {
 	assignment.NeedsNilCheck = true
 	// Keep the original pointer expression for the nil-check; use a dereferenced
 	// expression for the actual assignment.
 	assignment.NilDefault = g.zeroValue(pair.TargetType, m.TargetPaths)
+	assignment.NilCheckExpr = assignment.SourceExpr
+	assignment.SourceExpr = "*" + assignment.SourceExpr
 
 	if len(m.TargetPaths) > 0 {
 		ft := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
 		if ft != nil && ft.Kind == analyze.TypeKindStruct {
 			assignment.NilDefault += " /* FIXME: zero value used for nil pointer */"
 		}
-	}
 
-	assignment.NilCheckExpr = assignment.SourceExpr
-	assignment.SourceExpr = "*" + assignment.SourceExpr
+		if len(m.SourcePaths) > 0 {
+			st := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+			if st != nil && st.Kind == analyze.TypeKindPointer {
+				assignment.SourceExpr = g.convertValue(assignment.SourceExpr, st.ElemType, ft, imports)
+			}
+		}
+	}
 }
 
 // applyPointerWrapStrategy applies the pointer wrap strategy.
@@ -104,7 +185,21 @@ func (g *Generator) applyPointerWrapStrategy(
 	if len(m.SourcePaths) > 0 {
 		typeStr := g.getFieldTypeString(pair.SourceType, m.SourcePaths[0].String(), imports)
 		srcExpr := g.sourceFieldExpr(m.SourcePaths, m, pair)
-		assignment.SourceExpr = fmt.Sprintf("func() *%s { v := %s; return &v }()", typeStr, srcExpr)
+
+		// Point to the target's element type, converting the value if it differs.
+		st := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+		if len(m.TargetPaths) > 0 {
+			tt := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+			if tt != nil && tt.Kind == analyze.TypeKindPointer && tt.ElemType != nil {
+				if converted := g.convertValue(srcExpr, st, tt.ElemType, imports); converted != srcExpr {
+					typeStr = g.typeRefString(tt.ElemType, imports)
+					srcExpr = converted
+				}
+			}
+		}
+
+		v := g.local("v")
+		assignment.SourceExpr = fmt.Sprintf("func() *%s { %s := %s; return &%s }()", typeStr, v, srcExpr, v)
 	}
 }
 
@@ -138,13 +233,13 @@ func (g *Generator) applyPointerNestedCastStrategy(
 		tgtElem = tgtType.ElemType
 	}
 
-	casterName := g.nestedFunctionName(srcElem, tgtElem)
 	tgtElemStr := g.typeRefString(tgtElem, imports)
 
 	// Generate: func() *TargetType { if src == nil { return nil }; v := Caster(*src); return &v }()
+	v := g.local("v")
 	assignment.SourceExpr = fmt.Sprintf(
-		"func() *%s { if %s == nil { return nil }; v := %s(*%s); return &v }()",
-		tgtElemStr, assignment.SourceExpr, casterName, assignment.SourceExpr,
+		"func() *%s { if %s == nil { return nil }; %s := %s; return &%s }()",
+		tgtElemStr, assignment.SourceExpr, v, g.nestedCall(srcElem, tgtElem, "*"+assignment.SourceExpr), v,
 	)
 }
 
@@ -162,18 +257,19 @@ func (g *Generator) applyNestedCastStrategy(
 	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
 
 	if srcType != nil && tgtType != nil {
-		casterName := g.nestedFunctionName(srcType, tgtType)
-		assignment.NestedCaster = casterName
+		assignment.NestedCaster = g.nestedFunctionName(srcType, tgtType)
 		// Always call the nested caster with the resolved source expression.
-		assignment.SourceExpr = fmt.Sprintf("%s(%s)", casterName, assignment.SourceExpr)
+		assignment.SourceExpr = g.nestedCall(srcType, tgtType, assignment.SourceExpr)
 	}
 }
 
-// applyTransformStrategy applies the transform function call strategy.
+// applyTransformStrategy applies the transform function call strategy, or
+// inlines the expression of a template transform.
 func (g *Generator) applyTransformStrategy(
 	assignment *assignmentData,
 	m *plan.ResolvedFieldMapping,
 	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
 ) {
 	if m.Transform == "" {
 		return
@@ -183,64 +279,625 @@ func (g *Generator) applyTransformStrategy(
 
 	// Append extras after explicit source paths (stable order as specified in YAML).
 	// Extras can reference either source fields or target fields.
-	if len(m.Extra) > 0 {
-		var extraArgs []string
-
-		for _, ev := range m.Extra {
-			// Prefer explicit source/target, else fallback to the extra name.
-			if ev.Def.Source != "" {
-				extraArgs = append(extraArgs, "in."+ev.Def.Source)
-				continue
-			}
+	for _, ev := range m.Extra {
+		// Constants are passed as their literal
+		if ev.Const != "" {
+			args = append(args, string(ev.Const))
+			continue
+		}
 
-			if ev.Def.Target != "" {
-				extraArgs = append(extraArgs, "out."+ev.Def.Target)
-				continue
-			}
+		// Prefer explicit source/target, else fallback to the extra name.
+		if ev.Def.Source != "" {
+			args = append(args, "in."+ev.Def.Source)
+			continue
+		}
 
-			// If Name matches a required arg, it should be passed verbatim.
-			isReq := false
+		if ev.Def.Target != "" {
+			args = append(args, "out."+ev.Def.Target)
+			continue
+		}
 
-			for _, req := range pair.Requires {
-				if req.Name == ev.Name {
-					isReq = true
-					break
-				}
-			}
+		// If Name matches a required arg, it should be passed verbatim.
+		isReq := false
 
-			if isReq {
-				extraArgs = append(extraArgs, ev.Name)
-			} else {
-				extraArgs = append(extraArgs, "in."+ev.Name)
+		for _, req := range pair.Requires {
+			if req.Name == ev.Name {
+				isReq = true
+				break
 			}
 		}
 
-		if args == "" {
-			args = strings.Join(extraArgs, ", ")
+		if isReq {
+			args = append(args, ev.Name)
 		} else {
-			args = args + ", " + strings.Join(extraArgs, ", ")
+			args = append(args, "in."+ev.Name)
+		}
+	}
+
+	if expr, ok := g.templateTransformExpr(m.Transform, args, imports); ok {
+		assignment.SourceExpr = expr
+		return
+	}
+
+	// Context-aware transforms receive the caster's ctx first.
+	if g.ctxTransforms[m.Transform] {
+		args = append([]string{"ctx"}, args...)
+	}
+
+	fn := m.Transform
+	if wrapper, ok := g.memoizeTransform(m, pair); ok {
+		fn = wrapper
+	} else if method, ok := g.transformMethod(m, pair); ok {
+		fn = method
+	}
+
+	assignment.SourceExpr = fmt.Sprintf("%s(%s)", fn, strings.Join(args, ", "))
+}
+
+// applyWrapperStrategy converts through a declared generic wrapper using its
+// get/present methods and new constructor or set method.
+func (g *Generator) applyWrapperStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	wc := plan.FindWrapperConversion(g.wrappers, srcType, tgtType)
+	if wc == nil {
+		return
+	}
+
+	src := assignment.SourceExpr
+	def := wc.Def
+
+	if wc.Unwrap {
+		get := fmt.Sprintf("%s.%s()", src, def.Get)
+
+		switch {
+		case !wc.Pointer:
+			assignment.SourceExpr = get
+		case def.Present != "":
+			v := g.local("v")
+			assignment.SourceExpr = fmt.Sprintf("func() *%s { if !%s.%s() { return nil }; %s := %s; return &%s }()",
+				g.typeRefString(wc.Value, imports), src, def.Present, v, get, v)
+		default:
+			v := g.local("v")
+			assignment.SourceExpr = fmt.Sprintf("func() *%s { %s := %s; return &%s }()",
+				g.typeRefString(wc.Value, imports), v, get, v)
+		}
+
+		return
+	}
+
+	value := src
+	if wc.Pointer {
+		value = "*" + src
+	}
+
+	// Build the wrapper: prefer the constructor, else call Set on a zero value.
+	w := g.local("w")
+
+	var build string
+	if def.New != "" {
+		build = fmt.Sprintf("%s = %s(%s)", w, g.wrapperConstructor(wc, imports), value)
+	} else {
+		build = fmt.Sprintf("%s.%s(%s)", w, def.Set, value)
+	}
+
+	wrapperStr := g.typeRefString(wc.Wrapper, imports)
+
+	switch {
+	case wc.Pointer:
+		assignment.SourceExpr = fmt.Sprintf("func() %s { var %s %s; if %s != nil { %s }; return %s }()",
+			wrapperStr, w, wrapperStr, src, build, w)
+	case def.New != "":
+		assignment.SourceExpr = fmt.Sprintf("%s(%s)", g.wrapperConstructor(wc, imports), value)
+	default:
+		assignment.SourceExpr = fmt.Sprintf("func() %s { var %s %s; %s; return %s }()", wrapperStr, w, wrapperStr, build, w)
+	}
+}
+
+// wrapperConstructor returns the qualified constructor of a wrapper, which lives
+// in the wrapper's own package.
+func (g *Generator) wrapperConstructor(wc *plan.WrapperConversion, imports map[string]importSpec) string {
+	pkgPath := wc.Wrapper.ID.PkgPath
+	if pkgPath == "" || pkgPath == g.contextPkgPath {
+		return wc.Def.New
+	}
+
+	g.addImport(imports, pkgPath)
+
+	return g.getPkgName(pkgPath) + "." + wc.Def.New
+}
+
+// applyInterfaceSwitchStrategy converts an interface-typed source with a type
+// switch over the registered implementations, calling each pair's caster.
+// Values of unregistered types leave the target at its zero value.
+func (g *Generator) applyInterfaceSwitchStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	cases := plan.FindInterfaceCases(g.implementations, g.graph, srcType, tgtType)
+	if len(cases) == 0 {
+		return
+	}
+
+	r, v, c := g.local("r"), g.local("v"), g.local("c")
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "func() (%s %s) { switch %s := %s.(type) {", r, g.typeRefString(tgtType, imports), v, assignment.SourceExpr)
+
+	for _, ic := range cases {
+		caseType := g.typeRefString(ic.Source, imports)
+		arg := v
+
+		if ic.SourcePointer {
+			caseType = "*" + caseType
+			arg = "*" + v
+		}
+
+		body := r + " = " + g.nestedCall(ic.Source, ic.Target, arg)
+		if ic.TargetPointer {
+			body = c + " := " + g.nestedCall(ic.Source, ic.Target, arg) + "; " + r + " = &" + c
+		}
+
+		if ic.SourcePointer {
+			body = "if " + v + " != nil { " + body + " }"
+		}
+
+		fmt.Fprintf(&sb, " case %s: %s;", caseType, body)
+	}
+
+	fmt.Fprintf(&sb, " }; return %s }()", r)
+
+	assignment.SourceExpr = sb.String()
+}
+
+// applyStringMethodStrategy converts to string with the source's String() method,
+// or from string with the target's parse function.
+func (g *Generator) applyStringMethodStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	sc := plan.FindStringConversion(g.graph, srcType, tgtType)
+	if sc == nil {
+		return
+	}
+
+	if !sc.Parse {
+		assignment.SourceExpr += ".String()"
+		return
+	}
+
+	fn := sc.Func.ID.Name
+	if pkgPath := sc.Func.ID.PkgPath; pkgPath != "" && pkgPath != g.contextPkgPath {
+		g.addImport(imports, pkgPath)
+		fn = g.getPkgName(pkgPath) + "." + fn
+	}
+
+	if !sc.ReturnsError {
+		assignment.SourceExpr = fmt.Sprintf("%s(%s)", fn, assignment.SourceExpr)
+		return
+	}
+
+	v := g.local("v")
+	assignment.SourceExpr = fmt.Sprintf("func() %s { %s, _ := %s(%s); return %s }()",
+		g.typeRefString(tgtType, imports), v, fn, assignment.SourceExpr, v)
+}
+
+// applyEnumMapStrategy converts a constant value with a switch over the enum_map
+// table. Unlisted values yield enum_default (or the zero value), or panic when
+// the mapping is strict.
+func (g *Generator) applyEnumMapStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	// Validation has already rejected values that aren't literals of these types.
+	literal := func(value string, t *analyze.TypeInfo) string {
+		if lit, err := mapping.EnumLiteral(value, t); err == nil {
+			return lit
+		}
+
+		return value
+	}
+
+	// Values may also be constants of the target package, such as warehouse.StateActive.
+	targetValue := func(value string) string {
+		if x, err := parser.ParseExpr(value); err == nil {
+			if _, ok := x.(*ast.SelectorExpr); ok {
+				return g.qualifyValue(value, m.Qualifiers, imports)
+			}
+		}
+
+		return literal(value, tgtType)
+	}
+
+	r := g.local("r")
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "func() (%s %s) { switch %s {", r, g.typeRefString(tgtType, imports), assignment.SourceExpr)
+
+	cases := make(map[string]bool, len(m.EnumMap))
+
+	for _, k := range mapping.SortedEnumKeys(m.EnumMap) {
+		// Equal keys are rejected by validation; only the first makes a case.
+		key := literal(k, srcType)
+		if cases[key] {
+			continue
+		}
+
+		cases[key] = true
+		fmt.Fprintf(&sb, " case %s: %s = %s;", key, r, targetValue(m.EnumMap[k]))
+	}
+
+	switch {
+	case m.EnumStrict:
+		imports["fmt"] = importSpec{Path: "fmt"}
+		fmt.Fprintf(&sb, ` default: panic(fmt.Sprintf("unmapped %s value %%v", %s));`,
+			m.SourcePaths[0], assignment.SourceExpr)
+	case m.EnumDefault != "":
+		fmt.Fprintf(&sb, " default: %s = %s;", r, targetValue(m.EnumDefault))
+	}
+
+	fmt.Fprintf(&sb, " }; return %s }()", r)
+
+	assignment.SourceExpr = sb.String()
+}
+
+// applySQLNullStrategy converts between a database/sql nullable type and a
+// value or pointer. Reading assigns the value only when Valid (null_default
+// otherwise); writing marks values and non-nil pointers as Valid.
+func (g *Generator) applySQLNullStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	nc := plan.FindSQLNullConversion(srcType, tgtType)
+	if nc == nil {
+		return
+	}
+
+	src := assignment.SourceExpr
+
+	if !nc.Unwrap {
+		value := src
+		if nc.Pointer {
+			assignment.ValidCheck = src + " != nil"
+			value = "*" + src
+			srcType = srcType.ElemType
 		}
+
+		assignment.SourceExpr = fmt.Sprintf("%s{%s: %s, Valid: true}", g.typeRefString(nc.Null, imports),
+			nc.Value.Name, g.convertValue(value, srcType, nc.Value.Type, imports))
+
+		return
+	}
+
+	assignment.ValidCheck = src + ".Valid"
+	value := src + "." + nc.Value.Name
+
+	if !nc.Pointer {
+		assignment.SourceExpr = g.convertValue(value, nc.Value.Type, tgtType, imports)
+
+		if m.NullDefault != "" {
+			assignment.ValidDefault = nullDefaultLiteral(m.NullDefault, tgtType)
+		}
+
+		return
+	}
+
+	elemStr := g.typeRefString(tgtType.ElemType, imports)
+	v := g.local("v")
+	assignment.SourceExpr = fmt.Sprintf("func() *%s { %s := %s; return &%s }()",
+		elemStr, v, g.convertValue(value, nc.Value.Type, tgtType.ElemType, imports), v)
+
+	if m.NullDefault != "" {
+		assignment.ValidDefault = fmt.Sprintf("func() *%s { %s := %s(%s); return &%s }()",
+			elemStr, v, elemStr, nullDefaultLiteral(m.NullDefault, tgtType.ElemType), v)
+	}
+}
+
+// nullDefaultLiteral returns null_default as a Go literal of type t. Validation
+// has already rejected values that aren't literals of t.
+func nullDefaultLiteral(value string, t *analyze.TypeInfo) string {
+	if lit, err := mapping.EnumLiteral(value, t); err == nil {
+		return lit
+	}
+
+	return value
+}
+
+// applyCollectStrategy builds a map or []KV literal holding the collected
+// source fields under their keys, converting them to the value type if needed.
+func (g *Generator) applyCollectStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.TargetPaths) == 0 {
+		return
+	}
+
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	// Validation has already rejected other targets, keys and source types.
+	ct, err := mapping.NewCollectTarget(tgtType)
+	if err != nil {
+		return
+	}
+
+	entries := mapping.CollectEntries(m.Collect)
+	elems := make([]string, 0, len(entries))
+	tgtStr := g.typeRefString(tgtType, imports)
+
+	// With omit_zero, the target is built by a function literal adding the
+	// non-zero sources one by one.
+	var (
+		adds      []string
+		collected string
+	)
+
+	if m.OmitZero {
+		collected = g.local("collected")
+	}
+
+	for _, e := range entries {
+		path, err := mapping.ParsePath(e.Source)
+		if err != nil {
+			continue
+		}
+
+		key, err := mapping.EnumLiteral(e.Key, ct.Key)
+		if err != nil {
+			key = e.Key
+		}
+
+		src := g.sourceFieldExpr([]mapping.FieldPath{path}, m, pair)
+		srcType := g.getFieldTypeInfo(pair.SourceType, e.Source)
+		value := g.collectValue(src, srcType, ct.Value, imports)
+
+		elem := key + ": " + value
+		if ct.Elem != nil {
+			elem = fmt.Sprintf("{%s: %s, %s: %s}", mapping.CollectKeyField, key, mapping.CollectValueField, value)
+		}
+
+		elems = append(elems, elem)
+
+		if !m.OmitZero {
+			continue
+		}
+
+		add := fmt.Sprintf("%s[%s] = %s", collected, key, value)
+		if ct.Elem != nil {
+			add = fmt.Sprintf("%s = append(%s, %s%s)", collected, collected, g.typeRefString(ct.Elem, imports), elem)
+		}
+
+		adds = append(adds, fmt.Sprintf("if %s {\n%s\n}\n", g.isNonZeroExpr(src, srcType, imports), add))
+	}
+
+	if m.OmitZero {
+		assignment.SourceExpr = fmt.Sprintf("func() %s {\n%s := %s{}\n\n%s\nreturn %s\n}()",
+			tgtStr, collected, tgtStr, strings.Join(adds, "\n"), collected)
+
+		return
+	}
+
+	assignment.SourceExpr = fmt.Sprintf("%s{%s}", tgtStr, strings.Join(elems, ", "))
+}
+
+// collectValue returns src, of type srcType, as a collected value of type
+// value: formatted with strconv, converted, or as-is.
+func (g *Generator) collectValue(
+	src string,
+	srcType, value *analyze.TypeInfo,
+	imports map[string]importSpec,
+) string {
+	if f := mapping.NewCollectFormat(srcType, value); f != nil {
+		arg := src
+		if srcType.ID.PkgPath != "" || srcType.ID.Name != f.Arg {
+			arg = fmt.Sprintf("%s(%s)", f.Arg, src)
+		}
+
+		imports["strconv"] = importSpec{Path: "strconv"}
+		formatted := fmt.Sprintf("strconv.%s(%s%s)", f.Func, arg, f.Args)
+
+		if !isPlainString(value) {
+			formatted = fmt.Sprintf("%s(%s)", g.typeRefString(value, imports), formatted)
+		}
+
+		return formatted
+	}
+
+	if convert, _ := mapping.CollectConversion(srcType, value); convert {
+		return fmt.Sprintf("%s(%s)", g.typeRefString(value, imports), src)
+	}
+
+	return src
+}
+
+// applyMapKeyStrategy assigns the value of a key of the source map, parsed
+// into the target field; a missing key or a value that doesn't parse leaves
+// the field unassigned.
+func (g *Generator) applyMapKeyStrategy(
+	assignment *assignmentData,
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+	imports map[string]importSpec,
+) {
+	if len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+		return
+	}
+
+	srcType := g.getFieldTypeInfo(pair.SourceType, m.SourcePaths[0].String())
+	tgtType := g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
+
+	// Validation has already rejected other sources and targets.
+	elemType, err := mapping.MapKeysValue(srcType)
+	if err != nil {
+		return
+	}
+
+	p, err := mapping.NewMapValueParse(tgtType)
+	if err != nil {
+		return
+	}
+
+	value := fmt.Sprintf("%s[%s]", assignment.SourceExpr, strconv.Quote(m.MapKey))
+	v := g.local("v")
+
+	converted := v
+
+	if name, args := p.ParseFunc(); name == "" {
+		ok := g.local("ok")
+		assignment.ValidCheck = fmt.Sprintf("%s, %s := %s; %s", v, ok, value, ok)
+
+		if elemType.ID != p.Field.ID {
+			converted = g.wrapConversion(v, p.Field, imports)
+		}
+	} else {
+		if !isPlainString(elemType) {
+			value = "string(" + value + ")"
+		}
+
+		errName := g.local("err")
+		assignment.ValidCheck = fmt.Sprintf("%s, %s := strconv.%s(%s%s); %s == nil", v, errName, name, value, args, errName)
+		imports["strconv"] = importSpec{Path: "strconv"}
+
+		if p.Field.ID.PkgPath != "" || p.Field.ID.Name != parsedType(name) {
+			converted = g.wrapConversion(v, p.Field, imports)
+		}
+	}
+
+	switch {
+	case !p.Pointer:
+		assignment.SourceExpr = converted
+	case converted == v:
+		assignment.SourceExpr = "&" + v
+	default:
+		ptr := g.local("p")
+		assignment.SourceExpr = fmt.Sprintf("func() *%s { %s := %s; return &%s }()",
+			g.typeRefString(p.Field, imports), ptr, converted, ptr)
+	}
+}
+
+// parsedType returns the type of the values returned by strconv function name.
+func parsedType(name string) string {
+	switch name {
+	case "ParseBool":
+		return "bool"
+	case "ParseFloat":
+		return "float64"
+	case "ParseUint":
+		return "uint64"
+	default:
+		return "int64"
+	}
+}
+
+// isPlainString reports whether t is the predeclared string type.
+func isPlainString(t *analyze.TypeInfo) bool {
+	return t != nil && t.Kind == analyze.TypeKindBasic && t.ID.PkgPath == "" && t.ID.Name == "string"
+}
+
+// qualifyValue rewrites the package qualifiers of a const or expr value to the
+// aliases of their imports, adding them.
+func (g *Generator) qualifyValue(value string, qualifiers map[string]string, imports map[string]importSpec) string {
+	if len(qualifiers) == 0 {
+		return value
+	}
+
+	x, err := parser.ParseExpr(value)
+	if err != nil {
+		return value
+	}
+
+	ast.Inspect(x, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if id, ok := sel.X.(*ast.Ident); ok {
+			if pkgPath, ok := qualifiers[id.Name]; ok {
+				id.Name = g.getPkgName(pkgPath)
+				g.addImport(imports, pkgPath)
+			}
+		}
+
+		return true
+	})
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), x); err != nil {
+		return value
 	}
 
-	assignment.SourceExpr = fmt.Sprintf("%s(%s)", m.Transform, args)
+	return buf.String()
 }
 
 // buildSliceMapping generates the slice mapping code.
 func (g *Generator) buildSliceMapping(
+	target string,
 	m *plan.ResolvedFieldMapping,
 	pair *plan.ResolvedTypePair,
 	imports map[string]importSpec,
 ) string {
-	return g.buildCollectionMapping(m, pair, imports, "slice")
+	return g.buildCollectionMapping(target, m, pair, imports, "slice")
 }
 
 // buildMapMapping generates the map mapping code.
 func (g *Generator) buildMapMapping(
+	target string,
 	m *plan.ResolvedFieldMapping,
 	pair *plan.ResolvedTypePair,
 	imports map[string]importSpec,
 ) string {
-	return g.buildCollectionMapping(m, pair, imports, "map")
+	return g.buildCollectionMapping(target, m, pair, imports, "map")
 }
 
 // buildExtraArgsForNestedCall builds the extra arguments string for a nested caster call.
@@ -253,6 +910,8 @@ func (g *Generator) buildExtraArgsForNestedCall(extra []mapping.ExtraVal) string
 
 	for _, ev := range extra {
 		switch {
+		case ev.Const != "":
+			args = append(args, string(ev.Const))
 		case ev.Def.Target != "":
 			// If the extra has a target definition, use "out.<target>"
 			args = append(args, "out."+ev.Def.Target)
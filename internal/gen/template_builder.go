@@ -2,7 +2,9 @@ package gen
 
 import (
 	"fmt"
+	"go/types"
 	"maps"
+	"slices"
 	"sort"
 	"strings"
 
@@ -25,7 +27,38 @@ type templateData struct {
 	NestedCasters     []nestedCasterRef
 	MissingTransforms []MissingTransform
 	ExtraArgs         []extraArg
-	StructDef         string
+	// TypeParams is the caster's type parameter list (e.g., "[T any]"), if any.
+	TypeParams string
+	// Receiver is the caster's receiver (e.g., "c *Casters") in the methods style.
+	Receiver string
+	// SourceMethod makes the caster FunctionName a method of its source
+	// type, taking in as its receiver.
+	SourceMethod bool
+	// Overrides adds the overrides parameter applied to the result.
+	Overrides bool
+	// MemoizedTransforms is used by the memoized transforms file.
+	MemoizedTransforms []MemoizedTransform
+	StructDef          string
+	UsesContext        bool
+	// CloneHelpers is used by the clone helpers file.
+	CloneHelpers []CloneHelper
+	// DebugChecks are the target fields checked before returning in the debug
+	// variant of the caster.
+	DebugChecks []debugCheck
+	// MergeFunctionName names the merge variant of the caster, if generated.
+	MergeFunctionName string
+	// DepthLimit is the depth limit of the recursive cycle of the caster, if
+	// any; the caster then calls DepthCall, its variant DepthFunctionName
+	// taking the depth of recursion.
+	DepthLimit        int
+	DepthFunctionName string
+	DepthCall         string
+	// ViaCall chains the casters through the intermediate type of a via pair,
+	// making up the whole caster body.
+	ViaCall string
+	// BeforeHook and AfterHook call the hook functions of the pair, if any.
+	BeforeHook string
+	AfterHook  string
 }
 
 // extraArg represents an additional argument to a caster function.
@@ -38,8 +71,17 @@ type extraArg struct {
 type assignmentData struct {
 	TargetField string
 	SourceExpr  string
-	Comment     string
-	Strategy    plan.ConversionStrategy
+	// For targets assigned by a setter method (e.g., "out.SetName"), called
+	// with TargetField, then a local variable of type SetterType
+	Setter     string
+	SetterType string
+	Comment    string
+	Deprecated string
+	Strategy   plan.ConversionStrategy
+	// For the merge variant: MergeNever skips the assignment, MergeCheck
+	// guards it with a zero check of the target
+	MergeNever bool
+	MergeCheck string
 	// For slice mapping
 	IsSlice      bool
 	SliceElemVar string
@@ -54,6 +96,20 @@ type assignmentData struct {
 	NilDefault    string
 	// For pointer nil check
 	NilCheckExpr string
+	// For assignments guarded by a condition (e.g., "in.Name.Valid"),
+	// assigning ValidDefault otherwise if set
+	ValidCheck   string
+	ValidDefault string
+	// For assignments reading source elements by index or key, the bounds
+	// and nil checks of the paths (e.g., "len(in.Items) > 0")
+	SourceGuard string
+	// For assignments guarded by the when condition of the field mapping,
+	// assigning ElseDefault otherwise if set
+	When        string
+	ElseDefault string
+	// For assignments calling casters of a depth-limited recursive cycle,
+	// the condition under which they recurse (e.g., "depth < 3")
+	DepthGuard string
 }
 
 // nestedCasterRef tracks a nested caster function that needs to be called.
@@ -65,6 +121,8 @@ type nestedCasterRef struct {
 
 // buildTemplateData constructs the template data from a resolved type pair.
 func (g *Generator) buildTemplateData(pair *plan.ResolvedTypePair) *templateData {
+	g.foreignImports = make(map[string]importSpec)
+
 	srcPkgAlias := g.getPkgName(pair.SourceType.ID.PkgPath)
 	tgtPkgAlias := g.getPkgName(pair.TargetType.ID.PkgPath)
 
@@ -77,6 +135,8 @@ func (g *Generator) buildTemplateData(pair *plan.ResolvedTypePair) *templateData
 		PackageName:      g.config.PackageName,
 		Filename:         g.filename(pair),
 		FunctionName:     g.functionName(pair),
+		Receiver:         g.receiver(),
+		Overrides:        g.config.Overrides,
 		GenerateComments: g.config.GenerateComments,
 		SourceType: typeRef{
 			Package: srcPkgAlias,
@@ -88,38 +148,96 @@ func (g *Generator) buildTemplateData(pair *plan.ResolvedTypePair) *templateData
 		},
 	}
 
-	// Add Requires as extra args
-	if len(pair.Requires) > 0 {
-		for _, req := range pair.Requires {
-			data.ExtraArgs = append(data.ExtraArgs, extraArg{
-				Name: req.Name,
-				Type: req.Type,
-			})
+	if pair.Method != "" {
+		data.FunctionName = pair.Method
+		data.SourceMethod = true
+	}
+
+	// Add Requires as extra args, pass-through ones typed by a type parameter
+	generic := g.genericRequires(pair)
+	data.TypeParams = typeParamList(pair, generic)
+
+	for _, req := range pair.Requires {
+		typ := req.Type
+		if param, ok := generic[req.Name]; ok {
+			typ = param
 		}
+
+		data.ExtraArgs = append(data.ExtraArgs, extraArg{
+			Name: req.Name,
+			Type: typ,
+		})
 	}
 
 	// Collect imports
 	imports := make(map[string]importSpec)
 	g.addImport(imports, pair.SourceType.ID.PkgPath)
+
+	if pair.NeedsContext {
+		data.UsesContext = true
+		imports["context"] = importSpec{Path: "context"}
+	}
 	// Don't add import for generated target types
 	if !pair.IsGeneratedTarget {
 		g.addImport(imports, pair.TargetType.ID.PkgPath)
 	}
 
+	// Instantiated generic types are spelled with their type arguments.
+	data.SourceType.Name += g.typeArgsString(pair.SourceType, imports)
+	data.TargetType.Name += g.typeArgsString(pair.TargetType, imports)
+
 	// Generate struct definition if needed
 	g.processStructDefinition(data, pair, imports)
 
+	g.casterKey = fmt.Sprintf("%s->%s", pair.SourceType.ID, pair.TargetType.ID)
+	if limit := g.depthLimit(g.casterKey); limit > 0 {
+		data.DepthLimit = limit
+		data.DepthFunctionName = depthFunctionName(g.functionName(pair))
+
+		data.DepthCall = data.DepthFunctionName
+		if g.methods() {
+			data.DepthCall = methodsReceiver + "." + data.DepthFunctionName
+		}
+	}
+
+	if pair.Via != nil {
+		data.ViaCall = g.nestedCall(pair.Via, pair.TargetType, g.nestedCall(pair.SourceType, pair.Via, "in"))
+	}
+
+	if pair.Before != "" {
+		data.BeforeHook = g.hookCall(pair.Before, imports)
+	}
+
+	if pair.After != "" {
+		data.AfterHook = g.hookCall(pair.After, imports)
+	}
+
 	// Process mappings
 	for _, m := range pair.Mappings {
+		g.recursed = false
+
 		assignment := g.buildAssignment(&m, pair, imports)
+		if assignment != nil && g.recursed {
+			assignment.DepthGuard = fmt.Sprintf("depth < %d", data.DepthLimit)
+		}
+
 		if assignment != nil {
 			data.Assignments = append(data.Assignments, *assignment)
 		}
+
+		if m.PendingSource != "" {
+			data.UnmappedTODOs = append(data.UnmappedTODOs,
+				fmt.Sprintf("TODO: %s - assign from %s once it exists", g.targetFieldExpr(m.TargetPaths), m.PendingSource))
+		}
 	}
 
 	// Reorder assignments based on implicit dependencies (e.g., extra.def.target).
 	g.orderAssignmentsByDependencies(data, pair)
 
+	if needsMerge(pair) {
+		data.MergeFunctionName = g.mergeFunctionName(pair)
+	}
+
 	// Add TODO comments for unmapped fields
 	if g.config.IncludeUnmappedTODOs {
 		for _, unmapped := range pair.UnmappedTargets {
@@ -134,6 +252,16 @@ func (g *Generator) buildTemplateData(pair *plan.ResolvedTypePair) *templateData
 	// Identify missing transforms
 	g.identifyMissingTransforms(pair)
 
+	if g.methods() {
+		g.recordCasterMethod(data, pair)
+	}
+
+	if g.config.Benchmarks || g.config.Tests {
+		g.recordFixture(data, pair)
+	}
+
+	maps.Copy(imports, g.foreignImports)
+
 	// Convert imports map to sorted slice
 	for _, imp := range imports {
 		data.Imports = append(data.Imports, imp)
@@ -171,9 +299,16 @@ func (g *Generator) processStructDefinition(
 		g.contextPkgPath = ""
 	}
 
-	// Use a temporary map to capture imports for the struct
+	// Use a temporary map to capture imports for the struct. Targets generated
+	// from several sources are declared by the caster of the first one.
 	structImports := make(map[string]importSpec)
+	declared := g.declaredTargets[pair.TargetType.ID]
+
 	if structDef, err := g.GenerateStruct(pair, structImports); err == nil {
+		if structDef != "" {
+			g.declaredTargets[pair.TargetType.ID] = true
+		}
+
 		if moveStruct {
 			// Add import to caster file manually (since we removed struct def from here)
 			g.addImport(imports, targetPkgPath)
@@ -189,10 +324,12 @@ func (g *Generator) processStructDefinition(
 			}
 
 			// Store struct def for later generation in the target package
-			dir := g.graph.Packages[targetPkgPath].Dir
-			pkgName := g.graph.Packages[targetPkgPath].Name
-			g.addMissingType(dir, pkgName, structDef, importedSpecs)
-		} else {
+			if !declared {
+				dir := g.graph.Packages[targetPkgPath].Dir
+				pkgName := g.graph.Packages[targetPkgPath].Name
+				g.addMissingType(dir, pkgName, structDef, importedSpecs)
+			}
+		} else if !declared {
 			// Merge structImports into imports for the current file
 			maps.Copy(imports, structImports)
 
@@ -215,6 +352,8 @@ func (g *Generator) buildAssignment(
 		return nil
 	}
 
+	g.idents = g.assignmentScope(pair)
+
 	targetField := g.targetFieldExpr(m.TargetPaths)
 	sourceExpr := g.sourceFieldExpr(m.SourcePaths, m, pair)
 
@@ -227,14 +366,38 @@ func (g *Generator) buildAssignment(
 		TargetField: targetField,
 		SourceExpr:  sourceExpr,
 		Comment:     comment,
+		Deprecated:  deprecationNote(m),
 		Strategy:    m.Strategy,
+		SourceGuard: g.sourceGuard(m, pair),
+		When:        m.When,
+		ElseDefault: m.ElseDefault,
+	}
+
+	// Properties exposed by methods are computed into a local variable, then set.
+	if setter, field := g.targetSetter(pair.TargetType, m.TargetPaths); setter != "" {
+		assignment.TargetField = g.local("value")
+		assignment.Setter = setter
+		assignment.SetterType = g.typeRefString(field.Type, imports)
 	}
 
+	g.applyCopyMode(assignment, m, pair)
 	g.applyConversionStrategy(assignment, m, pair, imports)
+	g.applyNilToEmpty(assignment, m, pair, imports)
+	g.applyMergePolicy(assignment, m, pair, imports)
 
 	return assignment
 }
 
+// deprecationNote returns the deprecation comment of a field mapping, including
+// its sunset date when declared. Deprecations are emitted regardless of GenerateComments.
+func deprecationNote(m *plan.ResolvedFieldMapping) string {
+	if m.Deprecated == "" || m.Sunset == "" {
+		return m.Deprecated
+	}
+
+	return m.Deprecated + " (sunset " + m.Sunset + ")"
+}
+
 // collectNestedCasters adds nested caster references to the template data.
 func (g *Generator) collectNestedCasters(
 	data *templateData,
@@ -242,20 +405,28 @@ func (g *Generator) collectNestedCasters(
 	imports map[string]importSpec,
 ) {
 	for _, nested := range pair.NestedPairs {
+		// Conversions of anonymous structs are inlined, calling no caster
+		if nested.Inline() {
+			continue
+		}
+
 		nestedRef := nestedCasterRef{
 			FunctionName: g.nestedFunctionName(nested.SourceType, nested.TargetType),
 			SourceType: typeRef{
 				Package: g.getPkgName(nested.SourceType.ID.PkgPath),
-				Name:    nested.SourceType.ID.Name,
+				Name:    nested.SourceType.ID.Name + g.typeArgsString(nested.SourceType, imports),
 			},
 			TargetType: typeRef{
 				Package: g.getPkgName(nested.TargetType.ID.PkgPath),
-				Name:    nested.TargetType.ID.Name,
+				Name:    nested.TargetType.ID.Name + g.typeArgsString(nested.TargetType, imports),
 			},
 		}
-		// Add imports for nested types
-		g.addImport(imports, nested.SourceType.ID.PkgPath)
-		g.addImport(imports, nested.TargetType.ID.PkgPath)
+		// Add imports for nested types; a via pair's caster doesn't spell its
+		// intermediate type
+		if pair.Via == nil {
+			g.addImport(imports, nested.SourceType.ID.PkgPath)
+			g.addImport(imports, nested.TargetType.ID.PkgPath)
+		}
 
 		data.NestedCasters = append(data.NestedCasters, nestedRef)
 	}
@@ -279,8 +450,9 @@ func (g *Generator) orderAssignmentsByDependencies(data *templateData, pair *pla
 	// Build index by exact target field expr, using the assignment list.
 	byTarget := make(map[string]int, n)
 	for i := range n {
+		// Properties assigned by setters can't be read back as out fields.
 		t := data.Assignments[i].TargetField
-		if t != "" {
+		if t != "" && data.Assignments[i].Setter == "" {
 			byTarget[t] = i
 		}
 	}
@@ -337,6 +509,50 @@ func (g *Generator) targetFieldExpr(paths []mapping.FieldPath) string {
 	return "out." + paths[0].String()
 }
 
+// targetSetter returns the setter call assigning the target path (e.g.,
+// "out.Address.SetStreet") and the property it sets, or "" if the path
+// ends with a plain field.
+func (g *Generator) targetSetter(targetType *analyze.TypeInfo, paths []mapping.FieldPath) (string, *analyze.FieldInfo) {
+	if len(paths) == 0 || len(paths[0].Segments) == 0 {
+		return "", nil
+	}
+
+	segments := paths[0].Segments
+	current := targetType
+
+	var field *analyze.FieldInfo
+
+	for i, seg := range segments {
+		if current == nil || seg.IsSlice {
+			return "", nil
+		}
+
+		if current.Kind == analyze.TypeKindPointer {
+			current = current.ElemType
+		}
+
+		field = current.FieldByName(seg.Name)
+		if field == nil {
+			return "", nil
+		}
+
+		if i < len(segments)-1 {
+			current = field.Type
+		}
+	}
+
+	if field.Setter == "" {
+		return "", nil
+	}
+
+	prefix := mapping.FieldPath{Segments: segments[:len(segments)-1]}.String()
+	if prefix != "" {
+		prefix += "."
+	}
+
+	return "out." + prefix + field.Setter, field
+}
+
 // sourceFieldExpr builds the source field expression.
 func (g *Generator) sourceFieldExpr(
 	paths []mapping.FieldPath,
@@ -359,11 +575,129 @@ func (g *Generator) sourceFieldExpr(
 		}
 	}
 
-	return "in." + paths[0].String()
+	return g.sourcePathExpr(pair.SourceType, paths[0])
+}
+
+// sourcePathExpr returns the expression reading path from in. Fields with a
+// proto getter (see analyze.FieldInfo.Getter) are read through it, which is
+// nil-safe and the only way to reach oneof members.
+func (g *Generator) sourcePathExpr(sourceType *analyze.TypeInfo, path mapping.FieldPath) string {
+	expr, _ := g.sourcePathAccess(sourceType, path)
+
+	return expr
 }
 
-// buildTransformArgs builds the argument list for a transform function call.
-func (g *Generator) buildTransformArgs(paths []mapping.FieldPath, pair *plan.ResolvedTypePair) string {
+// sourcePathAccess returns the expression reading path from in, as
+// sourcePathExpr, and the conditions under which reading it doesn't panic:
+// the elements it accesses by index are within the bounds of their slice
+// (e.g., "len(in.Items) > 0"), and the pointer elements it accesses a field
+// of aren't nil.
+func (g *Generator) sourcePathAccess(sourceType *analyze.TypeInfo, path mapping.FieldPath) (string, []string) {
+	var (
+		sb     strings.Builder
+		guards []string
+	)
+
+	sb.WriteString("in")
+
+	current := sourceType
+
+	for i, seg := range path.Segments {
+		var field *analyze.FieldInfo
+
+		if current != nil {
+			if current.Kind == analyze.TypeKindPointer {
+				current = current.ElemType
+			}
+
+			field = current.FieldByName(seg.Name)
+		}
+
+		sb.WriteString(".")
+
+		if field != nil && field.Getter != "" {
+			sb.WriteString(field.Getter + "()")
+		} else {
+			sb.WriteString(seg.Name)
+		}
+
+		current = nil
+
+		if seg.IsSlice {
+			sb.WriteString("[]")
+		} else if field != nil {
+			current = field.Type
+		}
+
+		if seg.Key == "" {
+			continue
+		}
+
+		// Arrays are indexed within their length, which Validate checks
+		container := current
+		for container != nil && container.Kind == analyze.TypeKindAlias && container.Underlying != nil {
+			container = container.Underlying
+		}
+
+		if container != nil && container.Kind == analyze.TypeKindSlice {
+			guards = append(guards, fmt.Sprintf("len(%s) > %s", sb.String(), seg.Key))
+		}
+
+		sb.WriteString("[" + seg.Key + "]")
+
+		if current != nil {
+			current, _ = mapping.KeyedElem(current, seg.Key)
+		}
+
+		if current != nil && current.Kind == analyze.TypeKindPointer && i < len(path.Segments)-1 {
+			guards = append(guards, sb.String()+" != nil")
+		}
+	}
+
+	return sb.String(), guards
+}
+
+// sourceGuard returns the condition under which the source paths of m can be
+// read (see sourcePathAccess), or "" if they always can.
+func (g *Generator) sourceGuard(m *plan.ResolvedFieldMapping, pair *plan.ResolvedTypePair) string {
+	var guards []string
+
+	for _, p := range m.SourcePaths {
+		if !p.IsKeyed() || isRequiresArg(pair, p.Root()) {
+			continue
+		}
+
+		_, pathGuards := g.sourcePathAccess(pair.SourceType, p)
+		for _, guard := range pathGuards {
+			if !slices.Contains(guards, guard) {
+				guards = append(guards, guard)
+			}
+		}
+	}
+
+	return strings.Join(guards, " && ")
+}
+
+// hookCall returns the function called by hook name, qualified with the
+// import of its package when it resolves to a loaded function. Other names
+// refer to functions of the casters package and are kept as written.
+func (g *Generator) hookCall(name string, imports map[string]importSpec) string {
+	fn := mapping.ResolveFunc(name, g.graph)
+	if fn == nil {
+		return name
+	}
+
+	if fn.ID.PkgPath == g.contextPkgPath {
+		return fn.ID.Name
+	}
+
+	g.addImport(imports, fn.ID.PkgPath)
+
+	return g.getPkgName(fn.ID.PkgPath) + "." + fn.ID.Name
+}
+
+// buildTransformArgs builds the arguments of a transform function call.
+func (g *Generator) buildTransformArgs(paths []mapping.FieldPath, pair *plan.ResolvedTypePair) []string {
 	args := make([]string, 0, len(paths))
 
 	for _, p := range paths {
@@ -383,11 +717,11 @@ func (g *Generator) buildTransformArgs(paths []mapping.FieldPath, pair *plan.Res
 		if isReq {
 			args = append(args, p.String())
 		} else {
-			args = append(args, "in."+p.String())
+			args = append(args, g.sourcePathExpr(pair.SourceType, p))
 		}
 	}
 
-	return strings.Join(args, ", ")
+	return args
 }
 
 // getRequiredArgType returns the TypeInfo for a required argument by name, or nil if not found.
@@ -426,6 +760,11 @@ func (g *Generator) identifyMissingTransforms(
 			continue
 		}
 
+		// Template transforms are generated inline
+		if _, ok := g.templateTransforms[m.Transform]; ok {
+			continue
+		}
+
 		if !seen[m.Transform] {
 			// Check if we already have this transform in the global map
 			if _, exists := g.missingTransforms[m.Transform]; exists {
@@ -433,65 +772,92 @@ func (g *Generator) identifyMissingTransforms(
 				continue
 			}
 
-			// Determine argument types
-			var argInfos []*analyze.TypeInfo
+			argInfos, returnInfo := g.transformSignature(&m, pair)
 
-			for _, sp := range m.SourcePaths {
-				// First check if this source path refers to a required argument
-				var info *analyze.TypeInfo
-				if len(sp.Segments) > 0 {
-					info = g.getRequiredArgType(pair, sp.Segments[0].Name)
-				}
+			g.missingTransforms[m.Transform] = MissingTransformInfo{
+				Name:       m.Transform,
+				Args:       argInfos,
+				ReturnType: returnInfo,
+			}
+			seen[m.Transform] = true
+		}
+	}
+}
 
-				// If not a required arg, look up from source type
-				if info == nil {
-					info = g.getFieldTypeInfo(pair.SourceType, sp.String())
-				}
+// constArgType returns the basic type a const extra is passed to a transform
+// stub with, or nil if it has none.
+func constArgType(c mapping.ExtraConst) *analyze.TypeInfo {
+	name := c.DefaultType()
 
-				argInfos = append(argInfos, info)
-			}
+	obj := types.Universe.Lookup(name)
+	if obj == nil {
+		return nil
+	}
 
-			// Also add 'extra' types if any
-			for _, exp := range m.Extra {
-				var info *analyze.TypeInfo
+	return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic, GoType: obj.Type()}
+}
 
-				// First check if the extra matches a required argument
-				info = g.getRequiredArgType(pair, exp.Name)
-				if info != nil {
-					argInfos = append(argInfos, info)
-					continue
-				}
+// transformSignature determines the argument and return types a transform is called with
+// for mapping m: source paths first, then extras, returning the first target field's type.
+// Types that can't be determined are nil.
+func (g *Generator) transformSignature(
+	m *plan.ResolvedFieldMapping,
+	pair *plan.ResolvedTypePair,
+) ([]*analyze.TypeInfo, *analyze.TypeInfo) {
+	var argInfos []*analyze.TypeInfo
+
+	for _, sp := range m.SourcePaths {
+		// First check if this source path refers to a required argument
+		var info *analyze.TypeInfo
+		if len(sp.Segments) > 0 {
+			info = g.getRequiredArgType(pair, sp.Segments[0].Name)
+		}
 
-				switch {
-				case exp.Def.Source != "":
-					// Check if source refers to a required arg
-					info = g.getRequiredArgType(pair, exp.Def.Source)
-					if info == nil {
-						info = g.getFieldTypeInfo(pair.SourceType, exp.Def.Source)
-					}
-				case exp.Def.Target != "":
-					// Reference to target type field
-					info = g.getFieldTypeInfo(pair.TargetType, exp.Def.Target)
-				default:
-					// Fallback - check if name matches a required arg
-					info = g.getRequiredArgType(pair, exp.Name)
-				}
+		// If not a required arg, look up from source type
+		if info == nil {
+			info = g.getFieldTypeInfo(pair.SourceType, sp.String())
+		}
 
-				argInfos = append(argInfos, info)
-			}
+		argInfos = append(argInfos, info)
+	}
 
-			// Determine return type
-			var returnInfo *analyze.TypeInfo
-			if len(m.TargetPaths) > 0 {
-				returnInfo = g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
-			}
+	// Also add 'extra' types if any
+	for _, exp := range m.Extra {
+		var info *analyze.TypeInfo
 
-			g.missingTransforms[m.Transform] = MissingTransformInfo{
-				Name:       m.Transform,
-				Args:       argInfos,
-				ReturnType: returnInfo,
+		// First check if the extra matches a required argument
+		info = g.getRequiredArgType(pair, exp.Name)
+		if info != nil {
+			argInfos = append(argInfos, info)
+			continue
+		}
+
+		switch {
+		case exp.Const != "":
+			// Untyped constant, passed with its default type
+			info = constArgType(exp.Const)
+		case exp.Def.Source != "":
+			// Check if source refers to a required arg
+			info = g.getRequiredArgType(pair, exp.Def.Source)
+			if info == nil {
+				info = g.getFieldTypeInfo(pair.SourceType, exp.Def.Source)
 			}
-			seen[m.Transform] = true
+		case exp.Def.Target != "":
+			// Reference to target type field
+			info = g.getFieldTypeInfo(pair.TargetType, exp.Def.Target)
+		default:
+			// Fallback - check if name matches a required arg
+			info = g.getRequiredArgType(pair, exp.Name)
 		}
+
+		argInfos = append(argInfos, info)
+	}
+
+	// Determine return type
+	var returnInfo *analyze.TypeInfo
+	if len(m.TargetPaths) > 0 {
+		returnInfo = g.getFieldTypeInfo(pair.TargetType, m.TargetPaths[0].String())
 	}
+
+	return argInfos, returnInfo
 }
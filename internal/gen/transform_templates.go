@@ -0,0 +1,68 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"caster-generator/internal/mapping"
+)
+
+// templateTransform is a transform declared with a template, generated inline
+// instead of called.
+type templateTransform struct {
+	tmpl    *template.Template
+	imports []string
+}
+
+// loadTemplateTransforms parses the templates of the template transforms in defs.
+func loadTemplateTransforms(defs []mapping.TransformDef) (map[string]templateTransform, error) {
+	transforms := make(map[string]templateTransform)
+
+	for i := range defs {
+		def := &defs[i]
+		if def.Template == "" {
+			continue
+		}
+
+		tmpl, err := def.ParseTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("transform %q: %w", def.Name, err)
+		}
+
+		transforms[def.Name] = templateTransform{tmpl: tmpl, imports: def.Imports}
+	}
+
+	return transforms, nil
+}
+
+// templateTransformExpr returns the expression of the template transform name
+// applied to args, adding its imports, or false if name isn't one. Ctx template
+// transforms refer to the caster's ctx themselves.
+func (g *Generator) templateTransformExpr(
+	name string,
+	args []string,
+	imports map[string]importSpec,
+) (string, bool) {
+	tt, ok := g.templateTransforms[name]
+	if !ok {
+		return "", false
+	}
+
+	data := mapping.TransformTemplateData{Args: args}
+	if len(args) > 0 {
+		data.Source = args[0]
+	}
+
+	var buf bytes.Buffer
+	if err := tt.tmpl.Execute(&buf, data); err != nil {
+		g.templateErr = fmt.Errorf("transform %q: %w", name, err)
+		return "", false
+	}
+
+	for _, path := range tt.imports {
+		imports[path] = importSpec{Path: path}
+	}
+
+	return buf.String(), true
+}
@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"fmt"
+	"go/types"
 	"slices"
 	"strings"
 
@@ -52,25 +54,24 @@ type importSpec struct {
 	Path  string
 }
 
-// getPkgName returns the package name for a given package path.
-// It tries to look up the name from the type graph, falling back to the path base alias.
+// getPkgName returns the name generated code refers to a package by: its
+// alias if its name collides with another package's (see pkgAliases), else
+// its name from the type graph, falling back to the path base.
 func (g *Generator) getPkgName(pkgPath string) string {
 	if pkgPath == "" {
 		return ""
 	}
 
-	if g.graph != nil {
-		if pkgInfo, ok := g.graph.Packages[pkgPath]; ok {
-			return pkgInfo.Name
-		}
+	if alias, ok := g.aliases[pkgPath]; ok {
+		return alias
 	}
 
-	return common.PkgAlias(pkgPath)
+	return g.pkgName(pkgPath)
 }
 
 // addImport adds an import to the imports map.
 func (g *Generator) addImport(imports map[string]importSpec, pkgPath string) {
-	if pkgPath == "" {
+	if pkgPath == "" || imports == nil {
 		return
 	}
 
@@ -120,26 +121,33 @@ func (g *Generator) typeRefString(t *analyze.TypeInfo, imports map[string]import
 		return "map[" + key + "]" + val
 
 	case analyze.TypeKindArray:
-		// Keep length information by using go/types' string.
-		// This avoids having to store the array length explicitly in TypeInfo.
+		// The length isn't stored in TypeInfo, so take it from go/types.
+		if arr, ok := t.GoType.(*types.Array); ok && t.ElemType != nil {
+			return fmt.Sprintf("[%d]%s", arr.Len(), g.typeRefString(t.ElemType, imports))
+		}
+
 		return t.GoType.String()
 
 	case analyze.TypeKindStruct, analyze.TypeKindExternal, analyze.TypeKindAlias:
+		if t.IsAnonymousStruct() {
+			return g.structLiteral(t, imports)
+		}
+
 		// If the type has a package path, use it for import and qualification.
 		// Even if IsGenerated is true, if PkgPath is set, we treat it as a cross-package reference
 		// unless we are generating into that same package.
 		if t.ID.PkgPath != "" {
 			// If we are generating code IN the same package as the type, omit prefix/import.
 			if t.ID.PkgPath == g.contextPkgPath {
-				return t.ID.Name
+				return t.ID.Name + g.typeArgsString(t, imports)
 			}
 
 			g.addImport(imports, t.ID.PkgPath)
 
-			return g.getPkgName(t.ID.PkgPath) + "." + t.ID.Name
+			return g.getPkgName(t.ID.PkgPath) + "." + t.ID.Name + g.typeArgsString(t, imports)
 		}
 
-		return t.ID.Name
+		return t.ID.Name + g.typeArgsString(t, imports)
 
 	default:
 		return common.InterfaceTypeStr
@@ -168,9 +176,29 @@ func (g *Generator) wrapConversion(
 ) string {
 	typeStr := g.typeRefString(targetType, imports)
 
+	// *T(x) would dereference T(x); pointer, channel and func types need parentheses.
+	if strings.HasPrefix(typeStr, "*") || strings.HasPrefix(typeStr, "<-") || strings.HasPrefix(typeStr, "func") {
+		typeStr = "(" + typeStr + ")"
+	}
+
 	return typeStr + "(" + expr + ")"
 }
 
+// convertValue converts expr from type from to type to when the types differ
+// but Go can convert between them (e.g., identical structs of two packages).
+func (g *Generator) convertValue(
+	expr string,
+	from, to *analyze.TypeInfo,
+	imports map[string]importSpec,
+) string {
+	if from == nil || to == nil || from.GoType == nil || to.GoType == nil ||
+		types.Identical(from.GoType, to.GoType) || !types.ConvertibleTo(from.GoType, to.GoType) {
+		return expr
+	}
+
+	return g.wrapConversion(expr, to, imports)
+}
+
 // zeroValue returns the zero value for a target type.
 func (g *Generator) zeroValue(typeInfo *analyze.TypeInfo, paths []mapping.FieldPath) string {
 	if len(paths) == 0 {
@@ -334,13 +362,14 @@ func (g *Generator) getFieldTypeInfo(typeInfo *analyze.TypeInfo, fieldPath strin
 		return nil
 	}
 
-	parts := strings.Split(fieldPath, ".")
-	current := typeInfo
+	fp, err := mapping.ParsePath(fieldPath)
+	if err != nil {
+		return nil
+	}
 
-	for _, part := range parts {
-		// Remove slice notation if present
-		part = strings.TrimSuffix(part, "[]")
+	current := typeInfo
 
+	for _, seg := range fp.Segments {
 		if current.Kind == analyze.TypeKindPointer && current.ElemType != nil {
 			current = current.ElemType
 		}
@@ -349,21 +378,42 @@ func (g *Generator) getFieldTypeInfo(typeInfo *analyze.TypeInfo, fieldPath strin
 			return nil
 		}
 
-		current = g.findFieldInStruct(current, part)
+		current = g.findFieldInStruct(current, seg.Name)
 		if current == nil {
 			return nil
 		}
+
+		// Elements accessed by index or key have the element type
+		if seg.Key != "" {
+			if current, _ = mapping.KeyedElem(current, seg.Key); current == nil {
+				return nil
+			}
+		}
 	}
 
 	return current
 }
 
-// findFieldInStruct finds a field by name in a struct type.
+// typeArgsString returns the type argument list of an instantiated generic type
+// (e.g., "[string]"), or an empty string for non-generic types.
+func (g *Generator) typeArgsString(t *analyze.TypeInfo, imports map[string]importSpec) string {
+	if len(t.TypeArgs) == 0 {
+		return ""
+	}
+
+	args := make([]string, len(t.TypeArgs))
+	for i, arg := range t.TypeArgs {
+		args[i] = g.typeRefString(arg, imports)
+	}
+
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// findFieldInStruct finds a field by name in a struct type, including fields
+// promoted from embedded structs.
 func (g *Generator) findFieldInStruct(structType *analyze.TypeInfo, fieldName string) *analyze.TypeInfo {
-	for _, field := range structType.Fields {
-		if field.Name == fieldName {
-			return field.Type
-		}
+	if field := structType.FieldByName(fieldName); field != nil {
+		return field.Type
 	}
 
 	return nil
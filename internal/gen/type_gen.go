@@ -25,9 +25,12 @@ func (g *Generator) GenerateStruct(pair *plan.ResolvedTypePair, imports map[stri
 	sb.WriteString(fmt.Sprintf("type %s struct {\n", t.ID.Name))
 
 	for _, f := range t.Fields {
+		if f.Doc != "" {
+			sb.WriteString("\t// " + f.Doc + "\n")
+		}
+
 		typeStr := g.typeStringForStruct(f.Type, imports)
-		jsonTag := lowerFirst(f.Name)
-		sb.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\"`\n", f.Name, typeStr, jsonTag))
+		sb.WriteString(fmt.Sprintf("\t%s %s `%s`\n", f.Name, typeStr, structTag(&f)))
 	}
 
 	sb.WriteString("}\n")
@@ -48,6 +51,21 @@ func (g *Generator) typeStringForStruct(t *analyze.TypeInfo, imports map[string]
 	return g.typeRefString(t, imports)
 }
 
+// structTag returns the struct tag of a generated field: its own tags, after
+// a json tag naming it in lowerCamelCase unless it has one.
+func structTag(f *analyze.FieldInfo) string {
+	if _, ok := f.Tag.Lookup("json"); ok {
+		return string(f.Tag)
+	}
+
+	tag := fmt.Sprintf("json:%q", lowerFirst(f.Name))
+	if f.Tag != "" {
+		tag += " " + string(f.Tag)
+	}
+
+	return tag
+}
+
 func lowerFirst(s string) string {
 	if s == "" {
 		return ""
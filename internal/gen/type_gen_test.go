@@ -44,6 +44,53 @@ func TestGenerator_GenerateStruct_Basic(t *testing.T) {
 	assert.True(t, strings.HasSuffix(strings.TrimSpace(result), "}"))
 }
 
+func TestGenerator_GenerateStruct_FieldDocs(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	pair := &plan.ResolvedTypePair{
+		TargetType: &analyze.TypeInfo{
+			ID:          analyze.TypeID{Name: "UserView"},
+			Kind:        analyze.TypeKindStruct,
+			IsGenerated: true,
+			Fields: []analyze.FieldInfo{
+				{Name: "Name", Exported: true, Type: str, Doc: "Name is set from store.User.Name."},
+				{Name: "Note", Exported: true, Type: str, Index: 1},
+			},
+		},
+		IsGeneratedTarget: true,
+	}
+
+	result, err := NewGenerator(DefaultGeneratorConfig()).GenerateStruct(pair, make(map[string]importSpec))
+	require.NoError(t, err)
+	assert.Equal(t, "type UserView struct {\n"+
+		"\t// Name is set from store.User.Name.\n"+
+		"\tName string `json:\"name\"`\n"+
+		"\tNote string `json:\"note\"`\n"+
+		"}\n", result)
+}
+
+func TestGenerator_GenerateStruct_FieldTags(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	pair := &plan.ResolvedTypePair{
+		TargetType: &analyze.TypeInfo{
+			ID:          analyze.TypeID{Name: "OrderView"},
+			Kind:        analyze.TypeKindStruct,
+			IsGenerated: true,
+			Fields: []analyze.FieldInfo{
+				{Name: "OrderID", Exported: true, Type: str, Tag: `json:"order_id,omitempty" db:"order_id"`},
+				{Name: "State", Exported: true, Type: str, Tag: `db:"state"`, Index: 1},
+			},
+		},
+		IsGeneratedTarget: true,
+	}
+
+	result, err := NewGenerator(DefaultGeneratorConfig()).GenerateStruct(pair, make(map[string]importSpec))
+	require.NoError(t, err)
+	assert.Equal(t, "type OrderView struct {\n"+
+		"\tOrderID string `json:\"order_id,omitempty\" db:\"order_id\"`\n"+
+		"\tState string `json:\"state\" db:\"state\"`\n"+
+		"}\n", result)
+}
+
 func TestGenerator_GenerateStruct_WithPointerField(t *testing.T) {
 	// Setup a target type with pointer fields
 	targetType := &analyze.TypeInfo{
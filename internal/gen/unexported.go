@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/plan"
+)
+
+// outputPackage returns the import path of the analyzed package whose
+// directory is the output directory, "" if there is none. Casters generated
+// into it refer to its types unqualified, so they may access their unexported
+// fields.
+func (g *Generator) outputPackage() (string, error) {
+	if g.config.OutputDir == "" || g.graph == nil {
+		return "", nil
+	}
+
+	outDir, err := filepath.Abs(g.config.OutputDir)
+	if err != nil {
+		return "", err
+	}
+
+	for path, pkg := range g.graph.Packages {
+		if pkg.Dir != outDir {
+			continue
+		}
+
+		if pkg.Name != g.config.PackageName {
+			return "", fmt.Errorf("output directory %s holds package %s, not %s", g.config.OutputDir, pkg.Name,
+				g.config.PackageName)
+		}
+
+		return path, nil
+	}
+
+	return "", nil
+}
+
+// checkUnexported reports the unexported fields mapped by pairs allowing them
+// that their caster can't access, being generated outside their package.
+func (g *Generator) checkUnexported(p *plan.ResolvedMappingPlan) error {
+	for i := range p.TypePairs {
+		pair := &p.TypePairs[i]
+		if !pair.AllowUnexported {
+			continue
+		}
+
+		for _, m := range pair.Mappings {
+			if m.Strategy == plan.StrategyIgnore {
+				continue
+			}
+
+			for _, fp := range m.SourcePaths {
+				if err := g.checkAccessible(pair, pair.SourceType, fp); err != nil {
+					return err
+				}
+			}
+
+			for _, fp := range m.TargetPaths {
+				if err := g.checkAccessible(pair, pair.TargetType, fp); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkAccessible reports the first unexported field along the path fp of
+// type t declared outside the output package.
+func (g *Generator) checkAccessible(pair *plan.ResolvedTypePair, t *analyze.TypeInfo, fp mapping.FieldPath) error {
+	current := t
+
+	for _, seg := range fp.Segments {
+		for current != nil && current.Kind == analyze.TypeKindPointer {
+			current = current.ElemType
+		}
+
+		// Nothing is known past an unresolved or nil element type.
+		if current == nil {
+			return nil
+		}
+
+		// Paths naming requires arguments don't start with a field.
+		f := current.FieldByName(seg.Name)
+		if f == nil {
+			return nil
+		}
+
+		if !f.Exported && current.ID.PkgPath != g.outputPkgPath {
+			return fmt.Errorf("%s->%s: unexported field %s.%s is only accessible to casters generated into %s",
+				pair.SourceType.ID, pair.TargetType.ID, current.ID, seg.Name, current.ID.PkgPath)
+		}
+
+		current = f.Type
+
+		if seg.IsSlice {
+			for current != nil && current.Kind == analyze.TypeKindPointer {
+				current = current.ElemType
+			}
+
+			if current != nil {
+				current = current.ElemType
+			}
+		} else if seg.Key != "" {
+			current, _ = mapping.KeyedElem(current, seg.Key)
+		}
+	}
+
+	return nil
+}
+
+// unqualifyOutputPackage drops the import of the output package from the
+// generated files of the output directory, referring to its declarations
+// unqualified.
+func (g *Generator) unqualifyOutputPackage(files []GeneratedFile) error {
+	for i := range files {
+		if filepath.Dir(files[i].Filename) != "." {
+			continue
+		}
+
+		content, err := unqualify(files[i].Filename, files[i].Content, g.outputPkgPath)
+		if err != nil {
+			return err
+		}
+
+		files[i].Content = content
+	}
+
+	return nil
+}
+
+// unqualify removes the import of pkgPath from the Go source src, replacing
+// the identifiers qualified by it with bare ones.
+func unqualify(filename string, src []byte, pkgPath string) ([]byte, error) {
+	if !bytes.Contains(src, []byte(strconv.Quote(pkgPath))) {
+		return src, nil
+	}
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+
+	var spec importSpec
+
+	for _, imp := range file.Imports {
+		if s := importSpecOf(imp); s.Path == pkgPath {
+			spec = s
+		}
+	}
+
+	if spec.Alias == "" || spec.Alias == "_" || spec.Alias == "." {
+		return src, nil
+	}
+
+	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+		sel, ok := c.Node().(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if x, ok := sel.X.(*ast.Ident); ok && x.Name == spec.Alias {
+			c.Replace(ast.NewIdent(sel.Sel.Name))
+		}
+
+		return true
+	})
+
+	astutil.DeleteNamedImport(fset, file, spec.Alias, spec.Path)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("formatting %s: %w", filename, err)
+	}
+
+	return buf.Bytes(), nil
+}
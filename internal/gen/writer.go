@@ -1,9 +1,16 @@
 package gen
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // File permission constants.
@@ -12,17 +19,40 @@ const (
 	filePerm = 0o644
 )
 
+// OutputBackend receives the files of a generation run. File names are
+// relative to the output directory, and may leave it (missing types files
+// are written next to the types they complete).
+type OutputBackend interface {
+	Write(files []GeneratedFile) error
+}
+
 // WriteFiles writes all generated files to the output directory.
 // It creates the directory if it doesn't exist.
 func WriteFiles(files []GeneratedFile, outputDir string) error {
+	return (&DirBackend{Dir: outputDir}).Write(files)
+}
+
+// DirBackend writes generated files to an output directory, replacing the
+// files of an earlier run.
+type DirBackend struct {
+	Dir string
+}
+
+// Write creates the output directory if it doesn't exist, then writes files.
+func (b *DirBackend) Write(files []GeneratedFile) error {
 	// Create output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, dirPerm)
+	err := os.MkdirAll(b.Dir, dirPerm)
 	if err != nil {
 		return fmt.Errorf("creating output directory: %w", err)
 	}
 
 	for _, file := range files {
-		outputPath := filepath.Join(outputDir, file.Filename)
+		outputPath := filepath.Join(b.Dir, file.Filename)
+
+		// Files of other output packages live in directories of their own
+		if err := os.MkdirAll(filepath.Dir(outputPath), dirPerm); err != nil {
+			return fmt.Errorf("creating directory of %s: %w", file.Filename, err)
+		}
 
 		err := os.WriteFile(outputPath, file.Content, filePerm)
 		if err != nil {
@@ -32,3 +62,119 @@ func WriteFiles(files []GeneratedFile, outputDir string) error {
 
 	return nil
 }
+
+// MemoryBackend keeps generated files in memory, by file name.
+type MemoryBackend struct {
+	Files map[string][]byte
+}
+
+// Write adds files to b.Files, replacing those of the same name.
+func (b *MemoryBackend) Write(files []GeneratedFile) error {
+	if b.Files == nil {
+		b.Files = make(map[string][]byte, len(files))
+	}
+
+	for _, file := range files {
+		b.Files[file.Filename] = bytes.Clone(file.Content)
+	}
+
+	return nil
+}
+
+// zipModTime is the earliest time zip archives can hold.
+var zipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ZipBackend writes generated files to a zip archive, named relative to the
+// output directory. Entries share a fixed modification time, so archives of
+// the same files are byte-identical.
+type ZipBackend struct {
+	W io.Writer
+}
+
+// Write writes one archive holding files. Files outside the output directory,
+// such as the missing types files of other packages, have no name in the
+// archive and fail the write before anything is written.
+func (b *ZipBackend) Write(files []GeneratedFile) error {
+	for _, file := range files {
+		if !filepath.IsLocal(file.Filename) {
+			return fmt.Errorf("archiving file %s: outside the output directory", file.Filename)
+		}
+	}
+
+	zw := zip.NewWriter(b.W)
+
+	for _, file := range files {
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(filepath.Clean(file.Filename)),
+			Method:   zip.Deflate,
+			Modified: zipModTime,
+		}
+		header.SetMode(filePerm)
+
+		w, err := zw.CreateHeader(header)
+		if err == nil {
+			_, err = w.Write(file.Content)
+		}
+
+		if err != nil {
+			return fmt.Errorf("archiving file %s: %w", file.Filename, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+
+	return nil
+}
+
+// PatchBackend writes a unified diff turning the files of the output
+// directory into the generated ones, leaving the directory untouched. Paths
+// in the patch are Dir joined with file names, prefixed with "a/" and "b/"
+// as git does, so the patch applies with `git apply` or `patch -p1` from the
+// directory Dir is relative to. Unchanged files are left out.
+type PatchBackend struct {
+	Dir string
+	W   io.Writer
+	// Changed lists the names of the files that differ from the output
+	// directory, set by Write.
+	Changed []string
+}
+
+// Write diffs files against the output directory.
+func (b *PatchBackend) Write(files []GeneratedFile) error {
+	var patch strings.Builder
+
+	for _, file := range files {
+		outputPath := filepath.Join(b.Dir, file.Filename)
+
+		old, err := os.ReadFile(outputPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("reading file %s: %w", file.Filename, err)
+		}
+
+		if err == nil && bytes.Equal(old, file.Content) {
+			continue
+		}
+
+		b.Changed = append(b.Changed, file.Filename)
+
+		name := filepath.ToSlash(outputPath)
+		fmt.Fprintf(&patch, "diff --git a/%s b/%s\n", name, name)
+
+		if err != nil {
+			fmt.Fprintf(&patch, "new file mode 100%o\n--- /dev/null\n", filePerm)
+		} else {
+			fmt.Fprintf(&patch, "--- a/%s\n", name)
+		}
+
+		fmt.Fprintf(&patch, "+++ b/%s\n", name)
+		writeHunks(&patch, diffLines(splitLines(old), splitLines(file.Content)))
+	}
+
+	if _, err := io.WriteString(b.W, patch.String()); err != nil {
+		return fmt.Errorf("writing patch: %w", err)
+	}
+
+	return nil
+}
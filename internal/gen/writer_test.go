@@ -0,0 +1,163 @@
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirBackend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	require.NoError(t, (&DirBackend{Dir: dir}).Write([]GeneratedFile{{Filename: "a.go", Content: []byte("package a\n")}}))
+
+	content, err := os.ReadFile(filepath.Join(dir, "a.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package a\n", string(content))
+}
+
+func TestMemoryBackend(t *testing.T) {
+	content := []byte("package a\n")
+	backend := &MemoryBackend{}
+
+	require.NoError(t, backend.Write([]GeneratedFile{{Filename: "a.go", Content: content}}))
+
+	content[0] = 'P'
+	assert.Equal(t, map[string][]byte{"a.go": []byte("package a\n")}, backend.Files)
+}
+
+func TestZipBackend(t *testing.T) {
+	var buf bytes.Buffer
+
+	files := []GeneratedFile{
+		{Filename: "a.go", Content: []byte("package a\n")},
+		{Filename: "sub/b.go", Content: []byte("package sub\n")},
+	}
+	require.NoError(t, (&ZipBackend{W: &buf}).Write(files))
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+
+	for i, f := range r.File {
+		assert.Equal(t, files[i].Filename, f.Name)
+
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, string(files[i].Content), string(content))
+	}
+
+	// Archives of the same files are byte-identical
+	var again bytes.Buffer
+
+	require.NoError(t, (&ZipBackend{W: &again}).Write(files))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	var partial bytes.Buffer
+
+	err = (&ZipBackend{W: &partial}).Write(append(files, GeneratedFile{Filename: "../store/missing_types.go"}))
+	require.ErrorContains(t, err, "outside the output directory")
+	assert.Zero(t, partial.Len())
+}
+
+func TestPatchBackend(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.Mkdir("out", 0o755))
+
+	oldLines := []string{"package a", "", "func A() {", "\tone()", "\ttwo()", "\tthree()", "\tfour()", "\tfive()",
+		"\tsix()", "\tseven()", "\teight()", "\tnine()", "}", ""}
+	newLines := slicesReplace(oldLines, map[int]string{4: "\tTWO()", 11: "\tNINE()"})
+
+	require.NoError(t, os.WriteFile("out/a.go", []byte(strings.Join(oldLines, "\n")), 0o644))
+	require.NoError(t, os.WriteFile("out/same.go", []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile("out/tail.go", []byte("package a"), 0o644))
+
+	var buf bytes.Buffer
+
+	backend := &PatchBackend{Dir: "out", W: &buf}
+	err := backend.Write([]GeneratedFile{
+		{Filename: "a.go", Content: []byte(strings.Join(newLines, "\n"))},
+		{Filename: "same.go", Content: []byte("package a\n")},
+		{Filename: "tail.go", Content: []byte("package a\n")},
+		{Filename: "new.go", Content: []byte("package a\n\nvar X = 1\n")},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `diff --git a/out/a.go b/out/a.go
+--- a/out/a.go
++++ b/out/a.go
+@@ -2,12 +2,12 @@
+`+" \n"+` func A() {
+ 	one()
+-	two()
++	TWO()
+ 	three()
+ 	four()
+ 	five()
+ 	six()
+ 	seven()
+ 	eight()
+-	nine()
++	NINE()
+ }
+diff --git a/out/tail.go b/out/tail.go
+--- a/out/tail.go
++++ b/out/tail.go
+@@ -1,1 +1,1 @@
+-package a
+\ No newline at end of file
++package a
+diff --git a/out/new.go b/out/new.go
+new file mode 100644
+--- /dev/null
++++ b/out/new.go
+@@ -0,0 +1,3 @@
++package a
++
++var X = 1
+`, buf.String())
+	assert.Equal(t, []string{"a.go", "tail.go", "new.go"}, backend.Changed)
+
+	// The output directory is left untouched
+	_, err = os.Stat("out/new.go")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDiffLines_SeparateHunks(t *testing.T) {
+	from := make([]string, 20)
+	for i := range from {
+		from[i] = strings.Repeat("x", i) + "\n"
+	}
+
+	to := slicesReplace(from, map[int]string{1: "a\n", 18: "b\n"})
+
+	var patch strings.Builder
+
+	writeHunks(&patch, diffLines(from, to))
+
+	assert.Equal(t, 2, strings.Count(patch.String(), "@@ -"))
+	assert.Contains(t, patch.String(), "@@ -1,5 +1,5 @@\n")
+	assert.Contains(t, patch.String(), "@@ -16,5 +16,5 @@\n")
+}
+
+// slicesReplace returns a copy of lines with the lines at the given indexes
+// replaced.
+func slicesReplace(lines []string, replaced map[int]string) []string {
+	out := append([]string(nil), lines...)
+	for i, line := range replaced {
+		out[i] = line
+	}
+
+	return out
+}
@@ -0,0 +1,160 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"caster-generator/internal/analyze"
+)
+
+// Field names of the key/value structs that []KV collect targets hold.
+const (
+	CollectKeyField   = "Key"
+	CollectValueField = "Value"
+)
+
+// CollectEntry is a source field that a collect mapping stores under Key.
+type CollectEntry struct {
+	Source string // Source field path (e.g., "Color")
+	Key    string // Map key or Key field value (e.g., "color")
+}
+
+// CollectEntries returns the entries of a collect table in a stable order.
+func CollectEntries(collect map[string]string) []CollectEntry {
+	entries := make([]CollectEntry, 0, len(collect))
+	for _, src := range SortedEnumKeys(collect) {
+		entries = append(entries, CollectEntry{Source: src, Key: collect[src]})
+	}
+
+	return entries
+}
+
+// CollectTarget describes the target of a collect mapping: a map keyed by a
+// basic type, or a slice of structs with Key and Value fields.
+type CollectTarget struct {
+	Key   *analyze.TypeInfo // Map key type, or type of the Key field
+	Value *analyze.TypeInfo // Map value type, or type of the Value field
+	Elem  *analyze.TypeInfo // Slice element struct; nil for map targets
+}
+
+// errCollectTarget is returned for collect targets that are neither maps nor
+// slices of key/value structs.
+var errCollectTarget = errors.New("collect requires a map target or a slice of structs with Key and Value fields")
+
+// NewCollectTarget returns the CollectTarget of target field type t.
+func NewCollectTarget(t *analyze.TypeInfo) (*CollectTarget, error) {
+	u := t
+	for u != nil && u.Kind == analyze.TypeKindAlias && u.Underlying != nil {
+		u = u.Underlying
+	}
+
+	if u == nil {
+		return nil, errCollectTarget
+	}
+
+	var ct *CollectTarget
+
+	switch u.Kind {
+	case analyze.TypeKindMap:
+		ct = &CollectTarget{Key: u.KeyType, Value: u.ElemType}
+	case analyze.TypeKindSlice:
+		elem := u.ElemType
+		if elem == nil || elem.Kind != analyze.TypeKindStruct {
+			return nil, errCollectTarget
+		}
+
+		ct = &CollectTarget{Elem: elem}
+
+		// Keys and values are set in a composite literal, so only declared fields count.
+		for i := range elem.Fields {
+			switch f := &elem.Fields[i]; f.Name {
+			case CollectKeyField:
+				ct.Key = f.Type
+			case CollectValueField:
+				ct.Value = f.Type
+			}
+		}
+
+		if ct.Key == nil || ct.Value == nil {
+			return nil, errCollectTarget
+		}
+	default:
+		return nil, errCollectTarget
+	}
+
+	if enumBasicType(ct.Key) == nil {
+		return nil, errors.New("collect requires string, numeric or bool keys")
+	}
+
+	return ct, nil
+}
+
+// CollectConversion reports whether a source value of type src must be
+// converted to be collected as value type dst, e.g. a string-based Color into
+// a map[string]string. It fails when src can't be collected as dst.
+func CollectConversion(src, dst *analyze.TypeInfo) (bool, error) {
+	switch {
+	case src == nil || dst == nil:
+		return false, nil
+	case dst.IsInterface():
+		return false, nil
+	case src.IsNamed() && src.ID == dst.ID:
+		return false, nil
+	}
+
+	srcBasic, dstBasic := enumBasicType(src), enumBasicType(dst)
+	if srcBasic != nil && dstBasic != nil && srcBasic.ID.Name == dstBasic.ID.Name {
+		return true, nil
+	}
+
+	return false, fmt.Errorf("can't collect %s into %s values", typeName(src), typeName(dst))
+}
+
+// CollectFormat describes how a numeric or bool source value is formatted to
+// be collected as a string value.
+type CollectFormat struct {
+	Func string // strconv function (e.g., "FormatInt")
+	Arg  string // Basic type the value is converted to first (e.g., "int64")
+	Args string // Arguments following the value (e.g., ", 10")
+}
+
+// NewCollectFormat returns how a source value of type src is formatted to be
+// collected as value type dst, or nil unless src is numeric or bool and dst
+// has a string underlying type.
+func NewCollectFormat(src, dst *analyze.TypeInfo) *CollectFormat {
+	if !isStringBased(dst) {
+		return nil
+	}
+
+	srcBasic := enumBasicType(src)
+	if srcBasic == nil {
+		return nil
+	}
+
+	switch name := srcBasic.ID.Name; {
+	case name == "bool":
+		return &CollectFormat{Func: "FormatBool", Arg: "bool"}
+	case name == "float32", name == "float64":
+		return &CollectFormat{Func: "FormatFloat", Arg: "float64", Args: ", 'f', -1, " + strings.TrimPrefix(name, "float")}
+	case name == "byte" || strings.HasPrefix(name, "uint"):
+		return &CollectFormat{Func: "FormatUint", Arg: "uint64", Args: ", 10"}
+	case name == "rune" || strings.HasPrefix(name, "int"):
+		return &CollectFormat{Func: "FormatInt", Arg: "int64", Args: ", 10"}
+	default:
+		// Strings are converted, complex numbers aren't collected
+		return nil
+	}
+}
+
+// typeName returns a readable name of t for diagnostics.
+func typeName(t *analyze.TypeInfo) string {
+	switch {
+	case t.GoType != nil:
+		return t.GoType.String()
+	case t.IsNamed():
+		return t.ID.String()
+	default:
+		return t.Kind.String()
+	}
+}
@@ -0,0 +1,157 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestNewCollectTarget(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	kv := func(key, value string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			Kind: analyze.TypeKindSlice,
+			ElemType: &analyze.TypeInfo{
+				ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "KV"},
+				Kind: analyze.TypeKindStruct,
+				Fields: []analyze.FieldInfo{
+					{Name: key, Exported: true, Type: basic("string")},
+					{Name: value, Exported: true, Type: basic("string")},
+				},
+			},
+		}
+	}
+
+	ct, err := NewCollectTarget(&analyze.TypeInfo{
+		Kind: analyze.TypeKindMap, KeyType: basic("string"), ElemType: basic("int"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "string", ct.Key.ID.Name)
+	assert.Equal(t, "int", ct.Value.ID.Name)
+	assert.Nil(t, ct.Elem)
+
+	ct, err = NewCollectTarget(kv("Key", "Value"))
+	require.NoError(t, err)
+	assert.Equal(t, "KV", ct.Elem.ID.Name)
+
+	_, err = NewCollectTarget(kv("Name", "Value"))
+	require.Error(t, err)
+
+	_, err = NewCollectTarget(basic("string"))
+	require.Error(t, err)
+
+	_, err = NewCollectTarget(&analyze.TypeInfo{
+		Kind: analyze.TypeKindMap, KeyType: &analyze.TypeInfo{Kind: analyze.TypeKindStruct}, ElemType: basic("int"),
+	})
+	assert.ErrorContains(t, err, "keys")
+}
+
+func TestCollectConversion(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	color := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Color"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: str,
+	}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+
+	convert, err := CollectConversion(str, str)
+	require.NoError(t, err)
+	assert.False(t, convert)
+
+	convert, err = CollectConversion(color, str)
+	require.NoError(t, err)
+	assert.True(t, convert)
+
+	_, err = CollectConversion(num, str)
+	assert.ErrorContains(t, err, "can't collect int into string values")
+}
+
+func TestNewCollectFormat(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	label := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Label"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: basic("string"),
+	}
+
+	assert.Equal(t, &CollectFormat{Func: "FormatInt", Arg: "int64", Args: ", 10"},
+		NewCollectFormat(basic("int"), label))
+	assert.Equal(t, &CollectFormat{Func: "FormatUint", Arg: "uint64", Args: ", 10"},
+		NewCollectFormat(basic("byte"), label))
+	assert.Equal(t, &CollectFormat{Func: "FormatFloat", Arg: "float64", Args: ", 'f', -1, 32"},
+		NewCollectFormat(basic("float32"), basic("string")))
+	assert.Equal(t, &CollectFormat{Func: "FormatBool", Arg: "bool"}, NewCollectFormat(basic("bool"), basic("string")))
+
+	// Strings are converted, and only string values formatted.
+	assert.Nil(t, NewCollectFormat(label, basic("string")))
+	assert.Nil(t, NewCollectFormat(basic("int"), basic("int64")))
+	assert.Nil(t, NewCollectFormat(basic("complex64"), basic("string")))
+}
+
+func TestValidate_Collect(t *testing.T) {
+	graph := buildTestTypeGraph()
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	dst := graph.Types[analyze.TypeID{PkgPath: "caster-generator/warehouse", Name: "Order"}]
+	dst.Fields = append(dst.Fields, analyze.FieldInfo{
+		Name: "Attributes", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+		},
+	})
+
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: Attributes
+        collect:
+          FirstName: first
+          LastName: last
+      - target: Attributes
+        collect:
+          FirstName: name
+          LastName: name
+          Items: items
+          Color: color
+      - target: Customer
+        collect:
+          FirstName: first
+      - target: Attributes
+        source: OrderID
+        collect:
+          FirstName: first
+      - target: Customer
+        source: CustomerName
+        omit_zero: true
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, graph)
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.FieldPath+": "+e.Code)
+	}
+
+	assert.Equal(t, []string{
+		"Color: invalid_source_path",
+		"Items: collect_type_mismatch",
+		"Attributes: duplicate_collect_key",
+		"Customer: collect_unsupported_type",
+		"Attributes: conflicting_collect",
+		"Customer: invalid_omit_zero",
+		"Attributes: duplicate_target",
+		"Attributes: duplicate_target",
+		"Customer: duplicate_target",
+	}, codes)
+}
@@ -0,0 +1,106 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// conditionIdents are the bare identifiers a when condition may use besides
+// the fields of in.
+var conditionIdents = map[string]bool{"true": true, "false": true, "nil": true}
+
+// ConditionPaths parses the when condition of a field mapping, a Go boolean
+// expression over the source fields (e.g., `in.Type == "premium" && in.Total > 0`),
+// and returns the source field paths it reads, in order of appearance.
+// Besides in's fields, conditions may only use literals, true, false, nil,
+// operators and len.
+func ConditionPaths(expr string) ([]string, error) {
+	x, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+
+	var paths []string
+
+	if err := conditionPaths(x, &paths); err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("condition %q doesn't read any source field", expr)
+	}
+
+	return paths, nil
+}
+
+// conditionPaths appends the source field paths read by x to paths, failing
+// on the expressions conditions don't support.
+func conditionPaths(x ast.Expr, paths *[]string) error {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		return nil
+	case *ast.Ident:
+		if !conditionIdents[x.Name] {
+			return fmt.Errorf("unknown identifier %s (source fields are read as in.Field)", x.Name)
+		}
+
+		return nil
+	case *ast.ParenExpr:
+		return conditionPaths(x.X, paths)
+	case *ast.UnaryExpr:
+		if x.Op != token.NOT && x.Op != token.SUB {
+			return fmt.Errorf("unsupported operator %s", x.Op)
+		}
+
+		return conditionPaths(x.X, paths)
+	case *ast.BinaryExpr:
+		if err := conditionPaths(x.X, paths); err != nil {
+			return err
+		}
+
+		return conditionPaths(x.Y, paths)
+	case *ast.SelectorExpr:
+		path, ok := sourceSelector(x)
+		if !ok {
+			return errors.New("unsupported selector (source fields are read as in.Field)")
+		}
+
+		*paths = append(*paths, path)
+
+		return nil
+	case *ast.CallExpr:
+		if fn, ok := x.Fun.(*ast.Ident); !ok || fn.Name != "len" || len(x.Args) != 1 {
+			return errors.New("unsupported call (only len is allowed)")
+		}
+
+		return conditionPaths(x.Args[0], paths)
+	default:
+		return fmt.Errorf("unsupported expression %T", x)
+	}
+}
+
+// sourceSelector returns the field path of a selector chain rooted at in
+// (e.g., "Customer.Name" for in.Customer.Name).
+func sourceSelector(x *ast.SelectorExpr) (string, bool) {
+	names := []string{x.Sel.Name}
+
+	for {
+		switch inner := x.X.(type) {
+		case *ast.Ident:
+			if inner.Name != "in" {
+				return "", false
+			}
+
+			return strings.Join(names, "."), true
+		case *ast.SelectorExpr:
+			names = append([]string{inner.Sel.Name}, names...)
+			x = inner
+		default:
+			return "", false
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{"comparison", `in.Type == "premium"`, []string{"Type"}, false},
+		{"nested field", `in.Customer.Tier != ""`, []string{"Customer.Tier"}, false},
+		{"logic", `!in.Deleted && (in.Total > 0 || in.Note != nil)`, []string{"Deleted", "Total", "Note"}, false},
+		{"len", `len(in.Items) > 0`, []string{"Items"}, false},
+		{"negative literal", `in.Total > -1`, []string{"Total"}, false},
+		{"syntax error", `in.Type ==`, nil, true},
+		{"bare identifier", `Type == "premium"`, nil, true},
+		{"other receiver", `out.Type == "premium"`, nil, true},
+		{"function call", `strings.HasPrefix(in.Type, "p")`, nil, true},
+		{"no source field", `true`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConditionPaths(tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_When(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: Customer
+        source: CustomerName
+        when: in.Price > 100 && in.FirstName != ""
+        else_default: '"guest"'
+      - target: Amount
+        source: Price
+        when: in.Total > 0
+      - target: Status
+        default: '"open"'
+        else_default: '"closed"'
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_when", result.Errors[0].Code)
+	assert.Equal(t, "Amount", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[0].Message, `field "Total" not found`)
+	assert.Equal(t, "else_default_without_when", result.Errors[1].Code)
+}
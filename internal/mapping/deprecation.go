@@ -0,0 +1,80 @@
+package mapping
+
+import (
+	"fmt"
+	"time"
+
+	"caster-generator/internal/diagnostic"
+)
+
+// SunsetLayout is the date format of FieldMapping.Sunset.
+const SunsetLayout = "2006-01-02"
+
+// CheckDeprecations reports deprecated field mappings: a warning while the
+// mapping is still allowed, and an error once its sunset date has passed.
+// Ignore entries still present after their until date are warned about.
+// Malformed dates are reported by Validate and skipped here.
+func CheckDeprecations(mf *MappingFile, now time.Time) *diagnostic.Diagnostics {
+	res := &diagnostic.Diagnostics{}
+	if mf == nil {
+		return res
+	}
+
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+		tpStr := fmt.Sprintf("%s->%s", tm.Source, tm.Target)
+
+		for _, fm := range append(append([]FieldMapping{}, tm.Fields...), tm.Auto...) {
+			if fm.Deprecated == "" {
+				continue
+			}
+
+			target := fm.Target.First()
+
+			// The sunset day itself is still allowed.
+			sunset, err := time.Parse(SunsetLayout, fm.Sunset)
+			if err == nil && !now.Before(sunset.AddDate(0, 0, 1)) {
+				res.AddError("deprecated_field_sunset",
+					fmt.Sprintf("field %q was sunset on %s: %s", target, fm.Sunset, fm.Deprecated), tpStr, target)
+
+				continue
+			}
+
+			msg := fmt.Sprintf("field %q is deprecated: %s", target, fm.Deprecated)
+			if err == nil {
+				msg += " (sunset " + fm.Sunset + ")"
+			}
+
+			res.AddWarning("deprecated_field", msg, tpStr, target)
+		}
+
+		for _, ig := range tm.Ignore {
+			mark := res.Mark()
+			checkIgnoreExpiry(res, tpStr, &ig, now)
+			res.Locate(mark, ig.Pos)
+		}
+	}
+
+	return res
+}
+
+// checkIgnoreExpiry warns about an ignore entry whose until date has passed,
+// so the decision to leave its field unmapped gets revisited.
+func checkIgnoreExpiry(res *diagnostic.Diagnostics, typePairStr string, ig *IgnoreEntry, now time.Time) {
+	// The until day itself is still allowed.
+	until, err := time.Parse(SunsetLayout, ig.Until)
+	if err != nil || now.Before(until.AddDate(0, 0, 1)) {
+		return
+	}
+
+	msg := fmt.Sprintf("field %q is still ignored after %s", ig.Target, ig.Until)
+	if ig.Reason != "" {
+		msg += ": " + ig.Reason
+	}
+
+	if ig.Ticket != "" {
+		msg += " (ticket " + ig.Ticket + ")"
+	}
+
+	res.AddWarning("expired_ignore", msg, typePairStr, ig.Target)
+}
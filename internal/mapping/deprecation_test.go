@@ -0,0 +1,116 @@
+package mapping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDeprecations(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: Price
+        target: Amount
+        deprecated: use Total
+        sunset: "2025-01-31"
+      - source: CustomerName
+        target: Customer
+        deprecated: use DisplayName
+      - source: OrderID
+        target: ID
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+	require.True(t, Validate(mf, buildTestTypeGraph()).IsValid())
+
+	before := CheckDeprecations(mf, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC))
+	assert.Empty(t, before.Errors)
+	require.Len(t, before.Warnings, 2)
+	assert.Equal(t, "deprecated_field", before.Warnings[0].Code)
+	assert.Contains(t, before.Warnings[0].Message, "(sunset 2025-01-31)")
+
+	after := CheckDeprecations(mf, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, after.Errors, 1)
+	assert.Equal(t, "deprecated_field_sunset", after.Errors[0].Code)
+	assert.Equal(t, "Amount", after.Errors[0].FieldPath)
+	require.Len(t, after.Warnings, 1)
+}
+
+func TestValidate_Sunset(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: Price
+        target: Amount
+        deprecated: use Total
+        sunset: 2025-01
+      - source: OrderID
+        target: ID
+        sunset: "2025-01-31"
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.Code)
+	}
+
+	assert.ElementsMatch(t, []string{"invalid_sunset", "sunset_without_deprecated"}, codes)
+}
+
+func TestCheckDeprecations_ExpiredIgnore(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore:
+      - Status
+      - target: DisplayName
+        reason: filled by the UI
+        until: 2025-01-31
+        ticket: SHOP-42
+      - {target: FullName, until: 2025-03-01}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+	require.True(t, Validate(mf, buildTestTypeGraph()).IsValid())
+
+	before := CheckDeprecations(mf, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC))
+	assert.Empty(t, before.Warnings)
+
+	after := CheckDeprecations(mf, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.Empty(t, after.Errors)
+	require.Len(t, after.Warnings, 1)
+	assert.Equal(t, "expired_ignore", after.Warnings[0].Code)
+	assert.Equal(t, "DisplayName", after.Warnings[0].FieldPath)
+	assert.Equal(t, `field "DisplayName" is still ignored after 2025-01-31: filled by the UI (ticket SHOP-42)`,
+		after.Warnings[0].Message)
+	assert.Equal(t, 7, after.Warnings[0].Pos.Line)
+}
+
+func TestValidate_IgnoreUntil(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore:
+      - {target: Status, until: 2025-01}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_ignore_until", result.Errors[0].Code)
+	assert.Equal(t, 6, result.Errors[0].Pos.Line)
+}
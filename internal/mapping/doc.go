@@ -14,6 +14,7 @@
 //   - Support path expressions for nested shapes (e.g., "Items[].ProductID")
 //   - Priority-based conflict resolution (121 > fields > ignore > auto)
 //   - Introspection hints (dive/final) to control recursive resolution
+//   - Include directives merging mapping files split across a project
 //
 // # Schema Overview
 //
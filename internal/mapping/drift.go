@@ -0,0 +1,302 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/analyze"
+)
+
+// DriftEdit is an edit of a mapping file following its Go structs: a field
+// path renamed, or an entry dropped because its field no longer exists.
+type DriftEdit struct {
+	// TypePair is the mapping's pair as written (e.g., "store.Order->warehouse.Order").
+	TypePair string
+	// Section is the mapping section edited (121, fields, auto or ignore).
+	Section string
+	// Field is the path of the field that no longer exists.
+	Field string
+	// Renamed is the path Field was replaced with, empty if the entry was dropped.
+	Renamed string
+}
+
+// String describes the edit.
+func (e DriftEdit) String() string {
+	if e.Renamed != "" {
+		return fmt.Sprintf("%s: renamed %s to %s in %s", e.TypePair, e.Field, e.Renamed, e.Section)
+	}
+
+	return fmt.Sprintf("%s: dropped %s from %s (field no longer exists)", e.TypePair, e.Field, e.Section)
+}
+
+// FixStaleFields patches mapping YAML data whose field paths refer to fields
+// deleted from the Go structs, keeping its layout and comments. Paths of
+// fields renamed, as RenamedPath tells, are rewritten; other entries referring
+// to deleted fields are dropped, with a comment above their section. Mappings
+// whose types don't resolve are left alone.
+func FixStaleFields(data []byte, graph *analyze.TypeGraph) ([]byte, []DriftEdit, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse mapping YAML: %w", err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("mapping YAML is not a mapping")
+	}
+
+	mappings := valueNode(doc.Content[0], "mappings")
+	if mappings == nil || mappings.Kind != yaml.SequenceNode {
+		return data, nil, nil
+	}
+
+	var edits []DriftEdit
+
+	for _, entry := range mappings.Content {
+		edits = append(edits, fixStaleEntry(entry, graph)...)
+	}
+
+	if len(edits) == 0 {
+		return data, nil, nil
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+
+	return out, edits, nil
+}
+
+// staleFixer fixes the field paths of one type mapping entry.
+type staleFixer struct {
+	srcT, dstT *analyze.TypeInfo
+	requires   map[string]bool
+	// used holds the paths the entry refers to, by side, so that a field
+	// already mapped isn't taken for the new name of a deleted one.
+	used  [2]map[string]bool
+	edits []DriftEdit
+	pair  string
+}
+
+const (
+	sourceSide = iota
+	targetSide
+)
+
+// fixStaleEntry fixes the sections of a type mapping entry of the mappings sequence.
+func fixStaleEntry(entry *yaml.Node, graph *analyze.TypeGraph) []DriftEdit {
+	src, tgt := valueNode(entry, "source"), valueNode(entry, "target")
+	if src == nil || tgt == nil || src.Kind != yaml.ScalarNode || tgt.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	f := &staleFixer{
+		srcT:     ResolveTypeID(src.Value, graph),
+		dstT:     ResolveTypeID(tgt.Value, graph),
+		requires: make(map[string]bool),
+		used:     [2]map[string]bool{make(map[string]bool), make(map[string]bool)},
+		pair:     src.Value + "->" + tgt.Value,
+	}
+	if f.srcT == nil || f.dstT == nil {
+		return nil
+	}
+
+	if requires := valueNode(entry, "requires"); requires != nil {
+		for _, arg := range requires.Content {
+			if name := valueNode(arg, "name"); name != nil {
+				f.requires[name.Value] = true
+			}
+		}
+	}
+
+	f.collectUsed(entry)
+
+	for i := 0; i+1 < len(entry.Content); i += 2 {
+		key, value := entry.Content[i], entry.Content[i+1]
+
+		switch key.Value {
+		case "121":
+			f.fixOneToOne(key, value)
+		case "fields", "auto":
+			f.fixFieldMappings(key, value)
+		case "ignore":
+			f.fixIgnore(key, value)
+		}
+	}
+
+	return f.edits
+}
+
+// collectUsed records the paths of the 121, fields, auto and ignore sections.
+func (f *staleFixer) collectUsed(entry *yaml.Node) {
+	if oneToOne := valueNode(entry, "121"); oneToOne != nil && oneToOne.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(oneToOne.Content); i += 2 {
+			f.used[sourceSide][oneToOne.Content[i].Value] = true
+			f.used[targetSide][oneToOne.Content[i+1].Value] = true
+		}
+	}
+
+	for _, section := range []string{"fields", "auto"} {
+		if seq := valueNode(entry, section); seq != nil {
+			for _, fm := range seq.Content {
+				for _, n := range pathNodes(valueNode(fm, "source")) {
+					f.used[sourceSide][n.Value] = true
+				}
+
+				for _, n := range pathNodes(valueNode(fm, "target")) {
+					f.used[targetSide][n.Value] = true
+				}
+			}
+		}
+	}
+
+	if ignore := valueNode(entry, "ignore"); ignore != nil {
+		for _, n := range ignore.Content {
+			f.used[targetSide][n.Value] = true
+		}
+	}
+}
+
+// fixOneToOne fixes the source: target pairs of a 121 section.
+func (f *staleFixer) fixOneToOne(key, value *yaml.Node) {
+	if value.Kind != yaml.MappingNode {
+		return
+	}
+
+	kept := value.Content[:0]
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		sp, tp := value.Content[i], value.Content[i+1]
+		if f.fix(key, sp, sourceSide, tp.Value) && f.fix(key, tp, targetSide, sp.Value) {
+			kept = append(kept, sp, tp)
+		}
+	}
+
+	value.Content = kept
+}
+
+// fixFieldMappings fixes the field mappings of a fields or auto section.
+func (f *staleFixer) fixFieldMappings(key, value *yaml.Node) {
+	if value.Kind != yaml.SequenceNode {
+		return
+	}
+
+	kept := value.Content[:0]
+
+	for _, fm := range value.Content {
+		sources, targets := pathNodes(valueNode(fm, "source")), pathNodes(valueNode(fm, "target"))
+
+		// Types of a 1:1 mapping without transform match the renamed field's
+		var sourcePath, targetPath string
+		if len(sources) == 1 && len(targets) == 1 && valueNode(fm, "transform") == nil {
+			sourcePath, targetPath = sources[0].Value, targets[0].Value
+		}
+
+		ok := true
+
+		for _, n := range sources {
+			ok = ok && (f.requires[strings.Split(n.Value, ".")[0]] || f.fix(key, n, sourceSide, targetPath))
+		}
+
+		for _, n := range targets {
+			ok = ok && f.fix(key, n, targetSide, sourcePath)
+		}
+
+		if ok {
+			kept = append(kept, fm)
+		}
+	}
+
+	value.Content = kept
+}
+
+// fixIgnore fixes the target paths of an ignore section.
+func (f *staleFixer) fixIgnore(key, value *yaml.Node) {
+	if value.Kind != yaml.SequenceNode {
+		return
+	}
+
+	kept := value.Content[:0]
+
+	for _, n := range value.Content {
+		if f.fix(key, n, targetSide, "") {
+			kept = append(kept, n)
+		}
+	}
+
+	value.Content = kept
+}
+
+// fix renames the path of node n if its field was renamed, reporting whether
+// it is kept; a dropped path is recorded in a comment above the section key.
+// The type of the counterpart path, on the other side, is the type a renamed
+// field must have; it is unknown if the path is empty or stale too.
+func (f *staleFixer) fix(key, n *yaml.Node, side int, counterpart string) bool {
+	t, other := f.srcT, f.dstT
+	if side == targetSide {
+		t, other = f.dstT, f.srcT
+	}
+
+	var want *analyze.TypeInfo
+	if counterpart != "" {
+		want, _ = resolvePathType(counterpart, other)
+	}
+
+	renamed, stale := RenamedPath(n.Value, t, want, func(path string) bool { return f.used[side][path] })
+	if !stale {
+		return true
+	}
+
+	edit := DriftEdit{TypePair: f.pair, Section: key.Value, Field: n.Value, Renamed: renamed}
+	f.edits = append(f.edits, edit)
+
+	if renamed != "" {
+		f.used[side][renamed] = true
+		n.Value = renamed
+
+		return true
+	}
+
+	comment := fmt.Sprintf("check -fix: dropped %s (field no longer exists)", edit.Field)
+	if key.HeadComment != "" {
+		comment = key.HeadComment + "\n" + comment
+	}
+
+	key.HeadComment = comment
+
+	return false
+}
+
+// pathNodes returns the scalar nodes holding the field paths of a source or
+// target value: a path, a {path: hint} map, or a sequence of those.
+func pathNodes(n *yaml.Node) []*yaml.Node {
+	if n == nil {
+		return nil
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Value == "" {
+			return nil
+		}
+
+		return []*yaml.Node{n}
+	case yaml.MappingNode:
+		if len(n.Content) > 0 {
+			return []*yaml.Node{n.Content[0]}
+		}
+	case yaml.SequenceNode:
+		var nodes []*yaml.Node
+		for _, item := range n.Content {
+			nodes = append(nodes, pathNodes(item)...)
+		}
+
+		return nodes
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+// buildDriftTypeGraph declares a store.User and an api.User whose fields
+// drifted from an older mapping.
+func buildDriftTypeGraph() *analyze.TypeGraph {
+	graph := analyze.NewTypeGraph()
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+
+	declare := func(pkgPath string, fields ...string) {
+		id := analyze.TypeID{PkgPath: pkgPath, Name: "User"}
+		user := &analyze.TypeInfo{ID: id, Kind: analyze.TypeKindStruct}
+
+		for _, name := range fields {
+			user.Fields = append(user.Fields, analyze.FieldInfo{Name: name, Type: str, Exported: true})
+		}
+
+		graph.Types[id] = user
+		graph.Packages[pkgPath] = &analyze.PackageInfo{Path: pkgPath, Name: "store", Types: []analyze.TypeID{id}}
+	}
+
+	declare("example/store", "ID", "FullName", "Email")
+	declare("example/api", "ID", "FullNames", "Mail", "Phone")
+	graph.Packages["example/api"].Name = "api"
+
+	return graph
+}
+
+func TestFixStaleFields(t *testing.T) {
+	data := []byte(`# users
+mappings:
+  - source: store.User
+    target: api.User
+    requires:
+      - name: tenant
+    121:
+      ID: ID # keep the ID
+      FullName: FullName
+      Nick: Phone
+    fields:
+      - source: tenant
+        target: Mail
+      - source: Email
+        target: Mail
+    ignore:
+      - Legacy
+  - source: store.Missing
+    target: api.User
+    121:
+      Gone: Gone
+`)
+
+	out, edits, err := FixStaleFields(data, buildDriftTypeGraph())
+	require.NoError(t, err)
+
+	var got []string
+	for _, e := range edits {
+		got = append(got, e.String())
+	}
+
+	// Requires args aren't fields, and unresolved mappings are left alone.
+	assert.Equal(t, []string{
+		"store.User->api.User: renamed FullName to FullNames in 121",
+		"store.User->api.User: dropped Nick from 121 (field no longer exists)",
+		"store.User->api.User: dropped Legacy from ignore (field no longer exists)",
+	}, got)
+
+	assert.Contains(t, string(out), "# users")
+	assert.Contains(t, string(out), "ID: ID # keep the ID")
+	assert.Contains(t, string(out), "# check -fix: dropped Nick (field no longer exists)")
+
+	mf, err := Parse(out)
+	require.NoError(t, err)
+	require.Len(t, mf.TypeMappings, 2)
+
+	user := mf.TypeMappings[0]
+	assert.Equal(t, map[string]string{"ID": "ID", "FullName": "FullNames"}, user.OneToOne)
+	assert.Len(t, user.Fields, 2)
+	assert.Empty(t, user.Ignore)
+	assert.Equal(t, map[string]string{"Gone": "Gone"}, mf.TypeMappings[1].OneToOne)
+
+	unchanged, edits, err := FixStaleFields(out, buildDriftTypeGraph())
+	require.NoError(t, err)
+	assert.Empty(t, edits)
+	assert.Equal(t, out, unchanged)
+}
@@ -0,0 +1,138 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"caster-generator/internal/analyze"
+)
+
+// errEnumType is returned for enum_map fields that aren't string, numeric or bool.
+var errEnumType = errors.New("enum_map requires string, numeric or bool types")
+
+// EnumLiteral returns an enum_map key or value as a Go literal of type t,
+// whose underlying type must be basic (e.g., "ACTIVE" for a string-based
+// Status, 1 for an int-based State). Strings may be given bare or as a Go
+// string literal, which isn't quoted again (ACTIVE and "ACTIVE" are the same
+// value). Numbers and bools are returned in canonical form, so values
+// written differently but equal (e.g., "1" and "0x1") have the same literal.
+func EnumLiteral(value string, t *analyze.TypeInfo) (string, error) {
+	t = enumBasicType(t)
+	if t == nil {
+		return "", errEnumType
+	}
+
+	var (
+		err error
+		tok token.Token
+	)
+
+	switch name := t.ID.Name; {
+	case name == "string":
+		if s, ok := unquoteString(value); ok {
+			return strconv.Quote(s), nil
+		}
+
+		return strconv.Quote(value), nil
+	case name == "bool":
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(b), nil
+		}
+	case strings.HasPrefix(name, "uint") || name == "byte":
+		_, err = strconv.ParseUint(value, 0, 64)
+		tok = token.INT
+	case strings.HasPrefix(name, "int") || name == "rune":
+		_, err = strconv.ParseInt(value, 0, 64)
+		tok = token.INT
+	case strings.HasPrefix(name, "float"):
+		_, err = strconv.ParseFloat(value, 64)
+		tok = token.FLOAT
+	default:
+		return "", errEnumType
+	}
+
+	if err == nil {
+		if lit, ok := canonicalNumber(value, tok, t.ID.Name); ok {
+			// Sizes are checked as for default literals, e.g. 300 overflows a uint8.
+			if x, err := parser.ParseExpr(lit); err == nil && !literalFits(literalValue(x), t.ID.Name) {
+				return "", fmt.Errorf("%q overflows %s", value, t.ID.Name)
+			}
+
+			return lit, nil
+		}
+	}
+
+	return "", fmt.Errorf("%q is not a valid %s", value, t.ID.Name)
+}
+
+// unquoteString returns the value of s if it is a Go string literal, quoted
+// or raw.
+func unquoteString(s string) (string, bool) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '`') {
+		return "", false
+	}
+
+	v, err := strconv.Unquote(s)
+
+	return v, err == nil
+}
+
+// canonicalNumber returns the number literal value of kind tok (token.INT or
+// token.FLOAT) in canonical form: decimal integers, and floats as the
+// shortest literal of their typeName value. It returns false if value isn't
+// a Go number literal, optionally signed.
+func canonicalNumber(value string, tok token.Token, typeName string) (string, bool) {
+	digits, negative := strings.CutPrefix(strings.TrimPrefix(value, "+"), "-")
+
+	v := constant.MakeFromLiteral(digits, tok, 0)
+	if v.Kind() == constant.Unknown {
+		return "", false
+	}
+
+	if negative {
+		v = constant.UnaryOp(token.SUB, v, 0)
+	}
+
+	if v.Kind() == constant.Int {
+		return v.ExactString(), true
+	}
+
+	f, _ := constant.Float64Val(v)
+	if typeName == "float32" {
+		return strconv.FormatFloat(f, 'g', -1, 32), true
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64), true
+}
+
+// SortedEnumKeys returns the keys of an enum_map in a stable order.
+func SortedEnumKeys(enumMap map[string]string) []string {
+	keys := make([]string, 0, len(enumMap))
+	for k := range enumMap {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// enumBasicType returns the basic type underlying t, or nil if there is none.
+func enumBasicType(t *analyze.TypeInfo) *analyze.TypeInfo {
+	for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	if t == nil || t.Kind != analyze.TypeKindBasic {
+		return nil
+	}
+
+	return t
+}
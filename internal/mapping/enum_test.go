@@ -0,0 +1,119 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestEnumLiteral(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	named := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Status"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: basic("string"),
+	}
+	structType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "Order"}, Kind: analyze.TypeKindStruct}
+
+	tests := []struct {
+		name    string
+		value   string
+		typ     *analyze.TypeInfo
+		want    string
+		wantErr bool
+	}{
+		{"string", "ACTIVE", basic("string"), `"ACTIVE"`, false},
+		{"named string", "ACTIVE", named, `"ACTIVE"`, false},
+		{"quoted string", `"ACTIVE"`, basic("string"), `"ACTIVE"`, false},
+		{"quoted escapes", `"a\tb"`, basic("string"), `"a\tb"`, false},
+		{"unterminated quote", `"ACTIVE`, basic("string"), `"\"ACTIVE"`, false},
+		{"quoted int", `"1"`, basic("int"), "", true},
+		{"int", "-1", basic("int"), "-1", false},
+		{"hex uint", "0x1f", basic("uint8"), "31", false},
+		{"signed int", "+1_000", basic("int64"), "1000", false},
+		{"float", "0.5", basic("float64"), "0.5", false},
+		{"integral float", "2.50e1", basic("float64"), "25", false},
+		{"bool", "true", basic("bool"), "true", false},
+		{"numeric bool", "1", basic("bool"), "true", false},
+		{"not a float", "NaN", basic("float64"), "", true},
+		{"not an int", "ACTIVE", basic("int"), "", true},
+		{"negative uint", "-1", basic("uint"), "", true},
+		{"overflow", "300", basic("uint8"), "", true},
+		{"int8 bound", "-128", basic("int8"), "-128", false},
+		{"struct", "x", structType, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EnumLiteral(tt.value, tt.typ)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate_EnumMap(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: CustomerName
+        target: Customer
+        enum_default: UNKNOWN
+      - source: OrderID
+        target: ID
+        transform: Convert
+        enum_map:
+          A: B
+      - source: [FirstName, LastName]
+        target: Status
+        transform: Join
+        enum_map:
+          A: B
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.Code)
+	}
+
+	assert.ElementsMatch(t, []string{"enum_options_without_map", "conflicting_enum_map", "invalid_enum_map"}, codes)
+}
+
+func TestValidate_EnumMapDuplicateKeys(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: Price
+        target: Status
+        enum_map:
+          "1": ONE
+          "0x1": ALSO_ONE
+          "2": TWO
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "duplicate_enum_key", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, `"0x1" and "1"`)
+}
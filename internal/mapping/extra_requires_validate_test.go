@@ -100,4 +100,45 @@ func TestValidate_ExtraRequiresMustBeDeclared(t *testing.T) {
 		res := mapping.Validate(mf, graph)
 		require.True(t, res.IsValid(), "validation errors: %#v", res.Errors)
 	})
+
+	// Test 4: Extra with a const - passed as a literal, no requires needed
+	t.Run("extra with const is valid without requires", func(t *testing.T) {
+		mf := &mapping.MappingFile{TypeMappings: []mapping.TypeMapping{{
+			Source: "src.Src",
+			Target: "dst.Dst",
+			Fields: []mapping.FieldMapping{{
+				Source: mapping.FieldRefArray{{Path: "A"}},
+				Target: mapping.FieldRefArray{{Path: "B"}},
+				Extra:  mapping.ExtraVals{{Name: "layout", Const: `"2006-01-02"`}, {Name: "max", Const: "-8"}},
+			}},
+		}}}
+
+		res := mapping.Validate(mf, graph)
+		require.True(t, res.IsValid(), "validation errors: %#v", res.Errors)
+	})
+
+	// Test 5: Consts must be literals, and can't be combined with a def
+	t.Run("extra const must be a literal without def", func(t *testing.T) {
+		mf := &mapping.MappingFile{TypeMappings: []mapping.TypeMapping{{
+			Source: "src.Src",
+			Target: "dst.Dst",
+			Fields: []mapping.FieldMapping{{
+				Source: mapping.FieldRefArray{{Path: "A"}},
+				Target: mapping.FieldRefArray{{Path: "B"}},
+				Extra: mapping.ExtraVals{
+					{Name: "inf", Const: ".inf"},
+					{Name: "both", Const: "1", Def: mapping.ExtraDef{Source: "A"}},
+				},
+			}},
+		}}}
+
+		res := mapping.Validate(mf, graph)
+
+		codes := make([]string, 0, len(res.Errors))
+		for _, e := range res.Errors {
+			codes = append(codes, e.Code)
+		}
+
+		require.Equal(t, []string{"invalid_extra_const", "conflicting_extra"}, codes)
+	})
 }
@@ -0,0 +1,55 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// FieldPattern matches field names against an ignore_patterns entry: a glob
+// (e.g., "XXX_*") or a regular expression between slashes (e.g., "/^XXX_/").
+type FieldPattern struct {
+	pattern string
+	re      *regexp.Regexp // Nil for globs
+}
+
+// CompileFieldPattern parses an ignore_patterns entry.
+func CompileFieldPattern(pattern string) (*FieldPattern, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore_patterns regular expression %q: %w", pattern, err)
+		}
+
+		return &FieldPattern{pattern: pattern, re: re}, nil
+	}
+
+	if pattern == "" {
+		return nil, errors.New("empty ignore_patterns entry")
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid ignore_patterns glob %q: %w", pattern, err)
+	}
+
+	return &FieldPattern{pattern: pattern}, nil
+}
+
+// Match reports whether the field name matches the pattern. Globs match the
+// whole name, regular expressions any part of it.
+func (p *FieldPattern) Match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+
+	ok, _ := path.Match(p.pattern, name)
+
+	return ok
+}
+
+// String returns the pattern as written.
+func (p *FieldPattern) String() string {
+	return p.pattern
+}
@@ -0,0 +1,68 @@
+package mapping
+
+import (
+	"fmt"
+
+	"caster-generator/internal/analyze"
+)
+
+// FlatField is a field pair that a flatten or unflatten directive maps.
+type FlatField struct {
+	Source string // Source field path (e.g., "Address.Street" or "AddressStreet")
+	Target string // Target field path (e.g., "AddressStreet" or "Address.Street")
+}
+
+// FlattenFields returns the fields a flatten directive on field maps: each
+// field F of the struct field of srcT is mapped to the target field named
+// field+F (e.g., Address.Street -> AddressStreet), if dstT declares it.
+func FlattenFields(srcT, dstT *analyze.TypeInfo, field string) ([]FlatField, error) {
+	nested, err := flatStruct(srcT, field)
+	if err != nil {
+		return nil, fmt.Errorf("flatten %s: %w", field, err)
+	}
+
+	var pairs []FlatField
+
+	for _, f := range nested.Fields {
+		if f.Exported && dstT.FieldByName(field+f.Name) != nil {
+			pairs = append(pairs, FlatField{Source: field + "." + f.Name, Target: field + f.Name})
+		}
+	}
+
+	return pairs, nil
+}
+
+// UnflattenFields returns the fields an unflatten directive on field maps:
+// each field F of the struct field of dstT is assigned from the source field
+// named field+F (e.g., AddressStreet -> Address.Street), if srcT declares it.
+func UnflattenFields(srcT, dstT *analyze.TypeInfo, field string) ([]FlatField, error) {
+	nested, err := flatStruct(dstT, field)
+	if err != nil {
+		return nil, fmt.Errorf("unflatten %s: %w", field, err)
+	}
+
+	var pairs []FlatField
+
+	for _, f := range nested.Fields {
+		if f.Exported && srcT.FieldByName(field+f.Name) != nil {
+			pairs = append(pairs, FlatField{Source: field + f.Name, Target: field + "." + f.Name})
+		}
+	}
+
+	return pairs, nil
+}
+
+// flatStruct returns the struct type of the named field of t. Pointer fields
+// aren't supported, since reading or assigning through them needs nil checks.
+func flatStruct(t *analyze.TypeInfo, field string) (*analyze.TypeInfo, error) {
+	f := t.FieldByName(field)
+	if f == nil {
+		return nil, fmt.Errorf("field %q not found in %s", field, t.ID)
+	}
+
+	if f.Type == nil || f.Type.Kind != analyze.TypeKindStruct {
+		return nil, fmt.Errorf("field %q is not a struct (pointers are not supported)", field)
+	}
+
+	return f.Type, nil
+}
@@ -0,0 +1,57 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestFlattenFields(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	address := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Address"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Street", Exported: true, Type: str},
+			{Name: "City", Exported: true, Type: str},
+			{Name: "zip", Type: str},
+		},
+	}
+	nested := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/store", Name: "Customer"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Address", Exported: true, Type: address},
+			{Name: "Billing", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: address}},
+			{Name: "Name", Exported: true, Type: str},
+		},
+	}
+	flat := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/warehouse", Name: "Customer"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "AddressStreet", Exported: true, Type: str},
+			{Name: "AddressZip", Exported: true, Type: str},
+		},
+	}
+
+	pairs, err := FlattenFields(nested, flat, "Address")
+	require.NoError(t, err)
+	assert.Equal(t, []FlatField{{Source: "Address.Street", Target: "AddressStreet"}}, pairs)
+
+	pairs, err = UnflattenFields(flat, nested, "Address")
+	require.NoError(t, err)
+	assert.Equal(t, []FlatField{{Source: "AddressStreet", Target: "Address.Street"}}, pairs)
+
+	_, err = FlattenFields(nested, flat, "Billing")
+	require.ErrorContains(t, err, "not a struct")
+
+	_, err = FlattenFields(nested, flat, "Name")
+	require.Error(t, err)
+
+	_, err = UnflattenFields(nested, flat, "Shipping")
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,369 @@
+package mapping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/analyze"
+)
+
+// ErrIncludeCycle is returned when mapping files include each other.
+var ErrIncludeCycle = errors.New("include cycle")
+
+// IncludeConflict records a definition made differently by two included files.
+// Neither wins, so validation reports it as an error.
+type IncludeConflict struct {
+	// Kind is the kind of definition: "mapping", "transform", "wrapper" or
+	// "implementation".
+	Kind string
+	// Key identifies the definition (e.g., "store.Order->warehouse.Order").
+	Key string
+	// Files are the included files defining it, in include order.
+	Files []string
+}
+
+// loadFile loads path and merges the files it includes. chain holds the
+// absolute paths of the including files, to detect cycles.
+func loadFile(path string, chain []string) (*MappingFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mapping file %s: %w", path, err)
+	}
+
+	if slices.Contains(chain, abs) {
+		return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(chain, abs), " -> "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file %s: %w", path, err)
+	}
+
+	mf, err := parse(data, path)
+	if err != nil {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		return nil, err
+	}
+
+	if len(mf.Include) == 0 {
+		return mf, nil
+	}
+
+	files, err := includedFiles(filepath.Dir(path), mf.Include)
+	if err != nil {
+		return nil, fmt.Errorf("mapping file %s: %w", path, err)
+	}
+
+	m := newIncludeMerger()
+
+	for _, file := range files {
+		inc, err := loadFile(file, append(chain, abs))
+		if err != nil {
+			return nil, err
+		}
+
+		m.add(inc, file)
+	}
+
+	m.apply(mf)
+
+	return mf, nil
+}
+
+// includedFiles expands include globs relative to dir. Each pattern must
+// match at least one file; matches are sorted and deduplicated.
+func includedFiles(dir string, patterns []string) ([]string, error) {
+	var files []string
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("include %q matches no files", pattern)
+		}
+
+		for _, match := range matches {
+			if !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// includeMerger collects the definitions of included files.
+type includeMerger struct {
+	mappings        keyedDefs[TypeMapping]
+	transforms      keyedDefs[TransformDef]
+	wrappers        keyedDefs[WrapperDef]
+	implementations keyedDefs[ImplementationDef]
+	ignoreTags      []string
+	ignorePatterns  []string
+	stripAffixes    []string
+	synonyms        map[string][]string
+	abbreviations   map[string]string
+	templates       map[string][]FieldMapping
+	conflicts       []IncludeConflict
+}
+
+func newIncludeMerger() *includeMerger {
+	return &includeMerger{
+		mappings:        keyedDefs[TypeMapping]{kind: "mapping"},
+		transforms:      keyedDefs[TransformDef]{kind: "transform"},
+		wrappers:        keyedDefs[WrapperDef]{kind: "wrapper"},
+		implementations: keyedDefs[ImplementationDef]{kind: "implementation"},
+	}
+}
+
+// add merges the definitions of included file inc. Mappings inherit the
+// file's copy_mode, since it doesn't carry over to the including file; its
+// ignore_tags add up with the including file's, and its synonyms,
+// abbreviations and templates of names defined nowhere before are added.
+func (m *includeMerger) add(inc *MappingFile, file string) {
+	for _, tm := range inc.TypeMappings {
+		if tm.CopyMode == CopyDefault {
+			tm.CopyMode = inc.CopyMode
+		}
+
+		tm.includedFrom = file
+		m.mappings.add(tm.Key(), tm, file)
+	}
+
+	for _, t := range inc.Transforms {
+		m.transforms.add(t.Name, t, file)
+	}
+
+	for _, w := range inc.Wrappers {
+		m.wrappers.add(w.Type, w, file)
+	}
+
+	for _, impl := range inc.Implementations {
+		m.implementations.add(impl.Source+"->"+impl.Target, impl, file)
+	}
+
+	m.ignoreTags = append(m.ignoreTags, inc.IgnoreTags...)
+	m.ignorePatterns = append(m.ignorePatterns, inc.IgnorePatterns...)
+	m.stripAffixes = append(m.stripAffixes, inc.StripAffixes...)
+
+	addMissing(&m.synonyms, inc.Match.Synonyms)
+	addMissing(&m.abbreviations, inc.Match.Abbreviations)
+	addMissing(&m.templates, inc.Templates)
+	m.conflicts = append(m.conflicts, inc.IncludeConflicts...)
+}
+
+// apply merges the collected definitions into the including file mf. Its own
+// definitions take precedence over included ones with the same key, and
+// included definitions keep their include order after them.
+func (m *includeMerger) apply(mf *MappingFile) {
+	mf.TypeMappings = m.mappings.merge(mf.TypeMappings, (*TypeMapping).Key, &m.conflicts)
+	mf.Transforms = m.transforms.merge(mf.Transforms, func(t *TransformDef) string { return t.Name }, &m.conflicts)
+	mf.Wrappers = m.wrappers.merge(mf.Wrappers, func(w *WrapperDef) string { return w.Type }, &m.conflicts)
+	mf.Implementations = m.implementations.merge(mf.Implementations,
+		func(impl *ImplementationDef) string { return impl.Source + "->" + impl.Target }, &m.conflicts)
+	mf.IncludeConflicts = append(mf.IncludeConflicts, m.conflicts...)
+
+	for _, key := range m.ignoreTags {
+		if !slices.Contains(mf.IgnoreTags, key) {
+			mf.IgnoreTags = append(mf.IgnoreTags, key)
+		}
+	}
+
+	for _, pattern := range m.ignorePatterns {
+		if !slices.Contains(mf.IgnorePatterns, pattern) {
+			mf.IgnorePatterns = append(mf.IgnorePatterns, pattern)
+		}
+	}
+
+	addMissing(&mf.Match.Synonyms, m.synonyms)
+	addMissing(&mf.Match.Abbreviations, m.abbreviations)
+	addMissing(&mf.Templates, m.templates)
+
+	for _, affix := range m.stripAffixes {
+		if !slices.Contains(mf.StripAffixes, affix) {
+			mf.StripAffixes = append(mf.StripAffixes, affix)
+		}
+	}
+}
+
+// addMissing adds the entries of src whose key *dst lacks to *dst.
+func addMissing[V any](dst *map[string]V, src map[string]V) {
+	for key, value := range src {
+		if _, ok := (*dst)[key]; !ok {
+			if *dst == nil {
+				*dst = make(map[string]V)
+			}
+
+			(*dst)[key] = value
+		}
+	}
+}
+
+// mergeIncludedMappings applies the include precedence of LoadFile to type
+// mappings naming the same pair differently (e.g., "store.Order" and
+// "example.com/store.Order"), which only graph can tell apart: the first
+// mapping of a pair is kept and later included ones are dropped, conflicting
+// when both are included and differ. Conflicts of pairs the including file
+// defines are resolved.
+func mergeIncludedMappings(mf *MappingFile, graph *analyze.TypeGraph) {
+	type typePair [2]*analyze.TypeInfo
+
+	resolve := func(source, target string) (typePair, bool) {
+		pair := typePair{ResolveTypeID(source, graph), ResolveTypeID(target, graph)}
+		return pair, pair[0] != nil && pair[1] != nil
+	}
+
+	first := make(map[typePair]int)
+	kept := make([]TypeMapping, 0, len(mf.TypeMappings))
+
+	for _, tm := range mf.TypeMappings {
+		pair, ok := resolve(tm.Source, tm.Target)
+		if tm.IsPackageMapping() || !ok {
+			kept = append(kept, tm)
+			continue
+		}
+
+		i, seen := first[pair]
+		if !seen || tm.includedFrom == "" {
+			first[pair] = len(kept)
+			kept = append(kept, tm)
+
+			continue
+		}
+
+		if prev := &kept[i]; prev.includedFrom != "" && !sameMapping(*prev, tm) {
+			addIncludeConflict(&mf.IncludeConflicts, prev, tm.includedFrom)
+		}
+	}
+
+	mf.TypeMappings = kept
+
+	mf.IncludeConflicts = slices.DeleteFunc(mf.IncludeConflicts, func(c IncludeConflict) bool {
+		source, target, _ := strings.Cut(c.Key, "->")
+		pair, _ := resolve(source, target)
+		i, ok := first[pair]
+
+		return c.Kind == "mapping" && ok && kept[i].includedFrom == ""
+	})
+}
+
+// sameMapping reports whether two mappings read the same but for how they
+// spell their types.
+func sameMapping(a, b TypeMapping) bool {
+	a.Source, a.Target = "", ""
+	b.Source, b.Target = "", ""
+
+	return sameDef(a, b)
+}
+
+// addIncludeConflict records that file defines the pair of tm differently.
+func addIncludeConflict(conflicts *[]IncludeConflict, tm *TypeMapping, file string) {
+	for i := range *conflicts {
+		if c := &(*conflicts)[i]; c.Kind == "mapping" && c.Key == tm.Key() {
+			if !slices.Contains(c.Files, file) {
+				c.Files = append(c.Files, file)
+			}
+
+			return
+		}
+	}
+
+	*conflicts = append(*conflicts, IncludeConflict{Kind: "mapping", Key: tm.Key(), Files: []string{tm.includedFrom, file}})
+}
+
+// Key identifies a mapping by its type pair as written, or by its package
+// pair for package mappings.
+func (tm *TypeMapping) Key() string {
+	if tm.IsPackageMapping() {
+		return tm.SourcePkg + "/*->" + tm.TargetPkg + "/*"
+	}
+
+	return tm.Source + "->" + tm.Target
+}
+
+// keyedDefs collects included definitions by key, keeping the first one and
+// the files of differing ones.
+type keyedDefs[T any] struct {
+	kind  string
+	keys  []string
+	defs  map[string]T
+	files map[string][]string
+	clash map[string]bool
+}
+
+func (k *keyedDefs[T]) add(key string, def T, file string) {
+	if k.defs == nil {
+		k.defs = make(map[string]T)
+		k.files = make(map[string][]string)
+		k.clash = make(map[string]bool)
+	}
+
+	existing, ok := k.defs[key]
+	if !ok {
+		k.keys = append(k.keys, key)
+		k.defs[key] = def
+	} else if !sameDef(existing, def) {
+		k.clash[key] = true
+	}
+
+	k.files[key] = append(k.files[key], file)
+}
+
+// sameDef reports whether two definitions read the same, wherever they are
+// defined (their positions differ).
+func sameDef[T any](a, b T) bool {
+	yamlA, errA := yaml.Marshal(a)
+	yamlB, errB := yaml.Marshal(b)
+
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return bytes.Equal(yamlA, yamlB)
+}
+
+// merge appends the collected definitions whose key own doesn't define, and
+// records conflicts between included files the including file didn't resolve.
+func (k *keyedDefs[T]) merge(own []T, key func(*T) string, conflicts *[]IncludeConflict) []T {
+	defined := make(map[string]bool, len(own))
+	for i := range own {
+		defined[key(&own[i])] = true
+	}
+
+	// Conflicts of nested includes are resolved by defining the key here too.
+	*conflicts = slices.DeleteFunc(*conflicts, func(c IncludeConflict) bool {
+		return c.Kind == k.kind && defined[c.Key]
+	})
+
+	for _, name := range k.keys {
+		if defined[name] {
+			continue
+		}
+
+		if k.clash[name] {
+			*conflicts = append(*conflicts, IncludeConflict{Kind: k.kind, Key: name, Files: k.files[name]})
+		}
+
+		own = append(own, k.defs[name])
+	}
+
+	return own
+}
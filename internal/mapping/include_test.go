@@ -0,0 +1,253 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+)
+
+// writeFiles writes name -> content files under dir, creating directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func mappingKeys(mf *MappingFile) []string {
+	keys := make([]string, 0, len(mf.TypeMappings))
+	for i := range mf.TypeMappings {
+		keys = append(keys, mf.TypeMappings[i].Key())
+	}
+
+	return keys
+}
+
+func ignoreTargets(entries []IgnoreEntry) []string {
+	targets := make([]string, 0, len(entries))
+	for _, e := range entries {
+		targets = append(targets, e.Target)
+	}
+
+	return targets
+}
+
+func TestLoadFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"mapping.yaml": `
+include: ["mappings/*.yaml"]
+ignore_tags: [json]
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore: [Internal]
+transforms:
+  - name: Shared
+    source_type: int
+    target_type: string
+`,
+		"mappings/a.yaml": `
+copy_mode: deep
+strip_affixes: [DTO]
+ignore_patterns: ["XXX_*"]
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+  - source: store.Item
+    target: warehouse.Item
+transforms:
+  - name: Shared
+    source_type: int
+    target_type: int
+`,
+		"mappings/b.yaml": `
+include: [nested/c.yaml]
+ignore_tags: [caster, json]
+mappings:
+  - source: store.User
+    target: warehouse.User
+    copy_mode: alias
+`,
+		"mappings/nested/c.yaml": `
+mappings:
+  - source: store.Address
+    target: warehouse.Address
+`,
+	})
+
+	mf, err := LoadFile(filepath.Join(dir, "mapping.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"store.Order->warehouse.Order",
+		"store.Item->warehouse.Item",
+		"store.User->warehouse.User",
+		"store.Address->warehouse.Address",
+	}, mappingKeys(mf))
+	assert.Empty(t, mf.IncludeConflicts)
+
+	// The including file's definitions win.
+	assert.Equal(t, []string{"Internal"}, ignoreTargets(mf.TypeMappings[0].Ignore))
+	require.Len(t, mf.Transforms, 1)
+	assert.Equal(t, "string", mf.Transforms[0].TargetType)
+
+	// Included mappings keep their file's copy_mode.
+	assert.Equal(t, CopyDeep, mf.TypeMappings[1].CopyMode)
+	assert.Equal(t, CopyAlias, mf.TypeMappings[2].CopyMode)
+	assert.Equal(t, CopyDefault, mf.TypeMappings[3].CopyMode)
+
+	// ignore_tags, ignore_patterns and strip_affixes add up.
+	assert.Equal(t, []string{"json", "caster"}, mf.IgnoreTags)
+	assert.Equal(t, []string{"XXX_*"}, mf.IgnorePatterns)
+	assert.Equal(t, []string{"DTO"}, mf.StripAffixes)
+}
+
+func TestLoadFile_IncludeConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"mapping.yaml": `
+include: [a.yaml, b.yaml]
+`,
+		"a.yaml": `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore: [A]
+  - source: store.Item
+    target: warehouse.Item
+`,
+		"b.yaml": `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore: [B]
+  - source: store.Item
+    target: warehouse.Item
+`,
+	})
+
+	mf, err := LoadFile(filepath.Join(dir, "mapping.yaml"))
+	require.NoError(t, err)
+
+	// The first definition is kept; identical ones don't conflict.
+	assert.Equal(t, []string{"store.Order->warehouse.Order", "store.Item->warehouse.Item"}, mappingKeys(mf))
+	assert.Equal(t, []string{"A"}, ignoreTargets(mf.TypeMappings[0].Ignore))
+	assert.Equal(t, diagnostic.Position{File: filepath.Join(dir, "a.yaml"), Line: 3, Column: 5}, mf.TypeMappings[0].Pos)
+
+	require.Len(t, mf.IncludeConflicts, 1)
+	assert.Equal(t, "mapping", mf.IncludeConflicts[0].Kind)
+	assert.Equal(t, "store.Order->warehouse.Order", mf.IncludeConflicts[0].Key)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}, mf.IncludeConflicts[0].Files)
+
+	diags := Validate(mf, analyze.NewTypeGraph())
+
+	var codes []string
+	for _, e := range diags.Errors {
+		codes = append(codes, e.Code)
+	}
+
+	assert.Contains(t, codes, "include_conflict")
+
+	// Defining the pair in a file including both resolves the conflict.
+	writeFiles(t, dir, map[string]string{
+		"root.yaml": `
+include: [mapping.yaml]
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+`,
+	})
+
+	mf, err = LoadFile(filepath.Join(dir, "root.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, mf.IncludeConflicts)
+	assert.Empty(t, mf.TypeMappings[0].Ignore)
+}
+
+func TestLoadFile_IncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":      "include: [b.yaml]\n",
+		"b.yaml":      "include: [a.yaml]\n",
+		"none.yaml":   "include: [missing/*.yaml]\n",
+		"bad.yaml":    "include: [broken.yaml]\n",
+		"broken.yaml": "mappings: [\n",
+	})
+
+	_, err := LoadFile(filepath.Join(dir, "a.yaml"))
+	require.ErrorIs(t, err, ErrIncludeCycle)
+
+	_, err = LoadFile(filepath.Join(dir, "none.yaml"))
+	require.ErrorContains(t, err, "matches no files")
+
+	_, err = LoadFile(filepath.Join(dir, "bad.yaml"))
+	require.ErrorContains(t, err, "broken.yaml")
+}
+
+func TestExpandPackageMappings_MergesIncludedSpellings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"mapping.yaml": `
+include: [a.yaml, b.yaml, c.yaml]
+`,
+		"a.yaml": `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore: [Amount]
+`,
+		"b.yaml": `
+mappings:
+  - source: caster-generator/store.Order
+    target: caster-generator/warehouse.Order
+    ignore: [Amount]
+`,
+		"c.yaml": `
+mappings:
+  - source: caster-generator/store.Order
+    target: warehouse.Order
+    ignore: [Status]
+`,
+	})
+
+	mf, err := LoadFile(filepath.Join(dir, "mapping.yaml"))
+	require.NoError(t, err)
+	require.Len(t, mf.TypeMappings, 3)
+	assert.Empty(t, mf.IncludeConflicts)
+
+	graph := buildTestTypeGraph()
+	require.False(t, ExpandPackageMappings(mf, graph).HasErrors())
+
+	// b.yaml reads like a.yaml; c.yaml maps the same pair differently.
+	assert.Equal(t, []string{"store.Order->warehouse.Order"}, mappingKeys(mf))
+	require.Len(t, mf.IncludeConflicts, 1)
+	assert.Equal(t, "store.Order->warehouse.Order", mf.IncludeConflicts[0].Key)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "c.yaml")}, mf.IncludeConflicts[0].Files)
+
+	// Defining the pair in the including file, however spelled, resolves it.
+	writeFiles(t, dir, map[string]string{
+		"mapping.yaml": `
+include: [a.yaml, b.yaml, c.yaml]
+mappings:
+  - source: caster-generator/store.Order
+    target: caster-generator/warehouse.Order
+`,
+	})
+
+	mf, err = LoadFile(filepath.Join(dir, "mapping.yaml"))
+	require.NoError(t, err)
+	require.False(t, ExpandPackageMappings(mf, graph).HasErrors())
+
+	assert.Equal(t, []string{"caster-generator/store.Order->caster-generator/warehouse.Order"}, mappingKeys(mf))
+	assert.Empty(t, mf.IncludeConflicts)
+}
@@ -0,0 +1,211 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// schemaEnums are the values of the string types restricted to a set.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeFor[IntrospectionHint](): {string(HintDive), string(HintFinal)},
+	reflect.TypeFor[CopyMode]():          {string(CopyAlias), string(CopyShallow), string(CopyDeep)},
+	reflect.TypeFor[MergePolicy]():       {string(MergeOverwrite), string(MergeIfZero), string(MergeNever)},
+	reflect.TypeFor[PinPolicy]():         {string(PinError), string(PinTodo), string(PinFallbackAuto)},
+	reflect.TypeFor[FieldOrder](): {
+		string(FieldOrderYAML), string(FieldOrderAlphabetical), string(FieldOrderSource),
+	},
+}
+
+// schemaLiteralKeys are the keys holding Go literals or versions, which YAML
+// may write as numbers or booleans as well as strings.
+var schemaLiteralKeys = map[string]bool{
+	"version":      true,
+	"default":      true,
+	"const":        true,
+	"else_default": true,
+	"enum_default": true,
+	"null_default": true,
+	"enum_map":     true,
+}
+
+type jsonObject = map[string]any
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the mapping file
+// format, for editors to complete and validate mapping files. Like Parse, it
+// rejects unknown keys.
+func JSONSchema() ([]byte, error) {
+	b := &schemaBuilder{defs: make(jsonObject)}
+	root := b.schemaOf(reflect.TypeFor[MappingFile](), "")
+
+	schema := jsonObject{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "caster-generator mapping file",
+		"description": "Type mappings from which caster-generator generates casters.",
+		"$ref":        root["$ref"],
+		"$defs":       b.defs,
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON schema: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// schemaBuilder collects the definitions of the structs of the format.
+type schemaBuilder struct {
+	defs jsonObject
+}
+
+// schemaOf returns the schema of a value of type t, decoded under key.
+func (b *schemaBuilder) schemaOf(t reflect.Type, key string) jsonObject {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := schemaEnums[t]; ok {
+		return jsonObject{"type": "string", "enum": values}
+	}
+
+	if customYAML(t) {
+		return b.customSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return b.structRef(t)
+	case reflect.Slice, reflect.Array:
+		return jsonObject{"type": "array", "items": b.schemaOf(t.Elem(), key)}
+	case reflect.Map:
+		return jsonObject{"type": "object", "additionalProperties": b.schemaOf(t.Elem(), key)}
+	case reflect.String:
+		if schemaLiteralKeys[key] {
+			return literalSchema()
+		}
+
+		return jsonObject{"type": "string"}
+	case reflect.Bool:
+		return jsonObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonObject{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonObject{"type": "number"}
+	default:
+		return jsonObject{}
+	}
+}
+
+// structRef defines struct type t, rejecting unknown keys, and returns a reference to it.
+func (b *schemaBuilder) structRef(t reflect.Type) jsonObject {
+	ref := jsonObject{"$ref": "#/$defs/" + t.Name()}
+	if _, ok := b.defs[t.Name()]; ok {
+		return ref
+	}
+
+	properties := make(jsonObject)
+	def := jsonObject{"type": "object", "properties": properties, "additionalProperties": false}
+	b.defs[t.Name()] = def
+
+	var required []string
+
+	for _, f := range yamlFields(t) {
+		properties[f.Key] = b.schemaOf(f.Type, f.Key)
+	}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || strings.Contains(opts, "omitempty") || strings.Contains(opts, "inline") {
+			continue
+		}
+
+		if k := f.Type.Kind(); k != reflect.Slice && k != reflect.Map {
+			required = append(required, name)
+		}
+	}
+
+	if len(required) > 0 {
+		def["required"] = required
+	}
+
+	return ref
+}
+
+// customSchema returns the schema of a type decoding itself, after the
+// layouts its UnmarshalYAML accepts.
+func (b *schemaBuilder) customSchema(t reflect.Type) jsonObject {
+	stringSchema := jsonObject{"type": "string"}
+
+	switch t {
+	case reflect.TypeFor[StringOrArray](), reflect.TypeFor[StringArray]():
+		return anyOf(stringSchema, jsonObject{"type": "array", "items": stringSchema})
+
+	case reflect.TypeFor[FieldRefArray](), reflect.TypeFor[FieldRefOrString]():
+		// A path, or {path: hint}.
+		ref := anyOf(stringSchema, jsonObject{
+			"type":                 "object",
+			"minProperties":        1,
+			"maxProperties":        1,
+			"additionalProperties": b.schemaOf(reflect.TypeFor[IntrospectionHint](), ""),
+		})
+		if t == reflect.TypeFor[FieldRefOrString]() {
+			return ref
+		}
+
+		return anyOf(ref, jsonObject{"type": "array", "items": ref})
+
+	case reflect.TypeFor[ExtraVals]():
+		// A name, a list of names or of {name, def} and {name, const}, or a
+		// map of names to sources, or to {source}, {target} or {const}.
+		def := jsonObject{
+			"type":                 "object",
+			"properties":           jsonObject{"source": stringSchema, "target": stringSchema, "const": literalSchema()},
+			"additionalProperties": false,
+		}
+
+		return anyOf(
+			stringSchema,
+			jsonObject{"type": "array", "items": stringSchema},
+			jsonObject{"type": "array", "items": b.schemaOf(reflect.TypeFor[ExtraVal](), "")},
+			jsonObject{"type": "object", "additionalProperties": anyOf(stringSchema, def)},
+		)
+
+	case reflect.TypeFor[ExtraConst]():
+		return literalSchema()
+
+	case reflect.TypeFor[IgnoreEntry]():
+		// A path, or {target, reason, until, ticket}.
+		return anyOf(stringSchema, b.structRef(t))
+
+	case reflect.TypeFor[ArgDefArray]():
+		// A name, {name, type} with an optional type, or {name: type}.
+		return jsonObject{"type": "array", "items": anyOf(
+			stringSchema,
+			jsonObject{
+				"type":                 "object",
+				"properties":           jsonObject{"name": stringSchema, "type": stringSchema},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+			jsonObject{"type": "object", "minProperties": 1, "maxProperties": 1, "additionalProperties": stringSchema},
+		)}
+
+	default:
+		return jsonObject{}
+	}
+}
+
+// literalSchema accepts any scalar.
+func literalSchema() jsonObject {
+	return jsonObject{"type": []string{"string", "number", "integer", "boolean"}}
+}
+
+// anyOf returns a schema matching any of schemas.
+func anyOf(schemas ...jsonObject) jsonObject {
+	return jsonObject{"anyOf": schemas}
+}
@@ -1,31 +1,71 @@
 package mapping
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"reflect"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
 
-// LoadFile loads and parses a YAML mapping file from the given path.
+// LoadFile loads and parses a YAML mapping file from the given path, merging
+// the files it includes (see MappingFile.Include).
 func LoadFile(path string) (*MappingFile, error) {
-	data, err := os.ReadFile(path)
+	mf, err := loadFile(path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read mapping file %s: %w", path, err)
+		return nil, err
 	}
 
-	return Parse(data)
+	if err := expandTemplates(mf); err != nil {
+		return nil, err
+	}
+
+	return mf, nil
 }
 
-// Parse parses YAML data into a MappingFile.
+// Parse parses YAML data into a MappingFile. Keys the format doesn't know,
+// such as typos, fail with their line and column, and the entries of the
+// mapping record theirs (see TypeMapping.Pos).
 func Parse(data []byte) (*MappingFile, error) {
-	var mf MappingFile
-
-	err := yaml.Unmarshal(data, &mf)
+	mf, err := parse(data, "")
 	if err != nil {
+		return nil, err
+	}
+
+	if err := expandTemplates(mf); err != nil {
+		return nil, err
+	}
+
+	return mf, nil
+}
+
+// parse parses YAML data read from file, which positions refer to.
+func parse(data []byte, file string) (*MappingFile, error) {
+	var (
+		mf  MappingFile
+		doc yaml.Node
+	)
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse mapping YAML: %w", err)
 	}
 
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&mf); err != nil {
+			return nil, fmt.Errorf("failed to parse mapping YAML: %w", err)
+		}
+
+		root := doc.Content[0]
+		if errs := unknownKeys(root, reflect.TypeFor[MappingFile](), ""); len(errs) > 0 {
+			return nil, fmt.Errorf("failed to parse mapping YAML: %w", errors.Join(errs...))
+		}
+
+		setPositions(root, reflect.ValueOf(&mf), file)
+	}
+
 	// Apply defaults and normalize
 	applyDefaults(&mf)
 
@@ -40,7 +80,7 @@ func applyDefaults(mf *MappingFile) {
 
 	for i := range mf.Transforms {
 		t := &mf.Transforms[i]
-		if t.Func == "" {
+		if t.Func == "" && t.Template == "" {
 			t.Func = t.Name
 		}
 	}
@@ -74,7 +114,8 @@ func NormalizeTypeMapping(tm *TypeMapping) {
 	if len(tm.OneToOne) > 0 {
 		expanded := make([]FieldMapping, 0, len(tm.OneToOne))
 
-		for source, target := range tm.OneToOne {
+		for _, source := range slices.Sorted(maps.Keys(tm.OneToOne)) {
+			target := tm.OneToOne[source]
 			expanded = append(expanded, FieldMapping{
 				Source: FieldRefArray{{Path: source, Hint: HintNone}},
 				Target: FieldRefArray{{Path: target, Hint: HintNone}},
@@ -1,10 +1,13 @@
 package mapping
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/diagnostic"
 )
 
 func TestParse(t *testing.T) {
@@ -58,7 +61,7 @@ transforms:
 	// Check field mappings
 	assert.Len(t, tm.Fields, 4)
 	assert.Len(t, tm.Ignore, 1)
-	assert.Equal(t, "InternalField", tm.Ignore[0])
+	assert.Equal(t, "InternalField", tm.Ignore[0].Target)
 
 	// Field with default
 	assert.Equal(t, "Status", tm.Fields[0].Target.First())
@@ -150,6 +153,187 @@ mappings:
 	}
 }
 
+func TestParseUnknownKeys(t *testing.T) {
+	yaml := `
+mappings:
+  - source: A
+    target: B
+    ignor:
+      - Internal
+    fields:
+      - target: Status
+        default: '"x"'
+        hint: dive
+      - target:
+          Items: dive
+        extra:
+          - name: ID
+            def:
+              source: ID
+transforms:
+  - name: T
+    source_type: int
+    target_type: int
+    colour: red
+`
+
+	_, err := Parse([]byte(yaml))
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), `line 5, column 5: unknown key "ignor" in mappings[0] (did you mean "ignore"?)`)
+	assert.Contains(t, err.Error(), `line 10, column 9: unknown key "hint" in mappings[0].fields[0]`)
+	assert.Contains(t, err.Error(), `line 21, column 5: unknown key "colour" in transforms[0]`)
+	assert.NotContains(t, err.Error(), `"hint" in mappings[0].fields[0] (did you mean`)
+	assert.NotContains(t, err.Error(), "Items")
+	assert.NotContains(t, err.Error(), "def")
+}
+
+func TestParseExtraConst(t *testing.T) {
+	yaml := `
+mappings:
+  - source: A
+    target: B
+    fields:
+      - source: At
+        target: At
+        transform: FormatTime
+        extra: [{name: layout, const: "2006-01-02"}, {name: utc, const: True}, ID, {name: zone, const: '"UTC"'}]
+      - source: Name
+        target: Name
+        transform: Pad
+        extra:
+          width: {const: 10}
+          fill: Fill
+          ratio: {const: 0.5}
+`
+
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	fields := mf.TypeMappings[0].Fields
+	assert.Equal(t, ExtraVals{
+		{Name: "layout", Const: `"2006-01-02"`},
+		{Name: "utc", Const: "true"},
+		{Name: "ID", Def: ExtraDef{Source: "ID"}},
+		// Go string literals aren't quoted again.
+		{Name: "zone", Const: `"UTC"`},
+	}, fields[0].Extra)
+
+	// Maps keep the order the extras are passed in.
+	assert.Equal(t, ExtraVals{
+		{Name: "width", Const: "10"},
+		{Name: "fill", Def: ExtraDef{Source: "Fill"}},
+		{Name: "ratio", Const: "0.5"},
+	}, fields[1].Extra)
+
+	assert.Equal(t, "string", fields[0].Extra[0].Const.DefaultType())
+	assert.Equal(t, "bool", fields[0].Extra[1].Const.DefaultType())
+	assert.Equal(t, "int", fields[1].Extra[0].Const.DefaultType())
+	assert.Equal(t, "float64", fields[1].Extra[2].Const.DefaultType())
+
+	_, err = Parse([]byte("mappings:\n  - source: A\n    target: B\n    fields:\n" +
+		"      - source: A\n        target: B\n        extra: [{name: x, const: [1]}]\n"))
+	require.Error(t, err)
+}
+
+func TestParseGeneratedTypes(t *testing.T) {
+	yaml := `
+generated_types:
+  field_order: source
+  field_docs: true
+mappings:
+  - source: A
+    target: B
+    generate_target: true
+    121:
+      Name: Name
+      ID: Key
+`
+
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	assert.Equal(t, GeneratedTypes{FieldOrder: FieldOrderSource, FieldDocs: true}, mf.GeneratedTypes)
+	assert.Equal(t, map[string]diagnostic.Position{
+		"Name": {Line: 10, Column: 7},
+		"ID":   {Line: 11, Column: 7},
+	}, mf.TypeMappings[0].OneToOnePos)
+
+	mf.GeneratedTypes.FieldOrder = "random"
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.NotEmpty(t, result.Errors)
+	assert.Equal(t, "invalid_field_order", result.Errors[0].Code)
+}
+
+func TestParseIgnoreEntries(t *testing.T) {
+	yaml := `
+mappings:
+  - source: A
+    target: B
+    ignore:
+      - Notes
+      - {target: Internal, reason: "filled by the UI", until: 2025-06-30, ticket: SHOP-42}
+`
+
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	assert.Equal(t, []IgnoreEntry{
+		{Target: "Notes", Pos: diagnostic.Position{Line: 6, Column: 9}},
+		{
+			Target: "Internal", Reason: "filled by the UI", Until: "2025-06-30", Ticket: "SHOP-42",
+			Pos: diagnostic.Position{Line: 7, Column: 9},
+		},
+	}, mf.TypeMappings[0].Ignore)
+
+	// Entries without metadata are written back as paths.
+	out, err := Marshal(mf)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "- Notes\n")
+	assert.Contains(t, string(out), "reason: filled by the UI")
+
+	_, err = Parse([]byte("mappings:\n  - source: A\n    target: B\n    ignore:\n      - {target: X, reson: y}\n"))
+	require.ErrorContains(t, err, `unknown key "reson" in ignore entry (did you mean "reason"?)`)
+
+	_, err = Parse([]byte("mappings:\n  - source: A\n    target: B\n    ignore:\n      - {reason: y}\n"))
+	require.ErrorContains(t, err, "ignore entry needs a target")
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Ref  string `json:"$ref"`
+		Defs map[string]struct {
+			Properties           map[string]any `json:"properties"`
+			Required             []string       `json:"required"`
+			AdditionalProperties *bool          `json:"additionalProperties"`
+		} `json:"$defs"`
+	}
+
+	require.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, "#/$defs/MappingFile", schema.Ref)
+
+	root := schema.Defs["MappingFile"]
+	assert.Contains(t, root.Properties, "mappings")
+	assert.Contains(t, root.Properties, "include")
+	assert.NotContains(t, root.Properties, "IncludeConflicts")
+
+	tm := schema.Defs["TypeMapping"]
+	assert.Contains(t, tm.Properties, "ignore")
+	assert.Equal(t, []string{"target"}, schema.Defs["IgnoreEntry"].Required)
+	assert.Contains(t, tm.Properties, "121")
+	assert.NotContains(t, tm.Properties, "OneToOnePos")
+	require.NotNil(t, tm.AdditionalProperties)
+	assert.False(t, *tm.AdditionalProperties)
+
+	assert.Equal(t, []string{"name", "source_type", "target_type"}, schema.Defs["TransformDef"].Required)
+	assert.Equal(t, map[string]any{"type": "string", "enum": []any{"alias", "shallow", "deep"}},
+		schema.Defs["FieldMapping"].Properties["copy"])
+}
+
 func TestParsePath(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -196,6 +380,33 @@ func TestParsePath(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "Items[0].ID",
+			expected: FieldPath{
+				Segments: []PathSegment{
+					{Name: "Items", Key: "0"},
+					{Name: "ID"},
+				},
+			},
+		},
+		{
+			input: `Attrs["a.b]"]`,
+			expected: FieldPath{
+				Segments: []PathSegment{{Name: "Attrs", Key: `"a.b]"`}},
+			},
+		},
+		{
+			input:   "Items[x]",
+			wantErr: true,
+		},
+		{
+			input:   "Items[0",
+			wantErr: true,
+		},
+		{
+			input:   "Items[0]ID",
+			wantErr: true,
+		},
 		{
 			input:   "",
 			wantErr: true,
@@ -276,6 +487,11 @@ func TestFieldPathIsSimple(t *testing.T) {
 
 	slice := FieldPath{Segments: []PathSegment{{Name: "Items", IsSlice: true}}}
 	assert.False(t, slice.IsSimple())
+
+	keyed := FieldPath{Segments: []PathSegment{{Name: "Attrs", Key: `"color"`}}}
+	assert.False(t, keyed.IsSimple())
+	assert.True(t, keyed.IsKeyed())
+	assert.Equal(t, `Attrs["color"]`, keyed.String())
 }
 
 func TestMarshal(t *testing.T) {
@@ -607,3 +823,33 @@ func TestGetEffectiveHint(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldMappingIsPlain(t *testing.T) {
+	plain := func() *FieldMapping {
+		return &FieldMapping{
+			Source: FieldRefArray{{Path: "Name"}},
+			Target: FieldRefArray{{Path: "Label"}},
+			Pos:    diagnostic.Position{Line: 3},
+		}
+	}
+
+	assert.True(t, plain().IsPlain())
+
+	tests := map[string]func(fm *FieldMapping){
+		"two sources":  func(fm *FieldMapping) { fm.Source = append(fm.Source, FieldRef{Path: "ID"}) },
+		"hint":         func(fm *FieldMapping) { fm.Target[0].Hint = HintDive },
+		"transform":    func(fm *FieldMapping) { fm.Transform = "Upper" },
+		"omit_zero":    func(fm *FieldMapping) { fm.OmitZero = true },
+		"null_default": func(fm *FieldMapping) { fm.NullDefault = "0" },
+		"else_default": func(fm *FieldMapping) { fm.ElseDefault = `""` },
+		"enum_strict":  func(fm *FieldMapping) { fm.EnumStrict = true },
+		"optional":     func(fm *FieldMapping) { fm.OptionalSource = true },
+		"sunset":       func(fm *FieldMapping) { fm.Sunset = "2030-01-01" },
+	}
+
+	for name, set := range tests {
+		fm := plain()
+		set(fm)
+		assert.False(t, fm.IsPlain(), name)
+	}
+}
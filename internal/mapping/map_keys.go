@@ -0,0 +1,116 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"caster-generator/internal/analyze"
+)
+
+// MapKeyEntry is a key of a from_map_keys source map and the target field
+// its value is parsed into.
+type MapKeyEntry struct {
+	Key    string // Map key (e.g., "status")
+	Target string // Target field path (e.g., "Status")
+}
+
+// MapKeyEntries returns the entries of a from_map_keys table in a stable order.
+func MapKeyEntries(fromMapKeys map[string]string) []MapKeyEntry {
+	entries := make([]MapKeyEntry, 0, len(fromMapKeys))
+	for _, key := range SortedEnumKeys(fromMapKeys) {
+		entries = append(entries, MapKeyEntry{Key: key, Target: fromMapKeys[key]})
+	}
+
+	return entries
+}
+
+// errMapKeysSource is returned for from_map_keys sources that aren't maps of
+// strings keyed by strings.
+var errMapKeysSource = errors.New("from_map_keys requires a map source with string keys and values")
+
+// MapKeysValue returns the value type of a from_map_keys source map of type t:
+// a map whose keys and values have string underlying types.
+func MapKeysValue(t *analyze.TypeInfo) (*analyze.TypeInfo, error) {
+	u := t
+	for u != nil && u.Kind == analyze.TypeKindAlias && u.Underlying != nil {
+		u = u.Underlying
+	}
+
+	if u == nil || u.Kind != analyze.TypeKindMap || !isStringBased(u.KeyType) || !isStringBased(u.ElemType) {
+		return nil, errMapKeysSource
+	}
+
+	return u.ElemType, nil
+}
+
+// MapValueParse describes how a string map value is parsed into a target
+// field of a from_map_keys mapping.
+type MapValueParse struct {
+	// Field is the type the value is converted to: the field type, or the
+	// element type of a pointer field.
+	Field *analyze.TypeInfo
+	// Basic is the basic underlying type of Field (e.g., "int64").
+	Basic *analyze.TypeInfo
+	// Pointer is set for pointer fields, assigned the address of the value.
+	Pointer bool
+}
+
+// ParseFunc returns the strconv function parsing the value, with the
+// arguments following the string (e.g., "ParseInt", ", 10, 64"), or an empty
+// name for strings, which are only converted.
+func (p *MapValueParse) ParseFunc() (string, string) {
+	name := p.Basic.ID.Name
+
+	switch name {
+	case "byte":
+		name = "uint8"
+	case "rune":
+		name = "int32"
+	}
+
+	// The bit size of int, uint and uintptr is 0, their size on the platform.
+	bitSize := func(size string) string {
+		if size == "" || size == "ptr" {
+			return "0"
+		}
+
+		return size
+	}
+
+	switch {
+	case name == "string":
+		return "", ""
+	case name == "bool":
+		return "ParseBool", ""
+	case strings.HasPrefix(name, "float"):
+		return "ParseFloat", ", " + strings.TrimPrefix(name, "float")
+	case strings.HasPrefix(name, "uint"):
+		return "ParseUint", ", 10, " + bitSize(strings.TrimPrefix(name, "uint"))
+	default:
+		return "ParseInt", ", 10, " + bitSize(strings.TrimPrefix(name, "int"))
+	}
+}
+
+// NewMapValueParse returns how a string map value is parsed into a target
+// field of type t: a string, numeric or bool type, or a pointer to one.
+func NewMapValueParse(t *analyze.TypeInfo) (*MapValueParse, error) {
+	p := &MapValueParse{Field: t}
+	if t != nil && t.Kind == analyze.TypeKindPointer {
+		p.Field, p.Pointer = t.ElemType, true
+	}
+
+	p.Basic = enumBasicType(p.Field)
+	if p.Basic == nil || p.Basic.ID.Name == "complex64" || p.Basic.ID.Name == "complex128" {
+		return nil, fmt.Errorf("can't parse map values into %s fields (expected a string, numeric or bool type)",
+			typeName(t))
+	}
+
+	return p, nil
+}
+
+// isStringBased reports whether t has a string underlying type.
+func isStringBased(t *analyze.TypeInfo) bool {
+	basic := enumBasicType(t)
+	return basic != nil && basic.ID.Name == "string"
+}
@@ -0,0 +1,148 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestMapKeysValue(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	label := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Label"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: str,
+	}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+
+	elem, err := MapKeysValue(&analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: str, ElemType: label})
+	require.NoError(t, err)
+	assert.Same(t, label, elem)
+
+	// Named map types are looked through.
+	elem, err = MapKeysValue(&analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Params"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: &analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: label, ElemType: str},
+	})
+	require.NoError(t, err)
+	assert.Same(t, str, elem)
+
+	_, err = MapKeysValue(&analyze.TypeInfo{Kind: analyze.TypeKindMap, KeyType: str, ElemType: num})
+	require.Error(t, err)
+
+	_, err = MapKeysValue(str)
+	require.Error(t, err)
+}
+
+func TestMapValueParse_ParseFunc(t *testing.T) {
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+
+	tests := []struct {
+		typ  string
+		name string
+		args string
+	}{
+		{"string", "", ""},
+		{"bool", "ParseBool", ""},
+		{"int", "ParseInt", ", 10, 0"},
+		{"int64", "ParseInt", ", 10, 64"},
+		{"rune", "ParseInt", ", 10, 32"},
+		{"uint16", "ParseUint", ", 10, 16"},
+		{"byte", "ParseUint", ", 10, 8"},
+		{"uintptr", "ParseUint", ", 10, 0"},
+		{"float32", "ParseFloat", ", 32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			p, err := NewMapValueParse(basic(tt.typ))
+			require.NoError(t, err)
+
+			name, args := p.ParseFunc()
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestNewMapValueParse(t *testing.T) {
+	i64 := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic}
+	count := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "example/store", Name: "Count"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: i64,
+	}
+
+	p, err := NewMapValueParse(&analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: count})
+	require.NoError(t, err)
+	assert.True(t, p.Pointer)
+	assert.Same(t, count, p.Field)
+	assert.Equal(t, "int64", p.Basic.ID.Name)
+
+	_, err = NewMapValueParse(&analyze.TypeInfo{ID: analyze.TypeID{Name: "complex128"}, Kind: analyze.TypeKindBasic})
+	require.Error(t, err)
+
+	_, err = NewMapValueParse(&analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: i64})
+	assert.ErrorContains(t, err, "can't parse map values")
+}
+
+func TestValidate_FromMapKeys(t *testing.T) {
+	graph := buildTestTypeGraph()
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	src := graph.Types[analyze.TypeID{PkgPath: "caster-generator/store", Name: "Order"}]
+	src.Fields = append(src.Fields, analyze.FieldInfo{
+		Name: "Params", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+		},
+	})
+
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: Params
+        from_map_keys:
+          id: ID
+          amount: Amount
+      - source: Params
+        from_map_keys:
+          status: Status
+          state: Status
+          name: Nope
+      - source: Params
+        target: Customer
+        from_map_keys:
+          customer: Customer
+      - source: Price
+        from_map_keys:
+          price: FullName
+      - source: [Params, OrderID]
+        from_map_keys:
+          display: DisplayName
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, graph)
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.FieldPath+": "+e.Code)
+	}
+
+	assert.Equal(t, []string{
+		"Nope: invalid_target_path",
+		"Status: duplicate_from_map_keys_target",
+		"Params: conflicting_from_map_keys",
+		"Price: from_map_keys_unsupported_type",
+		"Params: invalid_from_map_keys",
+	}, codes)
+}
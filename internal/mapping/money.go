@@ -0,0 +1,226 @@
+package mapping
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
+)
+
+// Names of the builtin money transforms, converting between integer minor
+// units (e.g., cents) and decimal types with the scale of the file's currency.
+const (
+	TransformCentsToDecimal = "CentsToDecimal"
+	TransformDecimalToCents = "DecimalToCents"
+	TransformCentsToRat     = "CentsToRat"
+	TransformRatToCents     = "RatToCents"
+)
+
+// Currency configures the builtin money transforms.
+type Currency struct {
+	// Code is the ISO 4217 code of the amounts (e.g., "USD" or "JPY"), which
+	// sets MinorUnits for common currencies.
+	Code string `yaml:"code,omitempty"`
+
+	// MinorUnits is the number of decimal digits of the minor unit: 2 for
+	// cents (default: that of Code, or else 2).
+	MinorUnits *int `yaml:"minor_units,omitempty"`
+}
+
+// currencyMinorUnits are the minor units of common currencies by code.
+var currencyMinorUnits = map[string]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PLN": 2, "RUB": 2, "SEK": 2, "SGD": 2, "TND": 3,
+	"TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// maxMinorUnits keeps the scale of the minor unit within an int64.
+const maxMinorUnits = 18
+
+// currencyCode matches ISO 4217 codes.
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// IsZero reports whether c sets nothing.
+func (c *Currency) IsZero() bool {
+	return c.Code == "" && c.MinorUnits == nil
+}
+
+// Digits returns the number of decimal digits of the minor unit.
+func (c *Currency) Digits() int {
+	if c.MinorUnits != nil {
+		return *c.MinorUnits
+	}
+
+	if digits, ok := currencyMinorUnits[c.Code]; ok {
+		return digits
+	}
+
+	return 2
+}
+
+// Transforms returns the builtin money transforms, as template transforms
+// scaled by the minor unit of c. Transforms the file declares replace them.
+func (c *Currency) Transforms() []TransformDef {
+	digits := c.Digits()
+	scale := "1" + strings.Repeat("0", digits)
+
+	return []TransformDef{
+		{
+			Name:        TransformCentsToDecimal,
+			Description: "Converts integer minor units to a decimal.Decimal",
+			Template:    fmt.Sprintf("decimal.New(int64({{.Source}}), %d)", -digits),
+			Imports:     []string{"github.com/shopspring/decimal"},
+		},
+		{
+			Name:        TransformDecimalToCents,
+			Description: "Converts a decimal.Decimal to int64 minor units, rounding half away from zero",
+			Template:    fmt.Sprintf("{{.Source}}.Shift(%d).Round(0).IntPart()", digits),
+		},
+		{
+			Name:        TransformCentsToRat,
+			Description: "Converts integer minor units to a *big.Rat",
+			Template:    fmt.Sprintf("big.NewRat(int64({{.Source}}), %s)", scale),
+			Imports:     []string{"math/big"},
+		},
+		{
+			Name:        TransformRatToCents,
+			Description: "Converts a *big.Rat to int64 minor units, rounding half away from zero",
+			Template: "func() int64 {\n" +
+				"\tif {{.Source}} == nil {\n\t\treturn 0\n\t}\n\n" +
+				"\tcents, _ := strconv.ParseInt(new(big.Rat).Mul({{.Source}}, big.NewRat(" + scale +
+				", 1)).FloatString(0), 10, 64)\n\n" +
+				"\treturn cents\n}()",
+			Imports: []string{"math/big", "strconv"},
+		},
+	}
+}
+
+// isMoneyTransform reports whether name is a builtin money transform.
+func isMoneyTransform(name string) bool {
+	switch name {
+	case TransformCentsToDecimal, TransformDecimalToCents, TransformCentsToRat, TransformRatToCents:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateCurrency validates the currency block of a mapping file.
+func validateCurrency(res *diagnostic.Diagnostics, c *Currency) {
+	if c.Code != "" && !currencyCode.MatchString(c.Code) {
+		res.AddError("invalid_currency",
+			fmt.Sprintf("invalid currency code %q (expected an ISO 4217 code, e.g. USD)", c.Code), "", "currency.code")
+	}
+
+	switch {
+	case c.MinorUnits != nil && (*c.MinorUnits < 0 || *c.MinorUnits > maxMinorUnits):
+		res.AddError("invalid_currency",
+			fmt.Sprintf("invalid minor_units %d (expected 0 to %d)", *c.MinorUnits, maxMinorUnits), "",
+			"currency.minor_units")
+	case c.MinorUnits == nil && c.Code != "" && currencyCode.MatchString(c.Code):
+		if _, ok := currencyMinorUnits[c.Code]; !ok {
+			res.AddError("invalid_currency",
+				fmt.Sprintf("currency %s has no known minor unit; set minor_units", c.Code), "", "currency")
+		}
+	}
+}
+
+// validateMoneyTransform checks a field mapping using a builtin money
+// transform the file doesn't declare: it converts a single source between
+// integer minor units and the decimal type the transform is named after.
+func validateMoneyTransform(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+	knownTransforms map[string]struct{},
+) {
+	if !isMoneyTransform(fm.Transform) || fm.IsWildcard() {
+		return
+	}
+
+	if _, declared := knownTransforms[fm.Transform]; declared {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if len(fm.Source) != 1 || len(fm.Target) != 1 || len(fm.Extra) > 0 {
+		res.AddError("invalid_money_transform",
+			fmt.Sprintf("transform %s takes exactly one source and one target, without extra", fm.Transform),
+			typePairStr, target)
+
+		return
+	}
+
+	st, srcErr := resolvePathType(fm.Source[0].Path, srcT)
+	tt, tgtErr := resolvePathType(target, dstT)
+
+	if srcErr != nil || tgtErr != nil || st == nil || tt == nil || st.GoType == nil || tt.GoType == nil {
+		return
+	}
+
+	var from, to string
+
+	switch fm.Transform {
+	case TransformCentsToDecimal:
+		from = moneyInteger(st.GoType, "source")
+		to = moneyDecimal(tt.GoType, match.DecimalShopspring, false, "target")
+	case TransformDecimalToCents:
+		from = moneyDecimal(st.GoType, match.DecimalShopspring, false, "source")
+		to = moneyInteger(tt.GoType, "target")
+	case TransformCentsToRat:
+		from = moneyInteger(st.GoType, "source")
+		to = moneyDecimal(tt.GoType, match.DecimalRat, true, "target")
+	case TransformRatToCents:
+		from = moneyDecimal(st.GoType, match.DecimalRat, true, "source")
+		to = moneyInteger(tt.GoType, "target")
+	}
+
+	for _, problem := range []string{from, to} {
+		if problem != "" {
+			res.AddError("invalid_money_transform", fmt.Sprintf("transform %s: %s", fm.Transform, problem),
+				typePairStr, target)
+		}
+	}
+}
+
+// moneyInteger returns why t can't hold minor units on side ("source" or
+// "target"), or "": any integer source, or an int64 target.
+func moneyInteger(t types.Type, side string) string {
+	if side == "target" {
+		if !types.Identical(types.Unalias(t), types.Typ[types.Int64]) {
+			return fmt.Sprintf("needs an int64 target, not %s", t)
+		}
+
+		return ""
+	}
+
+	if basic, ok := t.Underlying().(*types.Basic); !ok || basic.Info()&types.IsInteger == 0 {
+		return fmt.Sprintf("needs an integer source, not %s", t)
+	}
+
+	return ""
+}
+
+// moneyDecimal returns why t isn't the decimal type of kind on side ("source"
+// or "target"), or a pointer to it if pointer is set, or "".
+func moneyDecimal(t types.Type, kind match.DecimalKind, pointer bool, side string) string {
+	_, isPointer := types.Unalias(t).(*types.Pointer)
+
+	want := kind.String()
+	if pointer {
+		want = "*" + want
+	}
+
+	if match.DecimalKindOf("", t) != kind || isPointer != pointer {
+		return fmt.Sprintf("needs a %s %s, not %s", want, side, t)
+	}
+
+	return ""
+}
@@ -0,0 +1,70 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"text/template"
+)
+
+// DefaultFuncTemplate is the naming template of casters when none is set
+// (e.g., "StoreOrderToWarehouseOrder").
+const DefaultFuncTemplate = "{{.SourceType}}To{{.TargetType}}"
+
+// Naming customizes the names of generated functions.
+type Naming struct {
+	// Func is the text/template naming casters and nested casters, executed
+	// on a FuncName (e.g., "Map{{.SourceName}}To{{.TargetName}}").
+	Func string `yaml:"func,omitempty"`
+}
+
+// FuncName holds the fragments of a caster name available to the naming
+// template. Package fragments are capitalized and type names carry the type
+// arguments of generic instantiations.
+type FuncName struct {
+	// SourceType and TargetType are the package and type name (e.g., "StoreOrder").
+	SourceType string
+	TargetType string
+	// SourcePkg and TargetPkg are the package names (e.g., "Store").
+	SourcePkg string
+	TargetPkg string
+	// SourceName and TargetName are the type names (e.g., "Order").
+	SourceName string
+	TargetName string
+}
+
+// ParseFuncTemplate parses a caster naming template, checking it names a
+// sample caster with a Go identifier.
+func ParseFuncTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("func").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template: %w", err)
+	}
+
+	sample := FuncName{
+		SourceType: "StoreOrder", TargetType: "WarehouseOrder",
+		SourcePkg: "Store", TargetPkg: "Warehouse",
+		SourceName: "Order", TargetName: "Order",
+	}
+
+	name, err := ExecuteFuncTemplate(tmpl, sample)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("naming template %q yields %q, not a Go identifier", text, name)
+	}
+
+	return tmpl, nil
+}
+
+// ExecuteFuncTemplate names the caster described by name with tmpl.
+func ExecuteFuncTemplate(tmpl *template.Template, name FuncName) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, name); err != nil {
+		return "", fmt.Errorf("invalid naming template: %w", err)
+	}
+
+	return buf.String(), nil
+}
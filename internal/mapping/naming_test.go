@@ -0,0 +1,26 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFuncTemplate(t *testing.T) {
+	tmpl, err := ParseFuncTemplate("Map{{.SourceName}}To{{.TargetPkg}}{{.TargetName}}")
+	require.NoError(t, err)
+
+	name, err := ExecuteFuncTemplate(tmpl, FuncName{SourceName: "Order", TargetPkg: "Api", TargetName: "Order"})
+	require.NoError(t, err)
+	assert.Equal(t, "MapOrderToApiOrder", name)
+
+	_, err = ParseFuncTemplate("{{.SourceType}")
+	assert.ErrorContains(t, err, "invalid naming template")
+
+	_, err = ParseFuncTemplate("{{.SourceType}}-{{.TargetType}}")
+	assert.ErrorContains(t, err, `yields "StoreOrder-WarehouseOrder", not a Go identifier`)
+
+	_, err = ParseFuncTemplate("{{.Source}}")
+	assert.ErrorContains(t, err, "invalid naming template")
+}
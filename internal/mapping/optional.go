@@ -0,0 +1,24 @@
+package mapping
+
+import "caster-generator/internal/analyze"
+
+// MissingOptionalSource returns the first source path of fm, an optional_source
+// field mapping of tm, that srcT doesn't have yet, or "" if all sources resolve
+// (or fm isn't optional). Paths starting with a required argument always resolve.
+func MissingOptionalSource(tm *TypeMapping, fm *FieldMapping, srcT *analyze.TypeInfo) string {
+	if !fm.OptionalSource || fm.HasValue() {
+		return ""
+	}
+
+	for _, s := range fm.Source {
+		if s.Path == "" || isRequiredArg(s.Path, tm) {
+			continue
+		}
+
+		if err := validatePathAgainstType(s.Path, srcT, tm.AllowUnexported); err != nil {
+			return s.Path
+		}
+	}
+
+	return ""
+}
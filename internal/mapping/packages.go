@@ -0,0 +1,294 @@
+package mapping
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
+)
+
+// minTypeNameScore is the minimum normalized name similarity for pairing
+// differently named structs of a package mapping (e.g., Order and Orders).
+const minTypeNameScore = 0.8
+
+// IsPackageMapping reports whether tm maps whole packages (source_pkg and
+// target_pkg) rather than a single type pair.
+func (tm *TypeMapping) IsPackageMapping() bool {
+	return tm.SourcePkg != "" || tm.TargetPkg != ""
+}
+
+// isPackageMapping is TypeMapping.IsPackageMapping for slices of mappings.
+func isPackageMapping(tm TypeMapping) bool {
+	return tm.IsPackageMapping()
+}
+
+// ExpandPackageMappings replaces every package mapping of mf with one type
+// mapping per struct pair of its packages, in place. Structs are paired by
+// name first, then by the closest name normalized with the strip_affixes and
+// abbreviations of mf; pairs already mapped by a
+// type mapping of mf are left to it. Malformed package mappings are reported
+// as errors and structs left without a pair as warnings.
+//
+// Included type mappings are first merged by the pairs their types resolve
+// to, not as written (see MappingFile.Include).
+func ExpandPackageMappings(mf *MappingFile, graph *analyze.TypeGraph) *diagnostic.Diagnostics {
+	res := &diagnostic.Diagnostics{}
+	if mf == nil || graph == nil {
+		return res
+	}
+
+	mergeIncludedMappings(mf, graph)
+
+	if !slices.ContainsFunc(mf.TypeMappings, isPackageMapping) {
+		return res
+	}
+
+	explicit := make(map[[2]*analyze.TypeInfo]bool)
+
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+		if !tm.IsPackageMapping() {
+			explicit[[2]*analyze.TypeInfo{ResolveTypeID(tm.Source, graph), ResolveTypeID(tm.Target, graph)}] = true
+		}
+	}
+
+	expanded := make([]TypeMapping, 0, len(mf.TypeMappings))
+
+	for _, tm := range mf.TypeMappings {
+		if !tm.IsPackageMapping() {
+			expanded = append(expanded, tm)
+			continue
+		}
+
+		pkgPair := fmt.Sprintf("%s->%s", tm.SourcePkg, tm.TargetPkg)
+
+		if err := checkPackageMapping(&tm); err != nil {
+			res.AddError("invalid_package_mapping", err.Error(), pkgPair, "")
+			continue
+		}
+
+		srcPkg, tgtPkg := findPackage(tm.SourcePkg, graph), findPackage(tm.TargetPkg, graph)
+		if srcPkg == nil || tgtPkg == nil {
+			missing := tm.SourcePkg
+			if srcPkg != nil {
+				missing = tm.TargetPkg
+			}
+
+			res.AddError("package_not_found", fmt.Sprintf("package %q not found", missing), pkgPair, missing)
+
+			continue
+		}
+
+		pairs, unpaired := pairStructs(packageStructs(srcPkg, graph), packageStructs(tgtPkg, graph), mf.Normalizer())
+
+		for _, p := range pairs {
+			if explicit[p] {
+				continue
+			}
+
+			expanded = append(expanded, TypeMapping{
+				Source:   p[0].ID.String(),
+				Target:   p[1].ID.String(),
+				Tags:     tm.Tags,
+				Requires: tm.Requires,
+				CopyMode: tm.CopyMode,
+				MaxDepth: tm.MaxDepth,
+				Matching: tm.Matching,
+				Output:   tm.Output,
+			})
+		}
+
+		for _, t := range unpaired {
+			res.AddWarning("unpaired_type",
+				fmt.Sprintf("struct %s has no matching struct in %s", t.ID, tgtPkg.Path), pkgPair, t.ID.Name)
+		}
+	}
+
+	mf.TypeMappings = expanded
+
+	return res
+}
+
+// checkPackageMapping reports a package mapping that declares anything besides
+// its packages and the options shared by its pairs.
+func checkPackageMapping(tm *TypeMapping) error {
+	switch {
+	case tm.SourcePkg == "" || tm.TargetPkg == "":
+		return errors.New("package mapping requires both source_pkg and target_pkg")
+	case !tm.MatchTypes:
+		return errors.New("package mapping requires match_types: true")
+	case tm.Source != "" || tm.Target != "":
+		return errors.New("package mapping can't declare source or target types")
+	case len(tm.OneToOne) > 0 || len(tm.PrefixMap) > 0 || len(tm.Fields) > 0 || len(tm.Use) > 0 ||
+		len(tm.Ignore) > 0 || len(tm.Auto) > 0 || tm.GenerateTarget:
+		return errors.New("package mapping can't declare fields; map the type pair explicitly instead")
+	case tm.Before != "" || tm.After != "" || tm.Method != "":
+		return errors.New("package mapping can't declare hooks or a method; map the type pair explicitly instead")
+	case tm.RequireFullCoverage:
+		return errors.New("package mapping can't require full coverage; map the type pair explicitly instead")
+	}
+
+	return nil
+}
+
+// findPackage returns the loaded package with import path pkg, or whose path
+// ends with it (e.g., "store" for "caster-generator/store").
+func findPackage(pkg string, graph *analyze.TypeGraph) *analyze.PackageInfo {
+	pkg = strings.TrimPrefix(pkg, "./")
+
+	if info, ok := graph.Packages[pkg]; ok {
+		return info
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(graph.Packages)) {
+		if strings.HasSuffix(path, "/"+pkg) {
+			return graph.Packages[path]
+		}
+	}
+
+	return nil
+}
+
+// packageStructs returns the non-generic structs declared by pkg, by name.
+func packageStructs(pkg *analyze.PackageInfo, graph *analyze.TypeGraph) []*analyze.TypeInfo {
+	var structs []*analyze.TypeInfo
+
+	for _, id := range pkg.Types {
+		if t := graph.GetType(id); t != nil && t.Kind == analyze.TypeKindStruct && !t.IsGeneric() {
+			structs = append(structs, t)
+		}
+	}
+
+	slices.SortFunc(structs, func(a, b *analyze.TypeInfo) int { return strings.Compare(a.ID.Name, b.ID.Name) })
+
+	return structs
+}
+
+// pairStructs pairs source structs with target structs of the same name, then
+// the remaining ones by decreasing similarity of their names normalized by n.
+// It returns the pairs in source name order and the source structs left
+// without a pair.
+func pairStructs(
+	sources, targets []*analyze.TypeInfo,
+	n match.Normalizer,
+) ([][2]*analyze.TypeInfo, []*analyze.TypeInfo) {
+	pairedTargets := make(map[*analyze.TypeInfo]bool)
+	bySource := make(map[*analyze.TypeInfo]*analyze.TypeInfo)
+
+	for _, s := range sources {
+		for _, t := range targets {
+			if s.ID.Name == t.ID.Name {
+				bySource[s], pairedTargets[t] = t, true
+			}
+		}
+	}
+
+	type candidate struct {
+		source, target *analyze.TypeInfo
+		score          float64
+	}
+
+	var candidates []candidate
+
+	for _, s := range sources {
+		if bySource[s] != nil {
+			continue
+		}
+
+		for _, t := range targets {
+			if pairedTargets[t] {
+				continue
+			}
+
+			if score := match.LevenshteinNormalized(n.Normalize(s.ID.Name), n.Normalize(t.ID.Name)); score >= minTypeNameScore {
+				candidates = append(candidates, candidate{source: s, target: t, score: score})
+			}
+		}
+	}
+
+	// Stable sort keeps name order among equal scores, so pairing is deterministic.
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return cmp.Compare(b.score, a.score) })
+
+	for _, c := range candidates {
+		if bySource[c.source] == nil && !pairedTargets[c.target] {
+			bySource[c.source], pairedTargets[c.target] = c.target, true
+		}
+	}
+
+	var (
+		pairs    [][2]*analyze.TypeInfo
+		unpaired []*analyze.TypeInfo
+	)
+
+	for _, s := range sources {
+		if t := bySource[s]; t != nil {
+			pairs = append(pairs, [2]*analyze.TypeInfo{s, t})
+		} else {
+			unpaired = append(unpaired, s)
+		}
+	}
+
+	return pairs, unpaired
+}
+
+// TypePackage returns the package of a qualified type or function name, in
+// short form (e.g., "store" for "store.Order") or as a full import path (e.g.,
+// "caster-generator/store" for "caster-generator/store.Product"), or "" if
+// the name is unqualified.
+func TypePackage(name string) string {
+	lastDot := strings.LastIndex(name, ".")
+	if lastDot == -1 {
+		return ""
+	}
+
+	return name[:lastDot]
+}
+
+// LoadPattern returns the package pattern loading the package of a type name:
+// short names are relative to the working directory ("./store"), while import
+// paths, of the main module or of a dependency ("github.com/acme/client"),
+// are kept.
+func LoadPattern(pkg string) string {
+	if pkg == "" || strings.Contains(pkg, "/") {
+		return pkg
+	}
+
+	return "./" + pkg
+}
+
+// Packages returns the packages to load for mf, sorted: those of its type
+// names and package mappings (see LoadPattern), and those of transforms
+// referring to functions by full import path.
+func Packages(mf *MappingFile) []string {
+	pkgSet := make(map[string]bool)
+
+	addPkg := func(pkg string) {
+		if pkg != "" {
+			pkgSet[LoadPattern(pkg)] = true
+		}
+	}
+
+	for _, tm := range mf.TypeMappings {
+		addPkg(TypePackage(tm.Source))
+		addPkg(TypePackage(tm.Target))
+		addPkg(tm.SourcePkg)
+		addPkg(tm.TargetPkg)
+	}
+
+	// Transforms referencing functions by full import path need their packages loaded too
+	for _, t := range mf.Transforms {
+		if t.Package != "" {
+			pkgSet[t.Package] = true
+		} else if pkg := TypePackage(t.Name); strings.Contains(pkg, "/") {
+			pkgSet[pkg] = true
+		}
+	}
+
+	return slices.Sorted(maps.Keys(pkgSet))
+}
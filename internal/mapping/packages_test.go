@@ -0,0 +1,151 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+// buildPackagesTypeGraph declares structs in mirrored store and api packages.
+func buildPackagesTypeGraph() *analyze.TypeGraph {
+	graph := analyze.NewTypeGraph()
+
+	declare := func(pkgPath string, names ...string) {
+		pkg := &analyze.PackageInfo{Path: pkgPath}
+
+		for _, name := range names {
+			id := analyze.TypeID{PkgPath: pkgPath, Name: name}
+			graph.Types[id] = &analyze.TypeInfo{ID: id, Kind: analyze.TypeKindStruct}
+			pkg.Types = append(pkg.Types, id)
+		}
+
+		graph.Packages[pkgPath] = pkg
+	}
+
+	declare("example/store", "Order", "Item", "Customer", "Ledger")
+	declare("example/api", "Order", "Items", "Customer", "Customers")
+
+	status := analyze.TypeID{PkgPath: "example/store", Name: "Status"}
+	graph.Types[status] = &analyze.TypeInfo{ID: status, Kind: analyze.TypeKindAlias}
+	graph.Packages["example/store"].Types = append(graph.Packages["example/store"].Types, status)
+
+	return graph
+}
+
+func TestExpandPackageMappings(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Customer
+    target: api.Customers
+    121:
+      Name: FullName
+  - source_pkg: store
+    target_pkg: example/api
+    match_types: true
+    tags: [sync]
+    copy_mode: deep
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	diags := ExpandPackageMappings(mf, buildPackagesTypeGraph())
+	require.Empty(t, diags.Errors)
+
+	var pairs []string
+	for _, tm := range mf.TypeMappings {
+		pairs = append(pairs, tm.Source+"->"+tm.Target)
+	}
+
+	// Same names pair first, then close names; Status isn't a struct.
+	assert.Equal(t, []string{
+		"store.Customer->api.Customers",
+		"example/store.Customer->example/api.Customer",
+		"example/store.Item->example/api.Items",
+		"example/store.Order->example/api.Order",
+	}, pairs)
+	assert.Equal(t, []string{"sync"}, mf.TypeMappings[1].Tags)
+	assert.Equal(t, CopyDeep, mf.TypeMappings[1].CopyMode)
+
+	require.Len(t, diags.Warnings, 1)
+	assert.Equal(t, "unpaired_type", diags.Warnings[0].Code)
+	assert.Equal(t, "Ledger", diags.Warnings[0].FieldPath)
+}
+
+func TestExpandPackageMappings_StripAffixes(t *testing.T) {
+	graph := buildPackagesTypeGraph()
+
+	for _, name := range []string{"OrderDTO", "LedgerModel"} {
+		id := analyze.TypeID{PkgPath: "example/dto", Name: name}
+		graph.Types[id] = &analyze.TypeInfo{ID: id, Kind: analyze.TypeKindStruct}
+	}
+
+	graph.Packages["example/dto"] = &analyze.PackageInfo{Path: "example/dto", Types: []analyze.TypeID{
+		{PkgPath: "example/dto", Name: "OrderDTO"},
+		{PkgPath: "example/dto", Name: "LedgerModel"},
+	}}
+
+	yaml := `
+strip_affixes: [DTO, Model, "Db Model"]
+mappings:
+  - source_pkg: store
+    target_pkg: dto
+    match_types: true
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	diags := ExpandPackageMappings(mf, graph)
+	require.Empty(t, diags.Errors)
+
+	var pairs []string
+	for _, tm := range mf.TypeMappings {
+		pairs = append(pairs, tm.Source+"->"+tm.Target)
+	}
+
+	assert.Equal(t, []string{
+		"example/store.Ledger->example/dto.LedgerModel",
+		"example/store.Order->example/dto.OrderDTO",
+	}, pairs)
+
+	result := Validate(mf, graph)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_strip_affix", result.Errors[0].Code)
+}
+
+func TestExpandPackageMappings_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		code string
+	}{
+		{"missing match_types", "mappings:\n  - source_pkg: store\n    target_pkg: api\n", "invalid_package_mapping"},
+		{"fields", "mappings:\n  - source_pkg: store\n    target_pkg: api\n    match_types: true\n    ignore: [ID]\n",
+			"invalid_package_mapping"},
+		{"unknown package", "mappings:\n  - source_pkg: store\n    target_pkg: dto\n    match_types: true\n",
+			"package_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mf, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+
+			diags := ExpandPackageMappings(mf, buildPackagesTypeGraph())
+			require.Len(t, diags.Errors, 1)
+			assert.Equal(t, tt.code, diags.Errors[0].Code)
+			assert.Empty(t, mf.TypeMappings)
+		})
+	}
+}
+
+func TestValidate_UnexpandedPackageMapping(t *testing.T) {
+	mf, err := Parse([]byte("mappings:\n  - source_pkg: store\n    target_pkg: api\n    match_types: true\n"))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildPackagesTypeGraph())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "unexpanded_package_mapping", result.Errors[0].Code)
+}
@@ -3,11 +3,17 @@ package mapping
 import (
 	"errors"
 	"fmt"
+	"go/constant"
+	"go/parser"
+	"go/types"
 	"strings"
+
+	"caster-generator/internal/analyze"
 )
 
 // ParsePath parses a field path string into a FieldPath.
-// Supports: "Field", "Nested.Field", "Items[]", "Items[].ProductID".
+// Supports: "Field", "Nested.Field", "Items[]", "Items[].ProductID", and
+// elements accessed by index or map key: "Items[0].ID", `Attrs["color"]`.
 func ParsePath(path string) (FieldPath, error) {
 	if path == "" {
 		return FieldPath{}, errors.New("empty path")
@@ -15,40 +21,128 @@ func ParsePath(path string) (FieldPath, error) {
 
 	var segments []PathSegment
 
-	parts := strings.SplitSeq(path, ".")
+	for rest := path; ; {
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+
+		seg := PathSegment{Name: rest[:end]}
+		rest = rest[end:]
 
-	for part := range parts {
-		if part == "" {
+		switch {
+		case seg.Name == "" && strings.HasPrefix(rest, "["):
+			return FieldPath{}, fmt.Errorf("invalid path %q: slice without field name", path)
+		case seg.Name == "":
 			return FieldPath{}, fmt.Errorf("invalid path %q: empty segment", path)
+		case !isValidIdent(seg.Name):
+			return FieldPath{}, fmt.Errorf("invalid path %q: invalid identifier %q", path, seg.Name)
 		}
 
-		isSlice := false
-		name := part
+		// Check for slice notation or an index or key
+		if strings.HasPrefix(rest, "[") {
+			closing := closingBracket(rest)
+			if closing < 0 {
+				return FieldPath{}, fmt.Errorf("invalid path %q: unclosed [ after %q", path, seg.Name)
+			}
 
-		// Check for slice notation
-		if strings.HasSuffix(part, "[]") {
-			isSlice = true
-			name = strings.TrimSuffix(part, "[]")
+			seg.Key = strings.TrimSpace(rest[1:closing])
+			seg.IsSlice = seg.Key == ""
+			rest = rest[closing+1:]
 
-			if name == "" {
-				return FieldPath{}, fmt.Errorf("invalid path %q: slice without field name", path)
+			if x, err := parser.ParseExpr(seg.Key); !seg.IsSlice && (err != nil || literalValue(x) == nil) {
+				return FieldPath{}, fmt.Errorf("invalid path %q: index %s of %q is not a literal", path, seg.Key, seg.Name)
 			}
 		}
 
-		// Validate identifier (basic check)
-		if !isValidIdent(name) {
-			return FieldPath{}, fmt.Errorf("invalid path %q: invalid identifier %q", path, name)
+		segments = append(segments, seg)
+
+		if rest == "" {
+			break
+		}
+
+		if rest[0] != '.' {
+			return FieldPath{}, fmt.Errorf("invalid path %q: unexpected %q after %q", path, rest, seg.Name)
 		}
 
-		segments = append(segments, PathSegment{
-			Name:    name,
-			IsSlice: isSlice,
-		})
+		rest = rest[1:]
 	}
 
 	return FieldPath{Segments: segments}, nil
 }
 
+// closingBracket returns the index of the ] closing the [ s starts with,
+// skipping quoted literals, or -1.
+func closingBracket(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case ']':
+			return i
+		case '"', '\'', '`':
+			quote := s[i]
+
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' && quote != '`' {
+					i++
+				}
+			}
+		}
+	}
+
+	return -1
+}
+
+// KeyedElem returns the type of the element a segment with key accesses on
+// t: a slice or array indexed by a non-negative integer, within the length of
+// arrays, or a map by a literal of its key type. Defined types of them are
+// accessed like their underlying type.
+func KeyedElem(t *analyze.TypeInfo, key string) (*analyze.TypeInfo, error) {
+	for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	var lit constant.Value
+	if x, err := parser.ParseExpr(key); err == nil {
+		lit = literalValue(x)
+	}
+
+	switch {
+	case t == nil || t.ElemType == nil:
+		return nil, fmt.Errorf("[%s] needs a slice, array or map", key)
+	case lit == nil:
+		return nil, fmt.Errorf("index %s is not a literal", key)
+	case t.Kind == analyze.TypeKindMap:
+		basic := enumBasicType(t.KeyType)
+		if basic == nil || !literalFits(lit, basic.ID.Name) {
+			return nil, fmt.Errorf("key %s is %s literal, not a key of %s", key, literalKind(lit), typeName(t))
+		}
+	case t.Kind == analyze.TypeKindSlice || t.Kind == analyze.TypeKindArray:
+		index, ok := constant.Int64Val(constant.ToInt(lit))
+		if !ok || index < 0 {
+			return nil, fmt.Errorf("index %s is not a non-negative integer", key)
+		}
+
+		if array, isArray := underlyingArray(t); isArray && index >= array.Len() {
+			return nil, fmt.Errorf("index %s is out of the bounds of %s", key, array)
+		}
+	default:
+		return nil, fmt.Errorf("[%s] needs a slice, array or map, not a %s", key, t.Kind)
+	}
+
+	return t.ElemType, nil
+}
+
+// underlyingArray returns the array type t is, if known.
+func underlyingArray(t *analyze.TypeInfo) (*types.Array, bool) {
+	if t.GoType == nil {
+		return nil, false
+	}
+
+	array, ok := t.GoType.Underlying().(*types.Array)
+
+	return array, ok
+}
+
 // ParsePaths parses multiple field paths from a StringOrArray.
 func ParsePaths(paths StringOrArray) ([]FieldPath, error) {
 	result := make([]FieldPath, 0, len(paths))
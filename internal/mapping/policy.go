@@ -0,0 +1,111 @@
+package mapping
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Policy selects which resolved but questionable mappings fail gen and check.
+// Every check is off unless set.
+type Policy struct {
+	// FailOnUnmapped fails on target fields left unmapped.
+	FailOnUnmapped bool `yaml:"fail_on_unmapped,omitempty"`
+
+	// MinAutoConfidence fails on auto-matched fields whose match confidence
+	// is below it (0 = any accepted match passes).
+	MinAutoConfidence float64 `yaml:"min_auto_confidence,omitempty"`
+
+	// FailOnLossy fails on numeric conversions to types that can't represent
+	// every source value, e.g. int64 to int32, float64 to int or int to uint.
+	FailOnLossy bool `yaml:"fail_on_lossy,omitempty"`
+
+	// FailOnMissingTransform fails on fields needing a transform that is
+	// neither set nor declared in transforms, which gen would stub.
+	FailOnMissingTransform bool `yaml:"fail_on_missing_transform,omitempty"`
+}
+
+// StrictPolicy is the policy of -strict: every fail_on_* check is set.
+var StrictPolicy = Policy{FailOnUnmapped: true, FailOnLossy: true, FailOnMissingTransform: true}
+
+// IsZero reports whether p fails on nothing.
+func (p *Policy) IsZero() bool {
+	return *p == Policy{}
+}
+
+// Or returns a policy failing on what p or other fails on, with the higher
+// min_auto_confidence.
+func (p Policy) Or(other Policy) Policy {
+	return Policy{
+		FailOnUnmapped:         p.FailOnUnmapped || other.FailOnUnmapped,
+		MinAutoConfidence:      max(p.MinAutoConfidence, other.MinAutoConfidence),
+		FailOnLossy:            p.FailOnLossy || other.FailOnLossy,
+		FailOnMissingTransform: p.FailOnMissingTransform || other.FailOnMissingTransform,
+	}
+}
+
+// Override returns p with the settings of overrides applied: comma-separated
+// keys of the policy block, with a value after "=" (e.g.,
+// "fail_on_unmapped,fail_on_lossy=false,min_auto_confidence=0.8"). Bare
+// fail_on_* keys are set to true.
+func (p Policy) Override(overrides string) (Policy, error) {
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if key == "min_auto_confidence" {
+			confidence, err := strconv.ParseFloat(value, 64)
+			if err != nil || !validConfidence(confidence) {
+				return p, fmt.Errorf("invalid min_auto_confidence %q (expected a number from 0 to 1)", value)
+			}
+
+			p.MinAutoConfidence = confidence
+
+			continue
+		}
+
+		flag := p.flag(key)
+		if flag == nil {
+			return p, fmt.Errorf("unknown policy %q (expected fail_on_unmapped, min_auto_confidence, "+
+				"fail_on_lossy or fail_on_missing_transform)", key)
+		}
+
+		*flag = true
+
+		if hasValue {
+			set, err := strconv.ParseBool(value)
+			if err != nil {
+				return p, fmt.Errorf("invalid %s value %q (expected true or false)", key, value)
+			}
+
+			*flag = set
+		}
+	}
+
+	return p, nil
+}
+
+// flag returns the fail_on_* setting named key, or nil.
+func (p *Policy) flag(key string) *bool {
+	switch key {
+	case "fail_on_unmapped":
+		return &p.FailOnUnmapped
+	case "fail_on_lossy":
+		return &p.FailOnLossy
+	case "fail_on_missing_transform":
+		return &p.FailOnMissingTransform
+	default:
+		return nil
+	}
+}
+
+// validConfidence reports whether c is within the range of match scores, 0 to 1.
+func validConfidence(c float64) bool {
+	return c >= 0 && c <= 1
+}
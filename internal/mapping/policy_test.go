@@ -0,0 +1,48 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Override(t *testing.T) {
+	base := Policy{FailOnLossy: true, MinAutoConfidence: 0.5}
+
+	p, err := base.Override("")
+	require.NoError(t, err)
+	assert.Equal(t, base, p)
+
+	p, err = base.Override(" fail_on_unmapped, fail_on_lossy=false ,min_auto_confidence=0.8")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{FailOnUnmapped: true, MinAutoConfidence: 0.8}, p)
+
+	_, err = base.Override("fail_on_everything")
+	require.ErrorContains(t, err, `unknown policy "fail_on_everything"`)
+
+	_, err = base.Override("fail_on_lossy=maybe")
+	require.ErrorContains(t, err, `invalid fail_on_lossy value "maybe"`)
+
+	_, err = base.Override("min_auto_confidence=1.5")
+	require.ErrorContains(t, err, "invalid min_auto_confidence")
+
+	assert.Equal(t, Policy{FailOnUnmapped: true, MinAutoConfidence: 0.5, FailOnLossy: true, FailOnMissingTransform: true},
+		base.Or(StrictPolicy))
+}
+
+func TestValidate_Policy(t *testing.T) {
+	mf, err := Parse([]byte(`
+policy:
+  fail_on_unmapped: true
+  min_auto_confidence: 2
+mappings: []
+`))
+	require.NoError(t, err)
+	assert.True(t, mf.Policy.FailOnUnmapped)
+
+	result := Validate(mf, buildTestTypeGraph())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_policy", result.Errors[0].Code)
+	assert.Equal(t, "policy.min_auto_confidence", result.Errors[0].FieldPath)
+}
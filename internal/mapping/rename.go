@@ -0,0 +1,114 @@
+package mapping
+
+import (
+	"go/types"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/match"
+)
+
+const (
+	// renameMinScore is the name similarity a field needs to be taken for the
+	// new name of a missing field, when its type matches the other side of
+	// the mapping.
+	renameMinScore = 0.6
+	// renameMinScoreUntyped is the name similarity needed when there is no
+	// type to match, as for ignore paths.
+	renameMinScoreUntyped = 0.8
+)
+
+// RenamedPath reports whether pathStr refers to a field missing from t, and
+// returns the path it likely refers to since the field was renamed, or "".
+// The renamed field is the only exported field of its struct, not used by the
+// mapping, whose name is close to the missing one and with which the path
+// resolves to type want (the type of the other side of the mapping). A nil
+// want matches any type, with a closer name. A nil used uses no path.
+func RenamedPath(pathStr string, t, want *analyze.TypeInfo, used func(string) bool) (string, bool) {
+	fp, err := ParsePath(pathStr)
+	if err != nil {
+		return "", false
+	}
+
+	current := t
+	for i, seg := range fp.Segments {
+		for current != nil && current.Kind == analyze.TypeKindPointer {
+			current = current.ElemType
+		}
+
+		if current == nil || current.Kind != analyze.TypeKindStruct {
+			return "", false
+		}
+
+		fld := current.FieldByName(seg.Name)
+		if fld != nil {
+			if current = fld.Type; seg.IsSlice {
+				for current != nil && current.Kind == analyze.TypeKindPointer {
+					current = current.ElemType
+				}
+
+				if current == nil || current.Kind != analyze.TypeKindSlice {
+					return "", false
+				}
+
+				current = current.ElemType
+			} else if seg.Key != "" {
+				if current, err = KeyedElem(current, seg.Key); err != nil {
+					return "", false
+				}
+			}
+
+			continue
+		}
+
+		minScore := renameMinScoreUntyped
+		if want != nil {
+			minScore = renameMinScore
+		}
+
+		var renamed string
+
+		for _, candidate := range current.Fields {
+			if !candidate.Exported || renameScore(seg.Name, candidate.Name) < minScore {
+				continue
+			}
+
+			fp.Segments[i].Name = candidate.Name
+			path := fp.String()
+
+			resolved, err := resolvePathType(path, t)
+			if err != nil || (used != nil && used(path)) || (want != nil && !identicalTypes(resolved, want)) {
+				continue
+			}
+
+			if renamed != "" {
+				return "", true
+			}
+
+			renamed = path
+		}
+
+		return renamed, true
+	}
+
+	return "", false
+}
+
+// renameScore returns the name similarity of a missing field and a field it
+// may have been renamed to, with and without common suffixes.
+func renameScore(missing, name string) float64 {
+	return max(match.NormalizedLevenshteinScore(missing, name),
+		match.NormalizedLevenshteinScoreWithSuffixStrip(missing, name))
+}
+
+// identicalTypes compares types by go/types identity, falling back to ID and kind.
+func identicalTypes(a, b *analyze.TypeInfo) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	if a.GoType != nil && b.GoType != nil {
+		return types.Identical(a.GoType, b.GoType)
+	}
+
+	return a.Kind == b.Kind && a.ID == b.ID && a.ID.Name != ""
+}
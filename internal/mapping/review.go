@@ -0,0 +1,210 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/analyze"
+)
+
+// ReviewAction is what a reviewer decided for an unmapped target field.
+type ReviewAction int
+
+const (
+	// ReviewAccept maps the field from the chosen source field.
+	ReviewAccept ReviewAction = iota
+	// ReviewIgnore adds the field to the ignore section.
+	ReviewIgnore
+	// ReviewAuto adds the field to the auto section, mapped from the chosen
+	// source field until a reviewer confirms it.
+	ReviewAuto
+)
+
+// ReviewDecision is a reviewer's decision on one target field of a type pair.
+type ReviewDecision struct {
+	// Source and Target are the full type IDs of the pair (e.g., "example.com/store.Order").
+	Source string
+	Target string
+	// Field is the target field path.
+	Field  string
+	Action ReviewAction
+	// From is the source field path of an accepted field.
+	From string
+	// Transform is the transform of an accepted field whose types need one.
+	// Such a field goes to the fields section, other accepted fields to 121,
+	// unless 121 already maps From to another field.
+	Transform string
+	// Comment is written above the entry the decision adds, if set.
+	Comment string
+}
+
+// FindTypeMapping returns the type mapping of mf converting source to target
+// (full type IDs), or nil. Package mappings are not matched.
+func (mf *MappingFile) FindTypeMapping(source, target string, graph *analyze.TypeGraph) *TypeMapping {
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+		if !tm.IsPackageMapping() && sameType(tm.Source, source, graph) && sameType(tm.Target, target, graph) {
+			return tm
+		}
+	}
+
+	return nil
+}
+
+// sameType reports whether the type name written in a mapping resolves to id.
+func sameType(written, id string, graph *analyze.TypeGraph) bool {
+	if written == id {
+		return true
+	}
+
+	t := ResolveTypeID(written, graph)
+
+	return t != nil && t.ID.String() == id
+}
+
+// ApplyReviewDecisions patches mapping YAML data with review decisions,
+// keeping its layout and comments. A decision on a type pair the data has no
+// mapping of adds one.
+func ApplyReviewDecisions(data []byte, decisions []ReviewDecision, graph *analyze.TypeGraph) ([]byte, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping YAML: %w", err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("mapping YAML is not a mapping")
+	}
+
+	mappings := childNode(doc.Content[0], "mappings", yaml.SequenceNode)
+
+	for _, d := range decisions {
+		entry := findMappingNode(mappings, d.Source, d.Target, graph)
+		if entry == nil {
+			entry = &yaml.Node{Kind: yaml.MappingNode}
+			entry.Content = append(entry.Content,
+				scalarNode("source"), scalarNode(d.Source),
+				scalarNode("target"), scalarNode(d.Target))
+			mappings.Content = append(mappings.Content, entry)
+		}
+
+		switch {
+		case d.Action == ReviewIgnore:
+			ignore := childNode(entry, "ignore", yaml.SequenceNode)
+			if !slices.ContainsFunc(ignore.Content, func(n *yaml.Node) bool { return ignoreTarget(n) == d.Field }) {
+				n := scalarNode(d.Field)
+				n.HeadComment = d.Comment
+				ignore.Content = append(ignore.Content, n)
+			}
+
+		case d.Action == ReviewAuto || d.Transform != "" || mapsOneToOne(entry, d.From):
+			field := &yaml.Node{Kind: yaml.MappingNode, HeadComment: d.Comment}
+			field.Content = append(field.Content,
+				scalarNode("source"), scalarNode(d.From),
+				scalarNode("target"), scalarNode(d.Field))
+
+			if d.Transform != "" {
+				field.Content = append(field.Content, scalarNode("transform"), scalarNode(d.Transform))
+			}
+
+			section := "fields"
+			if d.Action == ReviewAuto {
+				section = "auto"
+			}
+
+			fields := childNode(entry, section, yaml.SequenceNode)
+			fields.Content = append(fields.Content, field)
+
+		default:
+			from := scalarNode(d.From)
+			from.HeadComment = d.Comment
+			oneToOne := childNode(entry, "121", yaml.MappingNode)
+			oneToOne.Content = append(oneToOne.Content, from, scalarNode(d.Field))
+		}
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+
+	return out, nil
+}
+
+// mapsOneToOne reports whether the 121 section of mapping entry has source
+// field from as a key. Keys are unique, so mapping from to another target
+// takes a fields entry.
+func mapsOneToOne(entry *yaml.Node, from string) bool {
+	oneToOne := valueNode(entry, "121")
+
+	return oneToOne != nil && valueNode(oneToOne, from) != nil
+}
+
+// findMappingNode returns the entry of the mappings sequence converting
+// source to target, or nil.
+func findMappingNode(mappings *yaml.Node, source, target string, graph *analyze.TypeGraph) *yaml.Node {
+	for _, entry := range mappings.Content {
+		src, tgt := valueNode(entry, "source"), valueNode(entry, "target")
+		if src != nil && tgt != nil && sameType(src.Value, source, graph) && sameType(tgt.Value, target, graph) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// valueNode returns the value of key in mapping node m, or nil.
+func valueNode(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// ignoreTarget returns the target path of ignore entry node n, a path or an
+// object with a target.
+func ignoreTarget(n *yaml.Node) string {
+	if target := valueNode(n, "target"); target != nil {
+		return target.Value
+	}
+
+	return n.Value
+}
+
+// childNode returns the value of key in mapping node m, adding an empty node
+// of the given kind when the key is missing or null.
+func childNode(m *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	if v := valueNode(m, key); v != nil {
+		if v.Kind == yaml.ScalarNode && v.Tag == "!!null" {
+			*v = yaml.Node{Kind: kind}
+		}
+
+		return v
+	}
+
+	k := scalarNode(key)
+	if key == "121" {
+		// Unquoted, the key would read back as an integer.
+		k.Style = yaml.DoubleQuotedStyle
+	}
+
+	v := &yaml.Node{Kind: kind}
+	m.Content = append(m.Content, k, v)
+
+	return v
+}
+
+// scalarNode returns a string scalar node.
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
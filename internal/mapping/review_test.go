@@ -0,0 +1,67 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyReviewDecisions(t *testing.T) {
+	data := []byte(`version: "1"
+# Orders under review
+mappings:
+    - source: example.com/store.Order
+      target: example.com/warehouse.Order
+      "121":
+        ID: ID # keep the ID
+      ignore:
+`)
+
+	decisions := []ReviewDecision{
+		{Source: "example.com/store.Order", Target: "example.com/warehouse.Order", Field: "Status", From: "State"},
+		{Source: "example.com/store.Order", Target: "example.com/warehouse.Order", Field: "OrderID", From: "ID"},
+		{
+			Source: "example.com/store.Order", Target: "example.com/warehouse.Order",
+			Field: "Total", From: "Amount", Transform: "TODO_AmountToTotal",
+		},
+		{Source: "example.com/store.Order", Target: "example.com/warehouse.Order", Field: "Internal", Action: ReviewIgnore},
+		{Source: "example.com/store.Order", Target: "example.com/warehouse.Order", Field: "Internal", Action: ReviewIgnore},
+		{Source: "example.com/store.Item", Target: "example.com/warehouse.Item", Field: "Code", From: "SKU"},
+		{
+			Source: "example.com/store.Item", Target: "example.com/warehouse.Item",
+			Field: "Label", From: "Name", Action: ReviewAuto, Comment: "check -fix: candidates Name (0.61)",
+		},
+	}
+
+	out, err := ApplyReviewDecisions(data, decisions, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(out), "# Orders under review")
+	assert.Contains(t, string(out), "ID: ID # keep the ID")
+
+	mf, err := Parse(out)
+	require.NoError(t, err)
+	require.Len(t, mf.TypeMappings, 2)
+
+	order := mf.TypeMappings[0]
+	assert.Equal(t, map[string]string{"ID": "ID", "State": "Status"}, order.OneToOne)
+	assert.Equal(t, []string{"Internal"}, ignoreTargets(order.Ignore))
+	require.Len(t, order.Fields, 2)
+	// ID is a 121 key already, so mapping it again takes a fields entry.
+	assert.Equal(t, "ID", order.Fields[0].Source[0].Path)
+	assert.Equal(t, "OrderID", order.Fields[0].Target[0].Path)
+	assert.Empty(t, order.Fields[0].Transform)
+	assert.Equal(t, "Amount", order.Fields[1].Source[0].Path)
+	assert.Equal(t, "Total", order.Fields[1].Target[0].Path)
+	assert.Equal(t, "TODO_AmountToTotal", order.Fields[1].Transform)
+
+	item := mf.TypeMappings[1]
+	assert.Equal(t, "example.com/store.Item", item.Source)
+	assert.Equal(t, "example.com/warehouse.Item", item.Target)
+	assert.Equal(t, map[string]string{"SKU": "Code"}, item.OneToOne)
+	require.Len(t, item.Auto, 1)
+	assert.Equal(t, "Name", item.Auto[0].Source[0].Path)
+	assert.Equal(t, "Label", item.Auto[0].Target[0].Path)
+	assert.Contains(t, string(out), "# check -fix: candidates Name (0.61)")
+}
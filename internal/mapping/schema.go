@@ -1,9 +1,15 @@
 package mapping
 
 import (
+	"reflect"
+	"slices"
 	"strings"
+	"text/template"
 
+	"caster-generator/internal/analyze"
 	"caster-generator/internal/common"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
 )
 
 // MappingFile represents the root of a YAML mapping definition file.
@@ -12,20 +18,186 @@ type MappingFile struct {
 	// Version of the mapping schema (for future compatibility).
 	Version string `yaml:"version,omitempty"`
 
+	// Include lists globs of mapping files merged into this one by LoadFile,
+	// relative to this file's directory. Definitions of this file take
+	// precedence over included ones with the same type pair (or transform,
+	// wrapper name); included files that define one differently conflict.
+	// Type pairs spelled differently (e.g., by package name and by import
+	// path) are merged once the types are loaded, by ExpandPackageMappings.
+	Include []string `yaml:"include,omitempty"`
+
 	// TypeMappings is a list of type pair mappings.
 	TypeMappings []TypeMapping `yaml:"mappings"`
 
+	// Templates names lists of field mappings shared by the type mappings
+	// using them (see TypeMapping.Use), e.g. the rules of audit fields.
+	// Templates of included files are available too, unless this file
+	// defines one with the same name.
+	Templates map[string][]FieldMapping `yaml:"templates,omitempty"`
+
 	// Transforms defines custom transform functions available for use.
 	Transforms []TransformDef `yaml:"transforms,omitempty"`
+
+	// Wrappers declares generic single-value wrapper types (e.g., nullable.Nullable[T])
+	// so conversions between the wrapper, *T and T are planned without transforms.
+	Wrappers []WrapperDef `yaml:"wrappers,omitempty"`
+
+	// Implementations registers concrete source/target type pairs used to convert
+	// interface-typed fields: a type switch dispatches each pair to its caster.
+	Implementations []ImplementationDef `yaml:"implementations,omitempty"`
+
+	// CopyMode is the default copy mode of every mapping in the file.
+	// Mappings and fields may override it.
+	CopyMode CopyMode `yaml:"copy_mode,omitempty"`
+
+	// IgnoreTags lists struct tag keys (e.g., "json" or "caster") whose "-"
+	// value marks a target field as ignored, unless a rule maps it explicitly.
+	IgnoreTags []string `yaml:"ignore_tags,omitempty"`
+
+	// IgnorePatterns lists patterns of target field names ignored in every
+	// mapping, unless a rule maps them explicitly: globs (e.g., "XXX_*" or
+	// "*Unrecognized*") or regular expressions between slashes (e.g., "/^XXX_/").
+	IgnorePatterns []string `yaml:"ignore_patterns,omitempty"`
+
+	// StripAffixes lists name tokens (e.g., "DTO", "Model" or "V1") that
+	// auto-matching and package mappings ignore at the start or end of field
+	// and type names, so OrderDTO pairs with Order.
+	StripAffixes []string `yaml:"strip_affixes,omitempty"`
+
+	// Match tunes the name matching of auto-matched fields.
+	Match MatchOptions `yaml:"match,omitempty"`
+
+	// OnIncompatiblePin selects what happens to 121 entries whose types no
+	// longer convert without a transform, e.g. after an upstream type change.
+	OnIncompatiblePin PinPolicy `yaml:"on_incompatible_pin,omitempty"`
+
+	// Policy makes gen and check fail on mappings that resolve but may be
+	// wrong, such as unmapped targets or lossy conversions.
+	Policy Policy `yaml:"policy,omitempty"`
+
+	// Currency sets the minor unit of the builtin money transforms (e.g.,
+	// CentsToDecimal).
+	Currency Currency `yaml:"currency,omitempty"`
+
+	// Naming customizes the names of the generated casters.
+	Naming Naming `yaml:"naming,omitempty"`
+
+	// GeneratedTypes customizes the structs declared for generate_target
+	// mappings.
+	GeneratedTypes GeneratedTypes `yaml:"generated_types,omitempty"`
+
+	// IncludeConflicts are the definitions included files disagree on,
+	// reported by Validate.
+	IncludeConflicts []IncludeConflict `yaml:"-"`
+}
+
+// MatchOptions tunes how auto-matching compares field names.
+type MatchOptions struct {
+	// Synonyms maps a name to its domain-equivalent names (e.g., "Amount" to
+	// ["Price", "Cost", "Total"]), which then score as near-exact matches,
+	// as whole names or as tokens of them (UnitPrice and UnitAmount).
+	Synonyms map[string][]string `yaml:"synonyms,omitempty"`
+
+	// Abbreviations maps shorthand name tokens to their expansion (e.g.,
+	// "Qty" to "Quantity"), in addition to and overriding the built-in ones.
+	Abbreviations map[string]string `yaml:"abbreviations,omitempty"`
+
+	// ExplicitDefinedTypes keeps auto-matching from converting between a
+	// defined type and its underlying type or another defined type over it
+	// (e.g., UserID and int64): such fields need an explicit rule to approve
+	// the conversion.
+	ExplicitDefinedTypes bool `yaml:"explicit_defined_types,omitempty"`
+
+	// PositionWeight is the share (0-1) of candidate scores given to relative
+	// field position similarity, overriding the resolver's when not zero.
+	PositionWeight float64 `yaml:"position_weight,omitempty"`
+
+	// Tag is a struct tag key (e.g., "json") whose equal values pin field
+	// matches regardless of Go names, overriding the resolver's when set.
+	Tag string `yaml:"tag,omitempty"`
+}
+
+// Matching overrides the auto-match thresholds of the resolver for the
+// fields of one mapping. Zero values keep the resolver's.
+type Matching struct {
+	// MinConfidence is the minimum score of an auto-accepted match.
+	MinConfidence float64 `yaml:"min_confidence,omitempty"`
+
+	// MinGap is the minimum score gap between the two best candidates of an
+	// auto-accepted match.
+	MinGap float64 `yaml:"min_gap,omitempty"`
+
+	// AmbiguityThreshold reports candidates scoring within it as ambiguous.
+	AmbiguityThreshold float64 `yaml:"ambiguity_threshold,omitempty"`
+}
+
+// IsZero reports whether m keeps every threshold of the resolver.
+func (m *Matching) IsZero() bool {
+	return *m == Matching{}
+}
+
+// Or returns m with its zero thresholds taken from fallback.
+func (m Matching) Or(fallback Matching) Matching {
+	if m.MinConfidence == 0 {
+		m.MinConfidence = fallback.MinConfidence
+	}
+
+	if m.MinGap == 0 {
+		m.MinGap = fallback.MinGap
+	}
+
+	if m.AmbiguityThreshold == 0 {
+		m.AmbiguityThreshold = fallback.AmbiguityThreshold
+	}
+
+	return m
+}
+
+// Normalizer returns the name normalizer of auto-matching configured by the
+// strip_affixes and abbreviations of mf.
+func (mf *MappingFile) Normalizer() match.Normalizer {
+	return match.Normalizer{Affixes: mf.StripAffixes, Abbreviations: mf.Match.Abbreviations}
 }
 
 // TypeMapping defines how to map one source type to one target type.
 type TypeMapping struct {
 	// Source type identifier (e.g., "store.Order" or full path).
-	Source string `yaml:"source"`
+	Source string `yaml:"source,omitempty"`
 
 	// Target type identifier (e.g., "warehouse.Order" or full path).
-	Target string `yaml:"target"`
+	Target string `yaml:"target,omitempty"`
+
+	// Via converts through an intermediate type (e.g., "canonical.Order"):
+	// the caster chains the casters of the mappings from the source to Via
+	// and from Via to the target instead of mapping fields.
+	Via string `yaml:"via,omitempty"`
+
+	// Before and After name hook functions the caster calls around the
+	// conversion (e.g., "hooks.NormalizeOrder"): Before, a func(in Source)
+	// Source, replaces the source first, and After, a func(in Source, out
+	// *Target), adjusts the result last. Unqualified names refer to functions
+	// of the casters package.
+	Before string `yaml:"before,omitempty"`
+	After  string `yaml:"after,omitempty"`
+
+	// Method makes the caster a method of the source type with this name
+	// (e.g., "ToWarehouseOrder", called as order.ToWarehouseOrder()) instead
+	// of a function. It is generated into the package of the source type,
+	// which must belong to this module.
+	Method string `yaml:"method,omitempty"`
+
+	// SourcePkg and TargetPkg declare a package mapping instead of Source and
+	// Target: with MatchTypes, every struct of SourcePkg is paired with the
+	// same-named (or closest-named) struct of TargetPkg, and each pair is
+	// mapped with this mapping's tags, requires and copy_mode. Package
+	// mappings are replaced by their pairs by ExpandPackageMappings.
+	SourcePkg  string `yaml:"source_pkg,omitempty"`
+	TargetPkg  string `yaml:"target_pkg,omitempty"`
+	MatchTypes bool   `yaml:"match_types,omitempty"`
+
+	// Tags group mappings (e.g., by owning team) so check and gen can run
+	// over a subset with -tags.
+	Tags []string `yaml:"tags,omitempty"`
 
 	// Requires lists external variables required by this mapping function.
 	// These become additional arguments to the generated function.
@@ -35,25 +207,102 @@ type TypeMapping struct {
 	// and values are target fields. Supports 1:1 mappings only.
 	// Priority: highest (applied first).
 	// Example: { "OrderID": "ID", "CustomerName": "Customer" }
+	// Entries may be wildcard patterns with one "*" (e.g., "Meta*": "Meta*"),
+	// mapping the matching fields no explicit 121 or fields rule maps.
 	OneToOne map[string]string `yaml:"121,omitempty"`
 
+	// OneToOnePos holds the positions of the OneToOne entries by source
+	// field, set by Parse and LoadFile.
+	OneToOnePos map[string]diagnostic.Position `yaml:"-"`
+
+	// PrefixMap maps the fields of the source named with a prefix to the
+	// target fields named with another (e.g., {Billing: Payment} maps
+	// BillingCity to PaymentCity), like the 121 pattern "Billing*": "Payment*".
+	PrefixMap map[string]string `yaml:"prefix_map,omitempty"`
+
+	// PrefixMapPos holds the positions of the PrefixMap entries by source
+	// prefix, set by Parse and LoadFile.
+	PrefixMapPos map[string]diagnostic.Position `yaml:"-"`
+
 	// GenerateTarget indicates that the target type should be generated
 	// if it does not exist. The structure will be inferred from the mapping.
 	GenerateTarget bool `yaml:"generate_target,omitempty"`
 
+	// AllowUnexported lets the mapping read and assign unexported fields, for
+	// casters generated into the package declaring them.
+	AllowUnexported bool `yaml:"allow_unexported,omitempty"`
+
+	// MaxDepth limits how many levels of recursive fields the caster of a
+	// recursive type converts, leaving deeper ones unset (0 = unlimited).
+	MaxDepth int `yaml:"max_depth,omitempty"`
+
+	// RequireFullCoverage makes validation fail unless every exported target
+	// field is mapped by exactly one of 121, fields, ignore and auto, so new
+	// target fields must be mapped or ignored explicitly.
+	RequireFullCoverage bool `yaml:"require_full_coverage,omitempty"`
+
+	// Matching overrides the auto-match thresholds for the fields of this
+	// mapping, e.g. stricter ones for a noisy legacy type.
+	Matching Matching `yaml:"matching,omitempty"`
+
+	// GenerateMerge adds a MergeXIntoY variant of the caster updating an
+	// existing target instead of constructing one, following the merge policy
+	// of each field. It is implied when a field sets merge.
+	GenerateMerge bool `yaml:"generate_merge,omitempty"`
+
+	// Flatten lists struct fields of the source whose fields are mapped to
+	// same-named prefixed target fields (e.g., Address.Street -> AddressStreet).
+	Flatten StringArray `yaml:"flatten,omitempty"`
+
+	// Unflatten lists struct fields of the target assigned from same-named
+	// prefixed source fields (e.g., AddressStreet -> Address.Street).
+	Unflatten StringArray `yaml:"unflatten,omitempty"`
+
 	// Fields defines explicit field mappings with full control.
 	// Supports 1:1, 1:many, many:1, and many:many with transforms.
 	// Priority: second highest (after 121).
 	Fields []FieldMapping `yaml:"fields,omitempty"`
 
-	// Ignore lists target fields that should not be mapped.
+	// Use names templates whose field mappings are added to Fields by Parse
+	// and LoadFile, skipping those for targets the mapping already maps or
+	// ignores.
+	Use StringOrArray `yaml:"use,omitempty"`
+
+	// Ignore lists target fields that should not be mapped, as paths or as
+	// objects recording why and until when (e.g., {target: Notes, reason:
+	// "filled by the UI", until: 2025-06-30, ticket: SHOP-42}).
 	// Priority: third (after fields).
-	Ignore []string `yaml:"ignore,omitempty"`
+	Ignore []IgnoreEntry `yaml:"ignore,omitempty"`
 
 	// Auto contains auto-matched fields from best-effort matching.
 	// This is populated during resolution and has lowest priority.
 	// Fields here are overridden by 121, fields, or ignore.
 	Auto []FieldMapping `yaml:"auto,omitempty"`
+
+	// CopyMode is the default copy mode of this mapping's fields, overriding
+	// the file-level copy_mode. Fields may override it with copy.
+	CopyMode CopyMode `yaml:"copy_mode,omitempty"`
+
+	// Output generates this mapping's caster into another package than the
+	// one given by -package and -out.
+	Output *Output `yaml:"output,omitempty"`
+
+	// Pos is where the mapping is defined, set by Parse and LoadFile.
+	Pos diagnostic.Position `yaml:"-"`
+
+	// includedFrom is the included file LoadFile merged the mapping from,
+	// empty for mappings of the including file.
+	includedFrom string
+}
+
+// Output is the package a type mapping's caster is generated into.
+type Output struct {
+	// Package is the package name; it defaults to the base name of Dir.
+	Package string `yaml:"package,omitempty"`
+
+	// Dir is the package directory, relative to the working directory; it
+	// defaults to a Package directory inside the output directory.
+	Dir string `yaml:"dir,omitempty"`
 }
 
 // IntrospectionHint indicates how the engine should handle field introspection.
@@ -73,6 +322,132 @@ func (h IntrospectionHint) IsValid() bool {
 	return h == HintNone || h == HintDive || h == HintFinal
 }
 
+// CopyMode selects whether a field's slices, maps and pointers are shared with
+// the source or cloned into the target.
+type CopyMode string
+
+const (
+	// CopyDefault means no choice at this level; the enclosing level decides.
+	CopyDefault CopyMode = ""
+	// CopyAlias assigns the source value as-is, sharing its backing memory.
+	CopyAlias CopyMode = "alias"
+	// CopyShallow copies the top-level slice, map or pointee; elements are shared.
+	CopyShallow CopyMode = "shallow"
+	// CopyDeep recursively clones slices, maps, pointers and struct fields so
+	// the target doesn't alias the source.
+	CopyDeep CopyMode = "deep"
+)
+
+// IsValid returns true if the copy mode is a recognized value.
+func (c CopyMode) IsValid() bool {
+	return c == CopyDefault || c == CopyAlias || c == CopyShallow || c == CopyDeep
+}
+
+// Or returns c, or fallback when c is CopyDefault.
+func (c CopyMode) Or(fallback CopyMode) CopyMode {
+	if c == CopyDefault {
+		return fallback
+	}
+
+	return c
+}
+
+// MergePolicy selects how a field is assigned when merging into an existing
+// target value.
+type MergePolicy string
+
+const (
+	// MergeDefault means no choice; the field is overwritten.
+	MergeDefault MergePolicy = ""
+	// MergeOverwrite always assigns the field, as the caster does.
+	MergeOverwrite MergePolicy = "overwrite"
+	// MergeIfZero assigns the field only while the target field is zero,
+	// keeping values already set.
+	MergeIfZero MergePolicy = "if_zero"
+	// MergeNever leaves the target field as it is.
+	MergeNever MergePolicy = "never"
+)
+
+// IsValid returns true if the merge policy is a recognized value.
+func (m MergePolicy) IsValid() bool {
+	return m == MergeDefault || m == MergeOverwrite || m == MergeIfZero || m == MergeNever
+}
+
+// PinPolicy selects how a 121 entry with incompatible source and target types
+// is resolved.
+type PinPolicy string
+
+const (
+	// PinDefault leaves the entry needing a transform: gen refuses it as an
+	// incomplete mapping.
+	PinDefault PinPolicy = ""
+	// PinError reports the entry as an error, failing check as well as gen.
+	PinError PinPolicy = "error"
+	// PinTodo converts the entry with a placeholder transform, generated as a
+	// stub that panics until it is implemented.
+	PinTodo PinPolicy = "todo"
+	// PinFallbackAuto drops the entry, leaving its target to the other rules
+	// and auto-matching.
+	PinFallbackAuto PinPolicy = "fallback_auto"
+)
+
+// IsValid returns true if the pin policy is a recognized value.
+func (p PinPolicy) IsValid() bool {
+	return p == PinDefault || p == PinError || p == PinTodo || p == PinFallbackAuto
+}
+
+// Or returns p, or fallback when p is PinDefault.
+func (p PinPolicy) Or(fallback PinPolicy) PinPolicy {
+	if p == PinDefault {
+		return fallback
+	}
+
+	return p
+}
+
+// GeneratedTypes customizes the structs declared for generate_target
+// mappings, so generated DTOs read well in review.
+type GeneratedTypes struct {
+	// FieldOrder orders the fields of the generated structs.
+	FieldOrder FieldOrder `yaml:"field_order,omitempty"`
+
+	// FieldDocs adds a doc comment to each field of the generated structs
+	// naming the source fields, and transform, it is assigned from.
+	FieldDocs bool `yaml:"field_docs,omitempty"`
+
+	// CopyTags lists struct tag keys (e.g., "json" or "db") copied to the
+	// fields of the generated structs from the source fields they are
+	// assigned from.
+	CopyTags []string `yaml:"copy_tags,omitempty"`
+}
+
+// IsZero reports whether g leaves the generated structs as they are by default.
+func (g *GeneratedTypes) IsZero() bool {
+	return g.FieldOrder == FieldOrderDefault && !g.FieldDocs && len(g.CopyTags) == 0
+}
+
+// FieldOrder selects the order of the fields of generated structs.
+type FieldOrder string
+
+const (
+	// FieldOrderDefault declares the 121 entries ordered by source field,
+	// then the fields and auto entries.
+	FieldOrderDefault FieldOrder = ""
+	// FieldOrderYAML declares the fields in the order of their entries in
+	// the mapping file.
+	FieldOrderYAML FieldOrder = "yaml"
+	// FieldOrderAlphabetical declares the fields ordered by name.
+	FieldOrderAlphabetical FieldOrder = "alphabetical"
+	// FieldOrderSource declares the fields in the order of the source fields
+	// they are assigned from, fields without a source last.
+	FieldOrderSource FieldOrder = "source"
+)
+
+// IsValid returns true if the field order is a recognized value.
+func (o FieldOrder) IsValid() bool {
+	return o == FieldOrderDefault || o == FieldOrderYAML || o == FieldOrderAlphabetical || o == FieldOrderSource
+}
+
 // FieldRef represents a field path with an optional introspection hint.
 // YAML formats supported:
 //   - Simple string: "Name"
@@ -275,17 +650,55 @@ type FieldMapping struct {
 
 	// Target is the target field path(s) with optional hints.
 	// Examples: "Name", ["FirstName", "DisplayName"], {Address: dive}
-	Target FieldRefArray `yaml:"target"`
+	// Omitted with FromMapKeys, which names the targets.
+	Target FieldRefArray `yaml:"target,omitempty"`
 
 	// TargetType explicitly defines the type of the target field
 	// when GenerateTarget is used on the parent mapping.
 	// Examples: "string", "*int", "my.Type".
 	TargetType string `yaml:"target_type,omitempty"`
 
+	// Tags are the struct tags of the target fields when GenerateTarget is
+	// used on the parent mapping, by key (e.g., json: "order_id,omitempty").
+	// They override the tags copied from the source fields.
+	Tags map[string]string `yaml:"tags,omitempty"`
+
 	// Default is a literal value to assign if Source is empty.
 	// Supports basic types: strings (quoted), numbers, booleans.
 	Default *string `yaml:"default,omitempty"`
 
+	// Const is a constant assigned to the targets, typed by the target field:
+	// a literal of its basic type (e.g., 42, true, or pending or "pending"
+	// for a string) or a named constant of a loaded package (e.g.,
+	// warehouse.StatusPending).
+	Const string `yaml:"const,omitempty"`
+
+	// Expr is a Go expression assigned to the targets as-is (e.g., "time.Now()").
+	// Its qualified identifiers must be declared by loaded packages; it may read
+	// source fields as in.Field.
+	Expr string `yaml:"expr,omitempty"`
+
+	// Collect maps source field paths to the keys they are stored under in a
+	// map or []KV target (e.g., Color: color), instead of a transform.
+	Collect map[string]string `yaml:"collect,omitempty"`
+
+	// OmitZero leaves the collected source fields holding their zero value
+	// out of the collect target.
+	OmitZero bool `yaml:"omit_zero,omitempty"`
+
+	// FromMapKeys maps the keys of a map[string]string source to the target
+	// field paths their values are parsed into (e.g., status: Status); the
+	// targets of missing keys or values that don't parse are left unassigned.
+	FromMapKeys map[string]string `yaml:"from_map_keys,omitempty"`
+
+	// When is a Go boolean expression over the source fields (e.g.,
+	// `in.Type == "premium"`) guarding the assignment of the targets.
+	When string `yaml:"when,omitempty"`
+
+	// ElseDefault is a literal value assigned to the targets when the When
+	// condition doesn't hold. The targets keep their zero value if empty.
+	ElseDefault string `yaml:"else_default,omitempty"`
+
 	// Transform is the name of a transform function to apply.
 	// Required for many:1 mappings. For many:many, a unique transform
 	// name is auto-generated if not specified.
@@ -294,6 +707,90 @@ type FieldMapping struct {
 	// Extra lists additional info field paths from the source type (or parent scope)
 	// that should be passed to the mapping/transform/caster.
 	Extra ExtraVals `yaml:"extra,omitempty"`
+
+	// NilToEmpty makes a nil source slice or map produce an empty, non-nil
+	// collection in the target (marshals as [] / {} instead of null).
+	NilToEmpty bool `yaml:"nil_to_empty,omitempty"`
+
+	// PreserveNil keeps a nil source slice or map nil in the target instead of
+	// allocating an empty collection. Mutually exclusive with NilToEmpty.
+	PreserveNil bool `yaml:"preserve_nil,omitempty"`
+
+	// DedupBy names a field of the source slice elements: elements whose
+	// value of it equals that of an earlier element are dropped from the
+	// target slice (e.g., "ID" to collapse denormalized rows).
+	DedupBy string `yaml:"dedup_by,omitempty"`
+
+	// Merge selects how the MergeXIntoY variant of the caster assigns this
+	// field: "overwrite" (the default) always assigns it, "if_zero" only when
+	// the target field is still zero and "never" leaves it untouched.
+	Merge MergePolicy `yaml:"merge,omitempty"`
+
+	// Copy overrides the mapping's copy_mode for this field:
+	// "alias" shares the source value, "shallow" copies its top level
+	// and "deep" clones it recursively.
+	Copy CopyMode `yaml:"copy,omitempty"`
+
+	// EnumMap maps source constant values to target values (e.g., ACTIVE: 1).
+	// The generator emits a switch over them instead of calling a transform.
+	EnumMap map[string]string `yaml:"enum_map,omitempty"`
+
+	// EnumDefault is the target value for source values missing from EnumMap.
+	// The target's zero value is used when empty.
+	EnumDefault string `yaml:"enum_default,omitempty"`
+
+	// EnumStrict makes the caster panic on source values missing from EnumMap.
+	EnumStrict bool `yaml:"enum_strict,omitempty"`
+
+	// NullDefault is the target value for NULL sources of database/sql
+	// nullable types (e.g., sql.NullString). The zero value (or nil for
+	// pointer targets) is used when empty.
+	NullDefault string `yaml:"null_default,omitempty"`
+
+	// Deprecated marks a legacy field mapping that is being phased out
+	// (e.g., "use NewTotal after 2025-01"). The message is written into the
+	// generated code and check reports the mapping as a warning.
+	Deprecated string `yaml:"deprecated,omitempty"`
+
+	// Sunset is the date (YYYY-MM-DD) after which check fails on a deprecated mapping.
+	Sunset string `yaml:"sunset,omitempty"`
+
+	// OptionalSource tolerates source paths missing from the source type, for
+	// mappings written against an upcoming schema version: the mapping is
+	// reported as a warning and its targets are left with a TODO until the
+	// source fields exist.
+	OptionalSource bool `yaml:"optional_source,omitempty"`
+
+	// Pos is where the field mapping is defined, set by Parse and LoadFile.
+	Pos diagnostic.Position `yaml:"-"`
+}
+
+// IgnoreEntry is an ignored target field with optional metadata explaining
+// the decision. YAML formats supported:
+//   - Simple string: "Notes"
+//   - Object: {target: Notes, reason: "filled by the UI", until: 2025-06-30, ticket: SHOP-42}
+type IgnoreEntry struct {
+	// Target is the ignored target field path (e.g., "Notes").
+	Target string `yaml:"target"`
+
+	// Reason explains why the field is left unmapped; explain shows it.
+	Reason string `yaml:"reason,omitempty"`
+
+	// Until is the date (YYYY-MM-DD) after which check warns that the field
+	// is still ignored.
+	Until string `yaml:"until,omitempty"`
+
+	// Ticket references the issue tracking the ignored field (e.g., "SHOP-42").
+	Ticket string `yaml:"ticket,omitempty"`
+
+	// Pos is where the entry is defined, set by Parse and LoadFile.
+	Pos diagnostic.Position `yaml:"-"`
+}
+
+// HasMetadata reports whether the entry carries more than its target, so
+// it is written as an object rather than a plain path.
+func (e *IgnoreEntry) HasMetadata() bool {
+	return e.Reason != "" || e.Until != "" || e.Ticket != ""
 }
 
 // ExtraDef represents an extra value definition.
@@ -308,9 +805,16 @@ type ExtraVals []ExtraVal
 // ExtraVal is a single extra value definition with a name.
 type ExtraVal struct {
 	Name string   `yaml:"name"`
-	Def  ExtraDef `yaml:"def"`
+	Def  ExtraDef `yaml:"def,omitempty"`
+	// Const is a literal passed instead of a field (e.g., a date layout).
+	Const ExtraConst `yaml:"const,omitempty"`
 }
 
+// ExtraConst is the Go literal of a constant extra argument: strings are kept
+// quoted (e.g., "2006-01-02"), numbers and bools aren't (e.g., 10 or true).
+// A YAML string that already is a Go string literal isn't quoted again.
+type ExtraConst string
+
 // StringOrArray is a type that can be unmarshaled from either a string or an array of strings.
 // This allows YAML fields to accept both "field" and ["field1", "field2"].
 type StringOrArray []string
@@ -364,6 +868,28 @@ func (fm *FieldMapping) GetCardinality() Cardinality {
 	return CardinalityManyToMany
 }
 
+// HasValue returns true if the mapping assigns a default, const or expr value
+// instead of reading a source.
+func (fm *FieldMapping) HasValue() bool {
+	return fm.Default != nil || fm.Const != "" || fm.Expr != ""
+}
+
+// IsPlain reports whether the mapping reads one source field into one target
+// field without hints or any other option, so a 121 entry can express it.
+// Options are found by reflection, so fields added to FieldMapping count as
+// options without updating this check.
+func (fm *FieldMapping) IsPlain() bool {
+	if len(fm.Source) != 1 || len(fm.Target) != 1 ||
+		fm.Source[0].Hint != HintNone || fm.Target[0].Hint != HintNone {
+		return false
+	}
+
+	options := *fm
+	options.Source, options.Target, options.Pos = nil, nil, diagnostic.Position{}
+
+	return reflect.ValueOf(options).IsZero()
+}
+
 // NeedsTransform returns true if this mapping requires a transform function.
 // Many:1 always requires transform. Many:many requires transform.
 // 1:1 with incompatible types may need transform (checked during validation).
@@ -403,6 +929,84 @@ type TransformDef struct {
 
 	// AutoGenerated indicates this transform was auto-generated during resolution.
 	AutoGenerated bool `yaml:"auto_generated,omitempty"`
+
+	// Ctx indicates the transform takes a context.Context as its first argument.
+	// Casters calling it (directly or through nested casters) accept and forward ctx.
+	Ctx bool `yaml:"ctx,omitempty"`
+
+	// Memoize wraps a pure transform in a generated cache keyed by its arguments,
+	// so repeated calls with the same input (e.g., per slice element) run once.
+	Memoize bool `yaml:"memoize,omitempty"`
+
+	// Template is an inline Go expression generated in place of a function call,
+	// written as a text/template over the argument expressions: .Source is the
+	// first source (e.g., "strings.ToUpper({{.Source}})"), .Args all arguments.
+	Template string `yaml:"template,omitempty"`
+
+	// Imports lists the import paths the template refers to (e.g., "strings").
+	Imports []string `yaml:"imports,omitempty"`
+
+	// Pos is where the transform is defined, set by Parse and LoadFile.
+	Pos diagnostic.Position `yaml:"-"`
+}
+
+// TransformTemplateData is the data a transform template is executed with.
+type TransformTemplateData struct {
+	// Source is the expression of the first argument.
+	Source string
+	// Args are the expressions of all arguments: sources, then extras.
+	Args []string
+}
+
+// ParseTemplate parses the template of a template transform.
+func (t *TransformDef) ParseTemplate() (*template.Template, error) {
+	return template.New(t.Name).Parse(t.Template)
+}
+
+// WrapperDef describes a generic wrapper type holding a single value of its
+// type argument, and the methods used to read and build it.
+type WrapperDef struct {
+	// Type is the generic type without type arguments, either fully qualified
+	// (e.g., "github.com/oapi-codegen/nullable.Nullable") or by its last path
+	// element (e.g., "nullable.Nullable").
+	Type string `yaml:"type"`
+
+	// Get is the method returning the wrapped value (e.g., "MustGet").
+	Get string `yaml:"get"`
+
+	// Present is an optional method reporting whether a value is set (e.g., "IsSpecified").
+	// When declared, unwrapping into *T yields nil for absent values.
+	Present string `yaml:"present,omitempty"`
+
+	// New is an optional constructor in the wrapper's package taking the value
+	// (e.g., "NewNullableWithValue").
+	New string `yaml:"new,omitempty"`
+
+	// Set is an optional pointer-receiver method storing the value (e.g., "Set").
+	// Used to build the wrapper when New is not declared.
+	Set string `yaml:"set,omitempty"`
+}
+
+// Matches reports whether id names the declared wrapper type.
+func (w *WrapperDef) Matches(id analyze.TypeID) bool {
+	full := id.String()
+
+	return w.Type != "" && (full == w.Type || strings.HasSuffix(full, "/"+w.Type))
+}
+
+// CanWrap reports whether the wrapper declares a way to build it from a value.
+func (w *WrapperDef) CanWrap() bool {
+	return w.New != "" || w.Set != ""
+}
+
+// ImplementationDef registers a concrete type pair for interface-typed fields.
+// Both types must also have a mapping so their caster is generated.
+type ImplementationDef struct {
+	// Source is the concrete source type (e.g., "store.CardPayment").
+	Source string `yaml:"source"`
+
+	// Target is the concrete target type (e.g., "warehouse.CardPayment").
+	Target string `yaml:"target"`
 }
 
 // MappingPriority represents the priority level of a mapping rule.
@@ -438,6 +1042,11 @@ type PathSegment struct {
 
 	// IsSlice indicates this segment accesses slice elements (e.g., "Items[]").
 	IsSlice bool
+
+	// Key is the Go literal of the index or map key of the single element the
+	// segment accesses (e.g., 0 in "Items[0]" or "color" quoted in
+	// `Attrs["color"]`), or empty.
+	Key string
 }
 
 // FieldPath represents a parsed field path like "Items[].ProductID".
@@ -460,6 +1069,8 @@ func (p FieldPath) String() string {
 
 		if seg.IsSlice {
 			resultSb396.WriteString("[]")
+		} else if seg.Key != "" {
+			resultSb396.WriteString("[" + seg.Key + "]")
 		}
 	}
 
@@ -468,9 +1079,16 @@ func (p FieldPath) String() string {
 	return result
 }
 
-// IsSimple returns true if this is a simple single-field path (no nesting, no slices).
+// IsSimple returns true if this is a simple single-field path (no nesting, no
+// slices, no index or key).
 func (p FieldPath) IsSimple() bool {
-	return len(p.Segments) == 1 && !p.Segments[0].IsSlice
+	return len(p.Segments) == 1 && !p.Segments[0].IsSlice && p.Segments[0].Key == ""
+}
+
+// IsKeyed returns true if a segment of the path accesses an element by index
+// or key (e.g., "Items[0].ID").
+func (p FieldPath) IsKeyed() bool {
+	return slices.ContainsFunc(p.Segments, func(seg PathSegment) bool { return seg.Key != "" })
 }
 
 // Root returns the first segment's field name.
@@ -482,6 +1100,16 @@ func (p FieldPath) Root() string {
 	return p.Segments[0].Name
 }
 
+// Last returns the last segment's field name, the name of the field the path
+// leads to.
+func (p FieldPath) Last() string {
+	if len(p.Segments) == 0 {
+		return ""
+	}
+
+	return p.Segments[len(p.Segments)-1].Name
+}
+
 // IsEmpty returns true if the path has no segments.
 func (p FieldPath) IsEmpty() bool {
 	return len(p.Segments) == 0
@@ -494,7 +1122,7 @@ func (p FieldPath) Equals(other FieldPath) bool {
 	}
 
 	for i, seg := range p.Segments {
-		if seg.Name != other.Segments[i].Name || seg.IsSlice != other.Segments[i].IsSlice {
+		if seg != other.Segments[i] {
 			return false
 		}
 	}
@@ -0,0 +1,38 @@
+package mapping
+
+import (
+	"fmt"
+	"slices"
+)
+
+// HasAnyTag reports whether the mapping is tagged with one of tags.
+func (tm *TypeMapping) HasAnyTag(tags []string) bool {
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.Contains(tm.Tags, tag)
+	})
+}
+
+// TaggedTypePairs returns the "Source->Target" keys, as written in the mapping
+// file, of the mappings tagged with one of tags. Returns an error if a tag is
+// used by no mapping, which usually is a typo.
+func TaggedTypePairs(mf *MappingFile, tags []string) (map[string]bool, error) {
+	pairs := make(map[string]bool)
+
+	for _, tag := range tags {
+		found := false
+
+		for i := range mf.TypeMappings {
+			tm := &mf.TypeMappings[i]
+			if slices.Contains(tm.Tags, tag) {
+				pairs[tm.Key()] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("tag %q is not used by any mapping", tag)
+		}
+	}
+
+	return pairs, nil
+}
@@ -0,0 +1,37 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaggedTypePairs(t *testing.T) {
+	mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    tags: [billing, critical]
+  - source: store.Invoice
+    target: warehouse.Invoice
+    tags: [billing]
+  - source: store.Item
+    target: warehouse.Item
+`))
+	require.NoError(t, err)
+
+	assert.True(t, mf.TypeMappings[0].HasAnyTag([]string{"critical"}))
+	assert.False(t, mf.TypeMappings[2].HasAnyTag([]string{"critical"}))
+
+	pairs, err := TaggedTypePairs(mf, []string{"critical"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"store.Order->warehouse.Order": true}, pairs)
+
+	pairs, err = TaggedTypePairs(mf, []string{"billing"})
+	require.NoError(t, err)
+	assert.Len(t, pairs, 2)
+
+	_, err = TaggedTypePairs(mf, []string{"critical", "typo"})
+	require.ErrorContains(t, err, `tag "typo"`)
+}
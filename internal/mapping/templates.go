@@ -0,0 +1,67 @@
+package mapping
+
+import (
+	"fmt"
+	"slices"
+)
+
+// expandTemplates adds the field mappings of the templates each type mapping
+// of mf uses to its fields, after its own and in the order of use. Template
+// rules for a target the mapping already maps (by 121, fields or an earlier
+// template) or ignores are skipped, so the mapping's own rules win.
+func expandTemplates(mf *MappingFile) error {
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+		if len(tm.Use) == 0 {
+			continue
+		}
+
+		mapped := make(map[string]bool)
+
+		for _, target := range tm.OneToOne {
+			mapped[target] = true
+		}
+
+		for _, fm := range tm.Fields {
+			for _, ref := range fm.Target {
+				mapped[ref.Path] = true
+			}
+		}
+
+		for _, entry := range tm.Ignore {
+			mapped[entry.Target] = true
+		}
+
+		for _, name := range tm.Use {
+			rules, ok := mf.Templates[name]
+			if !ok {
+				return templateError(tm, name)
+			}
+
+			for _, fm := range rules {
+				if slices.ContainsFunc(fm.Target, func(ref FieldRef) bool { return mapped[ref.Path] }) {
+					continue
+				}
+
+				for _, ref := range fm.Target {
+					mapped[ref.Path] = true
+				}
+
+				tm.Fields = append(tm.Fields, fm)
+			}
+		}
+	}
+
+	return nil
+}
+
+// templateError reports that tm uses the unknown template name, at the
+// position of tm if known.
+func templateError(tm *TypeMapping, name string) error {
+	err := fmt.Errorf("mapping %s uses unknown template %q", tm.Key(), name)
+	if tm.Pos.IsValid() {
+		err = fmt.Errorf("%s: %w", tm.Pos, err)
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldTargets(fields []FieldMapping) []string {
+	targets := make([]string, 0, len(fields))
+	for _, fm := range fields {
+		targets = append(targets, fm.Target.First())
+	}
+
+	return targets
+}
+
+func TestParse_Templates(t *testing.T) {
+	mf, err := Parse([]byte(`
+templates:
+  audit:
+    - target: CreatedAt
+      source: Created
+    - target: UpdatedAt
+      source: Updated
+    - target: [CreatedBy, UpdatedBy]
+      source: Author
+  ids:
+    - target: ID
+      source: UUID
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    use: [audit, ids]
+    121: {Modified: UpdatedAt}
+    fields:
+      - target: Total
+        source: Price
+  - source: store.Item
+    target: warehouse.Item
+    use: audit
+    ignore: [UpdatedBy]
+`))
+	require.NoError(t, err)
+
+	order, item := mf.TypeMappings[0], mf.TypeMappings[1]
+
+	// The mapping's own rules come first and win over the templates'
+	assert.Equal(t, []string{"Total", "CreatedAt", "CreatedBy", "ID"}, fieldTargets(order.Fields))
+	assert.Equal(t, []string{"CreatedAt", "UpdatedAt"}, fieldTargets(item.Fields))
+
+	// Template rules keep the position of their definition
+	assert.Equal(t, 4, order.Fields[1].Pos.Line)
+}
+
+func TestParse_UnknownTemplate(t *testing.T) {
+	_, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    use: audit
+`))
+	require.Error(t, err)
+	assert.Equal(t, `line 3, column 5: mapping store.Order->warehouse.Order uses unknown template "audit"`, err.Error())
+}
+
+func TestLoadFile_IncludedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"mapping.yaml": `
+include: [shared.yaml]
+templates:
+  ids:
+    - target: ID
+      source: Key
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    use: [audit, ids]
+`,
+		"shared.yaml": `
+templates:
+  audit:
+    - target: CreatedAt
+      source: Created
+  ids:
+    - target: ID
+      source: UUID
+mappings:
+  - source: store.Item
+    target: warehouse.Item
+    use: ids
+`,
+	})
+
+	mf, err := LoadFile(filepath.Join(dir, "mapping.yaml"))
+	require.NoError(t, err)
+	require.Len(t, mf.TypeMappings, 2)
+
+	// The including file's templates take precedence, also for included mappings
+	order, item := mf.TypeMappings[0], mf.TypeMappings[1]
+	assert.Equal(t, []string{"CreatedAt", "ID"}, fieldTargets(order.Fields))
+	assert.Equal(t, "Key", order.Fields[1].Source.First())
+	assert.Equal(t, "Key", item.Fields[0].Source.First())
+	assert.Equal(t, filepath.Join(dir, "shared.yaml"), order.Fields[0].Pos.File)
+}
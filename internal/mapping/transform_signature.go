@@ -0,0 +1,278 @@
+package mapping
+
+import (
+	"fmt"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+)
+
+// ValidateTransformSignatures checks transforms that refer to real Go functions
+// against their declarations in the type graph.
+//
+// For every field mapping using such a transform, the function's parameters are
+// compared with the source field (and extra) types, and its single result with the
+// target field type. Transforms without a package qualifier are skipped: their stubs
+// are generated into the casters package and cannot drift.
+func ValidateTransformSignatures(mf *MappingFile, graph *analyze.TypeGraph) *diagnostic.Diagnostics {
+	res := &diagnostic.Diagnostics{}
+	if mf == nil || graph == nil {
+		return res
+	}
+
+	defs := make(map[string]*TransformDef, len(mf.Transforms))
+	for i := range mf.Transforms {
+		defs[mf.Transforms[i].Name] = &mf.Transforms[i]
+	}
+
+	reportedMissing := make(map[string]bool)
+
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+		tpStr := fmt.Sprintf("%s->%s", tm.Source, tm.Target)
+
+		srcT := ResolveTypeID(tm.Source, graph)
+		dstT := ResolveTypeID(tm.Target, graph)
+
+		if srcT == nil || dstT == nil {
+			continue
+		}
+
+		for _, fm := range append(append([]FieldMapping{}, tm.Fields...), tm.Auto...) {
+			if fm.Transform == "" {
+				continue
+			}
+
+			funcRef := transformFuncRef(defs[fm.Transform], fm.Transform)
+			if funcRef == "" {
+				continue
+			}
+
+			fn := ResolveFunc(funcRef, graph)
+			if fn == nil {
+				if !reportedMissing[funcRef] {
+					reportedMissing[funcRef] = true
+
+					mark := res.Mark()
+					res.AddWarning("transform_func_not_found",
+						fmt.Sprintf("transform %q: function %s not found in loaded packages; signature not checked",
+							fm.Transform, funcRef),
+						tpStr, "")
+					res.Locate(mark, fm.Pos)
+				}
+
+				continue
+			}
+
+			// Wildcard rules are checked per field they expand to
+			rules := []FieldMapping{fm}
+			if fm.IsWildcard() {
+				rules, _ = WildcardFieldMappings(&fm, srcT, dstT)
+			}
+
+			def := defs[fm.Transform]
+			mark := res.Mark()
+
+			for _, rule := range rules {
+				checkTransformCall(res, tpStr, tm, &rule, def != nil && def.Ctx, fn, srcT, dstT)
+			}
+
+			res.Locate(mark, fm.Pos)
+		}
+	}
+
+	return res
+}
+
+// transformFuncRef returns the qualified function reference ("pkg.Func") for a transform,
+// or an empty string if the transform lives in the generated package or is a template.
+func transformFuncRef(def *TransformDef, name string) string {
+	if def != nil && def.Template != "" {
+		return ""
+	}
+
+	if def != nil && def.Package != "" {
+		fn := def.Func
+		if fn == "" {
+			fn = def.Name
+		}
+
+		return def.Package + "." + fn
+	}
+
+	if def != nil && strings.Contains(def.Func, ".") {
+		return def.Func
+	}
+
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	return ""
+}
+
+// checkTransformCall compares the argument and result types of a single transform usage
+// with the declared function signature.
+func checkTransformCall(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	tm *TypeMapping,
+	fm *FieldMapping,
+	withCtx bool,
+	fn *analyze.FuncInfo,
+	srcT, dstT *analyze.TypeInfo,
+) {
+	// Generic functions are instantiated at the call site; their parameters can't be compared directly.
+	if fn.Signature != nil && fn.Signature.TypeParams().Len() > 0 {
+		return
+	}
+
+	target := fm.Target.First()
+	args := transformArgTypes(tm, fm, srcT, dstT)
+	if withCtx {
+		// The context.Context argument is supplied by the caster; its type is not checked here.
+		args = append([]transformArg{{desc: "ctx"}}, args...)
+	}
+
+	arityOK := len(args) == len(fn.Params)
+	if fn.Variadic {
+		arityOK = len(args) >= len(fn.Params)-1
+	}
+
+	if !arityOK {
+		res.AddError("transform_signature_mismatch",
+			fmt.Sprintf("transform %q: %s takes %d argument(s), mapping passes %d",
+				fm.Transform, fn.ID, len(fn.Params), len(args)),
+			typePairStr, target)
+
+		return
+	}
+
+	for i, arg := range args {
+		param := paramTypeAt(fn, i)
+		if arg.lit != nil && param != nil && !constFits(arg.lit, param) {
+			res.AddError("transform_signature_mismatch",
+				fmt.Sprintf("transform %q: argument %d (%s) is %s constant, %s expects %s",
+					fm.Transform, i+1, arg.desc, literalKind(arg.lit), fn.ID, param),
+				typePairStr, target)
+
+			continue
+		}
+
+		if arg.typ == nil || arg.typ.GoType == nil || param == nil {
+			continue
+		}
+
+		if !types.AssignableTo(arg.typ.GoType, param) {
+			res.AddError("transform_signature_mismatch",
+				fmt.Sprintf("transform %q: argument %d (%s) has type %s, %s expects %s",
+					fm.Transform, i+1, arg.desc, arg.typ.GoType, fn.ID, param),
+				typePairStr, target)
+		}
+	}
+
+	if len(fn.Results) != 1 {
+		res.AddError("transform_signature_mismatch",
+			fmt.Sprintf("transform %q: %s must return exactly one value, returns %d",
+				fm.Transform, fn.ID, len(fn.Results)),
+			typePairStr, target)
+
+		return
+	}
+
+	if target == "" {
+		return
+	}
+
+	targetType, err := resolvePathType(target, dstT)
+	if err != nil || targetType == nil || targetType.GoType == nil || fn.Results[0].GoType == nil {
+		return
+	}
+
+	if !types.AssignableTo(fn.Results[0].GoType, targetType.GoType) {
+		res.AddError("transform_signature_mismatch",
+			fmt.Sprintf("transform %q: %s returns %s, target field %s has type %s",
+				fm.Transform, fn.ID, fn.Results[0].GoType, target, targetType.GoType),
+			typePairStr, target)
+	}
+}
+
+// transformArg describes one argument passed to a transform at a call site.
+type transformArg struct {
+	desc string
+	typ  *analyze.TypeInfo // nil if the type is unknown (e.g., untyped requires arg)
+	lit  constant.Value    // value of a const extra, which is untyped
+}
+
+// transformArgTypes returns the arguments the generator passes to a transform:
+// the source paths first, followed by extras in declaration order.
+func transformArgTypes(tm *TypeMapping, fm *FieldMapping, srcT, dstT *analyze.TypeInfo) []transformArg {
+	var args []transformArg
+
+	for _, s := range fm.Source {
+		arg := transformArg{desc: "source " + s.Path}
+		if !isRequiredArg(s.Path, tm) {
+			arg.typ, _ = resolvePathType(s.Path, srcT)
+		}
+
+		args = append(args, arg)
+	}
+
+	for _, ev := range fm.Extra {
+		arg := transformArg{desc: "extra " + ev.Name}
+
+		switch {
+		case ev.Const != "":
+			arg.lit, _ = ev.Const.Value()
+		case ev.Def.Source != "":
+			arg.typ, _ = resolvePathType(ev.Def.Source, srcT)
+		case ev.Def.Target != "":
+			arg.typ, _ = resolvePathType(ev.Def.Target, dstT)
+		}
+
+		args = append(args, arg)
+	}
+
+	return args
+}
+
+// constFits reports whether untyped constant v can be passed to a parameter of
+// type param: one of a basic type it is a valid value of, or an empty interface.
+func constFits(v constant.Value, param types.Type) bool {
+	switch u := param.Underlying().(type) {
+	case *types.Basic:
+		return literalFits(v, u.Name())
+	case *types.Interface:
+		return u.NumMethods() == 0
+	default:
+		return false
+	}
+}
+
+// paramTypeAt returns the go/types parameter type accepted at position i,
+// unwrapping the variadic slice for trailing arguments.
+func paramTypeAt(fn *analyze.FuncInfo, i int) types.Type {
+	if fn.Signature == nil {
+		return nil
+	}
+
+	params := fn.Signature.Params()
+	last := params.Len() - 1
+
+	if fn.Variadic && i >= last {
+		if slice, ok := params.At(last).Type().(*types.Slice); ok {
+			return slice.Elem()
+		}
+
+		return nil
+	}
+
+	if i > last {
+		return nil
+	}
+
+	return params.At(i).Type()
+}
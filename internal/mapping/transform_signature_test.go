@@ -0,0 +1,208 @@
+package mapping
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+// buildSignatureTestGraph creates a type graph with go/types-backed fields and
+// a conv package exposing a few transform functions.
+func buildSignatureTestGraph() *analyze.TypeGraph {
+	graph := analyze.NewTypeGraph()
+
+	basic := func(k types.BasicKind) *analyze.TypeInfo {
+		return &analyze.TypeInfo{Kind: analyze.TypeKindBasic, GoType: types.Typ[k]}
+	}
+
+	strT := basic(types.String)
+	intT := basic(types.Int)
+	floatT := basic(types.Float64)
+
+	src := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/src", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Price", Exported: true, Type: floatT, Index: 0},
+			{Name: "Name", Exported: true, Type: strT, Index: 1},
+		},
+	}
+	dst := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/dst", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Cents", Exported: true, Type: intT, Index: 0},
+			{Name: "Label", Exported: true, Type: strT, Index: 1},
+		},
+	}
+	graph.Types[src.ID] = src
+	graph.Types[dst.ID] = dst
+
+	addFunc := func(name string, params, results []*analyze.TypeInfo) {
+		vars := func(ts []*analyze.TypeInfo) []*types.Var {
+			out := make([]*types.Var, 0, len(ts))
+			for _, t := range ts {
+				out = append(out, types.NewParam(0, nil, "", t.GoType))
+			}
+
+			return out
+		}
+
+		id := analyze.TypeID{PkgPath: "example/conv", Name: name}
+		graph.Funcs[id] = &analyze.FuncInfo{
+			ID:        id,
+			Params:    params,
+			Results:   results,
+			Signature: types.NewSignatureType(nil, nil, nil, types.NewTuple(vars(params)...), types.NewTuple(vars(results)...), false),
+		}
+	}
+
+	addFunc("DollarsToCents", []*analyze.TypeInfo{floatT}, []*analyze.TypeInfo{intT})
+	addFunc("Format", []*analyze.TypeInfo{strT, floatT}, []*analyze.TypeInfo{strT})
+	addFunc("Pair", []*analyze.TypeInfo{floatT}, []*analyze.TypeInfo{intT, strT})
+	addFunc("Round", []*analyze.TypeInfo{floatT, intT}, []*analyze.TypeInfo{intT})
+
+	return graph
+}
+
+func TestValidateTransformSignatures_Match(t *testing.T) {
+	yaml := `
+mappings:
+  - source: example/src.Order
+    target: example/dst.Order
+    fields:
+      - target: Cents
+        source: Price
+        transform: conv.DollarsToCents
+      - target: Label
+        source: [Name, Price]
+        transform: conv.Format
+      - target: Label
+        source: Name
+        transform: conv.Format
+        extra: [{name: scale, const: 100}]
+      - target: Cents
+        source: Price
+        transform: conv.Round
+        extra:
+          places: {const: 2}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	diags := ValidateTransformSignatures(mf, buildSignatureTestGraph())
+	assert.Empty(t, diags.Errors)
+	assert.Empty(t, diags.Warnings)
+}
+
+func TestValidateTransformSignatures_Mismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+	}{
+		{
+			name: "wrong argument type",
+			fields: `      - target: Cents
+        source: Name
+        transform: conv.DollarsToCents`,
+		},
+		{
+			name: "wrong argument count",
+			fields: `      - target: Label
+        source: Name
+        transform: conv.Format`,
+		},
+		{
+			name: "wrong result type",
+			fields: `      - target: Label
+        source: Price
+        transform: conv.DollarsToCents`,
+		},
+		{
+			name: "wrong const type",
+			fields: `      - target: Cents
+        source: Price
+        transform: conv.Round
+        extra: [{name: places, const: "2"}]`,
+		},
+		{
+			name: "const out of range",
+			fields: `      - target: Cents
+        source: Price
+        transform: conv.Round
+        extra: [{name: places, const: 1.5}]`,
+		},
+		{
+			name: "multiple results",
+			fields: `      - target: Cents
+        source: Price
+        transform: conv.Pair`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := `
+mappings:
+  - source: example/src.Order
+    target: example/dst.Order
+    fields:
+` + tt.fields + "\n"
+
+			mf, err := Parse([]byte(yaml))
+			require.NoError(t, err)
+
+			diags := ValidateTransformSignatures(mf, buildSignatureTestGraph())
+			require.Len(t, diags.Errors, 1)
+			assert.Equal(t, "transform_signature_mismatch", diags.Errors[0].Code)
+		})
+	}
+}
+
+func TestValidateTransformSignatures_PackageField(t *testing.T) {
+	yaml := `
+mappings:
+  - source: example/src.Order
+    target: example/dst.Order
+    fields:
+      - target: Label
+        source: Price
+        transform: ToCents
+transforms:
+  - name: ToCents
+    package: example/conv
+    func: DollarsToCents
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	diags := ValidateTransformSignatures(mf, buildSignatureTestGraph())
+	require.Len(t, diags.Errors, 1)
+	assert.Contains(t, diags.Errors[0].Message, "returns int")
+}
+
+func TestValidateTransformSignatures_SkipsUnqualifiedAndMissing(t *testing.T) {
+	yaml := `
+mappings:
+  - source: example/src.Order
+    target: example/dst.Order
+    fields:
+      - target: Cents
+        source: Name
+        transform: LocalStub
+      - target: Label
+        source: Name
+        transform: conv.Unknown
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	diags := ValidateTransformSignatures(mf, buildSignatureTestGraph())
+	assert.Empty(t, diags.Errors)
+	require.Len(t, diags.Warnings, 1)
+	assert.Equal(t, "transform_func_not_found", diags.Warnings[0].Code)
+}
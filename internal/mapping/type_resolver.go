@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"go/types"
 	"strings"
 
 	"caster-generator/internal/analyze"
@@ -9,12 +10,17 @@ import (
 // ResolveTypeID resolves a type ID string like:
 // - "store.Order" (short)
 // - "caster-generator/store.Order" (full)
-// - "Order" (name only).
+// - "Order" (name only)
+// - "store.Page[store.Order]" (generic instantiation).
 func ResolveTypeID(typeIDStr string, graph *analyze.TypeGraph) *analyze.TypeInfo {
 	if graph == nil {
 		return nil
 	}
 
+	if strings.HasSuffix(typeIDStr, "]") && strings.Contains(typeIDStr, "[") {
+		return resolveInstance(typeIDStr, graph)
+	}
+
 	// Name-only: best-effort match by type name.
 	if !strings.Contains(typeIDStr, ".") {
 		name := typeIDStr
@@ -23,7 +29,7 @@ func ResolveTypeID(typeIDStr string, graph *analyze.TypeGraph) *analyze.TypeInfo
 		}
 
 		for id, t := range graph.Types {
-			if id.Name == name {
+			if id.Name == name && id.Args == "" {
 				return t
 			}
 		}
@@ -50,7 +56,7 @@ func ResolveTypeID(typeIDStr string, graph *analyze.TypeGraph) *analyze.TypeInfo
 
 	// 2) suffix match (for short forms like "store.Order" vs "caster-generator/store.Order")
 	for id, t := range graph.Types {
-		if id.Name != name {
+		if id.Name != name || id.Args != "" {
 			continue
 		}
 
@@ -61,3 +67,121 @@ func ResolveTypeID(typeIDStr string, graph *analyze.TypeGraph) *analyze.TypeInfo
 
 	return nil
 }
+
+// ResolveFunc resolves a qualified function name like:
+// - "transforms.DollarsToCents" (short)
+// - "caster-generator/examples/transforms.DollarsToCents" (full).
+// Unqualified names are not resolved since they refer to the generated package.
+func ResolveFunc(funcStr string, graph *analyze.TypeGraph) *analyze.FuncInfo {
+	if graph == nil {
+		return nil
+	}
+
+	lastDot := strings.LastIndex(funcStr, ".")
+	if lastDot <= 0 || lastDot == len(funcStr)-1 {
+		return nil
+	}
+
+	pkgStr := funcStr[:lastDot]
+	name := funcStr[lastDot+1:]
+
+	if fn := graph.GetFunc(analyze.TypeID{PkgPath: pkgStr, Name: name}); fn != nil {
+		return fn
+	}
+
+	// Of several packages ending in pkgStr, the first by path is taken, so
+	// the result doesn't depend on map order.
+	var found *analyze.FuncInfo
+
+	for id, fn := range graph.Funcs {
+		if id.Name == name && strings.HasSuffix(id.PkgPath, "/"+pkgStr) &&
+			(found == nil || id.PkgPath < found.ID.PkgPath) {
+			found = fn
+		}
+	}
+
+	return found
+}
+
+// resolveInstance resolves an instantiation of a generic type, such as
+// "store.Page[store.Order]", instantiating it when the graph doesn't have it yet.
+func resolveInstance(typeIDStr string, graph *analyze.TypeGraph) *analyze.TypeInfo {
+	open := strings.Index(typeIDStr, "[")
+
+	generic := ResolveTypeID(typeIDStr[:open], graph)
+	if generic == nil || !generic.IsGeneric() {
+		return nil
+	}
+
+	var args []types.Type
+
+	for _, argStr := range splitTypeArgs(typeIDStr[open+1 : len(typeIDStr)-1]) {
+		arg := resolveTypeArg(argStr, graph)
+		if arg == nil {
+			return nil
+		}
+
+		args = append(args, arg)
+	}
+
+	inst, err := graph.Instantiate(generic, args)
+	if err != nil {
+		return nil
+	}
+
+	return inst
+}
+
+// resolveTypeArg resolves a type argument: a predeclared type, a type known
+// to the graph, or a pointer or slice of one.
+func resolveTypeArg(argStr string, graph *analyze.TypeGraph) types.Type {
+	switch {
+	case strings.HasPrefix(argStr, "*"):
+		if elem := resolveTypeArg(argStr[1:], graph); elem != nil {
+			return types.NewPointer(elem)
+		}
+
+		return nil
+	case strings.HasPrefix(argStr, "[]"):
+		if elem := resolveTypeArg(argStr[2:], graph); elem != nil {
+			return types.NewSlice(elem)
+		}
+
+		return nil
+	}
+
+	if obj, ok := types.Universe.Lookup(argStr).(*types.TypeName); ok {
+		return obj.Type()
+	}
+
+	if t := ResolveTypeID(argStr, graph); t != nil && t.GoType != nil {
+		return t.GoType
+	}
+
+	return nil
+}
+
+// splitTypeArgs splits a type argument list on its top-level commas.
+func splitTypeArgs(list string) []string {
+	var (
+		args  []string
+		depth int
+		start int
+	)
+
+	for i, r := range list {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(args, strings.TrimSpace(list[start:]))
+}
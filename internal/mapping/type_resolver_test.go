@@ -0,0 +1,43 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestSplitTypeArgs(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"string", []string{"string"}},
+		{"store.Order, int", []string{"store.Order", "int"}},
+		{"store.Page[store.Order], []int", []string{"store.Page[store.Order]", "[]int"}},
+		{"store.Pair[int, string],*store.Item", []string{"store.Pair[int, string]", "*store.Item"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, splitTypeArgs(tt.list), tt.list)
+	}
+}
+
+func TestResolveFunc(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+	for _, pkg := range []string{"example.com/b/conv", "example.com/a/conv", "example.com/c/conv"} {
+		id := analyze.TypeID{PkgPath: pkg, Name: "Parse"}
+		graph.Funcs[id] = &analyze.FuncInfo{ID: id}
+	}
+
+	// Suffix matches in several packages always resolve to the first by path.
+	for range 10 {
+		fn := ResolveFunc("conv.Parse", graph)
+		assert.Equal(t, "example.com/a/conv", fn.ID.PkgPath)
+	}
+
+	assert.Equal(t, "example.com/c/conv", ResolveFunc("example.com/c/conv.Parse", graph).ID.PkgPath)
+	assert.Nil(t, ResolveFunc("conv.Format", graph))
+	assert.Nil(t, ResolveFunc("Parse", graph))
+}
@@ -1,10 +1,16 @@
 package mapping
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"caster-generator/internal/analyze"
 	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
 )
 
 // Validate validates a mapping definition against the given type graph.
@@ -22,6 +28,12 @@ func Validate(mf *MappingFile, graph *analyze.TypeGraph) *diagnostic.Diagnostics
 		return res
 	}
 
+	for _, c := range mf.IncludeConflicts {
+		res.AddError("include_conflict",
+			fmt.Sprintf("%s %q is defined differently in %s; define it in the including file to choose one",
+				c.Kind, c.Key, strings.Join(c.Files, ", ")), "", c.Key)
+	}
+
 	// Validate transform defs: detect duplicates (required by tests).
 	seenTransforms := map[string]struct{}{}
 
@@ -31,63 +43,260 @@ func Validate(mf *MappingFile, graph *analyze.TypeGraph) *diagnostic.Diagnostics
 			continue
 		}
 
+		mark := res.Mark()
+
 		if _, ok := seenTransforms[name]; ok {
 			res.AddError("duplicate_transform", fmt.Sprintf("duplicate transform %q", name), "", name)
-			continue
+		} else {
+			seenTransforms[name] = struct{}{}
+
+			validateTransformTemplate(res, &mf.Transforms[i])
+			validateMemoize(res, &mf.Transforms[i])
 		}
 
-		seenTransforms[name] = struct{}{}
+		res.Locate(mark, mf.Transforms[i].Pos)
 	}
 
+	if !mf.CopyMode.IsValid() {
+		res.AddError("invalid_copy_mode",
+			fmt.Sprintf("invalid copy_mode %q (expected alias, shallow or deep)", mf.CopyMode), "", "copy_mode")
+	}
+
+	if !mf.OnIncompatiblePin.IsValid() {
+		res.AddError("invalid_pin_policy",
+			fmt.Sprintf("invalid on_incompatible_pin %q (expected error, todo or fallback_auto)", mf.OnIncompatiblePin),
+			"", "on_incompatible_pin")
+	}
+
+	if !validConfidence(mf.Policy.MinAutoConfidence) {
+		res.AddError("invalid_policy",
+			fmt.Sprintf("invalid min_auto_confidence %v (expected a number from 0 to 1)", mf.Policy.MinAutoConfidence),
+			"", "policy.min_auto_confidence")
+	}
+
+	validateCurrency(res, &mf.Currency)
+
+	if !mf.GeneratedTypes.FieldOrder.IsValid() {
+		res.AddError("invalid_field_order",
+			fmt.Sprintf("invalid field_order %q (expected yaml, alphabetical or source)", mf.GeneratedTypes.FieldOrder),
+			"", "generated_types.field_order")
+	}
+
+	for _, key := range mf.IgnoreTags {
+		if !isTagKey(key) {
+			res.AddError("invalid_ignore_tag",
+				fmt.Sprintf("invalid ignore_tags key %q (expected a struct tag key, e.g. json)", key), "", "ignore_tags")
+		}
+	}
+
+	for _, pattern := range mf.IgnorePatterns {
+		if _, err := CompileFieldPattern(pattern); err != nil {
+			res.AddError("invalid_ignore_pattern", err.Error(), "", "ignore_patterns")
+		}
+	}
+
+	for _, key := range mf.GeneratedTypes.CopyTags {
+		if !isTagKey(key) {
+			res.AddError("invalid_copy_tag",
+				fmt.Sprintf("invalid copy_tags key %q (expected a struct tag key, e.g. json)", key), "",
+				"generated_types.copy_tags")
+		}
+	}
+
+	for _, affix := range mf.StripAffixes {
+		if !isNameToken(affix) {
+			res.AddError("invalid_strip_affix",
+				fmt.Sprintf("invalid strip_affixes entry %q (expected a single name token, e.g. DTO or V1)", affix),
+				"", "strip_affixes")
+		}
+	}
+
+	if !validConfidence(mf.Match.PositionWeight) {
+		res.AddError("invalid_matching",
+			fmt.Sprintf("invalid match position_weight %v (expected a number from 0 to 1)", mf.Match.PositionWeight),
+			"", "match.position_weight")
+	}
+
+	if mf.Match.Tag != "" && !isTagKey(mf.Match.Tag) {
+		res.AddError("invalid_matching", fmt.Sprintf("invalid match tag %q (expected a struct tag key)", mf.Match.Tag),
+			"", "match.tag")
+	}
+
+	validateAbbreviations(res, mf.Match.Abbreviations)
+	validateSynonyms(res, mf.Match.Synonyms, mf.Normalizer())
+
+	if mf.Naming.Func != "" {
+		if _, err := ParseFuncTemplate(mf.Naming.Func); err != nil {
+			res.AddError("invalid_naming", err.Error(), "", "naming.func")
+		}
+	}
+
+	validateWrappers(res, mf.Wrappers)
+	validateImplementations(res, mf, graph)
+
+	outputDirs := make(map[string]string)
+
 	for i := range mf.TypeMappings {
 		tm := &mf.TypeMappings[i]
-		tpStr := fmt.Sprintf("%s->%s", tm.Source, tm.Target)
 
-		srcT := ResolveTypeID(tm.Source, graph)
-		if srcT == nil {
-			res.AddError("source_type_not_found", fmt.Sprintf("source type %q not found", tm.Source), tpStr, tm.Source)
-			continue
+		mark := res.Mark()
+		validateTypeMapping(res, mf, tm, graph, seenTransforms, outputDirs)
+		res.Locate(mark, tm.Pos)
+	}
+
+	return res
+}
+
+// validateTypeMapping validates a type mapping against the type graph.
+func validateTypeMapping(
+	res *diagnostic.Diagnostics,
+	mf *MappingFile,
+	tm *TypeMapping,
+	graph *analyze.TypeGraph,
+	seenTransforms map[string]struct{},
+	outputDirs map[string]string,
+) {
+	tpStr := fmt.Sprintf("%s->%s", tm.Source, tm.Target)
+
+	if tm.IsPackageMapping() {
+		res.AddError("unexpanded_package_mapping",
+			"package mappings must be expanded with ExpandPackageMappings before validation",
+			fmt.Sprintf("%s->%s", tm.SourcePkg, tm.TargetPkg), "")
+
+		return
+	}
+
+	if !tm.CopyMode.IsValid() {
+		res.AddError("invalid_copy_mode",
+			fmt.Sprintf("invalid copy_mode %q (expected alias, shallow or deep)", tm.CopyMode), tpStr, "copy_mode")
+	}
+
+	if tm.MaxDepth < 0 {
+		res.AddError("invalid_max_depth", fmt.Sprintf("invalid max_depth %d (expected 0 or more)", tm.MaxDepth),
+			tpStr, "max_depth")
+	}
+
+	for _, threshold := range []struct {
+		key   string
+		value float64
+	}{
+		{"min_confidence", tm.Matching.MinConfidence},
+		{"min_gap", tm.Matching.MinGap},
+		{"ambiguity_threshold", tm.Matching.AmbiguityThreshold},
+	} {
+		if !validConfidence(threshold.value) {
+			res.AddError("invalid_matching",
+				fmt.Sprintf("invalid matching %s %v (expected a number from 0 to 1)", threshold.key, threshold.value),
+				tpStr, "matching."+threshold.key)
 		}
+	}
 
-		dstT := ResolveTypeID(tm.Target, graph)
-		if dstT == nil {
-			// If GenerateTarget is true, skip target type validation
-			// The target type will be generated during resolution
-			if tm.GenerateTarget {
-				// Skip field validation against target for generated types
-				continue
-			}
+	validateOutput(res, tpStr, tm.Output, outputDirs)
+	validateFieldTags(res, tpStr, tm)
 
-			res.AddError("target_type_not_found", fmt.Sprintf("target type %q not found", tm.Target), tpStr, tm.Target)
+	srcT := ResolveTypeID(tm.Source, graph)
+	if srcT == nil {
+		res.AddError("source_type_not_found", fmt.Sprintf("source type %q not found", tm.Source), tpStr, tm.Source)
+		return
+	}
+
+	if tm.GenerateTarget {
+		validateWritableTarget(res, tpStr, tm.Target, graph)
+	}
+
+	if tm.Method != "" {
+		validateMethod(res, tpStr, tm, srcT, graph)
+	}
+
+	dstT := ResolveTypeID(tm.Target, graph)
+	if dstT == nil {
+		// If GenerateTarget is true, skip target type validation
+		// The target type will be generated during resolution
+		if tm.GenerateTarget {
+			// Skip field validation against target for generated types
+			return
+		}
+
+		res.AddError("target_type_not_found", fmt.Sprintf("target type %q not found", tm.Target), tpStr, tm.Target)
+
+		return
+	}
+
+	if srcT.IsGeneric() || dstT.IsGeneric() {
+		res.AddError("generic_type_not_instantiated",
+			"generic types must be mapped per instantiation (e.g., store.Page[store.Order])", tpStr, "")
 
+		return
+	}
+
+	validateHooks(res, tpStr, tm, srcT, dstT, graph)
+
+	if tm.Via != "" {
+		validateVia(res, tpStr, mf, tm, srcT, dstT, graph)
+		return
+	}
+
+	// 121 shorthand
+	for _, sp := range slices.Sorted(maps.Keys(tm.OneToOne)) {
+		tp := tm.OneToOne[sp]
+		if IsWildcard(sp) || IsWildcard(tp) {
 			continue
 		}
 
-		// 121 shorthand
-		for sp, tp := range tm.OneToOne {
-			if err := validatePathAgainstType(sp, srcT); err != nil {
-				res.AddError("invalid_source_path", fmt.Sprintf("invalid source path in 121: %v", err), tpStr, sp)
-			}
+		if err := validatePathAgainstType(sp, srcT, tm.AllowUnexported); err != nil {
+			want, _ := resolvePathType(tp, dstT)
+			addPathError(res, tpStr, "invalid_source_path", "invalid source path in 121", sp, err, srcT, want, tm, false)
+		}
+
+		if err := validateTargetPath(tp, dstT, tm.AllowUnexported); err != nil {
+			want, _ := resolvePathType(sp, srcT)
+			addPathError(res, tpStr, "invalid_target_path", "invalid target path in 121", tp, err, dstT, want, tm, true)
+		}
+	}
+
+	validateFlatten(res, tpStr, srcT, dstT, tm)
+	validateWildcards(res, tpStr, srcT, dstT, tm)
+
+	// fields + auto, wildcard rules as the fields they expand to
+	for _, fm := range append(append([]FieldMapping{}, tm.Fields...), tm.Auto...) {
+		mark := res.Mark()
 
-			if err := validatePathAgainstType(tp, dstT); err != nil {
-				res.AddError("invalid_target_path", fmt.Sprintf("invalid target path in 121: %v", err), tpStr, tp)
+		rules := []FieldMapping{fm}
+		if fm.IsWildcard() {
+			var err error
+			if rules, err = WildcardFieldMappings(&fm, srcT, dstT); err != nil {
+				res.AddError("invalid_wildcard", err.Error(), tpStr, fm.Target.First())
 			}
 		}
 
-		// fields + auto
-		for _, fm := range append(append([]FieldMapping{}, tm.Fields...), tm.Auto...) {
-			validateFieldMapping(res, tpStr, srcT, dstT, tm, &fm, seenTransforms)
+		for _, rule := range rules {
+			validateFieldMapping(res, tpStr, srcT, dstT, tm, &rule, seenTransforms, graph)
 		}
 
-		// ignore paths
-		for _, ig := range tm.Ignore {
-			if err := validatePathAgainstType(ig, dstT); err != nil {
-				res.AddError("invalid_ignore_path", fmt.Sprintf("invalid ignore path: %v", err), tpStr, ig)
-			}
+		res.Locate(mark, fm.Pos)
+	}
+
+	// ignore paths
+	for _, ig := range tm.Ignore {
+		mark := res.Mark()
+
+		if err := validateTargetPath(ig.Target, dstT, tm.AllowUnexported); err != nil {
+			addPathError(res, tpStr, "invalid_ignore_path", "invalid ignore path", ig.Target, err, dstT, nil, tm, true)
+		}
+
+		if _, err := time.Parse(SunsetLayout, ig.Until); ig.Until != "" && err != nil {
+			res.AddError("invalid_ignore_until",
+				fmt.Sprintf("until %q is not a YYYY-MM-DD date", ig.Until), tpStr, ig.Target)
 		}
+
+		res.Locate(mark, ig.Pos)
 	}
 
-	return res
+	validateDuplicateTargets(res, tpStr, tm)
+
+	if tm.RequireFullCoverage {
+		validateCoverage(res, tpStr, mf, srcT, dstT, tm)
+	}
 }
 
 // validateFieldMapping validates a single field mapping within a type mapping.
@@ -98,57 +307,171 @@ func validateFieldMapping(
 	parent *TypeMapping,
 	fm *FieldMapping,
 	knownTransforms map[string]struct{},
+	graph *analyze.TypeGraph,
 ) {
 	if fm == nil {
 		return
 	}
 
-	validateTargets(res, typePairStr, dstT, fm)
-	validateSources(res, typePairStr, srcT, parent, fm)
+	validateTargets(res, typePairStr, srcT, dstT, parent, fm)
+	validateSources(res, typePairStr, srcT, dstT, parent, fm)
 	validateTransform(res, typePairStr, fm, knownTransforms)
+	validateMoneyTransform(res, typePairStr, srcT, dstT, fm, knownTransforms)
 	validateExtra(res, typePairStr, srcT, dstT, parent, fm)
+	validateNilPolicy(res, typePairStr, dstT, fm)
+	validateDedupBy(res, typePairStr, srcT, dstT, fm)
+	validateCopyMode(res, typePairStr, srcT, fm)
+	validateMerge(res, typePairStr, fm)
+	validateEnumMap(res, typePairStr, srcT, dstT, fm, graph)
+	validateNullDefault(res, typePairStr, srcT, dstT, fm)
+	validateDefault(res, typePairStr, dstT, fm)
+	validateSunset(res, typePairStr, fm)
+	validateWhen(res, typePairStr, srcT, parent, fm)
+	validateValue(res, typePairStr, srcT, dstT, parent, fm, graph)
+	validateCollect(res, typePairStr, srcT, dstT, parent, fm)
+	validateFromMapKeys(res, typePairStr, srcT, dstT, parent, fm)
 }
 
-func validatePathAgainstType(pathStr string, typeInfo *analyze.TypeInfo) error {
+// addPathError reports path, which doesn't resolve on t with err, under code,
+// or as field_renamed when a field of it was likely renamed (see RenamedPath).
+// want is the type of the other side of the mapping, if known; target tells
+// the side of path.
+func addPathError(
+	res *diagnostic.Diagnostics,
+	typePairStr, code, prefix, path string,
+	err error,
+	t, want *analyze.TypeInfo,
+	tm *TypeMapping,
+	target bool,
+) {
+	used := mappedPaths(tm, target)
+	if renamed, _ := RenamedPath(path, t, want, func(p string) bool { return used[p] }); renamed != "" {
+		res.AddError("field_renamed", fmt.Sprintf("%s: %v (likely renamed to %s)", prefix, err, renamed), typePairStr, path)
+
+		return
+	}
+
+	res.AddError(code, fmt.Sprintf("%s: %v", prefix, err), typePairStr, path)
+}
+
+// mappedPaths returns the source paths, or the target paths if target is set,
+// that tm refers to in 121, fields, auto and ignore.
+func mappedPaths(tm *TypeMapping, target bool) map[string]bool {
+	paths := make(map[string]bool)
+
+	for sp, tp := range tm.OneToOne {
+		if target {
+			paths[tp] = true
+		} else {
+			paths[sp] = true
+		}
+	}
+
+	for _, fm := range append(append([]FieldMapping{}, tm.Fields...), tm.Auto...) {
+		refs := fm.Source
+		if target {
+			refs = fm.Target
+		}
+
+		for _, ref := range refs {
+			paths[ref.Path] = true
+		}
+
+		if target {
+			for _, path := range fm.FromMapKeys {
+				paths[path] = true
+			}
+		}
+	}
+
+	if target {
+		for _, ig := range tm.Ignore {
+			paths[ig.Target] = true
+		}
+	}
+
+	return paths
+}
+
+// counterpartType returns the type of the other side of a 1:1 field mapping
+// without transform, resolved on other, or nil: the type a field renamed on
+// this side must have.
+func counterpartType(fm *FieldMapping, other *analyze.TypeInfo, target bool) *analyze.TypeInfo {
+	if len(fm.Source) != 1 || len(fm.Target) != 1 || fm.Transform != "" || fm.HasValue() {
+		return nil
+	}
+
+	path := fm.Target[0].Path
+	if target {
+		path = fm.Source[0].Path
+	}
+
+	t, _ := resolvePathType(path, other)
+
+	return t
+}
+
+// validatePathAgainstType checks that a field path resolves on typeInfo,
+// through unexported fields only if allowUnexported is set.
+func validatePathAgainstType(pathStr string, typeInfo *analyze.TypeInfo, allowUnexported bool) error {
+	_, err := resolvePath(pathStr, typeInfo, allowUnexported)
+
+	return err
+}
+
+// validateTargetPath is validatePathAgainstType for target paths, which
+// assign fields and so can't access an element by index or key.
+func validateTargetPath(pathStr string, typeInfo *analyze.TypeInfo, allowUnexported bool) error {
+	if fp, err := ParsePath(pathStr); err == nil && fp.IsKeyed() {
+		return errors.New("only source paths can access an element by index or key")
+	}
+
+	return validatePathAgainstType(pathStr, typeInfo, allowUnexported)
+}
+
+// resolvePathType walks a field path through typeInfo and returns the type of
+// the addressed field (or slice element when the last segment uses [], or the
+// element it accesses by index or key).
+// Unexported fields resolve too; validatePathAgainstType reports them.
+func resolvePathType(pathStr string, typeInfo *analyze.TypeInfo) (*analyze.TypeInfo, error) {
+	return resolvePath(pathStr, typeInfo, true)
+}
+
+// resolvePath is resolvePathType, failing on unexported fields unless
+// allowUnexported is set.
+func resolvePath(pathStr string, typeInfo *analyze.TypeInfo, allowUnexported bool) (*analyze.TypeInfo, error) {
 	fp, err := ParsePath(pathStr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	current := typeInfo
 	for _, seg := range fp.Segments {
 		if current == nil {
-			return fmt.Errorf("nil type while resolving %q", seg.Name)
+			return nil, fmt.Errorf("nil type while resolving %q", seg.Name)
 		}
 
 		// Auto-deref pointers (matches resolver behavior).
 		for current.Kind == analyze.TypeKindPointer {
 			current = current.ElemType
 			if current == nil {
-				return fmt.Errorf("nil pointer element while resolving %q", seg.Name)
+				return nil, fmt.Errorf("nil pointer element while resolving %q", seg.Name)
 			}
 		}
 
 		// Resolve field on current struct.
 		if current.Kind != analyze.TypeKindStruct {
-			return fmt.Errorf("cannot access field %q on non-struct kind %s", seg.Name, current.Kind)
-		}
-
-		var fld *analyze.FieldInfo
-
-		for i := range current.Fields {
-			if current.Fields[i].Name == seg.Name {
-				fld = &current.Fields[i]
-				break
-			}
+			return nil, fmt.Errorf("cannot access field %q on non-struct kind %s", seg.Name, current.Kind)
 		}
 
+		// Fields promoted from embedded structs resolve by their bare name.
+		fld := current.FieldByName(seg.Name)
 		if fld == nil {
-			return fmt.Errorf("field %q not found in %s", seg.Name, current.ID)
+			return nil, fmt.Errorf("field %q not found in %s", seg.Name, current.ID)
 		}
 
-		if !fld.Exported {
-			return fmt.Errorf("field %q is not exported", seg.Name)
+		if !fld.Exported && !allowUnexported {
+			return nil, fmt.Errorf("field %q is not exported (set allow_unexported to map it)", seg.Name)
 		}
 
 		current = fld.Type
@@ -158,20 +481,40 @@ func validatePathAgainstType(pathStr string, typeInfo *analyze.TypeInfo) error {
 			for current.Kind == analyze.TypeKindPointer {
 				current = current.ElemType
 				if current == nil {
-					return fmt.Errorf("nil pointer element while resolving %q", seg.Name)
+					return nil, fmt.Errorf("nil pointer element while resolving %q", seg.Name)
 				}
 			}
 
 			if current.Kind != analyze.TypeKindSlice {
-				return fmt.Errorf("segment %q uses [] but resolved field is %s", seg.Name, current.Kind)
+				return nil, fmt.Errorf("segment %q uses [] but resolved field is %s", seg.Name, current.Kind)
 			}
 
 			current = current.ElemType
 			if current == nil {
-				return fmt.Errorf("nil slice element while resolving %q", seg.Name)
+				return nil, fmt.Errorf("nil slice element while resolving %q", seg.Name)
+			}
+		}
+
+		// Apply an index or key after selecting the field.
+		if seg.Key != "" {
+			if current, err = KeyedElem(current, seg.Key); err != nil {
+				return nil, fmt.Errorf("field %q: %w", seg.Name, err)
 			}
 		}
 	}
 
-	return nil
+	return current, nil
+}
+
+// isNameToken reports whether s is a single name token that name matching can
+// strip or expand, such as "DTO" or "Qty".
+func isNameToken(s string) bool {
+	tokens := match.TokenizeIdent(s)
+
+	return len(tokens) == 1 && strings.EqualFold(tokens[0], s)
+}
+
+// isTagKey reports whether key can be a struct tag key (e.g., json).
+func isTagKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, " :\"`")
 }
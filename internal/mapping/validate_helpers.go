@@ -2,17 +2,25 @@ package mapping
 
 import (
 	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"caster-generator/internal/analyze"
 	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
 )
 
 // validateTargets validates the target field references in a field mapping.
 func validateTargets(
 	res *diagnostic.Diagnostics,
 	typePairStr string,
-	dstT *analyze.TypeInfo,
+	srcT, dstT *analyze.TypeInfo,
+	parent *TypeMapping,
 	fm *FieldMapping,
 ) {
 	for _, t := range fm.Target {
@@ -21,8 +29,9 @@ func validateTargets(
 			continue
 		}
 
-		if err := validatePathAgainstType(t.Path, dstT); err != nil {
-			res.AddError("invalid_target_path", fmt.Sprintf("invalid target path: %v", err), typePairStr, t.Path)
+		if err := validateTargetPath(t.Path, dstT, parent.AllowUnexported); err != nil {
+			addPathError(res, typePairStr, "invalid_target_path", "invalid target path", t.Path, err,
+				dstT, counterpartType(fm, srcT, true), parent, true)
 		}
 
 		if !t.Hint.IsValid() {
@@ -35,12 +44,13 @@ func validateTargets(
 func validateSources(
 	res *diagnostic.Diagnostics,
 	typePairStr string,
-	srcT *analyze.TypeInfo,
+	srcT, dstT *analyze.TypeInfo,
 	parent *TypeMapping,
 	fm *FieldMapping,
 ) {
-	// Skip validation if using default value
-	if fm.Default != nil {
+	// Skip validation if using a default, const or expr value, or collecting
+	// sources validated by validateCollect
+	if fm.HasValue() || len(fm.Collect) > 0 {
 		return
 	}
 
@@ -59,8 +69,14 @@ func validateSources(
 		isReq := isRequiredArg(s.Path, parent)
 
 		if !isReq {
-			if err := validatePathAgainstType(s.Path, srcT); err != nil {
-				res.AddError("invalid_source_path", fmt.Sprintf("invalid source path: %v", err), typePairStr, s.Path)
+			if err := validatePathAgainstType(s.Path, srcT, parent.AllowUnexported); err != nil {
+				if fm.OptionalSource {
+					res.AddWarning("optional_source_missing",
+						fmt.Sprintf("optional source path not found yet, target left unset: %v", err), typePairStr, s.Path)
+				} else {
+					addPathError(res, typePairStr, "invalid_source_path", "invalid source path", s.Path, err,
+						srcT, counterpartType(fm, dstT, false), parent, false)
+				}
 			}
 		}
 
@@ -100,8 +116,9 @@ func validateTransform(
 ) {
 	card := fm.GetCardinality()
 
-	// many:1 and many:many require a transform
-	if fm.NeedsTransform() && fm.Transform == "" {
+	// many:1 and many:many require a transform; from_map_keys reports its own
+	// sources
+	if fm.NeedsTransform() && fm.Transform == "" && len(fm.FromMapKeys) == 0 {
 		res.AddError("missing_transform", card.String()+" mapping requires transform", typePairStr, "")
 	}
 
@@ -119,6 +136,97 @@ func validateTransform(
 	}
 }
 
+// validateWritableTarget reports a generate_target mapping whose target would
+// be written into a package of a dependency module, which is read-only.
+func validateWritableTarget(res *diagnostic.Diagnostics, typePairStr, target string, graph *analyze.TypeGraph) {
+	pkg := TypePackage(target)
+	if pkg == "" {
+		return
+	}
+
+	if info := findPackage(pkg, graph); info != nil && info.External {
+		res.AddError("read_only_target",
+			fmt.Sprintf("generate_target: %s belongs to module %s, whose types are read-only; "+
+				"generate the target into a package of this module", info.Path, info.Module),
+			typePairStr, target)
+	}
+}
+
+// validateMethod validates the method a caster is generated as: a Go
+// identifier the source type doesn't have as a field, declared on a named
+// struct of a package of this module, where the caster is generated.
+func validateMethod(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	tm *TypeMapping,
+	srcT *analyze.TypeInfo,
+	graph *analyze.TypeGraph,
+) {
+	switch {
+	case !token.IsIdentifier(tm.Method):
+		res.AddError("invalid_method", fmt.Sprintf("method %q is not a Go identifier", tm.Method), typePairStr, "method")
+	case tm.Output != nil:
+		res.AddError("invalid_method", "a caster generated as a method goes into the package of its source type, "+
+			"so it can't set output", typePairStr, "method")
+	case srcT.Kind != analyze.TypeKindStruct || srcT.ID.PkgPath == "" || len(srcT.TypeArgs) > 0:
+		res.AddError("invalid_method",
+			fmt.Sprintf("method: %s is not a named struct type, which methods can be declared on", srcT.ID),
+			typePairStr, "method")
+	case srcT.DeclaresField(tm.Method):
+		res.AddError("method_collision", fmt.Sprintf("method %s collides with field %s.%s", tm.Method, srcT.ID,
+			tm.Method), typePairStr, "method")
+	}
+
+	if info := graph.Packages[srcT.ID.PkgPath]; info != nil && info.External {
+		res.AddError("read_only_source",
+			fmt.Sprintf("method: %s belongs to module %s, whose types are read-only; "+
+				"generate a function caster instead", info.Path, info.Module),
+			typePairStr, "method")
+	}
+}
+
+// validateTransformTemplate validates the template of a template transform def:
+// it must parse, and the transform generated inline has no function to call,
+// import or cache.
+func validateTransformTemplate(res *diagnostic.Diagnostics, def *TransformDef) {
+	if def.Template == "" {
+		if len(def.Imports) > 0 {
+			res.AddError("invalid_transform_template",
+				fmt.Sprintf("transform %q: imports require a template", def.Name), "", def.Name)
+		}
+
+		return
+	}
+
+	if def.Func != "" || def.Package != "" || def.Memoize {
+		res.AddError("invalid_transform_template",
+			fmt.Sprintf("transform %q: template excludes func, package and memoize", def.Name), "", def.Name)
+	}
+
+	if _, err := def.ParseTemplate(); err != nil {
+		res.AddError("invalid_transform_template",
+			fmt.Sprintf("transform %q: %v", def.Name, err), "", def.Name)
+	}
+
+	for _, path := range def.Imports {
+		if path == "" || strings.ContainsAny(path, " \t\"") {
+			res.AddError("invalid_transform_template",
+				fmt.Sprintf("transform %q: invalid import path %q", def.Name, path), "", def.Name)
+		}
+	}
+}
+
+// validateMemoize rejects memoizing context-aware transforms: the cache is
+// keyed by the arguments only, so a result computed under one ctx (its
+// deadline, values or cancellation) would be served to every other.
+func validateMemoize(res *diagnostic.Diagnostics, def *TransformDef) {
+	if def.Memoize && def.Ctx {
+		res.AddError("invalid_memoize",
+			fmt.Sprintf("transform %q: memoize can't cache a ctx transform, whose result may depend on ctx", def.Name),
+			"", def.Name)
+	}
+}
+
 // validateExtra validates the extra definitions in a field mapping.
 func validateExtra(
 	res *diagnostic.Diagnostics,
@@ -147,7 +255,7 @@ func validateExtra(
 			if !declared {
 				// Relax validation: if the extra arg has a definition (source or target),
 				// it's creating a new value, not just forwarding a required arg.
-				isDefinition := ev.Def.Source != "" || ev.Def.Target != ""
+				isDefinition := ev.Def.Source != "" || ev.Def.Target != "" || ev.Const != ""
 
 				if !isDefinition {
 					res.AddError("undeclared_extra_arg",
@@ -157,16 +265,1097 @@ func validateExtra(
 			}
 		}
 
+		if ev.Const != "" {
+			if ev.Def.Source != "" || ev.Def.Target != "" {
+				res.AddError("conflicting_extra",
+					fmt.Sprintf("extra %q: const can't be combined with def.source or def.target", ev.Name), typePairStr, "")
+			} else if _, err := ev.Const.Value(); err != nil {
+				res.AddError("invalid_extra_const", fmt.Sprintf("extra %q: %v", ev.Name, err), typePairStr, "")
+			}
+
+			continue
+		}
+
 		if ev.Def.Source != "" {
-			if err := validatePathAgainstType(ev.Def.Source, srcT); err != nil {
+			if err := validatePathAgainstType(ev.Def.Source, srcT, parent.AllowUnexported); err != nil {
 				res.AddError("invalid_extra_source", fmt.Sprintf("invalid extra.def.source: %v", err), typePairStr, ev.Def.Source)
 			}
 		}
 
 		if ev.Def.Target != "" {
-			if err := validatePathAgainstType(ev.Def.Target, dstT); err != nil {
+			if err := validatePathAgainstType(ev.Def.Target, dstT, parent.AllowUnexported); err != nil {
 				res.AddError("invalid_extra_target", fmt.Sprintf("invalid extra.def.target: %v", err), typePairStr, ev.Def.Target)
 			}
 		}
 	}
 }
+
+// validateNilPolicy validates the nil_to_empty / preserve_nil options of a field mapping.
+func validateNilPolicy(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+) {
+	if !fm.NilToEmpty && !fm.PreserveNil {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if fm.NilToEmpty && fm.PreserveNil {
+		res.AddError("conflicting_nil_policy", "nil_to_empty and preserve_nil are mutually exclusive", typePairStr, target)
+
+		return
+	}
+
+	tt, err := resolvePathType(target, dstT)
+	if err != nil || tt == nil {
+		return
+	}
+
+	// A *[]T or *map[K]V target is set to a pointer to an empty collection.
+	if tt.Kind == analyze.TypeKindPointer && tt.ElemType != nil {
+		tt = tt.ElemType
+	}
+
+	if tt.Kind != analyze.TypeKindSlice && tt.Kind != analyze.TypeKindMap {
+		res.AddWarning("nil_policy_not_collection",
+			fmt.Sprintf("nil_to_empty/preserve_nil has no effect on %s target", tt.Kind),
+			typePairStr, target)
+	}
+}
+
+// validateDedupBy validates the dedup_by option of a field mapping: the source
+// must be a slice or array of structs (or struct pointers) with an exported,
+// comparable field of that name, and the target a slice.
+func validateDedupBy(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+) {
+	if fm.DedupBy == "" {
+		return
+	}
+
+	target := fm.Target.First()
+	fail := func(msg string) {
+		res.AddError("invalid_dedup_by", msg, typePairStr, target)
+	}
+
+	if fm.Transform != "" || len(fm.EnumMap) > 0 {
+		fail("dedup_by can't be combined with transform or enum_map")
+		return
+	}
+
+	if !fm.Source.IsSingle() || !fm.Target.IsSingle() {
+		fail("dedup_by requires a single source and target")
+		return
+	}
+
+	// Unresolvable paths are reported by validateSources and validateTargets.
+	st, err := resolvePathType(fm.Source.First(), srcT)
+	if err != nil || st == nil {
+		return
+	}
+
+	tt, err := resolvePathType(target, dstT)
+	if err != nil || tt == nil {
+		return
+	}
+
+	if tt.Kind != analyze.TypeKindSlice {
+		fail(fmt.Sprintf("dedup_by requires a slice target, got %s", tt.Kind))
+		return
+	}
+
+	if (st.Kind != analyze.TypeKindSlice && st.Kind != analyze.TypeKindArray) || st.ElemType == nil {
+		fail(fmt.Sprintf("dedup_by requires a slice or array source, got %s", st.Kind))
+		return
+	}
+
+	elem := st.ElemType
+	if elem.Kind == analyze.TypeKindPointer && elem.ElemType != nil {
+		elem = elem.ElemType
+	}
+
+	if elem.Kind != analyze.TypeKindStruct {
+		fail(fmt.Sprintf("dedup_by requires struct elements, got %s", elem.Kind))
+		return
+	}
+
+	fld := elem.FieldByName(fm.DedupBy)
+
+	switch {
+	case fld == nil:
+		fail(fmt.Sprintf("dedup_by field %q not found in %s", fm.DedupBy, elem.ID))
+	case !fld.Exported:
+		fail(fmt.Sprintf("dedup_by field %q of %s is unexported", fm.DedupBy, elem.ID))
+	case fld.Type != nil && fld.Type.GoType != nil && !types.Comparable(fld.Type.GoType):
+		fail(fmt.Sprintf("dedup_by field %q of %s has non-comparable type %s", fm.DedupBy, elem.ID, fld.Type.GoType))
+	}
+}
+
+// validateMerge validates the merge option of a field mapping: if_zero reads
+// the target field, so it can't apply to slice elements.
+func validateMerge(res *diagnostic.Diagnostics, typePairStr string, fm *FieldMapping) {
+	target := fm.Target.First()
+
+	if !fm.Merge.IsValid() {
+		res.AddError("invalid_merge_policy",
+			fmt.Sprintf("invalid merge %q (expected overwrite, if_zero or never)", fm.Merge), typePairStr, target)
+
+		return
+	}
+
+	if fm.Merge == MergeIfZero && strings.Contains(target, "[]") {
+		res.AddError("invalid_merge_policy",
+			"merge: if_zero can't check slice elements of "+target, typePairStr, target)
+	}
+}
+
+// validateOutput validates the output of a type mapping. dirs records the
+// package of each output directory, which all mappings must agree on.
+func validateOutput(res *diagnostic.Diagnostics, typePairStr string, out *Output, dirs map[string]string) {
+	if out == nil {
+		return
+	}
+
+	if out.Package == "" && out.Dir == "" {
+		res.AddError("invalid_output", "output needs a package or a dir", typePairStr, "output")
+		return
+	}
+
+	pkg := out.Package
+	if pkg == "" {
+		pkg = filepath.Base(out.Dir)
+	}
+
+	if !token.IsIdentifier(pkg) {
+		res.AddError("invalid_output",
+			fmt.Sprintf("output package %q is not a valid package name", pkg), typePairStr, "output")
+
+		return
+	}
+
+	if out.Dir == "" {
+		return
+	}
+
+	dir := filepath.Clean(out.Dir)
+	if other, ok := dirs[dir]; ok && other != pkg {
+		res.AddError("output_conflict",
+			fmt.Sprintf("output dir %s is given packages %q and %q", out.Dir, other, pkg), typePairStr, "output")
+
+		return
+	}
+
+	dirs[dir] = pkg
+}
+
+// validateFieldTags checks that only fields of generate_target mappings
+// declare struct tags, under valid keys and without backquotes, which
+// can't appear in the raw string of a tag.
+func validateFieldTags(res *diagnostic.Diagnostics, typePairStr string, tm *TypeMapping) {
+	for _, fm := range slices.Concat(tm.Fields, tm.Auto) {
+		if len(fm.Tags) == 0 {
+			continue
+		}
+
+		mark := res.Mark()
+
+		if !tm.GenerateTarget {
+			res.AddError("tags_without_generate_target",
+				"tags only apply to the fields of generate_target mappings", typePairStr, fm.Target.First())
+		}
+
+		for _, key := range SortedEnumKeys(fm.Tags) {
+			switch {
+			case !isTagKey(key):
+				res.AddError("invalid_field_tag",
+					fmt.Sprintf("invalid tags key %q (expected a struct tag key, e.g. json)", key),
+					typePairStr, fm.Target.First())
+			case strings.Contains(fm.Tags[key], "`"):
+				res.AddError("invalid_field_tag", fmt.Sprintf("tags value of %q can't contain a backquote", key),
+					typePairStr, fm.Target.First())
+			}
+		}
+
+		res.Locate(mark, fm.Pos)
+	}
+}
+
+// validateVia validates a mapping converting through an intermediate type:
+// the type exists, the file maps the source to it and it to the target, and
+// the mapping maps no fields itself.
+func validateVia(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	mf *MappingFile,
+	tm *TypeMapping,
+	srcT, dstT *analyze.TypeInfo,
+	graph *analyze.TypeGraph,
+) {
+	if len(tm.OneToOne) > 0 || len(tm.Fields) > 0 || len(tm.Ignore) > 0 || len(tm.Auto) > 0 ||
+		tm.GenerateTarget || tm.GenerateMerge {
+		res.AddError("invalid_via", "a mapping with via can't map fields, generate its target or a merge variant",
+			typePairStr, "via")
+	}
+
+	viaT := ResolveTypeID(tm.Via, graph)
+	if viaT == nil {
+		res.AddError("via_type_not_found", fmt.Sprintf("via type %q not found", tm.Via), typePairStr, "via")
+		return
+	}
+
+	if viaT.ID == srcT.ID || viaT.ID == dstT.ID {
+		res.AddError("invalid_via", fmt.Sprintf("via type %s is the source or target type", viaT.ID), typePairStr, "via")
+		return
+	}
+
+	for _, hop := range [][2]*analyze.TypeInfo{{srcT, viaT}, {viaT, dstT}} {
+		if !hasTypeMapping(mf, graph, hop[0].ID, hop[1].ID) {
+			res.AddError("via_mapping_missing",
+				fmt.Sprintf("via %s needs a mapping from %s to %s", tm.Via, hop[0].ID, hop[1].ID), typePairStr, "via")
+		}
+	}
+}
+
+// validateHooks checks the before and after hooks of tm against the
+// declarations of their functions: before must take and return the source
+// type, after must take the source type and a pointer to the target type.
+// Hooks of the casters package aren't loaded and are left to the compiler.
+func validateHooks(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	tm *TypeMapping,
+	srcT, dstT *analyze.TypeInfo,
+	graph *analyze.TypeGraph,
+) {
+	if srcT.GoType == nil || dstT.GoType == nil {
+		return
+	}
+
+	hooks := []struct {
+		key, name      string
+		params, result []types.Type
+	}{
+		{"before", tm.Before, []types.Type{srcT.GoType}, []types.Type{srcT.GoType}},
+		{"after", tm.After, []types.Type{srcT.GoType, types.NewPointer(dstT.GoType)}, nil},
+	}
+
+	for _, hook := range hooks {
+		if hook.name == "" || !strings.Contains(hook.name, ".") {
+			continue
+		}
+
+		fn := ResolveFunc(hook.name, graph)
+		if fn == nil {
+			res.AddWarning("hook_func_not_found",
+				fmt.Sprintf("%s hook %s not found in loaded packages; signature not checked", hook.key, hook.name),
+				typePairStr, hook.key)
+
+			continue
+		}
+
+		if fn.Signature == nil || fn.Signature.TypeParams().Len() > 0 {
+			continue
+		}
+
+		if !hookSignatureMatches(fn.Signature, hook.params, hook.result) {
+			res.AddError("hook_signature_mismatch",
+				fmt.Sprintf("%s hook %s has type %s, expected %s", hook.key, fn.ID, fn.Signature,
+					types.NewSignatureType(nil, nil, nil, hookTuple(hook.params), hookTuple(hook.result), false)),
+				typePairStr, hook.key)
+		}
+	}
+}
+
+// hookSignatureMatches reports whether a hook of signature sig can be called
+// with arguments of types params and its results assigned to types results.
+func hookSignatureMatches(sig *types.Signature, params, results []types.Type) bool {
+	if sig.Variadic() || sig.Params().Len() != len(params) || sig.Results().Len() != len(results) {
+		return false
+	}
+
+	for i, param := range params {
+		if !types.AssignableTo(param, sig.Params().At(i).Type()) {
+			return false
+		}
+	}
+
+	for i, result := range results {
+		if !types.AssignableTo(sig.Results().At(i).Type(), result) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hookTuple returns a tuple of unnamed variables of types ts.
+func hookTuple(ts []types.Type) *types.Tuple {
+	vars := make([]*types.Var, len(ts))
+	for i, t := range ts {
+		vars[i] = types.NewParam(token.NoPos, nil, "", t)
+	}
+
+	return types.NewTuple(vars...)
+}
+
+// validateCopyMode validates the copy option of a field mapping.
+func validateCopyMode(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT *analyze.TypeInfo,
+	fm *FieldMapping,
+) {
+	if fm.Copy == CopyDefault {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if !fm.Copy.IsValid() {
+		res.AddError("invalid_copy_mode",
+			fmt.Sprintf("invalid copy mode %q (expected alias, shallow or deep)", fm.Copy), typePairStr, target)
+
+		return
+	}
+
+	if len(fm.Source) != 1 {
+		return
+	}
+
+	st, err := resolvePathType(fm.Source[0].Path, srcT)
+	if err != nil || st == nil {
+		return
+	}
+
+	for st.Kind == analyze.TypeKindAlias && st.Underlying != nil {
+		st = st.Underlying
+	}
+
+	switch st.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindMap, analyze.TypeKindPointer, analyze.TypeKindArray,
+		analyze.TypeKindStruct:
+	default:
+		res.AddWarning("copy_mode_no_effect",
+			fmt.Sprintf("copy: %s has no effect on %s source", fm.Copy, st.Kind), typePairStr, target)
+	}
+}
+
+// validateEnumMap checks that an enum_map is used on a 1:1 mapping between
+// basic types, that its keys are literals of the source type and that its
+// values are literals or constants of the target type (see ConstExpr).
+func validateEnumMap(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+	graph *analyze.TypeGraph,
+) {
+	target := fm.Target.First()
+
+	if len(fm.EnumMap) == 0 {
+		if fm.EnumDefault != "" || fm.EnumStrict {
+			res.AddError("enum_options_without_map", "enum_default and enum_strict require enum_map", typePairStr, target)
+		}
+
+		return
+	}
+
+	switch {
+	case len(fm.Source) != 1 || len(fm.Target) != 1:
+		res.AddError("invalid_enum_map", "enum_map requires exactly one source and one target", typePairStr, target)
+		return
+	case fm.Transform != "" || fm.Default != nil:
+		res.AddError("conflicting_enum_map", "enum_map can't be combined with transform or default", typePairStr, target)
+		return
+	case fm.EnumStrict && fm.EnumDefault != "":
+		res.AddError("conflicting_enum_default", "enum_default and enum_strict are mutually exclusive", typePairStr, target)
+	}
+
+	st, srcErr := resolvePathType(fm.Source[0].Path, srcT)
+	tt, tgtErr := resolvePathType(target, dstT)
+
+	if srcErr != nil || tgtErr != nil || st == nil || tt == nil {
+		return
+	}
+
+	if enumBasicType(st) == nil || enumBasicType(tt) == nil {
+		res.AddError("enum_map_unsupported_type", errEnumType.Error(), typePairStr, target)
+		return
+	}
+
+	values := []string{}
+	keys := make(map[string]string, len(fm.EnumMap))
+
+	for _, k := range SortedEnumKeys(fm.EnumMap) {
+		values = append(values, fm.EnumMap[k])
+
+		lit, err := EnumLiteral(k, st)
+		if err != nil {
+			res.AddError("invalid_enum_value", fmt.Sprintf("enum_map key: %v", err), typePairStr, target)
+			continue
+		}
+
+		// Keys written differently but equal would make duplicate switch cases.
+		if other, ok := keys[lit]; ok {
+			res.AddError("duplicate_enum_key",
+				fmt.Sprintf("enum_map keys %q and %q are the same value %s", other, k, lit), typePairStr, target)
+		}
+
+		keys[lit] = k
+	}
+
+	if fm.EnumDefault != "" {
+		values = append(values, fm.EnumDefault)
+	}
+
+	// Values may also be constants of the target package (e.g., warehouse.StateActive).
+	for _, v := range values {
+		if _, _, err := ConstExpr(v, tt, graph); err != nil {
+			res.AddError("invalid_enum_value", fmt.Sprintf("enum_map value: %v", err), typePairStr, target)
+		}
+	}
+}
+
+// validateDefault checks the default and else_default of a field mapping
+// against the types of its targets (see CheckDefault).
+func validateDefault(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+) {
+	var values []string
+	if fm.Default != nil {
+		values = append(values, *fm.Default)
+	}
+
+	if fm.ElseDefault != "" {
+		values = append(values, fm.ElseDefault)
+	}
+
+	for _, t := range fm.Target {
+		// Unresolvable targets are reported by validateTargets.
+		tt, err := resolvePathType(t.Path, dstT)
+		if err != nil || tt == nil {
+			continue
+		}
+
+		for _, value := range values {
+			if err := CheckDefault(value, tt); err != nil {
+				res.AddError("invalid_default", fmt.Sprintf("target %s: %v", t.Path, err), typePairStr, t.Path)
+			}
+		}
+	}
+}
+
+// validateNullDefault checks that a null_default is used on a 1:1 mapping from
+// a database/sql nullable type and is a literal of the (pointed-to) target type.
+func validateNullDefault(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	fm *FieldMapping,
+) {
+	if fm.NullDefault == "" {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if len(fm.Source) != 1 || len(fm.Target) != 1 || fm.Transform != "" || fm.Default != nil || len(fm.EnumMap) > 0 {
+		res.AddError("invalid_null_default",
+			"null_default requires exactly one source and one target, without transform, default or enum_map",
+			typePairStr, target)
+
+		return
+	}
+
+	st, srcErr := resolvePathType(fm.Source[0].Path, srcT)
+	tt, tgtErr := resolvePathType(target, dstT)
+
+	if srcErr != nil || tgtErr != nil || st == nil || tt == nil {
+		return
+	}
+
+	if st.SQLNullValue() == nil {
+		res.AddError("invalid_null_default",
+			fmt.Sprintf("null_default requires a database/sql nullable source, got %s", st.ID), typePairStr, target)
+
+		return
+	}
+
+	if tt.Kind == analyze.TypeKindPointer && tt.ElemType != nil {
+		tt = tt.ElemType
+	}
+
+	if enumBasicType(tt) == nil {
+		res.AddError("invalid_null_default", "null_default requires a string, numeric or bool target", typePairStr, target)
+		return
+	}
+
+	if _, err := EnumLiteral(fm.NullDefault, tt); err != nil {
+		res.AddError("invalid_null_default", fmt.Sprintf("null_default: %v", err), typePairStr, target)
+	}
+}
+
+// validateSunset checks the sunset date of a deprecated field mapping.
+func validateSunset(res *diagnostic.Diagnostics, typePairStr string, fm *FieldMapping) {
+	if fm.Sunset == "" {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if fm.Deprecated == "" {
+		res.AddError("sunset_without_deprecated", "sunset requires a deprecated message", typePairStr, target)
+		return
+	}
+
+	if _, err := time.Parse(SunsetLayout, fm.Sunset); err != nil {
+		res.AddError("invalid_sunset",
+			fmt.Sprintf("sunset %q is not a YYYY-MM-DD date", fm.Sunset), typePairStr, target)
+	}
+}
+
+// validateValue checks the const and expr values of a field mapping: a const
+// must be a literal or constant of each target's type, and an expr may only
+// refer to declarations of loaded packages and to source fields.
+func validateValue(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	parent *TypeMapping,
+	fm *FieldMapping,
+	graph *analyze.TypeGraph,
+) {
+	if fm.Const == "" && fm.Expr == "" {
+		return
+	}
+
+	target := fm.Target.First()
+
+	if fm.Default != nil || (fm.Const != "" && fm.Expr != "") {
+		res.AddError("conflicting_value", "default, const and expr are mutually exclusive", typePairStr, target)
+		return
+	}
+
+	if len(fm.Source) > 0 || fm.Transform != "" || len(fm.EnumMap) > 0 {
+		res.AddError("conflicting_value", "const and expr can't be combined with source, transform or enum_map",
+			typePairStr, target)
+
+		return
+	}
+
+	if fm.Const != "" {
+		for _, t := range fm.Target {
+			// Unresolvable targets are reported by validateTargets.
+			tt, err := resolvePathType(t.Path, dstT)
+			if err != nil || tt == nil {
+				continue
+			}
+
+			if _, _, err := ConstExpr(fm.Const, tt, graph); err != nil {
+				res.AddError("invalid_const", err.Error(), typePairStr, t.Path)
+			}
+		}
+
+		return
+	}
+
+	_, paths, err := ExprQualifiers(fm.Expr, graph)
+	if err != nil {
+		res.AddError("invalid_expr", err.Error(), typePairStr, target)
+		return
+	}
+
+	for _, p := range paths {
+		if err := validatePathAgainstType(p, srcT, parent.AllowUnexported); err != nil {
+			res.AddError("invalid_expr", fmt.Sprintf("invalid source path in expr: %v", err), typePairStr, target)
+		}
+	}
+}
+
+// validateCollect checks that a collect mapping has a single map or []KV
+// target and that its sources resolve to values of the target's value type
+// under distinct keys of its key type.
+func validateCollect(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	parent *TypeMapping,
+	fm *FieldMapping,
+) {
+	target := fm.Target.First()
+
+	if len(fm.Collect) == 0 {
+		if fm.OmitZero {
+			res.AddError("invalid_omit_zero", "omit_zero only applies to collect", typePairStr, target)
+		}
+
+		return
+	}
+
+	switch {
+	case len(fm.Target) != 1:
+		res.AddError("invalid_collect", "collect requires exactly one target", typePairStr, target)
+		return
+	case len(fm.Source) > 0 || fm.Transform != "" || fm.HasValue() || len(fm.EnumMap) > 0:
+		res.AddError("conflicting_collect", "collect can't be combined with source, transform, enum_map or a value",
+			typePairStr, target)
+
+		return
+	}
+
+	tt, err := resolvePathType(target, dstT)
+	if err != nil || tt == nil {
+		return
+	}
+
+	ct, err := NewCollectTarget(tt)
+	if err != nil {
+		res.AddError("collect_unsupported_type", err.Error(), typePairStr, target)
+		return
+	}
+
+	keys := make(map[string]string)
+
+	for _, e := range CollectEntries(fm.Collect) {
+		lit, err := EnumLiteral(e.Key, ct.Key)
+		if err != nil {
+			res.AddError("invalid_collect_key", fmt.Sprintf("collect key: %v", err), typePairStr, target)
+		} else if prev, ok := keys[lit]; ok {
+			res.AddError("duplicate_collect_key",
+				fmt.Sprintf("collect key %s is used by %s and %s", e.Key, prev, e.Source), typePairStr, target)
+		} else {
+			keys[lit] = e.Source
+		}
+
+		st, err := resolvePath(e.Source, srcT, parent.AllowUnexported)
+		if err != nil {
+			res.AddError("invalid_source_path", fmt.Sprintf("invalid collect source: %v", err), typePairStr, e.Source)
+			continue
+		}
+
+		if NewCollectFormat(st, ct.Value) != nil {
+			continue
+		}
+
+		if _, err := CollectConversion(st, ct.Value); err != nil {
+			res.AddError("collect_type_mismatch", err.Error(), typePairStr, e.Source)
+		}
+	}
+}
+
+// validateFromMapKeys checks that a from_map_keys mapping has a single map
+// source with string keys and values, and that its keys name distinct target
+// fields of types its values parse into.
+func validateFromMapKeys(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT, dstT *analyze.TypeInfo,
+	parent *TypeMapping,
+	fm *FieldMapping,
+) {
+	if len(fm.FromMapKeys) == 0 {
+		return
+	}
+
+	source := fm.Source.First()
+
+	switch {
+	case len(fm.Source) != 1:
+		res.AddError("invalid_from_map_keys", "from_map_keys requires exactly one source", typePairStr, source)
+		return
+	case len(fm.Target) > 0 || fm.Transform != "" || fm.HasValue() || len(fm.EnumMap) > 0 || len(fm.Collect) > 0:
+		res.AddError("conflicting_from_map_keys",
+			"from_map_keys names its targets and can't be combined with target, transform, enum_map, collect or a value",
+			typePairStr, source)
+
+		return
+	}
+
+	if st, err := resolvePath(source, srcT, parent.AllowUnexported); err == nil && st != nil {
+		if _, err := MapKeysValue(st); err != nil {
+			res.AddError("from_map_keys_unsupported_type", err.Error(), typePairStr, source)
+		}
+	}
+
+	targets := make(map[string]string)
+
+	for _, e := range MapKeyEntries(fm.FromMapKeys) {
+		if prev, ok := targets[e.Target]; ok {
+			res.AddError("duplicate_from_map_keys_target",
+				fmt.Sprintf("%s is parsed from both keys %s and %s", e.Target, prev, e.Key), typePairStr, e.Target)
+
+			continue
+		}
+
+		targets[e.Target] = e.Key
+
+		tt, err := resolvePath(e.Target, dstT, parent.AllowUnexported)
+		if err != nil {
+			addPathError(res, typePairStr, "invalid_target_path", "invalid from_map_keys target", e.Target, err,
+				dstT, nil, parent, true)
+
+			continue
+		}
+
+		if _, err := NewMapValueParse(tt); err != nil {
+			res.AddError("from_map_keys_unsupported_type", err.Error(), typePairStr, e.Target)
+		}
+	}
+}
+
+// validateFlatten checks that the flatten and unflatten directives of a type
+// mapping name struct fields of the source and target, warning about those
+// that map no fields.
+func validateFlatten(res *diagnostic.Diagnostics, typePairStr string, srcT, dstT *analyze.TypeInfo, tm *TypeMapping) {
+	check := func(directive, side string, fields []string, expand func(_, _ *analyze.TypeInfo, _ string) ([]FlatField, error)) {
+		for _, field := range fields {
+			pairs, err := expand(srcT, dstT, field)
+
+			switch {
+			case err != nil:
+				res.AddError("invalid_"+directive, err.Error(), typePairStr, field)
+			case len(pairs) == 0:
+				res.AddWarning(directive+"_no_fields",
+					fmt.Sprintf("%s %s maps no fields: no %s field is named %s<Field>", directive, field, side, field),
+					typePairStr, field)
+			}
+		}
+	}
+
+	check("flatten", "target", tm.Flatten, FlattenFields)
+	check("unflatten", "source", tm.Unflatten, UnflattenFields)
+}
+
+// validateWildcards checks the wildcard 121 and prefix_map entries of tm;
+// the resolver reports the ones matching no fields.
+func validateWildcards(res *diagnostic.Diagnostics, typePairStr string, srcT, dstT *analyze.TypeInfo, tm *TypeMapping) {
+	for _, rule := range tm.WildcardRules() {
+		mark := res.Mark()
+
+		if _, err := WildcardFields(rule[0], rule[1], srcT, dstT); err != nil {
+			res.AddError("invalid_wildcard", err.Error(), typePairStr, rule[1])
+		}
+
+		pos, ok := tm.OneToOnePos[rule[0]]
+		if prefix, isPrefix := strings.CutSuffix(rule[0], "*"); !ok && isPrefix {
+			pos = tm.PrefixMapPos[prefix]
+		}
+
+		res.Locate(mark, pos)
+	}
+}
+
+// validateDuplicateTargets reports the target paths mapped by more than one
+// fields entry of tm, which the resolver would map by the first only.
+func validateDuplicateTargets(res *diagnostic.Diagnostics, typePairStr string, tm *TypeMapping) {
+	entries := make(map[string]int)
+
+	for i := range tm.Fields {
+		fm := &tm.Fields[i]
+		if fm.IsWildcard() {
+			continue
+		}
+
+		mark := res.Mark()
+
+		for _, ref := range fm.Target {
+			if j, ok := entries[ref.Path]; ok {
+				res.AddError("duplicate_target",
+					fmt.Sprintf("target %q is already mapped by fields entry %d", ref.Path, j+1), typePairStr, ref.Path)
+
+				continue
+			}
+
+			entries[ref.Path] = i
+		}
+
+		res.Locate(mark, fm.Pos)
+	}
+}
+
+// validateCoverage reports the exported target fields a mapping requiring
+// full coverage maps by none or several of its 121, fields, ignore and auto
+// rules. A field is covered by the rules of its nested fields or, when
+// embedded, of the fields promoted from it, by the wildcard, prefix_map,
+// flatten and unflatten rules expanding to it, and by the ignore_tags and
+// ignore_patterns of mf.
+func validateCoverage(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	mf *MappingFile,
+	srcT, dstT *analyze.TypeInfo,
+	tm *TypeMapping,
+) {
+	rules := make(map[string][]string)
+	covered := make(map[string]bool)
+
+	cover := func(path string) {
+		name, _, _ := strings.Cut(path, ".")
+		covered[strings.TrimSuffix(name, "[]")] = true
+	}
+
+	claim := func(rule, path string) {
+		if !slices.Contains(rules[path], rule) {
+			rules[path] = append(rules[path], rule)
+		}
+
+		cover(path)
+	}
+
+	for sp, tp := range tm.OneToOne {
+		if !IsWildcard(sp) && !IsWildcard(tp) {
+			claim("121", tp)
+		}
+	}
+
+	for _, section := range []struct {
+		rule   string
+		fields []FieldMapping
+	}{{"fields", tm.Fields}, {"auto", tm.Auto}} {
+		for i := range section.fields {
+			fm := &section.fields[i]
+
+			if fm.IsWildcard() {
+				expanded, _ := WildcardFieldMappings(fm, srcT, dstT)
+				for _, rule := range expanded {
+					cover(rule.Target.First())
+				}
+
+				continue
+			}
+
+			for _, ref := range fm.Target {
+				claim(section.rule, ref.Path)
+			}
+
+			for _, path := range fm.FromMapKeys {
+				claim(section.rule, path)
+			}
+		}
+	}
+
+	for _, ig := range tm.Ignore {
+		claim("ignore", ig.Target)
+	}
+
+	for _, rule := range tm.WildcardRules() {
+		pairs, _ := WildcardFields(rule[0], rule[1], srcT, dstT)
+		for _, pair := range pairs {
+			cover(pair.Target)
+		}
+	}
+
+	for _, directive := range []struct {
+		fields []string
+		expand func(_, _ *analyze.TypeInfo, _ string) ([]FlatField, error)
+	}{{tm.Flatten, FlattenFields}, {tm.Unflatten, UnflattenFields}} {
+		for _, field := range directive.fields {
+			pairs, _ := directive.expand(srcT, dstT, field)
+			for _, pair := range pairs {
+				cover(pair.Target)
+			}
+		}
+	}
+
+	for _, pf := range dstT.AccessibleFields() {
+		if len(pf.Path) > 0 && covered[pf.Field.Name] {
+			covered[pf.Path[0]] = true
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(rules)) {
+		if len(rules[path]) > 1 {
+			res.AddError("overlapping_target",
+				fmt.Sprintf("target %q is mapped by %s (require_full_coverage)", path, strings.Join(rules[path], " and ")),
+				typePairStr, path)
+		}
+	}
+
+	var patterns []*FieldPattern
+
+	for _, p := range mf.IgnorePatterns {
+		if pattern, err := CompileFieldPattern(p); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	for _, f := range dstT.Fields {
+		// Read-only fields, proto oneof members without a setter, can't be mapped
+		if !f.Exported || (f.Index < 0 && f.Setter == "") || covered[f.Name] {
+			continue
+		}
+
+		if slices.ContainsFunc(mf.IgnoreTags, func(key string) bool { return f.Tag.Get(key) == "-" }) ||
+			slices.ContainsFunc(patterns, func(p *FieldPattern) bool { return p.Match(f.Name) }) {
+			continue
+		}
+
+		res.AddError("uncovered_target",
+			fmt.Sprintf("target field %q is in none of 121, fields, ignore and auto (require_full_coverage)", f.Name),
+			typePairStr, f.Name)
+	}
+}
+
+// validateWhen checks that the when condition of a field mapping only reads
+// fields of the source type.
+func validateWhen(
+	res *diagnostic.Diagnostics,
+	typePairStr string,
+	srcT *analyze.TypeInfo,
+	parent *TypeMapping,
+	fm *FieldMapping,
+) {
+	target := fm.Target.First()
+
+	if fm.When == "" {
+		if fm.ElseDefault != "" {
+			res.AddError("else_default_without_when", "else_default requires a when condition", typePairStr, target)
+		}
+
+		return
+	}
+
+	paths, err := ConditionPaths(fm.When)
+	if err != nil {
+		res.AddError("invalid_when", err.Error(), typePairStr, target)
+		return
+	}
+
+	for _, p := range paths {
+		if err := validatePathAgainstType(p, srcT, parent.AllowUnexported); err != nil {
+			res.AddError("invalid_when", fmt.Sprintf("invalid source path in when: %v", err), typePairStr, target)
+		}
+	}
+}
+
+// validateWrappers validates generic wrapper declarations.
+func validateWrappers(res *diagnostic.Diagnostics, wrappers []WrapperDef) {
+	seen := make(map[string]bool)
+
+	for i := range wrappers {
+		w := &wrappers[i]
+
+		if w.Type == "" {
+			res.AddError("invalid_wrapper", fmt.Sprintf("wrapper #%d: type is required", i+1), "", "")
+			continue
+		}
+
+		if seen[w.Type] {
+			res.AddError("duplicate_wrapper", fmt.Sprintf("duplicate wrapper %q", w.Type), "", w.Type)
+			continue
+		}
+
+		seen[w.Type] = true
+
+		if w.Get == "" {
+			res.AddError("invalid_wrapper", fmt.Sprintf("wrapper %q: get method is required", w.Type), "", w.Type)
+		}
+
+		if w.New != "" && w.Set != "" {
+			res.AddWarning("wrapper_new_and_set",
+				fmt.Sprintf("wrapper %q: both new and set are declared; new is used", w.Type), "", w.Type)
+		}
+	}
+}
+
+// validateAbbreviations checks that abbreviations are single name tokens with
+// a non-empty expansion.
+func validateAbbreviations(res *diagnostic.Diagnostics, abbreviations map[string]string) {
+	for _, abbr := range slices.Sorted(maps.Keys(abbreviations)) {
+		if !isNameToken(abbr) || strings.TrimSpace(abbreviations[abbr]) == "" {
+			res.AddError("invalid_abbreviation",
+				fmt.Sprintf("invalid abbreviation %q: %q (expected a single name token and its expansion)",
+					abbr, abbreviations[abbr]), "", "match.abbreviations")
+		}
+	}
+}
+
+// validateSynonyms checks that synonym names aren't empty and that no name
+// belongs to two groups, which would make both groups synonyms.
+func validateSynonyms(res *diagnostic.Diagnostics, synonyms map[string][]string, n match.Normalizer) {
+	groupOf := make(map[string]string)
+
+	for _, key := range slices.Sorted(maps.Keys(synonyms)) {
+		for _, name := range append([]string{key}, synonyms[key]...) {
+			norm := n.Normalize(name)
+			if norm == "" {
+				res.AddError("invalid_synonym", fmt.Sprintf("synonyms of %q: empty name", key), "", "match.synonyms")
+				continue
+			}
+
+			if other, ok := groupOf[norm]; ok && other != key {
+				res.AddError("invalid_synonym",
+					fmt.Sprintf("%q is a synonym of both %q and %q", name, other, key), "", "match.synonyms")
+
+				continue
+			}
+
+			groupOf[norm] = key
+		}
+	}
+}
+
+// validateImplementations checks that registered implementation pairs resolve
+// and that each has a mapping, since the type switch calls its caster.
+func validateImplementations(res *diagnostic.Diagnostics, mf *MappingFile, graph *analyze.TypeGraph) {
+	seen := make(map[string]bool)
+
+	for i := range mf.Implementations {
+		impl := &mf.Implementations[i]
+
+		if impl.Source == "" || impl.Target == "" {
+			res.AddError("invalid_implementation",
+				fmt.Sprintf("implementation #%d: source and target are required", i+1), "", "")
+
+			continue
+		}
+
+		tpStr := fmt.Sprintf("%s->%s", impl.Source, impl.Target)
+		if seen[tpStr] {
+			res.AddError("duplicate_implementation", fmt.Sprintf("duplicate implementation %q", tpStr), tpStr, "")
+			continue
+		}
+
+		seen[tpStr] = true
+
+		srcT := ResolveTypeID(impl.Source, graph)
+		dstT := ResolveTypeID(impl.Target, graph)
+
+		if srcT == nil || dstT == nil {
+			res.AddError("implementation_type_not_found",
+				fmt.Sprintf("implementation %q: type not found", tpStr), tpStr, "")
+
+			continue
+		}
+
+		if !hasTypeMapping(mf, graph, srcT.ID, dstT.ID) {
+			res.AddError("implementation_not_mapped",
+				fmt.Sprintf("implementation %q has no mapping; add one so its caster is generated", tpStr), tpStr, "")
+		}
+	}
+}
+
+// hasTypeMapping reports whether the mapping file declares a mapping for the type pair.
+func hasTypeMapping(mf *MappingFile, graph *analyze.TypeGraph, src, dst analyze.TypeID) bool {
+	for i := range mf.TypeMappings {
+		tm := &mf.TypeMappings[i]
+
+		srcT := ResolveTypeID(tm.Source, graph)
+		dstT := ResolveTypeID(tm.Target, graph)
+
+		if srcT != nil && dstT != nil && srcT.ID == src && dstT.ID == dst {
+			return true
+		}
+	}
+
+	return false
+}
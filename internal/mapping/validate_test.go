@@ -1,12 +1,15 @@
 package mapping
 
 import (
+	"go/token"
+	"go/types"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
 )
 
 // buildTestTypeGraph creates a simple type graph for testing validation.
@@ -15,8 +18,8 @@ func buildTestTypeGraph() *analyze.TypeGraph {
 
 	// Create store.Order type
 	storeOrderID := analyze.TypeID{PkgPath: "caster-generator/store", Name: "Order"}
-	stringType := &analyze.TypeInfo{Kind: analyze.TypeKindBasic}
-	intType := &analyze.TypeInfo{Kind: analyze.TypeKindBasic}
+	stringType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	intType := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
 
 	itemType := &analyze.TypeInfo{
 		ID:   analyze.TypeID{PkgPath: "caster-generator/store", Name: "Item"},
@@ -160,6 +163,30 @@ mappings:
 	assert.Contains(t, result.Errors[0].Message, "nonexistent.Type")
 }
 
+func TestValidate_GenericNotInstantiated(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Page
+    target: warehouse.Order
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	graph := buildTestTypeGraph()
+	page := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: "caster-generator/store", Name: "Page"},
+		Kind:       analyze.TypeKindStruct,
+		TypeParams: []string{"T"},
+	}
+	graph.Types[page.ID] = page
+
+	result := Validate(mf, graph)
+
+	assert.False(t, result.IsValid())
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "generic_type_not_instantiated", result.Errors[0].Code)
+}
+
 func TestValidate_InvalidSourceField(t *testing.T) {
 	yaml := `
 mappings:
@@ -202,6 +229,51 @@ mappings:
 	assert.Contains(t, valErr.Error(), "NonExistentField")
 }
 
+func TestValidate_Positions(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    ignore: [Missing]
+    fields:
+      - target: ID
+        source: OrderID
+      - target: Status
+        source: NonExistentField
+  - source: store.Missing
+    target: warehouse.Order
+transforms:
+  - name: T
+    source_type: string
+    target_type: string
+  - name: T
+    source_type: string
+    target_type: string
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	assert.Equal(t, diagnostic.Position{Line: 3, Column: 5}, mf.TypeMappings[0].Pos)
+	assert.Equal(t, diagnostic.Position{Line: 9, Column: 9}, mf.TypeMappings[0].Fields[1].Pos)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	pos := make(map[string]string)
+	for _, e := range result.Errors {
+		pos[e.Code] = e.Pos.String()
+	}
+
+	// Errors point at the innermost entry at fault.
+	assert.Equal(t, map[string]string{
+		"invalid_source_path":   "line 9, column 9",
+		"invalid_ignore_path":   "line 5, column 14",
+		"source_type_not_found": "line 11, column 5",
+		"duplicate_transform":   "line 17, column 5",
+	}, pos)
+
+	assert.Contains(t, result.Error().Error(), "line 9, column 9: [store.Order->warehouse.Order] NonExistentField:")
+}
+
 func TestValidate_UnexportedField(t *testing.T) {
 	yaml := `
 mappings:
@@ -223,6 +295,203 @@ mappings:
 	assert.Contains(t, valErr.Error(), "not exported")
 }
 
+func TestValidate_AllowUnexported(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    allow_unexported: true
+    fields:
+      - target: ID
+        source: internal
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	graph := buildTestTypeGraph()
+	result := Validate(mf, graph)
+
+	assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+}
+
+func TestValidate_MaxDepth(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    max_depth: -1
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_max_depth", result.Errors[0].Code)
+	assert.Equal(t, "max_depth", result.Errors[0].FieldPath)
+}
+
+func TestValidate_Matching(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    matching: {min_confidence: 0.85, min_gap: 1.5}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.85, mf.TypeMappings[0].Matching.MinConfidence, 1e-9)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_matching", result.Errors[0].Code)
+	assert.Equal(t, "matching.min_gap", result.Errors[0].FieldPath)
+}
+
+func TestValidate_Via(t *testing.T) {
+	validate := func(yaml string) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(yaml))
+		require.NoError(t, err)
+
+		return Validate(mf, buildTestTypeGraph())
+	}
+
+	t.Run("composes existing mappings", func(t *testing.T) {
+		result := validate(`
+mappings:
+  - source: store.Order
+    target: store.Item
+  - source: store.Item
+    target: warehouse.Order
+  - source: store.Order
+    target: warehouse.Order
+    via: store.Item
+`)
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+	})
+
+	t.Run("missing hop", func(t *testing.T) {
+		result := validate(`
+mappings:
+  - source: store.Order
+    target: store.Item
+  - source: store.Order
+    target: warehouse.Order
+    via: store.Item
+`)
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, "via_mapping_missing", result.Errors[0].Code)
+		assert.Contains(t, result.Errors[0].Message, "from caster-generator/store.Item to caster-generator/warehouse.Order")
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		result := validate(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    via: canonical.Order
+`)
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, "via_type_not_found", result.Errors[0].Code)
+	})
+
+	t.Run("with fields", func(t *testing.T) {
+		result := validate(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    via: warehouse.Order
+    fields:
+      - target: ID
+        source: OrderID
+`)
+		codes := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			codes = append(codes, e.Code)
+		}
+
+		assert.Equal(t, []string{"invalid_via", "invalid_via"}, codes)
+	})
+}
+
+func TestValidate_Hooks(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	named := func(pkg string) (*analyze.TypeInfo, types.Type) {
+		p := types.NewPackage("example/"+pkg, pkg)
+		field := types.NewField(token.NoPos, p, "Name", types.Typ[types.String], false)
+		obj := types.NewTypeName(token.NoPos, p, "Order", nil)
+		typ := types.NewNamed(obj, types.NewStruct([]*types.Var{field}, nil), nil)
+
+		info := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: p.Path(), Name: "Order"}, Kind: analyze.TypeKindStruct,
+			GoType: typ}
+		graph.Types[info.ID] = info
+
+		return info, typ
+	}
+	_, src := named("src")
+	_, dst := named("dst")
+
+	addFunc := func(name string, params, results []types.Type) {
+		tuple := func(ts []types.Type) *types.Tuple {
+			vars := make([]*types.Var, 0, len(ts))
+			for _, t := range ts {
+				vars = append(vars, types.NewParam(token.NoPos, nil, "", t))
+			}
+
+			return types.NewTuple(vars...)
+		}
+
+		id := analyze.TypeID{PkgPath: "example/hooks", Name: name}
+		graph.Funcs[id] = &analyze.FuncInfo{
+			ID:        id,
+			Signature: types.NewSignatureType(nil, nil, nil, tuple(params), tuple(results), false),
+		}
+	}
+	addFunc("Normalize", []types.Type{src}, []types.Type{src})
+	addFunc("Stamp", []types.Type{src, types.NewPointer(dst)}, nil)
+	addFunc("Check", []types.Type{src}, nil)
+
+	validate := func(hooks string) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(`
+mappings:
+  - source: example/src.Order
+    target: example/dst.Order
+` + hooks))
+		require.NoError(t, err)
+
+		return Validate(mf, graph)
+	}
+
+	t.Run("matching signatures", func(t *testing.T) {
+		result := validate("    before: hooks.Normalize\n    after: hooks.Stamp\n")
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("casters package", func(t *testing.T) {
+		result := validate("    before: normalize\n")
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("mismatched signature", func(t *testing.T) {
+		result := validate("    before: hooks.Check\n    after: hooks.Normalize\n")
+		require.Len(t, result.Errors, 2)
+		assert.Equal(t, "hook_signature_mismatch", result.Errors[0].Code)
+		assert.Contains(t, result.Errors[0].Message, "expected func(example/src.Order) example/src.Order")
+		assert.Equal(t, "after", result.Errors[1].FieldPath)
+	})
+
+	t.Run("unknown function", func(t *testing.T) {
+		result := validate("    after: hooks.Missing\n")
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+		require.Len(t, result.Warnings, 1)
+		assert.Equal(t, "hook_func_not_found", result.Warnings[0].Code)
+	})
+}
+
 func TestValidate_NestedPath(t *testing.T) {
 	yaml := `
 mappings:
@@ -265,6 +534,364 @@ transforms:
 	assert.Contains(t, valErr.Error(), "duplicate transform")
 }
 
+func TestValidate_TransformTemplates(t *testing.T) {
+	yaml := `
+mappings: []
+transforms:
+  - name: Upper
+    template: strings.ToUpper({{.Source}})
+    imports: [strings]
+  - name: Broken
+    template: "{{.Source"
+  - name: Called
+    func: FormatCode
+    template: "{{.Source}}"
+  - name: Unused
+    imports: [strings]
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 3)
+
+	for _, e := range result.Errors {
+		assert.Equal(t, "invalid_transform_template", e.Code)
+	}
+
+	assert.Contains(t, result.Errors[0].Message, `transform "Broken"`)
+	assert.Equal(t, `transform "Called": template excludes func, package and memoize`, result.Errors[1].Message)
+	assert.Equal(t, `transform "Unused": imports require a template`, result.Errors[2].Message)
+}
+
+func TestValidate_MemoizeCtx(t *testing.T) {
+	yaml := `
+mappings: []
+transforms:
+  - name: FormatMoney
+    memoize: true
+  - name: LookupRate
+    ctx: true
+    memoize: true
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_memoize", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, `transform "LookupRate"`)
+}
+
+func TestValidate_ReadOnlyTarget(t *testing.T) {
+	graph := buildTestTypeGraph()
+	graph.Packages["github.com/acme/client"] = &analyze.PackageInfo{
+		Path: "github.com/acme/client", Name: "client", Module: "github.com/acme/client", External: true,
+	}
+
+	mf := &MappingFile{TypeMappings: []TypeMapping{
+		{Source: "store.Order", Target: "client.OrderDTO", GenerateTarget: true},
+		{Source: "store.Order", Target: "OrderDTO", GenerateTarget: true},
+	}}
+
+	result := Validate(mf, graph)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "read_only_target", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, "github.com/acme/client belongs to module github.com/acme/client")
+}
+
+func TestValidate_Method(t *testing.T) {
+	validate := func(options string, external bool) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+` + options))
+		require.NoError(t, err)
+
+		graph := buildTestTypeGraph()
+		if external {
+			graph.Packages["caster-generator/store"] = &analyze.PackageInfo{
+				Path: "caster-generator/store", Name: "store", Module: "caster-generator", External: true,
+			}
+		}
+
+		return Validate(mf, graph)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		result := validate("    method: ToWarehouse\n", false)
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for options, want := range map[string]string{
+			"    method: to-warehouse\n":                      `method "to-warehouse" is not a Go identifier`,
+			"    method: Items\n":                             "method Items collides with field",
+			"    method: internal\n":                          "method internal collides with field",
+			"    method: ToWarehouse\n    output: {dir: x}\n": "it can't set output",
+		} {
+			result := validate(options, false)
+			require.Len(t, result.Errors, 1, options)
+			assert.Contains(t, result.Errors[0].Message, want)
+			assert.Equal(t, "method", result.Errors[0].FieldPath)
+		}
+	})
+
+	t.Run("read-only source", func(t *testing.T) {
+		result := validate("    method: ToWarehouse\n", true)
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, "read_only_source", result.Errors[0].Code)
+	})
+}
+
+func TestValidate_DuplicateTargets(t *testing.T) {
+	mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: ID
+        source: OrderID
+      - target: [Customer, Status]
+        source: CustomerName
+      - target: ID
+        source: CustomerName
+      - target: Customer
+        source: FirstName
+`))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+	require.Len(t, result.Errors, 2, "errors: %v", result.Errors)
+
+	assert.Equal(t, "duplicate_target", result.Errors[0].Code)
+	assert.Equal(t, `target "ID" is already mapped by fields entry 1`, result.Errors[0].Message)
+	assert.Equal(t, 10, result.Errors[0].Pos.Line)
+	assert.Equal(t, `target "Customer" is already mapped by fields entry 2`, result.Errors[1].Message)
+	assert.Equal(t, 12, result.Errors[1].Pos.Line)
+}
+
+func TestValidate_RequireFullCoverage(t *testing.T) {
+	validate := func(rules string) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    require_full_coverage: true
+` + rules))
+		require.NoError(t, err)
+
+		return Validate(mf, buildTestTypeGraph())
+	}
+
+	t.Run("covered", func(t *testing.T) {
+		result := validate(`    121:
+      OrderID: ID
+      First*: Full*
+    fields:
+      - target: Customer
+        source: CustomerName
+    ignore: [Status]
+    auto:
+      - target: Amount
+        source: Price
+      - target: DisplayName
+        source: CustomerName
+`)
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+	})
+
+	t.Run("uncovered", func(t *testing.T) {
+		result := validate(`    121:
+      OrderID: ID
+    ignore: [Status, DisplayName, FullName]
+`)
+		require.Len(t, result.Errors, 2)
+
+		for i, field := range []string{"Customer", "Amount"} {
+			assert.Equal(t, "uncovered_target", result.Errors[i].Code)
+			assert.Equal(t, field, result.Errors[i].FieldPath)
+		}
+	})
+
+	t.Run("ignored by file", func(t *testing.T) {
+		result := validate(`    121:
+      OrderID: ID
+      CustomerName: Customer
+      Price: Amount
+ignore_patterns: [Status, "*Name"]
+`)
+		assert.True(t, result.IsValid(), "errors: %v", result.Errors)
+	})
+
+	t.Run("overlapping", func(t *testing.T) {
+		result := validate(`    121:
+      OrderID: ID
+      CustomerName: Customer
+      Price: Amount
+    ignore: [Status, DisplayName, FullName, ID]
+    auto:
+      - target: Amount
+        source: Price
+`)
+		require.Len(t, result.Errors, 2)
+		assert.Equal(t, "overlapping_target", result.Errors[0].Code)
+		assert.Contains(t, result.Errors[0].Message, `target "Amount" is mapped by 121 and auto`)
+		assert.Contains(t, result.Errors[1].Message, `target "ID" is mapped by 121 and ignore`)
+	})
+}
+
+func TestValidate_KeyedPaths(t *testing.T) {
+	graph := buildTestTypeGraph()
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	integer := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+	src := graph.Types[analyze.TypeID{PkgPath: "caster-generator/store", Name: "Order"}]
+	src.Fields = append(src.Fields,
+		analyze.FieldInfo{Name: "Attrs", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindMap, KeyType: str, ElemType: str,
+		}},
+		analyze.FieldInfo{Name: "Codes", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindArray, ElemType: integer, GoType: types.NewArray(types.Typ[types.Int], 3),
+		}},
+	)
+
+	validate := func(source, target string) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    121:
+      '` + source + `': ` + target + `
+`))
+		require.NoError(t, err)
+
+		return Validate(mf, graph)
+	}
+
+	for source, target := range map[string]string{
+		"Items[0].ProductID": "ID",
+		`Attrs["color"]`:     "Status",
+		"Codes[2]":           "Amount",
+	} {
+		result := validate(source, target)
+		assert.True(t, result.IsValid(), "%s: %v", source, result.Errors)
+	}
+
+	for source, want := range map[string]string{
+		"Items[-1].ProductID":  "index -1 is not a non-negative integer",
+		`Items["a"].ProductID`: `index "a" is not a non-negative integer`,
+		"Attrs[1]":             "key 1 is an integer literal, not a key of map",
+		"Codes[3]":             "index 3 is out of the bounds of [3]int",
+		"Price[0]":             "[0] needs a slice, array or map",
+	} {
+		result := validate(source, "Amount")
+		require.Len(t, result.Errors, 1, source)
+		assert.Equal(t, "invalid_source_path", result.Errors[0].Code)
+		assert.Contains(t, result.Errors[0].Message, want)
+	}
+
+	result := validate("OrderID", "Status[0]")
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_target_path", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, "only source paths can access an element by index or key")
+}
+
+func TestValidate_Currency(t *testing.T) {
+	graph := buildTestTypeGraph()
+
+	for currency, want := range map[string]string{
+		"{code: USD}":                  "",
+		"{code: XAU, minor_units: 0}":  "",
+		"{minor_units: 3}":             "",
+		"{code: usd}":                  `invalid currency code "usd"`,
+		"{code: XAU}":                  "currency XAU has no known minor unit; set minor_units",
+		"{code: EUR, minor_units: 19}": "invalid minor_units 19 (expected 0 to 18)",
+	} {
+		mf, err := Parse([]byte("currency: " + currency + "\nmappings: []\n"))
+		require.NoError(t, err, currency)
+
+		result := Validate(mf, graph)
+		if want == "" {
+			assert.True(t, result.IsValid(), "%s: %v", currency, result.Errors)
+			continue
+		}
+
+		require.Len(t, result.Errors, 1, currency)
+		assert.Equal(t, "invalid_currency", result.Errors[0].Code)
+		assert.Contains(t, result.Errors[0].Message, want)
+	}
+}
+
+func TestValidate_MoneyTransforms(t *testing.T) {
+	graph := buildTestTypeGraph()
+
+	named := func(path, name string) types.Type {
+		obj := types.NewTypeName(token.NoPos, types.NewPackage(path, name), name, nil)
+		return types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+	}
+
+	decimal := named("github.com/shopspring/decimal", "Decimal")
+	rat := types.NewPointer(named("math/big", "Rat"))
+	cents := &analyze.TypeInfo{
+		ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.Int64],
+	}
+
+	src := graph.Types[analyze.TypeID{PkgPath: "caster-generator/store", Name: "Order"}]
+	src.Fields = append(src.Fields,
+		analyze.FieldInfo{Name: "TotalCents", Exported: true, Type: cents},
+		analyze.FieldInfo{Name: "Ratio", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, GoType: rat}},
+	)
+
+	dst := graph.Types[analyze.TypeID{PkgPath: "caster-generator/warehouse", Name: "Order"}]
+	dst.Fields = append(dst.Fields,
+		analyze.FieldInfo{Name: "Total", Exported: true, Type: &analyze.TypeInfo{
+			Kind: analyze.TypeKindExternal, GoType: decimal,
+		}},
+		analyze.FieldInfo{Name: "RatioCents", Exported: true, Type: cents},
+	)
+
+	validate := func(source, target, transform, extra string) *diagnostic.Diagnostics {
+		mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: ` + source + `
+        target: ` + target + `
+        transform: ` + transform + `
+` + extra))
+		require.NoError(t, err)
+
+		return Validate(mf, graph)
+	}
+
+	assert.True(t, validate("TotalCents", "Total", "CentsToDecimal", "").IsValid())
+	assert.True(t, validate("Ratio", "RatioCents", "RatToCents", "").IsValid())
+
+	result := validate("TotalCents", "Total", "DecimalToCents", "")
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_money_transform", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, "needs a decimal.Decimal source, not int64")
+	assert.Contains(t, result.Errors[1].Message, "needs an int64 target, not github.com/shopspring/decimal.Decimal")
+
+	result = validate("TotalCents", "RatioCents", "CentsToRat", "")
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "transform CentsToRat: needs a *big.Rat target, not int64")
+
+	result = validate("[TotalCents, Ratio]", "Total", "CentsToDecimal", "")
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "takes exactly one source and one target")
+
+	// A declared transform replaces the builtin one
+	declared := validate("TotalCents", "RatioCents", "CentsToDecimal", "transforms:\n  - name: CentsToDecimal\n")
+	assert.True(t, declared.IsValid(), "%v", declared.Errors)
+}
+
 func TestValidate_FieldMappingWithIgnore(t *testing.T) {
 	yaml := `
 mappings:
@@ -526,3 +1153,562 @@ transforms:
 
 	assert.True(t, result.IsValid(), "expected valid mapping, got errors: %v", result.Errors)
 }
+
+func TestValidate_NilPolicyConflict(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: ID
+        source: OrderID
+        nil_to_empty: true
+        preserve_nil: true
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.False(t, result.IsValid())
+	assert.Equal(t, "conflicting_nil_policy", result.Errors[0].Code)
+}
+
+func TestValidate_NilPolicyOnNonCollection(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: ID
+        source: OrderID
+        preserve_nil: true
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	assert.True(t, result.IsValid(), "unexpected errors: %v", result.Errors)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "nil_policy_not_collection", result.Warnings[0].Code)
+}
+
+func TestValidate_PinPolicy(t *testing.T) {
+	mf, err := Parse([]byte("on_incompatible_pin: warn\nmappings: []\n"))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_pin_policy", result.Errors[0].Code)
+}
+
+func TestValidate_DedupBy(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: store.Order
+    fields:
+      - target: Items
+        source: Items
+        dedup_by: ProductID
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: ID
+        source: OrderID
+        dedup_by: ProductID
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1, "unexpected errors: %v", result.Errors)
+	assert.Equal(t, "invalid_dedup_by", result.Errors[0].Code)
+	assert.Equal(t, "ID", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[0].Message, "requires a slice target")
+
+	mf.TypeMappings[0].Fields[0].DedupBy = "Missing"
+	result = Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Contains(t, result.Errors[0].Message, `dedup_by field "Missing" not found`)
+}
+
+func TestValidate_MergePolicy(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    generate_merge: true
+    fields:
+      - target: ID
+        source: OrderID
+        merge: if_zero
+      - target: Status
+        source: CustomerName
+        merge: keep
+  - source: store.Order
+    target: store.Order
+    fields:
+      - target: Items[].ProductID
+        source: Items[].ProductID
+        merge: if_zero
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+	assert.True(t, mf.TypeMappings[0].GenerateMerge)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2, "unexpected errors: %v", result.Errors)
+	assert.Equal(t, "invalid_merge_policy", result.Errors[0].Code)
+	assert.Equal(t, "Status", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[0].Message, `invalid merge "keep"`)
+	assert.Equal(t, "invalid_merge_policy", result.Errors[1].Code)
+	assert.Contains(t, result.Errors[1].Message, "can't check slice elements")
+}
+
+func TestValidate_CopyMode(t *testing.T) {
+	yaml := `
+copy_mode: alias
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    copy_mode: full
+    fields:
+      - target: ID
+        source: OrderID
+        copy: deep
+      - target: Status
+        source: CustomerName
+        copy: clone
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_copy_mode", result.Errors[0].Code)
+	assert.Equal(t, "copy_mode", result.Errors[0].FieldPath)
+	assert.Equal(t, "invalid_copy_mode", result.Errors[1].Code)
+	assert.Equal(t, "Status", result.Errors[1].FieldPath)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "copy_mode_no_effect", result.Warnings[0].Code)
+}
+
+func TestValidate_IgnoreTags(t *testing.T) {
+	mf := &MappingFile{IgnoreTags: []string{"json", "caster", "", `json:"-"`}}
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_ignore_tag", result.Errors[0].Code)
+	assert.Equal(t, "ignore_tags", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[1].Message, `"json:\"-\""`)
+}
+
+func TestValidate_FieldTags(t *testing.T) {
+	yaml := `
+generated_types:
+  copy_tags: [json, "db:x"]
+mappings:
+  - source: store.Order
+    target: OrderDTO
+    generate_target: true
+    fields:
+      - source: OrderID
+        target: ID
+        tags: {json: "id,omitempty", "": x, db: "a` + "`" + `b"}
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - source: OrderID
+        target: ID
+        tags: {json: id}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.FieldPath+": "+e.Code)
+	}
+
+	assert.Equal(t, []string{
+		"generated_types.copy_tags: invalid_copy_tag",
+		"ID: invalid_field_tag",
+		"ID: invalid_field_tag",
+		"ID: tags_without_generate_target",
+	}, codes)
+	assert.Equal(t, 9, result.Errors[1].Pos.Line)
+}
+
+func TestValidate_IgnorePatterns(t *testing.T) {
+	mf := &MappingFile{IgnorePatterns: []string{"XXX_*", "/^Legacy/", "CreatedAt", "[bad", "/(/", ""}}
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 3)
+	assert.Equal(t, "invalid_ignore_pattern", result.Errors[0].Code)
+	assert.Equal(t, "ignore_patterns", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[0].Message, `glob "[bad"`)
+	assert.Contains(t, result.Errors[1].Message, `regular expression "/(/"`)
+}
+
+func TestFieldPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"XXX_*", "XXX_unrecognized", true},
+		{"XXX_*", "ID", false},
+		{"*Unrecognized*", "LegacyUnrecognizedData", true},
+		{"CreatedAt", "CreatedAt", true},
+		{"CreatedAt", "CreatedAtUTC", false},
+		{"/^Legacy/", "LegacyID", true},
+		{"/^Legacy/", "IDLegacy", false},
+		{"/", "/", true},
+	}
+
+	for _, tt := range tests {
+		p, err := CompileFieldPattern(tt.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, p.Match(tt.name), "%s matching %s", tt.pattern, tt.name)
+	}
+}
+
+func TestValidate_Synonyms(t *testing.T) {
+	yaml := `
+match:
+  synonyms:
+    Amount: [Price, Cost, ""]
+    Total: [Sum, cost]
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_synonym", result.Errors[0].Code)
+	assert.Equal(t, `synonyms of "Amount": empty name`, result.Errors[0].Message)
+	assert.Equal(t, `"cost" is a synonym of both "Amount" and "Total"`, result.Errors[1].Message)
+}
+
+func TestValidate_Abbreviations(t *testing.T) {
+	mf := &MappingFile{Match: MatchOptions{Abbreviations: map[string]string{
+		"Qty":      "Quantity",
+		"Ship Amt": "ShippingAmount",
+		"Ref":      " ",
+	}}}
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_abbreviation", result.Errors[0].Code)
+	assert.Contains(t, result.Errors[0].Message, `"Ref"`)
+	assert.Contains(t, result.Errors[1].Message, `"Ship Amt"`)
+}
+
+func TestValidate_PositionWeight(t *testing.T) {
+	for _, weight := range []float64{0, 0.3, 1} {
+		mf := &MappingFile{Match: MatchOptions{PositionWeight: weight}}
+		assert.Empty(t, Validate(mf, buildTestTypeGraph()).Errors, "weight %v", weight)
+	}
+
+	for _, weight := range []float64{-0.1, 1.5} {
+		mf := &MappingFile{Match: MatchOptions{PositionWeight: weight}}
+		result := Validate(mf, buildTestTypeGraph())
+
+		require.Len(t, result.Errors, 1, "weight %v", weight)
+		assert.Equal(t, "invalid_matching", result.Errors[0].Code)
+		assert.Equal(t, "match.position_weight", result.Errors[0].FieldPath)
+	}
+}
+
+func TestValidate_MatchTag(t *testing.T) {
+	assert.Empty(t, Validate(&MappingFile{Match: MatchOptions{Tag: "json"}}, buildTestTypeGraph()).Errors)
+
+	result := Validate(&MappingFile{Match: MatchOptions{Tag: `json:"id"`}}, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_matching", result.Errors[0].Code)
+	assert.Equal(t, "match.tag", result.Errors[0].FieldPath)
+}
+
+func TestValidate_Wrappers(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+wrappers:
+  - type: nullable.Nullable
+    get: MustGet
+    new: NewNullableWithValue
+    set: Set
+  - type: nullable.Nullable
+    get: Get
+  - type: optional.Option
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.Code)
+	}
+
+	assert.ElementsMatch(t, []string{"duplicate_wrapper", "invalid_wrapper"}, codes)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "wrapper_new_and_set", result.Warnings[0].Code)
+}
+
+func TestWrapperDef_Matches(t *testing.T) {
+	id := analyze.TypeID{PkgPath: "github.com/oapi-codegen/nullable", Name: "Nullable"}
+
+	assert.True(t, (&WrapperDef{Type: "nullable.Nullable"}).Matches(id))
+	assert.True(t, (&WrapperDef{Type: "github.com/oapi-codegen/nullable.Nullable"}).Matches(id))
+	assert.False(t, (&WrapperDef{Type: "codegen/nullable.Nullable"}).Matches(analyze.TypeID{Name: "Nullable"}))
+	assert.False(t, (&WrapperDef{Type: "able.Nullable"}).Matches(id))
+}
+
+func TestValidate_Implementations(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+implementations:
+  - source: store.Order
+    target: warehouse.Order
+  - source: store.Order
+    target: warehouse.Order
+  - source: store.Item
+    target: warehouse.Order
+  - source: store.Missing
+    target: warehouse.Order
+  - source: store.Order
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.Code)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"duplicate_implementation",
+		"implementation_not_mapped",
+		"implementation_type_not_found",
+		"invalid_implementation",
+	}, codes)
+}
+
+func TestValidate_OptionalSource(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: ID
+        source: TrackingID
+        optional_source: true
+      - target: Status
+        source: Carrier
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "invalid_source_path", result.Errors[0].Code)
+	assert.Equal(t, "Carrier", result.Errors[0].FieldPath)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "optional_source_missing", result.Warnings[0].Code)
+	assert.Equal(t, "TrackingID", result.Warnings[0].FieldPath)
+
+	tm := &mf.TypeMappings[0]
+	srcT := ResolveTypeID(tm.Source, buildTestTypeGraph())
+	assert.Equal(t, "TrackingID", MissingOptionalSource(tm, &tm.Fields[0], srcT))
+	assert.Empty(t, MissingOptionalSource(tm, &tm.Fields[1], srcT), "only optional sources are reported")
+}
+
+func TestValidate_NullDefault(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int64"}, Kind: analyze.TypeKindBasic}
+	boolean := &analyze.TypeInfo{ID: analyze.TypeID{Name: "bool"}, Kind: analyze.TypeKindBasic}
+	nullable := func(name, field string, value *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: "database/sql", Name: name},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: field, Exported: true, Type: value},
+				{Name: "Valid", Exported: true, Type: boolean, Index: 1},
+			},
+		}
+	}
+	row := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/db", Name: "Row"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: nullable("NullString", "String", str)},
+			{Name: "Count", Exported: true, Type: nullable("NullInt64", "Int64", num), Index: 1},
+			{Name: "Plain", Exported: true, Type: str, Index: 2},
+		},
+	}
+	user := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "example/api", Name: "User"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Count", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: num}, Index: 1},
+			{Name: "Plain", Exported: true, Type: str, Index: 2},
+		},
+	}
+	graph.Types[row.ID], graph.Types[user.ID] = row, user
+
+	yaml := `
+mappings:
+  - source: example/db.Row
+    target: example/api.User
+    fields:
+      - source: Name
+        target: Name
+        null_default: unknown
+      - source: Count
+        target: Count
+        null_default: many
+      - source: Plain
+        target: Plain
+        null_default: none
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, graph)
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "invalid_null_default", result.Errors[0].Code)
+	assert.Equal(t, "Count", result.Errors[0].FieldPath)
+	assert.Contains(t, result.Errors[0].Message, `"many" is not a valid int64`)
+	assert.Equal(t, "invalid_null_default", result.Errors[1].Code)
+	assert.Equal(t, "Plain", result.Errors[1].FieldPath)
+}
+
+func TestValidate_Output(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    output: {package: apiconv, dir: ./internal/apiconv}
+  - source: store.Item
+    target: warehouse.Order
+    output: {dir: ./internal/apiconv/}
+  - source: store.Order
+    target: store.Item
+    output: {package: other, dir: internal/apiconv}
+  - source: store.Item
+    target: store.Order
+    output: {dir: ./internal/api-conv}
+  - source: store.Item
+    target: store.Item
+    output: {}
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	var codes []string
+	for _, e := range result.Errors {
+		if e.FieldPath == "output" {
+			codes = append(codes, e.Code)
+		}
+	}
+
+	assert.Equal(t, []string{"output_conflict", "invalid_output", "invalid_output"}, codes)
+}
+
+func TestValidate_FieldRenamed(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    121:
+      Customer: Customer
+      OrderID: Identifier
+    fields:
+      - source: Price
+        target: Amounts
+    ignore:
+      - Statuss
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make(map[string]string)
+	for _, e := range result.Errors {
+		codes[e.FieldPath] = e.Code + ": " + e.Message
+	}
+
+	// Identifier is too far from ID; Statuss has no type to match but a close name.
+	assert.Equal(t, map[string]string{
+		"Customer": `field_renamed: invalid source path in 121: field "Customer" not found in ` +
+			`caster-generator/store.Order (likely renamed to CustomerName)`,
+		"Identifier": `invalid_target_path: invalid target path in 121: field "Identifier" not found in ` +
+			`caster-generator/warehouse.Order`,
+		"Amounts": `field_renamed: invalid target path: field "Amounts" not found in ` +
+			`caster-generator/warehouse.Order (likely renamed to Amount)`,
+		"Statuss": `field_renamed: invalid ignore path: field "Statuss" not found in ` +
+			`caster-generator/warehouse.Order (likely renamed to Status)`,
+	}, codes)
+}
+
+func TestValidate_DefaultType(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: Amount
+        default: '"pending"'
+      - target: Status
+        default: '"pending"'
+      - target: Customer
+        default: '"guest"'
+        when: in.CustomerName != ""
+        else_default: "0"
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	var msgs []string
+	for _, e := range result.Errors {
+		if e.Code == "invalid_default" {
+			msgs = append(msgs, e.Message)
+		}
+	}
+
+	assert.Equal(t, []string{
+		`target Amount: default "pending" is a string literal, expected a literal of type int`,
+		`target Customer: default 0 is an integer literal, expected a literal of type string`,
+	}, msgs)
+}
@@ -0,0 +1,336 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"strings"
+
+	"caster-generator/internal/analyze"
+)
+
+// ConstExpr returns the const value of a field mapping as a Go expression of
+// the target field type t, with the packages it refers to by qualifier. The
+// value is either a literal of t's basic underlying type (e.g., 42, true, or
+// pending or "pending" for a string, see EnumLiteral) or a constant of a
+// loaded package assignable to t (e.g., warehouse.StatusPending). A qualifier
+// naming the package of t refers to it, whatever its import path.
+func ConstExpr(value string, t *analyze.TypeInfo, graph *analyze.TypeGraph) (string, map[string]string, error) {
+	if qualifier, name, ok := qualifiedIdent(value); ok {
+		pkg := graph.Packages[t.ID.PkgPath]
+		if pkg == nil || pkg.Name != qualifier {
+			pkg = findPackage(qualifier, graph)
+		}
+
+		if pkg == nil {
+			return "", nil, fmt.Errorf("package %s of const %s is not loaded", qualifier, value)
+		}
+
+		c := graph.GetConst(analyze.TypeID{PkgPath: pkg.Path, Name: name})
+		if c == nil {
+			return "", nil, fmt.Errorf("constant %s not found in %s", name, pkg.Path)
+		}
+
+		if c.Type != nil && c.Type.GoType != nil && t.GoType != nil && !types.AssignableTo(c.Type.GoType, t.GoType) {
+			return "", nil, fmt.Errorf("constant %s of type %s is not assignable to %s", value, c.Type.GoType, t.GoType)
+		}
+
+		return value, map[string]string{qualifier: pkg.Path}, nil
+	}
+
+	lit, err := EnumLiteral(value, t)
+	if errors.Is(err, errEnumType) {
+		return "", nil, fmt.Errorf("const %q requires a string, numeric or bool target, or a named constant", value)
+	}
+
+	return lit, nil, err
+}
+
+// CheckDefault checks a default (or else_default), a Go expression assigned
+// to target fields of type t as-is. Literals (e.g., 42, -1.5, true or
+// "pending" quoted) must be valid values of t's basic underlying type, bare
+// identifiers must be predeclared, and other expressions, such as calls or
+// qualified constants, are left to the compiler.
+func CheckDefault(value string, t *analyze.TypeInfo) error {
+	x, err := parser.ParseExpr(value)
+	if err != nil {
+		return fmt.Errorf("default %s is not a Go expression: %w", value, err)
+	}
+
+	lit := literalValue(x)
+	if lit == nil {
+		if id, ok := x.(*ast.Ident); ok && types.Universe.Lookup(id.Name) == nil {
+			return fmt.Errorf("default %s is not declared (quote string literals, e.g. '%q')", value, value)
+		}
+
+		return nil
+	}
+
+	basic := enumBasicType(t)
+	if basic == nil {
+		switch t.Kind {
+		case analyze.TypeKindPointer, analyze.TypeKindSlice, analyze.TypeKindArray, analyze.TypeKindMap,
+			analyze.TypeKindStruct:
+			return fmt.Errorf("default %s is %s literal, which can't be assigned to a %s", value, literalKind(lit), t.Kind)
+		default:
+			// Interfaces and opaque types may accept it.
+			return nil
+		}
+	}
+
+	expected := basic.ID.Name
+	if t.ID.Name != basic.ID.Name {
+		expected = fmt.Sprintf("%s (%s)", basic.ID.Name, t.ID)
+	}
+
+	if literalFits(lit, basic.ID.Name) {
+		return nil
+	}
+
+	if isNumeric(lit) && constant.ToInt(lit).Kind() == constant.Int && isIntType(basic.ID.Name) {
+		return fmt.Errorf("default %s overflows %s", value, expected)
+	}
+
+	return fmt.Errorf("default %s is %s literal, expected a literal of type %s", value, literalKind(lit), expected)
+}
+
+// Value returns the constant value of a const extra, failing unless it is a
+// string, numeric or bool literal.
+func (c ExtraConst) Value() (constant.Value, error) {
+	x, err := parser.ParseExpr(string(c))
+	if err == nil {
+		if v := literalValue(x); v != nil {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("const %s is not a string, numeric or bool literal", c)
+}
+
+// DefaultType returns the type a const extra has when passed to a parameter
+// of no declared type, as for untyped Go constants (e.g., int for 10).
+func (c ExtraConst) DefaultType() string {
+	if x, err := parser.ParseExpr(string(c)); err == nil {
+		if lit, ok := x.(*ast.BasicLit); ok && lit.Kind == token.CHAR {
+			return "rune"
+		}
+	}
+
+	v, err := c.Value()
+	if err != nil {
+		return ""
+	}
+
+	switch v.Kind() {
+	case constant.String:
+		return "string"
+	case constant.Bool:
+		return "bool"
+	case constant.Int:
+		return "int"
+	case constant.Float:
+		return "float64"
+	default:
+		return "complex128"
+	}
+}
+
+// literalValue returns the constant value of a literal expression, possibly
+// signed or parenthesized, or nil if x isn't one.
+func literalValue(x ast.Expr) constant.Value {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return literalValue(x.X)
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(x.Value, x.Kind, 0)
+	case *ast.Ident:
+		if x.Name == "true" || x.Name == "false" {
+			return constant.MakeBool(x.Name == "true")
+		}
+	case *ast.UnaryExpr:
+		if v := literalValue(x.X); v != nil && (x.Op == token.SUB || x.Op == token.ADD) && isNumeric(v) {
+			return constant.UnaryOp(x.Op, v, 0)
+		}
+	}
+
+	return nil
+}
+
+// literalFits reports whether literal value v can be assigned to the basic type name.
+func literalFits(v constant.Value, name string) bool {
+	switch {
+	case name == "string":
+		return v.Kind() == constant.String
+	case name == "bool":
+		return v.Kind() == constant.Bool
+	case strings.HasPrefix(name, "complex"):
+		return isNumeric(v)
+	case strings.HasPrefix(name, "float"):
+		return isNumeric(v) && constant.ToFloat(v).Kind() == constant.Float
+	}
+
+	if !isNumeric(v) || !isIntType(name) {
+		return false
+	}
+
+	v = constant.ToInt(v)
+	if v.Kind() != constant.Int {
+		return false
+	}
+
+	lo, hi := intRange(name)
+
+	return constant.Compare(v, token.GEQ, lo) && constant.Compare(v, token.LEQ, hi)
+}
+
+// intRange returns the bounds of the integer type name, taking int, uint and
+// uintptr as 64 bits wide.
+func intRange(name string) (constant.Value, constant.Value) {
+	bits := map[string]int{
+		"int8": 8, "int16": 16, "int32": 32, "rune": 32,
+		"uint8": 8, "byte": 8, "uint16": 16, "uint32": 32,
+	}[name]
+	if bits == 0 {
+		bits = 64
+	}
+
+	if strings.HasPrefix(name, "int") || name == "rune" {
+		return constant.MakeInt64(math.MinInt64 >> (64 - bits)), constant.MakeInt64(math.MaxInt64 >> (64 - bits))
+	}
+
+	return constant.MakeInt64(0), constant.MakeUint64(math.MaxUint64 >> (64 - bits))
+}
+
+// isIntType reports whether the basic type name is an integer type.
+func isIntType(name string) bool {
+	return strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint") || name == "byte" || name == "rune"
+}
+
+// isNumeric reports whether v is a number.
+func isNumeric(v constant.Value) bool {
+	k := v.Kind()
+
+	return k == constant.Int || k == constant.Float || k == constant.Complex
+}
+
+// literalKind describes the kind of literal value v, with its article.
+func literalKind(v constant.Value) string {
+	switch v.Kind() {
+	case constant.String:
+		return "a string"
+	case constant.Bool:
+		return "a bool"
+	case constant.Int:
+		return "an integer"
+	case constant.Float:
+		return "a float"
+	default:
+		return "a complex"
+	}
+}
+
+// ExprQualifiers checks the identifiers of the expr of a field mapping, a Go
+// expression assigned to the target as-is. Qualified identifiers must be
+// declared by a loaded package, bare ones must be predeclared (e.g., len or
+// nil), and in.Field selectors read the source. It returns the packages the
+// expression refers to by qualifier and the source field paths it reads.
+func ExprQualifiers(expr string, graph *analyze.TypeGraph) (map[string]string, []string, error) {
+	x, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid expr %q: %w", expr, err)
+	}
+
+	qualifiers := make(map[string]string)
+
+	var (
+		paths []string
+		errs  []string
+		visit func(n ast.Node) bool
+	)
+
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.KeyValueExpr:
+			// Keys of struct literals name fields, not declarations.
+			if _, ok := n.Key.(*ast.Ident); ok {
+				ast.Inspect(n.Value, visit)
+				return false
+			}
+		case *ast.SelectorExpr:
+			if path, ok := sourceSelector(n); ok {
+				paths = append(paths, path)
+				return false
+			}
+
+			if id, ok := n.X.(*ast.Ident); ok {
+				if err := checkQualified(id.Name, n.Sel.Name, graph, qualifiers); err != nil {
+					errs = append(errs, err.Error())
+				}
+
+				return false
+			}
+
+			// Fields and methods of other values (e.g., a call result) aren't checked.
+			ast.Inspect(n.X, visit)
+
+			return false
+		case *ast.Ident:
+			if types.Universe.Lookup(n.Name) == nil {
+				errs = append(errs, "unknown identifier "+n.Name)
+			}
+		}
+
+		return true
+	}
+
+	ast.Inspect(x, visit)
+
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid expr %q: %s", expr, strings.Join(errs, "; "))
+	}
+
+	return qualifiers, paths, nil
+}
+
+// checkQualified checks that the package of qualifier is loaded and declares
+// name, recording its import path in qualifiers.
+func checkQualified(qualifier, name string, graph *analyze.TypeGraph, qualifiers map[string]string) error {
+	pkg := findPackage(qualifier, graph)
+	if pkg == nil {
+		return fmt.Errorf("package %s is not loaded", qualifier)
+	}
+
+	id := analyze.TypeID{PkgPath: pkg.Path, Name: name}
+	if graph.GetType(id) == nil && graph.GetFunc(id) == nil && graph.GetConst(id) == nil {
+		return fmt.Errorf("%s.%s is not declared in %s", qualifier, name, pkg.Path)
+	}
+
+	qualifiers[qualifier] = pkg.Path
+
+	return nil
+}
+
+// qualifiedIdent splits a qualified identifier such as warehouse.StatusPending.
+func qualifiedIdent(value string) (string, string, bool) {
+	x, err := parser.ParseExpr(value)
+	if err != nil {
+		return "", "", false
+	}
+
+	sel, ok := x.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+
+	return id.Name, sel.Sel.Name, true
+}
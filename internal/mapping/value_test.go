@@ -0,0 +1,258 @@
+package mapping
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+// buildValueTypeGraph returns a graph of a warehouse package declaring a
+// Status string type, its StatusPending constant and a Now function.
+func buildValueTypeGraph() (*analyze.TypeGraph, *analyze.TypeInfo) {
+	const pkgPath = "example/warehouse"
+
+	graph := analyze.NewTypeGraph()
+	graph.Packages[pkgPath] = &analyze.PackageInfo{Path: pkgPath, Name: "warehouse"}
+
+	named := types.NewNamed(types.NewTypeName(0, types.NewPackage(pkgPath, "warehouse"), "Status", nil),
+		types.Typ[types.String], nil)
+	status := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: pkgPath, Name: "Status"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic},
+		GoType:     named,
+	}
+	graph.Types[status.ID] = status
+
+	pending := analyze.TypeID{PkgPath: pkgPath, Name: "StatusPending"}
+	graph.Consts[pending] = &analyze.ConstInfo{ID: pending, Type: status, Value: `"pending"`}
+
+	now := analyze.TypeID{PkgPath: pkgPath, Name: "Now"}
+	graph.Funcs[now] = &analyze.FuncInfo{ID: now}
+
+	return graph, status
+}
+
+func TestConstExpr(t *testing.T) {
+	graph, status := buildValueTypeGraph()
+	intType := &analyze.TypeInfo{
+		ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.Int],
+	}
+
+	tests := []struct {
+		name       string
+		value      string
+		typ        *analyze.TypeInfo
+		want       string
+		qualifiers map[string]string
+		wantErr    bool
+	}{
+		{"int literal", "42", intType, "42", nil, false},
+		{"string literal", "pending", status, `"pending"`, nil, false},
+		{"quoted string literal", `"pending"`, status, `"pending"`, nil, false},
+		{"raw string literal", "`pending`", status, `"pending"`, nil, false},
+		{"named constant", "warehouse.StatusPending", status, "warehouse.StatusPending",
+			map[string]string{"warehouse": "example/warehouse"}, false},
+		{"constant of another type", "warehouse.StatusPending", intType, "", nil, true},
+		{"unknown constant", "warehouse.StatusPaid", status, "", nil, true},
+		{"unknown package", "store.StatusPending", status, "", nil, true},
+		{"invalid literal", "many", intType, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, qualifiers, err := ConstExpr(tt.value, tt.typ, graph)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.qualifiers, qualifiers)
+		})
+	}
+}
+
+func TestConstExpr_TargetPackage(t *testing.T) {
+	const pkgPath = "example/warehouse/v2"
+
+	graph := analyze.NewTypeGraph()
+	graph.Packages[pkgPath] = &analyze.PackageInfo{Path: pkgPath, Name: "warehouse"}
+
+	state := &analyze.TypeInfo{
+		ID:         analyze.TypeID{PkgPath: pkgPath, Name: "State"},
+		Kind:       analyze.TypeKindAlias,
+		Underlying: &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic},
+	}
+	active := analyze.TypeID{PkgPath: pkgPath, Name: "StateActive"}
+	graph.Consts[active] = &analyze.ConstInfo{ID: active, Type: state, Value: "1"}
+
+	// The qualifier names the package of the target type, not its directory
+	got, qualifiers, err := ConstExpr("warehouse.StateActive", state, graph)
+	require.NoError(t, err)
+	assert.Equal(t, "warehouse.StateActive", got)
+	assert.Equal(t, map[string]string{"warehouse": pkgPath}, qualifiers)
+
+	_, _, err = ConstExpr("warehouse.StateGone", state, graph)
+	assert.ErrorContains(t, err, "constant StateGone not found in example/warehouse/v2")
+}
+
+func TestCheckDefault(t *testing.T) {
+	_, status := buildValueTypeGraph()
+	basic := func(name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{Name: name}, Kind: analyze.TypeKindBasic}
+	}
+	pointer := &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: basic("int")}
+
+	tests := []struct {
+		name    string
+		value   string
+		typ     *analyze.TypeInfo
+		wantErr string
+	}{
+		{"int", "42", basic("int"), ""},
+		{"negative float", "-1.5", basic("float64"), ""},
+		{"integral float to int", "2.0", basic("int64"), ""},
+		{"quoted string", `"pending"`, status, ""},
+		{"bool", "true", basic("bool"), ""},
+		{"nil pointer", "nil", pointer, ""},
+		{"call", "time.Now()", basic("int"), ""},
+		{"string to int", `"pending"`, basic("int"),
+			`default "pending" is a string literal, expected a literal of type int`},
+		{"int to named string", "3", status,
+			"default 3 is an integer literal, expected a literal of type string (example/warehouse.Status)"},
+		{"float to int", "1.5", basic("int"), "default 1.5 is a float literal, expected a literal of type int"},
+		{"overflow", "300", basic("uint8"), "default 300 overflows uint8"},
+		{"negative unsigned", "-1", basic("uint"), "default -1 overflows uint"},
+		{"literal to pointer", "5", pointer, "default 5 is an integer literal, which can't be assigned to a pointer"},
+		{"unquoted string", "pending", status, `default pending is not declared (quote string literals, e.g. '"pending"')`},
+		{"not an expression", "1 +", basic("int"), "default 1 + is not a Go expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDefault(tt.value, tt.typ)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidate_StringLiterals(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: Status
+        const: '"open"'
+      - target: Customer
+        const: open
+      - target: Amount
+        const: '"100"'
+      - source: Price
+        target: DisplayName
+        enum_map:
+          1: '"ONE"'
+          2: TWO
+        enum_default: '"NONE"'
+      - source: CustomerName
+        target: FullName
+        enum_map:
+          '"a"': A
+          a: B
+      - source: OrderID
+        target: ID
+        enum_map:
+          a: warehouse.StatusPending
+        enum_default: warehouse.StatusGone
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	graph := buildTestTypeGraph()
+	graph.Packages["caster-generator/warehouse"] = &analyze.PackageInfo{Path: "caster-generator/warehouse", Name: "warehouse"}
+	pending := analyze.TypeID{PkgPath: "caster-generator/warehouse", Name: "StatusPending"}
+	graph.Consts[pending] = &analyze.ConstInfo{ID: pending, Value: `"pending"`}
+
+	result := Validate(mf, graph)
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.FieldPath+": "+e.Code)
+	}
+
+	// Quoted and bare strings are the same value on string targets only, and
+	// enum values may be constants of the target package
+	assert.Equal(t, []string{
+		"Amount: invalid_const",
+		"FullName: duplicate_enum_key",
+		"ID: invalid_enum_value",
+	}, codes)
+	assert.Contains(t, result.Errors[2].Message, "constant StatusGone not found")
+}
+
+func TestExprQualifiers(t *testing.T) {
+	graph, _ := buildValueTypeGraph()
+
+	qualifiers, paths, err := ExprQualifiers(
+		`warehouse.Status(in.Code) + warehouse.StatusPending + string(warehouse.Now().Month())`, graph)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"warehouse": "example/warehouse"}, qualifiers)
+	assert.Equal(t, []string{"Code"}, paths)
+
+	_, _, err = ExprQualifiers(`strconv.Itoa(in.Count) + suffix`, graph)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "package strconv is not loaded; unknown identifier suffix")
+
+	_, _, err = ExprQualifiers(`warehouse.Later()`, graph)
+	assert.ErrorContains(t, err, "warehouse.Later is not declared in example/warehouse")
+}
+
+func TestValidate_ConstExpr(t *testing.T) {
+	yaml := `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    fields:
+      - target: Amount
+        const: "100"
+      - target: Status
+        const: open
+      - target: Customer
+        expr: in.FirstName + " " + in.LastName
+      - target: DisplayName
+        expr: in.Nickname
+      - target: FullName
+        const: "1"
+        expr: in.FirstName
+      - target: ID
+        source: OrderID
+        const: x
+`
+	mf, err := Parse([]byte(yaml))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	codes := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		codes = append(codes, e.FieldPath+": "+e.Code)
+	}
+
+	assert.Equal(t, []string{
+		"DisplayName: invalid_expr",
+		"FullName: conflicting_value",
+		"ID: conflicting_value",
+	}, codes)
+}
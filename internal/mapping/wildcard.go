@@ -0,0 +1,104 @@
+package mapping
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/analyze"
+)
+
+// IsWildcard reports whether a 121 or fields path is a wildcard pattern
+// (e.g., "Meta*"), mapping a family of fields rather than one.
+func IsWildcard(path string) bool {
+	return strings.Contains(path, "*")
+}
+
+// IsWildcard reports whether fm is a wildcard rule: one of its paths is a
+// pattern.
+func (fm *FieldMapping) IsWildcard() bool {
+	return slices.ContainsFunc(slices.Concat(fm.Source, fm.Target), func(ref FieldRef) bool {
+		return IsWildcard(ref.Path)
+	})
+}
+
+// WildcardRules returns the wildcard 121 entries of tm and its prefix_map
+// entries as source -> target patterns (e.g., "Billing*" -> "Payment*"),
+// sorted by source pattern.
+func (tm *TypeMapping) WildcardRules() [][2]string {
+	var rules [][2]string
+
+	for _, source := range slices.Sorted(maps.Keys(tm.OneToOne)) {
+		if target := tm.OneToOne[source]; IsWildcard(source) || IsWildcard(target) {
+			rules = append(rules, [2]string{source, target})
+		}
+	}
+
+	for _, source := range slices.Sorted(maps.Keys(tm.PrefixMap)) {
+		rules = append(rules, [2]string{source + "*", tm.PrefixMap[source] + "*"})
+	}
+
+	slices.SortStableFunc(rules, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+
+	return rules
+}
+
+// WildcardFields returns the field pairs a wildcard rule maps: each exported
+// field of srcT whose name matches source, with "*" standing for a non-empty
+// part of it, is mapped to the field of dstT named by target with "*"
+// replaced by that part (e.g., BillingCity -> PaymentCity for "Billing*" and
+// "Payment*"), if dstT declares it. Patterns are field names with one "*".
+func WildcardFields(source, target string, srcT, dstT *analyze.TypeInfo) ([]FlatField, error) {
+	for _, pattern := range []string{source, target} {
+		if strings.Count(pattern, "*") != 1 || strings.ContainsAny(pattern, ".[]") {
+			return nil, fmt.Errorf("pattern %q must be a field name with one *", pattern)
+		}
+	}
+
+	prefix, suffix, _ := strings.Cut(source, "*")
+
+	var pairs []FlatField
+
+	for _, pf := range srcT.AccessibleFields() {
+		name := pf.Field.Name
+		if !pf.Field.Exported || len(name) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		part := name[len(prefix) : len(name)-len(suffix)]
+		if dst := strings.Replace(target, "*", part, 1); dstT.FieldByName(dst) != nil {
+			pairs = append(pairs, FlatField{Source: name, Target: dst})
+		}
+	}
+
+	return pairs, nil
+}
+
+// WildcardFieldMappings returns the field mappings wildcard rule fm of a
+// mapping from srcT to dstT expands to, one per field pair (see
+// WildcardFields) with the other options of fm. It must map one source
+// pattern to one target pattern.
+func WildcardFieldMappings(fm *FieldMapping, srcT, dstT *analyze.TypeInfo) ([]FieldMapping, error) {
+	if len(fm.Source) != 1 || len(fm.Target) != 1 {
+		return nil, fmt.Errorf("wildcard field mapping %s -> %s must map one source pattern to one target pattern",
+			strings.Join(fm.Source.Paths(), ", "), strings.Join(fm.Target.Paths(), ", "))
+	}
+
+	pairs, err := WildcardFields(fm.Source[0].Path, fm.Target[0].Path, srcT, dstT)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make([]FieldMapping, 0, len(pairs))
+
+	for _, p := range pairs {
+		rule := *fm
+		rule.Source = FieldRefArray{{Path: p.Source, Hint: fm.Source[0].Hint}}
+		rule.Target = FieldRefArray{{Path: p.Target, Hint: fm.Target[0].Hint}}
+		expanded = append(expanded, rule)
+	}
+
+	return expanded, nil
+}
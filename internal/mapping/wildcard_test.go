@@ -0,0 +1,72 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"caster-generator/internal/analyze"
+)
+
+func TestWildcardFields(t *testing.T) {
+	graph := buildTestTypeGraph()
+	srcT := graph.Types[analyze.TypeID{PkgPath: "caster-generator/store", Name: "Order"}]
+	dstT := graph.Types[analyze.TypeID{PkgPath: "caster-generator/warehouse", Name: "Order"}]
+
+	pairs, err := WildcardFields("Order*", "*", srcT, dstT)
+	require.NoError(t, err)
+	assert.Equal(t, []FlatField{{Source: "OrderID", Target: "ID"}}, pairs)
+
+	pairs, err = WildcardFields("*Name", "Full*", srcT, dstT)
+	require.NoError(t, err)
+	assert.Empty(t, pairs)
+
+	for _, pattern := range []string{"Meta", "Meta**", "Items[*]", "Meta.*"} {
+		_, err := WildcardFields(pattern, "*", srcT, dstT)
+		assert.EqualError(t, err, `pattern "`+pattern+`" must be a field name with one *`)
+	}
+}
+
+func TestWildcardRules(t *testing.T) {
+	tm := &TypeMapping{
+		OneToOne:  map[string]string{"Meta*": "Meta*", "OrderID": "ID", "Total": "Sum*"},
+		PrefixMap: map[string]string{"Billing": "Payment"},
+	}
+
+	assert.Equal(t, [][2]string{{"Billing*", "Payment*"}, {"Meta*", "Meta*"}, {"Total", "Sum*"}}, tm.WildcardRules())
+}
+
+func TestValidate_Wildcards(t *testing.T) {
+	mf, err := Parse([]byte(`
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    121:
+      "Order*": "*"
+      "Price": "Amount*"
+    prefix_map: {"First": "Full", "Last*": "X"}
+    fields:
+      - source: "First*"
+        target: "Display*"
+        transform: conv.Missing
+      - source: "Customer*"
+        target: [A, B]
+`))
+	require.NoError(t, err)
+
+	result := Validate(mf, buildTestTypeGraph())
+
+	var got []string
+	for _, e := range result.Errors {
+		got = append(got, e.Code+" "+e.Pos.String()+": "+e.Message)
+	}
+
+	assert.Equal(t, []string{
+		`invalid_wildcard line 8, column 35: pattern "Last**" must be a field name with one *`,
+		`invalid_wildcard line 7, column 7: pattern "Price" must be a field name with one *`,
+		`unknown_transform line 10, column 9: referenced transform "conv.Missing" is not declared in transforms`,
+		`invalid_wildcard line 13, column 9: wildcard field mapping Customer* -> A, B must map one source ` +
+			`pattern to one target pattern`,
+	}, got)
+}
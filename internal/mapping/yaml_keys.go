@@ -0,0 +1,267 @@
+package mapping
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/match"
+)
+
+// yamlField is a key of a struct decoded from YAML.
+type yamlField struct {
+	Key  string
+	Type reflect.Type
+	// Index is the index sequence of the field, for reflect.Value.FieldByIndex.
+	Index []int
+}
+
+// yamlFields returns the keys of struct type t by their yaml tags, with the
+// keys of inlined structs.
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if strings.Contains(opts, "inline") {
+			for _, inlined := range yamlFields(f.Type) {
+				inlined.Index = append([]int{i}, inlined.Index...)
+				fields = append(fields, inlined)
+			}
+
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields = append(fields, yamlField{Key: name, Type: f.Type, Index: []int{i}})
+	}
+
+	return fields
+}
+
+var (
+	yamlUnmarshalerType         = reflect.TypeFor[yaml.Unmarshaler]()
+	yamlObsoleteUnmarshalerType = reflect.TypeFor[interface{ UnmarshalYAML(func(any) error) error }]()
+)
+
+// customYAML reports whether t decodes itself, accepting several layouts.
+func customYAML(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+
+	return p.Implements(yamlUnmarshalerType) || p.Implements(yamlObsoleteUnmarshalerType)
+}
+
+// unknownKeys returns the errors of the keys of node that type t has no field
+// for, at the given key path, with their line and column. Types decoding
+// themselves are not checked.
+func unknownKeys(node *yaml.Node, t reflect.Type, at string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if node == nil || customYAML(t) {
+		return nil
+	}
+
+	var errs []error
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields := yamlFields(t)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+
+			field := findYAMLField(fields, key.Value)
+			if field == nil {
+				errs = append(errs, unknownKeyError(key, at, fields))
+				continue
+			}
+
+			errs = append(errs, unknownKeys(value, field.Type, joinKeyPath(at, key.Value))...)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+
+		for i, item := range node.Content {
+			errs = append(errs, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", at, i))...)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, unknownKeys(node.Content[i+1], t.Elem(), joinKeyPath(at, node.Content[i].Value))...)
+		}
+	}
+
+	return errs
+}
+
+var (
+	positionType    = reflect.TypeFor[diagnostic.Position]()
+	keyPositionType = reflect.TypeFor[map[string]diagnostic.Position]()
+)
+
+// setPositions sets the Pos field of the structs decoded from node into v,
+// and of those they hold, to the position of their YAML entries in file. The
+// positions of the keys of a map field X go to an XPos field of the struct.
+func setPositions(node *yaml.Node, v reflect.Value, file string) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	if node == nil {
+		return
+	}
+
+	// Structs decoding themselves, from a scalar too, still get their position.
+	if v.Kind() == reflect.Struct {
+		if pos := v.FieldByName("Pos"); pos.IsValid() && pos.Type() == positionType {
+			pos.Set(reflect.ValueOf(diagnostic.Position{File: file, Line: node.Line, Column: node.Column}))
+		}
+	}
+
+	if customYAML(v.Type()) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		fields := yamlFields(v.Type())
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if field := findYAMLField(fields, node.Content[i].Value); field != nil {
+				setPositions(node.Content[i+1], v.FieldByIndex(field.Index), file)
+				setKeyPositions(node.Content[i+1], v, field, file)
+			}
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for i, item := range node.Content {
+			if i < v.Len() {
+				setPositions(item, v.Index(i), file)
+			}
+		}
+
+	// Values of maps can't be set, but the elements of slices they hold can.
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode || v.Type().Key().Kind() != reflect.String ||
+			v.Type().Elem().Kind() != reflect.Slice {
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := v.MapIndex(reflect.ValueOf(node.Content[i].Value).Convert(v.Type().Key())); value.IsValid() {
+				setPositions(node.Content[i+1], value, file)
+			}
+		}
+	}
+}
+
+// setKeyPositions sets the XPos field of struct v holding the map field X
+// decoded from node to the positions of the keys of node in file.
+func setKeyPositions(node *yaml.Node, v reflect.Value, field *yamlField, file string) {
+	if node.Kind != yaml.MappingNode || len(field.Index) != 1 {
+		return
+	}
+
+	pos := v.FieldByName(v.Type().Field(field.Index[0]).Name + "Pos")
+	if !pos.IsValid() || pos.Type() != keyPositionType {
+		return
+	}
+
+	positions := make(map[string]diagnostic.Position, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		positions[key.Value] = diagnostic.Position{File: file, Line: key.Line, Column: key.Column}
+	}
+
+	pos.Set(reflect.ValueOf(positions))
+}
+
+// findYAMLField returns the field of key, or nil.
+func findYAMLField(fields []yamlField, key string) *yamlField {
+	for i := range fields {
+		if fields[i].Key == key {
+			return &fields[i]
+		}
+	}
+
+	return nil
+}
+
+// unknownKeyError describes an unknown key of the struct at path at,
+// suggesting the known key closest to it.
+func unknownKeyError(key *yaml.Node, at string, fields []yamlField) error {
+	where := at
+	if where == "" {
+		where = "the top level"
+	}
+
+	var (
+		closest string
+		best    = 3
+	)
+
+	for _, f := range fields {
+		if d := match.Levenshtein(key.Value, f.Key); d < best && d < len(f.Key) {
+			closest, best = f.Key, d
+		}
+	}
+
+	if closest != "" {
+		return fmt.Errorf("line %d, column %d: unknown key %q in %s (did you mean %q?)",
+			key.Line, key.Column, key.Value, where, closest)
+	}
+
+	return fmt.Errorf("line %d, column %d: unknown key %q in %s", key.Line, key.Column, key.Value, where)
+}
+
+// joinKeyPath appends key to the key path at.
+func joinKeyPath(at, key string) string {
+	if at == "" {
+		return key
+	}
+
+	return at + "." + key
+}
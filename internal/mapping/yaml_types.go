@@ -3,7 +3,9 @@ package mapping
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"slices"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 
@@ -231,176 +233,167 @@ func (f FieldRefArray) MarshalYAML() (any, error) {
 
 // --- ExtraVals YAML methods ---
 
-// UnmarshalYAML implements yaml.Unmarshaler for ExtraVals.
-func (e *ExtraVals) UnmarshalYAML(unmarshal func(any) error) error {
-	// Try list of full ExtraVal objects (explicit syntax)
-	var objects []ExtraVal
-	if err := unmarshal(&objects); err == nil {
-		*e = objects
+// UnmarshalYAML implements yaml.Unmarshaler for ExtraVals. Accepts a name, a
+// list of names or of {name, def} and {name, const} objects, or a map of names
+// to sources or to {source}, {target} or {const}, kept in declaration order,
+// the order the extras are passed in.
+func (e *ExtraVals) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*e = []ExtraVal{{Name: node.Value, Def: ExtraDef{Source: node.Value}}}
 		return nil
-	}
 
-	// Try list of strings first
-	var list []string
-	if err := unmarshal(&list); err == nil {
-		result := make([]ExtraVal, len(list))
-		for i, s := range list {
-			result[i] = ExtraVal{Name: s, Def: ExtraDef{Source: s}}
+	case yaml.SequenceNode:
+		result := make([]ExtraVal, 0, len(node.Content))
+
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				result = append(result, ExtraVal{Name: item.Value, Def: ExtraDef{Source: item.Value}})
+				continue
+			}
+
+			var ev ExtraVal
+			if err := item.Decode(&ev); err != nil {
+				return err
+			}
+
+			result = append(result, ev)
 		}
 
 		*e = result
 
 		return nil
-	}
 
-	// Try single string
-	var single string
-	if err := unmarshal(&single); err == nil {
-		*e = []ExtraVal{{Name: single, Def: ExtraDef{Source: single}}}
-		return nil
-	}
+	case yaml.MappingNode:
+		result := make([]ExtraVal, 0, len(node.Content)/2)
 
-	// Try map
-	var m map[string]any
-	if err := unmarshal(&m); err == nil {
-		var result []ExtraVal
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i].Value, node.Content[i+1]
 
-		for k, v := range m {
 			// v can be string (implied source) or object
-			switch val := v.(type) {
-			case string:
-				result = append(result, ExtraVal{Name: k, Def: ExtraDef{Source: val}})
-			case map[string]any:
-				def := ExtraDef{}
-				if src, ok := val["source"].(string); ok {
-					def.Source = src
+			switch v.Kind {
+			case yaml.ScalarNode:
+				result = append(result, ExtraVal{Name: k, Def: ExtraDef{Source: v.Value}})
+			case yaml.MappingNode:
+				var def struct {
+					ExtraDef `yaml:",inline"`
+
+					Const ExtraConst `yaml:"const"`
 				}
 
-				if tgt, ok := val["target"].(string); ok {
-					def.Target = tgt
+				if err := v.Decode(&def); err != nil {
+					return fmt.Errorf("extra %s: %w", k, err)
 				}
 
-				result = append(result, ExtraVal{Name: k, Def: def})
+				result = append(result, ExtraVal{Name: k, Def: def.ExtraDef, Const: def.Const})
 			default:
 				return fmt.Errorf("invalid extra definition for %s", k)
 			}
 		}
-		// Sort for determinism? Use Slice sort later if needed. Map iteration is random.
-		*e = result
-
-		return nil
-	}
-
-	return errors.New("expected string, list of strings, or map for extra (MODIFIED)")
-}
 
-// --- StringArray YAML methods ---
+		*e = result
 
-// UnmarshalYAML implements yaml.Unmarshaler for StringArray.
-func (s *StringArray) UnmarshalYAML(unmarshal func(any) error) error {
-	var single string
-	if err := unmarshal(&single); err == nil {
-		*s = []string{single}
 		return nil
-	}
 
-	var multi []string
-	if err := unmarshal(&multi); err == nil {
-		*s = multi
-		return nil
+	default:
+		return errors.New("expected string, list of strings, or map for extra")
 	}
-
-	return errors.New("expected string or list of strings")
 }
 
-// --- FieldRefOrString YAML methods ---
+// --- ExtraConst YAML methods ---
 
-// UnmarshalYAML implements the yaml.Unmarshaler interface for FieldRefOrString.
-func (f *FieldRefOrString) UnmarshalYAML(unmarshal func(any) error) error {
-	var s string
-	if err := unmarshal(&s); err == nil {
-		f.Path = s
-		f.Hint = HintNone
+// errExtraConst is returned for const extras that aren't scalars.
+var errExtraConst = errors.New("const must be a string, number or bool")
 
-		return nil
+// UnmarshalYAML implements yaml.Unmarshaler for ExtraConst, quoting strings
+// unless they already are Go string literals.
+func (c *ExtraConst) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return errExtraConst
 	}
 
-	var ref FieldRef
-	if err := unmarshal(&ref); err == nil {
-		*f = FieldRefOrString{ref}
-		return nil
-	}
+	switch node.ShortTag() {
+	case "!!str":
+		s, ok := unquoteString(node.Value)
+		if !ok {
+			s = node.Value
+		}
 
-	// Try map for hint structure {Path: Hint}
-	var m map[string]string
-	if err := unmarshal(&m); err == nil && len(m) == 1 {
-		for k, v := range m {
-			f.Path = k
-			f.Hint = IntrospectionHint(v)
+		*c = ExtraConst(strconv.Quote(s))
+	case "!!int", "!!float":
+		*c = ExtraConst(node.Value)
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
 		}
 
-		return nil
+		*c = ExtraConst(strconv.FormatBool(b))
+	default:
+		return errExtraConst
 	}
 
-	return errors.New("expected string or field reference")
+	return nil
 }
 
-// --- ArgDefArray YAML methods ---
+// MarshalYAML implements yaml.Marshaler for ExtraConst.
+func (c ExtraConst) MarshalYAML() (any, error) {
+	return c.Node(), nil
+}
 
-// UnmarshalYAML implements yaml.Unmarshaler for ArgDefArray.
-func (a *ArgDefArray) UnmarshalYAML(unmarshal func(any) error) error {
-	var list []any
-	if err := unmarshal(&list); err != nil {
-		return err
+// Node returns the YAML scalar of the constant: a quoted string, or the
+// literal as-is.
+func (c ExtraConst) Node() *yaml.Node {
+	if s, err := strconv.Unquote(string(c)); err == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: s, Style: yaml.DoubleQuotedStyle}
 	}
 
-	result := make([]ArgDef, 0, len(list))
-	for _, item := range list {
-		switch v := item.(type) {
-		case string:
-			result = append(result, ArgDef{Name: v, Type: "interface{}"})
-		case map[string]any:
-			// Check if this is an explicit object definition (has "name" key)
-			if nameVal, hasName := v["name"]; hasName {
-				name, ok := nameVal.(string)
-				if !ok {
-					return errors.New("invalid argument name, expected string")
-				}
-
-				typeStr := "interface{}"
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(c)}
+}
 
-				if typeVal, hasType := v["type"]; hasType {
-					if ts, ok := typeVal.(string); ok {
-						typeStr = ts
-					} else {
-						return errors.New("invalid argument type, expected string")
-					}
-				}
+// --- IgnoreEntry YAML methods ---
 
-				result = append(result, ArgDef{Name: name, Type: typeStr})
+// ignoreEntryFields is the object layout of an IgnoreEntry.
+type ignoreEntryFields IgnoreEntry
 
-				continue
-			}
+// UnmarshalYAML implements yaml.Unmarshaler for IgnoreEntry. Accepts a target
+// path or an object with the target and its metadata.
+func (e *IgnoreEntry) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*e = IgnoreEntry{Target: node.Value}
+		return nil
 
-			// Fallback to Key-Value definition: { "paramName": "paramType" }
-			if len(v) != 1 {
-				return errors.New("invalid argument definition, expected {name: type} or {name: ..., type: ...}")
-			}
+	case yaml.MappingNode:
+		if errs := unknownKeys(node, reflect.TypeFor[ignoreEntryFields](), "ignore entry"); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
 
-			for k, val := range v {
-				typeStr, ok := val.(string)
-				if !ok {
-					return fmt.Errorf("invalid argument type for %s, expected string", k)
-				}
+		var fields ignoreEntryFields
+		if err := node.Decode(&fields); err != nil {
+			return err
+		}
 
-				result = append(result, ArgDef{Name: k, Type: typeStr})
-			}
-		default:
-			return errors.New("expected string or map for argument definition")
+		if fields.Target == "" {
+			return fmt.Errorf("line %d, column %d: ignore entry needs a target", node.Line, node.Column)
 		}
+
+		*e = IgnoreEntry(fields)
+
+		return nil
+
+	default:
+		return fmt.Errorf("line %d, column %d: expected a target path or an object for ignore entry", node.Line,
+			node.Column)
 	}
+}
 
-	*a = result
+// MarshalYAML implements yaml.Marshaler for IgnoreEntry. Entries without
+// metadata are written as their target path.
+func (e IgnoreEntry) MarshalYAML() (any, error) {
+	if !e.HasMetadata() {
+		return e.Target, nil
+	}
 
-	return nil
+	return ignoreEntryFields(e), nil
 }
@@ -1,7 +1,9 @@
 package match
 
 import (
+	"fmt"
 	"go/types"
+	"math"
 	"sort"
 
 	"caster-generator/internal/analyze"
@@ -13,8 +15,11 @@ type Candidate struct {
 	TargetField *analyze.FieldInfo
 
 	// Scoring components
-	NameScore  float64                 // Normalized Levenshtein similarity (0-1)
-	TypeCompat TypeCompatibilityResult // Type compatibility result
+	NameScore     float64                 // Normalized Levenshtein similarity (0-1)
+	TypeCompat    TypeCompatibilityResult // Type compatibility result
+	PositionScore float64                 // Relative field position similarity (0-1), 0 if disabled
+	TagMatch      bool                    // Source and target share the same value of the match tag
+	Synonym       bool                    // Source and target names differ but are synonyms
 
 	// Combined score for ranking (higher is better)
 	CombinedScore float64
@@ -24,63 +29,187 @@ type Candidate struct {
 	NormalizedTargetName string
 }
 
+// Explain returns a short human-readable breakdown of the candidate's score.
+func (c *Candidate) Explain() string {
+	expl := fmt.Sprintf("name=%.2f, type=%s", c.NameScore, c.TypeCompat.Compatibility)
+	if c.PositionScore > 0 {
+		expl += fmt.Sprintf(", position=%.2f", c.PositionScore)
+	}
+
+	if c.TagMatch {
+		expl += ", tag=match"
+	}
+
+	if c.Synonym {
+		expl += ", synonym"
+	}
+
+	return expl
+}
+
 // CandidateList is a list of candidates with ranking functionality.
 type CandidateList []Candidate
 
+// RankOptions holds optional scoring signals for RankCandidatesWithOptions.
+type RankOptions struct {
+	// PositionWeight is the share (0-1) of the combined score given to relative
+	// field position similarity. Zero disables the positional signal.
+	// Useful for legacy structs whose names diverged but whose field order mirrors the original.
+	PositionWeight float64
+	// TargetFields is the full field list of the target struct, used to locate
+	// the target field's relative position. Required when PositionWeight > 0.
+	TargetFields []analyze.FieldInfo
+	// MatchTag is a struct tag key (e.g. "json" or "db") whose values decide field
+	// equivalence. Candidates with the same tag value as the target are scored as an
+	// exact name match and pinned above all others. Empty disables tag matching.
+	MatchTag string
+	// Compat optionally overrides type compatibility for a source/target pair,
+	// e.g. for conversions the planner knows how to generate. Returning false
+	// falls back to the built-in check.
+	Compat func(source, target *analyze.TypeInfo) (TypeCompatibilityResult, bool)
+	// Unexported also ranks unexported source fields, for casters generated
+	// into the package declaring them.
+	Unexported bool
+	// StripAffixes lists name tokens (e.g. "DTO" or "V1") ignored at the start
+	// or end of field names, see NormalizeIdent.
+	StripAffixes []string
+	// Synonyms maps names to domain-equivalent names (e.g. "Amount" to
+	// ["Price", "Cost", "Total"]). Names equal once synonyms are replaced,
+	// as a whole or token by token, score SynonymNameScore.
+	Synonyms map[string][]string
+	// Abbreviations maps shorthand name tokens to their expansion (e.g.
+	// "Qty" to "Quantity") in addition to DefaultAbbreviations.
+	Abbreviations map[string]string
+	// Scorer replaces the built-in name and type scoring, e.g. to score names
+	// by embeddings. Nil uses the built-in scorer, see NewDefaultScorer.
+	Scorer Scorer
+}
+
 // RankCandidates finds and ranks potential source field matches for a target field.
 // Returns candidates sorted by combined score (descending).
 func RankCandidates(
 	targetField *analyze.FieldInfo,
 	sourceFields []analyze.FieldInfo,
+) CandidateList {
+	return RankCandidatesWithOptions(targetField, sourceFields, RankOptions{})
+}
+
+// RankCandidatesWithOptions is like RankCandidates but allows enabling optional
+// scoring signals such as relative field position.
+func RankCandidatesWithOptions(
+	targetField *analyze.FieldInfo,
+	sourceFields []analyze.FieldInfo,
+	opts RankOptions,
 ) CandidateList {
 	var candidates CandidateList
 
-	targetNorm := NormalizeIdent(targetField.Name)
-	targetNormStripped := NormalizeIdentWithSuffixStrip(targetField.Name)
+	usePosition := opts.PositionWeight > 0 && len(opts.TargetFields) > 0
+	targetPos := relativePosition(targetField.Name, opts.TargetFields)
+
+	normalizer := Normalizer{Affixes: opts.StripAffixes, Abbreviations: opts.Abbreviations}
+	targetNorm := normalizer.Normalize(targetField.Name)
+
+	builtin := newDefaultScorer(normalizer, opts.Synonyms)
+
+	var scorer Scorer = builtin
+	if opts.Scorer != nil {
+		scorer = opts.Scorer
+	}
+
+	var targetTag string
+	if opts.MatchTag != "" {
+		targetTag = targetField.TagName(opts.MatchTag)
+	}
 
 	for i := range sourceFields {
 		sourceField := &sourceFields[i]
 
-		// Skip unexported fields
-		if !sourceField.Exported {
+		// Skip unexported fields, unless allowed
+		if !sourceField.Exported && !opts.Unexported {
 			continue
 		}
 
-		sourceNorm := NormalizeIdent(sourceField.Name)
-		sourceNormStripped := NormalizeIdentWithSuffixStrip(sourceField.Name)
+		sourceNorm := normalizer.Normalize(sourceField.Name)
 
-		// Calculate name similarity (use max of regular and suffix-stripped)
-		nameScore := LevenshteinNormalized(sourceNorm, targetNorm)
-
-		nameScoreStripped := LevenshteinNormalized(sourceNormStripped, targetNormStripped)
+		// Calculate name similarity
+		var (
+			nameScore float64
+			synonym   bool
+		)
 
-		if nameScoreStripped > nameScore {
-			nameScore = nameScoreStripped
+		if opts.Scorer != nil {
+			nameScore = opts.Scorer.NameScore(sourceField, targetField)
+		} else {
+			nameScore, synonym = builtin.nameScore(sourceField.Name, targetField.Name)
 		}
 
 		// Check type compatibility
-		var typeCompat TypeCompatibilityResult
-		if sourceField.Type != nil && sourceField.Type.GoType != nil &&
-			targetField.Type != nil && targetField.Type.GoType != nil {
+		var (
+			typeCompat TypeCompatibilityResult
+			overridden bool
+		)
+
+		if opts.Compat != nil {
+			typeCompat, overridden = opts.Compat(sourceField.Type, targetField.Type)
+		}
+
+		switch {
+		case overridden:
+			// Decided by the caller
+		case sourceField.Type != nil && sourceField.Type.GoType != nil &&
+			targetField.Type != nil && targetField.Type.GoType != nil:
 			typeCompat = ScorePointerCompatibility(
 				sourceField.Type.GoType,
 				targetField.Type.GoType,
 			)
-		} else {
+		default:
 			typeCompat = TypeCompatibilityResult{
 				Compatibility: TypeIncompatible,
 				Reason:        "type information unavailable",
 			}
 		}
 
+		// Floats and decimal amounts never convert implicitly
+		if sourceField.Type != nil && targetField.Type != nil {
+			if reason := MoneyConversion(sourceField.Name, sourceField.Type.GoType, targetField.Name,
+				targetField.Type.GoType); reason != "" {
+				typeCompat = TypeCompatibilityResult{
+					Compatibility: TypeIncompatible,
+					Reason:        reason,
+					SourceType:    typeCompat.SourceType,
+					TargetType:    typeCompat.TargetType,
+				}
+			}
+		}
+
+		// Identical wire names make the fields equivalent regardless of their Go names
+		tagMatch := targetTag != "" && sourceField.TagName(opts.MatchTag) == targetTag
+
 		// Calculate combined score
-		combinedScore := calculateCombinedScore(nameScore, typeCompat.Compatibility)
+		typeScore := scorer.TypeScore(sourceField, targetField, typeCompat)
+
+		combinedScore := scorer.Combine(nameScore, typeScore)
+		if tagMatch {
+			combinedScore = scorer.Combine(1.0, typeScore)
+		}
+
+		var positionScore float64
+
+		if usePosition && targetPos >= 0 {
+			if sourcePos := relativePosition(sourceField.Name, sourceFields); sourcePos >= 0 {
+				positionScore = 1 - math.Abs(sourcePos-targetPos)
+				combinedScore = combinedScore*(1-opts.PositionWeight) + positionScore*opts.PositionWeight
+			}
+		}
 
 		candidates = append(candidates, Candidate{
 			SourceField:          sourceField,
 			TargetField:          targetField,
 			NameScore:            nameScore,
 			TypeCompat:           typeCompat,
+			PositionScore:        positionScore,
+			TagMatch:             tagMatch,
+			Synonym:              synonym,
 			CombinedScore:        combinedScore,
 			NormalizedSourceName: sourceNorm,
 			NormalizedTargetName: targetNorm,
@@ -161,28 +290,63 @@ func RankCandidatesWithTypes(
 //   - Name similarity: 60% (0.0-0.6)
 //   - Type compatibility: 40% (0.0-0.4)
 func calculateCombinedScore(nameScore float64, typeCompat TypeCompatibility) float64 {
+	return combineScores(nameScore, typeCompatScore(typeCompat))
+}
+
+// combineScores weighs a name score and a type score into a combined score.
+func combineScores(nameScore, typeScore float64) float64 {
 	const (
 		nameWeight = 0.6
 		typeWeight = 0.4
 	)
 
-	// Normalize type compatibility to 0-1 range
-	var typeScore float64
+	return nameScore*nameWeight + typeScore*typeWeight
+}
 
+// typeCompatScore normalizes type compatibility to the 0-1 range.
+func typeCompatScore(typeCompat TypeCompatibility) float64 {
 	switch typeCompat {
 	case TypeIdentical:
-		typeScore = 1.0
+		return 1.0
 	case TypeAssignable:
-		typeScore = 0.9
+		return 0.9
 	case TypeConvertible:
-		typeScore = 0.7
+		return 0.7
 	case TypeNeedsTransform:
-		typeScore = 0.4
-	case TypeIncompatible:
-		typeScore = 0.0
+		return 0.4
+	default:
+		return 0.0
 	}
+}
 
-	return nameScore*nameWeight + typeScore*typeWeight
+// relativePosition returns the position (0-1) of the named exported field among
+// the exported fields of a struct, or -1 if the field is not found.
+// A struct with a single exported field places it at 0.
+func relativePosition(name string, fields []analyze.FieldInfo) float64 {
+	pos := -1
+	count := 0
+
+	for i := range fields {
+		if !fields[i].Exported {
+			continue
+		}
+
+		if fields[i].Name == name {
+			pos = count
+		}
+
+		count++
+	}
+
+	if pos < 0 {
+		return -1
+	}
+
+	if count <= 1 {
+		return 0
+	}
+
+	return float64(pos) / float64(count-1)
 }
 
 // Len implements sort.Interface.
@@ -192,8 +356,13 @@ func (c CandidateList) Len() int { return len(c) }
 func (c CandidateList) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 
 // Less implements sort.Interface.
-// Sorts by combined score descending, then by source field name for determinism.
+// Sorts tag matches first, then by combined score descending, then by source
+// field name for determinism.
 func (c CandidateList) Less(i, j int) bool {
+	if c[i].TagMatch != c[j].TagMatch {
+		return c[i].TagMatch
+	}
+
 	// Higher score comes first
 	if c[i].CombinedScore != c[j].CombinedScore {
 		return c[i].CombinedScore > c[j].CombinedScore
@@ -222,7 +391,7 @@ func (c CandidateList) Best() *Candidate {
 
 // IsAmbiguous returns true if the top two candidates are within the threshold.
 func (c CandidateList) IsAmbiguous(threshold float64) bool {
-	if len(c) < 2 {
+	if len(c) < 2 || c.pinned() {
 		return false
 	}
 
@@ -263,8 +432,8 @@ func (c CandidateList) HighConfidence(minScore, minGap float64) *Candidate {
 		return nil
 	}
 
-	// If there's a second candidate, must have sufficient gap
-	if len(c) > 1 {
+	// If there's a second candidate, must have sufficient gap (unless pinned by tag)
+	if len(c) > 1 && !c.pinned() {
 		gap := c[0].CombinedScore - c[1].CombinedScore
 		if gap < minGap {
 			return nil
@@ -274,6 +443,11 @@ func (c CandidateList) HighConfidence(minScore, minGap float64) *Candidate {
 	return best
 }
 
+// pinned reports whether the best candidate is the only one matching by tag.
+func (c CandidateList) pinned() bool {
+	return len(c) > 0 && c[0].TagMatch && (len(c) == 1 || !c[1].TagMatch)
+}
+
 // Confidence thresholds for auto-accepting matches.
 const (
 	// DefaultMinScore is the minimum combined score for auto-acceptance.
@@ -2,6 +2,8 @@ package match
 
 import (
 	"go/types"
+	"reflect"
+	"strings"
 	"testing"
 
 	"caster-generator/internal/analyze"
@@ -321,3 +323,212 @@ func TestRankCandidates_Determinism(t *testing.T) {
 		}
 	}
 }
+
+func TestRankCandidatesWithOptions_Position(t *testing.T) {
+	stringType := types.Typ[types.String]
+	field := func(name string) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: &analyze.TypeInfo{GoType: stringType}}
+	}
+
+	// Legacy struct: names diverged, but field order mirrors the target.
+	sourceFields := []analyze.FieldInfo{field("Alpha"), field("Beta"), field("Gamma")}
+	targetFields := []analyze.FieldInfo{field("First"), field("Second"), field("Third")}
+
+	withoutPos := RankCandidates(&targetFields[2], sourceFields)
+	for _, c := range withoutPos {
+		if c.PositionScore != 0 {
+			t.Errorf("PositionScore should be 0 when disabled, got %f for %s", c.PositionScore, c.SourceField.Name)
+		}
+	}
+
+	withPos := RankCandidatesWithOptions(&targetFields[2], sourceFields, RankOptions{
+		PositionWeight: 0.5,
+		TargetFields:   targetFields,
+	})
+
+	best := withPos.Best()
+	if best == nil || best.SourceField.Name != "Gamma" {
+		t.Fatalf("Expected Gamma to rank first by position, got %+v", best)
+	}
+
+	if best.PositionScore != 1.0 {
+		t.Errorf("Expected PositionScore 1.0 for same relative position, got %f", best.PositionScore)
+	}
+
+	if !strings.Contains(best.Explain(), "position=1.00") {
+		t.Errorf("Explain() should mention position, got %q", best.Explain())
+	}
+}
+
+func TestRelativePosition(t *testing.T) {
+	fields := []analyze.FieldInfo{
+		{Name: "A", Exported: true},
+		{Name: "hidden", Exported: false},
+		{Name: "B", Exported: true},
+		{Name: "C", Exported: true},
+	}
+
+	tests := []struct {
+		name     string
+		expected float64
+	}{
+		{"A", 0},
+		{"B", 0.5},
+		{"C", 1},
+		{"hidden", -1},
+		{"Missing", -1},
+	}
+
+	for _, tt := range tests {
+		if got := relativePosition(tt.name, fields); got != tt.expected {
+			t.Errorf("relativePosition(%q) = %f, want %f", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRankCandidatesWithOptions_MatchTag(t *testing.T) {
+	stringType := &analyze.TypeInfo{GoType: types.Typ[types.String]}
+	field := func(name, tag string) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: stringType, Tag: reflect.StructTag(tag)}
+	}
+
+	// DTO field names differ from the domain, but the wire names agree.
+	sourceFields := []analyze.FieldInfo{
+		field("Identifier", `json:"user_id"`),
+		field("UserName", `json:"login"`),
+		field("UserID", `json:"legacy_id"`),
+	}
+	target := field("UserID", `json:"user_id,omitempty"`)
+
+	withoutTag := RankCandidates(&target, sourceFields)
+	if best := withoutTag.Best(); best == nil || best.SourceField.Name != "UserID" {
+		t.Fatalf("Expected UserID to win on name without tag matching, got %+v", best)
+	}
+
+	withTag := RankCandidatesWithOptions(&target, sourceFields, RankOptions{MatchTag: "json"})
+
+	best := withTag.HighConfidence(DefaultMinScore, DefaultMinGap)
+	if best == nil || best.SourceField.Name != "Identifier" {
+		t.Fatalf("Expected Identifier to be pinned by json tag, got %+v", withTag.Best())
+	}
+
+	if !best.TagMatch || !strings.Contains(best.Explain(), "tag=match") {
+		t.Errorf("Expected tag match to be reported, got %q", best.Explain())
+	}
+
+	if withTag.IsAmbiguous(DefaultAmbiguityThreshold) {
+		t.Error("A single tag match should not be ambiguous")
+	}
+}
+
+func TestRankCandidatesWithOptions_StripAffixes(t *testing.T) {
+	stringType := &analyze.TypeInfo{GoType: types.Typ[types.String]}
+	field := func(name string) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: stringType}
+	}
+
+	sourceFields := []analyze.FieldInfo{field("CustomerDTO"), field("CustomerName")}
+	target := field("Customer")
+
+	if best := RankCandidates(&target, sourceFields).HighConfidence(DefaultMinScore, DefaultMinGap); best != nil {
+		t.Fatalf("Expected no confident match without affixes, got %s", best.SourceField.Name)
+	}
+
+	ranked := RankCandidatesWithOptions(&target, sourceFields, RankOptions{StripAffixes: []string{"DTO"}})
+
+	best := ranked.HighConfidence(DefaultMinScore, DefaultMinGap)
+	if best == nil || best.SourceField.Name != "CustomerDTO" || best.NameScore != 1 {
+		t.Fatalf("Expected CustomerDTO to match exactly with DTO stripped, got %+v", ranked.Best())
+	}
+}
+
+func TestRankCandidatesWithOptions_Synonyms(t *testing.T) {
+	stringType := &analyze.TypeInfo{GoType: types.Typ[types.String]}
+	field := func(name string) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: stringType}
+	}
+
+	opts := RankOptions{Synonyms: map[string][]string{"Amount": {"Price", "Cost", "Total"}}}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"Amount", "Price"},
+		{"UnitAmount", "UnitCost"},
+		{"Cost", "Price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			target := field(tt.target)
+			ranked := RankCandidatesWithOptions(&target, []analyze.FieldInfo{field(tt.want), field("Name")}, opts)
+
+			best := ranked.HighConfidence(DefaultMinScore, DefaultMinGap)
+			if best == nil || best.SourceField.Name != tt.want {
+				t.Fatalf("Expected %s to match %s as a synonym, got %+v", tt.target, tt.want, ranked.Best())
+			}
+
+			if best.NameScore != SynonymNameScore || !strings.Contains(best.Explain(), "synonym") {
+				t.Errorf("Expected a synonym name score, got %q", best.Explain())
+			}
+		})
+	}
+
+	// An identical name still ranks above a synonym.
+	target := field("Amount")
+	ranked := RankCandidatesWithOptions(&target, []analyze.FieldInfo{field("Price"), field("Amount")}, opts)
+
+	if best := ranked.Best(); best.SourceField.Name != "Amount" || best.Synonym {
+		t.Errorf("Expected the identical name to win, got %+v", best)
+	}
+}
+
+// glossaryScorer scores names by a project glossary, falling back to the
+// built-in scorer.
+type glossaryScorer struct {
+	Scorer
+
+	glossary map[string]string
+}
+
+func (s glossaryScorer) NameScore(source, target *analyze.FieldInfo) float64 {
+	if s.glossary[target.Name] == source.Name {
+		return 1
+	}
+
+	return s.Scorer.NameScore(source, target)
+}
+
+func TestRankCandidatesWithOptions_Scorer(t *testing.T) {
+	stringType := &analyze.TypeInfo{GoType: types.Typ[types.String]}
+	field := func(name string) analyze.FieldInfo {
+		return analyze.FieldInfo{Name: name, Exported: true, Type: stringType}
+	}
+
+	sourceFields := []analyze.FieldInfo{field("Sku"), field("Status")}
+	target := field("ArticleNumber")
+
+	scorer := glossaryScorer{
+		Scorer:   NewDefaultScorer(Normalizer{}, nil),
+		glossary: map[string]string{"ArticleNumber": "Sku"},
+	}
+
+	ranked := RankCandidatesWithOptions(&target, sourceFields, RankOptions{Scorer: scorer})
+
+	best := ranked.HighConfidence(DefaultMinScore, DefaultMinGap)
+	if best == nil || best.SourceField.Name != "Sku" || best.NameScore != 1 {
+		t.Fatalf("Expected Sku to be matched by the glossary, got %+v", ranked.Best())
+	}
+
+	// Other names are scored by the built-in scorer through the fallback.
+	scores := make(map[string]float64)
+	for _, c := range RankCandidates(&target, sourceFields) {
+		scores[c.SourceField.Name] = c.CombinedScore
+	}
+
+	if got := ranked[1]; got.CombinedScore != scores[got.SourceField.Name] {
+		t.Errorf("Expected %s to score %.2f as built-in, got %.2f",
+			got.SourceField.Name, scores[got.SourceField.Name], got.CombinedScore)
+	}
+}
@@ -1,6 +1,7 @@
 package match
 
 import (
+	"fmt"
 	"go/types"
 )
 
@@ -57,13 +58,27 @@ type TypeCompatibilityResult struct {
 	Reason        string // Human-readable explanation
 	SourceType    string // String representation of source type
 	TargetType    string // String representation of target type
+	// Warning flags a conversion that compiles but may hide a semantic mismatch
+	// (e.g., a string enum converted to plain string). Empty when there is none.
+	Warning string
 }
 
+// ReasonStringEnumConversion is the reason reported for conversions between
+// defined string types (enums) and plain strings or other string enums.
+const ReasonStringEnumConversion = "string enum conversion"
+
+// ReasonDefinedTypeConversion is the reason reported for conversions between
+// a defined type and its underlying type or another defined type over it
+// (e.g., type UserID int64 and int64).
+const ReasonDefinedTypeConversion = "defined type conversion"
+
 // ScoreTypeCompatibility determines the compatibility between a source and target type.
-// Uses go/types for accurate type analysis.
+// Uses go/types for accurate type analysis. Aliases are scored as their
+// canonical type.
 func ScoreTypeCompatibility(source, target types.Type) TypeCompatibilityResult {
 	sourceStr := source.String()
 	targetStr := target.String()
+	source, target = types.Unalias(source), types.Unalias(target)
 
 	// Check for identical types
 	if types.Identical(source, target) {
@@ -87,9 +102,24 @@ func ScoreTypeCompatibility(source, target types.Type) TypeCompatibilityResult {
 
 	// Check for convertibility (numeric conversions, string/[]byte, etc.)
 	if types.ConvertibleTo(source, target) {
+		if warning := stringEnumWarning(source, target); warning != "" {
+			return TypeCompatibilityResult{
+				Compatibility: TypeConvertible,
+				Reason:        ReasonStringEnumConversion,
+				SourceType:    sourceStr,
+				TargetType:    targetStr,
+				Warning:       warning,
+			}
+		}
+
+		reason := "source is convertible to target"
+		if IsDefinedTypeConversion(source, target) {
+			reason = ReasonDefinedTypeConversion
+		}
+
 		return TypeCompatibilityResult{
 			Compatibility: TypeConvertible,
-			Reason:        "source is convertible to target",
+			Reason:        reason,
 			SourceType:    sourceStr,
 			TargetType:    targetStr,
 		}
@@ -141,6 +171,17 @@ func needsTransform(source, target types.Type) bool {
 		}
 	}
 
+	// Pointer to pointer of structs (e.g., *Node -> *NodeDTO, the usual field of
+	// recursive types)
+	if sourceIsPtr && targetIsPtr {
+		_, sourceElemIsStruct := sourcePtr.Elem().Underlying().(*types.Struct)
+		_, targetElemIsStruct := targetPtr.Elem().Underlying().(*types.Struct)
+
+		if sourceElemIsStruct && targetElemIsStruct {
+			return true
+		}
+	}
+
 	// Slice to slice with different element types
 	sourceSlice, sourceIsSlice := sourceUnderlying.(*types.Slice)
 	targetSlice, targetIsSlice := targetUnderlying.(*types.Slice)
@@ -172,6 +213,64 @@ func needsTransform(source, target types.Type) bool {
 	return false
 }
 
+// stringEnumWarning returns a warning when source and target are both string-based
+// and at least one of them is a defined (named) string type, i.e. a string enum.
+// Plain Go conversion compiles for such pairs, but values are copied verbatim,
+// so renamed or reworded enum constants silently produce invalid values.
+func stringEnumWarning(source, target types.Type) string {
+	srcEnum := IsStringEnumType(source)
+	tgtEnum := IsStringEnumType(target)
+
+	if !IsStringType(source) || !IsStringType(target) || (!srcEnum && !tgtEnum) {
+		return ""
+	}
+
+	if srcEnum && tgtEnum {
+		return fmt.Sprintf("%s and %s are distinct string enums; values are copied verbatim, "+
+			"consider an explicit enum value mapping", source, target)
+	}
+
+	return fmt.Sprintf("%s -> %s converts between a string enum and a plain string; "+
+		"values are not validated, consider an explicit enum value mapping", source, target)
+}
+
+// IsStringEnumType returns true if the type is a defined type whose underlying
+// type is string (e.g., type Status string), or an alias of one.
+func IsStringEnumType(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+
+	return IsStringType(named)
+}
+
+// IsDefinedTypeConversion reports whether source and target are distinct types
+// over identical underlying types other than structs, at least one of them a
+// defined type (e.g., type UserID int64 and int64, or UserID and type
+// AccountID int64), so a conversion like warehouse.UserID(in.ID) converts
+// between them. Pointers are looked through on both sides.
+func IsDefinedTypeConversion(source, target types.Type) bool {
+	srcPtr, srcIsPtr := types.Unalias(source).(*types.Pointer)
+	tgtPtr, tgtIsPtr := types.Unalias(target).(*types.Pointer)
+
+	if srcIsPtr {
+		source = srcPtr.Elem()
+	}
+
+	if tgtIsPtr {
+		target = tgtPtr.Elem()
+	}
+
+	_, srcNamed := types.Unalias(source).(*types.Named)
+	_, tgtNamed := types.Unalias(target).(*types.Named)
+
+	_, isStruct := source.Underlying().(*types.Struct)
+
+	return (srcNamed || tgtNamed) && !isStruct && !types.Identical(source, target) &&
+		types.Identical(source.Underlying(), target.Underlying())
+}
+
 // ScorePointerCompatibility checks compatibility considering pointer wrapping/unwrapping.
 func ScorePointerCompatibility(source, target types.Type) TypeCompatibilityResult {
 	result := ScoreTypeCompatibility(source, target)
@@ -180,7 +279,7 @@ func ScorePointerCompatibility(source, target types.Type) TypeCompatibilityResul
 	}
 
 	// Try unwrapping source pointer
-	if ptr, ok := source.(*types.Pointer); ok {
+	if ptr, ok := types.Unalias(source).(*types.Pointer); ok {
 		innerResult := ScoreTypeCompatibility(ptr.Elem(), target)
 		if innerResult.Compatibility >= TypeConvertible {
 			return TypeCompatibilityResult{
@@ -193,7 +292,7 @@ func ScorePointerCompatibility(source, target types.Type) TypeCompatibilityResul
 	}
 
 	// Try wrapping source as pointer
-	if ptr, ok := target.(*types.Pointer); ok {
+	if ptr, ok := types.Unalias(target).(*types.Pointer); ok {
 		innerResult := ScoreTypeCompatibility(source, ptr.Elem())
 		if innerResult.Compatibility >= TypeConvertible {
 			return TypeCompatibilityResult{
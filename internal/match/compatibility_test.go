@@ -3,6 +3,8 @@ package match
 import (
 	"go/types"
 	"testing"
+
+	"caster-generator/internal/common"
 )
 
 func TestTypeCompatibility_String(t *testing.T) {
@@ -123,6 +125,11 @@ func TestScoreTypeCompatibility_Pointers(t *testing.T) {
 	intType := types.Typ[types.Int]
 	ptrIntType := types.NewPointer(intType)
 	ptrPtrIntType := types.NewPointer(ptrIntType)
+	structType := func(name, field string) types.Type {
+		fields := []*types.Var{types.NewField(0, nil, field, intType, false)}
+
+		return types.NewNamed(types.NewTypeName(0, nil, name, nil), types.NewStruct(fields, nil), nil)
+	}
 
 	tests := []struct {
 		name     string
@@ -154,6 +161,12 @@ func TestScoreTypeCompatibility_Pointers(t *testing.T) {
 			target:   ptrIntType,
 			expected: TypeIncompatible,
 		},
+		{
+			name:     "*struct to *struct needs transform",
+			source:   types.NewPointer(structType("Node", "Value")),
+			target:   types.NewPointer(structType("NodeDTO", "Val")),
+			expected: TypeNeedsTransform,
+		},
 	}
 
 	for _, tt := range tests {
@@ -289,3 +302,212 @@ func TestIsStringType(t *testing.T) {
 		})
 	}
 }
+
+func newStringEnum(pkgPath, name string) types.Type {
+	pkg := types.NewPackage(pkgPath, common.PkgAlias(pkgPath))
+	obj := types.NewTypeName(0, pkg, name, nil)
+
+	return types.NewNamed(obj, types.Typ[types.String], nil)
+}
+
+func TestScoreTypeCompatibility_StringEnums(t *testing.T) {
+	stringType := types.Typ[types.String]
+	srcStatus := newStringEnum("example/store", "Status")
+	tgtStatus := newStringEnum("example/warehouse", "Status")
+
+	tests := []struct {
+		name        string
+		source      types.Type
+		target      types.Type
+		expected    TypeCompatibility
+		wantWarning bool
+	}{
+		{"enum to string", srcStatus, stringType, TypeConvertible, true},
+		{"string to enum", stringType, tgtStatus, TypeConvertible, true},
+		{"enum to other enum", srcStatus, tgtStatus, TypeConvertible, true},
+		{"enum to same enum", srcStatus, srcStatus, TypeIdentical, false},
+		{"string to string", stringType, stringType, TypeIdentical, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ScoreTypeCompatibility(tt.source, tt.target)
+			if result.Compatibility != tt.expected {
+				t.Errorf("ScoreTypeCompatibility() = %v, want %v", result.Compatibility, tt.expected)
+			}
+
+			if got := result.Warning != ""; got != tt.wantWarning {
+				t.Errorf("warning = %q, want present=%v", result.Warning, tt.wantWarning)
+			}
+
+			if tt.wantWarning && result.Reason != ReasonStringEnumConversion {
+				t.Errorf("reason = %q, want %q", result.Reason, ReasonStringEnumConversion)
+			}
+		})
+	}
+}
+
+func TestIsStringEnumType(t *testing.T) {
+	if !IsStringEnumType(newStringEnum("example/store", "Status")) {
+		t.Error("named string type should be a string enum")
+	}
+
+	if IsStringEnumType(types.Typ[types.String]) {
+		t.Error("plain string should not be a string enum")
+	}
+}
+
+func TestScoreTypeCompatibility_DefinedTypes(t *testing.T) {
+	int64Type := types.Typ[types.Int64]
+	named := func(pkgPath, name string, underlying types.Type) types.Type {
+		pkg := types.NewPackage(pkgPath, common.PkgAlias(pkgPath))
+		return types.NewNamed(types.NewTypeName(0, pkg, name, nil), underlying, nil)
+	}
+	userID := named("example/warehouse", "UserID", int64Type)
+	accountID := named("example/store", "AccountID", int64Type)
+	order := named("example/store", "Order", types.NewStruct(nil, nil))
+	orderDTO := named("example/warehouse", "OrderDTO", types.NewStruct(nil, nil))
+
+	tests := []struct {
+		name    string
+		source  types.Type
+		target  types.Type
+		defined bool
+	}{
+		{"underlying to defined", int64Type, userID, true},
+		{"defined to underlying", userID, int64Type, true},
+		{"defined to other defined", accountID, userID, true},
+		{"pointers", types.NewPointer(int64Type), types.NewPointer(userID), true},
+		{"pointer to defined", types.NewPointer(userID), int64Type, true},
+		{"same defined type", userID, userID, false},
+		{"basic conversion", types.Typ[types.Int32], int64Type, false},
+		{"other underlying", types.Typ[types.Int32], userID, false},
+		{"structs", order, orderDTO, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDefinedTypeConversion(tt.source, tt.target); got != tt.defined {
+				t.Errorf("IsDefinedTypeConversion() = %v, want %v", got, tt.defined)
+			}
+		})
+	}
+
+	result := ScoreTypeCompatibility(int64Type, userID)
+	if result.Compatibility != TypeConvertible || result.Reason != ReasonDefinedTypeConversion {
+		t.Errorf("ScoreTypeCompatibility() = %v (%s), want %v (%s)",
+			result.Compatibility, result.Reason, TypeConvertible, ReasonDefinedTypeConversion)
+	}
+}
+
+func TestScoreTypeCompatibility_AliasChains(t *testing.T) {
+	pkg := types.NewPackage("example/store", "store")
+	alias := func(name string, rhs types.Type) types.Type {
+		return types.NewAlias(types.NewTypeName(0, pkg, name, nil), rhs)
+	}
+	status := newStringEnum("example/store", "Status")
+	statusAlias := alias("StatusAlias", alias("StatusAlias2", status))
+	intPtr := alias("IntPtr", types.NewPointer(types.Typ[types.Int]))
+
+	if result := ScoreTypeCompatibility(statusAlias, status); result.Compatibility != TypeIdentical {
+		t.Errorf("alias chain to its type = %v, want %v", result.Compatibility, TypeIdentical)
+	}
+
+	if !IsStringEnumType(statusAlias) {
+		t.Error("alias of a string enum should be a string enum")
+	}
+
+	result := ScoreTypeCompatibility(statusAlias, types.Typ[types.String])
+	if result.Compatibility != TypeConvertible || result.Reason != ReasonStringEnumConversion {
+		t.Errorf("alias of enum to string = %v (%s), want %v (%s)",
+			result.Compatibility, result.Reason, TypeConvertible, ReasonStringEnumConversion)
+	}
+
+	if result.SourceType != "example/store.StatusAlias" {
+		t.Errorf("SourceType = %q, want the alias name", result.SourceType)
+	}
+
+	result = ScorePointerCompatibility(intPtr, types.Typ[types.Int])
+	if result.Compatibility != TypeNeedsTransform || result.Reason != "requires pointer dereference" {
+		t.Errorf("alias of *int to int = %v (%s), want pointer dereference", result.Compatibility, result.Reason)
+	}
+}
+
+func TestLossyConversion(t *testing.T) {
+	typ := func(kind types.BasicKind) types.Type { return types.Typ[kind] }
+
+	tests := []struct {
+		source, target types.BasicKind
+		lossy          bool
+	}{
+		{types.Int32, types.Int64, false},
+		{types.Int64, types.Int32, true},
+		{types.Int, types.Int64, false},
+		{types.Uint32, types.Int64, false},
+		{types.Uint64, types.Int64, true},
+		{types.Int, types.Uint, true},
+		{types.Uint8, types.Uint16, false},
+		{types.Float32, types.Float64, false},
+		{types.Float64, types.Float32, true},
+		{types.Float64, types.Int64, true},
+		{types.Int16, types.Float32, false},
+		{types.Int32, types.Float32, true},
+		{types.Int32, types.Float64, false},
+		{types.Int64, types.Float64, true},
+		{types.Float64, types.Complex128, false},
+		{types.Complex128, types.Complex64, true},
+		{types.Complex64, types.Float64, true},
+		{types.String, types.Int8, false},
+	}
+
+	for _, tt := range tests {
+		source, target := typ(tt.source), typ(tt.target)
+		t.Run(source.String()+"->"+target.String(), func(t *testing.T) {
+			if got := LossyConversion(source, target); got != tt.lossy {
+				t.Errorf("LossyConversion() = %v, want %v", got, tt.lossy)
+			}
+		})
+	}
+
+	if !LossyConversion(types.NewPointer(typ(types.Int64)), typ(types.Int8)) {
+		t.Error("pointers should be looked through")
+	}
+}
+
+func TestMoneyConversion(t *testing.T) {
+	named := func(path, name string) types.Type {
+		obj := types.NewTypeName(0, types.NewPackage(path, name), name, nil)
+		return types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+	}
+
+	decimal := named("github.com/shopspring/decimal", "Decimal")
+	rat := types.NewPointer(named("math/big", "Rat"))
+	float, cents := types.Typ[types.Float64], types.Typ[types.Int64]
+
+	tests := []struct {
+		name                   string
+		sourceName, targetName string
+		source, target         types.Type
+		want                   string
+	}{
+		{"float to cents", "Price", "PriceCents", float, cents, "float to integer cents needs an explicit transform"},
+		{"cents to float", "TotalCents", "Total", cents, float, "integer cents to float needs an explicit transform"},
+		{"float to decimal", "Price", "Price", float, decimal, "float to decimal.Decimal needs an explicit transform"},
+		{"rat to float", "Ratio", "Ratio", rat, types.NewPointer(float), "big.Rat to float needs an explicit transform"},
+		{"integer to float", "Count", "Count", cents, float, ""},
+		{"cents to cents", "TotalCents", "TotalCents", cents, cents, ""},
+		{"float to float", "Price", "Price", float, float, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MoneyConversion(tt.sourceName, tt.source, tt.targetName, tt.target); got != tt.want {
+				t.Errorf("MoneyConversion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := DecimalKindOf("Ratio", rat); got != DecimalRat {
+		t.Errorf("DecimalKindOf(*big.Rat) = %v, want %v", got, DecimalRat)
+	}
+}
@@ -6,4 +6,5 @@
 //   - Levenshtein: computes edit distance between strings
 //   - ScoreTypeCompatibility: scores type compatibility using go/types
 //   - RankCandidates: ranks potential field mappings
+//   - Scorer: pluggable name and type scoring of candidates
 package match
@@ -0,0 +1,80 @@
+package match
+
+import (
+	"go/types"
+)
+
+// sizes are the sizes of basic types lossy conversions are judged by: int,
+// uint and uintptr count as 64 bits, their size on 64-bit platforms.
+var sizes = types.SizesFor("gc", "amd64")
+
+// LossyConversion reports whether converting a value of type source to target
+// may lose information: a numeric conversion to a type that can't represent
+// every source value, such as int64 to int32, float64 to int, int to uint or
+// int64 to float64. Pointers are looked through.
+func LossyConversion(source, target types.Type) bool {
+	src, tgt := numericBasic(source), numericBasic(target)
+	if src == nil || tgt == nil {
+		return false
+	}
+
+	srcInfo, tgtInfo := src.Info(), tgt.Info()
+	srcBits, tgtBits := basicBits(src), basicBits(tgt)
+
+	switch {
+	case srcInfo&types.IsComplex != 0:
+		return tgtInfo&types.IsComplex == 0 || tgtBits < srcBits
+	case srcInfo&types.IsFloat != 0:
+		if tgtInfo&types.IsComplex != 0 {
+			return tgtBits/2 < srcBits
+		}
+
+		return tgtInfo&types.IsFloat == 0 || tgtBits < srcBits
+	}
+
+	// Integers: compare the bits holding their magnitude.
+	valueBits := srcBits
+	if srcInfo&types.IsUnsigned == 0 {
+		valueBits--
+	}
+
+	switch {
+	case tgtInfo&types.IsComplex != 0:
+		return valueBits > mantissaBits(tgtBits/2)
+	case tgtInfo&types.IsFloat != 0:
+		return valueBits > mantissaBits(tgtBits)
+	case tgtInfo&types.IsUnsigned != 0:
+		return srcInfo&types.IsUnsigned == 0 || tgtBits < srcBits
+	default:
+		return tgtBits-1 < valueBits
+	}
+}
+
+// numericBasic returns the numeric basic underlying type of t, or of the type
+// t points to, or nil.
+func numericBasic(t types.Type) *types.Basic {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsNumeric == 0 || basic.Info()&types.IsUntyped != 0 {
+		return nil
+	}
+
+	return basic
+}
+
+// basicBits returns the size of b in bits.
+func basicBits(b *types.Basic) int64 {
+	return sizes.Sizeof(b) * 8
+}
+
+// mantissaBits returns the bits of integer precision of a float of size bits.
+func mantissaBits(bits int64) int64 {
+	if bits == 32 {
+		return 24
+	}
+
+	return 53
+}
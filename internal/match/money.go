@@ -0,0 +1,93 @@
+package match
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// DecimalKind classifies the exact representations of decimal amounts, such
+// as money, which float types can't hold exactly.
+type DecimalKind int
+
+const (
+	// NotDecimal is any other type.
+	NotDecimal DecimalKind = iota
+	// DecimalShopspring is github.com/shopspring/decimal.Decimal.
+	DecimalShopspring
+	// DecimalRat is math/big.Rat.
+	DecimalRat
+	// DecimalCents is an integer counting minor units, told by its field name
+	// (e.g., PriceCents).
+	DecimalCents
+)
+
+// String returns a human-readable name of the decimal kind.
+func (k DecimalKind) String() string {
+	switch k {
+	case DecimalShopspring:
+		return "decimal.Decimal"
+	case DecimalRat:
+		return "big.Rat"
+	case DecimalCents:
+		return "integer cents"
+	default:
+		return "not a decimal"
+	}
+}
+
+// DecimalKindOf returns the decimal kind of a field of type t named name.
+// Pointers are looked through.
+func DecimalKindOf(name string, t types.Type) DecimalKind {
+	if t == nil {
+		return NotDecimal
+	}
+
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	if named, ok := types.Unalias(t).(*types.Named); ok && named.Obj().Pkg() != nil {
+		switch path := named.Obj().Pkg().Path(); {
+		case path == "github.com/shopspring/decimal" && named.Obj().Name() == "Decimal":
+			return DecimalShopspring
+		case path == "math/big" && named.Obj().Name() == "Rat":
+			return DecimalRat
+		}
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if ok && basic.Info()&types.IsInteger != 0 && strings.HasSuffix(strings.ToLower(name), "cents") {
+		return DecimalCents
+	}
+
+	return NotDecimal
+}
+
+// MoneyConversion returns why converting the source field to the target field
+// needs an explicit transform, or "" if it doesn't: one is a float and the
+// other a decimal amount, which a Go conversion would silently round or scale
+// wrongly (e.g., float64 dollars to int64 cents).
+func MoneyConversion(sourceName string, source types.Type, targetName string, target types.Type) string {
+	if source == nil || target == nil {
+		return ""
+	}
+
+	srcKind, tgtKind := DecimalKindOf(sourceName, source), DecimalKindOf(targetName, target)
+
+	switch {
+	case isFloat(source) && tgtKind != NotDecimal:
+		return fmt.Sprintf("float to %s needs an explicit transform", tgtKind)
+	case srcKind != NotDecimal && isFloat(target):
+		return fmt.Sprintf("%s to float needs an explicit transform", srcKind)
+	default:
+		return ""
+	}
+}
+
+// isFloat reports whether t, or the type t points to, has an underlying float
+// type.
+func isFloat(t types.Type) bool {
+	basic := numericBasic(t)
+	return basic != nil && basic.Info()&types.IsFloat != 0
+}
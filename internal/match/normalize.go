@@ -5,15 +5,56 @@ import (
 	"unicode"
 )
 
+// DefaultAbbreviations maps common shorthand name tokens, lowercase, to their
+// expansion. NormalizeIdent expands them so that Qty matches Quantity.
+var DefaultAbbreviations = map[string]string{
+	"addr": "address",
+	"amt":  "amount",
+	"cnt":  "count",
+	"cust": "customer",
+	"desc": "description",
+	"msg":  "message",
+	"nbr":  "number",
+	"num":  "number",
+	"pct":  "percent",
+	"qty":  "quantity",
+}
+
+// Normalizer normalizes identifiers for fuzzy matching with configured affixes
+// and abbreviations. The zero value normalizes as NormalizeIdent without affixes.
+type Normalizer struct {
+	// Affixes are tokens stripped at the start or end of names (e.g., "DTO").
+	Affixes []string
+	// Abbreviations maps shorthand tokens to their expansion (e.g., "Qty" to
+	// "Quantity"), in addition to and overriding DefaultAbbreviations. Keys
+	// match tokens ignoring case; mapping a token to itself disables a default.
+	Abbreviations map[string]string
+}
+
 // NormalizeIdent normalizes an identifier for fuzzy matching.
 // The normalization pipeline:
 // 1. Case-fold to lower.
 // 2. Strip separators (_, -, spaces).
 // 3. Tokenize CamelCase.
-// 4. Optionally strip common suffix/prefix tokens.
-func NormalizeIdent(s string) string {
+// 4. Strip a leading and a trailing token equal to one of affixes, ignoring
+// case, unless it is the only token (e.g., "OrderDTO" with "DTO" -> "order").
+// 5. Expand abbreviated tokens (e.g., "OrderQty" -> "orderquantity").
+func NormalizeIdent(s string, affixes ...string) string {
+	return Normalizer{Affixes: affixes}.Normalize(s)
+}
+
+// NormalizeIdentWithSuffixStrip normalizes and strips common suffixes/prefixes.
+// Common tokens to strip: id, ids, at, utc, timestamp.
+// Note: We avoid stripping short suffixes like "ts" as they're too aggressive.
+// Affixes are stripped first, as by NormalizeIdent.
+func NormalizeIdentWithSuffixStrip(s string, affixes ...string) string {
+	return Normalizer{Affixes: affixes}.NormalizeWithSuffixStrip(s)
+}
+
+// Normalize is NormalizeIdent with the affixes and abbreviations of n.
+func (n Normalizer) Normalize(s string) string {
 	// First expand CamelCase before lowercasing
-	tokens := tokenizeCamelCase(s)
+	tokens := n.tokens(s)
 
 	// Join, lowercase, and strip separators
 	joined := strings.Join(tokens, "")
@@ -23,11 +64,10 @@ func NormalizeIdent(s string) string {
 	return joined
 }
 
-// NormalizeIdentWithSuffixStrip normalizes and strips common suffixes/prefixes.
-// Common tokens to strip: id, ids, at, utc, timestamp.
-// Note: We avoid stripping short suffixes like "ts" as they're too aggressive.
-func NormalizeIdentWithSuffixStrip(s string) string {
-	normalized := NormalizeIdent(s)
+// NormalizeWithSuffixStrip is NormalizeIdentWithSuffixStrip with the affixes
+// and abbreviations of n.
+func (n Normalizer) NormalizeWithSuffixStrip(s string) string {
+	normalized := n.Normalize(s)
 
 	// Strip common suffixes (ordered from longer to shorter to avoid partial matches)
 	suffixes := []string{"timestamp", "ids", "utc", "id", "at"}
@@ -42,6 +82,56 @@ func NormalizeIdentWithSuffixStrip(s string) string {
 	return normalized
 }
 
+// tokens returns the lowercase tokens of s without affixes, with abbreviated
+// tokens expanded.
+func (n Normalizer) tokens(s string) []string {
+	tokens := stripAffixes(tokenizeCamelCase(s), n.Affixes)
+	for i, token := range tokens {
+		tokens[i] = strings.ToLower(n.expand(token))
+	}
+
+	return tokens
+}
+
+// expand returns the expansion of an abbreviated token, or token itself.
+func (n Normalizer) expand(token string) string {
+	for abbr, expansion := range n.Abbreviations {
+		if strings.EqualFold(token, abbr) {
+			return stripSeparators(expansion)
+		}
+	}
+
+	if expansion, ok := DefaultAbbreviations[strings.ToLower(token)]; ok {
+		return expansion
+	}
+
+	return token
+}
+
+// stripAffixes drops the first and the last of tokens if they equal one of
+// affixes, ignoring case, keeping at least one token.
+func stripAffixes(tokens, affixes []string) []string {
+	isAffix := func(token string) bool {
+		for _, affix := range affixes {
+			if strings.EqualFold(token, affix) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(tokens) > 1 && isAffix(tokens[0]) {
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) > 1 && isAffix(tokens[len(tokens)-1]) {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	return tokens
+}
+
 // tokenizeCamelCase splits a CamelCase or camelCase string into tokens.
 // Examples:
 //   - "OrderID" -> ["Order", "ID"]
@@ -96,6 +96,72 @@ func TestNormalizeIdentWithSuffixStrip(t *testing.T) {
 	}
 }
 
+func TestNormalizeIdent_Affixes(t *testing.T) {
+	affixes := []string{"DTO", "Model", "Db", "V1"}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"OrderDTO", "order"},
+		{"DbOrder", "order"},
+		{"order_model", "order"},
+		{"OrderV1", "order"},
+		{"DbOrderDTO", "order"},
+
+		// Only whole tokens are stripped
+		{"OrderDTOs", "orderdtos"},
+		{"Dbase", "dbase"},
+
+		// Should not strip if result would be empty
+		{"DTO", "dto"},
+		{"DbModel", "model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := NormalizeIdent(tt.input, affixes...)
+			if result != tt.expected {
+				t.Errorf("NormalizeIdent(%q, %v) = %q, want %q", tt.input, affixes, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Abbreviations(t *testing.T) {
+	tests := []struct {
+		abbreviations map[string]string
+		input         string
+		expected      string
+	}{
+		{nil, "Qty", "quantity"},
+		{nil, "OrderQty", "orderquantity"},
+		{nil, "ship_addr", "shipaddress"},
+		{nil, "TotalAmt", "totalamount"},
+		{nil, "NumItems", "numberitems"},
+		{nil, "Number", "number"},
+		{nil, "Numeric", "numeric"},
+
+		// Configured abbreviations add to and override the defaults
+		{map[string]string{"Ref": "Reference"}, "OrderRef", "orderreference"},
+		{map[string]string{"zip": "PostalCode"}, "ZipCode", "postalcodecode"},
+		{map[string]string{"num": "num"}, "NumItems", "numitems"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := Normalizer{Abbreviations: tt.abbreviations}.Normalize(tt.input)
+			if result != tt.expected {
+				t.Errorf("Normalize(%q) with %v = %q, want %q", tt.input, tt.abbreviations, result, tt.expected)
+			}
+		})
+	}
+
+	if score := NormalizedLevenshteinScore("Qty", "Quantity"); score != 1 {
+		t.Errorf("Expected Qty and Quantity to match exactly, got %.2f", score)
+	}
+}
+
 func TestTokenizeCamelCase(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -0,0 +1,69 @@
+package match
+
+import "caster-generator/internal/analyze"
+
+// Scorer scores how well a source field matches a target field. Programs
+// embedding the planner may implement it to rank candidates with their own
+// heuristics, such as embeddings, and pass it in RankOptions (or the
+// resolver's configuration). Tag matching and field positions still apply
+// on top of the combined score.
+type Scorer interface {
+	// NameScore returns the similarity (0-1) of the names of source and target.
+	NameScore(source, target *analyze.FieldInfo) float64
+	// TypeScore returns the score (0-1) of the types of source and target,
+	// given their built-in type compatibility.
+	TypeScore(source, target *analyze.FieldInfo, compat TypeCompatibilityResult) float64
+	// Combine returns the combined score (0-1) from a name and a type score.
+	Combine(nameScore, typeScore float64) float64
+}
+
+// defaultScorer is the built-in Scorer: the normalized Levenshtein similarity
+// of names (with or without common suffixes, or SynonymNameScore for
+// synonyms), and weights of 60% for the name and 40% for the type.
+type defaultScorer struct {
+	normalizer Normalizer
+	synonyms   synonymIndex
+}
+
+// NewDefaultScorer returns the built-in Scorer with the given normalizer and
+// synonym groups, for custom scorers to fall back on.
+func NewDefaultScorer(n Normalizer, synonyms map[string][]string) Scorer {
+	return newDefaultScorer(n, synonyms)
+}
+
+func newDefaultScorer(n Normalizer, synonyms map[string][]string) *defaultScorer {
+	return &defaultScorer{normalizer: n, synonyms: newSynonymIndex(synonyms, n)}
+}
+
+// NameScore implements Scorer.
+func (s *defaultScorer) NameScore(source, target *analyze.FieldInfo) float64 {
+	score, _ := s.nameScore(source.Name, target.Name)
+
+	return score
+}
+
+// TypeScore implements Scorer.
+func (s *defaultScorer) TypeScore(_, _ *analyze.FieldInfo, compat TypeCompatibilityResult) float64 {
+	return typeCompatScore(compat.Compatibility)
+}
+
+// Combine implements Scorer.
+func (s *defaultScorer) Combine(nameScore, typeScore float64) float64 {
+	return combineScores(nameScore, typeScore)
+}
+
+// nameScore returns the name score of source and target, and whether it is
+// due to synonyms.
+func (s *defaultScorer) nameScore(source, target string) (float64, bool) {
+	sourceNorm, targetNorm := s.normalizer.Normalize(source), s.normalizer.Normalize(target)
+
+	// Use max of regular and suffix-stripped
+	score := max(LevenshteinNormalized(sourceNorm, targetNorm), LevenshteinNormalized(
+		s.normalizer.NormalizeWithSuffixStrip(source), s.normalizer.NormalizeWithSuffixStrip(target)))
+
+	if score < SynonymNameScore && s.synonyms.synonymous(source, target, sourceNorm, targetNorm, s.normalizer) {
+		return SynonymNameScore, true
+	}
+
+	return score, false
+}
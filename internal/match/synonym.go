@@ -0,0 +1,56 @@
+package match
+
+import "strings"
+
+// SynonymNameScore is the name score of two different names made equal by
+// synonyms: near-exact, so an identical name still ranks first.
+const SynonymNameScore = 0.95
+
+// synonymIndex maps the normalized names of synonym groups to the normalized
+// name of their group's key.
+type synonymIndex map[string]string
+
+// newSynonymIndex indexes groups mapping a name to its domain-equivalent names
+// (e.g., "Amount" to ["Price", "Cost", "Total"]).
+func newSynonymIndex(groups map[string][]string, n Normalizer) synonymIndex {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	idx := make(synonymIndex)
+
+	for key, names := range groups {
+		canonical := n.Normalize(key)
+		idx[canonical] = canonical
+
+		for _, name := range names {
+			idx[n.Normalize(name)] = canonical
+		}
+	}
+
+	return idx
+}
+
+// canonical returns name normalized with its synonyms replaced by their
+// group's key: the whole name if it is a synonym, else each of its tokens
+// (e.g., "UnitPrice" -> "unitamount").
+func (idx synonymIndex) canonical(name string, n Normalizer) string {
+	if c, ok := idx[n.Normalize(name)]; ok {
+		return c
+	}
+
+	tokens := n.tokens(name)
+	for i, token := range tokens {
+		if c, ok := idx[token]; ok {
+			tokens[i] = c
+		}
+	}
+
+	return strings.Join(tokens, "")
+}
+
+// synonymous reports whether the different normalized names a and b are equal
+// once their synonyms are replaced.
+func (idx synonymIndex) synonymous(a, b, normA, normB string, n Normalizer) bool {
+	return len(idx) > 0 && normA != normB && idx.canonical(a, n) == idx.canonical(b, n)
+}
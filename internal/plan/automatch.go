@@ -1,7 +1,9 @@
 package plan
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 
 	"caster-generator/internal/analyze"
 	"caster-generator/internal/diagnostic"
@@ -17,46 +19,51 @@ func (r *Resolver) autoMatchRemainingFields(
 	diags *diagnostic.Diagnostics,
 	typePairStr string,
 ) {
-	// Get all source fields for matching
-	sourceFields := sourceType.Fields
+	// Get all source fields for matching, including fields promoted from embedded structs
+	sourceFields := matchableFields(sourceType, result.AllowUnexported)
+	targetFields := assignableFields(targetType, result.AllowUnexported)
+	partial := partiallyMappedEmbeds(targetFields, mappedTargets)
+	thresholds := r.autoMatchThresholds(result)
 
 	// Process each unmapped target field
-	for i := range targetType.Fields {
-		targetField := &targetType.Fields[i]
+	for _, tf := range targetFields {
+		targetField := tf.Field
 
-		// Skip if already mapped or unexported
-		if mappedTargets[targetField.Name] || !targetField.Exported {
+		// Skip if already mapped, unexported (unless allowed) or read-only: a proto
+		// oneof member or a property with a getter but no setter
+		if mappedTargets[targetField.Name] || (!targetField.Exported && !result.AllowUnexported) ||
+			(targetField.Index < 0 && targetField.Setter == "") {
 			continue
 		}
 
-		// Rank candidates
-		candidates := match.RankCandidates(targetField, sourceFields)
-
-		// Try to auto-match with high confidence
-		best := candidates.HighConfidence(r.config.MinConfidence, r.config.MinGap)
-
-		// Special case: if no high-confidence match but name matches well and both are structs/slices,
-		// allow matching based on structural compatibility
-		if best == nil && len(candidates) > 0 {
-			topCandidate := &candidates[0]
-			// Check if top candidate has high name score (>0.8) and is struct/slice to struct/slice
-			if topCandidate.NameScore >= 0.8 && topCandidate.SourceField.Type != nil && topCandidate.TargetField.Type != nil {
-				srcKind := topCandidate.SourceField.Type.Kind
-				tgtKind := topCandidate.TargetField.Type.Kind
-
-				// Allow struct-to-struct or slice-to-slice with good name match
-				if (srcKind == analyze.TypeKindStruct && tgtKind == analyze.TypeKindStruct) ||
-					(srcKind == analyze.TypeKindSlice && tgtKind == analyze.TypeKindSlice) ||
-					(srcKind == analyze.TypeKindArray && tgtKind == analyze.TypeKindArray) {
-					best = topCandidate
-				}
-			}
+		// Promoted target fields are only assigned individually when the embedded
+		// struct holding them isn't mapped as a whole. Embedded pointers are skipped,
+		// since assigning through them would require allocating the pointer first.
+		if tf.ViaPointer || anyMapped(tf.Path, mappedTargets) {
+			continue
+		}
+
+		// An embedded struct with some promoted fields already mapped explicitly is
+		// filled field by field instead of being overwritten as a whole.
+		if partial[targetField.Name] {
+			continue
 		}
 
-		if best != nil {
+		candidates := r.rankCandidates(targetField, sourceFields, result)
+
+		if best := r.autoMatchPick(candidates, thresholds); best != nil {
 			// Successful auto-match
 			strategy, compat := r.determineStrategyFromCandidate(best)
 
+			// Left needing a transform, which check would pass and gen refuse
+			if strategy == StrategyTransform &&
+				(unexportedAnonymous(best.SourceField.Type) || unexportedAnonymous(best.TargetField.Type)) {
+				diags.AddError("anonymous_struct",
+					fmt.Sprintf("field %q converts an anonymous struct with unexported fields, "+
+						"which needs a transform; map it in fields with one", targetField.Name),
+					typePairStr, targetField.Name)
+			}
+
 			targetPath := mapping.FieldPath{
 				Segments: []mapping.PathSegment{{Name: targetField.Name}},
 			}
@@ -64,6 +71,11 @@ func (r *Resolver) autoMatchRemainingFields(
 				Segments: []mapping.PathSegment{{Name: best.SourceField.Name}},
 			}
 
+			var synonym string
+			if best.Synonym {
+				synonym = "synonym, "
+			}
+
 			resolved := ResolvedFieldMapping{
 				TargetPaths: []mapping.FieldPath{targetPath},
 				SourcePaths: []mapping.FieldPath{sourcePath},
@@ -71,12 +83,18 @@ func (r *Resolver) autoMatchRemainingFields(
 				Cardinality: mapping.CardinalityOneToOne,
 				Strategy:    strategy,
 				Confidence:  best.CombinedScore,
-				Explanation: fmt.Sprintf("auto-matched: %s -> %s (score: %.2f, %s)",
-					best.SourceField.Name, targetField.Name, best.CombinedScore, compat),
+				Explanation: fmt.Sprintf("auto-matched: %s -> %s (%sscore: %.2f, %s)",
+					best.SourceField.Name, targetField.Name, synonym, best.CombinedScore, compat),
 			}
 
 			result.Mappings = append(result.Mappings, resolved)
 			mappedTargets[targetField.Name] = true
+		} else if isEmbeddedStruct(targetField) {
+			// Its promoted fields are matched individually further on.
+			continue
+		} else if r.autoMatchFlattened(result, targetField, sourceFields, mappedTargets, diags, typePairStr) {
+			// Matched by prefix against the fields of a source struct, or field by field.
+			continue
 		} else {
 			// Add to unmapped with candidates for suggestions
 			targetPath := mapping.FieldPath{
@@ -85,14 +103,22 @@ func (r *Resolver) autoMatchRemainingFields(
 
 			var reason string
 
+			unapproved, money := unapprovedDefinedType(candidates), moneyCandidate(candidates)
+
 			switch {
-			case candidates.IsAmbiguous(r.config.AmbiguityThreshold) && len(candidates) >= 2:
+			case unapproved != nil:
+				reason = fmt.Sprintf("%q converts %s to %s, a %s",
+					unapproved.SourceField.Name, unapproved.TypeCompat.SourceType, unapproved.TypeCompat.TargetType,
+					reasonExplicitDefinedType)
+			case money != nil:
+				reason = fmt.Sprintf("%q: %s", money.SourceField.Name, money.TypeCompat.Reason)
+			case candidates.IsAmbiguous(thresholds.AmbiguityThreshold) && len(candidates) >= 2:
 				reason = fmt.Sprintf("ambiguous: top candidates %q (%.2f) and %q (%.2f) are too close",
 					candidates[0].SourceField.Name, candidates[0].CombinedScore,
 					candidates[1].SourceField.Name, candidates[1].CombinedScore)
-			case len(candidates) > 0 && candidates[0].CombinedScore < r.config.MinConfidence:
+			case len(candidates) > 0 && candidates[0].CombinedScore < thresholds.MinConfidence:
 				reason = fmt.Sprintf("best match %q (%.2f) below threshold %.2f",
-					candidates[0].SourceField.Name, candidates[0].CombinedScore, r.config.MinConfidence)
+					candidates[0].SourceField.Name, candidates[0].CombinedScore, thresholds.MinConfidence)
 			case len(candidates) == 0:
 				reason = "no compatible source fields found"
 			default:
@@ -103,6 +129,7 @@ func (r *Resolver) autoMatchRemainingFields(
 				TargetField: targetField,
 				TargetPath:  targetPath,
 				Candidates:  candidates.Top(r.config.MaxCandidates),
+				Group:       suggestGroup(targetField, sourceFields),
 				Reason:      reason,
 			})
 
@@ -112,3 +139,172 @@ func (r *Resolver) autoMatchRemainingFields(
 		}
 	}
 }
+
+// rankCandidates ranks the source fields auto-matching considers for a target field.
+func (r *Resolver) rankCandidates(
+	targetField *analyze.FieldInfo,
+	sourceFields []analyze.FieldInfo,
+	tp *ResolvedTypePair,
+) match.CandidateList {
+	return match.RankCandidatesWithOptions(targetField, sourceFields, match.RankOptions{
+		PositionWeight: cmp.Or(r.mappingDef.Match.PositionWeight, r.config.PositionWeight),
+		TargetFields:   tp.TargetType.Fields,
+		MatchTag:       cmp.Or(r.mappingDef.Match.Tag, r.config.MatchTag),
+		Compat:         r.overrideCompat,
+		Unexported:     tp.AllowUnexported,
+		StripAffixes:   r.stripAffixes(),
+		Synonyms:       r.mappingDef.Match.Synonyms,
+		Abbreviations:  r.mappingDef.Match.Abbreviations,
+		Scorer:         r.config.Scorer,
+	})
+}
+
+// stripAffixes returns the name tokens auto-matching ignores: the configured
+// ones and those of the mapping file.
+func (r *Resolver) stripAffixes() []string {
+	return slices.Concat(r.config.StripAffixes, r.mappingDef.StripAffixes)
+}
+
+// normalizer returns the name normalizer of auto-matching.
+func (r *Resolver) normalizer() match.Normalizer {
+	return match.Normalizer{Affixes: r.stripAffixes(), Abbreviations: r.mappingDef.Match.Abbreviations}
+}
+
+// autoMatchThresholds returns the auto-match thresholds of tp: those its
+// mapping's matching block sets, or else the config's.
+func (r *Resolver) autoMatchThresholds(tp *ResolvedTypePair) mapping.Matching {
+	return tp.Matching.Or(mapping.Matching{
+		MinConfidence:      r.config.MinConfidence,
+		MinGap:             r.config.MinGap,
+		AmbiguityThreshold: r.config.AmbiguityThreshold,
+	})
+}
+
+// autoMatchPick returns the candidate auto-matching accepts under thresholds,
+// or nil if none.
+func (r *Resolver) autoMatchPick(candidates match.CandidateList, thresholds mapping.Matching) *match.Candidate {
+	// Try to auto-match with high confidence
+	if best := candidates.HighConfidence(thresholds.MinConfidence, thresholds.MinGap); best != nil {
+		return best
+	}
+
+	// Special case: if no high-confidence match but name matches well and both are structs/slices,
+	// allow matching based on structural compatibility
+	if len(candidates) > 0 {
+		topCandidate := &candidates[0]
+		// Check if top candidate has high name score (>0.8) and is struct/slice to struct/slice
+		if topCandidate.NameScore >= 0.8 && topCandidate.SourceField.Type != nil && topCandidate.TargetField.Type != nil {
+			srcKind := topCandidate.SourceField.Type.Kind
+			tgtKind := topCandidate.TargetField.Type.Kind
+
+			// Allow struct-to-struct or slice-to-slice with good name match
+			if (srcKind == analyze.TypeKindStruct && tgtKind == analyze.TypeKindStruct) ||
+				(srcKind == analyze.TypeKindSlice && tgtKind == analyze.TypeKindSlice) ||
+				(srcKind == analyze.TypeKindArray && tgtKind == analyze.TypeKindArray) {
+				return topCandidate
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchableFields returns the fields of a struct usable as auto-match sources:
+// its own fields, fields promoted from embedded (non-pointer) structs and
+// properties read through getter methods, then its unexported fields if allowed.
+func matchableFields(t *analyze.TypeInfo, unexported bool) []analyze.FieldInfo {
+	var fields []analyze.FieldInfo
+
+	for _, pf := range t.AccessibleFields() {
+		if pf.ViaPointer {
+			continue
+		}
+
+		fields = append(fields, *pf.Field)
+	}
+
+	for _, f := range t.MethodFields() {
+		if f.Getter != "" {
+			fields = append(fields, f)
+		}
+	}
+
+	if unexported {
+		fields = append(fields, t.Unexported...)
+	}
+
+	return fields
+}
+
+// assignableFields returns the fields of a struct auto-matching considers as
+// targets: its accessible fields, properties assigned through setter methods,
+// then its unexported fields if allowed.
+func assignableFields(t *analyze.TypeInfo, unexported bool) []analyze.PromotedField {
+	fields := t.AccessibleFields()
+
+	methodFields := t.MethodFields()
+	for i := range methodFields {
+		if methodFields[i].Setter != "" {
+			fields = append(fields, analyze.PromotedField{Field: &methodFields[i]})
+		}
+	}
+
+	if unexported {
+		for i := range t.Unexported {
+			fields = append(fields, analyze.PromotedField{Field: &t.Unexported[i]})
+		}
+	}
+
+	return fields
+}
+
+// partiallyMappedEmbeds returns the names of embedded target fields that hold
+// at least one already mapped promoted field.
+func partiallyMappedEmbeds(fields []analyze.PromotedField, mappedTargets map[string]bool) map[string]bool {
+	partial := make(map[string]bool)
+
+	for _, pf := range fields {
+		if pf.Depth() > 0 && mappedTargets[pf.Field.Name] {
+			for _, name := range pf.Path {
+				partial[name] = true
+			}
+		}
+	}
+
+	return partial
+}
+
+// anyMapped reports whether any of the named target fields is already mapped.
+func anyMapped(names []string, mappedTargets map[string]bool) bool {
+	for _, name := range names {
+		if mappedTargets[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEmbeddedStruct reports whether f embeds a struct by value.
+func isEmbeddedStruct(f *analyze.FieldInfo) bool {
+	return f.Embedded && f.Type != nil && f.Type.Kind == analyze.TypeKindStruct
+}
+
+// moneyCandidate returns the candidate of the same name as the target that
+// converts between a float and a decimal amount, which needs an explicit
+// transform, or nil.
+func moneyCandidate(candidates match.CandidateList) *match.Candidate {
+	for i := range candidates {
+		c := &candidates[i]
+		if c.NameScore < 1 || c.SourceField.Type == nil || c.TargetField.Type == nil {
+			continue
+		}
+
+		if match.MoneyConversion(c.SourceField.Name, c.SourceField.Type.GoType, c.TargetField.Name,
+			c.TargetField.Type.GoType) != "" {
+			return c
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package plan
+
+import "fmt"
+
+// markContextPairs sets NeedsContext on every type pair whose caster calls a
+// context-aware transform (TransformDef.Ctx), either directly or through any
+// nested caster, so that ctx can be threaded through the generated call chain.
+func (r *Resolver) markContextPairs(plan *ResolvedMappingPlan) {
+	if r.mappingDef == nil {
+		return
+	}
+
+	ctxTransforms := make(map[string]bool)
+
+	for _, t := range r.mappingDef.Transforms {
+		if t.Ctx {
+			ctxTransforms[t.Name] = true
+		}
+	}
+
+	if len(ctxTransforms) == 0 {
+		return
+	}
+
+	// The same pair may be reachable through several pointers (plan copies and the
+	// resolver cache); track them all by key.
+	pairs := make(map[string][]*ResolvedTypePair)
+	deps := make(map[string][]string)
+	needs := make(map[string]bool)
+
+	var traverse func(pair *ResolvedTypePair)
+
+	traverse = func(pair *ResolvedTypePair) {
+		if pair == nil {
+			return
+		}
+
+		key := getPairKey(pair)
+
+		for _, p := range pairs[key] {
+			if p == pair {
+				return
+			}
+		}
+
+		pairs[key] = append(pairs[key], pair)
+
+		for _, m := range pair.Mappings {
+			if m.Strategy == StrategyTransform && ctxTransforms[m.Transform] {
+				needs[key] = true
+			}
+		}
+
+		for i := range pair.NestedPairs {
+			np := &pair.NestedPairs[i]
+			if np.SourceType == nil || np.TargetType == nil {
+				continue
+			}
+
+			deps[key] = append(deps[key], fmt.Sprintf("%s->%s", np.SourceType.ID, np.TargetType.ID))
+			traverse(np.ResolvedPair)
+		}
+	}
+
+	for i := range plan.TypePairs {
+		traverse(&plan.TypePairs[i])
+	}
+
+	// Propagate to callers until nothing changes (handles recursive pairs).
+	for changed := true; changed; {
+		changed = false
+
+		for key, children := range deps {
+			if needs[key] {
+				continue
+			}
+
+			for _, child := range children {
+				if needs[child] {
+					needs[key] = true
+					changed = true
+
+					break
+				}
+			}
+		}
+	}
+
+	for key, ps := range pairs {
+		if !needs[key] {
+			continue
+		}
+
+		for _, p := range ps {
+			p.NeedsContext = true
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestMarkContextPairs(t *testing.T) {
+	newStruct := func(pkg, name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct}
+	}
+
+	srcOrder, dstOrder := newStruct("test/src", "Order"), newStruct("test/dst", "Order")
+	srcItem, dstItem := newStruct("test/src", "Item"), newStruct("test/dst", "Item")
+	srcNote, dstNote := newStruct("test/src", "Note"), newStruct("test/dst", "Note")
+
+	itemPair := &ResolvedTypePair{
+		SourceType: srcItem,
+		TargetType: dstItem,
+		Mappings: []ResolvedFieldMapping{
+			{Strategy: StrategyTransform, Transform: "LookupStatus"},
+		},
+	}
+
+	p := &ResolvedMappingPlan{
+		TypePairs: []ResolvedTypePair{
+			{
+				SourceType: srcOrder,
+				TargetType: dstOrder,
+				NestedPairs: []NestedConversion{
+					{SourceType: srcItem, TargetType: dstItem, ResolvedPair: itemPair},
+				},
+			},
+			*itemPair,
+			{
+				SourceType: srcNote,
+				TargetType: dstNote,
+				Mappings: []ResolvedFieldMapping{
+					{Strategy: StrategyTransform, Transform: "PlainTransform"},
+				},
+			},
+		},
+	}
+
+	r := NewResolver(analyze.NewTypeGraph(), &mapping.MappingFile{
+		Transforms: []mapping.TransformDef{
+			{Name: "LookupStatus", Ctx: true},
+			{Name: "PlainTransform"},
+		},
+	}, DefaultConfig())
+
+	r.markContextPairs(p)
+
+	if !p.TypePairs[0].NeedsContext {
+		t.Error("Order caster should need ctx through nested Item caster")
+	}
+
+	if !p.TypePairs[1].NeedsContext || !itemPair.NeedsContext {
+		t.Error("Item caster should need ctx for LookupStatus")
+	}
+
+	if p.TypePairs[2].NeedsContext {
+		t.Error("Note caster should not need ctx")
+	}
+}
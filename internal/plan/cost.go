@@ -0,0 +1,239 @@
+package plan
+
+import (
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+// Relative weights of the operations counted by Cost.Score.
+const (
+	costAssign     = 1
+	costCall       = 2
+	costAllocation = 4
+	// costElements is the number of elements assumed per collection, so the
+	// body of a loop weighs that many times its own cost.
+	costElements = 10
+)
+
+// Cost estimates the run-time work of generated code, to spot the casters
+// dominating conversion time before profiling. Counts are static: a call
+// inside a loop counts once, while Score weighs it by the loop.
+type Cost struct {
+	// Allocations counts the places allocating slices, maps or pointers.
+	Allocations int `json:"allocations"`
+	// Calls counts the calls of transforms, nested casters and copy helpers.
+	Calls int `json:"calls"`
+	// LoopDepth is the deepest nesting of loops over collections.
+	LoopDepth int `json:"loop_depth"`
+	// Score is the weighed number of operations, assuming collections of
+	// ten elements.
+	Score int `json:"score"`
+}
+
+// plus returns the cost of running c, then o.
+func (c Cost) plus(o Cost) Cost {
+	return Cost{
+		Allocations: c.Allocations + o.Allocations,
+		Calls:       c.Calls + o.Calls,
+		LoopDepth:   max(c.LoopDepth, o.LoopDepth),
+		Score:       c.Score + o.Score,
+	}
+}
+
+// loop returns the cost of a loop running c for every element of a collection.
+func (c Cost) loop() Cost {
+	c.LoopDepth++
+	c.Score *= costElements
+
+	return c
+}
+
+var (
+	callCost       = Cost{Calls: 1, Score: costCall}
+	allocationCost = Cost{Allocations: 1, Score: costAllocation}
+)
+
+// PairCost returns the cost of the caster of pair: the sum of its assignment
+// costs. planCopy is the plan-wide copy mode (see ResolvedMappingPlan.CopyMode).
+// Nested casters are counted as calls, their own cost is reported on their pair.
+func PairCost(pair *ResolvedTypePair, planCopy mapping.CopyMode) Cost {
+	var total Cost
+
+	for i := range pair.Mappings {
+		total = total.plus(AssignmentCost(&pair.Mappings[i], pair, planCopy))
+	}
+
+	return total
+}
+
+// AssignmentCost estimates the cost of the assignment generated for m.
+func AssignmentCost(m *ResolvedFieldMapping, pair *ResolvedTypePair, planCopy mapping.CopyMode) Cost {
+	if m.Strategy == StrategyIgnore {
+		return Cost{}
+	}
+
+	cost := Cost{Score: costAssign}
+
+	var srcType, tgtType *analyze.TypeInfo
+
+	if len(m.SourcePaths) > 0 {
+		srcType = fieldTypeAt(m.SourcePaths[0], pair.SourceType)
+	}
+
+	if len(m.TargetPaths) > 0 {
+		tgtType = fieldTypeAt(m.TargetPaths[0], pair.TargetType)
+	}
+
+	switch m.Strategy {
+	case StrategyDirectAssign, StrategyConvert:
+		return cost.plus(copyCost(srcType, m.Copy.Or(pair.CopyMode).Or(planCopy)))
+	case StrategyPointerWrap:
+		return cost.plus(allocationCost)
+	case StrategySliceMap, StrategyMap:
+		return cost.plus(collectionCost(srcType, tgtType))
+	case StrategyPointerNestedCast:
+		return cost.plus(callCost).plus(allocationCost)
+	case StrategyNestedCast, StrategyWrapper, StrategyInterfaceSwitch:
+		return cost.plus(callCost)
+	case StrategyTransform:
+		return cost.plus(callCost)
+	case StrategySQLNull:
+		// Only NULL-checked values read into pointers are allocated.
+		if nc := FindSQLNullConversion(srcType, tgtType); nc != nil && nc.Pointer && nc.Unwrap {
+			return cost.plus(allocationCost)
+		}
+
+		return cost
+	case StrategyCollect:
+		// The map or slice literal is allocated.
+		return cost.plus(allocationCost)
+	case StrategyMapKey:
+		// The map is looked up, and the value parsed unless it is a string.
+		return cost.plus(callCost)
+	case StrategyStringMethod:
+		// Formatting and parsing allocate the string or the parsed value.
+		return cost.plus(callCost).plus(allocationCost)
+	default:
+		return cost
+	}
+}
+
+// collectionCost returns the cost of converting collection src to tgt
+// element by element.
+func collectionCost(src, tgt *analyze.TypeInfo) Cost {
+	src, tgt = costShape(src), costShape(tgt)
+	if src == nil || tgt == nil {
+		return allocationCost
+	}
+
+	return allocationCost.plus(elementCost(src.ElemType, tgt.ElemType).loop())
+}
+
+// elementCost returns the cost of converting one collection element.
+func elementCost(src, tgt *analyze.TypeInfo) Cost {
+	cost := Cost{Score: costAssign}
+
+	src, tgt = costShape(src), costShape(tgt)
+	if src == nil || tgt == nil || src == tgt {
+		return cost
+	}
+
+	switch tgt.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindArray, analyze.TypeKindMap:
+		return cost.plus(collectionCost(src, tgt))
+	case analyze.TypeKindPointer:
+		return cost.plus(allocationCost).plus(elementCost(derefShape(src), tgt.ElemType))
+	case analyze.TypeKindStruct:
+		return cost.plus(callCost)
+	default:
+		return cost
+	}
+}
+
+// copyCost returns the cost of copying a value of type t in copy mode mode.
+func copyCost(t *analyze.TypeInfo, mode mapping.CopyMode) Cost {
+	t = costShape(t)
+	if t == nil {
+		return Cost{}
+	}
+
+	switch mode {
+	case mapping.CopyShallow:
+		if t.Kind == analyze.TypeKindSlice || t.Kind == analyze.TypeKindMap || t.Kind == analyze.TypeKindPointer {
+			return callCost.plus(allocationCost)
+		}
+	case mapping.CopyDeep:
+		return deepCopyCost(t, make(map[*analyze.TypeInfo]bool))
+	default:
+	}
+
+	return Cost{}
+}
+
+// deepCopyCost returns the cost of cloning t, zero when a plain copy shares
+// no memory. seen guards recursive types.
+func deepCopyCost(t *analyze.TypeInfo, seen map[*analyze.TypeInfo]bool) Cost {
+	t = costShape(t)
+	if t == nil || seen[t] {
+		return Cost{}
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	switch t.Kind {
+	case analyze.TypeKindSlice, analyze.TypeKindMap:
+		elem := Cost{Score: costAssign}.plus(deepCopyCost(t.ElemType, seen))
+
+		return callCost.plus(allocationCost).plus(elem.loop())
+	case analyze.TypeKindPointer:
+		return callCost.plus(allocationCost).plus(deepCopyCost(t.ElemType, seen))
+	case analyze.TypeKindArray:
+		// Arrays are copied by value; only elements sharing memory are cloned.
+		elem := deepCopyCost(t.ElemType, seen)
+		if elem == (Cost{}) {
+			return Cost{}
+		}
+
+		return callCost.plus(Cost{Score: costAssign}.plus(elem).loop())
+	case analyze.TypeKindStruct:
+		// Structs are copied by value; only exported fields sharing memory are cloned.
+		var fields Cost
+
+		for _, f := range t.Fields {
+			if !f.Exported {
+				continue
+			}
+
+			if field := deepCopyCost(f.Type, seen); field != (Cost{}) {
+				fields = fields.plus(Cost{Score: costAssign}).plus(field)
+			}
+		}
+
+		if fields == (Cost{}) {
+			return Cost{}
+		}
+
+		return callCost.plus(fields)
+	default:
+		return Cost{}
+	}
+}
+
+// costShape looks through named types declared over other types.
+func costShape(t *analyze.TypeInfo) *analyze.TypeInfo {
+	for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+		t = t.Underlying
+	}
+
+	return t
+}
+
+// derefShape returns the element of pointer t, or t itself.
+func derefShape(t *analyze.TypeInfo) *analyze.TypeInfo {
+	if t != nil && t.Kind == analyze.TypeKindPointer {
+		return t.ElemType
+	}
+
+	return t
+}
@@ -0,0 +1,113 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestAssignmentCost(t *testing.T) {
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	srcItem := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "test/src", Name: "Item"}, Kind: analyze.TypeKindStruct}
+	dstItem := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "test/dst", Name: "Item"}, Kind: analyze.TypeKindStruct}
+	tagsType := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: str}
+	labelType := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "test/src", Name: "Label"}, Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Text", Exported: true, Type: str},
+			{Name: "Tags", Exported: true, Type: tagsType},
+		}}
+	labelsType := &analyze.TypeInfo{Kind: analyze.TypeKindArray, ElemType: labelType}
+	namesType := &analyze.TypeInfo{Kind: analyze.TypeKindArray, ElemType: str}
+
+	pair := &ResolvedTypePair{
+		SourceType: &analyze.TypeInfo{Kind: analyze.TypeKindStruct, Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Items", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: srcItem}},
+			{Name: "Tags", Exported: true, Type: tagsType},
+			{Name: "Label", Exported: true, Type: labelType},
+			{Name: "Labels", Exported: true, Type: labelsType},
+			{Name: "Names", Exported: true, Type: namesType},
+		}},
+		TargetType: &analyze.TypeInfo{Kind: analyze.TypeKindStruct, Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: str},
+			{Name: "Items", Exported: true, Type: &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: dstItem}},
+			{Name: "Tags", Exported: true, Type: tagsType},
+			{Name: "Label", Exported: true, Type: labelType},
+			{Name: "Labels", Exported: true, Type: labelsType},
+			{Name: "Names", Exported: true, Type: namesType},
+		}},
+	}
+
+	assignment := func(field string, strategy ConversionStrategy, copyMode mapping.CopyMode) *ResolvedFieldMapping {
+		path := []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: field}}}}
+
+		return &ResolvedFieldMapping{TargetPaths: path, SourcePaths: path, Strategy: strategy, Copy: copyMode}
+	}
+
+	tests := []struct {
+		name string
+		m    *ResolvedFieldMapping
+		want Cost
+	}{
+		{"direct", assignment("Name", StrategyDirectAssign, mapping.CopyDefault), Cost{Score: 1}},
+		{"ignored", assignment("Name", StrategyIgnore, mapping.CopyDefault), Cost{}},
+		{"transform", assignment("Name", StrategyTransform, mapping.CopyDefault), Cost{Calls: 1, Score: 3}},
+		// make, then a nested caster call per element
+		{"slice of structs", assignment("Items", StrategySliceMap, mapping.CopyDefault),
+			Cost{Allocations: 1, Calls: 1, LoopDepth: 1, Score: 1 + 4 + 10*(1+2)}},
+		{"aliased slice", assignment("Tags", StrategyDirectAssign, mapping.CopyAlias), Cost{Score: 1}},
+		{"shallow slice", assignment("Tags", StrategyDirectAssign, mapping.CopyShallow),
+			Cost{Allocations: 1, Calls: 1, Score: 1 + 2 + 4}},
+		{"deep slice", assignment("Tags", StrategyDirectAssign, mapping.CopyDeep),
+			Cost{Allocations: 1, Calls: 1, LoopDepth: 1, Score: 1 + 2 + 4 + 10}},
+		// the struct clone, then the clone of its Tags field
+		{"deep struct", assignment("Label", StrategyDirectAssign, mapping.CopyDeep),
+			Cost{Allocations: 1, Calls: 2, LoopDepth: 1, Score: 1 + 2 + 1 + (2 + 4 + 10)}},
+		// the array clone, then a struct clone per element
+		{"deep array", assignment("Labels", StrategyDirectAssign, mapping.CopyDeep),
+			Cost{Allocations: 1, Calls: 3, LoopDepth: 2, Score: 1 + 2 + 10*(1+2+1+(2+4+10))}},
+		{"deep value array", assignment("Names", StrategyDirectAssign, mapping.CopyDeep), Cost{Score: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AssignmentCost(tt.m, pair, mapping.CopyDefault); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	pair.Mappings = []ResolvedFieldMapping{*tests[0].m, *tests[3].m}
+
+	want := Cost{Allocations: 1, Calls: 1, LoopDepth: 1, Score: 36}
+	if got := PairCost(pair, mapping.CopyDefault); got != want {
+		t.Errorf("pair cost: expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSuggestionReport_SortByCost(t *testing.T) {
+	report := &SuggestionReport{TypePairs: []TypePairReport{
+		{Source: "cheap", Cost: Cost{Score: 1}},
+		{Source: "costly", Cost: Cost{Score: 35}, Assignments: []AssignmentReport{
+			{TargetField: "Name", Cost: Cost{Score: 1}},
+			{TargetField: "Items", Cost: Cost{Score: 34}},
+		}},
+		{Source: "also cheap", Cost: Cost{Score: 1}},
+	}}
+
+	report.SortByCost()
+
+	var order []string
+	for _, tp := range report.TypePairs {
+		order = append(order, tp.Source)
+	}
+
+	if order[0] != "costly" || order[1] != "cheap" || order[2] != "also cheap" {
+		t.Errorf("unexpected order %v", order)
+	}
+
+	if report.TypePairs[0].Assignments[0].TargetField != "Items" {
+		t.Errorf("expected the costliest assignment first, got %s", report.TypePairs[0].Assignments[0].TargetField)
+	}
+}
@@ -0,0 +1,181 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"caster-generator/internal/mapping"
+)
+
+// EffectiveTypePair lists the rules that decide each target field of a type pair
+// after the mapping layers (121, fields, ignore, auto, auto-matching) combine.
+type EffectiveTypePair struct {
+	Source   string          `json:"source"`
+	Target   string          `json:"target"`
+	Rules    []EffectiveRule `json:"rules"`
+	Unmapped []string        `json:"unmapped,omitempty"`
+}
+
+// EffectiveRule is the winning rule for one target field and where it came from.
+type EffectiveRule struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources,omitempty"`
+	Origin  string   `json:"origin"`
+	// Defined is where the rule is defined ("file:line:column"), including the
+	// included mapping file it came from.
+	Defined     string  `json:"defined,omitempty"`
+	Strategy    string  `json:"strategy"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	Explanation string  `json:"explanation,omitempty"`
+	// Overrides lists the origins of lower-priority rules shadowed by this one.
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// EffectiveConfig computes the effective rules of every top-level type pair.
+// When several rules target the same field, the one with the highest priority
+// origin wins and the others are recorded as overridden.
+func EffectiveConfig(plan *ResolvedMappingPlan) []EffectiveTypePair {
+	pairs := make([]EffectiveTypePair, 0, len(plan.TypePairs))
+
+	for i := range plan.TypePairs {
+		tp := &plan.TypePairs[i]
+		ep := EffectiveTypePair{
+			Source: tp.SourceType.ID.String(),
+			Target: tp.TargetType.ID.String(),
+			Rules:  []EffectiveRule{},
+		}
+
+		// MappingSource values are declared in priority order.
+		mappings := append([]ResolvedFieldMapping{}, tp.Mappings...)
+		sort.SliceStable(mappings, func(a, b int) bool { return mappings[a].Source < mappings[b].Source })
+
+		claimed := make(map[string]int)
+
+		for _, m := range mappings {
+			winner, shadowed := claimingRule(&m, claimed)
+			if shadowed {
+				ep.Rules[winner].Overrides = append(ep.Rules[winner].Overrides, ruleOrigin(&m))
+				continue
+			}
+
+			rule := EffectiveRule{
+				Target:      joinPaths(m.TargetPaths),
+				Origin:      m.Source.String(),
+				Strategy:    m.Strategy.String(),
+				Explanation: m.Explanation,
+			}
+
+			if m.Pos.IsValid() {
+				rule.Defined = m.Pos.String()
+			}
+
+			for _, sp := range m.SourcePaths {
+				rule.Sources = append(rule.Sources, sp.String())
+			}
+
+			if m.Source == MappingSourceAutoMatched {
+				rule.Confidence = m.Confidence
+			}
+
+			for _, tp := range m.TargetPaths {
+				claimed[tp.String()] = len(ep.Rules)
+			}
+
+			ep.Rules = append(ep.Rules, rule)
+		}
+
+		sort.SliceStable(ep.Rules, func(a, b int) bool { return ep.Rules[a].Target < ep.Rules[b].Target })
+
+		for _, um := range tp.UnmappedTargets {
+			ep.Unmapped = append(ep.Unmapped, um.TargetPath.String())
+		}
+
+		pairs = append(pairs, ep)
+	}
+
+	return pairs
+}
+
+// ruleOrigin returns the origin of m, with where it is defined if known.
+func ruleOrigin(m *ResolvedFieldMapping) string {
+	if !m.Pos.IsValid() {
+		return m.Source.String()
+	}
+
+	return m.Source.String() + " at " + m.Pos.String()
+}
+
+// claimingRule reports whether every target of m is already decided by a
+// higher-priority rule, returning the index of that rule.
+func claimingRule(m *ResolvedFieldMapping, claimed map[string]int) (int, bool) {
+	winner := -1
+
+	for _, tp := range m.TargetPaths {
+		idx, ok := claimed[tp.String()]
+		if !ok {
+			return -1, false
+		}
+
+		winner = idx
+	}
+
+	return winner, winner >= 0
+}
+
+// joinPaths joins field paths with ", ".
+func joinPaths(paths []mapping.FieldPath) string {
+	parts := make([]string, len(paths))
+	for i, p := range paths {
+		parts[i] = p.String()
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// FormatEffectiveConfig formats effective rules as aligned, human-readable text.
+func FormatEffectiveConfig(pairs []EffectiveTypePair) string {
+	var sb strings.Builder
+
+	for _, ep := range pairs {
+		fmt.Fprintf(&sb, "=== %s -> %s ===\n", ep.Source, ep.Target)
+
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+		for _, rule := range ep.Rules {
+			from := "<- " + strings.Join(rule.Sources, ", ")
+			if rule.Strategy == StrategyIgnore.String() {
+				from = "(ignored)"
+			} else if len(rule.Sources) == 0 {
+				from = "(no source)"
+			}
+
+			origin := rule.Origin
+			if rule.Confidence > 0 {
+				origin += fmt.Sprintf(" %.2f", rule.Confidence)
+			}
+
+			if rule.Defined != "" {
+				origin += " at " + rule.Defined
+			}
+
+			note := rule.Explanation
+			if len(rule.Overrides) > 0 {
+				note += " [overrides " + strings.Join(rule.Overrides, ", ") + "]"
+			}
+
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", rule.Target, from, origin, note)
+		}
+
+		_ = tw.Flush()
+
+		if len(ep.Unmapped) > 0 {
+			fmt.Fprintf(&sb, "  unmapped: %s\n", strings.Join(ep.Unmapped, ", "))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,151 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	sourceType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "OrderID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Title", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	targetType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/target", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Title", Exported: true, Type: basicTypeInfo()},
+			{Name: "Status", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[targetType.ID] = targetType
+
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{
+			{
+				Source:   "source.Order",
+				Target:   "target.Order",
+				OneToOne: map[string]string{"OrderID": "ID"},
+				Fields: []mapping.FieldMapping{
+					{Source: mapping.FieldRefArray{{Path: "Title"}}, Target: mapping.FieldRefArray{{Path: "ID"}}},
+				},
+				Ignore: []mapping.IgnoreEntry{{Target: "Status"}},
+			},
+		},
+	}
+
+	plan, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	pairs := EffectiveConfig(plan)
+	if len(pairs) != 1 {
+		t.Fatalf("Expected 1 type pair, got %d", len(pairs))
+	}
+
+	type ruleSummary struct {
+		target, origin string
+		overrides      []string
+	}
+
+	var got []ruleSummary
+	for _, rule := range pairs[0].Rules {
+		got = append(got, ruleSummary{rule.Target, rule.Origin, rule.Overrides})
+	}
+
+	want := []ruleSummary{
+		{"ID", "yaml:121", []string{"yaml:fields"}},
+		{"Status", "yaml:ignore", nil},
+		{"Title", "auto", nil},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EffectiveConfig() rules = %v, want %v", got, want)
+	}
+
+	text := FormatEffectiveConfig(pairs)
+	if !strings.Contains(text, "[overrides yaml:fields]") || !strings.Contains(text, "(ignored)") {
+		t.Errorf("FormatEffectiveConfig() missing origins:\n%s", text)
+	}
+}
+
+func TestEffectiveConfigIncludedRules(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	orderType := func(pkg string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: pkg, Name: "Order"},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: "ID", Exported: true, Type: basicTypeInfo()},
+				{Name: "Note", Exported: true, Type: basicTypeInfo()},
+			},
+		}
+	}
+
+	for _, typ := range []*analyze.TypeInfo{orderType("test/source"), orderType("test/target")} {
+		graph.Types[typ.ID] = typ
+	}
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "mapping.yaml")
+	included := filepath.Join(dir, "orders.yaml")
+
+	writeFile := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(root, "include: [orders.yaml]\n")
+	writeFile(included, `mappings:
+  - source: source.Order
+    target: target.Order
+    121:
+      ID: ID
+    ignore: [Note]
+`)
+
+	mf, err := mapping.LoadFile(root)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	plan, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	defined := make(map[string]string)
+	for _, rule := range EffectiveConfig(plan)[0].Rules {
+		defined[rule.Target] = rule.Defined
+	}
+
+	if want := included + ":5:7"; defined["ID"] != want {
+		t.Errorf("Expected ID to be defined at %s, got %q", want, defined["ID"])
+	}
+
+	if !strings.HasPrefix(defined["Note"], included+":") {
+		t.Errorf("Expected Note to be defined in %s, got %q", included, defined["Note"])
+	}
+
+	if text := FormatEffectiveConfig(EffectiveConfig(plan)); !strings.Contains(text, "yaml:121 at "+included) {
+		t.Errorf("FormatEffectiveConfig() missing the included file:\n%s", text)
+	}
+}
@@ -0,0 +1,307 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+)
+
+// FieldExplanation is the decision trail of one target field of a type pair:
+// the rule deciding it, the rules it overrides and the source fields
+// auto-matching ranked for it.
+type FieldExplanation struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Field  string `json:"field"`
+	// Rule is the rule deciding the field, nil when the field is unmapped.
+	Rule *EffectiveRule `json:"rule,omitempty"`
+	// Shadowed lists the lower-priority rules targeting the field.
+	Shadowed []EffectiveRule `json:"shadowed,omitempty"`
+	// Enclosing is the rule assigning a field holding the field as a whole
+	// (e.g., Customer for Customer.Name), when no rule targets it directly.
+	Enclosing *EffectiveRule `json:"enclosing,omitempty"`
+	// Unmapped is why auto-matching left the field unmapped.
+	Unmapped string `json:"unmapped,omitempty"`
+	// Candidates are the source fields auto-matching ranks for a top-level
+	// field, best first.
+	Candidates []ExplainedCandidate `json:"candidates,omitempty"`
+}
+
+// ExplainedCandidate is a source field ranked by auto-matching, with the
+// reason it was picked or rejected.
+type ExplainedCandidate struct {
+	Source        string  `json:"source"`
+	Score         float64 `json:"score"`
+	NameScore     float64 `json:"name_score"`
+	Compatibility string  `json:"compatibility"`
+	Verdict       string  `json:"verdict"`
+}
+
+// ExplainField explains how the field named by spec (a target type followed
+// by a field path, e.g., "warehouse.Order.Status") is decided in every type
+// pair of the plan with that target, including nested pairs.
+func (r *Resolver) ExplainField(plan *ResolvedMappingPlan, spec string) ([]FieldExplanation, error) {
+	for i := range len(spec) {
+		if spec[i] != '.' {
+			continue
+		}
+
+		t := mapping.ResolveTypeID(spec[:i], r.graph)
+		if t == nil {
+			continue
+		}
+
+		path, err := mapping.ParsePath(spec[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", spec, err)
+		}
+
+		if fieldTypeAt(path, t) == nil {
+			return nil, fmt.Errorf("field %q: %s has no field %s", spec, t.ID, path)
+		}
+
+		var explanations []FieldExplanation
+
+		// A nested pair may be resolved once per caster using it.
+		seen := make(map[string]bool)
+
+		forEachResolvedPair(plan, func(tp *ResolvedTypePair) {
+			if tp.TargetType.ID != t.ID || seen[getPairKey(tp)] {
+				return
+			}
+
+			seen[getPairKey(tp)] = true
+			explanations = append(explanations, r.explainField(tp, path.String()))
+		})
+
+		if len(explanations) == 0 {
+			return nil, fmt.Errorf("field %q: no type pair of the mapping targets %s", spec, t.ID)
+		}
+
+		return explanations, nil
+	}
+
+	return nil, fmt.Errorf("field %q: no target type found (expected Type.Field, e.g. warehouse.Order.Status)", spec)
+}
+
+// explainField explains how the field at path is decided in the type pair.
+func (r *Resolver) explainField(tp *ResolvedTypePair, path string) FieldExplanation {
+	e := FieldExplanation{
+		Source: tp.SourceType.ID.String(),
+		Target: tp.TargetType.ID.String(),
+		Field:  path,
+	}
+
+	// MappingSource values are declared in priority order.
+	mappings := append([]ResolvedFieldMapping{}, tp.Mappings...)
+	sort.SliceStable(mappings, func(a, b int) bool { return mappings[a].Source < mappings[b].Source })
+
+	for i := range mappings {
+		if !targetsPath(&mappings[i], path) {
+			continue
+		}
+
+		rule := explainedRule(&mappings[i])
+		if e.Rule == nil {
+			e.Rule = &rule
+		} else {
+			e.Shadowed = append(e.Shadowed, rule)
+		}
+	}
+
+	for _, um := range tp.UnmappedTargets {
+		if um.TargetPath.String() == path {
+			e.Unmapped = um.Reason
+		}
+	}
+
+	if strings.Contains(path, ".") {
+		if e.Rule == nil {
+			e.Enclosing = enclosingRule(mappings, path)
+		}
+
+		return e
+	}
+
+	targetField := tp.TargetType.FieldByName(path)
+	if targetField == nil {
+		return e
+	}
+
+	candidates := r.rankCandidates(targetField, matchableFields(tp.SourceType, tp.AllowUnexported), tp)
+	thresholds := r.autoMatchThresholds(tp)
+	pick := r.autoMatchPick(candidates, thresholds)
+
+	for i := range candidates {
+		c := &candidates[i]
+		e.Candidates = append(e.Candidates, ExplainedCandidate{
+			Source:        c.SourceField.Name,
+			Score:         c.CombinedScore,
+			NameScore:     c.NameScore,
+			Compatibility: c.TypeCompat.Compatibility.String(),
+			Verdict:       r.candidateVerdict(candidates, i, pick, thresholds, e.Rule),
+		})
+	}
+
+	return e
+}
+
+// targetsPath reports whether the mapping assigns the target field at path.
+func targetsPath(m *ResolvedFieldMapping, path string) bool {
+	for _, tp := range m.TargetPaths {
+		if tp.String() == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enclosingRule returns the highest-priority rule among mappings (sorted by
+// priority) assigning a field that holds the field at path, or nil.
+func enclosingRule(mappings []ResolvedFieldMapping, path string) *EffectiveRule {
+	for prefix := path; strings.Contains(prefix, "."); {
+		prefix = prefix[:strings.LastIndex(prefix, ".")]
+
+		for i := range mappings {
+			if targetsPath(&mappings[i], prefix) {
+				rule := explainedRule(&mappings[i])
+
+				return &rule
+			}
+		}
+	}
+
+	return nil
+}
+
+// explainedRule describes a mapping as an effective rule.
+func explainedRule(m *ResolvedFieldMapping) EffectiveRule {
+	rule := EffectiveRule{
+		Target:      joinPaths(m.TargetPaths),
+		Origin:      m.Source.String(),
+		Strategy:    m.Strategy.String(),
+		Explanation: m.Explanation,
+	}
+
+	for _, sp := range m.SourcePaths {
+		rule.Sources = append(rule.Sources, sp.String())
+	}
+
+	if m.Source == MappingSourceAutoMatched {
+		rule.Confidence = m.Confidence
+	}
+
+	return rule
+}
+
+// candidateVerdict explains why auto-matching under thresholds picked or
+// rejected the i-th candidate, and whether the deciding rule used it.
+func (r *Resolver) candidateVerdict(
+	candidates match.CandidateList,
+	i int,
+	pick *match.Candidate,
+	thresholds mapping.Matching,
+	rule *EffectiveRule,
+) string {
+	c := &candidates[i]
+	best := &candidates[0]
+
+	var verdict string
+
+	switch {
+	case pick == c:
+		verdict = "auto-match pick"
+	case c.TypeCompat.Compatibility < match.TypeNeedsTransform:
+		verdict = "incompatible"
+		if c.TypeCompat.Reason != "" {
+			verdict += ": " + c.TypeCompat.Reason
+		}
+	case c.CombinedScore < thresholds.MinConfidence:
+		verdict = fmt.Sprintf("score below min_confidence %.2f", thresholds.MinConfidence)
+	case i == 0 && len(candidates) > 1:
+		verdict = fmt.Sprintf("within min_gap %.2f of %s (%.2f)",
+			thresholds.MinGap, candidates[1].SourceField.Name, candidates[1].CombinedScore)
+	default:
+		verdict = fmt.Sprintf("outscored by %s (%.2f)", best.SourceField.Name, best.CombinedScore)
+	}
+
+	if rule == nil || rule.Origin == MappingSourceAutoMatched.String() {
+		return verdict
+	}
+
+	for _, src := range rule.Sources {
+		if src == c.SourceField.Name {
+			return verdict + "; used by " + rule.Origin
+		}
+	}
+
+	if pick == c {
+		return verdict + "; overridden by " + rule.Origin
+	}
+
+	return verdict
+}
+
+// FormatFieldExplanations formats field explanations as human-readable text.
+func FormatFieldExplanations(explanations []FieldExplanation) string {
+	var sb strings.Builder
+
+	for _, e := range explanations {
+		fmt.Fprintf(&sb, "=== %s -> %s: %s ===\n", e.Source, e.Target, e.Field)
+
+		switch {
+		case e.Rule != nil:
+			fmt.Fprintf(&sb, "  decided by: %s\n", formatExplainedRule(e.Rule))
+			fmt.Fprintf(&sb, "  strategy:   %s\n", e.Rule.Strategy)
+		case e.Enclosing != nil:
+			fmt.Fprintf(&sb, "  decided by: %s, as part of %s\n", formatExplainedRule(e.Enclosing), e.Enclosing.Target)
+		case e.Unmapped != "":
+			fmt.Fprintf(&sb, "  unmapped:   %s\n", e.Unmapped)
+		default:
+			sb.WriteString("  unmapped:   no rule targets the field\n")
+		}
+
+		for i := range e.Shadowed {
+			fmt.Fprintf(&sb, "  overrides:  %s\n", formatExplainedRule(&e.Shadowed[i]))
+		}
+
+		if len(e.Candidates) > 0 {
+			sb.WriteString("  candidates:\n")
+
+			tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+			for _, c := range e.Candidates {
+				fmt.Fprintf(tw, "    %s\t%.2f\tname %.2f\t%s\t%s\n",
+					c.Source, c.Score, c.NameScore, c.Compatibility, c.Verdict)
+			}
+
+			_ = tw.Flush()
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// formatExplainedRule formats a rule as its origin, sources and explanation.
+func formatExplainedRule(rule *EffectiveRule) string {
+	from := "<- " + strings.Join(rule.Sources, ", ")
+	if rule.Strategy == StrategyIgnore.String() {
+		from = "(ignored)"
+	} else if len(rule.Sources) == 0 {
+		from = "(no source)"
+	}
+
+	text := rule.Origin + " " + from
+	if rule.Explanation != "" {
+		text += " (" + rule.Explanation + ")"
+	}
+
+	return text
+}
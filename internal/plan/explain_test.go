@@ -0,0 +1,159 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestExplainField(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	sourceType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "OrderID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Title", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	targetType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/target", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Title", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[targetType.ID] = targetType
+
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{
+			{
+				Source:   "source.Order",
+				Target:   "target.Order",
+				OneToOne: map[string]string{"OrderID": "ID"},
+				Fields: []mapping.FieldMapping{
+					{Source: mapping.FieldRefArray{{Path: "Title"}}, Target: mapping.FieldRefArray{{Path: "ID"}}},
+				},
+			},
+		},
+	}
+
+	resolver := NewResolver(graph, mf, DefaultConfig())
+
+	plan, err := resolver.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	explanations, err := resolver.ExplainField(plan, "target.Order.ID")
+	if err != nil {
+		t.Fatalf("ExplainField failed: %v", err)
+	}
+
+	if len(explanations) != 1 {
+		t.Fatalf("Expected 1 explanation, got %d", len(explanations))
+	}
+
+	e := explanations[0]
+	if e.Rule == nil || e.Rule.Origin != "yaml:121" || len(e.Shadowed) != 1 || e.Shadowed[0].Origin != "yaml:fields" {
+		t.Errorf("ID: expected yaml:121 overriding yaml:fields, got rule %+v, shadowed %+v", e.Rule, e.Shadowed)
+	}
+
+	for _, c := range e.Candidates {
+		if c.Source == "OrderID" && !strings.HasSuffix(c.Verdict, "used by yaml:121") {
+			t.Errorf("ID: expected OrderID to be used by yaml:121, got %q", c.Verdict)
+		}
+	}
+
+	explanations, err = resolver.ExplainField(plan, "target.Order.Title")
+	if err != nil {
+		t.Fatalf("ExplainField failed: %v", err)
+	}
+
+	e = explanations[0]
+	if e.Rule == nil || e.Rule.Origin != "auto" {
+		t.Errorf("Title: expected auto-matched rule, got %+v", e.Rule)
+	}
+
+	if len(e.Candidates) != 2 || e.Candidates[0].Source != "Title" || e.Candidates[0].Verdict != "auto-match pick" {
+		t.Errorf("Title: expected Title as auto-match pick, got %+v", e.Candidates)
+	}
+
+	if e.Candidates[1].Verdict == "" {
+		t.Errorf("Title: expected a verdict for the rejected candidate %q", e.Candidates[1].Source)
+	}
+
+	text := FormatFieldExplanations(explanations)
+	if !strings.Contains(text, "decided by: auto <- Title") || !strings.Contains(text, "auto-match pick") {
+		t.Errorf("FormatFieldExplanations() missing decision trail:\n%s", text)
+	}
+
+	if _, err := resolver.ExplainField(plan, "target.Order.Missing"); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+
+	if _, err := resolver.ExplainField(plan, "source.Order.Title"); err == nil {
+		t.Error("Expected an error for a type no pair targets")
+	}
+}
+
+func TestExplainField_IgnoreReason(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	sourceType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "test/source", Name: "Order"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: basicTypeInfo()}},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	targetType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/target", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Notes", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[targetType.ID] = targetType
+
+	ignored := mapping.IgnoreEntry{Target: "Notes", Reason: "filled by the UI", Until: "2025-06-30", Ticket: "SHOP-42"}
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{
+			{Source: "source.Order", Target: "target.Order", Ignore: []mapping.IgnoreEntry{ignored}},
+		},
+	}
+
+	resolver := NewResolver(graph, mf, DefaultConfig())
+
+	plan, err := resolver.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	explanations, err := resolver.ExplainField(plan, "target.Order.Notes")
+	if err != nil {
+		t.Fatalf("ExplainField failed: %v", err)
+	}
+
+	want := "explicitly ignored: filled by the UI (until 2025-06-30, ticket SHOP-42)"
+	if e := explanations[0]; e.Rule == nil || e.Rule.Explanation != want {
+		t.Errorf("Notes: expected explanation %q, got rule %+v", want, e.Rule)
+	}
+
+	// The metadata survives exporting the plan.
+	exported, err := ExportSuggestions(plan)
+	if err != nil {
+		t.Fatalf("ExportSuggestions failed: %v", err)
+	}
+
+	if got := exported.TypeMappings[0].Ignore; len(got) != 1 || got[0] != ignored {
+		t.Errorf("Expected exported ignore entries [%+v], got %+v", ignored, got)
+	}
+}
@@ -0,0 +1,230 @@
+package plan
+
+import (
+	"fmt"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+)
+
+// resolveFlattenDirectives adds the field mappings expanded from the flatten
+// and unflatten directives of tm. Targets already mapped by 121 or fields are
+// left to them.
+func (r *Resolver) resolveFlattenDirectives(
+	tm *mapping.TypeMapping,
+	result *ResolvedTypePair,
+	mappedTargets map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	expand := func(
+		directive string,
+		fields []string,
+		fieldsOf func(srcT, dstT *analyze.TypeInfo, field string) ([]mapping.FlatField, error),
+	) {
+		for _, field := range fields {
+			pairs, err := fieldsOf(result.SourceType, result.TargetType, field)
+			if err != nil {
+				diags.AddWarning(directive+"_error", err.Error(), typePairStr, field)
+				continue
+			}
+
+			for _, p := range pairs {
+				if mappedTargets[p.Target] {
+					continue
+				}
+
+				sp, err := mapping.ParsePath(p.Source)
+				if err != nil {
+					continue
+				}
+
+				tp, err := mapping.ParsePath(p.Target)
+				if err != nil {
+					continue
+				}
+
+				strategy, expl := r.determineStrategyWithHint(sp, tp, result.SourceType, result.TargetType, mapping.HintNone)
+
+				result.Mappings = append(result.Mappings, ResolvedFieldMapping{
+					SourcePaths: []mapping.FieldPath{sp},
+					TargetPaths: []mapping.FieldPath{tp},
+					Source:      MappingSourceYAMLFields,
+					Cardinality: mapping.CardinalityOneToOne,
+					Strategy:    strategy,
+					Confidence:  1.0,
+					Explanation: fmt.Sprintf("%s %s: 1:1 (%s)", directive, field, expl),
+					Flattened:   field,
+				})
+				mappedTargets[p.Target] = true
+			}
+		}
+	}
+
+	expand("flatten", tm.Flatten, mapping.FlattenFields)
+	expand("unflatten", tm.Unflatten, mapping.UnflattenFields)
+
+	// Unflattened structs are assigned field by field, never as a whole.
+	for _, field := range tm.Unflatten {
+		mappedTargets[field] = true
+	}
+}
+
+// autoMatchFlattened auto-matches targetField, left unmatched by name, by
+// prefix: a target field named after a source struct field and one of its
+// fields (AddressStreet from Address.Street), or a target struct field whose
+// fields are named after prefixed source fields (Address.Street from
+// AddressStreet). Only exact normalized names match. It reports whether
+// targetField was mapped.
+func (r *Resolver) autoMatchFlattened(
+	result *ResolvedTypePair,
+	targetField *analyze.FieldInfo,
+	sourceFields []analyze.FieldInfo,
+	mappedTargets map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) bool {
+	if sp, best := r.flattenedSource(result, targetField, sourceFields); best != nil {
+		result.Mappings = append(result.Mappings, r.flattenedMapping(best, sp, mapping.FieldPath{
+			Segments: []mapping.PathSegment{{Name: targetField.Name}},
+		}, "flattened"))
+		mappedTargets[targetField.Name] = true
+
+		return true
+	}
+
+	if !flattenable(targetField) {
+		return false
+	}
+
+	var (
+		mapped    []ResolvedFieldMapping
+		unmatched []*analyze.FieldInfo
+	)
+
+	for i := range targetField.Type.Fields {
+		nested := &targetField.Type.Fields[i]
+		if !nested.Exported {
+			continue
+		}
+
+		prefixed := *nested
+		prefixed.Name = targetField.Name + nested.Name
+
+		best := r.exactPick(r.rankCandidates(&prefixed, sourceFields, result), result)
+		if best == nil {
+			unmatched = append(unmatched, nested)
+			continue
+		}
+
+		mapped = append(mapped, r.flattenedMapping(best, mapping.FieldPath{
+			Segments: []mapping.PathSegment{{Name: best.SourceField.Name}},
+		}, mapping.FieldPath{
+			Segments: []mapping.PathSegment{{Name: targetField.Name}, {Name: nested.Name}},
+		}, "unflattened"))
+	}
+
+	if len(mapped) == 0 {
+		return false
+	}
+
+	result.Mappings = append(result.Mappings, mapped...)
+	mappedTargets[targetField.Name] = true
+
+	for _, nested := range unmatched {
+		path := targetField.Name + "." + nested.Name
+
+		result.UnmappedTargets = append(result.UnmappedTargets, UnmappedField{
+			TargetField: nested,
+			TargetPath: mapping.FieldPath{
+				Segments: []mapping.PathSegment{{Name: targetField.Name}, {Name: nested.Name}},
+			},
+			Reason: "no source field named " + targetField.Name + nested.Name,
+		})
+
+		diags.AddWarning("unmapped_field",
+			fmt.Sprintf("target field %q: no source field named %s", path, targetField.Name+nested.Name),
+			typePairStr, path)
+	}
+
+	return true
+}
+
+// flattenedSource returns the source path of a struct field's field whose
+// prefixed name matches targetField, with its candidate, or nil if none does.
+func (r *Resolver) flattenedSource(
+	result *ResolvedTypePair,
+	targetField *analyze.FieldInfo,
+	sourceFields []analyze.FieldInfo,
+) (mapping.FieldPath, *match.Candidate) {
+	var (
+		prefixed []analyze.FieldInfo
+		paths    = make(map[string]mapping.FieldPath)
+	)
+
+	for i := range sourceFields {
+		outer := &sourceFields[i]
+		if !flattenable(outer) {
+			continue
+		}
+
+		for _, nested := range outer.Type.Fields {
+			if !nested.Exported {
+				continue
+			}
+
+			paths[outer.Name+nested.Name] = mapping.FieldPath{
+				Segments: []mapping.PathSegment{{Name: outer.Name}, {Name: nested.Name}},
+			}
+
+			nested.Name = outer.Name + nested.Name
+			prefixed = append(prefixed, nested)
+		}
+	}
+
+	if len(prefixed) == 0 {
+		return mapping.FieldPath{}, nil
+	}
+
+	best := r.exactPick(r.rankCandidates(targetField, prefixed, result), result)
+	if best == nil {
+		return mapping.FieldPath{}, nil
+	}
+
+	return paths[best.SourceField.Name], best
+}
+
+// exactPick returns the candidate auto-matching accepts if its name matches
+// the target name exactly once normalized, or nil.
+func (r *Resolver) exactPick(candidates match.CandidateList, tp *ResolvedTypePair) *match.Candidate {
+	best := r.autoMatchPick(candidates, r.autoMatchThresholds(tp))
+	if best == nil || r.normalizer().Normalize(best.SourceField.Name) != r.normalizer().Normalize(best.TargetField.Name) {
+		return nil
+	}
+
+	return best
+}
+
+// flattenedMapping returns the auto-matched mapping of a prefix-based match.
+func (r *Resolver) flattenedMapping(best *match.Candidate, sp, tp mapping.FieldPath, how string) ResolvedFieldMapping {
+	strategy, compat := r.determineStrategyFromCandidate(best)
+
+	return ResolvedFieldMapping{
+		TargetPaths: []mapping.FieldPath{tp},
+		SourcePaths: []mapping.FieldPath{sp},
+		Source:      MappingSourceAutoMatched,
+		Cardinality: mapping.CardinalityOneToOne,
+		Strategy:    strategy,
+		Confidence:  best.CombinedScore,
+		Explanation: fmt.Sprintf("auto-matched: %s -> %s (%s, score: %.2f, %s)",
+			sp, tp, how, best.CombinedScore, compat),
+	}
+}
+
+// flattenable reports whether f is a struct field, by value and not embedded,
+// whose fields prefix-based matching may pair.
+func flattenable(f *analyze.FieldInfo) bool {
+	return !f.Embedded && f.Type != nil && f.Type.Kind == analyze.TypeKindStruct
+}
@@ -0,0 +1,153 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+// buildFlattenGraph returns a graph of a source Customer holding an Address
+// struct and a target Customer with the flattened AddressStreet and AddressCity.
+func buildFlattenGraph() *analyze.TypeGraph {
+	graph := analyze.NewTypeGraph()
+
+	address := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Address"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Street", Exported: true, Type: basicTypeInfo()},
+			{Name: "City", Exported: true, Type: basicTypeInfo()},
+			{Name: "Zip", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[address.ID] = address
+
+	nested := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Customer"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: basicTypeInfo()},
+			{Name: "Address", Exported: true, Type: address},
+		},
+	}
+	graph.Types[nested.ID] = nested
+
+	flat := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/target", Name: "Customer"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "Name", Exported: true, Type: basicTypeInfo()},
+			{Name: "AddressStreet", Exported: true, Type: basicTypeInfo()},
+			{Name: "AddressCity", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[flat.ID] = flat
+
+	return graph
+}
+
+// mappingsByTarget returns the source path and origin of each mapping by target path.
+func mappingsByTarget(tp *ResolvedTypePair) map[string]ResolvedFieldMapping {
+	got := make(map[string]ResolvedFieldMapping)
+
+	for _, m := range tp.Mappings {
+		got[m.TargetPaths[0].String()] = m
+	}
+
+	return got
+}
+
+func TestResolverFlattenDirectives(t *testing.T) {
+	graph := buildFlattenGraph()
+
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{
+			{
+				Source:   "source.Customer",
+				Target:   "target.Customer",
+				Flatten:  mapping.StringArray{"Address"},
+				OneToOne: map[string]string{"Name": "AddressCity"},
+			},
+			{
+				Source:    "target.Customer",
+				Target:    "source.Customer",
+				Unflatten: mapping.StringArray{"Address"},
+			},
+		},
+	}
+
+	plan, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	flat := mappingsByTarget(&plan.TypePairs[0])
+	if m := flat["AddressStreet"]; m.SourcePaths[0].String() != "Address.Street" || m.Flattened != "Address" {
+		t.Errorf("Expected AddressStreet <- Address.Street by flatten, got %+v", m)
+	}
+
+	if m := flat["AddressCity"]; m.Source != MappingSourceYAML121 {
+		t.Errorf("Expected the 121 mapping of AddressCity to win over flatten, got %+v", m)
+	}
+
+	nested := mappingsByTarget(&plan.TypePairs[1])
+	for tgt, src := range map[string]string{"Address.Street": "AddressStreet", "Address.City": "AddressCity"} {
+		if m := nested[tgt]; len(m.SourcePaths) == 0 || m.SourcePaths[0].String() != src || m.Flattened != "Address" {
+			t.Errorf("Expected %s <- %s by unflatten, got %+v", tgt, src, m)
+		}
+	}
+
+	if _, ok := nested["Address"]; ok {
+		t.Error("Unflattened Address must not be assigned as a whole")
+	}
+
+	exported, err := ExportSuggestions(plan)
+	if err != nil {
+		t.Fatalf("ExportSuggestions failed: %v", err)
+	}
+
+	for _, tm := range exported.TypeMappings {
+		if len(tm.Fields) != 0 {
+			t.Errorf("Expected flattened fields to be exported as directives, got fields %+v", tm.Fields)
+		}
+	}
+
+	if got := exported.TypeMappings[1].Unflatten; len(got) != 1 || got[0] != "Address" {
+		t.Errorf("Expected unflatten [Address] to be exported, got %v", got)
+	}
+}
+
+func TestResolverAutoMatchFlattened(t *testing.T) {
+	graph := buildFlattenGraph()
+
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{
+			{Source: "source.Customer", Target: "target.Customer"},
+			{Source: "target.Customer", Target: "source.Customer"},
+		},
+	}
+
+	plan, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	flat := mappingsByTarget(&plan.TypePairs[0])
+	for tgt, src := range map[string]string{"AddressStreet": "Address.Street", "AddressCity": "Address.City"} {
+		m := flat[tgt]
+		if len(m.SourcePaths) == 0 || m.SourcePaths[0].String() != src || m.Source != MappingSourceAutoMatched {
+			t.Errorf("Expected %s to be auto-matched from %s, got %+v", tgt, src, m)
+		}
+	}
+
+	nested := mappingsByTarget(&plan.TypePairs[1])
+	if m := nested["Address.Street"]; len(m.SourcePaths) == 0 || m.SourcePaths[0].String() != "AddressStreet" {
+		t.Errorf("Expected Address.Street to be auto-matched from AddressStreet, got %+v", m)
+	}
+
+	unmapped := plan.TypePairs[1].UnmappedTargets
+	if len(unmapped) != 1 || unmapped[0].TargetPath.String() != "Address.Zip" {
+		t.Errorf("Expected Address.Zip to be reported unmapped, got %+v", unmapped)
+	}
+}
@@ -1,6 +1,8 @@
 package plan
 
 import (
+	"go/types"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -144,3 +146,328 @@ func findField(fields []analyze.FieldInfo, name string) *analyze.FieldInfo {
 
 	return nil
 }
+
+func TestGenerateTarget_ExistingType(t *testing.T) {
+	yamlContent := `
+version: "1"
+mappings:
+  - source: test/source.Source
+    target: test/target.Target
+    generate_target: true
+    fields:
+      - source: ID
+        target: ID
+`
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.String]}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.Int]}
+
+	resolve := func(t *testing.T, existing *analyze.TypeInfo) (*ResolvedMappingPlan, error) {
+		t.Helper()
+
+		mf, err := mapping.Parse([]byte(yamlContent))
+		require.NoError(t, err)
+
+		graph := analyze.NewTypeGraph()
+		sourceType := &analyze.TypeInfo{
+			ID:     analyze.TypeID{PkgPath: "test/source", Name: "Source"},
+			Kind:   analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{{Name: "ID", Exported: true, Type: str}},
+		}
+		graph.Types[sourceType.ID] = sourceType
+		graph.Types[existing.ID] = existing
+
+		return NewResolver(graph, mf, DefaultConfig()).Resolve()
+	}
+
+	targetID := analyze.TypeID{PkgPath: "test/target", Name: "Target"}
+
+	t.Run("compatible shape is reused", func(t *testing.T) {
+		result, err := resolve(t, &analyze.TypeInfo{ID: targetID, Kind: analyze.TypeKindStruct, Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: str},
+			{Name: "Note", Exported: true, Type: str},
+		}})
+		require.NoError(t, err)
+		require.Len(t, result.TypePairs, 1)
+		assert.False(t, result.TypePairs[0].IsGeneratedTarget)
+
+		var codes []string
+		for _, w := range result.Diagnostics.Warnings {
+			codes = append(codes, w.Code)
+		}
+
+		assert.Contains(t, codes, "generated_target_exists")
+		assert.Contains(t, codes, "generated_target_drift")
+	})
+
+	t.Run("different shape fails", func(t *testing.T) {
+		result, err := resolve(t, &analyze.TypeInfo{ID: targetID, Kind: analyze.TypeKindStruct, Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: num},
+		}})
+		require.NoError(t, err)
+		assert.Empty(t, result.TypePairs)
+		require.Len(t, result.Diagnostics.Errors, 1)
+		assert.Contains(t, result.Diagnostics.Errors[0].Message, "field ID is int instead of string")
+		assert.Contains(t, result.Diagnostics.Errors[0].Message, "rename the target (e.g., TargetGenerated)")
+	})
+
+	t.Run("output of an earlier run is regenerated", func(t *testing.T) {
+		result, err := resolve(t, &analyze.TypeInfo{ID: targetID, Kind: analyze.TypeKindStruct, FromCaster: true})
+		require.NoError(t, err)
+		require.Len(t, result.TypePairs, 1)
+		assert.True(t, result.TypePairs[0].IsGeneratedTarget)
+		assert.NotNil(t, result.TypePairs[0].TargetType.FieldByName("ID"))
+	})
+}
+
+func TestGenerateTarget_MultipleSources(t *testing.T) {
+	yamlContent := `
+version: "1"
+mappings:
+  - source: test/source.User
+    target: test/target.View
+    generate_target: true
+    121:
+      ID: UserID
+      Name: Name
+  - source: test/source.Account
+    target: test/target.View
+    generate_target: true
+    121:
+      ID: AccountID
+      Balance: Balance
+`
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.String]}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic, GoType: types.Typ[types.Int]}
+
+	resolve := func(t *testing.T, accountName *analyze.TypeInfo, content string) *ResolvedMappingPlan {
+		t.Helper()
+
+		mf, err := mapping.Parse([]byte(content))
+		require.NoError(t, err)
+
+		graph := analyze.NewTypeGraph()
+		for _, typ := range []*analyze.TypeInfo{
+			{
+				ID:   analyze.TypeID{PkgPath: "test/source", Name: "User"},
+				Kind: analyze.TypeKindStruct,
+				Fields: []analyze.FieldInfo{
+					{Name: "ID", Exported: true, Type: num},
+					{Name: "Name", Exported: true, Type: str},
+				},
+			},
+			{
+				ID:   analyze.TypeID{PkgPath: "test/source", Name: "Account"},
+				Kind: analyze.TypeKindStruct,
+				Fields: []analyze.FieldInfo{
+					{Name: "ID", Exported: true, Type: num},
+					{Name: "Name", Exported: true, Type: accountName},
+					{Name: "Balance", Exported: true, Type: num},
+				},
+			},
+		} {
+			graph.Types[typ.ID] = typ
+		}
+
+		result, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+		require.NoError(t, err)
+
+		return result
+	}
+
+	t.Run("fields of all sources are merged", func(t *testing.T) {
+		result := resolve(t, str, yamlContent)
+		assert.Empty(t, result.Diagnostics.Errors)
+		require.Len(t, result.TypePairs, 2)
+
+		users, accounts := result.TypePairs[0], result.TypePairs[1]
+		assert.Same(t, users.TargetType, accounts.TargetType)
+		assert.True(t, accounts.IsGeneratedTarget)
+
+		var names []string
+		for _, f := range users.TargetType.Fields {
+			names = append(names, f.Name)
+		}
+
+		assert.Equal(t, []string{"UserID", "Name", "Balance", "AccountID"}, names)
+
+		// Each caster fills the fields of its source, leaving the others.
+		for _, m := range accounts.Mappings {
+			target := m.TargetPaths[0].String()
+			if target == "Name" || target == "UserID" {
+				assert.Equal(t, MappingSourceSharedTarget, m.Source, target)
+				assert.Equal(t, StrategyIgnore, m.Strategy, target)
+				assert.Equal(t, "contributed by test/source.User", m.Explanation)
+			} else {
+				assert.Equal(t, MappingSourceYAML121, m.Source, target)
+			}
+		}
+
+		assert.Empty(t, accounts.UnmappedTargets)
+	})
+
+	t.Run("fields of different types conflict", func(t *testing.T) {
+		content := yamlContent + "      Name: Name\n"
+
+		result := resolve(t, num, content)
+		require.Len(t, result.Diagnostics.Errors, 1)
+
+		conflict := result.Diagnostics.Errors[0]
+		assert.Equal(t, "generated_target_conflict", conflict.Code)
+		assert.Equal(t, "test/source.Account->test/target.View", conflict.TypePair)
+		assert.Equal(t, "Name", conflict.FieldPath)
+		assert.Contains(t, conflict.Message, "is string from test/source.User but int from test/source.Account")
+		assert.Equal(t, 10, conflict.Pos.Line)
+	})
+
+	t.Run("fields of the same type are shared", func(t *testing.T) {
+		content := yamlContent + "      Name: Name\n"
+
+		result := resolve(t, str, content)
+		assert.Empty(t, result.Diagnostics.Errors)
+		require.Len(t, result.TypePairs, 2)
+		assert.Len(t, result.TypePairs[0].TargetType.Fields, 4)
+	})
+}
+
+func TestGenerateTarget_FieldOrderAndDocs(t *testing.T) {
+	yamlContent := `
+version: "1"
+generated_types:
+  field_docs: true
+mappings:
+  - source: test/source.Order
+    target: test/target.OrderView
+    generate_target: true
+    fields:
+      - source: [Price, Tax]
+        target: Amount
+        transform: AddTax
+    121:
+      Name: Title
+      ID: Key
+`
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	num := &analyze.TypeInfo{ID: analyze.TypeID{Name: "int"}, Kind: analyze.TypeKindBasic}
+
+	fields := func(t *testing.T, order mapping.FieldOrder) []analyze.FieldInfo {
+		t.Helper()
+
+		mf, err := mapping.Parse([]byte(yamlContent))
+		require.NoError(t, err)
+
+		mf.GeneratedTypes.FieldOrder = order
+
+		graph := analyze.NewTypeGraph()
+		sourceType := &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: "test/source", Name: "Order"},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: "Name", Exported: true, Type: str},
+				{Name: "Price", Exported: true, Type: num},
+				{Name: "ID", Exported: true, Type: str},
+				{Name: "Tax", Exported: true, Type: num},
+			},
+		}
+		graph.Types[sourceType.ID] = sourceType
+
+		result, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+		require.NoError(t, err)
+		require.Len(t, result.TypePairs, 1)
+
+		return result.TypePairs[0].TargetType.Fields
+	}
+
+	names := func(fields []analyze.FieldInfo) []string {
+		var names []string
+
+		for i, f := range fields {
+			assert.Equal(t, i, f.Index)
+
+			names = append(names, f.Name)
+		}
+
+		return names
+	}
+
+	assert.Equal(t, []string{"Key", "Title", "Amount"}, names(fields(t, mapping.FieldOrderDefault)))
+	assert.Equal(t, []string{"Amount", "Title", "Key"}, names(fields(t, mapping.FieldOrderYAML)))
+	assert.Equal(t, []string{"Amount", "Key", "Title"}, names(fields(t, mapping.FieldOrderAlphabetical)))
+	assert.Equal(t, []string{"Title", "Amount", "Key"}, names(fields(t, mapping.FieldOrderSource)))
+
+	docs := make(map[string]string)
+	for _, f := range fields(t, mapping.FieldOrderDefault) {
+		docs[f.Name] = f.Doc
+	}
+
+	assert.Equal(t, map[string]string{
+		"Key":    "Key is set from source.Order.ID.",
+		"Title":  "Title is set from source.Order.Name.",
+		"Amount": "Amount is set by AddTax(source.Order.Price, source.Order.Tax).",
+	}, docs)
+}
+
+func TestGenerateTarget_FieldTags(t *testing.T) {
+	yamlContent := `
+version: "1"
+generated_types:
+  copy_tags: [json, db]
+mappings:
+  - source: test/source.Order
+    target: test/target.OrderView
+    generate_target: true
+    fields:
+      - source: Status
+        target: State
+        tags: {db: state, yaml: "-"}
+    121:
+      ID: OrderID
+      Note: Note
+`
+	mf, err := mapping.Parse([]byte(yamlContent))
+	require.NoError(t, err)
+
+	str := &analyze.TypeInfo{ID: analyze.TypeID{Name: "string"}, Kind: analyze.TypeKindBasic}
+	graph := analyze.NewTypeGraph()
+	sourceType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Order"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: str, Tag: `json:"id" db:"order_id" validate:"required"`},
+			{Name: "Status", Exported: true, Type: str, Tag: `json:"status,omitempty" db:"status"`},
+			{Name: "Note", Exported: true, Type: str},
+		},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	result, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	require.NoError(t, err)
+	require.Len(t, result.TypePairs, 1)
+
+	tags := make(map[string]reflect.StructTag)
+	for _, f := range result.TypePairs[0].TargetType.Fields {
+		tags[f.Name] = f.Tag
+	}
+
+	assert.Equal(t, map[string]reflect.StructTag{
+		"OrderID": `json:"id" db:"order_id"`,
+		"Note":    "",
+		"State":   `json:"status,omitempty" db:"state" yaml:"-"`,
+	}, tags)
+
+	// The tags are kept by the exported mapping.
+	exported, err := ExportSuggestions(result)
+	require.NoError(t, err)
+	require.Len(t, exported.TypeMappings, 1)
+	assert.Equal(t, []string{"json", "db"}, exported.GeneratedTypes.CopyTags)
+
+	var state *mapping.FieldMapping
+
+	for i, fm := range exported.TypeMappings[0].Fields {
+		if fm.Target.First() == "State" {
+			state = &exported.TypeMappings[0].Fields[i]
+		}
+	}
+
+	require.NotNil(t, state)
+	assert.Equal(t, map[string]string{"db": "state", "yaml": "-"}, state.Tags)
+}
@@ -0,0 +1,259 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/common"
+)
+
+// Graph formats of FormatGraph.
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// Graph is the dependency graph of a plan: its casters, the nested casters
+// they call and the transforms they use.
+type Graph struct {
+	// Casters are the type pairs of the plan, top-level ones first, then
+	// nested ones in the order they are reached.
+	Casters []GraphCaster
+	// Transforms are the names of the transforms the casters use, sorted.
+	Transforms []string
+	// Edges are the calls of the casters, in the order of Casters.
+	Edges []GraphEdge
+}
+
+// GraphCaster is a type pair of a Graph.
+type GraphCaster struct {
+	// Key is the pair as "source->target" full type IDs.
+	Key string
+	// Source and Target are the type names qualified by package name.
+	Source string
+	Target string
+	// TopLevel is true for the pairs of the mapping, false for nested ones.
+	TopLevel bool
+}
+
+// GraphEdge is a call of a caster to a nested caster or to a transform.
+type GraphEdge struct {
+	// From is the key of the calling caster.
+	From string
+	// To is the key of the nested caster, or the name of the transform.
+	To        string
+	Transform bool
+	// Fields are the target field paths assigned through the call, sorted.
+	Fields []string
+}
+
+// BuildGraph returns the dependency graph of a plan.
+func BuildGraph(p *ResolvedMappingPlan) *Graph {
+	g := &Graph{}
+	casters := make(map[string]bool)
+	transforms := make(map[string]bool)
+	edges := make(map[string]int)
+
+	addCaster := func(src, tgt *analyze.TypeInfo, topLevel bool) string {
+		key := fmt.Sprintf("%s->%s", src.ID, tgt.ID)
+		if !casters[key] {
+			casters[key] = true
+			g.Casters = append(g.Casters, GraphCaster{
+				Key: key, Source: shortTypeName(src.ID, p.TypeGraph), Target: shortTypeName(tgt.ID, p.TypeGraph), TopLevel: topLevel,
+			})
+		}
+
+		return key
+	}
+
+	addEdge := func(from, to string, transform bool, fields ...string) {
+		key := fmt.Sprintf("%s|%s|%t", from, to, transform)
+
+		i, ok := edges[key]
+		if !ok {
+			i = len(g.Edges)
+			edges[key] = i
+			g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Transform: transform})
+		}
+
+		for _, f := range fields {
+			if !slices.Contains(g.Edges[i].Fields, f) {
+				g.Edges[i].Fields = append(g.Edges[i].Fields, f)
+			}
+		}
+
+		slices.Sort(g.Edges[i].Fields)
+	}
+
+	for i := range p.TypePairs {
+		addCaster(p.TypePairs[i].SourceType, p.TypePairs[i].TargetType, true)
+	}
+
+	forEachResolvedPair(p, func(tp *ResolvedTypePair) {
+		from := addCaster(tp.SourceType, tp.TargetType, false)
+
+		for _, nc := range tp.NestedPairs {
+			if nc.SourceType == nil || nc.TargetType == nil {
+				continue
+			}
+
+			fields := make([]string, 0, len(nc.ReferencedBy))
+			for _, ref := range nc.ReferencedBy {
+				fields = append(fields, ref.String())
+			}
+
+			addEdge(from, addCaster(nc.SourceType, nc.TargetType, false), false, fields...)
+		}
+
+		for _, m := range tp.Mappings {
+			if m.Transform == "" {
+				continue
+			}
+
+			transforms[m.Transform] = true
+
+			fields := make([]string, 0, len(m.TargetPaths))
+			for _, path := range m.TargetPaths {
+				fields = append(fields, path.String())
+			}
+
+			addEdge(from, m.Transform, true, fields...)
+		}
+	})
+
+	for name := range transforms {
+		g.Transforms = append(g.Transforms, name)
+	}
+
+	slices.Sort(g.Transforms)
+
+	return g
+}
+
+// FormatGraph renders a graph in the DOT language of Graphviz or as a
+// Mermaid flowchart. Casters are boxes, bold for the top-level ones, and
+// transforms are ellipses; edges are labeled with the target fields they assign.
+func FormatGraph(g *Graph, format string) (string, error) {
+	ids := make(map[string]string)
+	for i, c := range g.Casters {
+		ids[c.Key] = fmt.Sprintf("n%d", i)
+	}
+
+	transformIDs := make(map[string]string)
+	for i, name := range g.Transforms {
+		transformIDs[name] = fmt.Sprintf("t%d", i)
+	}
+
+	target := func(e GraphEdge) string {
+		if e.Transform {
+			return transformIDs[e.To]
+		}
+
+		return ids[e.To]
+	}
+
+	var sb strings.Builder
+
+	switch format {
+	case GraphDOT:
+		sb.WriteString("digraph casters {\n\trankdir=LR;\n\tnode [shape=box];\n")
+
+		for _, c := range g.Casters {
+			style := ""
+			if c.TopLevel {
+				style = ", style=bold"
+			}
+
+			fmt.Fprintf(&sb, "\t%s [label=%s%s];\n", ids[c.Key], strconv.Quote(c.Source+" -> "+c.Target), style)
+		}
+
+		for _, name := range g.Transforms {
+			fmt.Fprintf(&sb, "\t%s [label=%s, shape=ellipse];\n", transformIDs[name], strconv.Quote(name))
+		}
+
+		for _, e := range g.Edges {
+			var attrs []string
+			if len(e.Fields) > 0 {
+				attrs = append(attrs, "label="+strconv.Quote(strings.Join(e.Fields, ", ")))
+			}
+
+			if e.Transform {
+				attrs = append(attrs, "style=dashed")
+			}
+
+			fmt.Fprintf(&sb, "\t%s -> %s", ids[e.From], target(e))
+
+			if len(attrs) > 0 {
+				fmt.Fprintf(&sb, " [%s]", strings.Join(attrs, ", "))
+			}
+
+			sb.WriteString(";\n")
+		}
+
+		sb.WriteString("}\n")
+
+	case GraphMermaid:
+		sb.WriteString("flowchart LR\n")
+
+		var topLevel []string
+
+		for _, c := range g.Casters {
+			fmt.Fprintf(&sb, "\t%s[%s]\n", ids[c.Key], mermaidText(c.Source+" -> "+c.Target))
+
+			if c.TopLevel {
+				topLevel = append(topLevel, ids[c.Key])
+			}
+		}
+
+		for _, name := range g.Transforms {
+			fmt.Fprintf(&sb, "\t%s([%s])\n", transformIDs[name], mermaidText(name))
+		}
+
+		for _, e := range g.Edges {
+			arrow := "-->"
+			if e.Transform {
+				arrow = "-.->"
+			}
+
+			if len(e.Fields) > 0 {
+				arrow += "|" + mermaidText(strings.Join(e.Fields, ", ")) + "|"
+			}
+
+			fmt.Fprintf(&sb, "\t%s %s %s\n", ids[e.From], arrow, target(e))
+		}
+
+		if len(topLevel) > 0 {
+			fmt.Fprintf(&sb, "\tclassDef topLevel stroke-width:3px\n\tclass %s topLevel\n", strings.Join(topLevel, ","))
+		}
+
+	default:
+		return "", fmt.Errorf("unknown graph format %q (expected %s or %s)", format, GraphDOT, GraphMermaid)
+	}
+
+	return sb.String(), nil
+}
+
+// mermaidText quotes s as Mermaid node or edge text.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// shortTypeName returns the name of a type qualified by its package name
+// (e.g., store.Order), or its last path element if graph doesn't hold it.
+func shortTypeName(id analyze.TypeID, graph *analyze.TypeGraph) string {
+	short := analyze.TypeID{Name: id.Name, Args: id.Args, Literal: id.Literal}.String()
+	if id.PkgPath == "" {
+		return short
+	}
+
+	if graph != nil {
+		if pkg, ok := graph.Packages[id.PkgPath]; ok && pkg.Name != "" {
+			return pkg.Name + "." + short
+		}
+	}
+
+	return common.PkgAlias(id.PkgPath) + "." + short
+}
@@ -0,0 +1,90 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestFormatGraph(t *testing.T) {
+	newStruct := func(pkg, name string) *analyze.TypeInfo {
+		return &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct}
+	}
+	path := func(s string) mapping.FieldPath {
+		fp, err := mapping.ParsePath(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return fp
+	}
+
+	srcItem, dstItem := newStruct("example.com/store", "Item"), newStruct("example.com/api", "Item")
+	itemPair := &ResolvedTypePair{
+		SourceType: srcItem,
+		TargetType: dstItem,
+		Mappings: []ResolvedFieldMapping{
+			{TargetPaths: []mapping.FieldPath{path("Price")}, Transform: "CentsToDollars"},
+		},
+	}
+
+	p := &ResolvedMappingPlan{
+		TypePairs: []ResolvedTypePair{{
+			SourceType: newStruct("example.com/store", "Order"),
+			TargetType: newStruct("example.com/api", "Order"),
+			Mappings: []ResolvedFieldMapping{
+				{TargetPaths: []mapping.FieldPath{path("Total")}, Transform: "CentsToDollars"},
+			},
+			NestedPairs: []NestedConversion{
+				{SourceType: srcItem, TargetType: dstItem, ReferencedBy: []mapping.FieldPath{path("Items[]")}, ResolvedPair: itemPair},
+				{SourceType: srcItem, TargetType: dstItem, ReferencedBy: []mapping.FieldPath{path("Featured")}, ResolvedPair: itemPair},
+			},
+		}},
+	}
+
+	g := BuildGraph(p)
+
+	dot, err := FormatGraph(g, GraphDOT)
+	if err != nil {
+		t.Fatalf("FormatGraph failed: %v", err)
+	}
+
+	wantDOT := `digraph casters {
+	rankdir=LR;
+	node [shape=box];
+	n0 [label="store.Order -> api.Order", style=bold];
+	n1 [label="store.Item -> api.Item"];
+	t0 [label="CentsToDollars", shape=ellipse];
+	n0 -> n1 [label="Featured, Items[]"];
+	n0 -> t0 [label="Total", style=dashed];
+	n1 -> t0 [label="Price", style=dashed];
+}
+`
+	if dot != wantDOT {
+		t.Errorf("DOT graph =\n%s\nwant\n%s", dot, wantDOT)
+	}
+
+	mermaid, err := FormatGraph(g, GraphMermaid)
+	if err != nil {
+		t.Fatalf("FormatGraph failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"flowchart LR\n",
+		"\tn0[\"store.Order -> api.Order\"]\n",
+		"\tt0([\"CentsToDollars\"])\n",
+		"\tn0 -->|\"Featured, Items[]\"| n1\n",
+		"\tn1 -.->|\"Price\"| t0\n",
+		"\tclass n0 topLevel\n",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid graph misses %q:\n%s", want, mermaid)
+		}
+	}
+
+	if _, err := FormatGraph(g, "svg"); err == nil {
+		t.Error("FormatGraph should reject unknown formats")
+	}
+}
@@ -0,0 +1,149 @@
+package plan
+
+import (
+	"go/types"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+// groupRule describes a common way a target field combines several source
+// fields, which auto-matching never proposes on its own since it only pairs
+// fields one to one.
+type groupRule struct {
+	// targets are the suffixes of the target field names the rule applies to,
+	// longest first. The rest of the name prefixes every part (e.g., Customer
+	// in CustomerName groups CustomerFirstName and CustomerLastName).
+	targets []string
+
+	// parts are the source fields of the group, in transform argument order.
+	parts []groupPart
+
+	// text limits the rule to string targets.
+	text bool
+}
+
+// groupPart is a source field of a group rule, matched by any of its names.
+type groupPart struct {
+	names    []string
+	optional bool
+}
+
+// groupRules are the many-to-one patterns suggested for unmapped targets.
+var groupRules = []groupRule{
+	{ // FirstName + LastName -> FullName
+		targets: []string{"FullName", "Name"},
+		parts: []groupPart{
+			{names: []string{"FirstName", "GivenName"}},
+			{names: []string{"MiddleName"}, optional: true},
+			{names: []string{"LastName", "FamilyName", "Surname"}},
+		},
+		text: true,
+	},
+	{ // Street + City + Zip -> Address
+		targets: []string{"FullAddress", "Address"},
+		parts: []groupPart{
+			{names: []string{"Street", "StreetAddress"}},
+			{names: []string{"City"}},
+			{names: []string{"State", "Region"}, optional: true},
+			{names: []string{"Zip", "ZipCode", "PostalCode", "Postcode"}},
+			{names: []string{"Country"}, optional: true},
+		},
+		text: true,
+	},
+	{ // Amount + Currency -> Money
+		targets: []string{"Money", ""},
+		parts: []groupPart{
+			{names: []string{"Amount"}},
+			{names: []string{"Currency", "CurrencyCode"}},
+		},
+	},
+}
+
+// suggestGroup returns the source fields a group rule suggests combining into
+// targetField with a transform, or nil if no rule matches.
+func suggestGroup(targetField *analyze.FieldInfo, sourceFields []analyze.FieldInfo) []mapping.FieldPath {
+	for _, rule := range groupRules {
+		if rule.text && !isText(targetField.Type) {
+			continue
+		}
+
+		for _, suffix := range rule.targets {
+			prefix, ok := cutSuffixFold(targetField.Name, suffix)
+			if !ok {
+				continue
+			}
+
+			if group := rule.match(prefix, sourceFields); group != nil {
+				return group
+			}
+		}
+	}
+
+	return nil
+}
+
+// match returns the paths of the source fields named prefix followed by a
+// name of each part, or nil if a required part has no such field.
+func (rule *groupRule) match(prefix string, sourceFields []analyze.FieldInfo) []mapping.FieldPath {
+	var group []mapping.FieldPath
+
+	for _, part := range rule.parts {
+		name := findGroupPart(prefix, part.names, sourceFields)
+
+		switch {
+		case name != "":
+			group = append(group, mapping.FieldPath{Segments: []mapping.PathSegment{{Name: name}}})
+		case !part.optional:
+			return nil
+		}
+	}
+
+	return group
+}
+
+// findGroupPart returns the name of the first source field named prefix
+// followed by one of names, ignoring case, or "".
+func findGroupPart(prefix string, names []string, sourceFields []analyze.FieldInfo) string {
+	for _, name := range names {
+		for i := range sourceFields {
+			if strings.EqualFold(sourceFields[i].Name, prefix+name) {
+				return sourceFields[i].Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// cutSuffixFold is strings.CutSuffix ignoring case. It also requires the
+// remaining prefix to end a word, so FullName cuts Name but Surname doesn't.
+func cutSuffixFold(s, suffix string) (string, bool) {
+	if len(suffix) > len(s) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return "", false
+	}
+
+	prefix := s[:len(s)-len(suffix)]
+	if suffix != "" && prefix != "" && !isUpper(s[len(prefix)]) {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+// isUpper reports whether b is an ASCII upper-case letter.
+func isUpper(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+// isText reports whether t has an underlying string type.
+func isText(t *analyze.TypeInfo) bool {
+	if t == nil || t.GoType == nil {
+		return false
+	}
+
+	basic, ok := t.GoType.Underlying().(*types.Basic)
+
+	return ok && basic.Info()&types.IsString != 0
+}
@@ -0,0 +1,200 @@
+package plan
+
+import (
+	"fmt"
+	"go/types"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+)
+
+// InterfaceCase is one branch of the type switch converting an interface-typed
+// field: a registered implementation pair dispatched to its caster.
+type InterfaceCase struct {
+	// Source is the concrete source type (e.g., store.CardPayment).
+	Source *analyze.TypeInfo
+	// Target is the concrete target type (e.g., warehouse.CardPayment).
+	Target *analyze.TypeInfo
+	// SourcePointer is true when the case matches *Source rather than Source.
+	SourcePointer bool
+	// TargetPointer is true when the target field receives &Target.
+	TargetPointer bool
+}
+
+// FindInterfaceCases returns the type switch cases converting an interface-typed
+// src into tgt through the registered implementations, or nil if none applies.
+// The target may be an interface, or a concrete struct (or pointer to it).
+func FindInterfaceCases(
+	impls []mapping.ImplementationDef,
+	graph *analyze.TypeGraph,
+	src, tgt *analyze.TypeInfo,
+) []InterfaceCase {
+	if src == nil || tgt == nil || !src.IsInterface() || tgt.GoType == nil {
+		return nil
+	}
+
+	// Assignable interfaces need no dispatch.
+	if types.AssignableTo(src.GoType, tgt.GoType) {
+		return nil
+	}
+
+	srcIface, _ := src.GoType.Underlying().(*types.Interface)
+
+	var cases []InterfaceCase
+
+	for _, impl := range impls {
+		implSrc := mapping.ResolveTypeID(impl.Source, graph)
+		implTgt := mapping.ResolveTypeID(impl.Target, graph)
+
+		if implSrc == nil || implTgt == nil || implSrc.GoType == nil || implTgt.GoType == nil {
+			continue
+		}
+
+		tgtPtr, ok := implementationTarget(implTgt, tgt)
+		if !ok {
+			continue
+		}
+
+		if types.Implements(implSrc.GoType, srcIface) {
+			cases = append(cases, InterfaceCase{Source: implSrc, Target: implTgt, TargetPointer: tgtPtr})
+		}
+
+		if types.Implements(types.NewPointer(implSrc.GoType), srcIface) {
+			cases = append(cases, InterfaceCase{
+				Source: implSrc, Target: implTgt, SourcePointer: true, TargetPointer: tgtPtr,
+			})
+		}
+	}
+
+	return cases
+}
+
+// implementationTarget reports whether a converted implTgt value can be stored
+// in a field of type tgt, and whether it has to be stored by address.
+func implementationTarget(implTgt, tgt *analyze.TypeInfo) (bool, bool) {
+	switch {
+	case tgt.IsInterface():
+		tgtIface, _ := tgt.GoType.Underlying().(*types.Interface)
+
+		if types.Implements(implTgt.GoType, tgtIface) {
+			return false, true
+		}
+
+		if types.Implements(types.NewPointer(implTgt.GoType), tgtIface) {
+			return true, true
+		}
+
+		return false, false
+	case tgt.Kind == analyze.TypeKindPointer && tgt.ElemType != nil:
+		return true, sameType(implTgt, tgt.ElemType)
+	default:
+		return false, sameType(implTgt, tgt)
+	}
+}
+
+// explainInterfaceCases returns a short description used in mapping explanations.
+func explainInterfaceCases(cases []InterfaceCase) string {
+	return fmt.Sprintf("interface switch (%d cases)", len(cases))
+}
+
+// interfaceCases returns the type switch cases for src->tgt using the
+// implementations of the mapping file.
+func (r *Resolver) interfaceCases(src, tgt *analyze.TypeInfo) []InterfaceCase {
+	if r.mappingDef == nil {
+		return nil
+	}
+
+	return FindInterfaceCases(r.mappingDef.Implementations, r.graph, src, tgt)
+}
+
+// overrideCompat lets auto-matching rank conversions planned without transforms
+// (wrappers, interface implementations, String()/parse methods) as transformable
+// and sql.Null* conversions as convertible instead of incompatible, and defined
+// type conversions left to explicit rules as incompatible.
+func (r *Resolver) overrideCompat(src, tgt *analyze.TypeInfo) (match.TypeCompatibilityResult, bool) {
+	if compat, ok := r.definedTypeCompat(src, tgt); ok {
+		return compat, true
+	}
+
+	if compat, ok := r.wrapperCompat(src, tgt); ok {
+		return compat, true
+	}
+
+	if compat, ok := r.stringCompat(src, tgt); ok {
+		return compat, true
+	}
+
+	if compat, ok := sqlNullCompat(src, tgt); ok {
+		return compat, true
+	}
+
+	cases := r.interfaceCases(src, tgt)
+	if len(cases) == 0 {
+		return match.TypeCompatibilityResult{}, false
+	}
+
+	return match.TypeCompatibilityResult{
+		Compatibility: match.TypeNeedsTransform,
+		Reason:        explainInterfaceCases(cases),
+	}, true
+}
+
+// reasonExplicitDefinedType is the compatibility reason of defined type
+// conversions left to explicit rules.
+const reasonExplicitDefinedType = match.ReasonDefinedTypeConversion +
+	" needing an explicit rule (match.explicit_defined_types)"
+
+// unapprovedDefinedType returns the candidate of the same name as the target
+// whose defined type conversion is left to explicit rules, or nil.
+func unapprovedDefinedType(candidates match.CandidateList) *match.Candidate {
+	for i := range candidates {
+		if candidates[i].NameScore >= 1 && candidates[i].TypeCompat.Reason == reasonExplicitDefinedType {
+			return &candidates[i]
+		}
+	}
+
+	return nil
+}
+
+// definedTypeCompat ranks defined type conversions (e.g., UserID and int64) as
+// incompatible when match.explicit_defined_types leaves them to explicit rules.
+func (r *Resolver) definedTypeCompat(src, tgt *analyze.TypeInfo) (match.TypeCompatibilityResult, bool) {
+	if r.mappingDef == nil || !r.mappingDef.Match.ExplicitDefinedTypes ||
+		src == nil || tgt == nil || src.GoType == nil || tgt.GoType == nil ||
+		!match.IsDefinedTypeConversion(src.GoType, tgt.GoType) {
+		return match.TypeCompatibilityResult{}, false
+	}
+
+	return match.TypeCompatibilityResult{
+		Compatibility: match.TypeIncompatible,
+		Reason:        reasonExplicitDefinedType,
+		SourceType:    src.GoType.String(),
+		TargetType:    tgt.GoType.String(),
+	}, true
+}
+
+// addInterfaceNestedConversions records the casters called by an interface
+// switch as nested conversions, so they are resolved and ctx is propagated.
+func (r *Resolver) addInterfaceNestedConversions(
+	m *ResolvedFieldMapping,
+	result *ResolvedTypePair,
+	nestedMap map[string]*NestedConversion,
+) {
+	sourceFieldType := r.resolveFieldType(m.SourcePaths[0], result.SourceType)
+	targetFieldType := r.resolveFieldType(m.TargetPaths[0], result.TargetType)
+
+	for _, c := range r.interfaceCases(sourceFieldType, targetFieldType) {
+		key := fmt.Sprintf("%s->%s", c.Source.ID, c.Target.ID)
+		if existing, ok := nestedMap[key]; ok {
+			existing.ReferencedBy = append(existing.ReferencedBy, m.TargetPaths[0])
+			continue
+		}
+
+		nestedMap[key] = &NestedConversion{
+			SourceType:   c.Source,
+			TargetType:   c.Target,
+			ReferencedBy: []mapping.FieldPath{m.TargetPaths[0]},
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package plan
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+const paymentsSrc = `package payments
+
+type Payment interface{ Amount() int }
+
+type Card struct{ Cents int }
+
+func (c Card) Amount() int { return c.Cents }
+
+type Wire struct{ Cents int }
+
+func (w *Wire) Amount() int { return w.Cents }
+
+type PaymentDTO interface{ isPayment() }
+
+type CardDTO struct{ Cents int }
+
+func (CardDTO) isPayment() {}
+
+type WireDTO struct{ Cents int }
+
+func (*WireDTO) isPayment() {}
+`
+
+// checkPackage type-checks src and registers its named types and functions in a graph.
+func checkPackage(t *testing.T, pkgPath, src string) *analyze.TypeGraph {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "src.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	pkg, err := new(types.Config).Check(pkgPath, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("type-check: %v", err)
+	}
+
+	graph := analyze.NewTypeGraph()
+
+	for _, name := range pkg.Scope().Names() {
+		obj := pkg.Scope().Lookup(name)
+		id := analyze.TypeID{PkgPath: pkg.Path(), Name: name}
+
+		if fn, ok := obj.(*types.Func); ok {
+			graph.Funcs[id] = &analyze.FuncInfo{ID: id, Signature: fn.Type().(*types.Signature)}
+			continue
+		}
+
+		kind := analyze.TypeKindAlias
+		if _, ok := obj.Type().Underlying().(*types.Struct); ok {
+			kind = analyze.TypeKindStruct
+		}
+
+		graph.Types[id] = &analyze.TypeInfo{ID: id, Kind: kind, GoType: obj.Type()}
+	}
+
+	return graph
+}
+
+func TestFindInterfaceCases(t *testing.T) {
+	graph := checkPackage(t, "example.com/payments", paymentsSrc)
+	typ := func(name string) *analyze.TypeInfo {
+		return graph.Types[analyze.TypeID{PkgPath: "example.com/payments", Name: name}]
+	}
+	ptr := func(elem *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: elem, GoType: types.NewPointer(elem.GoType)}
+	}
+
+	impls := []mapping.ImplementationDef{
+		{Source: "payments.Card", Target: "payments.CardDTO"},
+		{Source: "payments.Wire", Target: "payments.WireDTO"},
+	}
+
+	type caseSummary struct {
+		source         string
+		srcPtr, tgtPtr bool
+	}
+
+	tests := []struct {
+		name     string
+		src, tgt *analyze.TypeInfo
+		want     []caseSummary
+	}{
+		{
+			name: "interface to interface",
+			src:  typ("Payment"),
+			tgt:  typ("PaymentDTO"),
+			want: []caseSummary{{"Card", false, false}, {"Card", true, false}, {"Wire", true, true}},
+		},
+		{
+			name: "interface to struct",
+			src:  typ("Payment"),
+			tgt:  typ("CardDTO"),
+			want: []caseSummary{{"Card", false, false}, {"Card", true, false}},
+		},
+		{
+			name: "interface to struct pointer",
+			src:  typ("Payment"),
+			tgt:  ptr(typ("WireDTO")),
+			want: []caseSummary{{"Wire", true, true}},
+		},
+		{name: "assignable interface", src: typ("Payment"), tgt: typ("Payment")},
+		{name: "struct source", src: typ("Card"), tgt: typ("PaymentDTO")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []caseSummary
+			for _, c := range FindInterfaceCases(impls, graph, tt.src, tt.tgt) {
+				got = append(got, caseSummary{c.Source.ID.Name, c.SourcePointer, c.TargetPointer})
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("FindInterfaceCases() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("case %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package plan
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"caster-generator/internal/mapping"
+)
+
+// ExportLocked exports a resolved plan as a fully explicit mapping file.
+// Every auto-matched field is frozen into a 121 or fields entry and every unmapped
+// target is ignored, so resolving the result never relies on fuzzy matching and
+// generated code doesn't change when the matcher does.
+func ExportLocked(plan *ResolvedMappingPlan) (*mapping.MappingFile, error) {
+	mf, err := ExportSuggestions(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range mf.TypeMappings {
+		lockTypeMapping(&mf.TypeMappings[i])
+	}
+
+	return mf, nil
+}
+
+// ExportLockedYAML exports a resolved plan as a fully explicit mapping YAML.
+// Unlike suggestions, transforms are written with their complete definitions.
+func ExportLockedYAML(plan *ResolvedMappingPlan) ([]byte, error) {
+	mf, err := ExportLocked(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "version"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: mf.Version},
+	)
+
+	if mf.CopyMode != mapping.CopyDefault {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "copy_mode"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(mf.CopyMode)},
+		)
+	}
+
+	root.Content = appendFlowList(root.Content, "ignore_tags", mf.IgnoreTags)
+	root.Content = appendFlowList(root.Content, "ignore_patterns", mf.IgnorePatterns)
+	root.Content = appendFlowList(root.Content, "strip_affixes", mf.StripAffixes)
+
+	root.Content, err = appendEncoded(root.Content, "match", mf.Match, matchOptionCount(&mf.Match))
+	if err != nil {
+		return nil, err
+	}
+
+	if mf.OnIncompatiblePin != mapping.PinDefault {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "on_incompatible_pin"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(mf.OnIncompatiblePin)},
+		)
+	}
+
+	if !mf.Policy.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "policy", mf.Policy, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !mf.Currency.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "currency", mf.Currency, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !mf.GeneratedTypes.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "generated_types", mf.GeneratedTypes, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mappingsValue := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for i := range mf.TypeMappings {
+		tmNode := buildTypeMappingNode(&mf.TypeMappings[i], nil, ExportConfig{})
+		mappingsValue.Content = append(mappingsValue.Content, tmNode)
+	}
+
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "mappings"}, mappingsValue)
+
+	root.Content, err = appendEncoded(root.Content, "transforms", mf.Transforms, len(mf.Transforms))
+	if err != nil {
+		return nil, err
+	}
+
+	root.Content, err = appendEncoded(root.Content, "wrappers", mf.Wrappers, len(mf.Wrappers))
+	if err != nil {
+		return nil, err
+	}
+
+	root.Content, err = appendEncoded(root.Content, "implementations", mf.Implementations, len(mf.Implementations))
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(root)
+}
+
+// lockTypeMapping moves auto-matched fields into the explicit sections: plain
+// renames become 121 entries, anything carrying hints or options becomes a fields
+// entry. So does a rename whose source is already a 121 key, which it would
+// otherwise replace.
+func lockTypeMapping(tm *mapping.TypeMapping) {
+	for _, fm := range tm.Auto {
+		if fm.IsPlain() {
+			if _, taken := tm.OneToOne[fm.Source[0].Path]; !taken {
+				tm.OneToOne[fm.Source[0].Path] = fm.Target[0].Path
+				continue
+			}
+		}
+
+		tm.Fields = append(tm.Fields, fm)
+	}
+
+	tm.Auto = nil
+}
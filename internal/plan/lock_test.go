@@ -0,0 +1,178 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestExportLockedYAML(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	sourceType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "Product"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Name", Exported: true, Type: basicTypeInfo()},
+			{Name: "Price", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	targetType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/target", Name: "Item"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "ID", Exported: true, Type: basicTypeInfo()},
+			{Name: "Name", Exported: true, Type: basicTypeInfo()},
+			{Name: "Cost", Exported: true, Type: basicTypeInfo()},
+			{Name: "Label", Exported: true, Type: basicTypeInfo()},
+			{Name: "Secret", Exported: true, Type: basicTypeInfo(), Tag: `json:"-"`},
+		},
+	}
+	graph.Types[targetType.ID] = targetType
+
+	mf := &mapping.MappingFile{
+		CopyMode:     mapping.CopyDeep,
+		IgnoreTags:   []string{"json"},
+		StripAffixes: []string{"DTO"},
+		Match:        mapping.MatchOptions{Synonyms: map[string][]string{"Amount": {"Cost"}}},
+		TypeMappings: []mapping.TypeMapping{
+			{
+				Source:   "source.Product",
+				Target:   "target.Item",
+				CopyMode: mapping.CopyAlias,
+				Fields: []mapping.FieldMapping{
+					{
+						Source:    mapping.FieldRefArray{{Path: "Price"}},
+						Target:    mapping.FieldRefArray{{Path: "Cost"}},
+						Transform: "PriceToCost",
+					},
+				},
+			},
+		},
+		Transforms: []mapping.TransformDef{
+			{Name: "PriceToCost", SourceType: "string", TargetType: "string", Memoize: true},
+		},
+	}
+
+	resolved, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	data, err := ExportLockedYAML(resolved)
+	if err != nil {
+		t.Fatalf("ExportLockedYAML failed: %v", err)
+	}
+
+	locked, err := mapping.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse locked mapping failed: %v\n%s", err, data)
+	}
+
+	if locked.CopyMode != mapping.CopyDeep {
+		t.Errorf("Expected file copy_mode deep, got %q", locked.CopyMode)
+	}
+
+	if len(locked.IgnoreTags) != 1 || locked.IgnoreTags[0] != "json" {
+		t.Errorf("Expected ignore_tags [json], got %v", locked.IgnoreTags)
+	}
+
+	if len(locked.StripAffixes) != 1 || locked.StripAffixes[0] != "DTO" {
+		t.Errorf("Expected strip_affixes [DTO], got %v", locked.StripAffixes)
+	}
+
+	if got := locked.Match.Synonyms["Amount"]; len(got) != 1 || got[0] != "Cost" {
+		t.Errorf("Expected synonyms {Amount: [Cost]}, got %v", locked.Match.Synonyms)
+	}
+
+	tm := locked.TypeMappings[0]
+	if tm.CopyMode != mapping.CopyAlias {
+		t.Errorf("Expected mapping copy_mode alias, got %q", tm.CopyMode)
+	}
+
+	if len(tm.Auto) != 0 {
+		t.Errorf("Expected no auto entries, got %d", len(tm.Auto))
+	}
+
+	if tm.OneToOne["ID"] != "ID" || tm.OneToOne["Name"] != "Name" {
+		t.Errorf("Expected auto matches frozen into 121, got %v", tm.OneToOne)
+	}
+
+	if len(tm.Ignore) != 1 || tm.Ignore[0].Target != "Label" {
+		t.Errorf("Expected unmapped Label to be ignored, got %v", tm.Ignore)
+	}
+
+	if len(locked.Transforms) != 1 || !locked.Transforms[0].Memoize || locked.Transforms[0].SourceType != "string" {
+		t.Errorf("Expected transform definition to be preserved, got %+v", locked.Transforms)
+	}
+
+	if strings.Contains(string(data), "confidence=") {
+		t.Errorf("Locked mapping must not carry match comments:\n%s", data)
+	}
+
+	// Resolving the lock must not need any fuzzy matching.
+	relocked, err := NewResolver(graph, locked, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve locked mapping failed: %v", err)
+	}
+
+	for _, m := range relocked.TypePairs[0].Mappings {
+		if m.Source == MappingSourceAutoMatched {
+			t.Errorf("Unexpected auto-matched field %s in locked plan", m.TargetPaths[0])
+		}
+	}
+
+	if len(relocked.TypePairs[0].UnmappedTargets) != 0 {
+		t.Errorf("Expected no unmapped targets, got %+v", relocked.TypePairs[0].UnmappedTargets)
+	}
+}
+
+func TestLockTypeMapping_SharedSource(t *testing.T) {
+	tm := &mapping.TypeMapping{
+		OneToOne: map[string]string{"Name": "Name"},
+		Auto: []mapping.FieldMapping{
+			{Source: mapping.FieldRefArray{{Path: "Name"}}, Target: mapping.FieldRefArray{{Path: "Label"}}},
+			{Source: mapping.FieldRefArray{{Path: "ID"}}, Target: mapping.FieldRefArray{{Path: "ID"}}},
+		},
+	}
+
+	lockTypeMapping(tm)
+
+	// The explicit entry is kept, and the auto match sharing its source is a fields entry.
+	if len(tm.OneToOne) != 2 || tm.OneToOne["Name"] != "Name" || tm.OneToOne["ID"] != "ID" {
+		t.Errorf("Expected 121 {Name: Name, ID: ID}, got %v", tm.OneToOne)
+	}
+
+	if len(tm.Fields) != 1 || tm.Fields[0].Source.First() != "Name" || tm.Fields[0].Target.First() != "Label" {
+		t.Errorf("Expected fields entry Name -> Label, got %+v", tm.Fields)
+	}
+
+	if len(tm.Auto) != 0 {
+		t.Errorf("Expected no auto entries, got %d", len(tm.Auto))
+	}
+}
+
+func TestLockTypeMapping_Options(t *testing.T) {
+	tm := &mapping.TypeMapping{
+		OneToOne: map[string]string{},
+		Auto: []mapping.FieldMapping{
+			{Source: mapping.FieldRefArray{{Path: "Note"}}, Target: mapping.FieldRefArray{{Path: "Note"}}, OmitZero: true},
+			{Source: mapping.FieldRefArray{{Path: "Age"}}, Target: mapping.FieldRefArray{{Path: "Age"}}, NullDefault: "0"},
+		},
+	}
+
+	lockTypeMapping(tm)
+
+	if len(tm.OneToOne) != 0 {
+		t.Errorf("Expected no 121 entries, got %v", tm.OneToOne)
+	}
+
+	if len(tm.Fields) != 2 || !tm.Fields[0].OmitZero || tm.Fields[1].NullDefault != "0" {
+		t.Errorf("Expected fields entries keeping omit_zero and null_default, got %+v", tm.Fields)
+	}
+}
@@ -0,0 +1,294 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"caster-generator/internal/mapping"
+)
+
+// CasterManifest describes the casters generated for a plan, in dependency order,
+// together with nested casters that are called but not generated.
+type CasterManifest struct {
+	// Casters lists generated type pairs; every caster appears after the casters it calls
+	// (except within recursive cycles).
+	Casters []ManifestEntry `json:"casters"`
+	// Missing lists nested casters referenced by generated code that no type pair provides.
+	Missing []MissingCaster `json:"missing,omitempty"`
+}
+
+// ManifestEntry describes one generated caster and the nested casters it calls.
+type ManifestEntry struct {
+	Pair      string   `json:"pair"`
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// MissingCaster is a nested caster that is required but not generated.
+type MissingCaster struct {
+	Pair       string   `json:"pair"`
+	RequiredBy []string `json:"required_by"`
+}
+
+// BuildManifest computes the whole-plan caster ordering and detects nested casters
+// that are called across files but have no generated definition. The casters of
+// the implicit pairs of p count as generated.
+func BuildManifest(p *ResolvedMappingPlan) *CasterManifest {
+	manifest := &CasterManifest{Casters: []ManifestEntry{}}
+	if p == nil {
+		return manifest
+	}
+
+	pairs := append(slices.Clone(p.TypePairs), p.ImplicitPairs()...)
+	deps := make(map[string][]string, len(pairs))
+	generated := make(map[string]bool, len(pairs))
+
+	var keys []string
+
+	for i := range pairs {
+		key := getPairKey(&pairs[i])
+		if generated[key] {
+			continue
+		}
+
+		generated[key] = true
+		keys = append(keys, key)
+		deps[key] = nestedPairKeys(&pairs[i])
+	}
+
+	requiredBy := make(map[string][]string)
+
+	for _, key := range keys {
+		for _, dep := range deps[key] {
+			if !generated[dep] {
+				requiredBy[dep] = append(requiredBy[dep], key)
+			}
+		}
+	}
+
+	// Depth-first post-order puts dependencies first; visited marks break recursive cycles.
+	visited := make(map[string]bool, len(keys))
+
+	var visit func(key string)
+
+	visit = func(key string) {
+		if visited[key] || !generated[key] {
+			return
+		}
+
+		visited[key] = true
+
+		for _, dep := range deps[key] {
+			visit(dep)
+		}
+
+		manifest.Casters = append(manifest.Casters, ManifestEntry{Pair: key, DependsOn: deps[key]})
+	}
+
+	for _, key := range keys {
+		visit(key)
+	}
+
+	missingKeys := make([]string, 0, len(requiredBy))
+	for key := range requiredBy {
+		missingKeys = append(missingKeys, key)
+	}
+
+	sort.Strings(missingKeys)
+
+	for _, key := range missingKeys {
+		manifest.Missing = append(manifest.Missing, MissingCaster{Pair: key, RequiredBy: requiredBy[key]})
+	}
+
+	return manifest
+}
+
+// ImplicitPairs returns the pairs no mapping declares whose casters the casters
+// of p call, directly or through other implicit pairs, once each and ordered by
+// key. Their casters are generated with the plan's, so that every nested caster
+// is defined once however many casters call it.
+func (p *ResolvedMappingPlan) ImplicitPairs() []ResolvedTypePair {
+	seen := make(map[string]bool, len(p.TypePairs))
+	for i := range p.TypePairs {
+		seen[getPairKey(&p.TypePairs[i])] = true
+	}
+
+	var implicit []ResolvedTypePair
+
+	var collect func(pair *ResolvedTypePair)
+
+	collect = func(pair *ResolvedTypePair) {
+		for _, np := range pair.NestedPairs {
+			nested := np.ResolvedPair
+			if nested == nil || !nested.Implicit {
+				continue
+			}
+
+			// Inline conversions declare no caster, but may call some.
+			if np.Inline() {
+				collect(nested)
+				continue
+			}
+
+			key := getPairKey(nested)
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			implicit = append(implicit, *nested)
+
+			collect(nested)
+		}
+	}
+
+	for i := range p.TypePairs {
+		collect(&p.TypePairs[i])
+	}
+
+	slices.SortFunc(implicit, func(a, b ResolvedTypePair) int {
+		return strings.Compare(getPairKey(&a), getPairKey(&b))
+	})
+
+	return implicit
+}
+
+// nestedPairKeys returns the sorted, de-duplicated nested caster keys called by a pair,
+// excluding self-references. The casters called by inline conversions are called by the pair.
+func nestedPairKeys(pair *ResolvedTypePair) []string {
+	self := getPairKey(pair)
+	seen := make(map[string]bool)
+
+	var keys []string
+
+	var collect func(pair *ResolvedTypePair)
+
+	collect = func(pair *ResolvedTypePair) {
+		for _, np := range pair.NestedPairs {
+			if np.SourceType == nil || np.TargetType == nil {
+				continue
+			}
+
+			key := fmt.Sprintf("%s->%s", np.SourceType.ID, np.TargetType.ID)
+			if key == self || seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			if np.Inline() {
+				if np.ResolvedPair != nil {
+					collect(np.ResolvedPair)
+				}
+
+				continue
+			}
+
+			keys = append(keys, key)
+		}
+	}
+
+	collect(pair)
+	sort.Strings(keys)
+
+	return keys
+}
+
+// FilterTags keeps only the type pairs whose mapping has one of tags, and drops
+// the diagnostics of the other pairs. Returns an error if a tag selects no pair.
+func (p *ResolvedMappingPlan) FilterTags(tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	for _, tag := range tags {
+		used := slices.ContainsFunc(p.TypePairs, func(tp ResolvedTypePair) bool {
+			return slices.Contains(tp.Tags, tag)
+		})
+		if !used {
+			return fmt.Errorf("tag %q is not used by any mapping", tag)
+		}
+	}
+
+	kept := p.TypePairs[:0]
+	keys := make(map[string]bool)
+
+	for _, tp := range p.TypePairs {
+		if slices.ContainsFunc(tp.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			kept = append(kept, tp)
+			keys[getPairKey(&tp)] = true
+		}
+	}
+
+	p.TypePairs = kept
+	p.Diagnostics.FilterTypePairs(func(typePair string) bool { return keys[typePair] })
+
+	return nil
+}
+
+// FilterTypePairs keeps only the type pairs selected by only.
+// Each entry has the form "Source->Target" using the same type names as the mapping file.
+// Returns an error if an entry is malformed or matches no type pair.
+func (p *ResolvedMappingPlan) FilterTypePairs(only []string) error {
+	if len(only) == 0 {
+		return nil
+	}
+
+	selected := make(map[string]bool, len(only))
+
+	for _, entry := range only {
+		src, tgt, ok := strings.Cut(entry, "->")
+		if !ok {
+			return fmt.Errorf("invalid type pair %q: expected Source->Target", entry)
+		}
+
+		srcT := mapping.ResolveTypeID(strings.TrimSpace(src), p.TypeGraph)
+		tgtT := mapping.ResolveTypeID(strings.TrimSpace(tgt), p.TypeGraph)
+
+		if srcT == nil || tgtT == nil {
+			return fmt.Errorf("type pair %q: type not found", entry)
+		}
+
+		key := fmt.Sprintf("%s->%s", srcT.ID, tgtT.ID)
+
+		found := false
+
+		for i := range p.TypePairs {
+			if getPairKey(&p.TypePairs[i]) == key {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("type pair %q is not defined in the mapping", entry)
+		}
+
+		selected[key] = true
+	}
+
+	kept := p.TypePairs[:0]
+
+	for _, tp := range p.TypePairs {
+		if selected[getPairKey(&tp)] {
+			kept = append(kept, tp)
+		}
+	}
+
+	p.TypePairs = kept
+
+	return nil
+}
+
+// FormatMissing returns a human-readable list of required-but-not-generated casters.
+func (m *CasterManifest) FormatMissing() string {
+	var sb strings.Builder
+
+	for _, mc := range m.Missing {
+		fmt.Fprintf(&sb, "  - %s (required by %s)\n", mc.Pair, strings.Join(mc.RequiredBy, ", "))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,168 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+)
+
+func manifestTestPlan() *ResolvedMappingPlan {
+	graph := analyze.NewTypeGraph()
+
+	newStruct := func(pkg, name string) *analyze.TypeInfo {
+		t := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: name}, Kind: analyze.TypeKindStruct}
+		graph.Types[t.ID] = t
+
+		return t
+	}
+
+	srcOrder, dstOrder := newStruct("test/src", "Order"), newStruct("test/dst", "Order")
+	srcItem, dstItem := newStruct("test/src", "Item"), newStruct("test/dst", "Item")
+	srcAddr, dstAddr := newStruct("test/src", "Address"), newStruct("test/dst", "Address")
+
+	return &ResolvedMappingPlan{
+		TypeGraph: graph,
+		TypePairs: []ResolvedTypePair{
+			{
+				SourceType: srcOrder,
+				TargetType: dstOrder,
+				NestedPairs: []NestedConversion{
+					{SourceType: srcItem, TargetType: dstItem, IsSliceElement: true},
+					{SourceType: srcAddr, TargetType: dstAddr},
+				},
+			},
+			{
+				SourceType: srcItem,
+				TargetType: dstItem,
+				NestedPairs: []NestedConversion{
+					{SourceType: srcItem, TargetType: dstItem}, // self reference
+				},
+			},
+			{SourceType: srcAddr, TargetType: dstAddr},
+		},
+	}
+}
+
+func TestBuildManifest_Order(t *testing.T) {
+	m := BuildManifest(manifestTestPlan())
+
+	if len(m.Missing) != 0 {
+		t.Fatalf("expected no missing casters, got %v", m.Missing)
+	}
+
+	want := []string{
+		"test/src.Address->test/dst.Address",
+		"test/src.Item->test/dst.Item",
+		"test/src.Order->test/dst.Order",
+	}
+
+	if len(m.Casters) != len(want) {
+		t.Fatalf("expected %d casters, got %d", len(want), len(m.Casters))
+	}
+
+	for i, w := range want {
+		if m.Casters[i].Pair != w {
+			t.Errorf("caster %d: expected %s, got %s", i, w, m.Casters[i].Pair)
+		}
+	}
+
+	if len(m.Casters[1].DependsOn) != 0 {
+		t.Errorf("self reference should not be a dependency, got %v", m.Casters[1].DependsOn)
+	}
+}
+
+func TestFilterTypePairs_MissingDependency(t *testing.T) {
+	p := manifestTestPlan()
+
+	if err := p.FilterTypePairs([]string{"test/src.Order->test/dst.Order", "test/src.Item->test/dst.Item"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(p.TypePairs) != 2 {
+		t.Fatalf("expected 2 type pairs after filtering, got %d", len(p.TypePairs))
+	}
+
+	m := BuildManifest(p)
+	if len(m.Missing) != 1 {
+		t.Fatalf("expected 1 missing caster, got %v", m.Missing)
+	}
+
+	if m.Missing[0].Pair != "test/src.Address->test/dst.Address" {
+		t.Errorf("unexpected missing caster: %s", m.Missing[0].Pair)
+	}
+
+	if len(m.Missing[0].RequiredBy) != 1 || m.Missing[0].RequiredBy[0] != "test/src.Order->test/dst.Order" {
+		t.Errorf("unexpected required_by: %v", m.Missing[0].RequiredBy)
+	}
+}
+
+func TestBuildManifest_ImplicitPairs(t *testing.T) {
+	p := manifestTestPlan()
+
+	if err := p.FilterTypePairs([]string{"test/src.Order->test/dst.Order", "test/src.Item->test/dst.Item"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An auto-matched nested pair, called by two casters, is generated once
+	src, dst := p.TypePairs[0].NestedPairs[1].SourceType, p.TypePairs[0].NestedPairs[1].TargetType
+	addr := &ResolvedTypePair{SourceType: src, TargetType: dst, Implicit: true}
+
+	for i := range p.TypePairs {
+		p.TypePairs[i].NestedPairs = append(p.TypePairs[i].NestedPairs[:1:1],
+			NestedConversion{SourceType: src, TargetType: dst, ResolvedPair: addr})
+	}
+
+	implicit := p.ImplicitPairs()
+	if len(implicit) != 1 || getPairKey(&implicit[0]) != "test/src.Address->test/dst.Address" {
+		t.Fatalf("expected the Address pair to be implicit, got %v", implicit)
+	}
+
+	m := BuildManifest(p)
+	if len(m.Missing) != 0 {
+		t.Fatalf("expected no missing casters, got %v", m.Missing)
+	}
+
+	if len(m.Casters) != 3 || m.Casters[0].Pair != "test/src.Address->test/dst.Address" {
+		t.Errorf("expected the Address caster first, got %v", m.Casters)
+	}
+}
+
+func TestFilterTypePairs_Errors(t *testing.T) {
+	tests := []string{
+		"test/src.Order",                // malformed
+		"test/src.Nope->test/dst.Order", // unknown type
+		"test/src.Order->test/dst.Item", // not a mapped pair
+	}
+
+	for _, entry := range tests {
+		if err := manifestTestPlan().FilterTypePairs([]string{entry}); err == nil {
+			t.Errorf("expected error for %q", entry)
+		}
+	}
+}
+
+func TestFilterTags(t *testing.T) {
+	p := manifestTestPlan()
+	p.TypePairs[0].Tags = []string{"billing", "critical"}
+	p.TypePairs[2].Tags = []string{"shipping"}
+	p.Diagnostics.AddWarning("w", "order", "test/src.Order->test/dst.Order", "")
+	p.Diagnostics.AddWarning("w", "item", "test/src.Item->test/dst.Item", "")
+	p.Diagnostics.AddWarning("w", "global", "", "")
+
+	if err := p.FilterTags([]string{"critical", "shipping"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(p.TypePairs) != 2 || getPairKey(&p.TypePairs[0]) != "test/src.Order->test/dst.Order" ||
+		getPairKey(&p.TypePairs[1]) != "test/src.Address->test/dst.Address" {
+		t.Fatalf("unexpected type pairs after filtering: %d", len(p.TypePairs))
+	}
+
+	if len(p.Diagnostics.Warnings) != 2 {
+		t.Errorf("expected order and global warnings to be kept, got %v", p.Diagnostics.Warnings)
+	}
+
+	if err := manifestTestPlan().FilterTags([]string{"nope"}); err == nil {
+		t.Error("expected error for unused tag")
+	}
+}
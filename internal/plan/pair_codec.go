@@ -0,0 +1,598 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"caster-generator/internal/analyze"
+)
+
+// Steps of the path from a type of the graph to a type it contains.
+const (
+	stepUnderlying = 'u'
+	stepElem       = 'e'
+	stepKey        = 'k'
+	stepField      = 'f'
+	stepUnexported = 'x'
+	stepTypeArg    = 'a'
+)
+
+var (
+	typeInfoType = reflect.TypeFor[*analyze.TypeInfo]()
+	fieldType    = reflect.TypeFor[*analyze.FieldInfo]()
+	pairType     = reflect.TypeFor[*ResolvedTypePair]()
+)
+
+// EncodeTypePair encodes tp and its nested pairs as JSON, to be restored by
+// DecodeTypePair from the graph of a later run. Types are encoded as
+// references: the ID of a type of graph, then the path to the type inside it
+// (e.g., the element type of the third field). Fields are the fields of such
+// types, or encoded by value if they belong to no type.
+//
+// It fails if tp refers to a type that can't be reached from the types of
+// the pairs, or holds values JSON can't represent.
+func EncodeTypePair(tp *ResolvedTypePair, graph *analyze.TypeGraph) ([]byte, error) {
+	e := &pairEncoder{
+		graph:  graph,
+		types:  make(map[*analyze.TypeInfo][]any),
+		fields: make(map[*analyze.FieldInfo]map[string]any),
+		pairs:  make(map[*ResolvedTypePair]int),
+	}
+	e.index(tp)
+
+	if _, err := e.pair(tp); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(e.wire)
+}
+
+// DecodeTypePair restores a type pair encoded by EncodeTypePair, looking
+// its types up in graph. It fails if a type is missing from graph.
+func DecodeTypePair(data []byte, graph *analyze.TypeGraph) (*ResolvedTypePair, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var wire []any
+	if err := dec.Decode(&wire); err != nil {
+		return nil, fmt.Errorf("decoding type pair: %w", err)
+	}
+
+	if len(wire) == 0 {
+		return nil, errors.New("decoding type pair: no pairs")
+	}
+
+	d := &pairDecoder{graph: graph, pairs: make([]*ResolvedTypePair, len(wire))}
+	for i := range wire {
+		d.pairs[i] = &ResolvedTypePair{}
+	}
+
+	for i, w := range wire {
+		if err := d.set(reflect.ValueOf(d.pairs[i]).Elem(), w); err != nil {
+			return nil, fmt.Errorf("decoding type pair: %w", err)
+		}
+	}
+
+	return d.pairs[0], nil
+}
+
+// pairEncoder encodes type pairs as trees of JSON values.
+type pairEncoder struct {
+	graph *analyze.TypeGraph
+	// types and fields map the types and fields reachable from the types of
+	// the pairs to their references.
+	types  map[*analyze.TypeInfo][]any
+	fields map[*analyze.FieldInfo]map[string]any
+	// pairs maps the pairs encoded to their index in wire.
+	pairs map[*ResolvedTypePair]int
+	wire  []any
+}
+
+// registered reports whether t is the type of the graph with its ID.
+func (e *pairEncoder) registered(t *analyze.TypeInfo) bool {
+	return t.ID != (analyze.TypeID{}) && e.graph != nil && e.graph.Types[t.ID] == t
+}
+
+// index records references to the types reachable from the types of tp and
+// its nested pairs, and to their fields. Types of the graph are referenced by
+// ID; others by the shortest path from one of them, so references only
+// depend on the declarations of types the pairs use.
+func (e *pairEncoder) index(tp *ResolvedTypePair) {
+	type entry struct {
+		t   *analyze.TypeInfo
+		ref []any
+	}
+
+	var queue []entry
+
+	visit := func(t *analyze.TypeInfo, ref []any) {
+		if t == nil {
+			return
+		}
+
+		if e.registered(t) {
+			ref = []any{t.ID.PkgPath, t.ID.Name, t.ID.Args, t.ID.Literal}
+		}
+
+		if _, seen := e.types[t]; seen || ref == nil {
+			return
+		}
+
+		e.types[t] = ref
+		queue = append(queue, entry{t: t, ref: ref})
+	}
+
+	seen := make(map[*ResolvedTypePair]bool)
+
+	var visitPair func(p *ResolvedTypePair)
+
+	visitPair = func(p *ResolvedTypePair) {
+		if p == nil || seen[p] {
+			return
+		}
+
+		seen[p] = true
+
+		visit(p.SourceType, nil)
+		visit(p.TargetType, nil)
+		visit(p.Via, nil)
+
+		for i := range p.NestedPairs {
+			visit(p.NestedPairs[i].SourceType, nil)
+			visit(p.NestedPairs[i].TargetType, nil)
+			visitPair(p.NestedPairs[i].ResolvedPair)
+		}
+	}
+
+	visitPair(tp)
+
+	step := func(ref []any, kind byte, i int) []any {
+		s := string(kind)
+		if i >= 0 {
+			s += strconv.Itoa(i)
+		}
+
+		return append(append([]any(nil), ref...), s)
+	}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		t := cur.t
+
+		visit(t.Underlying, step(cur.ref, stepUnderlying, -1))
+		visit(t.ElemType, step(cur.ref, stepElem, -1))
+		visit(t.KeyType, step(cur.ref, stepKey, -1))
+
+		for i := range t.Fields {
+			if _, ok := e.fields[&t.Fields[i]]; !ok {
+				e.fields[&t.Fields[i]] = map[string]any{"owner": cur.ref, "index": i}
+			}
+
+			visit(t.Fields[i].Type, step(cur.ref, stepField, i))
+		}
+
+		for i := range t.Unexported {
+			if _, ok := e.fields[&t.Unexported[i]]; !ok {
+				e.fields[&t.Unexported[i]] = map[string]any{"owner": cur.ref, "index": i, "unexported": true}
+			}
+
+			visit(t.Unexported[i].Type, step(cur.ref, stepUnexported, i))
+		}
+
+		for i, arg := range t.TypeArgs {
+			visit(arg, step(cur.ref, stepTypeArg, i))
+		}
+	}
+}
+
+// pair returns the index of p in wire, encoding it first if needed.
+func (e *pairEncoder) pair(p *ResolvedTypePair) (int, error) {
+	if i, ok := e.pairs[p]; ok {
+		return i, nil
+	}
+
+	i := len(e.wire)
+	e.pairs[p] = i
+	e.wire = append(e.wire, nil)
+
+	w, err := e.value(reflect.ValueOf(p).Elem())
+	if err != nil {
+		return 0, err
+	}
+
+	e.wire[i] = w
+
+	return i, nil
+}
+
+// value encodes v as a JSON value.
+func (e *pairEncoder) value(v reflect.Value) (any, error) {
+	switch v.Type() {
+	case typeInfoType:
+		t, _ := v.Interface().(*analyze.TypeInfo)
+		if t == nil {
+			return nil, nil
+		}
+
+		ref, ok := e.types[t]
+		if !ok {
+			return nil, fmt.Errorf("type %s (%s) is not reachable from the types of the pair", t.ID, t.Kind)
+		}
+
+		return ref, nil
+	case fieldType:
+		f, _ := v.Interface().(*analyze.FieldInfo)
+		if f == nil {
+			return nil, nil
+		}
+
+		if ref, ok := e.fields[f]; ok {
+			return ref, nil
+		}
+
+		w, err := e.value(v.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"value": w}, nil
+	case pairType:
+		p, _ := v.Interface().(*ResolvedTypePair)
+		if p == nil {
+			return nil, nil
+		}
+
+		return e.pair(p)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		return e.value(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		out := make([]any, v.Len())
+		for i := range out {
+			w, err := e.value(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = w
+		}
+
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+
+		out := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			w, err := e.value(it.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			out[it.Key().String()] = w
+		}
+
+		return out, nil
+	case reflect.Struct:
+		out := make(map[string]any)
+
+		for i := range v.NumField() {
+			f, fv := v.Type().Field(i), v.Field(i)
+			if fv.IsZero() {
+				continue
+			}
+
+			if !f.IsExported() {
+				return nil, fmt.Errorf("unexported field %s of %s", f.Name, v.Type())
+			}
+
+			w, err := e.value(fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", v.Type(), f.Name, err)
+			}
+
+			out[f.Name] = w
+		}
+
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// pairDecoder restores type pairs from trees of JSON values.
+type pairDecoder struct {
+	graph *analyze.TypeGraph
+	pairs []*ResolvedTypePair
+}
+
+// set decodes w into v.
+func (d *pairDecoder) set(v reflect.Value, w any) error {
+	if w == nil {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Type() {
+	case typeInfoType:
+		t, err := d.typeInfo(w)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(t))
+
+		return nil
+	case fieldType:
+		f, err := d.field(w)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(f))
+
+		return nil
+	case pairType:
+		i, err := toInt(w)
+		if err != nil || i < 0 || i >= int64(len(d.pairs)) {
+			return fmt.Errorf("invalid pair reference %v", w)
+		}
+
+		v.Set(reflect.ValueOf(d.pairs[i]))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := w.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %v", w)
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(w)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := w.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected number, got %v", w)
+		}
+
+		u, err := strconv.ParseUint(n.String(), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		n, ok := w.(json.Number)
+		if !ok {
+			return fmt.Errorf("expected number, got %v", w)
+		}
+
+		f, err := n.Float64()
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	case reflect.String:
+		s, ok := w.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %v", w)
+		}
+
+		v.SetString(s)
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		if err := d.set(p.Elem(), w); err != nil {
+			return err
+		}
+
+		v.Set(p)
+	case reflect.Slice:
+		items, ok := w.([]any)
+		if !ok {
+			return fmt.Errorf("expected list, got %v", w)
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := d.set(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+		v.Set(s)
+	case reflect.Map:
+		entries, ok := w.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected object, got %v", w)
+		}
+
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+
+		for key, entry := range entries {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := d.set(value, entry); err != nil {
+				return err
+			}
+
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+
+		v.Set(m)
+	case reflect.Struct:
+		entries, ok := w.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected object, got %v", w)
+		}
+
+		for name, entry := range entries {
+			f, ok := v.Type().FieldByName(name)
+			if !ok || !f.IsExported() || len(f.Index) != 1 {
+				return fmt.Errorf("unknown field %s of %s", name, v.Type())
+			}
+
+			if err := d.set(v.FieldByIndex(f.Index), entry); err != nil {
+				return fmt.Errorf("%s.%s: %w", v.Type(), name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// typeInfo looks up the type of a reference in the graph.
+func (d *pairDecoder) typeInfo(w any) (*analyze.TypeInfo, error) {
+	ref, ok := w.([]any)
+	if !ok || len(ref) < 4 {
+		return nil, fmt.Errorf("invalid type reference %v", w)
+	}
+
+	var id [4]string
+
+	for i := range id {
+		s, ok := ref[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type reference %v", w)
+		}
+
+		id[i] = s
+	}
+
+	typeID := analyze.TypeID{PkgPath: id[0], Name: id[1], Args: id[2], Literal: id[3]}
+
+	var t *analyze.TypeInfo
+	if d.graph != nil {
+		t = d.graph.Types[typeID]
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("type %s is not in the type graph", typeID)
+	}
+
+	for _, s := range ref[4:] {
+		step, ok := s.(string)
+		if !ok || step == "" {
+			return nil, fmt.Errorf("invalid type reference %v", w)
+		}
+
+		t = walkStep(t, step)
+		if t == nil {
+			return nil, fmt.Errorf("type %s has no %s", typeID, step)
+		}
+	}
+
+	return t, nil
+}
+
+// walkStep returns the type step leads to from t, or nil.
+func walkStep(t *analyze.TypeInfo, step string) *analyze.TypeInfo {
+	i, err := strconv.Atoi(step[1:])
+	indexed := err == nil && i >= 0
+
+	switch {
+	case step[0] == stepUnderlying && len(step) == 1:
+		return t.Underlying
+	case step[0] == stepElem && len(step) == 1:
+		return t.ElemType
+	case step[0] == stepKey && len(step) == 1:
+		return t.KeyType
+	case step[0] == stepField && indexed && i < len(t.Fields):
+		return t.Fields[i].Type
+	case step[0] == stepUnexported && indexed && i < len(t.Unexported):
+		return t.Unexported[i].Type
+	case step[0] == stepTypeArg && indexed && i < len(t.TypeArgs):
+		return t.TypeArgs[i]
+	default:
+		return nil
+	}
+}
+
+// field restores a field: a field of a type of the graph, or a field encoded
+// by value.
+func (d *pairDecoder) field(w any) (*analyze.FieldInfo, error) {
+	ref, ok := w.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid field reference %v", w)
+	}
+
+	if value, ok := ref["value"]; ok {
+		f := &analyze.FieldInfo{}
+		if err := d.set(reflect.ValueOf(f).Elem(), value); err != nil {
+			return nil, err
+		}
+
+		return f, nil
+	}
+
+	owner, err := d.typeInfo(ref["owner"])
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := toInt(ref["index"])
+	if err != nil {
+		return nil, err
+	}
+
+	fields := owner.Fields
+	if unexported, _ := ref["unexported"].(bool); unexported {
+		fields = owner.Unexported
+	}
+
+	if i < 0 || i >= int64(len(fields)) {
+		return nil, fmt.Errorf("type %s has no field %d", owner.ID, i)
+	}
+
+	return &fields[i], nil
+}
+
+// toInt converts a JSON number to an integer.
+func toInt(w any) (int64, error) {
+	n, ok := w.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected number, got %v", w)
+	}
+
+	return n.Int64()
+}
@@ -0,0 +1,77 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestTypePairCodec(t *testing.T) {
+	for _, example := range []string{"generics", "nested-slice", "pointers", "recursive-struct"} {
+		t.Run(example, func(t *testing.T) {
+			mf, err := mapping.LoadFile("../../examples/" + example + "/map.yaml")
+			if err != nil {
+				t.Fatalf("load mapping: %v", err)
+			}
+
+			graph, err := analyze.NewAnalyzer().LoadPackages("caster-generator/examples/" + example)
+			if err != nil {
+				t.Fatalf("load packages: %v", err)
+			}
+
+			resolved, err := NewResolver(graph, mf, DefaultConfig()).Resolve()
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+
+			for i := range resolved.TypePairs {
+				tp := &resolved.TypePairs[i]
+
+				data, err := EncodeTypePair(tp, graph)
+				if err != nil {
+					t.Fatalf("encode %s->%s: %v", tp.SourceType.ID, tp.TargetType.ID, err)
+				}
+
+				decoded, err := DecodeTypePair(data, graph)
+				if err != nil {
+					t.Fatalf("decode %s->%s: %v", tp.SourceType.ID, tp.TargetType.ID, err)
+				}
+
+				if decoded.SourceType != tp.SourceType || decoded.TargetType != tp.TargetType {
+					t.Errorf("%s->%s: types not restored from the graph", tp.SourceType.ID, tp.TargetType.ID)
+				}
+
+				if !reflect.DeepEqual(decoded, tp) {
+					t.Errorf("%s->%s: decoded pair differs from the encoded one", tp.SourceType.ID, tp.TargetType.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTypePairMissingType(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+	src := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "test/source", Name: "A"}, Kind: analyze.TypeKindStruct}
+	tgt := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: "test/target", Name: "B"}, Kind: analyze.TypeKindStruct}
+	graph.Types[src.ID] = src
+	graph.Types[tgt.ID] = tgt
+
+	data, err := EncodeTypePair(&ResolvedTypePair{SourceType: src, TargetType: tgt}, graph)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	delete(graph.Types, tgt.ID)
+
+	if _, err := DecodeTypePair(data, graph); err == nil {
+		t.Error("Expected an error for a type missing from the graph")
+	}
+
+	// Types outside the graph and the pair's types can't be referenced.
+	orphan := &analyze.TypeInfo{Kind: analyze.TypeKindSlice, ElemType: src}
+	if _, err := EncodeTypePair(&ResolvedTypePair{SourceType: src, TargetType: orphan}, graph); err == nil {
+		t.Error("Expected an error for a type not reachable from the graph")
+	}
+}
@@ -33,7 +33,7 @@ func TestResolveFieldMapping_YAMLPointerDerefStrategy(t *testing.T) {
 		Target: mapping.FieldRefArray{{Path: "LineItemPrice"}},
 	}
 
-	resolved, err := r.resolveFieldMapping(fm, src, tgt, MappingSourceYAMLFields)
+	resolved, err := r.resolveFieldMapping(nil, fm, src, tgt, MappingSourceYAMLFields)
 	if err != nil {
 		t.Fatalf("resolveFieldMapping: %v", err)
 	}
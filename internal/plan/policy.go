@@ -0,0 +1,118 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+)
+
+// policyCodePrefix starts the codes of the errors reporting policy violations.
+const policyCodePrefix = "policy_"
+
+// policy returns the policy resolution checks: the config's, or else the
+// mapping file's.
+func (r *Resolver) policy() mapping.Policy {
+	if r.config.Policy != nil {
+		return *r.config.Policy
+	}
+
+	return r.mappingDef.Policy
+}
+
+// checkPolicy reports the resolved mappings of plan the policy fails on as
+// policy_* errors.
+func (r *Resolver) checkPolicy(plan *ResolvedMappingPlan) {
+	policy := r.policy()
+	if policy.IsZero() {
+		return
+	}
+
+	declared := make(map[string]bool, len(plan.OriginalTransforms))
+	for _, t := range slices.Concat(plan.Currency.Transforms(), plan.OriginalTransforms) {
+		declared[t.Name] = true
+	}
+
+	diags := &plan.Diagnostics
+
+	for i := range plan.TypePairs {
+		tp := &plan.TypePairs[i]
+		typePairStr := tp.SourceType.ID.String() + "->" + tp.TargetType.ID.String()
+
+		if policy.FailOnUnmapped {
+			for _, um := range tp.UnmappedTargets {
+				diags.AddError(policyCodePrefix+"unmapped",
+					fmt.Sprintf("field %q is unmapped (policy fail_on_unmapped)", um.TargetPath), typePairStr,
+					um.TargetPath.String())
+			}
+		}
+
+		for j := range tp.Mappings {
+			r.checkMappingPolicy(policy, tp, &tp.Mappings[j], declared, diags, typePairStr)
+		}
+	}
+}
+
+// checkMappingPolicy reports the violations of policy by mapping m of tp.
+func (r *Resolver) checkMappingPolicy(
+	policy mapping.Policy,
+	tp *ResolvedTypePair,
+	m *ResolvedFieldMapping,
+	declared map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	if len(m.TargetPaths) == 0 {
+		return
+	}
+
+	target := m.TargetPaths[0].String()
+
+	if policy.MinAutoConfidence > 0 && m.Source == MappingSourceAutoMatched && m.Confidence < policy.MinAutoConfidence {
+		diags.AddError(policyCodePrefix+"low_confidence",
+			fmt.Sprintf("field %q is auto-matched with confidence %.2f, below min_auto_confidence %.2f",
+				target, m.Confidence, policy.MinAutoConfidence), typePairStr, target)
+	}
+
+	if policy.FailOnLossy && m.Strategy == StrategyConvert && len(m.SourcePaths) > 0 {
+		src := r.resolveFieldType(m.SourcePaths[0], tp.SourceType)
+		tgt := r.resolveFieldType(m.TargetPaths[0], tp.TargetType)
+
+		if src != nil && tgt != nil && src.GoType != nil && tgt.GoType != nil &&
+			match.LossyConversion(src.GoType, tgt.GoType) {
+			diags.AddError(policyCodePrefix+"lossy",
+				fmt.Sprintf("field %q converts %s to %s, which may lose information (policy fail_on_lossy)",
+					target, src.GoType, tgt.GoType), typePairStr, target)
+		}
+	}
+
+	// Transforms neither set nor declared are generated as stubs that panic.
+	needsTransform := m.Strategy == StrategyTransform || m.Transform != ""
+	missing := m.Transform == "" || (!strings.Contains(m.Transform, ".") && !declared[m.Transform])
+
+	if policy.FailOnMissingTransform && needsTransform && missing {
+		reason := "needs a transform"
+		if m.Transform != "" {
+			reason = fmt.Sprintf("uses transform %s, which is not declared in transforms", m.Transform)
+		}
+
+		diags.AddError(policyCodePrefix+"missing_transform",
+			fmt.Sprintf("field %q %s (policy fail_on_missing_transform)", target, reason), typePairStr, target)
+	}
+}
+
+// PolicyViolations returns the errors reporting mappings the policy fails on.
+func (p *ResolvedMappingPlan) PolicyViolations() []diagnostic.Diagnostic {
+	var violations []diagnostic.Diagnostic
+
+	for _, e := range p.Diagnostics.Errors {
+		if strings.HasPrefix(e.Code, policyCodePrefix) {
+			violations = append(violations, e)
+		}
+	}
+
+	return violations
+}
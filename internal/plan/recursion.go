@@ -0,0 +1,73 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/diagnostic"
+)
+
+// resolvingPair is a type pair being resolved, with the target field whose
+// nested conversion is being resolved from it.
+type resolvingPair struct {
+	key  string
+	pair *ResolvedTypePair
+	via  string
+}
+
+// enterPair records that the pair with the given key is being resolved,
+// returning the function that records it is done.
+func (r *Resolver) enterPair(key string, pair *ResolvedTypePair) func() {
+	r.resolving = append(r.resolving, resolvingPair{key: key, pair: pair})
+
+	return func() {
+		r.resolving = r.resolving[:len(r.resolving)-1]
+	}
+}
+
+// reportCycle reports the recursion closed by the nested conversion nc of
+// the innermost pair being resolved, when nc converts a pair still being
+// resolved. The casters of such pairs call each other until a nil pointer or
+// an empty collection ends the recursion, or max_depth is reached; without
+// max_depth, it is a warning.
+func (r *Resolver) reportCycle(key string, nc *NestedConversion, diags *diagnostic.Diagnostics) {
+	start := slices.IndexFunc(r.resolving, func(p resolvingPair) bool { return p.key == key })
+	if start < 0 || len(nc.ReferencedBy) == 0 {
+		return
+	}
+
+	cycle := r.resolving[start:]
+	last := &cycle[len(cycle)-1]
+	last.via = nc.ReferencedBy[0].String()
+
+	var sb strings.Builder
+
+	limited := false
+
+	for _, p := range cycle {
+		fmt.Fprintf(&sb, "%s via %s -> ", p.key, p.via)
+
+		limited = limited || p.pair.MaxDepth > 0
+	}
+
+	sb.WriteString(key)
+
+	msg := "recursive types: " + sb.String()
+	if limited {
+		diags.AddInfo("recursive_types", msg, last.key, last.via)
+
+		return
+	}
+
+	diags.AddWarning("recursive_types",
+		msg+"; cyclic values recurse without end unless a mapping of the cycle sets max_depth", last.key, last.via)
+}
+
+// descend records the target field of the innermost pair being resolved
+// whose nested conversion is resolved next.
+func (r *Resolver) descend(nc *NestedConversion) {
+	if len(r.resolving) > 0 && len(nc.ReferencedBy) > 0 {
+		r.resolving[len(r.resolving)-1].via = nc.ReferencedBy[0].String()
+	}
+}
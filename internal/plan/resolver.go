@@ -3,7 +3,10 @@ package plan
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"strings"
 
 	"caster-generator/internal/analyze"
 	"caster-generator/internal/diagnostic"
@@ -19,7 +22,7 @@ type ResolutionConfig struct {
 	MinGap float64
 	// AmbiguityThreshold marks pairs as ambiguous if within this difference.
 	AmbiguityThreshold float64
-	// StrictMode fails on any unresolved target fields.
+	// StrictMode fails resolution on any error diagnostic.
 	StrictMode bool
 	// MaxCandidates is the maximum number of candidates to include in suggestions.
 	MaxCandidates int
@@ -27,6 +30,23 @@ type ResolutionConfig struct {
 	RecursiveResolve bool
 	// MaxRecursionDepth limits recursion depth to prevent infinite loops (0 = unlimited).
 	MaxRecursionDepth int
+	// PositionWeight is the share of the combined score given to relative field
+	// position similarity during auto-matching (0 = disabled).
+	PositionWeight float64
+	// MatchTag is a struct tag key (e.g. "json") whose exactly matching values pin
+	// auto-match candidates (empty = disabled).
+	MatchTag string
+	// StripAffixes lists name tokens (e.g. "DTO") auto-matching ignores at the
+	// start or end of field names, in addition to the mapping file's.
+	StripAffixes []string
+	// Scorer replaces the built-in scoring of auto-match candidates
+	// (nil = built-in), for programs embedding the resolver.
+	Scorer match.Scorer
+	// OnIncompatiblePin overrides the mapping file's on_incompatible_pin
+	// (empty = use the file's).
+	OnIncompatiblePin mapping.PinPolicy
+	// Policy replaces the mapping file's policy (nil = use the file's).
+	Policy *mapping.Policy
 }
 
 // DefaultConfig returns the default resolution configuration.
@@ -50,6 +70,8 @@ type Resolver struct {
 	config     ResolutionConfig
 	// resolvedPairs caches already-resolved type pairs to prevent infinite recursion
 	resolvedPairs map[string]*ResolvedTypePair
+	// resolving is the stack of type pairs being resolved, outermost first.
+	resolving []resolvingPair
 }
 
 // NewResolver creates a new Resolver.
@@ -76,40 +98,101 @@ func NewResolver(
 
 // Resolve runs the full resolution pipeline and returns a ResolvedMappingPlan.
 func (r *Resolver) Resolve() (*ResolvedMappingPlan, error) {
-	plan := &ResolvedMappingPlan{
-		TypePairs:          []ResolvedTypePair{},
-		Diagnostics:        diagnostic.Diagnostics{},
-		TypeGraph:          r.graph,
-		OriginalTransforms: r.mappingDef.Transforms,
+	return r.ResolveSelected(nil)
+}
+
+// ResolveSelected is Resolve restricted to the type mappings for which
+// selected returns true (all of them if selected is nil). The other mappings
+// are still used for the nested conversions of the selected ones, when their
+// types are in the graph.
+func (r *Resolver) ResolveSelected(selected func(tm *mapping.TypeMapping) bool) (*ResolvedMappingPlan, error) {
+	plan, err := r.NewPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	// Process each type mapping
+	for _, tm := range r.mappingDef.TypeMappings {
+		if selected != nil && !selected(&tm) {
+			continue
+		}
+
+		if resolved := r.ResolveMapping(&tm, &plan.Diagnostics); resolved != nil {
+			plan.TypePairs = append(plan.TypePairs, *resolved)
+		}
 	}
 
+	return r.Finish(plan)
+}
+
+// NewPlan returns a plan without type pairs for the mapping file, and
+// creates its generated target types. ResolveMapping resolves pairs for it
+// and Finish completes it, as ResolveSelected does; callers reusing pairs
+// resolved by an earlier run add them in between.
+func (r *Resolver) NewPlan() (*ResolvedMappingPlan, error) {
 	if r.mappingDef == nil {
 		return nil, errors.New("mapping definition is required")
 	}
 
+	plan := &ResolvedMappingPlan{
+		TypePairs:            []ResolvedTypePair{},
+		Diagnostics:          diagnostic.Diagnostics{},
+		TypeGraph:            r.graph,
+		OriginalTransforms:   r.mappingDef.Transforms,
+		Wrappers:             r.mappingDef.Wrappers,
+		Implementations:      r.mappingDef.Implementations,
+		CopyMode:             r.mappingDef.CopyMode,
+		IgnoreTags:           r.mappingDef.IgnoreTags,
+		IgnorePatterns:       r.mappingDef.IgnorePatterns,
+		StripAffixes:         r.mappingDef.StripAffixes,
+		Synonyms:             r.mappingDef.Match.Synonyms,
+		Abbreviations:        r.mappingDef.Match.Abbreviations,
+		ExplicitDefinedTypes: r.mappingDef.Match.ExplicitDefinedTypes,
+		PositionWeight:       r.mappingDef.Match.PositionWeight,
+		MatchTag:             r.mappingDef.Match.Tag,
+		OnIncompatiblePin:    r.mappingDef.OnIncompatiblePin,
+		Policy:               r.mappingDef.Policy,
+		Currency:             r.mappingDef.Currency,
+		FuncTemplate:         r.mappingDef.Naming.Func,
+		GeneratedTypes:       r.mappingDef.GeneratedTypes,
+	}
+
 	// First pass: pre-create all virtual target types so they're available
 	// for nested type detection and resolution
-	r.preCreateVirtualTypes()
+	r.preCreateVirtualTypes(&plan.Diagnostics)
 
-	// Process each type mapping
-	for _, tm := range r.mappingDef.TypeMappings {
-		resolved, err := r.resolveTypeMapping(&tm, &plan.Diagnostics)
-		if err != nil {
-			plan.Diagnostics.AddError("resolve_failed", err.Error(),
-				fmt.Sprintf("%s->%s", tm.Source, tm.Target), "")
+	return plan, nil
+}
 
-			continue
-		}
+// ResolveMapping resolves tm into a type pair of a plan of NewPlan, adding
+// its diagnostics to diags. It returns nil if tm can't be resolved.
+func (r *Resolver) ResolveMapping(tm *mapping.TypeMapping, diags *diagnostic.Diagnostics) *ResolvedTypePair {
+	resolved, err := r.resolveTypeMapping(tm, diags)
+	if err != nil {
+		diags.AddError("resolve_failed", err.Error(),
+			fmt.Sprintf("%s->%s", tm.Source, tm.Target), "")
 
-		plan.TypePairs = append(plan.TypePairs, *resolved)
+		return nil
 	}
 
+	return resolved
+}
+
+// Finish runs the passes over all the type pairs of plan: requires types,
+// context arguments and the policy.
+func (r *Resolver) Finish(plan *ResolvedMappingPlan) (*ResolvedMappingPlan, error) {
 	// Deduce types for 'requires' arguments from usage context
 	r.deduceRequiresTypes(plan)
 
-	// In strict mode, fail if there are unresolved targets
+	// Propagate context.Context requirements from transforms up through nested casters
+	r.markContextPairs(plan)
+
+	// Report the resolved mappings the policy fails on
+	r.checkPolicy(plan)
+
+	// In strict mode, fail on any error
 	if r.config.StrictMode && plan.Diagnostics.HasErrors() {
-		return plan, errors.New("strict mode: resolution failed with errors")
+		return plan, fmt.Errorf("strict mode: resolution failed with errors: %w", plan.Diagnostics.Error())
 	}
 
 	return plan, nil
@@ -156,11 +239,14 @@ func (r *Resolver) resolveTypePairRecursive(
 		UnmappedTargets: []UnmappedField{},
 		NestedPairs:     []NestedConversion{},
 		Requires:        nil, // No explicit requirements for auto-matched nested types
+		Implicit:        true,
 	}
 
 	// Pre-cache to prevent infinite recursion for cyclic types
 	r.resolvedPairs[typePairKey] = result
 
+	defer r.enterPair(typePairKey, result)()
+
 	mappedTargets := make(map[string]bool)
 
 	// Only do auto-matching for nested types (no YAML rules available)
@@ -169,12 +255,46 @@ func (r *Resolver) resolveTypePairRecursive(
 	// Recursively detect and resolve nested conversions
 	r.detectNestedConversions(result, diags, depth)
 
+	// Flag conversions that hide string enum mismatches.
+	r.warnStringEnumConversions(result, diags, typePairKey)
+
 	// Sort for determinism
 	r.sortMappings(result)
 
 	return result, nil
 }
 
+// resolveVia resolves a mapping converting through an intermediate type as
+// the composition of the mappings to and from it, which become its nested pairs.
+func (r *Resolver) resolveVia(tm *mapping.TypeMapping, result *ResolvedTypePair, diags *diagnostic.Diagnostics) error {
+	via := mapping.ResolveTypeID(tm.Via, r.graph)
+	if via == nil {
+		return fmt.Errorf("via type %q not found", tm.Via)
+	}
+
+	result.Via = via
+
+	for _, hop := range [][2]*analyze.TypeInfo{{result.SourceType, via}, {via, result.TargetType}} {
+		resolved, err := r.resolveTypePairRecursive(hop[0], hop[1], diags, 0)
+		if err != nil {
+			return fmt.Errorf("via %s: %w", tm.Via, err)
+		}
+
+		if len(resolved.Requires) > 0 {
+			return fmt.Errorf("via %s: %s->%s takes requires arguments, which via can't pass",
+				tm.Via, hop[0].ID, hop[1].ID)
+		}
+
+		result.NestedPairs = append(result.NestedPairs, NestedConversion{
+			SourceType:   hop[0],
+			TargetType:   hop[1],
+			ResolvedPair: resolved,
+		})
+	}
+
+	return nil
+}
+
 // resolveTypeMapping resolves a single type mapping.
 func (r *Resolver) resolveTypeMapping(
 	tm *mapping.TypeMapping,
@@ -189,6 +309,12 @@ func (r *Resolver) resolveTypeMapping(
 	targetType := mapping.ResolveTypeID(tm.Target, r.graph)
 	isGeneratedTarget := false
 
+	// Name-only generated targets belong to the output package, so same-named
+	// types of other packages are neither reused nor collisions.
+	if tm.GenerateTarget && !strings.Contains(tm.Target, ".") {
+		targetType = r.graph.GetType(parseTypeID(tm.Target))
+	}
+
 	if targetType == nil {
 		if tm.GenerateTarget {
 			// Create virtual target type
@@ -198,8 +324,18 @@ func (r *Resolver) resolveTypeMapping(
 			return nil, fmt.Errorf("target type %q not found", tm.Target)
 		}
 	} else if tm.GenerateTarget {
-		// Target type was pre-created in preCreateVirtualTypes
-		isGeneratedTarget = true
+		// Target type was pre-created in preCreateVirtualTypes, or written by an
+		// earlier run; any other existing type is reused if its shape allows.
+		isGeneratedTarget = targetType.IsGenerated || targetType.FromCaster
+		if !isGeneratedTarget {
+			if err := r.checkExistingTarget(tm, sourceType, targetType, diags); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if sourceType.IsGeneric() || targetType.IsGeneric() {
+		return nil, fmt.Errorf("generic type in %s->%s must be instantiated with type arguments", tm.Source, tm.Target)
 	}
 
 	typePairStr := fmt.Sprintf("%s->%s", sourceType.ID, targetType.ID)
@@ -210,18 +346,42 @@ func (r *Resolver) resolveTypeMapping(
 	}
 
 	result := &ResolvedTypePair{
-		SourceType:        sourceType,
-		TargetType:        targetType,
-		Mappings:          []ResolvedFieldMapping{},
-		UnmappedTargets:   []UnmappedField{},
-		NestedPairs:       []NestedConversion{},
-		Requires:          tm.Requires, // Preserve requires
-		IsGeneratedTarget: isGeneratedTarget,
+		SourceType:          sourceType,
+		TargetType:          targetType,
+		Mappings:            []ResolvedFieldMapping{},
+		UnmappedTargets:     []UnmappedField{},
+		NestedPairs:         []NestedConversion{},
+		Requires:            tm.Requires, // Preserve requires
+		IsGeneratedTarget:   isGeneratedTarget,
+		CopyMode:            tm.CopyMode,
+		Tags:                tm.Tags,
+		GenerateMerge:       tm.GenerateMerge,
+		AllowUnexported:     tm.AllowUnexported,
+		Before:              tm.Before,
+		After:               tm.After,
+		Method:              tm.Method,
+		MaxDepth:            tm.MaxDepth,
+		RequireFullCoverage: tm.RequireFullCoverage,
+		Matching:            tm.Matching,
+		Output:              tm.Output,
+		Flatten:             tm.Flatten,
+		Unflatten:           tm.Unflatten,
 	}
 
 	// Pre-cache to prevent infinite recursion for cyclic types
 	r.resolvedPairs[typePairStr] = result
 
+	defer r.enterPair(typePairStr, result)()
+
+	if tm.Via != "" {
+		if err := r.resolveVia(tm, result, diags); err != nil {
+			delete(r.resolvedPairs, typePairStr)
+			return nil, err
+		}
+
+		return result, nil
+	}
+
 	// Check for requires conflicts
 	if conflicts := result.CheckRequireConflicts(); len(conflicts) > 0 {
 		for _, conflict := range conflicts {
@@ -235,13 +395,24 @@ func (r *Resolver) resolveTypeMapping(
 	mappedTargets := make(map[string]bool)
 
 	// Priority 1: Process 121 shorthand mappings (highest priority)
-	for sourcePath, targetPath := range tm.OneToOne {
+	for _, sourcePath := range slices.Sorted(maps.Keys(tm.OneToOne)) {
+		targetPath := tm.OneToOne[sourcePath]
+		if mapping.IsWildcard(sourcePath) || mapping.IsWildcard(targetPath) {
+			continue
+		}
+
 		resolved, err := r.resolve121Mapping(sourcePath, targetPath, sourceType, targetType)
 		if err != nil {
 			diags.AddWarning("121_mapping_error", err.Error(), typePairStr, targetPath)
 			continue
 		}
 
+		resolved.Pos = tm.OneToOnePos[sourcePath]
+
+		if resolved.Strategy == StrategyTransform && !r.applyPinPolicy(resolved, diags, typePairStr) {
+			continue
+		}
+
 		result.Mappings = append(result.Mappings, *resolved)
 		// Mark all target paths as mapped
 		for _, tp := range resolved.TargetPaths {
@@ -251,36 +422,49 @@ func (r *Resolver) resolveTypeMapping(
 
 	// Priority 2: Process explicit field mappings
 	for _, fm := range tm.Fields {
-		resolved, err := r.resolveFieldMapping(&fm, sourceType, targetType, MappingSourceYAMLFields)
+		if fm.IsWildcard() {
+			continue
+		}
+
+		resolvedAll, err := r.resolveFieldMappings(tm, &fm, sourceType, targetType, MappingSourceYAMLFields)
 		if err != nil {
 			diags.AddWarning("field_mapping_error", err.Error(), typePairStr, fm.Target.First())
 			continue
 		}
-		// Check for conflicts with higher priority mappings
-		for _, tp := range resolved.TargetPaths {
-			if mappedTargets[tp.String()] {
-				diags.AddWarning("mapping_override",
-					fmt.Sprintf("field %q already mapped by higher priority rule", tp.String()),
-					typePairStr, tp.String())
 
-				continue
+		for _, resolved := range resolvedAll {
+			// Check for conflicts with higher priority mappings
+			for _, tp := range resolved.TargetPaths {
+				if mappedTargets[tp.String()] {
+					diags.AddWarning("mapping_override",
+						fmt.Sprintf("field %q already mapped by higher priority rule", tp.String()),
+						typePairStr, tp.String())
+
+					continue
+				}
+
+				mappedTargets[tp.String()] = true
 			}
 
-			mappedTargets[tp.String()] = true
+			result.Mappings = append(result.Mappings, resolved)
 		}
-
-		result.Mappings = append(result.Mappings, *resolved)
 	}
 
+	// Fields expanded from wildcard rules, for targets explicit rules leave.
+	r.resolveWildcardRules(tm, result, mappedTargets, diags, typePairStr)
+
+	// Fields expanded from flatten and unflatten directives, like explicit fields.
+	r.resolveFlattenDirectives(tm, result, mappedTargets, diags, typePairStr)
+
 	// Priority 3: Process ignore list
-	for _, ignorePath := range tm.Ignore {
-		if mappedTargets[ignorePath] {
+	for _, ig := range tm.Ignore {
+		if mappedTargets[ig.Target] {
 			continue // Already handled by higher priority
 		}
 
-		fp, err := mapping.ParsePath(ignorePath)
+		fp, err := mapping.ParsePath(ig.Target)
 		if err != nil {
-			diags.AddWarning("ignore_parse_error", err.Error(), typePairStr, ignorePath)
+			diags.AddWarning("ignore_parse_error", err.Error(), typePairStr, ig.Target)
 			continue
 		}
 
@@ -289,29 +473,58 @@ func (r *Resolver) resolveTypeMapping(
 			SourcePaths: nil,
 			Source:      MappingSourceYAMLIgnore,
 			Strategy:    StrategyIgnore,
-			Explanation: "explicitly ignored",
+			Explanation: ignoreExplanation(&ig),
+			Pos:         ig.Pos,
 		}
+
+		if ig.HasMetadata() {
+			resolved.Ignore = &ig
+		}
+
 		result.Mappings = append(result.Mappings, resolved)
-		mappedTargets[ignorePath] = true
+		mappedTargets[ig.Target] = true
 	}
 
 	// Priority 4: Process YAML auto mappings
 	for _, fm := range tm.Auto {
-		resolved, err := r.resolveFieldMapping(&fm, sourceType, targetType, MappingSourceYAMLAuto)
+		resolvedAll, err := r.resolveFieldMappings(tm, &fm, sourceType, targetType, MappingSourceYAMLAuto)
 		if err != nil {
 			diags.AddWarning("auto_mapping_error", err.Error(), typePairStr, fm.Target.First())
 			continue
 		}
-		// Check for conflicts
-		for _, tp := range resolved.TargetPaths {
-			if mappedTargets[tp.String()] {
-				continue // Already handled by higher priority
+
+		for _, resolved := range resolvedAll {
+			// Check for conflicts
+			for _, tp := range resolved.TargetPaths {
+				if mappedTargets[tp.String()] {
+					continue // Already handled by higher priority
+				}
+
+				mappedTargets[tp.String()] = true
 			}
 
-			mappedTargets[tp.String()] = true
+			result.Mappings = append(result.Mappings, resolved)
 		}
+	}
 
-		result.Mappings = append(result.Mappings, *resolved)
+	// Target fields tagged "-" by one of ignore_tags and left unmapped above.
+	r.ignoreTaggedFields(result, targetType, mappedTargets, diags, typePairStr)
+
+	// Target fields named after one of ignore_patterns and left unmapped above.
+	r.ignorePatternFields(result, targetType, mappedTargets, diags, typePairStr)
+
+	// Fields of a generated target that only other source types contribute.
+	if tm.GenerateTarget {
+		r.ignoreSharedTargetFields(tm, result, sourceType, targetType, mappedTargets)
+	}
+
+	// Optional sources that don't exist yet are reported rather than failed on.
+	for _, m := range result.Mappings {
+		if m.PendingSource != "" {
+			diags.AddWarning("optional_source_missing",
+				fmt.Sprintf("target %q left unset: %s", m.TargetPaths[0].String(), m.Explanation),
+				typePairStr, m.PendingSource)
+		}
 	}
 
 	// Priority 5: Auto-match remaining target fields
@@ -323,6 +536,9 @@ func (r *Resolver) resolveTypeMapping(
 	// Derive dependency edges from `extra.def.target` references.
 	r.populateExtraTargetDependencies(result, diags)
 
+	// Flag conversions that hide string enum mismatches.
+	r.warnStringEnumConversions(result, diags, typePairStr)
+
 	// Sort for determinism
 	r.sortMappings(result)
 
@@ -358,8 +574,123 @@ func (r *Resolver) resolve121Mapping(
 	}, nil
 }
 
-// resolveFieldMapping resolves a FieldMapping from YAML.
+// applyPinPolicy applies on_incompatible_pin to a 121 mapping whose types
+// need a transform, and reports whether the mapping is kept.
+func (r *Resolver) applyPinPolicy(m *ResolvedFieldMapping, diags *diagnostic.Diagnostics, typePairStr string) bool {
+	pin := fmt.Sprintf("121 mapping %s -> %s has incompatible types", m.SourcePaths[0], m.TargetPaths[0])
+	target := m.TargetPaths[0].String()
+
+	switch r.config.OnIncompatiblePin.Or(r.mappingDef.OnIncompatiblePin) {
+	case mapping.PinError:
+		diags.AddError("incompatible_pin", pin+"; move it to fields with a transform", typePairStr, target)
+	case mapping.PinTodo:
+		m.Transform = generatePlaceholderTransformName(m.SourcePaths, m.TargetPaths)
+		m.Explanation = strings.TrimSuffix(m.Explanation, ")") + ", placeholder transform " + m.Transform + ")"
+		diags.AddWarning("incompatible_pin",
+			fmt.Sprintf("%s; converting it with placeholder transform %s", pin, m.Transform), typePairStr, target)
+	case mapping.PinFallbackAuto:
+		diags.AddWarning("incompatible_pin", pin+"; falling back to auto-matching", typePairStr, target)
+
+		return false
+	case mapping.PinDefault:
+		// Left needing a transform, which gen reports as an incomplete mapping.
+	}
+
+	return true
+}
+
+// resolveFieldMappings resolves a FieldMapping of tm from YAML into the
+// mappings of its targets: one per key of a from_map_keys mapping, otherwise
+// a single one.
+func (r *Resolver) resolveFieldMappings(
+	tm *mapping.TypeMapping,
+	fm *mapping.FieldMapping,
+	sourceType, targetType *analyze.TypeInfo,
+	source MappingSource,
+) ([]ResolvedFieldMapping, error) {
+	if len(fm.FromMapKeys) > 0 {
+		resolved, err := r.resolveMapKeyMappings(tm, fm, sourceType, source)
+		for i := range resolved {
+			resolved[i].Pos = fm.Pos
+		}
+
+		return resolved, err
+	}
+
+	resolved, err := r.resolveFieldMapping(tm, fm, sourceType, targetType, source)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.Pos = fm.Pos
+
+	return []ResolvedFieldMapping{*resolved}, nil
+}
+
+// resolveMapKeyMappings resolves a from_map_keys FieldMapping into a mapping
+// per key, parsing the value of the key in the source map into its target.
+func (r *Resolver) resolveMapKeyMappings(
+	tm *mapping.TypeMapping,
+	fm *mapping.FieldMapping,
+	sourceType *analyze.TypeInfo,
+	source MappingSource,
+) ([]ResolvedFieldMapping, error) {
+	sp, err := mapping.ParsePath(fm.Source.First())
+	if err != nil {
+		return nil, fmt.Errorf("invalid from_map_keys source path %q: %w", fm.Source.First(), err)
+	}
+
+	// An optional source that doesn't exist yet leaves the targets for later.
+	missing := mapping.MissingOptionalSource(tm, fm, sourceType)
+
+	var resolved []ResolvedFieldMapping
+
+	for _, e := range mapping.MapKeyEntries(fm.FromMapKeys) {
+		tp, err := mapping.ParsePath(e.Target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from_map_keys target path %q: %w", e.Target, err)
+		}
+
+		m := ResolvedFieldMapping{
+			SourcePaths: []mapping.FieldPath{sp},
+			TargetPaths: []mapping.FieldPath{tp},
+			Source:      source,
+			Strategy:    StrategyMapKey,
+			MapKey:      e.Key,
+			Cardinality: mapping.CardinalityOneToOne,
+			Confidence:  1.0,
+			Explanation: fmt.Sprintf("field mapping: 1:1 (map key %q)", e.Key),
+			Extra:       fm.Extra,
+			Merge:       fm.Merge,
+			Tags:        fm.Tags,
+			Deprecated:  fm.Deprecated,
+			Sunset:      fm.Sunset,
+			When:        fm.When,
+			ElseDefault: fm.ElseDefault,
+		}
+
+		if missing != "" {
+			m = ResolvedFieldMapping{
+				TargetPaths:   m.TargetPaths,
+				Source:        source,
+				Strategy:      StrategyIgnore,
+				Cardinality:   mapping.CardinalityOneToOne,
+				Explanation:   fmt.Sprintf("optional source %q not found yet", missing),
+				PendingSource: missing,
+				Deprecated:    fm.Deprecated,
+				Sunset:        fm.Sunset,
+			}
+		}
+
+		resolved = append(resolved, m)
+	}
+
+	return resolved, nil
+}
+
+// resolveFieldMapping resolves a FieldMapping of tm from YAML.
 func (r *Resolver) resolveFieldMapping(
+	tm *mapping.TypeMapping,
 	fm *mapping.FieldMapping,
 	sourceType, targetType *analyze.TypeInfo,
 	source MappingSource,
@@ -376,6 +707,24 @@ func (r *Resolver) resolveFieldMapping(
 		targetPaths = append(targetPaths, tp)
 	}
 
+	// An optional source that doesn't exist yet leaves the targets for later.
+	if missing := mapping.MissingOptionalSource(tm, fm, sourceType); missing != "" {
+		return &ResolvedFieldMapping{
+			TargetPaths:   targetPaths,
+			Source:        source,
+			Strategy:      StrategyIgnore,
+			Cardinality:   mapping.CardinalityOneToOne,
+			Explanation:   fmt.Sprintf("optional source %q not found yet", missing),
+			PendingSource: missing,
+			Deprecated:    fm.Deprecated,
+			Sunset:        fm.Sunset,
+		}, nil
+	}
+
+	if fm.Const != "" || fm.Expr != "" {
+		return r.resolveValueMapping(fm, targetPaths, targetType, source)
+	}
+
 	// Handle default value
 	if fm.Default != nil {
 		return &ResolvedFieldMapping{
@@ -386,9 +735,17 @@ func (r *Resolver) resolveFieldMapping(
 			Cardinality: mapping.CardinalityOneToOne,
 			Explanation: "default value: " + *fm.Default,
 			Extra:       fm.Extra,
+			Deprecated:  fm.Deprecated,
+			Sunset:      fm.Sunset,
+			When:        fm.When,
+			ElseDefault: fm.ElseDefault,
 		}, nil
 	}
 
+	if len(fm.Collect) > 0 {
+		return r.resolveCollectMapping(fm, targetPaths, source)
+	}
+
 	// Parse source paths
 	var sourcePaths []mapping.FieldPath
 
@@ -407,6 +764,9 @@ func (r *Resolver) resolveFieldMapping(
 	strategy := StrategyDirectAssign
 	explanation := "field mapping: 1:1"
 	cardinality := mapping.CardinalityOneToOne
+
+	var qualifiers map[string]string
+
 	// Default hint is none; for field mappings we currently only use the first source's hint.
 	hint := mapping.HintNone
 	if len(fm.Source) > 0 {
@@ -416,6 +776,15 @@ func (r *Resolver) resolveFieldMapping(
 	if fm.Transform != "" {
 		strategy = StrategyTransform
 		explanation = "field mapping: 1:1 (transform)"
+	} else if len(fm.EnumMap) > 0 {
+		strategy = StrategyEnumMap
+		explanation = "field mapping: 1:1 (enum map, 1 value)"
+
+		if len(fm.EnumMap) > 1 {
+			explanation = fmt.Sprintf("field mapping: 1:1 (enum map, %d values)", len(fm.EnumMap))
+		}
+
+		qualifiers = r.enumQualifiers(fm, targetPaths, targetType)
 	} else if len(sourcePaths) > 0 && len(targetPaths) > 0 {
 		st, expl := r.determineStrategyWithHint(
 			sourcePaths[0],
@@ -428,6 +797,10 @@ func (r *Resolver) resolveFieldMapping(
 		explanation = "field mapping: 1:1 (" + expl + ")"
 	}
 
+	if fm.DedupBy != "" {
+		explanation = strings.TrimSuffix(explanation, ")") + ", deduplicated by " + fm.DedupBy + ")"
+	}
+
 	return &ResolvedFieldMapping{
 		SourcePaths:   sourcePaths,
 		TargetPaths:   targetPaths,
@@ -439,6 +812,145 @@ func (r *Resolver) resolveFieldMapping(
 		Explanation:   explanation,
 		EffectiveHint: hint,
 		Extra:         fm.Extra,
+		NilToEmpty:    fm.NilToEmpty,
+		PreserveNil:   fm.PreserveNil,
+		DedupBy:       fm.DedupBy,
+		Copy:          fm.Copy,
+		Merge:         fm.Merge,
+		Tags:          fm.Tags,
+		EnumMap:       fm.EnumMap,
+		EnumDefault:   fm.EnumDefault,
+		EnumStrict:    fm.EnumStrict,
+		Qualifiers:    qualifiers,
+		NullDefault:   fm.NullDefault,
+		Deprecated:    fm.Deprecated,
+		Sunset:        fm.Sunset,
+		When:          fm.When,
+		ElseDefault:   fm.ElseDefault,
+	}, nil
+}
+
+// enumQualifiers returns the packages the enum_map values and enum_default of
+// fm refer to by qualifier, for values that are constants of the target
+// package. Invalid values are reported by validation.
+func (r *Resolver) enumQualifiers(
+	fm *mapping.FieldMapping,
+	targetPaths []mapping.FieldPath,
+	targetType *analyze.TypeInfo,
+) map[string]string {
+	if len(targetPaths) == 0 {
+		return nil
+	}
+
+	tt := r.resolveFieldType(targetPaths[0], targetType)
+	if tt == nil {
+		return nil
+	}
+
+	var qualifiers map[string]string
+
+	for _, v := range append(slices.Collect(maps.Values(fm.EnumMap)), fm.EnumDefault) {
+		if v == "" {
+			continue
+		}
+
+		if _, q, err := mapping.ConstExpr(v, tt, r.graph); err == nil && len(q) > 0 {
+			if qualifiers == nil {
+				qualifiers = make(map[string]string)
+			}
+
+			maps.Copy(qualifiers, q)
+		}
+	}
+
+	return qualifiers
+}
+
+// resolveValueMapping resolves a FieldMapping assigning a const or expr value,
+// planned as a default value qualified with the packages it refers to.
+func (r *Resolver) resolveValueMapping(
+	fm *mapping.FieldMapping,
+	targetPaths []mapping.FieldPath,
+	targetType *analyze.TypeInfo,
+	source MappingSource,
+) (*ResolvedFieldMapping, error) {
+	var (
+		value, explanation string
+		qualifiers         map[string]string
+		err                error
+	)
+
+	if fm.Const != "" {
+		tt := r.resolveFieldType(targetPaths[0], targetType)
+		if tt == nil {
+			return nil, fmt.Errorf("target %q of const not found", targetPaths[0])
+		}
+
+		value, qualifiers, err = mapping.ConstExpr(fm.Const, tt, r.graph)
+		explanation = "const value: " + fm.Const
+	} else {
+		value = fm.Expr
+		qualifiers, _, err = mapping.ExprQualifiers(fm.Expr, r.graph)
+		explanation = "expression: " + fm.Expr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolvedFieldMapping{
+		TargetPaths: targetPaths,
+		Source:      source,
+		Strategy:    StrategyDefault,
+		Default:     &value,
+		Const:       fm.Const,
+		Expr:        fm.Expr,
+		Qualifiers:  qualifiers,
+		Cardinality: mapping.CardinalityOneToOne,
+		Explanation: explanation,
+		Extra:       fm.Extra,
+		Deprecated:  fm.Deprecated,
+		Sunset:      fm.Sunset,
+		When:        fm.When,
+		ElseDefault: fm.ElseDefault,
+	}, nil
+}
+
+// resolveCollectMapping resolves a FieldMapping collecting source fields into
+// a map or []KV target; its source paths are the collected fields.
+func (r *Resolver) resolveCollectMapping(
+	fm *mapping.FieldMapping,
+	targetPaths []mapping.FieldPath,
+	source MappingSource,
+) (*ResolvedFieldMapping, error) {
+	var sourcePaths []mapping.FieldPath
+
+	for _, e := range mapping.CollectEntries(fm.Collect) {
+		sp, err := mapping.ParsePath(e.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collect source path %q: %w", e.Source, err)
+		}
+
+		sourcePaths = append(sourcePaths, sp)
+	}
+
+	return &ResolvedFieldMapping{
+		SourcePaths: sourcePaths,
+		TargetPaths: targetPaths,
+		Source:      source,
+		Strategy:    StrategyCollect,
+		Collect:     fm.Collect,
+		OmitZero:    fm.OmitZero,
+		Cardinality: mapping.CardinalityManyToOne,
+		Confidence:  1.0,
+		Explanation: fmt.Sprintf("field mapping: N:1 (collect, %d fields)", len(fm.Collect)),
+		Extra:       fm.Extra,
+		Merge:       fm.Merge,
+		Tags:        fm.Tags,
+		Deprecated:  fm.Deprecated,
+		Sunset:      fm.Sunset,
+		When:        fm.When,
+		ElseDefault: fm.ElseDefault,
 	}, nil
 }
 
@@ -463,9 +975,10 @@ func (r *Resolver) detectNestedConversions(result *ResolvedTypePair, diags *diag
 		r.analyzeMappingForNestedConversion(&m, result, nestedMap)
 	}
 
-	// Recursively resolve nested type pairs
-	for key, nc := range nestedMap {
-		r.resolveNestedConversion(key, nc, result, diags, depth)
+	// Recursively resolve nested type pairs, in order so recursive cycles are
+	// entered, and reported, the same way every run
+	for _, key := range slices.Sorted(maps.Keys(nestedMap)) {
+		r.resolveNestedConversion(key, nestedMap[key], result, diags, depth)
 	}
 }
 
@@ -474,7 +987,8 @@ func (r *Resolver) analyzeMappingForNestedConversion(
 	result *ResolvedTypePair,
 	nestedMap map[string]*NestedConversion,
 ) {
-	if m.Strategy != StrategyNestedCast && m.Strategy != StrategySliceMap {
+	if m.Strategy != StrategyNestedCast && m.Strategy != StrategyPointerNestedCast && m.Strategy != StrategySliceMap &&
+		m.Strategy != StrategyMap && m.Strategy != StrategyInterfaceSwitch {
 		return
 	}
 
@@ -483,6 +997,11 @@ func (r *Resolver) analyzeMappingForNestedConversion(
 		return
 	}
 
+	if m.Strategy == StrategyInterfaceSwitch {
+		r.addInterfaceNestedConversions(m, result, nestedMap)
+		return
+	}
+
 	sourceFieldType := r.resolveFieldType(m.SourcePaths[0], result.SourceType)
 	targetFieldType := r.resolveFieldType(m.TargetPaths[0], result.TargetType)
 
@@ -505,6 +1024,12 @@ func (r *Resolver) analyzeMappingForNestedConversion(
 		}
 	}
 
+	// For map mappings, the values are converted
+	if m.Strategy == StrategyMap && sourceFieldType.ElemType != nil && targetFieldType.ElemType != nil {
+		actualSourceType = sourceFieldType.ElemType
+		actualTargetType = targetFieldType.ElemType
+	}
+
 	// Handle pointer element types
 	if actualSourceType.Kind == analyze.TypeKindPointer && actualSourceType.ElemType != nil {
 		actualSourceType = actualSourceType.ElemType
@@ -541,6 +1066,8 @@ func (r *Resolver) resolveNestedConversion(
 ) {
 	// Note: if key is already in the cache, we reuse it (cycle-safe).
 	if cached, exists := r.resolvedPairs[key]; exists {
+		r.reportCycle(key, nc, diags)
+
 		nc.ResolvedPair = cached
 		result.NestedPairs = append(result.NestedPairs, *nc)
 
@@ -581,6 +1108,8 @@ func (r *Resolver) resolveNestedConversion(
 			return
 		}
 
+		r.descend(nc)
+
 		nestedResult, err := r.resolveTypePairRecursive(nc.SourceType, nc.TargetType, diags, depth+1)
 		if err != nil {
 			diags.AddWarning("nested_resolve_error", err.Error(), key, "")
@@ -594,6 +1123,31 @@ func (r *Resolver) resolveNestedConversion(
 	result.NestedPairs = append(result.NestedPairs, *nc)
 }
 
+// ignoreExplanation explains an explicitly ignored target with the reason,
+// date and ticket of its ignore entry (e.g., "explicitly ignored: filled by
+// the UI (until 2025-06-30, ticket SHOP-42)").
+func ignoreExplanation(ig *mapping.IgnoreEntry) string {
+	explanation := "explicitly ignored"
+	if ig.Reason != "" {
+		explanation += ": " + ig.Reason
+	}
+
+	var details []string
+	if ig.Until != "" {
+		details = append(details, "until "+ig.Until)
+	}
+
+	if ig.Ticket != "" {
+		details = append(details, "ticket "+ig.Ticket)
+	}
+
+	if len(details) > 0 {
+		explanation += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	return explanation
+}
+
 // sortMappings sorts mappings for deterministic output.
 func (r *Resolver) sortMappings(result *ResolvedTypePair) {
 	sort.Slice(result.Mappings, func(i, j int) bool {
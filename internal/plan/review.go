@@ -0,0 +1,207 @@
+package plan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"caster-generator/internal/mapping"
+)
+
+// ReviewItem is a target field left unmapped, awaiting a reviewer's decision.
+type ReviewItem struct {
+	// Source and Target are the full type IDs of the pair.
+	Source string
+	Target string
+	Field  string
+	// Reason is why auto-matching left the field unmapped.
+	Reason     string
+	Candidates []ReviewCandidate
+}
+
+// ReviewCandidate is a source field a reviewer may accept for a target field.
+type ReviewCandidate struct {
+	Field         string
+	Score         float64
+	Compatibility string
+	// Transform is the placeholder transform accepting the candidate adds,
+	// when its type needs one.
+	Transform string
+}
+
+// ReviewItems lists the unmapped target fields of every type pair of the plan,
+// nested pairs included, with the candidates auto-matching ranked for them.
+func (r *Resolver) ReviewItems(plan *ResolvedMappingPlan) []ReviewItem {
+	var items []ReviewItem
+
+	// A nested pair may be resolved once per caster using it.
+	seen := make(map[string]bool)
+
+	forEachResolvedPair(plan, func(tp *ResolvedTypePair) {
+		if seen[getPairKey(tp)] {
+			return
+		}
+
+		seen[getPairKey(tp)] = true
+
+		for _, um := range tp.UnmappedTargets {
+			item := ReviewItem{
+				Source: tp.SourceType.ID.String(),
+				Target: tp.TargetType.ID.String(),
+				Field:  um.TargetPath.String(),
+				Reason: um.Reason,
+			}
+
+			for i := range um.Candidates {
+				c := &um.Candidates[i]
+				rc := ReviewCandidate{
+					Field:         c.SourceField.Name,
+					Score:         c.CombinedScore,
+					Compatibility: c.TypeCompat.Compatibility.String(),
+				}
+
+				if strategy, _ := r.determineStrategyFromCandidate(c); strategy == StrategyTransform {
+					rc.Transform = generatePlaceholderTransformName(
+						[]mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: c.SourceField.Name}}}},
+						[]mapping.FieldPath{um.TargetPath})
+				}
+
+				item.Candidates = append(item.Candidates, rc)
+			}
+
+			items = append(items, item)
+		}
+	})
+
+	return items
+}
+
+// DriftDecisions returns the decisions check -fix takes for the target fields
+// left unmapped: a field with candidates goes to the auto section, mapped from
+// the best one, and other fields are ignored. Each decision comments the
+// candidates considered, for a reviewer to confirm.
+func DriftDecisions(items []ReviewItem) []mapping.ReviewDecision {
+	decisions := make([]mapping.ReviewDecision, 0, len(items))
+
+	for _, item := range items {
+		decision := mapping.ReviewDecision{Source: item.Source, Target: item.Target, Field: item.Field}
+
+		if len(item.Candidates) == 0 {
+			decision.Action = mapping.ReviewIgnore
+			decision.Comment = "check -fix: " + item.Field + " has no source candidate"
+			decisions = append(decisions, decision)
+
+			continue
+		}
+
+		names := make([]string, len(item.Candidates))
+		for i, c := range item.Candidates {
+			names[i] = fmt.Sprintf("%s (%.2f)", c.Field, c.Score)
+		}
+
+		best := item.Candidates[0]
+		decision.Action = mapping.ReviewAuto
+		decision.From = best.Field
+		decision.Transform = best.Transform
+		decision.Comment = "check -fix: candidates " + strings.Join(names, ", ")
+		decisions = append(decisions, decision)
+	}
+
+	return decisions
+}
+
+// Review walks the items, printing each with its candidates to out and
+// reading one answer per item from in: the number of a candidate to accept
+// it, "s" to skip the field, "i" to ignore it or "q" to stop. Invalid answers
+// are asked again. It returns the decisions taken until the last item, "q"
+// or the end of in.
+func Review(items []ReviewItem, in io.Reader, out io.Writer) ([]mapping.ReviewDecision, error) {
+	var decisions []mapping.ReviewDecision
+
+	scanner := bufio.NewScanner(in)
+
+	for n, item := range items {
+		fmt.Fprintf(out, "\n[%d/%d] %s -> %s: %s\n", n+1, len(items), item.Source, item.Target, item.Field)
+
+		if item.Reason != "" {
+			fmt.Fprintf(out, "  %s\n", item.Reason)
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+		for i, c := range item.Candidates {
+			note := c.Compatibility
+			if c.Transform != "" {
+				note += ", transform " + c.Transform
+			}
+
+			fmt.Fprintf(tw, "  %d) %s\t%.2f\t(%s)\n", i+1, c.Field, c.Score, note)
+		}
+
+		_ = tw.Flush()
+
+		prompt := "s skip, i ignore, q quit: "
+		if len(item.Candidates) > 0 {
+			prompt = fmt.Sprintf("1-%d accept, %s", len(item.Candidates), prompt)
+		}
+
+		for {
+			fmt.Fprint(out, prompt)
+
+			if !scanner.Scan() {
+				fmt.Fprintln(out)
+
+				return decisions, scanner.Err()
+			}
+
+			answer := strings.TrimSpace(scanner.Text())
+
+			if answer == "q" {
+				return decisions, nil
+			}
+
+			decision, ok := reviewDecision(&item, answer)
+			if !ok {
+				continue
+			}
+
+			if decision != nil {
+				decisions = append(decisions, *decision)
+			}
+
+			break
+		}
+	}
+
+	return decisions, nil
+}
+
+// reviewDecision interprets an answer about item: the decision it takes (nil
+// when the field is skipped), and false when the answer is invalid.
+func reviewDecision(item *ReviewItem, answer string) (*mapping.ReviewDecision, bool) {
+	decision := &mapping.ReviewDecision{Source: item.Source, Target: item.Target, Field: item.Field}
+
+	switch answer {
+	case "s":
+		return nil, true
+	case "i":
+		decision.Action = mapping.ReviewIgnore
+
+		return decision, true
+	}
+
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(item.Candidates) {
+		return nil, false
+	}
+
+	c := item.Candidates[n-1]
+	decision.Action = mapping.ReviewAccept
+	decision.From = c.Field
+	decision.Transform = c.Transform
+
+	return decision, true
+}
@@ -0,0 +1,139 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestReview(t *testing.T) {
+	items := []ReviewItem{
+		{
+			Source: "store.Order", Target: "warehouse.Order", Field: "Status",
+			Reason: "no high-confidence match",
+			Candidates: []ReviewCandidate{
+				{Field: "State", Score: 0.8, Compatibility: "identical"},
+				{Field: "Code", Score: 0.4, Compatibility: "incompatible", Transform: "TODO_CodeToStatus"},
+			},
+		},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Total"},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Note"},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Extra"},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Never"},
+	}
+
+	var out strings.Builder
+
+	// Out of range and unknown answers are asked again: Total takes three.
+	decisions, err := Review(items, strings.NewReader("3\n2\nx\n1\ns\ni\nq\n"), &out)
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+
+	want := []mapping.ReviewDecision{
+		{
+			Source: "store.Order", Target: "warehouse.Order", Field: "Status",
+			Action: mapping.ReviewAccept, From: "Code", Transform: "TODO_CodeToStatus",
+		},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Note", Action: mapping.ReviewIgnore},
+	}
+
+	if len(decisions) != len(want) {
+		t.Fatalf("Expected %d decisions, got %+v", len(want), decisions)
+	}
+
+	for i := range want {
+		if decisions[i] != want[i] {
+			t.Errorf("decision %d = %+v, want %+v", i, decisions[i], want[i])
+		}
+	}
+
+	text := out.String()
+	if !strings.Contains(text, "[1/5] store.Order -> warehouse.Order: Status") ||
+		!strings.Contains(text, "1-2 accept, s skip, i ignore, q quit: ") ||
+		strings.Contains(text, "[5/5]") {
+		t.Errorf("Review() output unexpected:\n%s", text)
+	}
+}
+
+func TestDriftDecisions(t *testing.T) {
+	items := []ReviewItem{
+		{
+			Source: "store.Order", Target: "warehouse.Order", Field: "Total",
+			Candidates: []ReviewCandidate{
+				{Field: "Amount", Score: 0.62, Transform: "TODO_AmountToTotal"},
+				{Field: "Tax", Score: 0.41},
+			},
+		},
+		{Source: "store.Order", Target: "warehouse.Order", Field: "Note"},
+	}
+
+	want := []mapping.ReviewDecision{
+		{
+			Source: "store.Order", Target: "warehouse.Order", Field: "Total",
+			Action: mapping.ReviewAuto, From: "Amount", Transform: "TODO_AmountToTotal",
+			Comment: "check -fix: candidates Amount (0.62), Tax (0.41)",
+		},
+		{
+			Source: "store.Order", Target: "warehouse.Order", Field: "Note",
+			Action: mapping.ReviewIgnore, Comment: "check -fix: Note has no source candidate",
+		},
+	}
+
+	decisions := DriftDecisions(items)
+	if len(decisions) != len(want) {
+		t.Fatalf("Expected %d decisions, got %+v", len(want), decisions)
+	}
+
+	for i := range want {
+		if decisions[i] != want[i] {
+			t.Errorf("decision %d = %+v, want %+v", i, decisions[i], want[i])
+		}
+	}
+}
+
+func TestReviewItemsAmbiguous(t *testing.T) {
+	graph := analyze.NewTypeGraph()
+
+	sourceType := &analyze.TypeInfo{
+		ID:   analyze.TypeID{PkgPath: "test/source", Name: "User"},
+		Kind: analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{
+			{Name: "HomeAddress", Exported: true, Type: basicTypeInfo()},
+			{Name: "WorkAddress", Exported: true, Type: basicTypeInfo()},
+		},
+	}
+	graph.Types[sourceType.ID] = sourceType
+
+	targetType := &analyze.TypeInfo{
+		ID:     analyze.TypeID{PkgPath: "test/target", Name: "User"},
+		Kind:   analyze.TypeKindStruct,
+		Fields: []analyze.FieldInfo{{Name: "Address", Exported: true, Type: basicTypeInfo()}},
+	}
+	graph.Types[targetType.ID] = targetType
+
+	mf := &mapping.MappingFile{TypeMappings: []mapping.TypeMapping{{Source: "source.User", Target: "target.User"}}}
+
+	r := NewResolver(graph, mf, DefaultConfig())
+
+	plan, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	// Ambiguous fields are left unmapped, so they are reviewed with every close candidate.
+	items := r.ReviewItems(plan)
+	if len(items) != 1 || items[0].Field != "Address" {
+		t.Fatalf("Expected Address to be reviewed, got %+v", items)
+	}
+
+	if !strings.HasPrefix(items[0].Reason, "ambiguous") {
+		t.Errorf("Expected an ambiguous reason, got %q", items[0].Reason)
+	}
+
+	if c := items[0].Candidates; len(c) < 2 || c[0].Field != "HomeAddress" || c[1].Field != "WorkAddress" {
+		t.Errorf("Expected both addresses as candidates, got %+v", c)
+	}
+}
@@ -0,0 +1,103 @@
+package plan
+
+import (
+	"go/types"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/match"
+)
+
+// SQLNullConversion describes a conversion between a database/sql nullable
+// type (e.g., sql.NullString) and a plain value or pointer. NULL becomes the
+// zero value, nil or null_default; values and non-nil pointers become valid.
+type SQLNullConversion struct {
+	// Null is the nullable type (e.g., sql.NullString).
+	Null *analyze.TypeInfo
+	// Value is the field holding the value (e.g., String).
+	Value *analyze.FieldInfo
+	// Unwrap is true when the source is the nullable type; false when the target is.
+	Unwrap bool
+	// Pointer is true when the plain side is *T rather than T.
+	Pointer bool
+}
+
+// Explain returns a short description used in mapping explanations.
+func (n *SQLNullConversion) Explain() string {
+	direction := "from "
+	if n.Unwrap {
+		direction = "to "
+	}
+
+	if n.Pointer {
+		direction += "pointer"
+	} else {
+		direction += "value"
+	}
+
+	return "sql." + n.Null.ID.Name + " " + direction
+}
+
+// FindSQLNullConversion returns how to convert between src and tgt through the
+// value and Valid fields of a database/sql nullable type, or nil if neither
+// side is one or the value doesn't convert to the other side.
+func FindSQLNullConversion(src, tgt *analyze.TypeInfo) *SQLNullConversion {
+	if src == nil || tgt == nil {
+		return nil
+	}
+
+	if value := src.SQLNullValue(); value != nil {
+		if ptr, ok := nullPlainMatches(value.Type, tgt); ok {
+			return &SQLNullConversion{Null: src, Value: value, Unwrap: true, Pointer: ptr}
+		}
+	}
+
+	if value := tgt.SQLNullValue(); value != nil {
+		if ptr, ok := nullPlainMatches(src, value.Type); ok {
+			return &SQLNullConversion{Null: tgt, Value: value, Pointer: ptr}
+		}
+	}
+
+	return nil
+}
+
+// nullPlainMatches reports whether values of from convert to to, either side
+// possibly being a pointer to such a value. It also reports the pointer.
+func nullPlainMatches(from, to *analyze.TypeInfo) (bool, bool) {
+	if valueConverts(from, to) {
+		return false, true
+	}
+
+	if to.Kind == analyze.TypeKindPointer && to.ElemType != nil {
+		return true, valueConverts(from, to.ElemType)
+	}
+
+	if from.Kind == analyze.TypeKindPointer && from.ElemType != nil {
+		return true, valueConverts(from.ElemType, to)
+	}
+
+	return false, false
+}
+
+// valueConverts reports whether a plain Go conversion turns from into to,
+// excluding integers into one-rune strings.
+func valueConverts(from, to *analyze.TypeInfo) bool {
+	if from.GoType == nil || to.GoType == nil {
+		return sameType(from, to)
+	}
+
+	return types.ConvertibleTo(from.GoType, to.GoType) && !isRuneConversion(from, to)
+}
+
+// sqlNullCompat lets auto-matching rank nullable conversions as convertible
+// instead of incompatible: they are generated without a transform.
+func sqlNullCompat(src, tgt *analyze.TypeInfo) (match.TypeCompatibilityResult, bool) {
+	nc := FindSQLNullConversion(src, tgt)
+	if nc == nil {
+		return match.TypeCompatibilityResult{}, false
+	}
+
+	return match.TypeCompatibilityResult{
+		Compatibility: match.TypeConvertible,
+		Reason:        nc.Explain(),
+	}, true
+}
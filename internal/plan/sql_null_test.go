@@ -0,0 +1,66 @@
+package plan
+
+import (
+	"go/types"
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/match"
+)
+
+func TestFindSQLNullConversion(t *testing.T) {
+	basic := func(name string, kind types.BasicKind) *analyze.TypeInfo {
+		return &analyze.TypeInfo{Kind: analyze.TypeKindBasic, ID: analyze.TypeID{Name: name}, GoType: types.Typ[kind]}
+	}
+	ptr := func(elem *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: elem}
+	}
+	nullable := func(pkgPath, name, field string, value *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:   analyze.TypeID{PkgPath: pkgPath, Name: name},
+			Kind: analyze.TypeKindStruct,
+			Fields: []analyze.FieldInfo{
+				{Name: field, Exported: true, Type: value},
+				{Name: "Valid", Exported: true, Type: basic("bool", types.Bool)},
+			},
+		}
+	}
+
+	str, num := basic("string", types.String), basic("int", types.Int)
+	nullString := nullable("database/sql", "NullString", "String", str)
+	nullInt := nullable("database/sql", "NullInt64", "Int64", basic("int64", types.Int64))
+
+	tests := []struct {
+		name     string
+		src, tgt *analyze.TypeInfo
+		want     string // Explain() of the conversion, empty for none
+	}{
+		{"to value", nullString, str, "sql.NullString to value"},
+		{"to converted value", nullInt, num, "sql.NullInt64 to value"},
+		{"to pointer", nullString, ptr(str), "sql.NullString to pointer"},
+		{"from value", str, nullString, "sql.NullString from value"},
+		{"from pointer", ptr(num), nullInt, "sql.NullInt64 from pointer"},
+		{"integer to string", nullInt, str, ""},
+		{"same nullable type", nullString, nullString, ""},
+		{"lookalike outside database/sql", nullable("example.com/db", "NullString", "String", str), str, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if nc := FindSQLNullConversion(tt.src, tt.tgt); nc != nil {
+				got = nc.Explain()
+			}
+
+			if got != tt.want {
+				t.Errorf("FindSQLNullConversion() = %q, want %q", got, tt.want)
+			}
+
+			// Auto-matching ranks them as convertible, since no transform is needed
+			if compat, ok := sqlNullCompat(tt.src, tt.tgt); ok != (tt.want != "") ||
+				ok && compat.Compatibility != match.TypeConvertible {
+				t.Errorf("sqlNullCompat() = %v, %v", compat.Compatibility, ok)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package plan
 
 import (
+	"fmt"
+	"go/types"
+	"strings"
+
 	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
 	"caster-generator/internal/mapping"
 	"caster-generator/internal/match"
 )
@@ -14,6 +19,7 @@ const (
 	explPointerDeref      = "pointer deref"
 	explPointerWrap       = "pointer wrap"
 	explMap               = "map copy"
+	explDefinedType       = match.VerdictConvertible + " (" + match.ReasonDefinedTypeConversion + ")"
 )
 
 // determineStrategy determines the conversion strategy based on source and target types.
@@ -43,6 +49,33 @@ func (r *Resolver) determineStrategyWithHint(
 		return StrategyTransform, "final (no introspection)"
 	}
 
+	// Floats and decimal amounts (e.g., float64 and PriceCents int64) only
+	// convert through a transform
+	if reason := match.MoneyConversion(sourcePath.Last(), sourceFieldType.GoType, targetPath.Last(),
+		targetFieldType.GoType); reason != "" {
+		return StrategyTransform, reason
+	}
+
+	// Declared generic wrappers (e.g., Nullable[T] <-> T) are converted via their methods
+	if wc := FindWrapperConversion(r.wrappers(), sourceFieldType, targetFieldType); wc != nil {
+		return StrategyWrapper, wc.Explain()
+	}
+
+	// Interface-typed sources dispatch to the casters of registered implementations
+	if cases := r.interfaceCases(sourceFieldType, targetFieldType); len(cases) > 0 {
+		return StrategyInterfaceSwitch, explainInterfaceCases(cases)
+	}
+
+	// Named types convert to and from string through String() or a parse function
+	if sc := r.stringConversion(sourceFieldType, targetFieldType); sc != nil {
+		return StrategyStringMethod, sc.Explain()
+	}
+
+	// database/sql nullable types convert through their Valid flag
+	if nc := FindSQLNullConversion(sourceFieldType, targetFieldType); nc != nil {
+		return StrategySQLNull, nc.Explain()
+	}
+
 	// For generated types, we can't use Go type compatibility check
 	// Instead, use structural matching based on Kind
 	if sourceFieldType.IsGenerated || targetFieldType.IsGenerated ||
@@ -59,6 +92,10 @@ func (r *Resolver) determineStrategyWithHint(
 	case match.TypeAssignable:
 		return StrategyDirectAssign, match.VerdictAssignable
 	case match.TypeConvertible:
+		if compat.Warning != "" || compat.Reason == match.ReasonDefinedTypeConversion {
+			return StrategyConvert, match.VerdictConvertible + " (" + compat.Reason + ")"
+		}
+
 		return StrategyConvert, match.VerdictConvertible
 	case match.TypeNeedsTransform:
 		return r.determineNeedsTransformStrategy(sourceFieldType, targetFieldType, hint)
@@ -76,11 +113,22 @@ func (r *Resolver) determineStrategyByKind(
 	srcKind := sourceFieldType.Kind
 	tgtKind := targetFieldType.Kind
 
+	// Defined types convert to and from their basic type (e.g., UserID and int64)
+	if definedTypeConversion(sourceFieldType, targetFieldType) {
+		return StrategyConvert, explDefinedType
+	}
+
 	// Same kind - direct assign or compatible
 	if srcKind == tgtKind {
 		switch srcKind {
 		default:
 			return StrategyDirectAssign, "same kind"
+		case analyze.TypeKindAlias:
+			if sourceFieldType.ID == targetFieldType.ID {
+				return StrategyDirectAssign, "identical"
+			}
+
+			return StrategyTransform, "distinct defined types"
 		case analyze.TypeKindBasic:
 			// For basic types with same name, direct assign
 			if sourceFieldType.ID.Name == targetFieldType.ID.Name {
@@ -96,10 +144,10 @@ func (r *Resolver) determineStrategyByKind(
 			return StrategyNestedCast, explNestedStruct
 		case analyze.TypeKindSlice, analyze.TypeKindArray:
 			if hint == mapping.HintDive {
-				return StrategySliceMap, "slice map (dive)"
+				return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType, "dive")
 			}
 
-			return StrategySliceMap, explSliceMap
+			return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType)
 		case analyze.TypeKindMap:
 			if hint == mapping.HintDive {
 				return StrategyMap, "map copy (dive)"
@@ -131,6 +179,89 @@ func (r *Resolver) determineStrategyByKind(
 	return StrategyTransform, "incompatible kinds"
 }
 
+// definedTypeConversion reports whether src and tgt are distinct types over
+// the same basic type, at least one of them a defined type (e.g., UserID and
+// int64), judged by kind for types without go/types information.
+func definedTypeConversion(src, tgt *analyze.TypeInfo) bool {
+	basic := func(t *analyze.TypeInfo) *analyze.TypeInfo {
+		for t != nil && t.Kind == analyze.TypeKindAlias && t.Underlying != nil {
+			t = t.Underlying
+		}
+
+		if t == nil || t.Kind != analyze.TypeKindBasic {
+			return nil
+		}
+
+		return t
+	}
+
+	srcBasic, tgtBasic := basic(src), basic(tgt)
+
+	return srcBasic != nil && tgtBasic != nil && src.ID != tgt.ID &&
+		(src.Kind == analyze.TypeKindAlias || tgt.Kind == analyze.TypeKindAlias) &&
+		srcBasic.ID.Name == tgtBasic.ID.Name
+}
+
+// explainSliceMap explains a slice map with the given qualifiers, noting
+// elements wrapped in or dereferenced from pointers (e.g., []Item -> []*ItemDTO).
+func explainSliceMap(src, tgt *analyze.TypeInfo, qualifiers ...string) string {
+	if src.ElemType != nil && tgt.ElemType != nil {
+		srcPtr := src.ElemType.Kind == analyze.TypeKindPointer
+		tgtPtr := tgt.ElemType.Kind == analyze.TypeKindPointer
+
+		switch {
+		case !srcPtr && tgtPtr:
+			qualifiers = append(qualifiers, "wrap elements")
+		case srcPtr && !tgtPtr:
+			qualifiers = append(qualifiers, "deref elements")
+		}
+	}
+
+	if len(qualifiers) == 0 {
+		return explSliceMap
+	}
+
+	return explSliceMap + " (" + strings.Join(qualifiers, ", ") + ")"
+}
+
+// nestable reports whether a nested caster can convert between two structs.
+// Casters are only generated for structs of analyzed packages, so a struct
+// from elsewhere (e.g., time.Time) needs a transform instead. Anonymous
+// structs are converted inline, spelling their literal, which unexported
+// fields would make a different type outside their package.
+func (r *Resolver) nestable(src, tgt *analyze.TypeInfo) bool {
+	if src.Kind != analyze.TypeKindStruct || tgt.Kind != analyze.TypeKindStruct {
+		return false
+	}
+
+	analyzed := func(t *analyze.TypeInfo) bool {
+		if t.IsAnonymousStruct() {
+			return !unexportedAnonymous(t)
+		}
+
+		return t.IsGenerated || r.graph.GetType(t.ID) != nil
+	}
+
+	return analyzed(src) && analyzed(tgt)
+}
+
+// unexportedAnonymous reports whether t, or the type it points to, is an
+// anonymous struct with unexported fields, which can't be spelled outside
+// its package to convert it inline.
+func unexportedAnonymous(t *analyze.TypeInfo) bool {
+	for t != nil && t.Kind == analyze.TypeKindPointer {
+		t = t.ElemType
+	}
+
+	if t == nil || !t.IsAnonymousStruct() {
+		return false
+	}
+
+	st, ok := t.GoType.(*types.Struct)
+
+	return !ok || st.NumFields() != len(t.Fields)
+}
+
 func (r *Resolver) determineNeedsTransformStrategy(
 	sourceFieldType, targetFieldType *analyze.TypeInfo,
 	hint mapping.IntrospectionHint,
@@ -150,7 +281,7 @@ func (r *Resolver) determineNeedsTransformStrategy(
 
 		tgtElem := targetFieldType.ElemType
 		if srcElem != nil && tgtElem != nil &&
-			srcElem.Kind == analyze.TypeKindStruct && tgtElem.Kind == analyze.TypeKindStruct {
+			r.nestable(srcElem, tgtElem) {
 			return StrategyPointerNestedCast, explPointerNestedCast
 		}
 	}
@@ -158,10 +289,10 @@ func (r *Resolver) determineNeedsTransformStrategy(
 	if sourceFieldType.Kind == analyze.TypeKindSlice && targetFieldType.Kind == analyze.TypeKindSlice {
 		// For slices, check if hint says dive (introspect elements) or final
 		if hint == mapping.HintDive {
-			return StrategySliceMap, explSliceMap + " (dive)"
+			return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType, "dive")
 		}
 
-		return StrategySliceMap, explSliceMap
+		return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType)
 	}
 
 	if sourceFieldType.Kind == analyze.TypeKindArray && targetFieldType.Kind == analyze.TypeKindArray {
@@ -173,7 +304,11 @@ func (r *Resolver) determineNeedsTransformStrategy(
 		return StrategySliceMap, explSliceMap + " (array)"
 	}
 
-	if sourceFieldType.Kind == analyze.TypeKindStruct && targetFieldType.Kind == analyze.TypeKindStruct {
+	if sourceFieldType.Kind == analyze.TypeKindMap && targetFieldType.Kind == analyze.TypeKindMap {
+		return StrategyMap, explMap
+	}
+
+	if r.nestable(sourceFieldType, targetFieldType) {
 		// For structs, check if hint says dive (recursively map fields) or final
 		if hint == mapping.HintDive {
 			return StrategyNestedCast, explNestedStruct + " (dive)"
@@ -195,13 +330,13 @@ func (r *Resolver) determineIncompatibleStrategy(
 
 		tgtElem := targetFieldType.ElemType
 		if srcElem != nil && tgtElem != nil &&
-			srcElem.Kind == analyze.TypeKindStruct && tgtElem.Kind == analyze.TypeKindStruct {
+			r.nestable(srcElem, tgtElem) {
 			return StrategyPointerNestedCast, explPointerNestedCast
 		}
 	}
 
 	// Also check for struct/slice even when marked as incompatible
-	if sourceFieldType.Kind == analyze.TypeKindStruct && targetFieldType.Kind == analyze.TypeKindStruct {
+	if r.nestable(sourceFieldType, targetFieldType) {
 		if hint == mapping.HintDive {
 			return StrategyNestedCast, explNestedStruct + " (dive)"
 		}
@@ -211,10 +346,10 @@ func (r *Resolver) determineIncompatibleStrategy(
 
 	if sourceFieldType.Kind == analyze.TypeKindSlice && targetFieldType.Kind == analyze.TypeKindSlice {
 		if hint == mapping.HintDive {
-			return StrategySliceMap, explSliceMap + " (dive)"
+			return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType, "dive")
 		}
 
-		return StrategySliceMap, explSliceMap
+		return StrategySliceMap, explainSliceMap(sourceFieldType, targetFieldType)
 	}
 
 	if sourceFieldType.Kind == analyze.TypeKindArray && targetFieldType.Kind == analyze.TypeKindArray {
@@ -225,17 +360,41 @@ func (r *Resolver) determineIncompatibleStrategy(
 		return StrategySliceMap, explSliceMap + " (array)"
 	}
 
+	if sourceFieldType.Kind == analyze.TypeKindMap && targetFieldType.Kind == analyze.TypeKindMap {
+		return StrategyMap, explMap
+	}
+
 	return StrategyTransform, "incompatible"
 }
 
 // determineStrategyFromCandidate determines the conversion strategy from a candidate match.
 func (r *Resolver) determineStrategyFromCandidate(cand *match.Candidate) (ConversionStrategy, string) {
+	if wc := FindWrapperConversion(r.wrappers(), cand.SourceField.Type, cand.TargetField.Type); wc != nil {
+		return StrategyWrapper, wc.Explain()
+	}
+
+	if cases := r.interfaceCases(cand.SourceField.Type, cand.TargetField.Type); len(cases) > 0 {
+		return StrategyInterfaceSwitch, explainInterfaceCases(cases)
+	}
+
+	if sc := r.stringConversion(cand.SourceField.Type, cand.TargetField.Type); sc != nil {
+		return StrategyStringMethod, sc.Explain()
+	}
+
+	if nc := FindSQLNullConversion(cand.SourceField.Type, cand.TargetField.Type); nc != nil {
+		return StrategySQLNull, nc.Explain()
+	}
+
 	switch cand.TypeCompat.Compatibility {
 	case match.TypeIdentical:
 		return StrategyDirectAssign, match.TypeIdentical.String()
 	case match.TypeAssignable:
 		return StrategyDirectAssign, match.TypeAssignable.String()
 	case match.TypeConvertible:
+		if cand.TypeCompat.Warning != "" || cand.TypeCompat.Reason == match.ReasonDefinedTypeConversion {
+			return StrategyConvert, match.TypeConvertible.String() + " (" + cand.TypeCompat.Reason + ")"
+		}
+
 		return StrategyConvert, match.TypeConvertible.String()
 	case match.TypeNeedsTransform:
 		// Check for specific strategies based on reason
@@ -253,13 +412,20 @@ func (r *Resolver) determineStrategyFromCandidate(cand *match.Candidate) (Conver
 			tgtKind := cand.TargetField.Type.Kind
 
 			// Handle struct-to-struct
-			if srcKind == analyze.TypeKindStruct && tgtKind == analyze.TypeKindStruct {
+			if r.nestable(cand.SourceField.Type, cand.TargetField.Type) {
 				return StrategyNestedCast, "nested struct"
 			}
 
+			// Handle pointer-to-pointer struct (e.g., *Node -> *NodeDTO)
+			if srcKind == analyze.TypeKindPointer && tgtKind == analyze.TypeKindPointer &&
+				cand.SourceField.Type.ElemType != nil && cand.TargetField.Type.ElemType != nil &&
+				r.nestable(cand.SourceField.Type.ElemType, cand.TargetField.Type.ElemType) {
+				return StrategyPointerNestedCast, explPointerNestedCast
+			}
+
 			// Handle slice-to-slice
 			if srcKind == analyze.TypeKindSlice && tgtKind == analyze.TypeKindSlice {
-				return StrategySliceMap, "slice map"
+				return StrategySliceMap, explainSliceMap(cand.SourceField.Type, cand.TargetField.Type)
 			}
 
 			// Handle array-to-array
@@ -281,12 +447,12 @@ func (r *Resolver) determineStrategyFromCandidate(cand *match.Candidate) (Conver
 			srcKind := cand.SourceField.Type.Kind
 			tgtKind := cand.TargetField.Type.Kind
 
-			if srcKind == analyze.TypeKindStruct && tgtKind == analyze.TypeKindStruct {
+			if r.nestable(cand.SourceField.Type, cand.TargetField.Type) {
 				return StrategyNestedCast, "nested struct"
 			}
 
 			if srcKind == analyze.TypeKindSlice && tgtKind == analyze.TypeKindSlice {
-				return StrategySliceMap, "slice map"
+				return StrategySliceMap, explainSliceMap(cand.SourceField.Type, cand.TargetField.Type)
 			}
 
 			if srcKind == analyze.TypeKindArray && tgtKind == analyze.TypeKindArray {
@@ -302,24 +468,61 @@ func (r *Resolver) determineStrategyFromCandidate(cand *match.Candidate) (Conver
 	}
 }
 
+// warnStringEnumConversions reports plain conversions between string enums and
+// strings (or other string enums). Such conversions compile but copy values
+// verbatim, so they are surfaced as warnings rather than silently accepted.
+func (r *Resolver) warnStringEnumConversions(
+	result *ResolvedTypePair,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	for _, m := range result.Mappings {
+		if m.Strategy != StrategyConvert || len(m.SourcePaths) == 0 || len(m.TargetPaths) == 0 {
+			continue
+		}
+
+		srcField := r.resolveFieldType(m.SourcePaths[0], result.SourceType)
+		tgtField := r.resolveFieldType(m.TargetPaths[0], result.TargetType)
+
+		if srcField == nil || tgtField == nil || srcField.GoType == nil || tgtField.GoType == nil {
+			continue
+		}
+
+		compat := match.ScoreTypeCompatibility(srcField.GoType, tgtField.GoType)
+		if compat.Warning == "" {
+			continue
+		}
+
+		targetPath := m.TargetPaths[0].String()
+		diags.Warnings = append(diags.Warnings, diagnostic.Diagnostic{
+			Severity:  diagnostic.DiagnosticWarning,
+			Code:      "string_enum_conversion",
+			Message:   fmt.Sprintf("field %q: %s", targetPath, compat.Warning),
+			TypePair:  typePairStr,
+			FieldPath: targetPath,
+			Suggestions: []string{
+				"map enum values explicitly with a transform, or ignore the field if the conversion is intended",
+			},
+		})
+	}
+}
+
 // resolveFieldType resolves the TypeInfo for a field at the given path.
 func (r *Resolver) resolveFieldType(path mapping.FieldPath, typeInfo *analyze.TypeInfo) *analyze.TypeInfo {
+	return fieldTypeAt(path, typeInfo)
+}
+
+// fieldTypeAt returns the TypeInfo of the field at path in typeInfo, or nil.
+func fieldTypeAt(path mapping.FieldPath, typeInfo *analyze.TypeInfo) *analyze.TypeInfo {
 	current := typeInfo
 
 	for i, seg := range path.Segments {
-		if current.Kind != analyze.TypeKindStruct {
+		if current == nil || current.Kind != analyze.TypeKindStruct {
 			return nil
 		}
 
-		var found *analyze.FieldInfo
-
-		for j := range current.Fields {
-			if current.Fields[j].Name == seg.Name {
-				found = &current.Fields[j]
-				break
-			}
-		}
-
+		// Promoted fields of embedded structs are addressable by their bare name.
+		found := current.FieldByName(seg.Name)
 		if found == nil {
 			return nil
 		}
@@ -330,6 +533,12 @@ func (r *Resolver) resolveFieldType(path mapping.FieldPath, typeInfo *analyze.Ty
 			current = current.ElemType
 		}
 
+		if seg.Key != "" {
+			if current, _ = mapping.KeyedElem(current, seg.Key); current == nil {
+				return nil
+			}
+		}
+
 		// Auto-deref pointers only when we're stepping *through* them to reach a deeper field.
 		// For leaf fields, we must preserve pointer-ness so strategies like PointerDeref can be selected.
 		isLast := i == len(path.Segments)-1
@@ -0,0 +1,150 @@
+package plan
+
+import (
+	"go/types"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/match"
+)
+
+// StringConversion describes a conversion between a named type and string
+// through the type's own API: its String() method, or a parse function
+// declared next to it (ParseX, XFromString or Parse).
+type StringConversion struct {
+	// Parse is true for string -> type; false for type -> string via String().
+	Parse bool
+	// Func is the parse function (set when Parse is true).
+	Func *analyze.FuncInfo
+	// ReturnsError is true when Func returns (T, error); errors yield the zero value.
+	ReturnsError bool
+}
+
+// Explain returns a short description used in mapping explanations.
+func (s *StringConversion) Explain() string {
+	if !s.Parse {
+		return "String() method"
+	}
+
+	if s.ReturnsError {
+		return "parse via " + s.Func.ID.Name + " (error yields zero value)"
+	}
+
+	return "parse via " + s.Func.ID.Name
+}
+
+// FindStringConversion returns how to convert between src and tgt through a
+// String() method or parse function, or nil if neither applies. Pairs that
+// Go can already convert directly (e.g., string-based enums) are left alone,
+// except integers, whose string conversion yields a rune rather than digits.
+func FindStringConversion(graph *analyze.TypeGraph, src, tgt *analyze.TypeInfo) *StringConversion {
+	if src == nil || tgt == nil || src.GoType == nil || tgt.GoType == nil {
+		return nil
+	}
+
+	if types.ConvertibleTo(src.GoType, tgt.GoType) && !isRuneConversion(src, tgt) {
+		return nil
+	}
+
+	if isPlainString(tgt) && hasStringMethod(src) {
+		return &StringConversion{}
+	}
+
+	if isPlainString(src) && tgt.IsNamed() {
+		if fn, withErr := findParseFunc(graph, tgt); fn != nil {
+			return &StringConversion{Parse: true, Func: fn, ReturnsError: withErr}
+		}
+	}
+
+	return nil
+}
+
+// isRuneConversion reports whether converting src to tgt turns an integer into
+// a one-rune string.
+func isRuneConversion(src, tgt *analyze.TypeInfo) bool {
+	srcBasic, ok := src.GoType.Underlying().(*types.Basic)
+	if !ok || srcBasic.Info()&types.IsInteger == 0 {
+		return false
+	}
+
+	tgtBasic, ok := tgt.GoType.Underlying().(*types.Basic)
+
+	return ok && tgtBasic.Info()&types.IsString != 0
+}
+
+// isPlainString reports whether t is the predeclared string type.
+func isPlainString(t *analyze.TypeInfo) bool {
+	return types.Identical(t.GoType, types.Typ[types.String])
+}
+
+// hasStringMethod reports whether a (addressable) value of named type t has a
+// String() string method.
+func hasStringMethod(t *analyze.TypeInfo) bool {
+	if !t.IsNamed() || t.Kind == analyze.TypeKindPointer {
+		return false
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(t.GoType, true, nil, "String")
+
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+
+	sig, ok := fn.Type().(*types.Signature)
+
+	return ok && sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Results().At(0).Type(), types.Typ[types.String])
+}
+
+// findParseFunc looks up a function in t's package taking a string and
+// returning t, optionally with an error. It reports whether the error is returned.
+func findParseFunc(graph *analyze.TypeGraph, t *analyze.TypeInfo) (*analyze.FuncInfo, bool) {
+	if graph == nil {
+		return nil, false
+	}
+
+	errType := types.Universe.Lookup("error").Type()
+
+	for _, name := range []string{"Parse" + t.ID.Name, t.ID.Name + "FromString", "Parse"} {
+		fn := graph.GetFunc(analyze.TypeID{PkgPath: t.ID.PkgPath, Name: name})
+		if fn == nil || fn.Signature == nil || fn.Variadic {
+			continue
+		}
+
+		params, results := fn.Signature.Params(), fn.Signature.Results()
+		if params.Len() != 1 || !types.Identical(params.At(0).Type(), types.Typ[types.String]) {
+			continue
+		}
+
+		if results.Len() == 0 || results.Len() > 2 || !types.Identical(results.At(0).Type(), t.GoType) {
+			continue
+		}
+
+		if results.Len() == 2 && !types.Identical(results.At(1).Type(), errType) {
+			continue
+		}
+
+		return fn, results.Len() == 2
+	}
+
+	return nil, false
+}
+
+// stringConversion returns the String()/parse conversion for src->tgt.
+func (r *Resolver) stringConversion(src, tgt *analyze.TypeInfo) *StringConversion {
+	return FindStringConversion(r.graph, src, tgt)
+}
+
+// stringCompat lets auto-matching rank String()/parse conversions as
+// transformable instead of incompatible.
+func (r *Resolver) stringCompat(src, tgt *analyze.TypeInfo) (match.TypeCompatibilityResult, bool) {
+	sc := r.stringConversion(src, tgt)
+	if sc == nil {
+		return match.TypeCompatibilityResult{}, false
+	}
+
+	return match.TypeCompatibilityResult{
+		Compatibility: match.TypeNeedsTransform,
+		Reason:        sc.Explain(),
+	}, true
+}
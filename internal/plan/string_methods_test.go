@@ -0,0 +1,67 @@
+package plan
+
+import (
+	"go/types"
+	"testing"
+
+	"caster-generator/internal/analyze"
+)
+
+const levelsSrc = `package levels
+
+type Level int
+
+func (l Level) String() string { return "" }
+
+func ParseLevel(s string) (Level, error) { return 0, nil }
+
+type Code struct{ V string }
+
+func (c *Code) String() string { return c.V }
+
+func CodeFromString(s string) Code { return Code{V: s} }
+
+type Status string
+
+func (s Status) String() string { return string(s) }
+
+func ParseStatus(s string) Status { return Status(s) }
+
+type Plain struct{}
+`
+
+func TestFindStringConversion(t *testing.T) {
+	graph := checkPackage(t, "example.com/levels", levelsSrc)
+	typ := func(name string) *analyze.TypeInfo {
+		return graph.Types[analyze.TypeID{PkgPath: "example.com/levels", Name: name}]
+	}
+	str := &analyze.TypeInfo{Kind: analyze.TypeKindBasic, ID: analyze.TypeID{Name: "string"}, GoType: types.Typ[types.String]}
+
+	tests := []struct {
+		name     string
+		src, tgt *analyze.TypeInfo
+		want     string // Explain() of the conversion, empty for none
+	}{
+		{"integer enum to string", typ("Level"), str, "String() method"},
+		{"pointer receiver", typ("Code"), str, "String() method"},
+		{"parse with error", str, typ("Level"), "parse via ParseLevel (error yields zero value)"},
+		{"from string", str, typ("Code"), "parse via CodeFromString"},
+		{"string enum converts directly", typ("Status"), str, ""},
+		{"string to string enum converts directly", str, typ("Status"), ""},
+		{"no methods", typ("Plain"), str, ""},
+		{"no parse function", str, typ("Plain"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if sc := FindStringConversion(graph, tt.src, tt.tgt); sc != nil {
+				got = sc.Explain()
+			}
+
+			if got != tt.want {
+				t.Errorf("FindStringConversion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
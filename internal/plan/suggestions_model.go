@@ -2,7 +2,10 @@ package plan
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"unicode"
 
 	"caster-generator/internal/mapping"
 )
@@ -11,11 +14,28 @@ import (
 // This allows users to review and approve auto-matched mappings.
 func ExportSuggestions(plan *ResolvedMappingPlan) (*mapping.MappingFile, error) {
 	mf := &mapping.MappingFile{
-		Version:      "1",
-		TypeMappings: []mapping.TypeMapping{},
-		Transforms:   plan.OriginalTransforms, // Preserve original transforms
+		Version:         "1",
+		TypeMappings:    []mapping.TypeMapping{},
+		Transforms:      plan.OriginalTransforms, // Preserve original transforms
+		Wrappers:        plan.Wrappers,           // Preserve wrapper declarations
+		Implementations: plan.Implementations,    // Preserve implementation pairs
 	}
 
+	mf.CopyMode = plan.CopyMode
+	mf.IgnoreTags = plan.IgnoreTags // Tag-ignored fields are left to ignore_tags
+	mf.IgnorePatterns = plan.IgnorePatterns
+	mf.StripAffixes = plan.StripAffixes
+	mf.Match.Synonyms = plan.Synonyms
+	mf.Match.Abbreviations = plan.Abbreviations
+	mf.Match.ExplicitDefinedTypes = plan.ExplicitDefinedTypes
+	mf.Match.PositionWeight = plan.PositionWeight
+	mf.Match.Tag = plan.MatchTag
+	mf.OnIncompatiblePin = plan.OnIncompatiblePin
+	mf.Policy = plan.Policy
+	mf.Currency = plan.Currency
+	mf.Naming.Func = plan.FuncTemplate
+	mf.GeneratedTypes = plan.GeneratedTypes
+
 	// Track already exported type pairs to avoid duplicates
 	exported := make(map[string]bool)
 
@@ -54,18 +74,43 @@ func exportTypePairSuggestions(tp *ResolvedTypePair) mapping.TypeMapping {
 		Requires: tp.Requires, // Preserve requires
 		OneToOne: make(map[string]string),
 		Fields:   []mapping.FieldMapping{},
-		Ignore:   []string{},
+		Ignore:   []mapping.IgnoreEntry{},
 		Auto:     []mapping.FieldMapping{},
 	}
 
+	// Preserve virtual targets
+	tm.GenerateTarget = tp.IsGeneratedTarget
+	tm.CopyMode = tp.CopyMode
+	tm.Tags = tp.Tags
+	tm.GenerateMerge = tp.GenerateMerge
+	tm.AllowUnexported = tp.AllowUnexported
+	tm.Before = tp.Before
+	tm.After = tp.After
+	tm.Method = tp.Method
+	tm.MaxDepth = tp.MaxDepth
+	tm.RequireFullCoverage = tp.RequireFullCoverage
+	tm.Matching = tp.Matching
+	tm.Flatten = tp.Flatten
+	tm.Unflatten = tp.Unflatten
+
+	if tp.Via != nil {
+		tm.Via = tp.Via.ID.String()
+	}
+
+	tm.Output = tp.Output
+
 	for _, m := range tp.Mappings {
 		switch m.Source {
 		case MappingSourceYAML121:
 			// Check if this 121 mapping has incompatible types (needs transform)
-			if m.Strategy == StrategyTransform && m.Transform == "" {
-				// Move to fields section with a placeholder transform
+			if m.Strategy == StrategyTransform {
+				// Move to fields section with a placeholder transform, unless
+				// on_incompatible_pin already set one
 				fm := exportFieldMapping(&m)
-				fm.Transform = generatePlaceholderTransformName(m.SourcePaths, m.TargetPaths)
+				if fm.Transform == "" {
+					fm.Transform = generatePlaceholderTransformName(m.SourcePaths, m.TargetPaths)
+				}
+
 				tm.Fields = append(tm.Fields, fm)
 			} else if len(m.SourcePaths) == 1 && len(m.TargetPaths) == 1 {
 				// Preserve as 121 mappings
@@ -73,6 +118,11 @@ func exportTypePairSuggestions(tp *ResolvedTypePair) mapping.TypeMapping {
 			}
 
 		case MappingSourceYAMLFields:
+			// Fields expanded from flatten directives are exported as the directives
+			if m.Flattened != "" {
+				continue
+			}
+
 			// Preserve explicit fields
 			fm := exportFieldMapping(&m)
 			// If this field mapping needs a transform but doesn't have one, add a placeholder
@@ -80,17 +130,17 @@ func exportTypePairSuggestions(tp *ResolvedTypePair) mapping.TypeMapping {
 				fm.Transform = generatePlaceholderTransformName(m.SourcePaths, m.TargetPaths)
 			}
 
-			tm.Fields = append(tm.Fields, fm)
+			tm.Fields = appendFieldMapping(tm.Fields, fm)
 
 		case MappingSourceYAMLIgnore, MappingSourceYAMLAuto:
 			// Keep these as-is
 			if m.Strategy == StrategyIgnore {
 				for _, tp := range m.TargetPaths {
-					tm.Ignore = append(tm.Ignore, tp.String())
+					tm.Ignore = append(tm.Ignore, exportIgnoreEntry(&m, tp))
 				}
 			} else {
 				fm := exportFieldMapping(&m)
-				tm.Auto = append(tm.Auto, fm)
+				tm.Auto = appendFieldMapping(tm.Auto, fm)
 			}
 
 		case MappingSourceAutoMatched:
@@ -102,9 +152,26 @@ func exportTypePairSuggestions(tp *ResolvedTypePair) mapping.TypeMapping {
 		}
 	}
 
-	// Add unmapped fields as ignored - user can review and move to fields if needed
+	// Add unmapped fields as ignored - user can review and move to fields if needed.
+	// Fields matching a many-to-one pattern are suggested with a placeholder
+	// transform combining the group instead.
 	for _, um := range tp.UnmappedTargets {
-		tm.Ignore = append(tm.Ignore, um.TargetPath.String())
+		if len(um.Group) > 0 {
+			sources := make(mapping.FieldRefArray, len(um.Group))
+			for i, sp := range um.Group {
+				sources[i] = mapping.FieldRef{Path: sp.String()}
+			}
+
+			tm.Fields = append(tm.Fields, mapping.FieldMapping{
+				Source:    sources,
+				Target:    mapping.FieldRefArray{{Path: um.TargetPath.String()}},
+				Transform: generatePlaceholderTransformName(um.Group, []mapping.FieldPath{um.TargetPath}),
+			})
+
+			continue
+		}
+
+		tm.Ignore = append(tm.Ignore, mapping.IgnoreEntry{Target: um.TargetPath.String()})
 	}
 
 	return tm
@@ -124,16 +191,54 @@ func generatePlaceholderTransformName(sourcePaths []mapping.FieldPath, targetPat
 		targetName = targetPaths[0].String()
 	}
 
-	// Create a descriptive placeholder name
-	return fmt.Sprintf("TODO_%sTo%s", sourceName, targetName)
+	// Create a descriptive placeholder name, dropping the dots and brackets
+	// of nested paths so it stays a Go identifier
+	name := fmt.Sprintf("TODO_%sTo%s", sourceName, targetName)
+
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return -1
+	}, name)
+}
+
+// appendFieldMapping appends fm to fields, merging the keys of a from_map_keys
+// mapping into the previous one when it parses keys of the same source map.
+func appendFieldMapping(fields []mapping.FieldMapping, fm mapping.FieldMapping) []mapping.FieldMapping {
+	if n := len(fields); n > 0 && len(fm.FromMapKeys) > 0 {
+		last := &fields[n-1]
+
+		if len(last.FromMapKeys) > 0 && last.Source.First() == fm.Source.First() && last.When == fm.When &&
+			last.ElseDefault == fm.ElseDefault && last.Merge == fm.Merge && last.Deprecated == fm.Deprecated &&
+			last.Sunset == fm.Sunset {
+			maps.Copy(last.FromMapKeys, fm.FromMapKeys)
+			return fields
+		}
+	}
+
+	return append(fields, fm)
+}
+
+// exportIgnoreEntry returns the ignore entry of target tp of an explicitly
+// ignored mapping m, with its metadata.
+func exportIgnoreEntry(m *ResolvedFieldMapping, tp mapping.FieldPath) mapping.IgnoreEntry {
+	if m.Ignore == nil {
+		return mapping.IgnoreEntry{Target: tp.String()}
+	}
+
+	return mapping.IgnoreEntry{
+		Target: tp.String(), Reason: m.Ignore.Reason, Until: m.Ignore.Until, Ticket: m.Ignore.Ticket,
+	}
 }
 
 // exportFieldMapping converts a ResolvedFieldMapping to a mapping.FieldMapping.
 func exportFieldMapping(m *ResolvedFieldMapping) mapping.FieldMapping {
 	fm := mapping.FieldMapping{}
 
-	// Set sources with hints
-	if len(m.SourcePaths) > 0 {
+	// Set sources with hints; collected sources are exported with collect
+	if len(m.SourcePaths) > 0 && len(m.Collect) == 0 {
 		sources := make(mapping.FieldRefArray, len(m.SourcePaths))
 
 		for i, sp := range m.SourcePaths {
@@ -169,8 +274,19 @@ func exportFieldMapping(m *ResolvedFieldMapping) mapping.FieldMapping {
 
 	fm.Target = targets
 
-	// Set default
-	if m.Default != nil {
+	// A parsed map key is exported with from_map_keys, which names its target
+	if m.MapKey != "" && len(m.TargetPaths) == 1 {
+		fm.Target = nil
+		fm.FromMapKeys = map[string]string{m.MapKey: m.TargetPaths[0].String()}
+	}
+
+	// Set default, or the const or expr value it was planned from
+	switch {
+	case m.Const != "":
+		fm.Const = m.Const
+	case m.Expr != "":
+		fm.Expr = m.Expr
+	case m.Default != nil:
 		fm.Default = m.Default
 	}
 
@@ -184,6 +300,23 @@ func exportFieldMapping(m *ResolvedFieldMapping) mapping.FieldMapping {
 		fm.Extra = m.Extra
 	}
 
+	fm.NilToEmpty = m.NilToEmpty
+	fm.PreserveNil = m.PreserveNil
+	fm.DedupBy = m.DedupBy
+	fm.Merge = m.Merge
+	fm.Copy = m.Copy
+	fm.EnumMap = m.EnumMap
+	fm.Collect = m.Collect
+	fm.OmitZero = m.OmitZero
+	fm.Tags = m.Tags
+	fm.EnumDefault = m.EnumDefault
+	fm.EnumStrict = m.EnumStrict
+	fm.NullDefault = m.NullDefault
+	fm.Deprecated = m.Deprecated
+	fm.Sunset = m.Sunset
+	fm.When = m.When
+	fm.ElseDefault = m.ElseDefault
+
 	return fm
 }
 
@@ -201,6 +334,17 @@ type TypePairReport struct {
 	ExplicitCount int
 	IgnoredCount  int
 	NeedsReview   bool
+	// Cost is the estimated cost of the pair's caster.
+	Cost Cost
+	// Assignments lists the cost of each generated assignment.
+	Assignments []AssignmentReport
+}
+
+// AssignmentReport describes the estimated cost of an assignment.
+type AssignmentReport struct {
+	TargetField string
+	Strategy    string
+	Cost        Cost
 }
 
 // MatchReport describes an auto-matched field.
@@ -217,13 +361,16 @@ type UnmappedReport struct {
 	TargetField string
 	Reason      string
 	Candidates  []CandidateReport
+	Group       []string
 }
 
 // CandidateReport describes a potential match candidate.
 type CandidateReport struct {
-	SourceField string
-	Score       float64
-	TypeCompat  string
+	SourceField   string
+	Score         float64
+	TypeCompat    string
+	PositionScore float64
+	Explanation   string
 }
 
 // GenerateReport creates a suggestion report from a resolved plan.
@@ -238,13 +385,23 @@ func GenerateReport(plan *ResolvedMappingPlan) *SuggestionReport {
 			Target:      tp.TargetType.ID.String(),
 			AutoMatched: []MatchReport{},
 			Unmapped:    []UnmappedReport{},
+			Cost:        PairCost(&tp, plan.CopyMode),
+			Assignments: []AssignmentReport{},
 		}
 
 		for _, m := range tp.Mappings {
+			if m.Strategy != StrategyIgnore && len(m.TargetPaths) > 0 {
+				tpr.Assignments = append(tpr.Assignments, AssignmentReport{
+					TargetField: m.TargetPaths[0].String(),
+					Strategy:    m.Strategy.String(),
+					Cost:        AssignmentCost(&m, &tp, plan.CopyMode),
+				})
+			}
+
 			switch m.Source {
 			case MappingSourceYAML121, MappingSourceYAMLFields, MappingSourceYAMLAuto:
 				tpr.ExplicitCount++
-			case MappingSourceYAMLIgnore:
+			case MappingSourceYAMLIgnore, MappingSourceTagIgnore, MappingSourcePatternIgnore, MappingSourceSharedTarget:
 				tpr.IgnoredCount++
 			case MappingSourceAutoMatched:
 				if len(m.SourcePaths) > 0 && len(m.TargetPaths) > 0 {
@@ -266,11 +423,17 @@ func GenerateReport(plan *ResolvedMappingPlan) *SuggestionReport {
 				Candidates:  []CandidateReport{},
 			}
 
+			for _, sp := range um.Group {
+				umr.Group = append(umr.Group, sp.String())
+			}
+
 			for _, c := range um.Candidates {
 				umr.Candidates = append(umr.Candidates, CandidateReport{
-					SourceField: c.SourceField.Name,
-					Score:       c.CombinedScore,
-					TypeCompat:  c.TypeCompat.Compatibility.String(),
+					SourceField:   c.SourceField.Name,
+					Score:         c.CombinedScore,
+					TypeCompat:    c.TypeCompat.Compatibility.String(),
+					PositionScore: c.PositionScore,
+					Explanation:   c.Explain(),
 				})
 			}
 
@@ -285,6 +448,20 @@ func GenerateReport(plan *ResolvedMappingPlan) *SuggestionReport {
 	return report
 }
 
+// SortByCost orders the type pairs, and the assignments of each pair, from
+// the most to the least costly. Ties keep their order.
+func (r *SuggestionReport) SortByCost() {
+	slices.SortStableFunc(r.TypePairs, func(a, b TypePairReport) int {
+		return b.Cost.Score - a.Cost.Score
+	})
+
+	for i := range r.TypePairs {
+		slices.SortStableFunc(r.TypePairs[i].Assignments, func(a, b AssignmentReport) int {
+			return b.Cost.Score - a.Cost.Score
+		})
+	}
+}
+
 // FormatReport formats a suggestion report as human-readable text.
 func FormatReport(report *SuggestionReport) string {
 	var result string
@@ -299,6 +476,7 @@ func FormatReport(report *SuggestionReport) string {
 		resultSb250.WriteString(fmt.Sprintf("\n=== %s -> %s ===\n", tp.Source, tp.Target))
 		resultSb250.WriteString(fmt.Sprintf("Explicit: %d, Ignored: %d, Auto-mapped: %d, Unmapped: %d\n",
 			tp.ExplicitCount, tp.IgnoredCount, len(tp.AutoMatched), len(tp.Unmapped)))
+		resultSb250.WriteString("Cost: " + formatCost(tp.Cost) + "\n")
 
 		if len(tp.AutoMatched) > 0 {
 			resultSb250.WriteString("\nAuto-mapped fields:\n")
@@ -323,11 +501,22 @@ func FormatReport(report *SuggestionReport) string {
 			for _, um := range tp.Unmapped {
 				resultSb265.WriteString(fmt.Sprintf("  ✗ %s: %s\n", um.TargetField, um.Reason))
 
+				if len(um.Group) > 0 {
+					resultSb265.WriteString(fmt.Sprintf("    Combine: %s (with a transform)\n", strings.Join(um.Group, " + ")))
+				}
+
 				if len(um.Candidates) > 0 {
 					resultSb265.WriteString("    Suggestions:\n")
 
 					var resultSb269 strings.Builder
 					for i, c := range um.Candidates {
+						if c.PositionScore > 0 {
+							resultSb269.WriteString(fmt.Sprintf("      %d. %s (%.0f%%, %s, position %.0f%%)\n",
+								i+1, c.SourceField, c.Score*100, c.TypeCompat, c.PositionScore*100))
+
+							continue
+						}
+
 						resultSb269.WriteString(fmt.Sprintf("      %d. %s (%.0f%%, %s)\n",
 							i+1, c.SourceField, c.Score*100, c.TypeCompat))
 					}
@@ -341,6 +530,14 @@ func FormatReport(report *SuggestionReport) string {
 			resultSb258.WriteString(resultSb265.String())
 		}
 
+		if len(tp.Assignments) > 0 {
+			resultSb250.WriteString("\nAssignment costs:\n")
+
+			for _, a := range tp.Assignments {
+				resultSb250.WriteString(fmt.Sprintf("  %s (%s): %s\n", a.TargetField, a.Strategy, formatCost(a.Cost)))
+			}
+		}
+
 		if tp.NeedsReview {
 			resultSb250.WriteString("\n⚠ This type pair needs manual review.\n")
 		} else {
@@ -356,3 +553,8 @@ func FormatReport(report *SuggestionReport) string {
 
 	return result
 }
+
+// formatCost formats a cost for FormatReport.
+func formatCost(c Cost) string {
+	return fmt.Sprintf("score %d (allocations %d, calls %d, loop depth %d)", c.Score, c.Allocations, c.Calls, c.LoopDepth)
+}
@@ -2,6 +2,9 @@ package plan
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -19,6 +22,9 @@ type ExportConfig struct {
 	AmbiguityThreshold float64
 	// IncludeRejectedComments adds comments explaining why fields were rejected.
 	IncludeRejectedComments bool
+	// IncludeThresholdSummary adds a header comment showing how many fields
+	// would auto-match at alternative thresholds.
+	IncludeThresholdSummary bool
 }
 
 // DefaultExportConfig returns default export configuration.
@@ -47,11 +53,70 @@ func ExportSuggestionsYAMLWithConfig(plan *ResolvedMappingPlan, config ExportCon
 	root := &yaml.Node{Kind: yaml.MappingNode}
 
 	// Add version
+	versionKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "version"}
+	if config.IncludeThresholdSummary {
+		versionKey.HeadComment = thresholdSummary(plan, config)
+	}
+
 	root.Content = append(root.Content,
-		&yaml.Node{Kind: yaml.ScalarNode, Value: "version"},
+		versionKey,
 		&yaml.Node{Kind: yaml.ScalarNode, Value: mf.Version},
 	)
 
+	if mf.CopyMode != mapping.CopyDefault {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "copy_mode"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(mf.CopyMode)},
+		)
+	}
+
+	root.Content = appendFlowList(root.Content, "ignore_tags", mf.IgnoreTags)
+	root.Content = appendFlowList(root.Content, "ignore_patterns", mf.IgnorePatterns)
+	root.Content = appendFlowList(root.Content, "strip_affixes", mf.StripAffixes)
+
+	root.Content, err = appendEncoded(root.Content, "match", mf.Match, matchOptionCount(&mf.Match))
+	if err != nil {
+		return nil, err
+	}
+
+	if mf.OnIncompatiblePin != mapping.PinDefault {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "on_incompatible_pin"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(mf.OnIncompatiblePin)},
+		)
+	}
+
+	if !mf.Policy.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "policy", mf.Policy, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !mf.Currency.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "currency", mf.Currency, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mf.Naming.Func != "" {
+		naming := &yaml.Node{Kind: yaml.MappingNode}
+		naming.Content = append(naming.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "func"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: mf.Naming.Func},
+		)
+
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "naming"}, naming)
+	}
+
+	if !mf.GeneratedTypes.IsZero() {
+		root.Content, err = appendEncoded(root.Content, "generated_types", mf.GeneratedTypes, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Add mappings
 	mappingsKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "mappings"}
 	mappingsValue := &yaml.Node{Kind: yaml.SequenceNode}
@@ -81,18 +146,90 @@ func ExportSuggestionsYAMLWithConfig(plan *ResolvedMappingPlan, config ExportCon
 	// Add transforms if present
 	root.Content = appendNamedList(root.Content, "transforms", mf.Transforms,
 		func(t mapping.TransformDef) string { return t.Name },
-		func(t mapping.TransformDef) (string, string) {
-			if t.Func != "" {
-				return "func", t.Func
+		func(t mapping.TransformDef) []*yaml.Node {
+			if t.Template != "" {
+				return appendFlowList(scalarPair("template", t.Template), "imports", t.Imports)
 			}
 
-			return "", ""
+			return scalarPair("func", t.Func)
 		},
 	)
 
+	// Add wrappers if present
+	root.Content, err = appendEncoded(root.Content, "wrappers", mf.Wrappers, len(mf.Wrappers))
+	if err != nil {
+		return nil, err
+	}
+
+	// Add implementations if present
+	root.Content, err = appendEncoded(root.Content, "implementations", mf.Implementations, len(mf.Implementations))
+	if err != nil {
+		return nil, err
+	}
+
 	return yaml.Marshal(root)
 }
 
+// appendEncoded appends a key with the YAML encoding of value, unless count is zero.
+func appendEncoded(parentContent []*yaml.Node, key string, value any, count int) ([]*yaml.Node, error) {
+	if count == 0 {
+		return parentContent, nil
+	}
+
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", key, err)
+	}
+
+	return append(parentContent, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode), nil
+}
+
+// matchOptionCount returns the number of options set in m.
+func matchOptionCount(m *mapping.MatchOptions) int {
+	n := len(m.Synonyms) + len(m.Abbreviations)
+	if m.ExplicitDefinedTypes {
+		n++
+	}
+
+	if m.PositionWeight != 0 {
+		n++
+	}
+
+	if m.Tag != "" {
+		n++
+	}
+
+	return n
+}
+
+// appendFlowList appends key and the flow sequence of values to a mapping
+// node's content, unless values is empty.
+func appendFlowList(parentContent []*yaml.Node, key string, values []string) []*yaml.Node {
+	if len(values) == 0 {
+		return parentContent
+	}
+
+	list := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, value := range values {
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+	}
+
+	return append(parentContent, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, list)
+}
+
+// scalarPair returns the key and value nodes of a scalar entry, or none if
+// value is empty.
+func scalarPair(key, value string) []*yaml.Node {
+	if value == "" {
+		return nil
+	}
+
+	return []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: key},
+		{Kind: yaml.ScalarNode, Value: value},
+	}
+}
+
 // findResolvedTypePair recursively finds a resolved type pair by source and target IDs.
 func findResolvedTypePair(plan *ResolvedMappingPlan, source, target string) *ResolvedTypePair {
 	for i := range plan.TypePairs {
@@ -130,16 +267,129 @@ func buildTypeMappingNode(tm *mapping.TypeMapping, resolvedTP *ResolvedTypePair,
 		&yaml.Node{Kind: yaml.ScalarNode, Value: tm.Target},
 	)
 
+	// via
+	if tm.Via != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "via"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: tm.Via},
+		)
+	}
+
+	// before, after and method
+	for _, opt := range [][2]string{{"before", tm.Before}, {"after", tm.After}, {"method", tm.Method}} {
+		if opt[1] != "" {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: opt[0]},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: opt[1]},
+			)
+		}
+	}
+
+	// tags
+	if len(tm.Tags) > 0 {
+		tags := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, tag := range tm.Tags {
+			tags.Content = append(tags.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag})
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tags"}, tags)
+	}
+
+	// generate_target
+	if tm.GenerateTarget {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "generate_target"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	// generate_merge
+	if tm.GenerateMerge {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "generate_merge"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	// allow_unexported
+	if tm.AllowUnexported {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "allow_unexported"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	// max_depth
+	if tm.MaxDepth > 0 {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "max_depth"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strconv.Itoa(tm.MaxDepth)},
+		)
+	}
+
+	// require_full_coverage
+	if tm.RequireFullCoverage {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "require_full_coverage"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	// matching
+	if !tm.Matching.IsZero() {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "matching"}, buildMatchingNode(tm.Matching))
+	}
+
+	// flatten / unflatten
+	for _, directive := range []struct {
+		key    string
+		fields []string
+	}{{"flatten", tm.Flatten}, {"unflatten", tm.Unflatten}} {
+		if len(directive.fields) == 0 {
+			continue
+		}
+
+		fields := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, f := range directive.fields {
+			fields.Content = append(fields.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f})
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: directive.key}, fields)
+	}
+
+	// output
+	if tm.Output != nil {
+		out := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+		if tm.Output.Package != "" {
+			out.Content = append(out.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "package"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: tm.Output.Package},
+			)
+		}
+
+		if tm.Output.Dir != "" {
+			out.Content = append(out.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: "dir"},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: tm.Output.Dir},
+			)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "output"}, out)
+	}
+
+	// copy_mode
+	if tm.CopyMode != mapping.CopyDefault {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "copy_mode"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(tm.CopyMode)},
+		)
+	}
+
 	// requires
 	node.Content = appendNamedList(node.Content, "requires", tm.Requires,
 		func(a mapping.ArgDef) string { return a.Name },
-		func(a mapping.ArgDef) (string, string) {
-			if a.Type != "" {
-				return "type", a.Type
-			}
-
-			return "", ""
-		},
+		func(a mapping.ArgDef) []*yaml.Node { return scalarPair("type", a.Type) },
 	)
 
 	// 121
@@ -161,11 +411,19 @@ func appendOneToOne(node *yaml.Node, oneToOne map[string]string) {
 	if len(oneToOne) > 0 {
 		oneToOneKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "121"}
 
+		// Sort by source path so output is stable across runs
+		sources := make([]string, 0, len(oneToOne))
+		for src := range oneToOne {
+			sources = append(sources, src)
+		}
+
+		sort.Strings(sources)
+
 		oneToOneValue := &yaml.Node{Kind: yaml.MappingNode}
-		for src, tgt := range oneToOne {
+		for _, src := range sources {
 			oneToOneValue.Content = append(oneToOneValue.Content,
 				&yaml.Node{Kind: yaml.ScalarNode, Value: src},
-				&yaml.Node{Kind: yaml.ScalarNode, Value: tgt},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: oneToOne[src]},
 			)
 		}
 
@@ -187,7 +445,7 @@ func appendFields(node *yaml.Node, fields []mapping.FieldMapping) {
 	}
 }
 
-func appendIgnore(node *yaml.Node, ignore []string, resolvedTP *ResolvedTypePair, config ExportConfig) {
+func appendIgnore(node *yaml.Node, ignore []mapping.IgnoreEntry, resolvedTP *ResolvedTypePair, config ExportConfig) {
 	if len(ignore) > 0 || (resolvedTP != nil && len(resolvedTP.UnmappedTargets) > 0 && config.IncludeRejectedComments) {
 		ignoreKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "ignore"}
 		ignoreValue := &yaml.Node{Kind: yaml.SequenceNode}
@@ -198,8 +456,9 @@ func appendIgnore(node *yaml.Node, ignore []string, resolvedTP *ResolvedTypePair
 				config.MinConfidence, config.MinGap, config.AmbiguityThreshold)
 		}
 
-		for _, ignorePath := range ignore {
-			ignoreNode := &yaml.Node{Kind: yaml.ScalarNode, Value: ignorePath}
+		for _, ig := range ignore {
+			ignorePath := ig.Target
+			ignoreNode := buildIgnoreNode(&ig)
 
 			// Find the corresponding unmapped field for comment
 			if config.IncludeRejectedComments && resolvedTP != nil {
@@ -218,6 +477,15 @@ func appendIgnore(node *yaml.Node, ignore []string, resolvedTP *ResolvedTypePair
 									break
 								}
 
+								if c.PositionScore > 0 {
+									commentParts = append(commentParts,
+										fmt.Sprintf("  %d. %s (score=%.2f, type=%s, position=%.2f)",
+											i+1, c.SourceField.Name, c.CombinedScore, c.TypeCompat.Compatibility.String(),
+											c.PositionScore))
+
+									continue
+								}
+
 								commentParts = append(commentParts,
 									fmt.Sprintf("  %d. %s (score=%.2f, type=%s)",
 										i+1, c.SourceField.Name, c.CombinedScore, c.TypeCompat.Compatibility.String()))
@@ -238,6 +506,20 @@ func appendIgnore(node *yaml.Node, ignore []string, resolvedTP *ResolvedTypePair
 	}
 }
 
+// buildIgnoreNode builds the node of an ignore entry: its target path, or a
+// flow mapping with its metadata.
+func buildIgnoreNode(ig *mapping.IgnoreEntry) *yaml.Node {
+	if !ig.HasMetadata() {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: ig.Target}
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+	node.Content = slices.Concat(scalarPair("target", ig.Target), scalarPair("reason", ig.Reason),
+		scalarPair("until", ig.Until), scalarPair("ticket", ig.Ticket))
+
+	return node
+}
+
 func appendAuto(node *yaml.Node, auto []mapping.FieldMapping, resolvedTP *ResolvedTypePair) {
 	if len(auto) > 0 {
 		autoKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "auto"}
@@ -272,7 +554,7 @@ func appendNamedList[T any](
 	key string,
 	items []T,
 	getName func(T) string,
-	getExtra func(T) (string, string)) []*yaml.Node {
+	getExtra func(T) []*yaml.Node) []*yaml.Node {
 	if len(items) == 0 {
 		return parentContent
 	}
@@ -289,13 +571,7 @@ func appendNamedList[T any](
 			&yaml.Node{Kind: yaml.ScalarNode, Value: name},
 		)
 
-		extraKey, extraVal := getExtra(item)
-		if extraKey != "" && extraVal != "" {
-			itemNode.Content = append(itemNode.Content,
-				&yaml.Node{Kind: yaml.ScalarNode, Value: extraKey},
-				&yaml.Node{Kind: yaml.ScalarNode, Value: extraVal},
-			)
-		}
+		itemNode.Content = append(itemNode.Content, getExtra(item)...)
 
 		seqValue.Content = append(seqValue.Content, itemNode)
 	}
@@ -351,6 +627,170 @@ func buildFieldMappingNode(fm *mapping.FieldMapping) *yaml.Node {
 		)
 	}
 
+	if fm.Const != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "const"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.Const},
+		)
+	}
+
+	if fm.Expr != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "expr"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.Expr},
+		)
+	}
+
+	// collected sources
+	if len(fm.Collect) > 0 {
+		collectValue := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, e := range mapping.CollectEntries(fm.Collect) {
+			collectValue.Content = append(collectValue.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: e.Source},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: e.Key},
+			)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "collect"}, collectValue)
+	}
+
+	if fm.OmitZero {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "omit_zero"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	// parsed map keys
+	if len(fm.FromMapKeys) > 0 {
+		keysValue := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, e := range mapping.MapKeyEntries(fm.FromMapKeys) {
+			keysValue.Content = append(keysValue.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: e.Key},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: e.Target},
+			)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "from_map_keys"}, keysValue)
+	}
+
+	// nil handling
+	if fm.NilToEmpty {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "nil_to_empty"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	if fm.PreserveNil {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "preserve_nil"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	if fm.DedupBy != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "dedup_by"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.DedupBy},
+		)
+	}
+
+	if fm.Copy != mapping.CopyDefault {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "copy"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(fm.Copy)},
+		)
+	}
+
+	if fm.Merge != mapping.MergeDefault {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "merge"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: string(fm.Merge)},
+		)
+	}
+
+	// struct tags of generated target fields
+	if len(fm.Tags) > 0 {
+		tagsValue := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, k := range mapping.SortedEnumKeys(fm.Tags) {
+			tagsValue.Content = append(tagsValue.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: k},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: fm.Tags[k]},
+			)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tags"}, tagsValue)
+	}
+
+	// enum value table
+	if len(fm.EnumMap) > 0 {
+		enumValue := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, k := range mapping.SortedEnumKeys(fm.EnumMap) {
+			enumValue.Content = append(enumValue.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: k},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: fm.EnumMap[k]},
+			)
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "enum_map"}, enumValue)
+	}
+
+	if fm.EnumDefault != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "enum_default"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.EnumDefault},
+		)
+	}
+
+	if fm.EnumStrict {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "enum_strict"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "true"},
+		)
+	}
+
+	if fm.NullDefault != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "null_default"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.NullDefault},
+		)
+	}
+
+	// condition
+	if fm.When != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "when"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.When},
+		)
+	}
+
+	if fm.ElseDefault != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "else_default"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.ElseDefault},
+		)
+	}
+
+	// deprecation
+	if fm.Deprecated != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "deprecated"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.Deprecated},
+		)
+	}
+
+	if fm.Sunset != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "sunset"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: fm.Sunset},
+		)
+	}
+
 	// extra
 	if len(fm.Extra) > 0 {
 		extraKey := &yaml.Node{Kind: yaml.ScalarNode, Value: "extra"}
@@ -386,6 +826,10 @@ func buildFieldMappingNode(fm *mapping.FieldMapping) *yaml.Node {
 				)
 			}
 
+			if ev.Const != "" {
+				evNode.Content = append(evNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "const"}, ev.Const.Node())
+			}
+
 			extraValue.Content = append(extraValue.Content, evNode)
 		}
 
@@ -429,3 +873,26 @@ func buildFieldRefArrayNode(refs mapping.FieldRefArray) *yaml.Node {
 
 	return node
 }
+
+// buildMatchingNode creates a flow mapping of the thresholds matching sets.
+func buildMatchingNode(matching mapping.Matching) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+
+	for _, threshold := range []struct {
+		key   string
+		value float64
+	}{
+		{"min_confidence", matching.MinConfidence},
+		{"min_gap", matching.MinGap},
+		{"ambiguity_threshold", matching.AmbiguityThreshold},
+	} {
+		if threshold.value != 0 {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: threshold.key},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatFloat(threshold.value, 'f', -1, 64)},
+			)
+		}
+	}
+
+	return node
+}
@@ -0,0 +1,119 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+)
+
+// ignoreTaggedFields ignores the exported target fields tagged "-" with one of
+// the mapping file's ignore_tags keys (e.g., json:"-"), unless a rule already
+// maps them or one of their subfields, and reports each with an info.
+func (r *Resolver) ignoreTaggedFields(
+	result *ResolvedTypePair,
+	targetType *analyze.TypeInfo,
+	mappedTargets map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	if len(r.mappingDef.IgnoreTags) == 0 {
+		return
+	}
+
+	for _, f := range targetType.Fields {
+		if !f.Exported || mappedTargets[f.Name] || mappedBelow(f.Name, mappedTargets) {
+			continue
+		}
+
+		key := ignoreTag(&f, r.mappingDef.IgnoreTags)
+		if key == "" {
+			continue
+		}
+
+		tag := fmt.Sprintf("%s:%q", key, "-")
+		result.Mappings = append(result.Mappings, ResolvedFieldMapping{
+			TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: f.Name}}}},
+			Source:      MappingSourceTagIgnore,
+			Strategy:    StrategyIgnore,
+			Explanation: "ignored by tag " + tag,
+		})
+		mappedTargets[f.Name] = true
+
+		diags.AddInfo("ignored_by_tag",
+			fmt.Sprintf("target field %q ignored by tag %s", f.Name, tag),
+			typePairStr, f.Name)
+	}
+}
+
+// ignorePatternFields ignores the exported target fields whose name matches
+// one of the mapping file's ignore_patterns (e.g., "XXX_*"), unless a rule
+// already maps them or one of their subfields, and reports each with an info.
+// Malformed patterns are reported by Validate and skipped here.
+func (r *Resolver) ignorePatternFields(
+	result *ResolvedTypePair,
+	targetType *analyze.TypeInfo,
+	mappedTargets map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	var patterns []*mapping.FieldPattern
+
+	for _, p := range r.mappingDef.IgnorePatterns {
+		if pattern, err := mapping.CompileFieldPattern(p); err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return
+	}
+
+	for _, f := range targetType.Fields {
+		if !f.Exported || mappedTargets[f.Name] || mappedBelow(f.Name, mappedTargets) {
+			continue
+		}
+
+		i := slices.IndexFunc(patterns, func(p *mapping.FieldPattern) bool { return p.Match(f.Name) })
+		if i < 0 {
+			continue
+		}
+
+		result.Mappings = append(result.Mappings, ResolvedFieldMapping{
+			TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: f.Name}}}},
+			Source:      MappingSourcePatternIgnore,
+			Strategy:    StrategyIgnore,
+			Explanation: fmt.Sprintf("ignored by pattern %q", patterns[i]),
+		})
+		mappedTargets[f.Name] = true
+
+		diags.AddInfo("ignored_by_pattern",
+			fmt.Sprintf("target field %q ignored by pattern %q", f.Name, patterns[i]),
+			typePairStr, f.Name)
+	}
+}
+
+// ignoreTag returns the first of keys whose tag value on f is "-", if any.
+func ignoreTag(f *analyze.FieldInfo, keys []string) string {
+	for _, key := range keys {
+		if f.Tag.Get(key) == "-" {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// mappedBelow reports whether a subfield of target field name is mapped.
+func mappedBelow(name string, mappedTargets map[string]bool) bool {
+	for path := range mappedTargets {
+		if strings.HasPrefix(path, name+".") || strings.HasPrefix(path, name+"[") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+)
+
+// checkExistingTarget handles a generate_target mapping whose target type
+// already exists, e.g. written by hand. The existing type is reused when it
+// declares every field the generated type would, with the same types; fields
+// only it declares are reported as drift. Otherwise generating the target
+// would redeclare the type, so an error suggests renaming it.
+func (r *Resolver) checkExistingTarget(
+	tm *mapping.TypeMapping,
+	sourceType, existing *analyze.TypeInfo,
+	diags *diagnostic.Diagnostics,
+) error {
+	typePairStr := fmt.Sprintf("%s->%s", sourceType.ID, existing.ID)
+	generated, _ := r.buildMergedTargetType(tm, sourceType)
+
+	// The generated type of transformed fields is guessed from their source,
+	// so only their presence is checked.
+	transformed := make(map[string]bool)
+
+	for _, c := range r.targetContributors(tm, sourceType) {
+		for _, fm := range c.mapping.Fields {
+			for _, t := range fm.Target {
+				transformed[t.Path] = transformed[t.Path] || fm.Transform != "" || len(fm.EnumMap) > 0 ||
+					len(fm.Collect) > 0
+			}
+
+			for _, target := range fm.FromMapKeys {
+				transformed[target] = true
+			}
+		}
+	}
+
+	var conflicts []string
+
+	if existing.Kind != analyze.TypeKindStruct {
+		conflicts = append(conflicts, "it is not a struct")
+	} else {
+		for _, f := range generated.Fields {
+			field := existing.FieldByName(f.Name)
+
+			switch {
+			case field == nil:
+				conflicts = append(conflicts, fmt.Sprintf("field %s is missing", f.Name))
+			case !transformed[f.Name] && !sameGoType(field.Type, f.Type):
+				conflicts = append(conflicts, fmt.Sprintf("field %s is %s instead of %s",
+					f.Name, field.Type.GoType, f.Type.GoType))
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("generate_target: %s already exists and differs from the generated type (%s); "+
+			"rename the target (e.g., %s) or drop generate_target",
+			existing.ID, strings.Join(conflicts, ", "), r.freeTypeName(existing.ID))
+	}
+
+	diags.AddWarning("generated_target_exists",
+		fmt.Sprintf("target %s already exists; reusing it instead of generating it", existing.ID),
+		typePairStr, "")
+
+	for _, f := range existing.Fields {
+		if f.Exported && generated.FieldByName(f.Name) == nil {
+			diags.AddWarning("generated_target_drift",
+				fmt.Sprintf("existing target declares field %q, which the mapping doesn't generate", f.Name),
+				typePairStr, f.Name)
+		}
+	}
+
+	return nil
+}
+
+// freeTypeName suggests a name for a generated type that doesn't collide with
+// the types of id's package.
+func (r *Resolver) freeTypeName(id analyze.TypeID) string {
+	name := id.Name + "Generated"
+
+	for i := 2; r.graph.GetType(analyze.TypeID{PkgPath: id.PkgPath, Name: name}) != nil; i++ {
+		name = fmt.Sprintf("%sGenerated%d", id.Name, i)
+	}
+
+	return name
+}
+
+// sameGoType reports whether existing field type x matches generated field
+// type y. Generated types have no go/types counterpart yet, so they match.
+func sameGoType(x, y *analyze.TypeInfo) bool {
+	if x == nil || y == nil || x.GoType == nil || y.GoType == nil {
+		return true
+	}
+
+	return types.Identical(x.GoType, y.GoType)
+}
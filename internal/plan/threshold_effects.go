@@ -0,0 +1,174 @@
+package plan
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"caster-generator/internal/match"
+)
+
+// riskyNameScore is the name score below which an extra match counts as
+// risky: it was accepted more for its type than for its name.
+const riskyNameScore = 0.5
+
+// ThresholdEffect describes how auto-matching would change under other
+// thresholds, computed from the candidate scores of a resolved plan.
+type ThresholdEffect struct {
+	// MinConfidence is the alternative confidence threshold.
+	MinConfidence float64
+	// MinGap is the alternative gap threshold.
+	MinGap float64
+	// Added counts unmapped targets whose best candidate would be accepted.
+	Added int
+	// Risky counts added matches needing a transform, with a low name score,
+	// or with a runner-up within the ambiguity threshold.
+	Risky int
+	// Dropped counts auto-matched targets that would no longer be accepted.
+	Dropped int
+}
+
+// String formats the effect as a one-line summary.
+func (e ThresholdEffect) String() string {
+	var parts []string
+
+	if e.Added > 0 {
+		parts = append(parts, fmt.Sprintf("+%s, %d risky", pluralMatches(e.Added), e.Risky))
+	}
+
+	if e.Dropped > 0 {
+		parts = append(parts, "-"+pluralMatches(e.Dropped))
+	}
+
+	if len(parts) == 0 {
+		return "no change"
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// pluralMatches formats a match count.
+func pluralMatches(n int) string {
+	if n == 1 {
+		return "1 match"
+	}
+
+	return fmt.Sprintf("%d matches", n)
+}
+
+// ThresholdEffects computes the effect of min_confidence 0.1 and 0.2 either
+// side of config.MinConfidence, and of lower min_gap values. Raising min_gap
+// is left out, as auto-matched mappings do not keep their runner-up scores,
+// and so are pairs whose mapping sets its own matching thresholds.
+func ThresholdEffects(plan *ResolvedMappingPlan, config ExportConfig) []ThresholdEffect {
+	var effects []ThresholdEffect
+
+	for _, delta := range []float64{-0.2, -0.1, 0.1, 0.2} {
+		alt := roundThreshold(config.MinConfidence + delta)
+		if alt <= 0 || alt > 1 {
+			continue
+		}
+
+		effects = append(effects, thresholdEffect(plan, config, alt, config.MinGap))
+	}
+
+	for _, delta := range []float64{-0.1, -0.05} {
+		alt := roundThreshold(config.MinGap + delta)
+		if alt < 0 {
+			continue
+		}
+
+		effects = append(effects, thresholdEffect(plan, config, config.MinConfidence, alt))
+	}
+
+	return effects
+}
+
+// roundThreshold rounds a threshold to two decimals, hiding float drift.
+func roundThreshold(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// thresholdEffect computes the effect of one pair of thresholds.
+func thresholdEffect(plan *ResolvedMappingPlan, config ExportConfig, minConfidence, minGap float64) ThresholdEffect {
+	effect := ThresholdEffect{MinConfidence: minConfidence, MinGap: minGap}
+
+	forEachResolvedPair(plan, func(tp *ResolvedTypePair) {
+		if !tp.Matching.IsZero() {
+			return
+		}
+
+		for _, uf := range tp.UnmappedTargets {
+			best := uf.Candidates.HighConfidence(minConfidence, minGap)
+			if best == nil {
+				continue
+			}
+
+			effect.Added++
+
+			if isRiskyMatch(uf.Candidates, best, config.AmbiguityThreshold) {
+				effect.Risky++
+			}
+		}
+
+		for _, m := range tp.Mappings {
+			// Matches below the current threshold were accepted structurally
+			// and stay whatever the threshold.
+			if m.Source == MappingSourceAutoMatched &&
+				m.Confidence >= config.MinConfidence && m.Confidence < minConfidence {
+				effect.Dropped++
+			}
+		}
+	})
+
+	return effect
+}
+
+// isRiskyMatch reports whether an extra match deserves a closer look.
+func isRiskyMatch(candidates match.CandidateList, best *match.Candidate, ambiguity float64) bool {
+	return best.TypeCompat.Compatibility == match.TypeNeedsTransform ||
+		best.NameScore < riskyNameScore ||
+		candidates.IsAmbiguous(ambiguity)
+}
+
+// forEachResolvedPair calls fn once for each type pair of the plan,
+// including nested pairs.
+func forEachResolvedPair(plan *ResolvedMappingPlan, fn func(tp *ResolvedTypePair)) {
+	seen := make(map[*ResolvedTypePair]bool)
+
+	var walk func(tp *ResolvedTypePair)
+
+	walk = func(tp *ResolvedTypePair) {
+		if tp == nil || seen[tp] {
+			return
+		}
+
+		seen[tp] = true
+		fn(tp)
+
+		for i := range tp.NestedPairs {
+			walk(tp.NestedPairs[i].ResolvedPair)
+		}
+	}
+
+	for i := range plan.TypePairs {
+		walk(&plan.TypePairs[i])
+	}
+}
+
+// thresholdSummary formats the threshold effects as a comment block.
+func thresholdSummary(plan *ResolvedMappingPlan, config ExportConfig) string {
+	lines := []string{fmt.Sprintf("# Threshold effects (min_confidence=%.2f, min_gap=%.2f):",
+		config.MinConfidence, config.MinGap)}
+
+	for _, e := range ThresholdEffects(plan, config) {
+		name, value := "min_confidence", e.MinConfidence
+		if e.MinConfidence == config.MinConfidence {
+			name, value = "min_gap", e.MinGap
+		}
+
+		lines = append(lines, fmt.Sprintf("#   at %s %.2f: %s", name, value, e))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,88 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/match"
+)
+
+func thresholdCandidate(score, nameScore float64, compat match.TypeCompatibility) match.Candidate {
+	return match.Candidate{
+		NameScore:     nameScore,
+		TypeCompat:    match.TypeCompatibilityResult{Compatibility: compat},
+		CombinedScore: score,
+	}
+}
+
+func TestThresholdEffects(t *testing.T) {
+	nested := &ResolvedTypePair{
+		UnmappedTargets: []UnmappedField{
+			// Accepted at 0.5, but only needs-transform.
+			{Candidates: match.CandidateList{thresholdCandidate(0.55, 0.9, match.TypeNeedsTransform)}},
+		},
+	}
+
+	plan := &ResolvedMappingPlan{
+		TypePairs: []ResolvedTypePair{
+			{
+				Mappings: []ResolvedFieldMapping{
+					{Source: MappingSourceAutoMatched, Confidence: 0.75},
+					{Source: MappingSourceAutoMatched, Confidence: 1},
+					// Accepted structurally below the threshold: never dropped.
+					{Source: MappingSourceAutoMatched, Confidence: 0.4},
+					{Source: MappingSourceYAMLFields},
+				},
+				UnmappedTargets: []UnmappedField{
+					{Candidates: match.CandidateList{thresholdCandidate(0.65, 0.8, match.TypeIdentical)}},
+					// Runner-up too close: accepted only at a lower min_gap, and ambiguous.
+					{Candidates: match.CandidateList{
+						thresholdCandidate(0.72, 0.8, match.TypeIdentical),
+						thresholdCandidate(0.64, 0.8, match.TypeIdentical),
+					}},
+					// Incompatible: never accepted.
+					{Candidates: match.CandidateList{thresholdCandidate(0.9, 1, match.TypeIncompatible)}},
+				},
+				NestedPairs: []NestedConversion{{ResolvedPair: nested}, {ResolvedPair: nested}},
+			},
+		},
+	}
+
+	config := DefaultExportConfig()
+
+	effects := ThresholdEffects(plan, config)
+	if len(effects) != 6 {
+		t.Fatalf("expected 6 effects, got %d", len(effects))
+	}
+
+	tests := []struct {
+		minConfidence, minGap float64
+		want                  string
+	}{
+		{0.5, 0.15, "+2 matches, 1 risky"},
+		{0.6, 0.15, "+1 match, 0 risky"},
+		{0.8, 0.15, "-1 match"},
+		{0.9, 0.15, "-1 match"},
+		{0.7, 0.05, "+1 match, 1 risky"},
+		{0.7, 0.1, "no change"},
+	}
+
+	for i, tt := range tests {
+		e := effects[i]
+		if e.MinConfidence != tt.minConfidence || e.MinGap != tt.minGap {
+			t.Errorf("effect %d: thresholds = %.2f/%.2f, want %.2f/%.2f",
+				i, e.MinConfidence, e.MinGap, tt.minConfidence, tt.minGap)
+		}
+
+		if e.String() != tt.want {
+			t.Errorf("effect %d: got %q, want %q", i, e.String(), tt.want)
+		}
+	}
+
+	config.MinConfidence = 0.6
+	config.MinGap = 0.05
+
+	effects = ThresholdEffects(plan, config)
+	if len(effects) != 5 {
+		t.Fatalf("expected min_gap below zero to be skipped, got %d effects", len(effects))
+	}
+}
@@ -19,6 +19,42 @@ type ResolvedMappingPlan struct {
 	Diagnostics diagnostic.Diagnostics
 	// OriginalTransforms preserves the transforms from the original mapping file.
 	OriginalTransforms []mapping.TransformDef
+	// Wrappers preserves the generic wrapper declarations from the original mapping file.
+	Wrappers []mapping.WrapperDef
+	// Implementations preserves the interface implementation pairs from the original mapping file.
+	Implementations []mapping.ImplementationDef
+	// CopyMode is the file-level default copy mode.
+	CopyMode mapping.CopyMode
+	// IgnoreTags preserves the tag keys marking ignored target fields.
+	IgnoreTags []string
+	// IgnorePatterns preserves the patterns of ignored target field names.
+	IgnorePatterns []string
+	// StripAffixes preserves the name tokens ignored by auto-matching.
+	StripAffixes []string
+	// Synonyms preserves the domain-equivalent names of auto-matching.
+	Synonyms map[string][]string
+	// Abbreviations preserves the name token expansions of auto-matching.
+	Abbreviations map[string]string
+	// ExplicitDefinedTypes preserves whether auto-matching leaves defined type
+	// conversions to explicit rules.
+	ExplicitDefinedTypes bool
+	// PositionWeight preserves the share of relative field position in
+	// candidate scores.
+	PositionWeight float64
+	// MatchTag preserves the struct tag key pinning field matches.
+	MatchTag string
+	// OnIncompatiblePin preserves the policy for 121 mappings with
+	// incompatible types.
+	OnIncompatiblePin mapping.PinPolicy
+	// Policy preserves the mapping file's policy (not its overrides).
+	Policy mapping.Policy
+	// Currency preserves the mapping file's currency, which scales the
+	// builtin money transforms.
+	Currency mapping.Currency
+	// FuncTemplate preserves the naming template of casters.
+	FuncTemplate string
+	// GeneratedTypes preserves the field order and docs of generated structs.
+	GeneratedTypes mapping.GeneratedTypes
 }
 
 // ArgDef represents a function argument definition.
@@ -43,6 +79,45 @@ type ResolvedTypePair struct {
 	Requires []mapping.ArgDef
 	// IsGeneratedTarget is true if the target type is generated from the mapping.
 	IsGeneratedTarget bool
+	// Implicit is true if no mapping declares the pair, which was resolved by
+	// auto-matching as the nested conversion of another pair.
+	Implicit bool
+	// NeedsContext is true if the caster calls a context-aware transform,
+	// directly or through nested casters, and therefore takes a ctx argument.
+	NeedsContext bool
+	// CopyMode is the mapping-level copy mode overriding the plan's CopyMode.
+	CopyMode mapping.CopyMode
+	// Tags are the tags of the mapping the pair was resolved from.
+	Tags []string
+	// GenerateMerge is true if the mapping asks for a merge variant of the caster.
+	GenerateMerge bool
+	// AllowUnexported is true if the mapping reads and assigns unexported fields.
+	AllowUnexported bool
+	// Via is the intermediate type the pair converts through, chaining the
+	// casters of its nested pairs source->Via and Via->target; nil otherwise.
+	Via *analyze.TypeInfo
+	// Before and After are the hook functions the caster calls before and
+	// after converting, or empty.
+	Before string
+	After  string
+	// Method names the method of the source type the caster is generated
+	// as, or is empty for a function.
+	Method string
+	// MaxDepth limits the levels of recursive fields the caster converts
+	// (0 = unlimited).
+	MaxDepth int
+	// RequireFullCoverage is true if the mapping must map every exported
+	// target field explicitly.
+	RequireFullCoverage bool
+	// Matching is the auto-match thresholds the mapping overrides.
+	Matching mapping.Matching
+	// Output is the package the mapping generates the caster into, nil for
+	// the output package.
+	Output *mapping.Output
+	// Flatten and Unflatten are the flatten and unflatten directives of the
+	// mapping the pair was resolved from.
+	Flatten   []string
+	Unflatten []string
 }
 
 // ResolvedFieldMapping represents a single resolved field mapping.
@@ -61,6 +136,25 @@ type ResolvedFieldMapping struct {
 	Transform string
 	// Default value to use if source is empty.
 	Default *string
+	// Const and Expr are the const and expr values of the YAML field mapping,
+	// assigned as Default, whose package qualifiers Qualifiers maps to import
+	// paths (e.g., "warehouse" -> "example.com/warehouse").
+	Const      string
+	Expr       string
+	Qualifiers map[string]string
+	// Collect maps the source paths of a collect mapping to their keys in the
+	// map or []KV target.
+	Collect map[string]string
+	// OmitZero leaves collected sources holding their zero value out.
+	OmitZero bool
+	// Tags are the struct tags the mapping gives its generated target fields.
+	Tags map[string]string
+	// Ignore is the entry of an explicitly ignored target recording why and
+	// until when it is ignored, nil for plain paths.
+	Ignore *mapping.IgnoreEntry
+	// MapKey is the key of the source map a from_map_keys mapping parses into
+	// its target.
+	MapKey string
 	// Confidence score for auto-matched mappings (0-1).
 	Confidence float64
 	// Explanation describes why this mapping was chosen.
@@ -73,6 +167,43 @@ type ResolvedFieldMapping struct {
 	// DependsOnTargets lists target field paths that must be assigned before this mapping.
 	// Derived from extra.def.target references (and potentially other implicit dependencies).
 	DependsOnTargets []mapping.FieldPath
+	// NilToEmpty forces a non-nil empty collection when the source slice/map is nil.
+	NilToEmpty bool
+	// PreserveNil keeps the target slice/map nil when the source is nil.
+	PreserveNil bool
+	// DedupBy is the field of source slice elements whose repeated values are
+	// dropped from the target slice.
+	DedupBy string
+	// Copy is the per-field copy mode overriding the type pair's CopyMode.
+	Copy mapping.CopyMode
+	// Merge is how the merge variant of the caster assigns the field.
+	Merge mapping.MergePolicy
+	// EnumMap is the source -> target value table of an enum_map mapping.
+	EnumMap map[string]string
+	// EnumDefault is the target value for unlisted source values (zero value if empty).
+	EnumDefault string
+	// EnumStrict makes unlisted source values panic instead of using EnumDefault.
+	EnumStrict bool
+	// NullDefault is the target value for NULL sources of a sql_null mapping
+	// (zero value or nil if empty).
+	NullDefault string
+	// Deprecated is the deprecation message of the YAML field mapping, if any.
+	Deprecated string
+	// Sunset is the date after which the deprecated mapping fails check.
+	Sunset string
+	// When is the condition over the source guarding the assignment, if any.
+	When string
+	// ElseDefault is the value assigned when When doesn't hold (none if empty).
+	ElseDefault string
+	// PendingSource is the source path an optional_source mapping is waiting
+	// for. Such a mapping is ignored and leaves a TODO in the caster.
+	PendingSource string
+	// Flattened is the struct field of the flatten or unflatten directive the
+	// mapping was expanded from, if any.
+	Flattened string
+	// Pos is where the rule of the mapping is defined, in the mapping file or
+	// the included file it came from; invalid for rules the resolver derived.
+	Pos diagnostic.Position
 }
 
 // MappingSource indicates where a mapping rule originated.
@@ -87,6 +218,12 @@ const (
 	MappingSourceYAMLIgnore
 	// MappingSourceYAMLAuto - from YAML auto section.
 	MappingSourceYAMLAuto
+	// MappingSourceTagIgnore - target field ignored by a "-" tag listed in ignore_tags.
+	MappingSourceTagIgnore
+	// MappingSourcePatternIgnore - target field ignored by a name listed in ignore_patterns.
+	MappingSourcePatternIgnore
+	// MappingSourceSharedTarget - field of a generated target filled by the caster of another source.
+	MappingSourceSharedTarget
 	// MappingSourceAutoMatched - auto-matched by best-effort algorithm.
 	MappingSourceAutoMatched
 )
@@ -102,6 +239,12 @@ func (s MappingSource) String() string {
 		return "yaml:ignore"
 	case MappingSourceYAMLAuto:
 		return "yaml:auto"
+	case MappingSourceTagIgnore:
+		return "tag:ignore"
+	case MappingSourcePatternIgnore:
+		return "pattern:ignore"
+	case MappingSourceSharedTarget:
+		return "shared_target"
 	case MappingSourceAutoMatched:
 		return "auto"
 	default:
@@ -135,6 +278,20 @@ const (
 	StrategyDefault
 	// StrategyIgnore - explicitly ignored field.
 	StrategyIgnore
+	// StrategyWrapper - convert through a declared generic wrapper (e.g., Nullable[T]).
+	StrategyWrapper
+	// StrategyInterfaceSwitch - type switch over registered implementations of an interface.
+	StrategyInterfaceSwitch
+	// StrategyStringMethod - call the type's String() method or its parse function.
+	StrategyStringMethod
+	// StrategyEnumMap - switch over the value table declared in enum_map.
+	StrategyEnumMap
+	// StrategySQLNull - convert through the Valid flag of a database/sql nullable type.
+	StrategySQLNull
+	// StrategyCollect - build a map or []KV literal from the fields declared in collect.
+	StrategyCollect
+	// StrategyMapKey - parse the value of a key of a source map, declared in from_map_keys.
+	StrategyMapKey
 )
 
 // String returns a human-readable strategy name.
@@ -162,6 +319,20 @@ func (s ConversionStrategy) String() string {
 		return "default"
 	case StrategyIgnore:
 		return "ignore"
+	case StrategyWrapper:
+		return "wrapper"
+	case StrategyInterfaceSwitch:
+		return "interface_switch"
+	case StrategyStringMethod:
+		return "string_method"
+	case StrategyEnumMap:
+		return "enum_map"
+	case StrategySQLNull:
+		return "sql_null"
+	case StrategyCollect:
+		return "collect"
+	case StrategyMapKey:
+		return "map_key"
 	default:
 		return common.UnknownStr
 	}
@@ -175,6 +346,9 @@ type UnmappedField struct {
 	TargetPath mapping.FieldPath
 	// Candidates are the ranked potential matches (for suggestions).
 	Candidates match.CandidateList
+	// Group are the source fields a many-to-one pattern suggests combining into
+	// it with a transform (e.g., FirstName and LastName for FullName).
+	Group []mapping.FieldPath
 	// Reason explains why it wasn't mapped.
 	Reason string
 }
@@ -194,6 +368,19 @@ type NestedConversion struct {
 	ResolvedPair *ResolvedTypePair
 }
 
+// Inline reports whether the conversion is generated inline in the casters
+// calling it, converting an anonymous struct, which has no name to declare a
+// nested caster for.
+func (nc *NestedConversion) Inline() bool {
+	return InlinePair(nc.SourceType, nc.TargetType)
+}
+
+// InlinePair reports whether a conversion from src to tgt is generated
+// inline, either being an anonymous struct.
+func InlinePair(src, tgt *analyze.TypeInfo) bool {
+	return src != nil && tgt != nil && (src.IsAnonymousStruct() || tgt.IsAnonymousStruct())
+}
+
 // IncompleteMappingInfo describes a mapping that requires a transform but doesn't have one.
 type IncompleteMappingInfo struct {
 	TypePair    string
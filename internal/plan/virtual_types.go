@@ -1,15 +1,26 @@
 package plan
 
 import (
+	"cmp"
+	"fmt"
+	"go/types"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
 	"caster-generator/internal/analyze"
+	"caster-generator/internal/common"
+	"caster-generator/internal/diagnostic"
 	"caster-generator/internal/mapping"
 )
 
 // preCreateVirtualTypes creates stub TypeInfo entries for all virtual target types
 // before resolution begins. This ensures they're available for nested type detection.
-func (r *Resolver) preCreateVirtualTypes() {
+// Fields that several sources of one target contribute with different types are
+// reported as errors.
+func (r *Resolver) preCreateVirtualTypes(diags *diagnostic.Diagnostics) {
 	if r.mappingDef == nil {
 		return
 	}
@@ -25,31 +36,210 @@ func (r *Resolver) preCreateVirtualTypes() {
 			continue
 		}
 
-		// Check if target type already exists (shouldn't for generate_target)
+		// Existing types are checked for collisions in resolveTypeMapping, except
+		// for the output of an earlier run, which is generated afresh.
 		targetID := parseTypeID(tm.Target)
-		if r.graph.GetType(targetID) != nil {
+		if existing := r.graph.GetType(targetID); existing != nil && !existing.FromCaster {
 			continue
 		}
 
-		// Create the virtual type (full structure will be populated in resolveTypeMapping)
-		r.createVirtualTargetType(&tm, sourceType)
+		// Create the virtual type (full structure will be populated in resolveTypeMapping).
+		// Targets of several sources are created once, from all of them.
+		targetType, conflicts := r.buildMergedTargetType(&tm, sourceType)
+		r.graph.Types[targetType.ID] = targetType
+
+		diags.Merge(conflicts)
 	}
 }
 
-// createVirtualTargetType creates a virtual TypeInfo for a generated target type.
-// It synthesizes the target structure from the mapping definition.
+// createVirtualTargetType creates a virtual TypeInfo for a generated target type
+// and registers it in the graph.
 func (r *Resolver) createVirtualTargetType(tm *mapping.TypeMapping, sourceType *analyze.TypeInfo) *analyze.TypeInfo {
-	// Parse target type ID from string
+	targetType, _ := r.buildMergedTargetType(tm, sourceType)
+
+	// Add to graph for future lookups
+	r.graph.Types[targetType.ID] = targetType
+
+	return targetType
+}
+
+// targetContributor is a generate_target mapping and its source type.
+type targetContributor struct {
+	mapping    *mapping.TypeMapping
+	sourceType *analyze.TypeInfo
+}
+
+// targetContributors returns the generate_target mappings generating the
+// target of tm, in file order, tm itself for a target of a single source.
+func (r *Resolver) targetContributors(tm *mapping.TypeMapping, sourceType *analyze.TypeInfo) []targetContributor {
 	targetID := parseTypeID(tm.Target)
 
-	// Create virtual type
-	targetType := &analyze.TypeInfo{
-		ID:          targetID,
-		Kind:        analyze.TypeKindStruct,
-		IsGenerated: true,
-		Fields:      []analyze.FieldInfo{},
+	var contributors []targetContributor
+
+	for i := range r.mappingDef.TypeMappings {
+		other := &r.mappingDef.TypeMappings[i]
+		if !other.GenerateTarget || parseTypeID(other.Target) != targetID {
+			continue
+		}
+
+		if otherSource := mapping.ResolveTypeID(other.Source, r.graph); otherSource != nil {
+			contributors = append(contributors, targetContributor{mapping: other, sourceType: otherSource})
+		}
 	}
 
+	if len(contributors) == 0 {
+		contributors = append(contributors, targetContributor{mapping: tm, sourceType: sourceType})
+	}
+
+	return contributors
+}
+
+// buildMergedTargetType synthesizes a generated target type from all the
+// mappings generating it, e.g. a projection gathering fields of several
+// source types. Fields contributed by several sources are declared once, and
+// those contributed with different types are reported as conflicts, the
+// first declaration winning.
+func (r *Resolver) buildMergedTargetType(
+	tm *mapping.TypeMapping,
+	sourceType *analyze.TypeInfo,
+) (*analyze.TypeInfo, diagnostic.Diagnostics) {
+	var conflicts diagnostic.Diagnostics
+
+	contributors := r.targetContributors(tm, sourceType)
+	if len(contributors) == 1 {
+		return r.buildVirtualTargetType(contributors[0].mapping, contributors[0].sourceType), conflicts
+	}
+
+	var fields []virtualField
+
+	// Source type contributing each field, for conflicts
+	declaredBy := make(map[string]*analyze.TypeInfo)
+
+	for i, c := range contributors {
+		mark := conflicts.Mark()
+
+		for _, f := range r.virtualFields(c.mapping, c.sourceType) {
+			j := slices.IndexFunc(fields, func(added virtualField) bool { return added.Name == f.Name })
+			if j < 0 {
+				f.contributor = i
+				fields = append(fields, f)
+				declaredBy[f.Name] = c.sourceType
+
+				continue
+			}
+
+			if !sameFieldType(fields[j].Type, f.Type) {
+				conflicts.AddError("generated_target_conflict",
+					fmt.Sprintf("field %q of generated target %s is %s from %s but %s from %s",
+						f.Name, tm.Target, typeLabel(fields[j].Type), declaredBy[f.Name].ID,
+						typeLabel(f.Type), c.sourceType.ID),
+					fmt.Sprintf("%s->%s", c.sourceType.ID, parseTypeID(tm.Target)), f.Name)
+
+				continue
+			}
+
+			fields[j].origins = append(fields[j].origins, f.origins...)
+		}
+
+		conflicts.Locate(mark, c.mapping.Pos)
+	}
+
+	return r.newVirtualType(tm.Target, fields), conflicts
+}
+
+// ignoreSharedTargetFields leaves the fields of a target generated from
+// several sources that only the other sources contribute to their casters,
+// rather than auto-matching them.
+func (r *Resolver) ignoreSharedTargetFields(
+	tm *mapping.TypeMapping,
+	result *ResolvedTypePair,
+	sourceType, targetType *analyze.TypeInfo,
+	mappedTargets map[string]bool,
+) {
+	contributors := r.targetContributors(tm, sourceType)
+	if len(contributors) == 1 {
+		return
+	}
+
+	own := r.buildVirtualTargetType(tm, sourceType)
+
+	for _, f := range targetType.Fields {
+		if mappedTargets[f.Name] || own.FieldByName(f.Name) != nil {
+			continue
+		}
+
+		var others []string
+
+		for _, c := range contributors {
+			if c.sourceType != sourceType && r.buildVirtualTargetType(c.mapping, c.sourceType).FieldByName(f.Name) != nil {
+				others = append(others, c.sourceType.ID.String())
+			}
+		}
+
+		result.Mappings = append(result.Mappings, ResolvedFieldMapping{
+			TargetPaths: []mapping.FieldPath{{Segments: []mapping.PathSegment{{Name: f.Name}}}},
+			Source:      MappingSourceSharedTarget,
+			Strategy:    StrategyIgnore,
+			Explanation: "contributed by " + strings.Join(others, ", "),
+		})
+		mappedTargets[f.Name] = true
+	}
+}
+
+// sameFieldType reports whether fields of generated target types x and y
+// have the same type. Types without a go/types counterpart, like generated
+// ones, are compared by identity, kind and element types.
+func sameFieldType(x, y *analyze.TypeInfo) bool {
+	if x == y {
+		return true
+	}
+
+	if x == nil || y == nil {
+		return false
+	}
+
+	if x.GoType != nil && y.GoType != nil {
+		return types.Identical(x.GoType, y.GoType)
+	}
+
+	return x.Kind == y.Kind && x.ID == y.ID &&
+		sameFieldType(x.KeyType, y.KeyType) && sameFieldType(x.ElemType, y.ElemType)
+}
+
+// typeLabel returns a readable name of t for diagnostics.
+func typeLabel(t *analyze.TypeInfo) string {
+	switch {
+	case t.GoType != nil:
+		return t.GoType.String()
+	case t.IsNamed():
+		return t.ID.String()
+	default:
+		return t.Kind.String()
+	}
+}
+
+// virtualField is a field a generate_target mapping declares, with what
+// orders and documents it.
+type virtualField struct {
+	analyze.FieldInfo
+
+	pos         diagnostic.Position // Position of the mapping entry, if known
+	contributor int                 // Index of the mapping among those generating the target
+	sourceIndex int                 // Index of the first source field in the source type, -1 if none
+	origins     []string            // How the field is assigned (e.g., "from store.User.ID")
+}
+
+// buildVirtualTargetType synthesizes the structure of a generated target type
+// from the mapping definition.
+func (r *Resolver) buildVirtualTargetType(tm *mapping.TypeMapping, sourceType *analyze.TypeInfo) *analyze.TypeInfo {
+	return r.newVirtualType(tm.Target, r.virtualFields(tm, sourceType))
+}
+
+// virtualFields returns the fields a generate_target mapping declares: the
+// 121 entries by source field, then the fields and auto entries.
+func (r *Resolver) virtualFields(tm *mapping.TypeMapping, sourceType *analyze.TypeInfo) []virtualField {
+	var fields []virtualField
+
 	// Build field index for source type
 	sourceFields := make(map[string]*analyze.FieldInfo)
 	for i := range sourceType.Fields {
@@ -69,39 +259,48 @@ func (r *Resolver) createVirtualTargetType(tm *mapping.TypeMapping, sourceType *
 	}
 
 	// Process 121 mappings
-	for sourcePath, targetPath := range tm.OneToOne {
-		if addedFields[targetPath] {
+	for _, sourcePath := range slices.Sorted(maps.Keys(tm.OneToOne)) {
+		targetPath := tm.OneToOne[sourcePath]
+		if addedFields[targetPath] || mapping.IsWildcard(targetPath) {
 			continue
 		}
 
 		if srcField, ok := sourceFields[sourcePath]; ok {
-			targetType.Fields = append(targetType.Fields, analyze.FieldInfo{
-				Name:     targetPath,
-				Exported: true,
-				Type:     remapType(srcField.Type),
-				Index:    len(targetType.Fields),
+			fields = append(fields, virtualField{
+				FieldInfo: analyze.FieldInfo{
+					Name: targetPath, Exported: true, Type: remapType(srcField.Type), Tag: r.fieldTag(srcField, nil),
+				},
+				pos:         tm.OneToOnePos[sourcePath],
+				sourceIndex: sourceFieldIndex(sourceType, sourcePath),
+				origins:     []string{"from " + sourceFieldLabel(sourceType, sourcePath)},
 			})
 			addedFields[targetPath] = true
 		}
 	}
 
-	// Process explicit field mappings
-	for _, fm := range tm.Fields {
+	// Process explicit field mappings, then auto mappings
+	for _, fm := range slices.Concat(tm.Fields, tm.Auto) {
 		for _, t := range fm.Target {
 			targetName := t.Path
-			if addedFields[targetName] {
+			if addedFields[targetName] || mapping.IsWildcard(targetName) {
 				continue
 			}
-			// Try to infer type from source
-			var fieldType *analyze.TypeInfo
+
+			// Try to infer type, and copied tags, from source
+			var srcField *analyze.FieldInfo
 
 			for _, s := range fm.Source {
-				if srcField, ok := sourceFields[s.Path]; ok {
-					fieldType = srcField.Type
+				if f, ok := sourceFields[s.Path]; ok {
+					srcField = f
 					break
 				}
 			}
 
+			var fieldType *analyze.TypeInfo
+			if srcField != nil {
+				fieldType = srcField.Type
+			}
+
 			if fieldType == nil {
 				// Default to interface{} if we can't infer
 				fieldType = &analyze.TypeInfo{
@@ -110,56 +309,161 @@ func (r *Resolver) createVirtualTargetType(tm *mapping.TypeMapping, sourceType *
 				}
 			}
 
-			targetType.Fields = append(targetType.Fields, analyze.FieldInfo{
-				Name:     targetName,
-				Exported: true,
-				Type:     remapType(fieldType),
-				Index:    len(targetType.Fields),
-			})
+			field := virtualField{
+				FieldInfo: analyze.FieldInfo{
+					Name: targetName, Exported: true, Type: remapType(fieldType), Tag: r.fieldTag(srcField, fm.Tags),
+				},
+				pos:         fm.Pos,
+				sourceIndex: -1,
+			}
+
+			if len(fm.Source) > 0 {
+				field.sourceIndex = sourceFieldIndex(sourceType, fm.Source[0].Path)
+			}
+
+			if origin := fieldOrigin(sourceType, &fm); origin != "" {
+				field.origins = []string{origin}
+			}
+
+			fields = append(fields, field)
 			addedFields[targetName] = true
 		}
 	}
 
-	// Process auto mappings
-	for _, fm := range tm.Auto {
-		for _, t := range fm.Target {
-			targetName := t.Path
-			if addedFields[targetName] {
-				continue
-			}
-			// Try to infer type from source
-			var fieldType *analyze.TypeInfo
+	return fields
+}
 
-			for _, s := range fm.Source {
-				if srcField, ok := sourceFields[s.Path]; ok {
-					fieldType = srcField.Type
-					break
-				}
-			}
+// newVirtualType returns the generated struct type target declaring fields,
+// ordered and documented as the generated_types of the mapping file ask.
+func (r *Resolver) newVirtualType(target string, fields []virtualField) *analyze.TypeInfo {
+	// Create virtual type
+	targetType := &analyze.TypeInfo{
+		ID:          parseTypeID(target),
+		Kind:        analyze.TypeKindStruct,
+		IsGenerated: true,
+		Fields:      []analyze.FieldInfo{},
+	}
 
-			if fieldType == nil {
-				fieldType = &analyze.TypeInfo{
-					ID:   analyze.TypeID{Name: "interface{}"},
-					Kind: analyze.TypeKindBasic,
-				}
-			}
+	options := r.mappingDef.GeneratedTypes
 
-			targetType.Fields = append(targetType.Fields, analyze.FieldInfo{
-				Name:     targetName,
-				Exported: true,
-				Type:     remapType(fieldType),
-				Index:    len(targetType.Fields),
+	switch options.FieldOrder {
+	case mapping.FieldOrderDefault:
+	case mapping.FieldOrderYAML:
+		// Fields of mappings built rather than parsed keep the default order.
+		if !slices.ContainsFunc(fields, func(f virtualField) bool { return !f.pos.IsValid() }) {
+			slices.SortStableFunc(fields, func(a, b virtualField) int {
+				return cmp.Or(cmp.Compare(a.pos.File, b.pos.File), cmp.Compare(a.pos.Line, b.pos.Line),
+					cmp.Compare(a.pos.Column, b.pos.Column))
 			})
-			addedFields[targetName] = true
 		}
+	case mapping.FieldOrderAlphabetical:
+		slices.SortStableFunc(fields, func(a, b virtualField) int { return cmp.Compare(a.Name, b.Name) })
+	case mapping.FieldOrderSource:
+		slices.SortStableFunc(fields, func(a, b virtualField) int {
+			// Fields without a source come last.
+			if (a.sourceIndex < 0) != (b.sourceIndex < 0) {
+				return cmp.Compare(b.sourceIndex, a.sourceIndex)
+			}
+
+			return cmp.Or(cmp.Compare(a.contributor, b.contributor), cmp.Compare(a.sourceIndex, b.sourceIndex))
+		})
 	}
 
-	// Add to graph for future lookups
-	r.graph.Types[targetID] = targetType
+	for _, f := range fields {
+		if options.FieldDocs && len(f.origins) > 0 {
+			f.Doc = f.Name + " is set " + strings.Join(f.origins, " or ") + "."
+		}
+
+		f.Index = len(targetType.Fields)
+		targetType.Fields = append(targetType.Fields, f.FieldInfo)
+	}
 
 	return targetType
 }
 
+// fieldTag returns the struct tag of a generated field: the copy_tags keys
+// of its source field src, if any, then the tags of its mapping, which
+// override copied ones.
+func (r *Resolver) fieldTag(src *analyze.FieldInfo, tags map[string]string) reflect.StructTag {
+	var keys, values []string
+
+	set := func(key, value string) {
+		if i := slices.Index(keys, key); i >= 0 {
+			values[i] = value
+			return
+		}
+
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	if src != nil {
+		for _, key := range r.mappingDef.GeneratedTypes.CopyTags {
+			if value, ok := src.Tag.Lookup(key); ok {
+				set(key, value)
+			}
+		}
+	}
+
+	for _, key := range mapping.SortedEnumKeys(tags) {
+		set(key, tags[key])
+	}
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + ":" + strconv.Quote(values[i])
+	}
+
+	return reflect.StructTag(strings.Join(parts, " "))
+}
+
+// fieldOrigin describes how a field mapping assigns its target for the doc
+// comment of a generated field (e.g., "by Total(store.Order.Items)"), or
+// returns "" if it has no source.
+func fieldOrigin(sourceType *analyze.TypeInfo, fm *mapping.FieldMapping) string {
+	sources := make([]string, 0, len(fm.Source))
+	for _, s := range fm.Source {
+		sources = append(sources, sourceFieldLabel(sourceType, s.Path))
+	}
+
+	switch {
+	case fm.Transform != "":
+		return fmt.Sprintf("by %s(%s)", fm.Transform, strings.Join(sources, ", "))
+	case len(sources) == 0:
+		return ""
+	case len(fm.EnumMap) > 0:
+		return "from " + sources[0] + " by enum_map"
+	default:
+		return "from " + strings.Join(sources, ", ")
+	}
+}
+
+// sourceFieldLabel names the source field at path of sourceType in doc
+// comments (e.g., "store.Order.Items").
+func sourceFieldLabel(sourceType *analyze.TypeInfo, path string) string {
+	name := sourceType.ID.Name
+	if alias := common.PkgAlias(sourceType.ID.PkgPath); alias != "" {
+		name = alias + "." + name
+	}
+
+	return name + "." + path
+}
+
+// sourceFieldIndex returns the index of the source field declaring the first
+// segment of path in sourceType, or -1.
+func sourceFieldIndex(sourceType *analyze.TypeInfo, path string) int {
+	name, _, _ := strings.Cut(path, ".")
+	name, _, _ = strings.Cut(name, "[")
+
+	for i := range sourceType.Fields {
+		if sourceType.Fields[i].Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // remapToGeneratedType checks if there's a generated target type mapping for the given source type
 // and returns the corresponding target type reference. For slices/pointers, it recursively remaps the element type.
 func (r *Resolver) remapToGeneratedType(srcType *analyze.TypeInfo) *analyze.TypeInfo {
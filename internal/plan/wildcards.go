@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/mapping"
+)
+
+// resolveWildcardRules adds the field mappings expanded from the wildcard 121,
+// prefix_map and fields rules of tm. Targets already mapped by explicit 121
+// or fields rules, or by an earlier wildcard rule, are left to them; pairs of
+// 121 rules whose types need a transform are left to the rules after them.
+// Rules matching no fields are reported.
+func (r *Resolver) resolveWildcardRules(
+	tm *mapping.TypeMapping,
+	result *ResolvedTypePair,
+	mappedTargets map[string]bool,
+	diags *diagnostic.Diagnostics,
+	typePairStr string,
+) {
+	unmatched := func(source, target string) {
+		diags.AddWarning("unmatched_wildcard",
+			fmt.Sprintf("wildcard %s -> %s matches no pair of source and target fields", source, target),
+			typePairStr, target)
+	}
+
+	for _, rule := range tm.WildcardRules() {
+		pairs, err := mapping.WildcardFields(rule[0], rule[1], result.SourceType, result.TargetType)
+		if err != nil {
+			diags.AddWarning("wildcard_error", err.Error(), typePairStr, rule[1])
+			continue
+		}
+
+		if len(pairs) == 0 {
+			unmatched(rule[0], rule[1])
+		}
+
+		for _, p := range pairs {
+			if mappedTargets[p.Target] {
+				continue
+			}
+
+			resolved, err := r.resolve121Mapping(p.Source, p.Target, result.SourceType, result.TargetType)
+			if err != nil {
+				diags.AddWarning("wildcard_error", err.Error(), typePairStr, p.Target)
+				continue
+			}
+
+			if resolved.Strategy == StrategyTransform {
+				diags.AddWarning("wildcard_incompatible",
+					fmt.Sprintf("wildcard %s -> %s skips %s -> %s, whose types need a transform",
+						rule[0], rule[1], p.Source, p.Target), typePairStr, p.Target)
+
+				continue
+			}
+
+			resolved.Explanation = fmt.Sprintf("wildcard %s -> %s: %s", rule[0], rule[1],
+				strings.TrimPrefix(resolved.Explanation, "explicit 121 mapping: "))
+			result.Mappings = append(result.Mappings, *resolved)
+			mappedTargets[p.Target] = true
+		}
+	}
+
+	for _, fm := range tm.Fields {
+		if !fm.IsWildcard() {
+			continue
+		}
+
+		rules, err := mapping.WildcardFieldMappings(&fm, result.SourceType, result.TargetType)
+		if err != nil {
+			diags.AddWarning("wildcard_error", err.Error(), typePairStr, fm.Target.First())
+			continue
+		}
+
+		if len(rules) == 0 {
+			unmatched(fm.Source.First(), fm.Target.First())
+		}
+
+		for _, rule := range rules {
+			if mappedTargets[rule.Target.First()] {
+				continue
+			}
+
+			resolvedAll, err := r.resolveFieldMappings(tm, &rule, result.SourceType, result.TargetType,
+				MappingSourceYAMLFields)
+			if err != nil {
+				diags.AddWarning("field_mapping_error", err.Error(), typePairStr, rule.Target.First())
+				continue
+			}
+
+			for _, resolved := range resolvedAll {
+				for _, tp := range resolved.TargetPaths {
+					mappedTargets[tp.String()] = true
+				}
+
+				result.Mappings = append(result.Mappings, resolved)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+// buildWildcardGraph returns a graph of source and target Orders with
+// families of prefixed fields.
+func buildWildcardGraph() *analyze.TypeGraph {
+	graph := analyze.NewTypeGraph()
+
+	structType := func(pkg string, fields ...string) *analyze.TypeInfo {
+		t := &analyze.TypeInfo{ID: analyze.TypeID{PkgPath: pkg, Name: "Order"}, Kind: analyze.TypeKindStruct}
+		for _, name := range fields {
+			t.Fields = append(t.Fields, analyze.FieldInfo{Name: name, Exported: true, Type: basicTypeInfo()})
+		}
+
+		graph.Types[t.ID] = t
+
+		return t
+	}
+
+	structType("test/source", "BillingCity", "BillingStreet", "MetaOwner", "MetaTag", "Meta", "NoteText")
+	structType("test/target", "PaymentCity", "PaymentStreet", "MetaOwner", "MetaTag", "Meta", "NoteBody", "Owner")
+
+	return graph
+}
+
+func TestResolverWildcardRules(t *testing.T) {
+	mf := &mapping.MappingFile{
+		TypeMappings: []mapping.TypeMapping{{
+			Source:    "source.Order",
+			Target:    "target.Order",
+			OneToOne:  map[string]string{"Meta*": "Meta*", "MetaOwner": "Owner", "Note*": "Note*"},
+			PrefixMap: map[string]string{"Billing": "Payment"},
+			Fields: []mapping.FieldMapping{
+				{Source: mapping.FieldRefArray{{Path: "Meta*"}}, Target: mapping.FieldRefArray{{Path: "*"}}},
+				{Source: mapping.FieldRefArray{{Path: "NoteText"}}, Target: mapping.FieldRefArray{{Path: "MetaTag"}}},
+			},
+		}},
+	}
+
+	plan, err := NewResolver(buildWildcardGraph(), mf, DefaultConfig()).Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	got := mappingsByTarget(&plan.TypePairs[0])
+	for tgt, src := range map[string]string{
+		"PaymentCity":   "BillingCity",
+		"PaymentStreet": "BillingStreet",
+		"MetaOwner":     "MetaOwner",
+		"MetaTag":       "NoteText", // Explicit rules win over wildcard ones
+		"Owner":         "MetaOwner",
+		"Meta":          "Meta",
+	} {
+		if m := got[tgt]; len(m.SourcePaths) == 0 || m.SourcePaths[0].String() != src {
+			t.Errorf("Expected %s <- %s, got %+v", tgt, src, m)
+		}
+	}
+
+	if m := got["PaymentCity"]; m.Explanation != "wildcard Billing* -> Payment*: BillingCity -> PaymentCity (identical)" {
+		t.Errorf("Unexpected explanation of PaymentCity: %q", m.Explanation)
+	}
+
+	var unmatched []string
+
+	for _, w := range plan.Diagnostics.Warnings {
+		if w.Code == "unmatched_wildcard" {
+			unmatched = append(unmatched, w.FieldPath)
+		}
+	}
+
+	if len(unmatched) != 1 || unmatched[0] != "Note*" {
+		t.Errorf("Expected Note* -> Note* to be reported as unmatched, got %v", unmatched)
+	}
+}
@@ -0,0 +1,121 @@
+package plan
+
+import (
+	"go/types"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+)
+
+// WrapperConversion describes a conversion through a declared generic wrapper,
+// e.g. nullable.Nullable[string] to string or *string and back.
+type WrapperConversion struct {
+	// Def is the matching wrapper declaration.
+	Def *mapping.WrapperDef
+	// Wrapper is the instantiated wrapper type (e.g., Nullable[string]).
+	Wrapper *analyze.TypeInfo
+	// Value is the wrapped type argument (e.g., string).
+	Value *analyze.TypeInfo
+	// Unwrap is true when the source is the wrapper; false when the target is.
+	Unwrap bool
+	// Pointer is true when the plain side is *T rather than T.
+	Pointer bool
+}
+
+// Explain returns a short description used in mapping explanations.
+func (w *WrapperConversion) Explain() string {
+	direction := "wrap"
+	if w.Unwrap {
+		direction = "unwrap"
+	}
+
+	if w.Pointer {
+		direction += " pointer"
+	}
+
+	return "wrapper " + direction
+}
+
+// FindWrapperConversion returns how to convert between src and tgt through one
+// of the declared wrappers, or nil if neither side wraps the other.
+func FindWrapperConversion(wrappers []mapping.WrapperDef, src, tgt *analyze.TypeInfo) *WrapperConversion {
+	if src == nil || tgt == nil {
+		return nil
+	}
+
+	for i := range wrappers {
+		def := &wrappers[i]
+		if def.Get == "" {
+			continue
+		}
+
+		if value := wrappedValue(def, src); value != nil {
+			if ptr, ok := plainMatches(value, tgt); ok {
+				return &WrapperConversion{Def: def, Wrapper: src, Value: value, Unwrap: true, Pointer: ptr}
+			}
+		}
+
+		if value := wrappedValue(def, tgt); value != nil && def.CanWrap() {
+			if ptr, ok := plainMatches(value, src); ok {
+				return &WrapperConversion{Def: def, Wrapper: tgt, Value: value, Pointer: ptr}
+			}
+		}
+	}
+
+	return nil
+}
+
+// wrappedValue returns the type argument of t if t instantiates the wrapper.
+func wrappedValue(def *mapping.WrapperDef, t *analyze.TypeInfo) *analyze.TypeInfo {
+	if len(t.TypeArgs) != 1 || !def.Matches(t.ID) {
+		return nil
+	}
+
+	return t.TypeArgs[0]
+}
+
+// plainMatches reports whether plain is the wrapped value type or a pointer to it.
+func plainMatches(value, plain *analyze.TypeInfo) (bool, bool) {
+	if sameType(value, plain) {
+		return false, true
+	}
+
+	if plain.Kind == analyze.TypeKindPointer && plain.ElemType != nil && sameType(value, plain.ElemType) {
+		return true, true
+	}
+
+	return false, false
+}
+
+// sameType compares types by go/types identity, falling back to ID and kind.
+func sameType(a, b *analyze.TypeInfo) bool {
+	if a.GoType != nil && b.GoType != nil {
+		return types.Identical(a.GoType, b.GoType)
+	}
+
+	return a.Kind == b.Kind && a.ID == b.ID && a.ID.Name != ""
+}
+
+// wrapperCompat lets auto-matching rank wrapper conversions as transformable
+// instead of incompatible.
+func (r *Resolver) wrapperCompat(src, tgt *analyze.TypeInfo) (match.TypeCompatibilityResult, bool) {
+	wc := FindWrapperConversion(r.wrappers(), src, tgt)
+	if wc == nil {
+		return match.TypeCompatibilityResult{}, false
+	}
+
+	return match.TypeCompatibilityResult{
+		Compatibility: match.TypeNeedsTransform,
+		Reason:        wc.Explain(),
+	}, true
+}
+
+// wrappers returns the wrapper declarations of the mapping file.
+func (r *Resolver) wrappers() []mapping.WrapperDef {
+	if r.mappingDef == nil {
+		return nil
+	}
+
+	return r.mappingDef.Wrappers
+}
@@ -0,0 +1,54 @@
+package plan
+
+import (
+	"testing"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/mapping"
+)
+
+func TestFindWrapperConversion(t *testing.T) {
+	strType := basicTypeInfo()
+	intType := &analyze.TypeInfo{Kind: analyze.TypeKindBasic, ID: analyze.TypeID{Name: "int"}}
+	nullable := func(arg *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{
+			ID:       analyze.TypeID{PkgPath: "github.com/acme/nullable", Name: "Nullable"},
+			Kind:     analyze.TypeKindExternal,
+			TypeArgs: []*analyze.TypeInfo{arg},
+		}
+	}
+	ptr := func(elem *analyze.TypeInfo) *analyze.TypeInfo {
+		return &analyze.TypeInfo{Kind: analyze.TypeKindPointer, ElemType: elem}
+	}
+
+	readOnly := []mapping.WrapperDef{{Type: "nullable.Nullable", Get: "Get"}}
+	full := []mapping.WrapperDef{{Type: "github.com/acme/nullable.Nullable", Get: "Get", Set: "Set"}}
+
+	tests := []struct {
+		name     string
+		wrappers []mapping.WrapperDef
+		src, tgt *analyze.TypeInfo
+		want     string // Explain() of the conversion, empty for none
+	}{
+		{"unwrap", readOnly, nullable(strType), strType, "wrapper unwrap"},
+		{"unwrap pointer", readOnly, nullable(strType), ptr(strType), "wrapper unwrap pointer"},
+		{"wrap", full, strType, nullable(strType), "wrapper wrap"},
+		{"wrap pointer", full, ptr(strType), nullable(strType), "wrapper wrap pointer"},
+		{"wrap needs new or set", readOnly, strType, nullable(strType), ""},
+		{"type argument mismatch", full, nullable(intType), strType, ""},
+		{"no wrappers", nil, nullable(strType), strType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if wc := FindWrapperConversion(tt.wrappers, tt.src, tt.tgt); wc != nil {
+				got = wc.Explain()
+			}
+
+			if got != tt.want {
+				t.Errorf("FindWrapperConversion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+// Package stats collects local, filesystem-only usage statistics about
+// caster-generator mappings and generated code.
+//
+// The report aggregates:
+//   - Mapping files, type pairs, field rules, and transforms found in YAML
+//   - Generated files, assigned and unmapped (TODO) target fields
+//   - A histogram of conversion strategies used by generated assignments
+//
+// Nothing is sent over the network; the report is written as JSON for
+// internal dashboards.
+package stats
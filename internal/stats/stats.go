@@ -0,0 +1,274 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"caster-generator/internal/mapping"
+)
+
+// generatedHeader marks files written by the generator.
+const generatedHeader = "// Code generated by caster-generator."
+
+// Report holds aggregate statistics for a directory tree.
+type Report struct {
+	// Root is the directory that was scanned.
+	Root string `json:"root"`
+	// MappingFiles is the number of YAML files recognized as mapping files.
+	MappingFiles int `json:"mapping_files"`
+	// GeneratedFiles is the number of Go files produced by caster-generator.
+	GeneratedFiles int `json:"generated_files"`
+	// TypePairs is the number of type mappings across all mapping files.
+	TypePairs int `json:"type_pairs"`
+	// Fields counts field rules in mapping files by section.
+	Fields FieldStats `json:"fields"`
+	// Transforms counts declared and referenced transforms.
+	Transforms TransformStats `json:"transforms"`
+	// Coverage describes how many target fields generated casters populate.
+	Coverage CoverageStats `json:"coverage"`
+	// Strategies is a histogram of conversion strategies in generated assignments.
+	Strategies map[string]int `json:"strategies"`
+}
+
+// FieldStats counts field rules by mapping section.
+type FieldStats struct {
+	OneToOne int `json:"one_to_one"`
+	Explicit int `json:"explicit"`
+	Auto     int `json:"auto"`
+	Ignored  int `json:"ignored"`
+	Total    int `json:"total"`
+}
+
+// TransformStats counts transform declarations and usages.
+type TransformStats struct {
+	// Declared is the number of entries in transforms sections.
+	Declared int `json:"declared"`
+	// Used is the number of field rules that apply a transform.
+	Used int `json:"used"`
+	// Distinct is the number of distinct transform names referenced by field rules.
+	Distinct int `json:"distinct"`
+}
+
+// CoverageStats describes target field coverage of generated casters.
+type CoverageStats struct {
+	// Assigned is the number of distinct target fields assigned by generated casters.
+	Assigned int `json:"assigned"`
+	// Unmapped is the number of TODO markers left for unmapped target fields.
+	Unmapped int `json:"unmapped"`
+	// Ratio is Assigned / (Assigned + Unmapped), or 0 when there is nothing to count.
+	Ratio float64 `json:"ratio"`
+}
+
+// Collect walks root and aggregates statistics from mapping files and generated code.
+// Hidden directories and vendor directories are skipped.
+func Collect(root string) (*Report, error) {
+	report := &Report{
+		Root:       root,
+		Strategies: make(map[string]int),
+	}
+	transformNames := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			return report.addMappingFile(path, transformNames)
+		case ".go":
+			return report.addGoFile(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+
+	report.Transforms.Distinct = len(transformNames)
+
+	if total := report.Coverage.Assigned + report.Coverage.Unmapped; total > 0 {
+		report.Coverage.Ratio = float64(report.Coverage.Assigned) / float64(total)
+	}
+
+	return report, nil
+}
+
+// addMappingFile adds counts from a YAML file if it is a mapping file.
+// Files that don't parse or have no mappings are ignored.
+func (r *Report) addMappingFile(path string, transformNames map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	mf, err := mapping.Parse(data)
+	if err != nil || len(mf.TypeMappings) == 0 {
+		return nil
+	}
+
+	r.MappingFiles++
+	r.TypePairs += len(mf.TypeMappings)
+	r.Transforms.Declared += len(mf.Transforms)
+
+	for _, tm := range mf.TypeMappings {
+		r.Fields.OneToOne += len(tm.OneToOne)
+		r.Fields.Explicit += len(tm.Fields)
+		r.Fields.Auto += len(tm.Auto)
+		r.Fields.Ignored += len(tm.Ignore)
+
+		for _, fm := range append(append([]mapping.FieldMapping{}, tm.Fields...), tm.Auto...) {
+			if fm.Transform != "" {
+				r.Transforms.Used++
+				transformNames[fm.Transform] = true
+			}
+		}
+	}
+
+	r.Fields.Total = r.Fields.OneToOne + r.Fields.Explicit + r.Fields.Auto + r.Fields.Ignored
+
+	return nil
+}
+
+// addGoFile adds counts from a Go file if it was produced by caster-generator.
+func (r *Report) addGoFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(generatedHeader)) {
+		return nil
+	}
+
+	r.GeneratedFiles++
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+	if err != nil {
+		// Unformatted or broken output; count the file but skip its contents.
+		return nil
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		r.Coverage.Assigned += countAssignedTargets(fn.Body)
+
+		for _, cg := range file.Comments {
+			if cg.Pos() < fn.Body.Lbrace || cg.End() > fn.Body.Rbrace {
+				continue
+			}
+
+			for _, c := range cg.List {
+				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				if strings.HasPrefix(text, "TODO:") {
+					r.Coverage.Unmapped++
+				} else if label := strategyLabel(text); label != "" {
+					r.Strategies[label]++
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// countAssignedTargets returns the number of distinct "out" fields assigned in body.
+func countAssignedTargets(body *ast.BlockStmt) int {
+	targets := make(map[string]bool)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+
+		for _, lhs := range assign.Lhs {
+			sel, ok := unwrapTarget(lhs).(*ast.SelectorExpr)
+			if !ok || rootIdent(sel) != "out" {
+				continue
+			}
+
+			targets[types.ExprString(sel)] = true
+		}
+
+		return true
+	})
+
+	return len(targets)
+}
+
+// unwrapTarget strips index and dereference expressions from an assignment target.
+func unwrapTarget(e ast.Expr) ast.Expr {
+	for {
+		switch v := e.(type) {
+		case *ast.IndexExpr:
+			e = v.X
+		case *ast.StarExpr:
+			e = v.X
+		case *ast.ParenExpr:
+			e = v.X
+		default:
+			return e
+		}
+	}
+}
+
+// rootIdent returns the name of the identifier a selector chain starts from.
+func rootIdent(e ast.Expr) string {
+	for {
+		switch v := e.(type) {
+		case *ast.SelectorExpr:
+			e = v.X
+		case *ast.Ident:
+			return v.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// strategyLabel extracts the strategy label from an assignment explanation comment,
+// e.g. "field mapping: 1:1 (slice map)" -> "slice map" and
+// "auto-matched: A -> B (score: 0.76, identical)" -> "identical".
+func strategyLabel(comment string) string {
+	open := strings.Index(comment, "(")
+	if open < 0 || !strings.HasSuffix(comment, ")") {
+		return ""
+	}
+
+	label := comment[open+1 : len(comment)-1]
+	if strings.HasPrefix(label, "score:") {
+		_, rest, ok := strings.Cut(label, ", ")
+		if !ok {
+			return ""
+		}
+
+		label = rest
+	}
+
+	return strings.TrimSpace(label)
+}
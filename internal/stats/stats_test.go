@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMapping = `
+mappings:
+  - source: store.Order
+    target: warehouse.Order
+    121:
+      OrderID: ID
+    fields:
+      - target: Amount
+        source: Price
+        transform: PriceToAmount
+    ignore:
+      - Status
+    auto:
+      - target: Customer
+        source: CustomerName
+transforms:
+  - name: PriceToAmount
+`
+
+const testGenerated = `// Code generated by caster-generator. DO NOT EDIT.
+
+package casters
+
+func StoreOrderToWarehouseOrder(in Order) Target {
+	out := Target{}
+
+	// explicit 121 mapping: OrderID -> ID (identical)
+	out.ID = in.OrderID
+
+	// field mapping: 1:1 (slice map)
+	out.Items = make([]Item, len(in.Items))
+	for i := range in.Items {
+		out.Items[i] = in.Items[i]
+	}
+
+	// auto-matched: CustomerName -> Customer (score: 0.90, identical)
+	out.Customer = in.CustomerName
+
+	// TODO: Status - no match found
+	return out
+}
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "map.yaml"), testMapping)
+	writeFile(t, filepath.Join(root, "other.yaml"), "name: not-a-mapping\n")
+	writeFile(t, filepath.Join(root, "generated", "order.go"), testGenerated)
+	writeFile(t, filepath.Join(root, "source.go"), "package store\n")
+	writeFile(t, filepath.Join(root, ".hidden", "map.yaml"), testMapping)
+
+	report, err := Collect(root)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	if report.MappingFiles != 1 || report.TypePairs != 1 {
+		t.Errorf("expected 1 mapping file with 1 pair, got %d files, %d pairs", report.MappingFiles, report.TypePairs)
+	}
+
+	want := FieldStats{OneToOne: 1, Explicit: 1, Auto: 1, Ignored: 1, Total: 4}
+	if report.Fields != want {
+		t.Errorf("fields: expected %+v, got %+v", want, report.Fields)
+	}
+
+	if report.Transforms != (TransformStats{Declared: 1, Used: 1, Distinct: 1}) {
+		t.Errorf("unexpected transforms: %+v", report.Transforms)
+	}
+
+	if report.GeneratedFiles != 1 {
+		t.Errorf("expected 1 generated file, got %d", report.GeneratedFiles)
+	}
+
+	if report.Coverage.Assigned != 3 || report.Coverage.Unmapped != 1 {
+		t.Errorf("unexpected coverage: %+v", report.Coverage)
+	}
+
+	if report.Coverage.Ratio != 0.75 {
+		t.Errorf("expected coverage ratio 0.75, got %v", report.Coverage.Ratio)
+	}
+
+	if report.Strategies["identical"] != 2 || report.Strategies["slice map"] != 1 {
+		t.Errorf("unexpected strategies: %v", report.Strategies)
+	}
+}
+
+func TestStrategyLabel(t *testing.T) {
+	tests := map[string]string{
+		"field mapping: 1:1 (transform)":               "transform",
+		"field mapping: 1:1 (slice map (dive))":        "slice map (dive)",
+		"auto-matched: A -> B (score: 0.76, map copy)": "map copy",
+		"Foo converts a.Foo to b.Foo.":                 "",
+		"explicit 121 mapping: X -> X (identical)":     "identical",
+	}
+
+	for in, want := range tests {
+		if got := strategyLabel(in); got != want {
+			t.Errorf("strategyLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
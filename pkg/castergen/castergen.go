@@ -0,0 +1,308 @@
+// Package castergen is the library API of caster-generator. It runs the
+// pipeline of the CLI for tools embedding the generator: analyze Go packages,
+// load a YAML mapping, resolve it into a plan and generate caster code.
+//
+// A typical run loads a mapping with LoadMapping, the packages it names with
+// Analyze(MappingPackages(mf), ...), resolves the mapping with Resolve, and
+// writes the files of Generate with WriteFiles.
+//
+// The types of this package are aliases of the generator's own, so values
+// flow between the steps unchanged.
+package castergen
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"caster-generator/internal/analyze"
+	"caster-generator/internal/diagnostic"
+	"caster-generator/internal/gen"
+	"caster-generator/internal/mapping"
+	"caster-generator/internal/match"
+	"caster-generator/internal/plan"
+)
+
+type (
+	// TypeGraph holds the types of the analyzed packages.
+	TypeGraph = analyze.TypeGraph
+	// MappingFile is a parsed YAML mapping definition.
+	MappingFile = mapping.MappingFile
+	// Plan is a resolved mapping: the field assignments of every type pair.
+	Plan = plan.ResolvedMappingPlan
+	// File is a generated Go source file.
+	File = gen.GeneratedFile
+	// Diagnostics collects the errors, warnings and infos of a step.
+	Diagnostics = diagnostic.Diagnostics
+	// Scorer scores auto-match candidates, see ResolveOptions.Scorer.
+	Scorer = match.Scorer
+	// Field is a struct field of an analyzed type, as scored by a Scorer.
+	Field = analyze.FieldInfo
+	// TypeCompatibility is the built-in compatibility of the types of two
+	// fields, as passed to Scorer.TypeScore.
+	TypeCompatibility = match.TypeCompatibilityResult
+)
+
+// Caster styles of GenerateOptions.Style.
+const (
+	StyleFunctions = gen.StyleFunctions
+	StyleMethods   = gen.StyleMethods
+)
+
+// AnalyzeOptions limits package loading. Zero values use the CLI defaults.
+type AnalyzeOptions struct {
+	// MaxPackages fails loading if the patterns match more packages.
+	MaxPackages int
+	// MaxTypes fails loading if the packages declare more exported types.
+	MaxTypes int
+	// Timeout fails loading if it takes longer.
+	Timeout time.Duration
+	// SkipDirs skips packages under directories with these names, in
+	// addition to vendor and testdata.
+	SkipDirs []string
+	// Proto treats protoc-gen-go structs as messages.
+	Proto bool
+}
+
+// ResolveOptions tunes resolution. Zero values use the CLI defaults.
+type ResolveOptions struct {
+	// MinConfidence is the minimum score for auto-matching a field.
+	MinConfidence float64
+	// MinGap is the minimum score gap between the two best candidates.
+	MinGap float64
+	// AmbiguityThreshold marks candidates within it as ambiguous.
+	AmbiguityThreshold float64
+	// Strict fails resolution on any error, and sets every fail_on_* check
+	// of the mapping file's policy.
+	Strict bool
+	// Policy overrides checks of the mapping file's policy, as comma-separated
+	// settings (e.g. "fail_on_unmapped,min_auto_confidence=0.8").
+	Policy string
+	// PositionWeight is the share of relative field position in candidate scores.
+	PositionWeight float64
+	// MatchTag is a struct tag key whose equal values pin field matches.
+	MatchTag string
+	// StripAffixes lists name tokens ignored in matching, in addition to the
+	// mapping file's strip_affixes.
+	StripAffixes []string
+	// OnIncompatiblePin overrides the mapping file's on_incompatible_pin
+	// ("error", "todo" or "fallback_auto").
+	OnIncompatiblePin string
+	// Scorer replaces the built-in candidate scoring.
+	Scorer Scorer
+	// Tags restricts the plan to the mappings with one of these tags.
+	Tags []string
+	// Only restricts the plan to these type pairs, as "Source->Target".
+	Only []string
+}
+
+// GenerateOptions configures code generation. Zero values use the CLI defaults.
+type GenerateOptions struct {
+	// PackageName is the name of the generated package ("casters").
+	PackageName string
+	// OutputDir is the directory the files are meant for ("./generated").
+	// Functions marked as kept in it are preserved.
+	OutputDir string
+	// DeepCopy clones reference values when the mapping sets no copy_mode.
+	DeepCopy bool
+	// SingleFile writes all casters, helpers and stubs to this one file.
+	SingleFile string
+	// GenericRequires types untyped requires passed only to transforms and
+	// nested casters with a type parameter.
+	GenericRequires bool
+	// Style emits casters as functions or as methods (StyleFunctions or StyleMethods).
+	Style string
+	// FuncTemplate is the naming template of casters, overriding naming.func.
+	FuncTemplate string
+	// Overrides gives each caster a final overrides ...func(*T) parameter
+	// applied to its result.
+	Overrides bool
+	// Benchmarks and Tests add _test.go files benchmarking and checking each caster.
+	Benchmarks bool
+	Tests      bool
+}
+
+// ErrInvalidMapping is wrapped by the errors of mappings that don't validate
+// against the analyzed types.
+var ErrInvalidMapping = errors.New("invalid mapping")
+
+// ErrPolicyViolation is wrapped by the errors of plans with mappings the
+// policy fails on.
+var ErrPolicyViolation = errors.New("policy violation")
+
+// Analyze loads the Go packages matching patterns (import paths, or
+// directories such as "./store") into a type graph.
+func Analyze(patterns []string, opts AnalyzeOptions) (*TypeGraph, error) {
+	limits := analyze.DefaultLimits()
+	if opts.MaxPackages > 0 {
+		limits.MaxPackages = opts.MaxPackages
+	}
+
+	if opts.MaxTypes > 0 {
+		limits.MaxTypes = opts.MaxTypes
+	}
+
+	if opts.Timeout > 0 {
+		limits.Timeout = opts.Timeout
+	}
+
+	limits.SkipDirs = append(append([]string{}, analyze.DefaultSkipDirs...), opts.SkipDirs...)
+
+	analyzer := analyze.NewAnalyzerWithLimits(limits)
+	analyzer.SetProto(opts.Proto)
+
+	return analyzer.LoadPackages(patterns...)
+}
+
+// LoadMapping reads a YAML mapping file, merging the files it includes.
+func LoadMapping(path string) (*MappingFile, error) {
+	return mapping.LoadFile(path)
+}
+
+// ParseMapping parses a YAML mapping definition. Includes aren't followed.
+func ParseMapping(data []byte) (*MappingFile, error) {
+	return mapping.Parse(data)
+}
+
+// MappingPackages returns the packages the types of mf are declared in, to
+// pass to Analyze.
+func MappingPackages(mf *MappingFile) []string {
+	return mapping.Packages(mf)
+}
+
+// NewDefaultScorer returns the built-in Scorer, normalizing names with the
+// strip_affixes and abbreviations of mf and opts and scoring the synonyms of
+// mf, for custom scorers to fall back on. mf may be nil.
+func NewDefaultScorer(mf *MappingFile, opts ResolveOptions) Scorer {
+	if mf == nil {
+		mf = &MappingFile{}
+	}
+
+	return match.NewDefaultScorer(match.Normalizer{
+		Affixes:       slices.Concat(opts.StripAffixes, mf.StripAffixes),
+		Abbreviations: mf.Match.Abbreviations,
+	}, mf.Match.Synonyms)
+}
+
+// Resolve validates mf against graph and resolves it into a plan. Package
+// mappings of mf are expanded in place. Invalid mappings fail with an error
+// wrapping ErrInvalidMapping; the warnings of validation and the diagnostics
+// of resolution are in the plan's Diagnostics.
+func Resolve(graph *TypeGraph, mf *MappingFile, opts ResolveOptions) (*Plan, error) {
+	diags := mapping.ExpandPackageMappings(mf, graph)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMapping, diags.Error())
+	}
+
+	validation := mapping.Validate(mf, graph)
+	if !validation.IsValid() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMapping, validation.Error())
+	}
+
+	diags.Merge(*validation)
+
+	pinPolicy := mapping.PinPolicy(opts.OnIncompatiblePin)
+	if !pinPolicy.IsValid() {
+		return nil, fmt.Errorf("invalid on_incompatible_pin %q (expected %s, %s or %s)",
+			opts.OnIncompatiblePin, mapping.PinError, mapping.PinTodo, mapping.PinFallbackAuto)
+	}
+
+	policy := mf.Policy
+	if opts.Strict {
+		policy = policy.Or(mapping.StrictPolicy)
+	}
+
+	policy, err := policy.Override(opts.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+
+	config := plan.DefaultConfig()
+	if opts.MinConfidence > 0 {
+		config.MinConfidence = opts.MinConfidence
+	}
+
+	if opts.MinGap > 0 {
+		config.MinGap = opts.MinGap
+	}
+
+	if opts.AmbiguityThreshold > 0 {
+		config.AmbiguityThreshold = opts.AmbiguityThreshold
+	}
+
+	config.StrictMode = opts.Strict
+	config.Policy = &policy
+	config.PositionWeight = opts.PositionWeight
+	config.MatchTag = opts.MatchTag
+	config.StripAffixes = opts.StripAffixes
+	config.OnIncompatiblePin = pinPolicy
+	config.Scorer = opts.Scorer
+
+	resolved, err := plan.NewResolver(graph, mf, config).Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.Diagnostics.MergeUnique(*diags)
+
+	if err := resolved.FilterTags(opts.Tags); err != nil {
+		return nil, fmt.Errorf("tags: %w", err)
+	}
+
+	if err := resolved.FilterTypePairs(opts.Only); err != nil {
+		return nil, fmt.Errorf("only: %w", err)
+	}
+
+	if violations := resolved.PolicyViolations(); len(violations) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrPolicyViolation, (&Diagnostics{Errors: violations}).Error())
+	}
+
+	return resolved, nil
+}
+
+// Generate generates the caster code of a plan. The files aren't written,
+// see WriteFiles.
+func Generate(p *Plan, opts GenerateOptions) ([]File, error) {
+	config := gen.DefaultGeneratorConfig()
+	if opts.PackageName != "" {
+		config.PackageName = opts.PackageName
+	}
+
+	if opts.OutputDir != "" {
+		config.OutputDir = opts.OutputDir
+	}
+
+	kept, err := gen.LoadKeptFuncs(config.OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading kept functions: %w", err)
+	}
+
+	config.DeclaredTransforms = make(map[string]bool)
+	for _, t := range p.OriginalTransforms {
+		config.DeclaredTransforms[t.Name] = true
+	}
+
+	config.Kept = kept
+	config.DeepCopy = opts.DeepCopy
+	config.SingleFile = opts.SingleFile
+	config.GenericRequires = opts.GenericRequires
+	config.Style = opts.Style
+	config.FuncTemplate = opts.FuncTemplate
+	config.Overrides = opts.Overrides
+	config.Benchmarks = opts.Benchmarks
+	config.Tests = opts.Tests
+
+	return gen.NewGenerator(config).Generate(p)
+}
+
+// WriteFiles writes generated files to dir, creating it if needed.
+func WriteFiles(files []File, dir string) error {
+	return (&gen.DirBackend{Dir: dir}).Write(files)
+}
+
+// ExportSuggestions returns the YAML mapping suggested by a plan, with its
+// auto-matched fields and the candidates of unmapped ones.
+func ExportSuggestions(p *Plan) ([]byte, error) {
+	return plan.ExportSuggestionsYAML(p)
+}
@@ -0,0 +1,90 @@
+package castergen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const productMapping = `
+version: "1"
+mappings:
+  - source: caster-generator/store.Product
+    target: caster-generator/warehouse.Product
+`
+
+func TestPipeline(t *testing.T) {
+	mf, err := ParseMapping([]byte(productMapping))
+	if err != nil {
+		t.Fatalf("ParseMapping failed: %v", err)
+	}
+
+	packages := MappingPackages(mf)
+	if len(packages) != 2 || packages[0] != "caster-generator/store" || packages[1] != "caster-generator/warehouse" {
+		t.Fatalf("Expected the store and warehouse packages, got %v", packages)
+	}
+
+	graph, err := Analyze(packages, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	p, err := Resolve(graph, mf, ResolveOptions{})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	files, err := Generate(p, GenerateOptions{PackageName: "convert", OutputDir: dir})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if err := WriteFiles(files, dir); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Filename))
+	if err != nil {
+		t.Fatalf("Reading generated file failed: %v", err)
+	}
+
+	if !strings.Contains(string(content), "package convert") ||
+		!strings.Contains(string(content), "func StoreProductToWarehouseProduct(") {
+		t.Errorf("Unexpected generated code:\n%s", content)
+	}
+
+	suggestions, err := ExportSuggestions(p)
+	if err != nil || !strings.Contains(string(suggestions), "caster-generator/warehouse.Product") {
+		t.Errorf("Expected suggestions for the mapping, got %v:\n%s", err, suggestions)
+	}
+}
+
+func TestResolve_InvalidMapping(t *testing.T) {
+	mf, err := ParseMapping([]byte(productMapping + "    121:\n      Missing: Name\n"))
+	if err != nil {
+		t.Fatalf("ParseMapping failed: %v", err)
+	}
+
+	graph, err := Analyze(MappingPackages(mf), AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if _, err := Resolve(graph, mf, ResolveOptions{}); !errors.Is(err, ErrInvalidMapping) {
+		t.Errorf("Expected ErrInvalidMapping, got %v", err)
+	}
+
+	valid, err := ParseMapping([]byte(productMapping))
+	if err != nil {
+		t.Fatalf("ParseMapping failed: %v", err)
+	}
+
+	_, err = Resolve(graph, valid, ResolveOptions{OnIncompatiblePin: "ignore"})
+	if err == nil || errors.Is(err, ErrInvalidMapping) {
+		t.Errorf("Expected an invalid pin policy error, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package castergen_test
+
+import (
+	"fmt"
+
+	"caster-generator/pkg/castergen"
+)
+
+// stockScorer scores warehouse stock as the store's inventory, and leaves
+// other names to the built-in scorer.
+type stockScorer struct {
+	castergen.Scorer
+}
+
+func (s stockScorer) NameScore(source, target *castergen.Field) float64 {
+	if source.Name == "Inventory" && target.Name == "Stock" {
+		return 1
+	}
+
+	return s.Scorer.NameScore(source, target)
+}
+
+func ExampleScorer() {
+	mf, err := castergen.ParseMapping([]byte(`
+mappings:
+  - source: caster-generator/store.Product
+    target: caster-generator/warehouse.Product
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	graph, err := castergen.Analyze(castergen.MappingPackages(mf), castergen.AnalyzeOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	opts := castergen.ResolveOptions{}
+	opts.Scorer = stockScorer{Scorer: castergen.NewDefaultScorer(mf, opts)}
+
+	p, err := castergen.Resolve(graph, mf, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, m := range p.TypePairs[0].Mappings {
+		if m.TargetPaths[0].String() == "Stock" {
+			fmt.Println("Stock <-", m.SourcePaths[0])
+		}
+	}
+
+	// Output: Stock <- Inventory
+}